@@ -217,8 +217,9 @@ func dumpTable(tx *genji.Tx, tableName string, w io.Writer) error {
 	}
 
 	fcs := ti.FieldConstraints
+	fks := ti.ForeignKeys
 	// Fields constraints should be displayed between parenthesis.
-	if len(fcs) > 0 {
+	if len(fcs) > 0 || len(fks) > 0 {
 		buf.WriteString(" (\n")
 	}
 
@@ -237,15 +238,38 @@ func dumpTable(tx *genji.Tx, tableName string, w io.Writer) error {
 		if fc.IsNotNull {
 			buf.WriteString(" NOT NULL")
 		}
+
+		if fc.Collation != document.BinaryCollation {
+			buf.WriteString(" COLLATE " + fc.Collation.String())
+		}
+
+		if fc.IsEncrypted {
+			buf.WriteString(" ENCRYPTED")
+		}
+	}
+
+	for i, fk := range fks {
+		if i > 0 || len(fcs) > 0 {
+			buf.WriteString(",\n")
+		}
+
+		fmt.Fprintf(&buf, "  FOREIGN KEY (%s) REFERENCES %s(%s)", fk.Path, fk.ReferencedTable, fk.ReferencedPath)
+		if fk.OnDelete == database.ForeignKeyCascade {
+			buf.WriteString(" ON DELETE CASCADE")
+		}
 	}
 
 	// Fields constraints close parenthesis.
-	if len(fcs) > 0 {
-		buf.WriteString("\n);\n")
-	} else {
-		buf.WriteString(";\n")
+	if len(fcs) > 0 || len(fks) > 0 {
+		buf.WriteString("\n)")
 	}
 
+	if ti.Strict {
+		buf.WriteString(" STRICT")
+	}
+
+	buf.WriteString(";\n")
+
 	// Print CREATE TABLE statement.
 	if _, err = buf.WriteTo(w); err != nil {
 		return err
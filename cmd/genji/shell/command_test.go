@@ -173,3 +173,45 @@ func TestRunDumpCmd(t *testing.T) {
 	}
 
 }
+
+func TestRunDumpCmdStrict(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	err = db.Exec(ctx, `CREATE TABLE test (a INTEGER) STRICT; INSERT INTO test (a) VALUES (1)`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = runDumpCmd(db, []string{`test`}, &buf)
+	require.NoError(t, err)
+
+	want := "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE test (\n  a INTEGER\n) STRICT;\n" +
+		"INSERT INTO test VALUES {\"a\": 1};\n" +
+		"COMMIT;\n"
+	require.Equal(t, want, buf.String())
+}
+
+func TestRunDumpCmdForeignKeys(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	err = db.Exec(ctx, `
+		CREATE TABLE users (id INTEGER PRIMARY KEY);
+		CREATE TABLE posts (FOREIGN KEY (user_id) REFERENCES users ON DELETE CASCADE);
+	`)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = runDumpCmd(db, []string{`posts`}, &buf)
+	require.NoError(t, err)
+
+	want := "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE posts (\n  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE\n);\n" +
+		"COMMIT;\n"
+	require.Equal(t, want, buf.String())
+}
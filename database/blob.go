@@ -0,0 +1,613 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+// DefaultExternalBlobMinSize is the size above which blobs are stored
+// outside of their document if no minimum size is configured.
+const DefaultExternalBlobMinSize = 1 << 20
+
+// blobChunkSize is the maximum size of the chunks in which external blobs are split.
+const blobChunkSize = 64 << 10
+
+// The blobs of a table are stored in a store whose name is the name of the table store
+// prefixed by blobStorePrefix instead of storePrefix.
+const blobStorePrefix = 'b'
+
+// External blobs are replaced in their document by a reference: a blob starting with
+// blobRefPrefix, followed by the size of the blob encoded as a uvarint.
+// Blobs that start with blobRefPrefix are always stored outside of their document,
+// regardless of their size, to make sure stored blobs starting with it are references.
+var blobRefPrefix = []byte("\x00genji_blob\x00")
+
+// blobStoreName returns the name of the store holding the external blobs of the table.
+func (ti *TableInfo) blobStoreName() []byte {
+	name := append([]byte(nil), ti.storeName...)
+	name[0] = blobStorePrefix
+	return name
+}
+
+// blobStore returns the store holding the external blobs of the table.
+// If it doesn't exist, it is created if create is true, otherwise it returns nil.
+// Internal and virtual tables don't have external blobs.
+func (t *Table) blobStore(create bool) (engine.Store, error) {
+	if t.infoStore == nil {
+		if create {
+			return nil, errors.New("cannot store external blobs in internal tables")
+		}
+		return nil, nil
+	}
+
+	info, err := t.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	name := info.blobStoreName()
+	st, err := t.tx.tx.GetStore(name)
+	if err == nil {
+		return st, nil
+	}
+	if err != engine.ErrStoreNotFound {
+		return nil, err
+	}
+	if !create {
+		return nil, nil
+	}
+
+	err = t.tx.tx.CreateStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.tx.tx.GetStore(name)
+}
+
+// dropBlobStore drops the store holding the external blobs of the table, if any.
+func (tx *Transaction) dropBlobStore(info *TableInfo) error {
+	err := tx.tx.DropStore(info.blobStoreName())
+	if err != nil && err != engine.ErrStoreNotFound {
+		return err
+	}
+
+	return nil
+}
+
+// The chunks of a blob are stored under the key of their document,
+// followed by the field of the blob and the index of the chunk:
+//
+//	uvarint(len(key)) key uvarint(len(field)) field uint64(index)
+func blobKeyPrefix(key []byte) []byte {
+	buf := make([]byte, 0, binary.MaxVarintLen64+len(key))
+	buf = appendUvarint(buf, uint64(len(key)))
+	return append(buf, key...)
+}
+
+func blobFieldPrefix(key []byte, field string) []byte {
+	buf := blobKeyPrefix(key)
+	buf = appendUvarint(buf, uint64(len(field)))
+	return append(buf, field...)
+}
+
+func blobChunkKey(prefix []byte, i uint64) []byte {
+	k := make([]byte, len(prefix)+8)
+	copy(k, prefix)
+	binary.BigEndian.PutUint64(k[len(prefix):], i)
+	return k
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], x)
+	return append(buf, tmp[:n]...)
+}
+
+// newBlobRef returns the reference to an external blob of the given size.
+func newBlobRef(size int64) document.Value {
+	return document.NewBlobValue(appendUvarint(append([]byte(nil), blobRefPrefix...), uint64(size)))
+}
+
+// blobRefSize returns the size of the external blob referenced by v.
+// It returns false if v is not a reference.
+func blobRefSize(v document.Value) (int64, bool) {
+	if v.Type != document.BlobValue {
+		return 0, false
+	}
+
+	b := v.V.([]byte)
+	if !bytes.HasPrefix(b, blobRefPrefix) {
+		return 0, false
+	}
+
+	size, n := binary.Uvarint(b[len(blobRefPrefix):])
+	if n <= 0 {
+		return 0, false
+	}
+
+	return int64(size), true
+}
+
+// hasBlobs returns whether blobs are stored under the given document key.
+func hasBlobs(st engine.Store, key []byte) bool {
+	prefix := blobKeyPrefix(key)
+
+	it := st.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	it.Seek(prefix)
+	return it.Valid() && bytes.HasPrefix(it.Item().Key(), prefix)
+}
+
+// deleteBlobs deletes the chunks stored under the given prefix.
+func deleteBlobs(st engine.Store, prefix []byte) error {
+	var keys [][]byte
+
+	it := st.NewIterator(engine.IteratorConfig{})
+	for it.Seek(prefix); it.Valid() && bytes.HasPrefix(it.Item().Key(), prefix); it.Next() {
+		keys = append(keys, append([]byte(nil), it.Item().Key()...))
+	}
+	err := it.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		err = st.Delete(k)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteDocumentBlobs deletes the external blobs of the document stored at key.
+func (t *Table) deleteDocumentBlobs(key []byte) error {
+	st, err := t.blobStore(false)
+	if err != nil || st == nil {
+		return err
+	}
+
+	return deleteBlobs(st, blobKeyPrefix(key))
+}
+
+// storeBlobs returns a copy of the stored version of a document in which the top-level blobs
+// larger than the ExternalBlobMinSize option of the database are replaced by references.
+// Their content is split in chunks stored under the key of the document.
+// If replace is true, the blobs previously stored under key are deleted. d may reference them,
+// which is why it is copied before.
+// If no blob is stored outside of the document, d is returned.
+func (t *Table) storeBlobs(key []byte, d document.Document, replace bool) (document.Document, error) {
+	if t.infoStore == nil {
+		return d, nil
+	}
+
+	var fb *document.FieldBuffer
+
+	if replace {
+		st, err := t.blobStore(false)
+		if err != nil {
+			return nil, err
+		}
+
+		if st != nil && hasBlobs(st, key) {
+			fb = document.NewFieldBuffer()
+			err = fb.Copy(d)
+			if err != nil {
+				return nil, err
+			}
+			d = fb
+
+			err = deleteBlobs(st, blobKeyPrefix(key))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	minSize := t.tx.db.ExternalBlobMinSize
+
+	var fields []string
+	err := d.Iterate(func(field string, v document.Value) error {
+		if v.Type != document.BlobValue {
+			return nil
+		}
+
+		b := v.V.([]byte)
+		if len(b) > minSize || bytes.HasPrefix(b, blobRefPrefix) {
+			fields = append(fields, field)
+		}
+		return nil
+	})
+	if err != nil || len(fields) == 0 {
+		return d, err
+	}
+
+	if fb == nil {
+		fb = document.NewFieldBuffer()
+		err = fb.Copy(d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	st, err := t.blobStore(true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		v, err := fb.GetByField(field)
+		if err != nil {
+			return nil, err
+		}
+
+		w := blobWriter{st: st, prefix: blobFieldPrefix(key, field)}
+		_, err = w.Write(v.V.([]byte))
+		if err != nil {
+			return nil, err
+		}
+		err = w.flush()
+		if err != nil {
+			return nil, err
+		}
+
+		err = fb.Replace(field, newBlobRef(w.size))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fb, nil
+}
+
+// loadBlob returns v, or the content of the external blob it references.
+func (t *Table) loadBlob(key []byte, field string, v document.Value) (document.Value, error) {
+	size, ok := blobRefSize(v)
+	if !ok {
+		return v, nil
+	}
+
+	r, err := t.newBlobReader(key, field, size)
+	if err != nil {
+		return document.Value{}, err
+	}
+
+	b := make([]byte, size)
+	_, err = io.ReadFull(r, b)
+	if err != nil {
+		return document.Value{}, err
+	}
+
+	return document.NewBlobValue(b), nil
+}
+
+func (t *Table) newBlobReader(key []byte, field string, size int64) (*blobReader, error) {
+	st, err := t.blobStore(false)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil {
+		return nil, fmt.Errorf("missing content of blob field %q", field)
+	}
+
+	return &blobReader{st: st, prefix: blobFieldPrefix(key, field), size: size, field: field}, nil
+}
+
+// BlobReader returns a reader of the blob stored in the given top-level field of the
+// document stored at key.
+// Blobs stored outside of their document are read chunk by chunk, without being entirely loaded
+// in memory. The reader is only valid until the end of the transaction.
+// If the field doesn't exist, it returns document.ErrFieldNotFound.
+func (t *Table) BlobReader(key []byte, field string) (io.Reader, error) {
+	if t.partitions != nil {
+		i, k, ok := splitPartitionKey(key, len(t.partitions))
+		if !ok {
+			return nil, ErrDocumentNotFound
+		}
+
+		return t.partitions[i].BlobReader(k, field)
+	}
+
+	info, err := t.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := t.Store.Get(key)
+	if err == engine.ErrKeyNotFound {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := t.tx.db.Codec.NewDocument(raw).GetByField(field)
+	if err != nil {
+		return nil, err
+	}
+
+	// encrypted values must be decrypted at once
+	if info.isEncrypted(document.ValuePath{document.ValuePathFragment{FieldName: field}}) {
+		d, err := t.GetDocument(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err = d.GetByField(field)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if v.Type != document.BlobValue {
+		return nil, fmt.Errorf("field %q is not a blob", field)
+	}
+
+	size, ok := blobRefSize(v)
+	if !ok {
+		return bytes.NewReader(v.V.([]byte)), nil
+	}
+
+	return t.newBlobReader(key, field, size)
+}
+
+// BlobWriter returns a writer that stores the data written to it as the blob
+// of the given top-level field of the document stored at key.
+// The data is split in chunks stored outside of the document as it is written,
+// which allows to store blobs that don't fit in memory.
+// The field of the document is only set once the writer is closed. The previous content
+// of the field is lost as soon as BlobWriter is called: if writing the blob fails,
+// the transaction must be rolled back.
+// Since the field is not validated like with Replace, it must not be encrypted, indexed,
+// or have a type other than BLOB.
+func (t *Table) BlobWriter(key []byte, field string) (io.WriteCloser, error) {
+	info, err := t.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.readOnly {
+		return nil, errors.New("cannot write to read-only table")
+	}
+
+	path := document.ValuePath{document.ValuePathFragment{FieldName: field}}
+	for _, fc := range info.FieldConstraints {
+		if !fc.Path.IsEqual(path) {
+			continue
+		}
+
+		if fc.IsEncrypted {
+			return nil, fmt.Errorf("cannot write blob to encrypted field %q", field)
+		}
+		if fc.Type != 0 && fc.Type != document.BlobValue {
+			return nil, fmt.Errorf("cannot write blob to field %q of type %s", field, fc.Type)
+		}
+	}
+
+	indexes, err := t.Indexes()
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range indexes {
+		if idx.Opts.Path.IsEqual(path) {
+			return nil, fmt.Errorf("cannot write blob to field %q indexed by %s", field, idx.Opts.IndexName)
+		}
+	}
+
+	_, err = t.Store.Get(key)
+	if err == engine.ErrKeyNotFound {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := t.blobStore(true)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := blobFieldPrefix(key, field)
+	err = deleteBlobs(st, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &documentBlobWriter{
+		blobWriter: blobWriter{st: st, prefix: prefix},
+		t:          t,
+		key:        key,
+		field:      field,
+	}, nil
+}
+
+// blobWriter splits the data written to it in chunks stored under prefix.
+type blobWriter struct {
+	st     engine.Store
+	prefix []byte
+	buf    []byte
+	chunks uint64
+	size   int64
+}
+
+func (w *blobWriter) Write(p []byte) (int, error) {
+	n := len(p)
+
+	for len(p) > 0 {
+		l := blobChunkSize - len(w.buf)
+		if l > len(p) {
+			l = len(p)
+		}
+
+		w.buf = append(w.buf, p[:l]...)
+		p = p[l:]
+
+		if len(w.buf) == blobChunkSize {
+			err := w.flush()
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	w.size += int64(n)
+	return n, nil
+}
+
+// flush stores the buffered data as a new chunk.
+func (w *blobWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	err := w.st.Put(blobChunkKey(w.prefix, w.chunks), w.buf)
+	if err != nil {
+		return err
+	}
+
+	// engines may keep the value until the end of the transaction
+	w.chunks++
+	w.buf = nil
+	return nil
+}
+
+// documentBlobWriter sets the field of the document to the reference
+// of the blob once it is entirely written.
+type documentBlobWriter struct {
+	blobWriter
+
+	t      *Table
+	key    []byte
+	field  string
+	closed bool
+}
+
+func (w *documentBlobWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("blob writer closed")
+	}
+
+	return w.blobWriter.Write(p)
+}
+
+// Close stores the remaining data and sets the field of the document.
+func (w *documentBlobWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	err := w.flush()
+	if err != nil {
+		return err
+	}
+
+	// the other fields of the document are stored unchanged
+	raw, err := w.t.Store.Get(w.key)
+	if err != nil {
+		return err
+	}
+
+	var fb document.FieldBuffer
+	err = fb.Copy(w.t.tx.db.Codec.NewDocument(raw))
+	if err != nil {
+		return err
+	}
+
+	err = fb.Set(document.ValuePath{document.ValuePathFragment{FieldName: w.field}}, newBlobRef(w.size))
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = w.t.tx.db.Codec.NewEncoder(&buf).EncodeDocument(&fb)
+	if err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+
+	return w.t.Store.Put(w.key, buf.Bytes())
+}
+
+// blobReader reads the chunks stored under prefix.
+type blobReader struct {
+	st     engine.Store
+	prefix []byte
+	field  string
+	size   int64
+
+	read  int64
+	chunk uint64
+	data  []byte
+	// unread part of data
+	buf []byte
+}
+
+func (r *blobReader) Read(p []byte) (int, error) {
+	if r.read >= r.size {
+		return 0, io.EOF
+	}
+
+	if len(r.buf) == 0 {
+		v, err := r.st.Get(blobChunkKey(r.prefix, r.chunk))
+		if err == engine.ErrKeyNotFound {
+			return 0, fmt.Errorf("missing content of blob field %q", r.field)
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		// values may only be valid until the next write
+		r.data = append(r.data[:0], v...)
+		r.buf = r.data
+		r.chunk++
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	r.read += int64(n)
+	return n, nil
+}
+
+// blobDocument replaces the references to external blobs of a stored document
+// by their content when its fields are read.
+type blobDocument struct {
+	document.Document
+
+	t *Table
+}
+
+func (d *blobDocument) GetByField(field string) (document.Value, error) {
+	v, err := d.Document.GetByField(field)
+	if err != nil {
+		return v, err
+	}
+
+	return d.t.loadBlob(d.Key(), field, v)
+}
+
+func (d *blobDocument) Iterate(fn func(field string, value document.Value) error) error {
+	return d.Document.Iterate(func(field string, v document.Value) error {
+		v, err := d.t.loadBlob(d.Key(), field, v)
+		if err != nil {
+			return err
+		}
+
+		return fn(field, v)
+	})
+}
+
+func (d *blobDocument) Key() []byte {
+	if k, ok := d.Document.(document.Keyer); ok {
+		return k.Key()
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package database_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine/boltengine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableExternalBlobs(t *testing.T) {
+	newTable := func(t *testing.T) (*database.Table, func()) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{
+			Codec:               msgpack.NewCodec(),
+			ExternalBlobMinSize: 10,
+		})
+		require.NoError(t, err)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+
+		err = tx.CreateTable("test", nil)
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		return tb, func() { tx.Rollback() }
+	}
+
+	getBlob := func(t *testing.T, tb *database.Table, key []byte, field string) []byte {
+		d, err := tb.GetDocument(key)
+		require.NoError(t, err)
+		v, err := d.GetByField(field)
+		require.NoError(t, err)
+		return v.V.([]byte)
+	}
+
+	large := bytes.Repeat([]byte("a"), 100)
+	small := []byte("b")
+
+	t.Run("Should store large blobs outside of the document", func(t *testing.T) {
+		tb, cleanup := newTable(t)
+		defer cleanup()
+
+		key, err := tb.Insert(document.NewFieldBuffer().
+			Add("large", document.NewBlobValue(large)).
+			Add("small", document.NewBlobValue(small)))
+		require.NoError(t, err)
+
+		raw, err := tb.Store.Get(key)
+		require.NoError(t, err)
+		require.Less(t, len(raw), len(large))
+
+		require.Equal(t, large, getBlob(t, tb, key, "large"))
+		require.Equal(t, small, getBlob(t, tb, key, "small"))
+
+		var fields []string
+		err = tb.Iterate(func(d document.Document) error {
+			return d.Iterate(func(field string, v document.Value) error {
+				fields = append(fields, field)
+				if field == "large" {
+					require.Equal(t, large, v.V.([]byte))
+				}
+				return nil
+			})
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"large", "small"}, fields)
+
+		r, err := tb.BlobReader(key, "large")
+		require.NoError(t, err)
+		b, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, large, b)
+
+		r, err = tb.BlobReader(key, "small")
+		require.NoError(t, err)
+		b, err = ioutil.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, small, b)
+	})
+
+	t.Run("Should keep blobs when other fields are replaced", func(t *testing.T) {
+		tb, cleanup := newTable(t)
+		defer cleanup()
+
+		key, err := tb.Insert(document.NewFieldBuffer().Add("large", document.NewBlobValue(large)))
+		require.NoError(t, err)
+
+		// the new document reads the blob from the previous version
+		d, err := tb.GetDocument(key)
+		require.NoError(t, err)
+		err = tb.Replace(key, d)
+		require.NoError(t, err)
+		require.Equal(t, large, getBlob(t, tb, key, "large"))
+
+		other := bytes.Repeat([]byte("c"), 50)
+		err = tb.Replace(key, document.NewFieldBuffer().Add("large", document.NewBlobValue(other)))
+		require.NoError(t, err)
+		require.Equal(t, other, getBlob(t, tb, key, "large"))
+
+		err = tb.Replace(key, document.NewFieldBuffer().Add("large", document.NewBlobValue(small)))
+		require.NoError(t, err)
+		require.Equal(t, small, getBlob(t, tb, key, "large"))
+
+		records, size, err := tb.SizeEstimate()
+		require.NoError(t, err)
+		require.EqualValues(t, 1, records)
+		require.Less(t, size, int64(len(other)))
+	})
+
+	t.Run("Should delete blobs with their document", func(t *testing.T) {
+		tb, cleanup := newTable(t)
+		defer cleanup()
+
+		key, err := tb.Insert(document.NewFieldBuffer().Add("large", document.NewBlobValue(large)))
+		require.NoError(t, err)
+
+		_, size, err := tb.SizeEstimate()
+		require.NoError(t, err)
+		require.Greater(t, size, int64(len(large)))
+
+		err = tb.Delete(key)
+		require.NoError(t, err)
+
+		records, size, err := tb.SizeEstimate()
+		require.NoError(t, err)
+		require.Zero(t, records)
+		require.Zero(t, size)
+	})
+
+	t.Run("Should not confuse blobs with references", func(t *testing.T) {
+		tb, cleanup := newTable(t)
+		defer cleanup()
+
+		fake := []byte("\x00genji_blob\x00\x05")
+		key, err := tb.Insert(document.NewFieldBuffer().Add("a", document.NewBlobValue(fake)))
+		require.NoError(t, err)
+		require.Equal(t, fake, getBlob(t, tb, key, "a"))
+	})
+
+	t.Run("BlobWriter", func(t *testing.T) {
+		tb, cleanup := newTable(t)
+		defer cleanup()
+
+		key, err := tb.Insert(document.NewFieldBuffer().
+			Add("a", document.NewIntegerValue(1)).
+			Add("b", document.NewBlobValue(large)))
+		require.NoError(t, err)
+
+		w, err := tb.BlobWriter(key, "c")
+		require.NoError(t, err)
+		_, err = w.Write(small)
+		require.NoError(t, err)
+		_, err = w.Write(small)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		// the other fields are unchanged
+		d, err := tb.GetDocument(key)
+		require.NoError(t, err)
+		v, err := d.GetByField("a")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(1), v)
+		require.Equal(t, large, getBlob(t, tb, key, "b"))
+		require.Equal(t, []byte("bb"), getBlob(t, tb, key, "c"))
+
+		_, err = tb.BlobWriter([]byte("unknown"), "c")
+		require.Equal(t, database.ErrDocumentNotFound, err)
+
+		err = tb.Tx().CreateIndex(database.IndexConfig{TableName: "test", IndexName: "idx_test_a", Path: parsePath(t, "a")})
+		require.NoError(t, err)
+		_, err = tb.BlobWriter(key, "a")
+		require.Error(t, err)
+	})
+}
+
+func TestTableStreamBlobs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genji")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ng, err := boltengine.NewEngine(filepath.Join(dir, "test.db"), 0600, nil)
+	require.NoError(t, err)
+	defer ng.Close()
+
+	db, err := database.New(ng, database.Options{Codec: msgpack.NewCodec()})
+	require.NoError(t, err)
+
+	const size = 8 << 20
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	err = tx.CreateTable("test", nil)
+	require.NoError(t, err)
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	key, err := tb.Insert(document.NewFieldBuffer().Add("name", document.NewTextValue("file")))
+	require.NoError(t, err)
+
+	// the blob is generated and hashed while it is written
+	written := sha256.New()
+	w, err := tb.BlobWriter(key, "data")
+	require.NoError(t, err)
+	n, err := io.Copy(w, io.TeeReader(io.LimitReader(rand.New(rand.NewSource(1)), size), written))
+	require.NoError(t, err)
+	require.EqualValues(t, size, n)
+	require.NoError(t, w.Close())
+
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	tx, err = db.Begin(false)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	tb, err = tx.GetTable("test")
+	require.NoError(t, err)
+
+	// the document only holds a reference to the blob
+	raw, err := tb.Store.Get(key)
+	require.NoError(t, err)
+	require.Less(t, len(raw), 1024)
+
+	r, err := tb.BlobReader(key, "data")
+	require.NoError(t, err)
+	read := sha256.New()
+	n, err = io.Copy(read, r)
+	require.NoError(t, err)
+	require.EqualValues(t, size, n)
+	require.Equal(t, written.Sum(nil), read.Sum(nil))
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding"
+	"github.com/golang/snappy"
+)
+
+// DefaultCompressionMinSize is the size under which encoded documents
+// are stored uncompressed if no minimum size is configured.
+const DefaultCompressionMinSize = 256
+
+// Stored values starting with valueFlag are followed by a byte
+// describing how the rest of the value is stored.
+// MessagePack never uses 0xc1, which guarantees that documents
+// written before compression was supported are always stored raw.
+// Raw documents that happen to start with valueFlag are stored
+// with a flag to avoid any ambiguity.
+const valueFlag byte = 0xc1
+
+const (
+	rawValue byte = iota
+	snappyValue
+)
+
+// CompressionOptions configures the compression of the documents
+// stored in the database.
+type CompressionOptions struct {
+	// If true, encoded documents are compressed using Snappy before being stored.
+	// A database containing compressed documents must always be opened with compression enabled.
+	Enabled bool
+	// Encoded documents smaller than MinSize are stored uncompressed.
+	// Defaults to DefaultCompressionMinSize.
+	MinSize int
+}
+
+// compressionCodec wraps a codec to compress the encoded documents
+// and decompress them transparently.
+// Documents are decompressed regardless of their size,
+// to allow reading databases that were written with another minimum size.
+type compressionCodec struct {
+	encoding.Codec
+
+	opts CompressionOptions
+}
+
+// newCompressionCodec returns codec wrapped in a compressionCodec if compression is enabled.
+// Otherwise, codec is returned as is and compressed documents cannot be read.
+func newCompressionCodec(codec encoding.Codec, opts CompressionOptions) encoding.Codec {
+	if !opts.Enabled {
+		return codec
+	}
+
+	if opts.MinSize <= 0 {
+		opts.MinSize = DefaultCompressionMinSize
+	}
+
+	return &compressionCodec{
+		Codec: codec,
+		opts:  opts,
+	}
+}
+
+// NewEncoder implements the encoding.Codec interface.
+func (c *compressionCodec) NewEncoder(w io.Writer) encoding.Encoder {
+	return &compressionEncoder{
+		w:     w,
+		codec: c,
+	}
+}
+
+// NewDocument implements the encoding.Codec interface.
+func (c *compressionCodec) NewDocument(data []byte) document.Document {
+	data, err := c.decompress(data)
+	if err != nil {
+		return malformedDocument{err: err}
+	}
+
+	return c.Codec.NewDocument(data)
+}
+
+// DecodeFields implements the encoding.FieldsDecoder interface.
+// If the underlying codec doesn't implement it, the document is lazily decoded instead.
+func (c *compressionCodec) DecodeFields(data []byte, paths ...document.ValuePath) (document.Document, error) {
+	data, err := c.decompress(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if fd, ok := c.Codec.(encoding.FieldsDecoder); ok {
+		return fd.DecodeFields(data, paths...)
+	}
+
+	return c.Codec.NewDocument(data), nil
+}
+
+// decompress returns the encoded document stored in data.
+func (c *compressionCodec) decompress(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != valueFlag {
+		return data, nil
+	}
+
+	switch data[1] {
+	case rawValue:
+		return data[2:], nil
+	case snappyValue:
+		buf, err := snappy.Decode(nil, data[2:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress document: %w", err)
+		}
+
+		return buf, nil
+	}
+
+	return nil, fmt.Errorf("unknown value flag %d", data[1])
+}
+
+type compressionEncoder struct {
+	w     io.Writer
+	codec *compressionCodec
+	buf   bytes.Buffer
+}
+
+// EncodeDocument encodes d using the underlying codec and compresses the result
+// if it is large enough and if compressing it actually reduces its size.
+func (e *compressionEncoder) EncodeDocument(d document.Document) error {
+	e.buf.Reset()
+	err := e.codec.Codec.NewEncoder(&e.buf).EncodeDocument(d)
+	if err != nil {
+		return err
+	}
+
+	data := e.buf.Bytes()
+
+	if len(data) >= e.codec.opts.MinSize {
+		compressed := snappy.Encode(nil, data)
+		if len(compressed)+2 < len(data) {
+			return e.write(snappyValue, compressed)
+		}
+	}
+
+	if len(data) > 0 && data[0] == valueFlag {
+		return e.write(rawValue, data)
+	}
+
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *compressionEncoder) write(flag byte, data []byte) error {
+	_, err := e.w.Write([]byte{valueFlag, flag})
+	if err != nil {
+		return err
+	}
+
+	_, err = e.w.Write(data)
+	return err
+}
+
+// malformedDocument is returned when a stored value cannot be decoded.
+// It returns the decoding error when accessed.
+type malformedDocument struct {
+	err error
+}
+
+func (d malformedDocument) GetByField(field string) (document.Value, error) {
+	return document.Value{}, d.err
+}
+
+func (d malformedDocument) Iterate(fn func(field string, value document.Value) error) error {
+	return d.err
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -19,17 +20,64 @@ type FieldConstraint struct {
 	Type         document.ValueType
 	IsPrimaryKey bool
 	IsNotNull    bool
+	// Collation used to compare and order the text values of the field.
+	Collation document.Collation
+	// ExplicitCollation is true if Collation was specified when declaring the field.
+	// Otherwise, TEXT fields use the default collation of the database.
+	// It is not stored with the table information.
+	ExplicitCollation bool
+	// DefaultValue is evaluated when a document is inserted
+	// without a value for this field. Nil if no default value was specified.
+	DefaultValue TableExpression
+	// If IsEncrypted is true, the value of the field is encrypted before being stored
+	// and decrypted when read, using the encryption key of the database.
+	// Encrypted fields can't be indexed.
+	IsEncrypted bool
+}
+
+// A TableExpression is an expression stored along with the table information,
+// such as the default value of a field.
+type TableExpression interface {
+	// Eval evaluates the expression within the given transaction.
+	Eval(tx *Transaction) (document.Value, error)
+	// ToDocument encodes the expression in a document
+	// so it can be stored with the table information.
+	ToDocument() (document.Document, error)
+}
+
+// decodeTableExpression is used to decode the table expressions stored
+// in the table information. It is registered by the package implementing
+// the expressions using the RegisterTableExpressionDecoder function.
+var decodeTableExpression func(d document.Document) (TableExpression, error)
+
+// RegisterTableExpressionDecoder registers the function used to decode
+// table expressions previously encoded with their ToDocument method.
+func RegisterTableExpressionDecoder(fn func(d document.Document) (TableExpression, error)) {
+	decodeTableExpression = fn
 }
 
 // ToDocument returns a document from f.
-func (f *FieldConstraint) ToDocument() document.Document {
+func (f *FieldConstraint) ToDocument() (document.Document, error) {
 	buf := document.NewFieldBuffer()
 
 	buf.Add("path", document.NewArrayValue(valuePathToArray(f.Path)))
 	buf.Add("type", document.NewIntegerValue(int64(f.Type)))
 	buf.Add("is_primary_key", document.NewBoolValue(f.IsPrimaryKey))
 	buf.Add("is_not_null", document.NewBoolValue(f.IsNotNull))
-	return buf
+	if f.Collation != document.BinaryCollation {
+		buf.Add("collation", document.NewIntegerValue(int64(f.Collation)))
+	}
+	if f.DefaultValue != nil {
+		d, err := f.DefaultValue.ToDocument()
+		if err != nil {
+			return nil, err
+		}
+		buf.Add("default_value", document.NewDocumentValue(d))
+	}
+	if f.IsEncrypted {
+		buf.Add("is_encrypted", document.NewBoolValue(true))
+	}
+	return buf, nil
 }
 
 // ScanDocument implements the document.Scanner interface.
@@ -61,6 +109,38 @@ func (f *FieldConstraint) ScanDocument(d document.Document) error {
 		return err
 	}
 	f.IsNotNull = v.V.(bool)
+
+	v, err = d.GetByField("collation")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		f.Collation = document.Collation(v.V.(int64))
+	}
+
+	v, err = d.GetByField("default_value")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		if decodeTableExpression == nil {
+			return errors.New("cannot decode default value: no table expression decoder registered")
+		}
+
+		f.DefaultValue, err = decodeTableExpression(v.V.(document.Document))
+		if err != nil {
+			return err
+		}
+	}
+
+	v, err = d.GetByField("is_encrypted")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		f.IsEncrypted = v.V.(bool)
+	}
+
 	return nil
 }
 
@@ -76,6 +156,12 @@ type TableInfo struct {
 	transactionID int64
 
 	FieldConstraints []FieldConstraint
+	// If Strict is true, documents can only contain the fields declared
+	// by the field constraints. Otherwise, the table is schemaless and
+	// accepts any other field.
+	Strict bool
+	// ForeignKeys lists the fields referencing the documents of other tables.
+	ForeignKeys []ForeignKey
 }
 
 // GetPrimaryKey returns the field constraint of the primary key.
@@ -91,7 +177,7 @@ func (ti *TableInfo) GetPrimaryKey() *FieldConstraint {
 }
 
 // ToDocument turns ti into a document.
-func (ti *TableInfo) ToDocument() document.Document {
+func (ti *TableInfo) ToDocument() (document.Document, error) {
 	buf := document.NewFieldBuffer()
 
 	buf.Add("table_name", document.NewTextValue(ti.tableName))
@@ -99,13 +185,27 @@ func (ti *TableInfo) ToDocument() document.Document {
 
 	vbuf := document.NewValueBuffer()
 	for _, fc := range ti.FieldConstraints {
-		vbuf = vbuf.Append(document.NewDocumentValue(fc.ToDocument()))
+		d, err := fc.ToDocument()
+		if err != nil {
+			return nil, err
+		}
+		vbuf = vbuf.Append(document.NewDocumentValue(d))
 	}
 
 	buf.Add("field_constraints", document.NewArrayValue(vbuf))
 
 	buf.Add("read_only", document.NewBoolValue(ti.readOnly))
-	return buf
+	if ti.Strict {
+		buf.Add("strict", document.NewBoolValue(ti.Strict))
+	}
+	if len(ti.ForeignKeys) > 0 {
+		vbuf := document.NewValueBuffer()
+		for _, fk := range ti.ForeignKeys {
+			vbuf = vbuf.Append(document.NewDocumentValue(fk.ToDocument()))
+		}
+		buf.Add("foreign_keys", document.NewArrayValue(vbuf))
+	}
+	return buf, nil
 }
 
 // ScanDocument decodes d into ti.
@@ -149,6 +249,31 @@ func (ti *TableInfo) ScanDocument(d document.Document) error {
 	}
 
 	ti.readOnly = v.V.(bool)
+
+	v, err = d.GetByField("strict")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		ti.Strict = v.V.(bool)
+	}
+
+	v, err = d.GetByField("foreign_keys")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		err = v.V.(document.Array).Iterate(func(i int, value document.Value) error {
+			var fk ForeignKey
+			err := fk.ScanDocument(value.V.(document.Document))
+			ti.ForeignKeys = append(ti.ForeignKeys, fk)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -159,10 +284,19 @@ type tableInfoStore struct {
 	db *Database
 	// tableInfos contains information about all the tables
 	tableInfos map[string]TableInfo
+	// information of the committed tables deleted by the current transaction,
+	// restored if it is rolled back.
+	deleted []deletedTableInfo
 
 	mu sync.RWMutex
 }
 
+type deletedTableInfo struct {
+	transactionID int64
+	tableName     string
+	info          TableInfo
+}
+
 func newTableInfoStore(db *Database, tx engine.Transaction) (*tableInfoStore, error) {
 	ts := tableInfoStore{
 		db: db,
@@ -209,8 +343,13 @@ func (t *tableInfoStore) Insert(tx *Transaction, tableName string, info *TableIn
 		info.storeName = buf[:n+1]
 	}
 
+	d, err := info.ToDocument()
+	if err != nil {
+		return err
+	}
+
 	var buf bytes.Buffer
-	err = t.db.Codec.NewEncoder(&buf).EncodeDocument(info.ToDocument())
+	err = t.db.Codec.NewEncoder(&buf).EncodeDocument(d)
 	if err != nil {
 		return err
 	}
@@ -268,6 +407,13 @@ func (t *tableInfoStore) Delete(tx *Transaction, tableName string) error {
 		return err
 	}
 
+	if info.transactionID == 0 {
+		t.deleted = append(t.deleted, deletedTableInfo{
+			transactionID: tx.id,
+			tableName:     tableName,
+			info:          info,
+		})
+	}
 	delete(t.tableInfos, tableName)
 
 	return nil
@@ -336,25 +482,59 @@ func (t *tableInfoStore) loadAllTableInfo(tx engine.Transaction) error {
 	return nil
 }
 
-// remove all tableInfo whose transaction id is equal to the given transacrion id.
+// remove all tableInfo whose transaction id is equal to the given transacrion id
+// and restore the tableInfo deleted by the transaction.
 // this is called when a read/write transaction is being rolled back.
 func (t *tableInfoStore) rollback(tx *Transaction) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	t.rollbackLocked(tx)
+}
+
+// rollbackLocked is the same as rollback but expects t.mu to be locked.
+func (t *tableInfoStore) rollbackLocked(tx *Transaction) {
 	for k, info := range t.tableInfos {
 		if info.transactionID == tx.id {
 			delete(t.tableInfos, k)
 		}
 	}
+
+	for _, d := range t.deleted {
+		if d.transactionID == tx.id {
+			t.tableInfos[d.tableName] = d.info
+		}
+	}
+	t.forgetDeleted(tx)
+}
+
+// forgetDeleted removes the tableInfo deleted by the transaction from the deleted list.
+func (t *tableInfoStore) forgetDeleted(tx *Transaction) {
+	deleted := t.deleted[:0]
+	for _, d := range t.deleted {
+		if d.transactionID != tx.id {
+			deleted = append(deleted, d)
+		}
+	}
+	t.deleted = deleted
 }
 
-// set all the tableInfo created by this transaction to 0.
+// commit calls commitFn to commit the transaction to the engine, then sets the transaction id
+// of all the tableInfo created by this transaction to 0.
+// The lock is held during the whole operation so that no other transaction can see
+// the committed data without the table information. If commitFn fails, the changes
+// made by the transaction are rolled back.
 // this is called when a read/write transaction is being commited.
-func (t *tableInfoStore) commit(tx *Transaction) {
+func (t *tableInfoStore) commit(tx *Transaction, commitFn func() error) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	err := commitFn()
+	if err != nil {
+		t.rollbackLocked(tx)
+		return err
+	}
+
 	for k := range t.tableInfos {
 		if t.tableInfos[k].transactionID == tx.id {
 			info := t.tableInfos[k]
@@ -362,6 +542,8 @@ func (t *tableInfoStore) commit(tx *Transaction) {
 			t.tableInfos[k] = info
 		}
 	}
+	t.forgetDeleted(tx)
+	return nil
 }
 
 // GetTableInfo returns a copy of all the table information.
@@ -388,6 +570,9 @@ type IndexConfig struct {
 
 	// If set, the index is typed and only accepts that type
 	Type document.ValueType
+
+	// Collation used to encode text values.
+	Collation document.Collation
 }
 
 // ToDocument creates a document from an IndexConfig.
@@ -401,6 +586,9 @@ func (i *IndexConfig) ToDocument() document.Document {
 	if i.Type != 0 {
 		buf.Add("type", document.NewIntegerValue(int64(i.Type)))
 	}
+	if i.Collation != document.BinaryCollation {
+		buf.Add("collation", document.NewIntegerValue(int64(i.Collation)))
+	}
 	return buf
 }
 
@@ -441,6 +629,14 @@ func (i *IndexConfig) ScanDocument(d document.Document) error {
 		i.Type = document.ValueType(v.V.(int64))
 	}
 
+	v, err = d.GetByField("collation")
+	if err != nil && err != document.ErrFieldNotFound {
+		return err
+	}
+	if err == nil {
+		i.Collation = document.Collation(v.V.(int64))
+	}
+
 	return nil
 }
 
@@ -451,6 +647,29 @@ type Index struct {
 	Opts IndexConfig
 }
 
+// Set associates the value of the indexed field of a document with its key.
+// If the value is too long to be indexed, it returns ErrIndexKeyTooLong.
+func (i *Index) Set(v document.Value, k []byte) error {
+	return i.keyError(i.Index.Set(v, k))
+}
+
+// SetBatch associates the values of the indexed field of several documents with their keys,
+// like Set.
+func (i *Index) SetBatch(entries []index.Entry) error {
+	return i.keyError(i.Index.SetBatch(entries))
+}
+
+// keyError returns ErrIndexKeyTooLong if err reports a value too long to be indexed.
+func (i *Index) keyError(err error) error {
+	var kErr *index.KeyTooLongError
+	if errors.As(err, &kErr) {
+		return fmt.Errorf("%w: the value of field %s takes %d bytes in index %s, the maximum is %d",
+			ErrIndexKeyTooLong, i.Opts.Path, kErr.Size, i.Opts.IndexName, kErr.MaxSize)
+	}
+
+	return err
+}
+
 type indexStore struct {
 	db *Database
 	st engine.Store
@@ -544,6 +763,23 @@ func (t *indexStore) ListAll() ([]*IndexConfig, error) {
 	return idxList, nil
 }
 
+// ListByTable returns the configuration of the indexes of the given table.
+func (t *indexStore) ListByTable(tableName string) ([]*IndexConfig, error) {
+	list, err := t.ListAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var idxList []*IndexConfig
+	for _, opts := range list {
+		if opts.TableName == tableName {
+			idxList = append(idxList, opts)
+		}
+	}
+
+	return idxList, nil
+}
+
 func arrayToValuePath(v document.Value) (document.ValuePath, error) {
 	var path document.ValuePath
 
@@ -22,14 +22,52 @@ func TestTableInfo(t *testing.T) {
 	info := &TableInfo{
 		FieldConstraints: []FieldConstraint{
 			{Path: newValuePath("k"), Type: document.DoubleValue, IsPrimaryKey: true},
+			{Path: document.ValuePath{document.ValuePathFragment{FieldName: "name"}}, Type: document.TextValue, Collation: document.NoCaseCollation},
 		},
 	}
 
-	doc := info.ToDocument()
+	doc, err := info.ToDocument()
+	require.NoError(t, err)
 
 	var res TableInfo
-	err := res.ScanDocument(doc)
+	err = res.ScanDocument(doc)
+	require.NoError(t, err)
+	require.Equal(t, info.FieldConstraints, res.FieldConstraints)
+	require.False(t, res.Strict)
+
+	info.Strict = true
+	doc, err = info.ToDocument()
+	require.NoError(t, err)
+
+	res = TableInfo{}
+	err = res.ScanDocument(doc)
+	require.NoError(t, err)
+	require.True(t, res.Strict)
+	require.Nil(t, res.ForeignKeys)
+
+	info.ForeignKeys = []ForeignKey{
+		{Path: document.ValuePath{document.ValuePathFragment{FieldName: "user_id"}}, ReferencedTable: "users", ReferencedPath: newValuePath("id"), OnDelete: ForeignKeyCascade},
+	}
+	doc, err = info.ToDocument()
+	require.NoError(t, err)
+
+	res = TableInfo{}
+	err = res.ScanDocument(doc)
 	require.NoError(t, err)
+	require.Equal(t, info.ForeignKeys, res.ForeignKeys)
+}
+
+var errUnencodable = errors.New("cannot encode expression")
+
+// unencodableExpr is a table expression that cannot be encoded.
+type unencodableExpr struct{}
+
+func (unencodableExpr) Eval(tx *Transaction) (document.Value, error) {
+	return document.NewNullValue(), nil
+}
+
+func (unencodableExpr) ToDocument() (document.Document, error) {
+	return nil, errUnencodable
 }
 
 func TestTableInfoStore(t *testing.T) {
@@ -80,6 +118,32 @@ func TestTableInfoStore(t *testing.T) {
 		}
 	})
 
+	t.Run("invalid default value", func(t *testing.T) {
+		ng := memoryengine.NewEngine()
+		defer ng.Close()
+
+		db, err := New(ng, Options{Codec: msgpack.NewCodec()})
+		require.NoError(t, err)
+		defer db.Close()
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		info := &TableInfo{
+			FieldConstraints: []FieldConstraint{
+				{Path: newValuePath("k"), DefaultValue: unencodableExpr{}},
+			},
+		}
+
+		// The encoding error of the default value should be returned.
+		err = tx.tableInfoStore.Insert(tx, "foo", info)
+		require.Equal(t, errUnencodable, err)
+
+		_, err = tx.tableInfoStore.Get(tx, "foo")
+		require.True(t, errors.Is(err, ErrTableNotFound), err)
+	})
+
 	t.Run("on rollback", func(t *testing.T) {
 		ng := memoryengine.NewEngine()
 		defer ng.Close()
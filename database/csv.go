@@ -0,0 +1,225 @@
+package database
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/genjidb/genji/document"
+)
+
+// CSVError is returned when a record of a CSV input cannot be loaded.
+type CSVError struct {
+	// Line of the input on which the record starts.
+	Line int
+	Err  error
+}
+
+func (e *CSVError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *CSVError) Unwrap() error {
+	return e.Err
+}
+
+// LoadCSV reads CSV records from r, as defined by RFC 4180, and inserts
+// each of them in the table. The first record is the header and contains the name
+// of the field associated with each column.
+// Cells are converted to the type of their field if it is declared in the table,
+// otherwise the type is inferred: integer, double, bool or text, in that order.
+// Empty cells are ignored and do not create a field.
+// It returns the number of inserted documents and stops at the first error,
+// which is returned as a *CSVError.
+func (t *Table) LoadCSV(r io.Reader) (int, error) {
+	info, err := t.Info()
+	if err != nil {
+		return 0, err
+	}
+
+	cr := newCSVReader(r)
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, cr.error(err)
+	}
+
+	fields := make([]string, len(header))
+	types := make([]document.ValueType, len(header))
+	for i, name := range header {
+		if name == "" {
+			return 0, cr.error(fmt.Errorf("empty field name in column %d", i+1))
+		}
+		fields[i] = name
+		types[i] = declaredType(info, name)
+	}
+
+	var n int
+	var fb document.FieldBuffer
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, cr.error(err)
+		}
+
+		fb.Reset()
+		for i, cell := range record {
+			if cell == "" {
+				continue
+			}
+
+			v, err := parseCSVCell(cell, types[i])
+			if err != nil {
+				return n, cr.error(fmt.Errorf("field %q: %w", fields[i], err))
+			}
+
+			fb.Add(fields[i], v)
+		}
+
+		_, err = t.Insert(&fb)
+		if err != nil {
+			return n, cr.error(err)
+		}
+		n++
+	}
+}
+
+// LoadCSV loads the CSV records read from r in the given table, like Table.LoadCSV.
+func (tx *Transaction) LoadCSV(tableName string, r io.Reader) (int, error) {
+	t, err := tx.GetTable(tableName)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.LoadCSV(r)
+}
+
+// declaredType returns the type of the top-level field name,
+// as declared in the field constraints of the table, or zero if it's not typed.
+func declaredType(info *TableInfo, name string) document.ValueType {
+	for _, fc := range info.FieldConstraints {
+		if len(fc.Path) == 1 && fc.Path[0].FieldName == name {
+			return fc.Type
+		}
+	}
+
+	return 0
+}
+
+// parseCSVCell converts a cell to the given type,
+// or infers its type if tp is zero.
+func parseCSVCell(cell string, tp document.ValueType) (document.Value, error) {
+	v := document.NewTextValue(cell)
+	if tp != 0 {
+		return v.CastAs(tp)
+	}
+
+	if i, err := strconv.ParseInt(cell, 10, 64); err == nil {
+		return document.NewIntegerValue(i), nil
+	}
+	if f, err := strconv.ParseFloat(cell, 64); err == nil {
+		return document.NewDoubleValue(f), nil
+	}
+	if strings.EqualFold(cell, "true") || strings.EqualFold(cell, "false") {
+		return document.NewBoolValue(strings.EqualFold(cell, "true")), nil
+	}
+
+	return v, nil
+}
+
+// csvReader reads CSV records and keeps track of the line on which
+// the last record read starts.
+type csvReader struct {
+	*csv.Reader
+
+	br *bufio.Reader
+	lc *lineCounter
+	// number of lines consumed by the records read so far
+	lines int
+	// line on which the last record read starts
+	line int
+}
+
+func newCSVReader(r io.Reader) *csvReader {
+	lc := lineCounter{r: r}
+	// the CSV reader reads from br directly, which allows to know
+	// how many bytes it consumed by subtracting the buffered ones
+	br := bufio.NewReader(&lc)
+	cr := csvReader{Reader: csv.NewReader(br), br: br, lc: &lc}
+	cr.ReuseRecord = true
+
+	return &cr
+}
+
+// Read the next record and compute the line on which it starts.
+func (r *csvReader) Read() ([]string, error) {
+	record, err := r.Reader.Read()
+	if err != nil {
+		return record, err
+	}
+
+	consumed := r.lc.read - int64(r.br.Buffered())
+	var terminated bool
+	for len(r.lc.newlines) > 0 && r.lc.newlines[0] < consumed {
+		terminated = r.lc.newlines[0] == consumed-1
+		r.lc.newlines = r.lc.newlines[1:]
+		r.lines++
+	}
+
+	// the last line of the record is the last line consumed,
+	// unless the input doesn't end with a newline
+	last := r.lines
+	if !terminated {
+		last++
+	}
+
+	// quoted fields may contain newlines
+	r.line = last
+	for _, field := range record {
+		r.line -= strings.Count(field, "\n")
+	}
+
+	return record, nil
+}
+
+// error wraps err with the line of the last record read.
+func (r *csvReader) error(err error) error {
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		return &CSVError{Line: perr.StartLine, Err: perr.Err}
+	}
+
+	return &CSVError{Line: r.line, Err: err}
+}
+
+// lineCounter records the offsets of the newlines read from r.
+type lineCounter struct {
+	r io.Reader
+	// number of bytes read
+	read int64
+	// offsets of the newlines that were read but not counted yet
+	newlines []int64
+}
+
+func (l *lineCounter) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == '\n' {
+			l.newlines = append(l.newlines, l.read+int64(i))
+		}
+	}
+	l.read += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,121 @@
+package database_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func tableToJSON(t testing.TB, tb *database.Table) string {
+	var buf bytes.Buffer
+	err := document.IteratorToJSONArray(&buf, tb)
+	require.NoError(t, err)
+	return buf.String()
+}
+
+func TestTableLoadCSV(t *testing.T) {
+	t.Run("Schemaless", func(t *testing.T) {
+		tb, cleanup := newTestTable(t)
+		defer cleanup()
+
+		n, err := tb.LoadCSV(strings.NewReader(
+			"a,b,c,d\n" +
+				"1,1.5,true,hello\n" +
+				"-2,,FALSE,\"hello, \"\"world\"\"\"\n" +
+				"3,1e3,yes,\"multi\nline\"\n",
+		))
+		require.NoError(t, err)
+		require.Equal(t, 3, n)
+
+		require.JSONEq(t, `[
+			{"a": 1, "b": 1.5, "c": true, "d": "hello"},
+			{"a": -2, "c": false, "d": "hello, \"world\""},
+			{"a": 3, "b": 1000.0, "c": "yes", "d": "multi\nline"}
+		]`, tableToJSON(t, tb))
+	})
+
+	t.Run("Typed", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", &database.TableInfo{
+			FieldConstraints: []database.FieldConstraint{
+				{Path: parsePath(t, "a"), Type: document.TextValue},
+				{Path: parsePath(t, "b"), Type: document.DoubleValue},
+				{Path: parsePath(t, "c"), Type: document.BoolValue},
+			},
+		})
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		n, err := tb.LoadCSV(strings.NewReader("a,b,c,d\n10,2,1,20\n"))
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+
+		err = tb.Iterate(func(d document.Document) error {
+			var a string
+			var b float64
+			var c bool
+			var e int64
+			err := document.Scan(d, &a, &b, &c, &e)
+			require.NoError(t, err)
+			require.Equal(t, "10", a)
+			require.Equal(t, 2.0, b)
+			require.True(t, c)
+			require.Equal(t, int64(20), e)
+
+			v, err := d.GetByField("b")
+			require.NoError(t, err)
+			require.Equal(t, document.DoubleValue, v.Type)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			input  string
+			loaded int
+			line   int
+		}{
+			{"Invalid type", "a,b\n1,2\n\"x\ny\",foo\n", 1, 3},
+			{"Wrong number of fields", "a,b\n1,2\n3\n", 1, 3},
+			{"Bare quote", "a,b\n1,2\n3,4\n5,\"6\n", 2, 4},
+			{"Empty field name", "a,\n1,2\n", 0, 1},
+			{"After blank lines", "a,b\n\n1,2\n\n\n3,x\n", 1, 6},
+			{"After multiline fields", "a,b\n\"x\r\ny\",2\n\"z\n\nw\",3\n4,x", 2, 7},
+			{"Without final newline", "a,b\n1,x", 0, 2},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				tx, cleanup := newTestDB(t)
+				defer cleanup()
+
+				err := tx.CreateTable("test", &database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "b"), Type: document.IntegerValue},
+					},
+				})
+				require.NoError(t, err)
+				tb, err := tx.GetTable("test")
+				require.NoError(t, err)
+
+				n, err := tb.LoadCSV(strings.NewReader(test.input))
+				require.Error(t, err)
+				require.Equal(t, test.loaded, n)
+
+				var cerr *database.CSVError
+				require.True(t, errors.As(err, &cerr))
+				require.Equal(t, test.line, cerr.Line)
+			})
+		}
+	})
+}
@@ -2,14 +2,22 @@
 package database
 
 import (
+	"crypto/cipher"
 	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
 	"github.com/genjidb/genji/engine"
 )
 
+// codecKey is the key under which the name of the codec is stored
+// in the meta store.
+const codecKey = "codec"
+
 // A Database manages a list of tables in an engine.
 type Database struct {
 	ng engine.Engine
@@ -22,6 +30,9 @@ type Database struct {
 	// incremented atomically every time Begin is called.
 	lastTransactionID int64
 
+	// This stores the last id used to name a temporary store.
+	lastTempStoreID int64
+
 	// If this is non-nil, the user is running an explicit transaction
 	// using the BEGIN statement.
 	// Only one attached transaction can be run at a time and any calls to DB.Begin()
@@ -31,12 +42,110 @@ type Database struct {
 
 	// Codec used to encode documents. Defaults to MessagePack.
 	Codec encoding.Codec
+
+	// If true, field names are matched regardless of their case
+	// when evaluating queries and checking constraints.
+	// Documents are always stored with the exact field names.
+	CaseInsensitiveFields bool
+
+	// Collation of the TEXT fields created without an explicit collation.
+	DefaultCollation document.Collation
+
+	// Logger receives the events emitted by the database.
+	// If nil, events are discarded.
+	Logger Logger
+
+	// Maximum number of bytes a statement can keep in memory
+	// while sorting, grouping or materializing documents. Sorts may use temporary
+	// stores instead, if the transaction is writable.
+	// If zero, the memory used by statements is not limited.
+	StatementMemoryLimit int64
+
+	// Maximum number of times a read-write transaction run by the Update method
+	// of the genji package is attempted when it conflicts with another transaction.
+	// If zero, it is attempted up to 5 times.
+	MaxUpdateAttempts int
+
+	// Number of documents read from the source of an INSERT ... SELECT statement
+	// before they are inserted. Only one batch is kept in memory at a time.
+	// If zero, documents are inserted by batches of 100.
+	InsertBatchSize int
+
+	// Visibility hides documents from table scans.
+	// If nil, all the documents are visible.
+	Visibility VisibilityFunc
+
+	// Maximum size in bytes of the keys of the index stores.
+	// Inserting a document with a value that is too long to be indexed
+	// fails with ErrIndexKeyTooLong. If zero, the size is not limited.
+	MaxIndexKeySize int
+
+	// Blobs stored in top-level fields that are larger than ExternalBlobMinSize bytes
+	// are split in chunks stored outside of their document, which only holds a reference to them.
+	// They are loaded when the field is read, or can be streamed using Table.BlobReader.
+	ExternalBlobMinSize int
+
+	// Maximum duration of a statement run by a query, if the query
+	// doesn't override it using query.Timeout. Statements running longer fail
+	// with context.DeadlineExceeded. If zero, statements are not limited.
+	StatementTimeout time.Duration
+
+	// fieldCipher encrypts the fields declared as ENCRYPTED.
+	// Nil if no encryption key was provided.
+	fieldCipher cipher.AEAD
+
+	// functions and aggregate functions registered by the user, by lower-cased name.
+	functions   map[string]Function
+	aggregates  map[string]AggregateFunction
+	functionsMu sync.RWMutex
+
+	// partition sets registered by the user, by name.
+	partitionSets   map[string][]string
+	partitionSetsMu sync.RWMutex
 }
 
 type Options struct {
-	Codec encoding.Codec
+	Codec                 encoding.Codec
+	CaseInsensitiveFields bool
+	// Compression of the documents stored in tables.
+	// Disabled by default. A database written with compression enabled
+	// must be opened with compression enabled.
+	Compression CompressionOptions
+	// Collation of the TEXT fields created without an explicit collation.
+	// Defaults to BinaryCollation.
+	DefaultCollation document.Collation
+	// Logger receives the events emitted by the database.
+	// Optional.
+	Logger Logger
+	// Maximum number of bytes a statement can keep in memory.
+	// Defaults to no limit.
+	StatementMemoryLimit int64
+	// Maximum number of attempts of a conflicting update.
+	// Defaults to 5.
+	MaxUpdateAttempts int
+	// Key used to encrypt the fields declared as ENCRYPTED, using AES-GCM.
+	// It must be 16, 24 or 32 bytes long.
+	// Optional, but required to read or write encrypted fields.
+	EncryptionKey []byte
+	// Number of documents inserted per batch by INSERT ... SELECT statements.
+	// Defaults to 100.
+	InsertBatchSize int
+	// Visibility hides documents from table scans.
+	// Optional.
+	Visibility VisibilityFunc
+	// Maximum size of the keys of the indexes, in bytes.
+	// Defaults to the maximum size of the keys of the engine, if any.
+	MaxIndexKeySize int
+	// Size in bytes above which top-level blobs are stored outside of their document.
+	// Defaults to DefaultExternalBlobMinSize.
+	ExternalBlobMinSize int
+	// Maximum duration of the statements.
+	// Defaults to no limit.
+	StatementTimeout time.Duration
 }
 
+var errCannotOpenTxWithinTx = errors.New("cannot open a transaction within a transaction")
+
 // New initializes the DB using the given engine.
 func New(ng engine.Engine, opts Options) (*Database, error) {
 	if opts.Codec == nil {
@@ -44,8 +153,34 @@ func New(ng engine.Engine, opts Options) (*Database, error) {
 	}
 
 	db := Database{
-		ng:    ng,
-		Codec: opts.Codec,
+		ng:                    ng,
+		Codec:                 newCompressionCodec(opts.Codec, opts.Compression),
+		CaseInsensitiveFields: opts.CaseInsensitiveFields,
+		DefaultCollation:      opts.DefaultCollation,
+		Logger:                opts.Logger,
+		StatementMemoryLimit:  opts.StatementMemoryLimit,
+		MaxUpdateAttempts:     opts.MaxUpdateAttempts,
+		InsertBatchSize:       opts.InsertBatchSize,
+		Visibility:            opts.Visibility,
+		MaxIndexKeySize:       opts.MaxIndexKeySize,
+		ExternalBlobMinSize:   opts.ExternalBlobMinSize,
+		StatementTimeout:      opts.StatementTimeout,
+	}
+
+	if db.ExternalBlobMinSize <= 0 {
+		db.ExternalBlobMinSize = DefaultExternalBlobMinSize
+	}
+
+	if l, ok := ng.(engine.KeySizeLimiter); ok && db.MaxIndexKeySize == 0 {
+		db.MaxIndexKeySize = l.MaxKeySize()
+	}
+
+	if opts.EncryptionKey != nil {
+		var err error
+		db.fieldCipher, err = newFieldCipher(opts.EncryptionKey)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	ntx, err := db.ng.Begin(true)
@@ -69,6 +204,11 @@ func New(ng engine.Engine, opts Options) (*Database, error) {
 		return nil, err
 	}
 
+	err = db.checkKeyFormat()
+	if err != nil {
+		return nil, err
+	}
+
 	return &db, nil
 }
 
@@ -85,7 +225,42 @@ func (db *Database) initInternalStores(tx engine.Transaction) error {
 	if err == engine.ErrStoreNotFound {
 		err = tx.CreateStore([]byte(indexStoreName))
 	}
-	return err
+	if err != nil {
+		return err
+	}
+
+	return db.checkCodec(tx)
+}
+
+// checkCodec ensures the database was encoded using the codec of db.
+// The name of the codec is stored upon creation of the database, or
+// the first time a database created without it is opened.
+func (db *Database) checkCodec(tx engine.Transaction) error {
+	st, err := tx.GetStore([]byte(metaStoreName))
+	if err == engine.ErrStoreNotFound {
+		err = tx.CreateStore([]byte(metaStoreName))
+		if err != nil {
+			return err
+		}
+		st, err = tx.GetStore([]byte(metaStoreName))
+	}
+	if err != nil {
+		return err
+	}
+
+	name, err := st.Get([]byte(codecKey))
+	if err == engine.ErrKeyNotFound {
+		return st.Put([]byte(codecKey), []byte(db.Codec.Name()))
+	}
+	if err != nil {
+		return err
+	}
+
+	if string(name) != db.Codec.Name() {
+		return fmt.Errorf("%w: database encoded with %q, opened with %q", ErrCodecMismatch, name, db.Codec.Name())
+	}
+
+	return nil
 }
 
 // Close the underlying engine.
@@ -110,13 +285,12 @@ func (db *Database) BeginTx(opts *TxOptions) (*Transaction, error) {
 		opts = new(TxOptions)
 	}
 
-	db.attachedTxMu.Lock()
-	defer db.attachedTxMu.Unlock()
-
-	if db.attachedTransaction != nil {
-		return nil, errors.New("cannot open a transaction within a transaction")
+	if db.GetAttachedTx() != nil {
+		return nil, errCannotOpenTxWithinTx
 	}
 
+	// The engine may block until other transactions are closed,
+	// which requires the lock to be released.
 	ntx, err := db.ng.Begin(!opts.ReadOnly)
 	if err != nil {
 		return nil, err
@@ -127,18 +301,32 @@ func (db *Database) BeginTx(opts *TxOptions) (*Transaction, error) {
 		db:             db,
 		tx:             ntx,
 		writable:       !opts.ReadOnly,
+		startTime:      time.Now(),
 		tableInfoStore: db.tableInfoStore,
 	}
 
 	tx.indexStore, err = tx.getIndexStore()
 	if err != nil {
+		ntx.Rollback()
 		return nil, err
 	}
 
 	if opts.Attached {
+		db.attachedTxMu.Lock()
+		defer db.attachedTxMu.Unlock()
+
+		// another transaction might have been attached
+		// while waiting for the engine.
+		if db.attachedTransaction != nil {
+			ntx.Rollback()
+			return nil, errCannotOpenTxWithinTx
+		}
+
 		db.attachedTransaction = &tx
 	}
 
+	db.Log(Event{Kind: EventTxBegin, TxID: tx.id, Writable: tx.writable})
+
 	return &tx, nil
 }
 
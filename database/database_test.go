@@ -0,0 +1,576 @@
+package database_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding"
+	"github.com/genjidb/genji/document/encoding/custom"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/genjidb/genji/key"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseCodec(t *testing.T) {
+	codecs := []encoding.Codec{msgpack.NewCodec(), custom.NewCodec()}
+
+	for _, codec := range codecs {
+		t.Run(codec.Name(), func(t *testing.T) {
+			ng := memoryengine.NewEngine()
+
+			db, err := database.New(ng, database.Options{Codec: codec})
+			require.NoError(t, err)
+
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+
+			err = tx.CreateTable("test", nil)
+			require.NoError(t, err)
+
+			tb, err := tx.GetTable("test")
+			require.NoError(t, err)
+
+			doc := document.NewFieldBuffer().
+				Add("a", document.NewIntegerValue(10)).
+				Add("b", document.NewTextValue("foo"))
+
+			key, err := tb.Insert(doc)
+			require.NoError(t, err)
+
+			err = tx.Commit()
+			require.NoError(t, err)
+
+			// reopening the database with the same codec must decode the document
+			db, err = database.New(ng, database.Options{Codec: codec})
+			require.NoError(t, err)
+
+			tx, err = db.Begin(false)
+			require.NoError(t, err)
+
+			tb, err = tx.GetTable("test")
+			require.NoError(t, err)
+
+			d, err := tb.GetDocument(key)
+			require.NoError(t, err)
+
+			got, err := document.MarshalJSON(d)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"a": 10, "b": "foo"}`, string(got))
+
+			err = tx.Rollback()
+			require.NoError(t, err)
+
+			// reopening the database with any other codec must fail
+			for _, other := range codecs {
+				if other.Name() == codec.Name() {
+					continue
+				}
+
+				_, err = database.New(ng, database.Options{Codec: other})
+				require.True(t, errors.Is(err, database.ErrCodecMismatch))
+			}
+		})
+	}
+}
+
+func TestDatabaseKeyFormat(t *testing.T) {
+	ng := memoryengine.NewEngine()
+
+	_, err := database.New(ng, database.Options{Codec: msgpack.NewCodec()})
+	require.NoError(t, err)
+
+	// the version of the keys is stored when the database is created
+	tx, err := ng.Begin(true)
+	require.NoError(t, err)
+	st, err := tx.GetStore([]byte("__genji_meta"))
+	require.NoError(t, err)
+	v, err := st.Get([]byte("key_format"))
+	require.NoError(t, err)
+	require.Equal(t, key.AppendUint64(nil, 2), v)
+
+	// databases written with a more recent encoding are refused
+	err = st.Put([]byte("key_format"), key.AppendUint64(nil, 3))
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	_, err = database.New(ng, database.Options{Codec: msgpack.NewCodec()})
+	require.True(t, errors.Is(err, database.ErrUnsupportedFormat), err)
+}
+
+func TestDatabaseKeyFormatMigration(t *testing.T) {
+	ng := memoryengine.NewEngine()
+	opts := database.Options{Codec: msgpack.NewCodec(), ExternalBlobMinSize: 10}
+	large := bytes.Repeat([]byte("a"), 100)
+
+	db, err := database.New(ng, opts)
+	require.NoError(t, err)
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	err = tx.CreateTable("test", &database.TableInfo{
+		FieldConstraints: []database.FieldConstraint{
+			{Path: parsePath(t, "id"), Type: document.TextValue, IsPrimaryKey: true, Collation: document.NoCaseCollation, ExplicitCollation: true},
+		},
+	})
+	require.NoError(t, err)
+	err = tx.CreateIndex(database.IndexConfig{IndexName: "idx_test_a", TableName: "test", Path: parsePath(t, "a")})
+	require.NoError(t, err)
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+	_, err = tb.Insert(document.NewFieldBuffer().
+		Add("id", document.NewTextValue("Alice")).
+		Add("a", document.NewIntegerValue(1)).
+		Add("b", document.NewBlobValue(large)))
+	require.NoError(t, err)
+
+	// version 1 encoded the primary key regardless of its collation
+	collatedKey, err := key.Append(nil, document.TextValue, "alice")
+	require.NoError(t, err)
+	binaryKey, err := key.Append(nil, document.TextValue, "Alice")
+	require.NoError(t, err)
+	v, err := tb.Store.Get(collatedKey)
+	require.NoError(t, err)
+	v = append([]byte(nil), v...)
+	require.NoError(t, tb.Store.Delete(collatedKey))
+	require.NoError(t, tb.Store.Put(binaryKey, v))
+
+	err = tx.ReIndex("idx_test_a")
+	require.NoError(t, err)
+	require.Equal(t, 1, countIndexedKeys(t, tx, "idx_test_a", binaryKey))
+
+	// the chunks of the external blobs are stored under the key of their document
+	info, err := tb.Info()
+	require.NoError(t, err)
+	infoDoc, err := info.ToDocument()
+	require.NoError(t, err)
+	storeName, err := infoDoc.GetByField("store_name")
+	require.NoError(t, err)
+	blobStoreName := append([]byte(nil), storeName.V.([]byte)...)
+	blobStoreName[0] = 'b'
+	blobKeyPrefix := func(k []byte) []byte {
+		var buf [binary.MaxVarintLen64]byte
+		n := binary.PutUvarint(buf[:], uint64(len(k)))
+		return append(buf[:n], k...)
+	}
+	require.NoError(t, tx.Commit())
+
+	etx, err := ng.Begin(true)
+	require.NoError(t, err)
+	blobs, err := etx.GetStore(blobStoreName)
+	require.NoError(t, err)
+	chunks := prefixedKeys(t, blobs, blobKeyPrefix(collatedKey))
+	require.NotEmpty(t, chunks)
+	for _, k := range chunks {
+		v, err := blobs.Get(k)
+		require.NoError(t, err)
+		v = append([]byte(nil), v...)
+		require.NoError(t, blobs.Delete(k))
+		k = append(blobKeyPrefix(binaryKey), k[len(blobKeyPrefix(collatedKey)):]...)
+		require.NoError(t, blobs.Put(k, v))
+	}
+	st, err := etx.GetStore([]byte("__genji_meta"))
+	require.NoError(t, err)
+	err = st.Put([]byte("key_format"), key.AppendUint64(nil, 1))
+	require.NoError(t, err)
+	require.NoError(t, etx.Commit())
+
+	// the primary key is encoded again when the database is opened
+	db, err = database.New(ng, opts)
+	require.NoError(t, err)
+
+	// the chunks of the external blobs are moved along with their document
+	etx, err = ng.Begin(false)
+	require.NoError(t, err)
+	blobs, err = etx.GetStore(blobStoreName)
+	require.NoError(t, err)
+	require.Len(t, prefixedKeys(t, blobs, blobKeyPrefix(collatedKey)), len(chunks))
+	require.Empty(t, prefixedKeys(t, blobs, blobKeyPrefix(binaryKey)))
+	require.NoError(t, etx.Rollback())
+
+	tx, err = db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	tb, err = tx.GetTable("test")
+	require.NoError(t, err)
+
+	_, err = tb.Store.Get(binaryKey)
+	require.Equal(t, engine.ErrKeyNotFound, err)
+	d, err := tb.GetDocument(collatedKey)
+	require.NoError(t, err)
+
+	b, err := d.GetByField("b")
+	require.NoError(t, err)
+	require.Equal(t, large, b.V.([]byte))
+
+	// the indexes of the table are rebuilt
+	require.Equal(t, 1, countIndexedKeys(t, tx, "idx_test_a", collatedKey))
+	require.Zero(t, countIndexedKeys(t, tx, "idx_test_a", binaryKey))
+
+	_, err = tb.Insert(document.NewFieldBuffer().Add("id", document.NewTextValue("ALICE")))
+	require.True(t, errors.Is(err, database.ErrDuplicateDocument), err)
+}
+
+// prefixedKeys returns the keys of st that start with prefix.
+func prefixedKeys(t *testing.T, st engine.Store, prefix []byte) [][]byte {
+	var keys [][]byte
+
+	it := st.NewIterator(engine.IteratorConfig{})
+	for it.Seek(prefix); it.Valid() && bytes.HasPrefix(it.Item().Key(), prefix); it.Next() {
+		keys = append(keys, append([]byte(nil), it.Item().Key()...))
+	}
+	require.NoError(t, it.Close())
+
+	return keys
+}
+
+func TestDatabaseCompression(t *testing.T) {
+	large := document.NewFieldBuffer().
+		Add("a", document.NewTextValue(strings.Repeat("genji", 200)))
+	small := document.NewFieldBuffer().
+		Add("a", document.NewTextValue("genji"))
+
+	encodedSize := func(t *testing.T, d document.Document) int {
+		var buf bytes.Buffer
+		err := msgpack.NewCodec().NewEncoder(&buf).EncodeDocument(d)
+		require.NoError(t, err)
+		return buf.Len()
+	}
+
+	tests := []struct {
+		name       string
+		opts       database.CompressionOptions
+		doc        document.Document
+		compressed bool
+	}{
+		{"disabled", database.CompressionOptions{}, large, false},
+		{"large", database.CompressionOptions{Enabled: true}, large, true},
+		{"small", database.CompressionOptions{Enabled: true}, small, false},
+		{"min size", database.CompressionOptions{Enabled: true, MinSize: 2000}, large, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ng := memoryengine.NewEngine()
+
+			codec := msgpack.NewCodec()
+			db, err := database.New(ng, database.Options{Codec: codec, Compression: test.opts})
+			require.NoError(t, err)
+			if !test.opts.Enabled {
+				// the codec is only wrapped if compression is enabled
+				require.Equal(t, codec, db.Codec)
+			}
+
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			err = tx.CreateTable("test", nil)
+			require.NoError(t, err)
+
+			tb, err := tx.GetTable("test")
+			require.NoError(t, err)
+
+			key, err := tb.Insert(test.doc)
+			require.NoError(t, err)
+
+			v, err := tb.Store.Get(key)
+			require.NoError(t, err)
+			if test.compressed {
+				require.Less(t, len(v), encodedSize(t, test.doc)/10)
+			} else {
+				require.Equal(t, encodedSize(t, test.doc), len(v))
+			}
+
+			expected, err := document.MarshalJSON(test.doc)
+			require.NoError(t, err)
+
+			d, err := tb.GetDocument(key)
+			require.NoError(t, err)
+			got, err := document.MarshalJSON(d)
+			require.NoError(t, err)
+			require.JSONEq(t, string(expected), string(got))
+
+			err = tb.Iterate(func(d document.Document) error {
+				got, err := document.MarshalJSON(d)
+				require.NoError(t, err)
+				require.JSONEq(t, string(expected), string(got))
+				return nil
+			})
+			require.NoError(t, err)
+
+			paths := []document.ValuePath{{document.ValuePathFragment{FieldName: "a"}}}
+			err = tb.IterateFields(paths, func(d document.Document) error {
+				require.Equal(t, key, d.(document.Keyer).Key())
+				got, err := document.MarshalJSON(d)
+				require.NoError(t, err)
+				require.JSONEq(t, string(expected), string(got))
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("Reopen without compression", func(t *testing.T) {
+		ng := memoryengine.NewEngine()
+
+		db, err := database.New(ng, database.Options{Codec: msgpack.NewCodec(), Compression: database.CompressionOptions{Enabled: true}})
+		require.NoError(t, err)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+
+		err = tx.CreateTable("test", nil)
+		require.NoError(t, err)
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		key, err := tb.Insert(large)
+		require.NoError(t, err)
+
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		// compressed documents are only decompressed if compression is enabled
+		db, err = database.New(ng, database.Options{Codec: msgpack.NewCodec()})
+		require.NoError(t, err)
+
+		tx, err = db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		tb, err = tx.GetTable("test")
+		require.NoError(t, err)
+
+		d, err := tb.GetDocument(key)
+		if err == nil {
+			_, err = d.GetByField("a")
+		}
+		require.Error(t, err)
+		require.NoError(t, tx.Rollback())
+
+		// a different minimum size doesn't prevent reading the documents
+		db, err = database.New(ng, database.Options{Codec: msgpack.NewCodec(), Compression: database.CompressionOptions{Enabled: true, MinSize: 2000}})
+		require.NoError(t, err)
+
+		tx, err = db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		tb, err = tx.GetTable("test")
+		require.NoError(t, err)
+
+		d, err = tb.GetDocument(key)
+		require.NoError(t, err)
+
+		v, err := d.GetByField("a")
+		require.NoError(t, err)
+		require.Equal(t, strings.Repeat("genji", 200), v.V)
+	})
+}
+
+func TestDatabaseEncryption(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+
+	path := func(fields ...string) document.ValuePath {
+		var p document.ValuePath
+		for _, f := range fields {
+			p = append(p, document.ValuePathFragment{FieldName: f})
+		}
+		return p
+	}
+
+	newDB := func(t *testing.T, ng *memoryengine.Engine, key []byte) *database.Database {
+		db, err := database.New(ng, database.Options{Codec: msgpack.NewCodec(), EncryptionKey: key})
+		require.NoError(t, err)
+		return db
+	}
+
+	doc, err := document.NewFromJSON([]byte(`{
+		"name": "alice",
+		"ssn": "123-45-6789",
+		"card": {"number": "4111111111111111", "exp": "12/30"},
+		"secret": [10, 20.5, "sesame"],
+		"nothing": null
+	}`))
+	require.NoError(t, err)
+	expected, err := document.MarshalJSON(doc)
+	require.NoError(t, err)
+
+	// createTable inserts doc in a table with encrypted fields
+	// and returns its key.
+	createTable := func(t *testing.T, db *database.Database) []byte {
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.CreateTable("test", &database.TableInfo{
+			FieldConstraints: []database.FieldConstraint{
+				{Path: path("ssn"), Type: document.TextValue, IsEncrypted: true},
+				{Path: path("card", "number"), IsEncrypted: true},
+				{Path: path("secret"), IsEncrypted: true},
+				{Path: path("nothing"), IsEncrypted: true},
+			},
+		})
+		require.NoError(t, err)
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		k, err := tb.Insert(doc)
+		require.NoError(t, err)
+
+		err = tx.Commit()
+		require.NoError(t, err)
+		return k
+	}
+
+	t.Run("Stored values are encrypted", func(t *testing.T) {
+		ng := memoryengine.NewEngine()
+		db := newDB(t, ng, key)
+		k := createTable(t, db)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		v, err := tb.Store.Get(k)
+		require.NoError(t, err)
+		require.True(t, bytes.Contains(v, []byte("alice")))
+		require.True(t, bytes.Contains(v, []byte("12/30")))
+		for _, plain := range []string{"123-45-6789", "4111111111111111", "sesame"} {
+			require.False(t, bytes.Contains(v, []byte(plain)), "%q is stored in clear", plain)
+		}
+
+		// the same value is encrypted differently every time
+		k2, err := tb.Insert(doc)
+		require.NoError(t, err)
+		d1, err := db.Codec.NewDocument(v).GetByField("ssn")
+		require.NoError(t, err)
+		v2, err := tb.Store.Get(k2)
+		require.NoError(t, err)
+		d2, err := db.Codec.NewDocument(v2).GetByField("ssn")
+		require.NoError(t, err)
+		require.Equal(t, document.BlobValue, d1.Type)
+		require.NotEqual(t, d1.V, d2.V)
+	})
+
+	t.Run("Reads return the original values", func(t *testing.T) {
+		ng := memoryengine.NewEngine()
+		db := newDB(t, ng, key)
+		k := createTable(t, db)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		d, err := tb.GetDocument(k)
+		require.NoError(t, err)
+		require.Equal(t, k, d.(document.Keyer).Key())
+		got, err := document.MarshalJSON(d)
+		require.NoError(t, err)
+		require.JSONEq(t, string(expected), string(got))
+
+		err = tb.Iterate(func(d document.Document) error {
+			require.Equal(t, k, d.(document.Keyer).Key())
+			got, err := document.MarshalJSON(d)
+			require.NoError(t, err)
+			require.JSONEq(t, string(expected), string(got))
+			return nil
+		})
+		require.NoError(t, err)
+
+		err = tb.IterateFields([]document.ValuePath{path("ssn")}, func(d document.Document) error {
+			v, err := d.GetByField("ssn")
+			require.NoError(t, err)
+			require.Equal(t, document.NewTextValue("123-45-6789"), v)
+			return nil
+		})
+		require.NoError(t, err)
+
+		// replaced documents are encrypted as well
+		fb, err := document.NewFromJSON([]byte(`{"ssn": "987-65-4321", "card": {"number": "5500000000000004"}}`))
+		require.NoError(t, err)
+		err = tb.Replace(k, fb)
+		require.NoError(t, err)
+
+		v, err := tb.Store.Get(k)
+		require.NoError(t, err)
+		require.False(t, bytes.Contains(v, []byte("987-65-4321")))
+		require.False(t, bytes.Contains(v, []byte("5500000000000004")))
+
+		d, err = tb.GetDocument(k)
+		require.NoError(t, err)
+		ssn, err := d.GetByField("ssn")
+		require.NoError(t, err)
+		require.Equal(t, document.NewTextValue("987-65-4321"), ssn)
+	})
+
+	t.Run("Wrong or missing key", func(t *testing.T) {
+		ng := memoryengine.NewEngine()
+		k := createTable(t, newDB(t, ng, key))
+
+		for _, key := range [][]byte{nil, bytes.Repeat([]byte{2}, 32)} {
+			tx, err := newDB(t, ng, key).Begin(true)
+			require.NoError(t, err)
+
+			tb, err := tx.GetTable("test")
+			require.NoError(t, err)
+
+			d, err := tb.GetDocument(k)
+			require.NoError(t, err)
+			_, err = d.GetByField("name")
+			require.Error(t, err)
+
+			if key == nil {
+				_, err = tb.Insert(doc)
+				require.Error(t, err)
+			}
+
+			err = tx.Rollback()
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		_, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec(), EncryptionKey: []byte("short")})
+		require.Error(t, err)
+	})
+
+	t.Run("Encrypted fields can't be indexed", func(t *testing.T) {
+		db := newDB(t, memoryengine.NewEngine(), key)
+		createTable(t, db)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		for _, p := range []document.ValuePath{path("ssn"), path("card", "number"), path("secret", "a")} {
+			err = tx.CreateIndex(database.IndexConfig{IndexName: "idx", TableName: "test", Path: p})
+			require.Error(t, err)
+		}
+
+		err = tx.CreateIndex(database.IndexConfig{IndexName: "idx", TableName: "test", Path: path("card", "exp")})
+		require.NoError(t, err)
+
+		err = tx.CreateTable("pk", &database.TableInfo{
+			FieldConstraints: []database.FieldConstraint{
+				{Path: path("id"), IsPrimaryKey: true, IsEncrypted: true},
+			},
+		})
+		require.Error(t, err)
+	})
+}
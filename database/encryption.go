@@ -0,0 +1,233 @@
+package database
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding"
+)
+
+// Encrypted values are stored as blobs starting with encryptedValueFlag,
+// followed by the nonce and the sealed value.
+// The flag allows to change the format of encrypted values in the future.
+const encryptedValueFlag byte = 0x01
+
+// The sealed value is the encoded document {"v": value}, which allows
+// to decode a value of any type with the codec of the database.
+const encryptedValueField = "v"
+
+var errNoEncryptionKey = errors.New("field is encrypted but the database has no encryption key")
+
+// newFieldCipher returns the AES-GCM cipher used to encrypt the fields
+// declared as ENCRYPTED. The key must be 16, 24 or 32 bytes long
+// to select AES-128, AES-192 or AES-256.
+func newFieldCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// encryptedPaths returns the paths of the fields declared as encrypted.
+func (ti *TableInfo) encryptedPaths() []document.ValuePath {
+	var paths []document.ValuePath
+
+	for _, fc := range ti.FieldConstraints {
+		if fc.IsEncrypted {
+			paths = append(paths, fc.Path)
+		}
+	}
+
+	return paths
+}
+
+// isEncrypted returns whether the field at path p is encrypted
+// or is nested in an encrypted field.
+func (ti *TableInfo) isEncrypted(p document.ValuePath) bool {
+	for _, ep := range ti.encryptedPaths() {
+		if len(ep) <= len(p) && ep.IsEqual(p[:len(ep)]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// encryptFields returns a copy of d in which the values of the encrypted fields
+// are replaced by their encrypted version. Null values are not encrypted.
+func (t *Table) encryptFields(d document.Document, paths []document.ValuePath) (document.Document, error) {
+	if len(paths) == 0 {
+		return d, nil
+	}
+
+	aead := t.tx.db.fieldCipher
+	if aead == nil {
+		return nil, errNoEncryptionKey
+	}
+
+	var fb document.FieldBuffer
+	err := fb.Copy(d)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range paths {
+		stored := p
+		if t.tx.db.CaseInsensitiveFields {
+			stored = p.ResolveFold(&fb)
+		}
+
+		v, err := stored.GetValue(&fb)
+		if err == document.ErrFieldNotFound || v.Type == document.NullValue {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		v, err = encryptValue(aead, t.tx.db.Codec, p, v)
+		if err != nil {
+			return nil, err
+		}
+
+		err = fb.Set(stored, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &fb, nil
+}
+
+func encryptValue(aead cipher.AEAD, codec encoding.Codec, p document.ValuePath, v document.Value) (document.Value, error) {
+	var buf bytes.Buffer
+	err := codec.NewEncoder(&buf).EncodeDocument(document.NewFieldBuffer().Add(encryptedValueField, v))
+	if err != nil {
+		return document.Value{}, err
+	}
+
+	data := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+buf.Len()+aead.Overhead())
+	data[0] = encryptedValueFlag
+	nonce := data[1:]
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return document.Value{}, err
+	}
+
+	// the path is authenticated with the value, so that an encrypted value
+	// can't be moved to another field.
+	data = aead.Seal(data, nonce, buf.Bytes(), []byte(p.String()))
+	return document.NewBlobValue(data), nil
+}
+
+func decryptValue(aead cipher.AEAD, codec encoding.Codec, p document.ValuePath, v document.Value) (document.Value, error) {
+	var data []byte
+	if v.Type == document.BlobValue {
+		data = v.V.([]byte)
+	}
+	if len(data) < 1+aead.NonceSize() || data[0] != encryptedValueFlag {
+		return document.Value{}, fmt.Errorf("invalid encrypted value for field %q", p)
+	}
+
+	nonce := data[1 : 1+aead.NonceSize()]
+	plain, err := aead.Open(nil, nonce, data[1+aead.NonceSize():], []byte(p.String()))
+	if err != nil {
+		return document.Value{}, fmt.Errorf("cannot decrypt field %q: %w", p, err)
+	}
+
+	return codec.NewDocument(plain).GetByField(encryptedValueField)
+}
+
+// decryptedDocument decrypts the encrypted fields of a stored document
+// the first time it is read.
+type decryptedDocument struct {
+	document.Document
+
+	db    *Database
+	paths []document.ValuePath
+
+	fb        document.FieldBuffer
+	decrypted bool
+}
+
+func (d *decryptedDocument) GetByField(field string) (document.Value, error) {
+	err := d.decrypt()
+	if err != nil {
+		return document.Value{}, err
+	}
+
+	return d.fb.GetByField(field)
+}
+
+func (d *decryptedDocument) Iterate(fn func(field string, value document.Value) error) error {
+	err := d.decrypt()
+	if err != nil {
+		return err
+	}
+
+	return d.fb.Iterate(fn)
+}
+
+func (d *decryptedDocument) Key() []byte {
+	if k, ok := d.Document.(document.Keyer); ok {
+		return k.Key()
+	}
+
+	return nil
+}
+
+func (d *decryptedDocument) Reset() {
+	d.decrypted = false
+}
+
+func (d *decryptedDocument) decrypt() error {
+	if d.decrypted {
+		return nil
+	}
+
+	aead := d.db.fieldCipher
+	if aead == nil {
+		return errNoEncryptionKey
+	}
+
+	d.fb.Reset()
+	err := d.fb.Copy(d.Document)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range d.paths {
+		stored := p
+		if d.db.CaseInsensitiveFields {
+			stored = p.ResolveFold(&d.fb)
+		}
+
+		v, err := stored.GetValue(&d.fb)
+		if err == document.ErrFieldNotFound || v.Type == document.NullValue {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		v, err = decryptValue(aead, d.db.Codec, p, v)
+		if err != nil {
+			return err
+		}
+
+		err = d.fb.Set(stored, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	d.decrypted = true
+	return nil
+}
@@ -2,6 +2,8 @@ package database
 
 import (
 	"errors"
+
+	"github.com/genjidb/genji/document"
 )
 
 var (
@@ -25,4 +27,65 @@ var (
 	// ErrDuplicateDocument is returned when another document is already associated with a given key, primary key,
 	// or if there is a unique index violation.
 	ErrDuplicateDocument = errors.New("duplicate document")
+
+	// ErrCodecMismatch is returned when opening a database with a codec
+	// different from the one used to encode its documents.
+	ErrCodecMismatch = errors.New("codec mismatch")
+
+	// ErrUnsupportedFormat is returned when opening a database whose keys were
+	// encoded by a more recent version of the package.
+	ErrUnsupportedFormat = errors.New("unsupported format")
+
+	// ErrResultTooLarge is returned when a statement needs to keep more data
+	// in memory than allowed by the StatementMemoryLimit option of the database.
+	ErrResultTooLarge = errors.New("result too large")
+
+	// ErrIndexKeyTooLong is returned when a value is too long to be indexed,
+	// because it exceeds the MaxIndexKeySize option of the database.
+	ErrIndexKeyTooLong = errors.New("index key too long")
+
+	// ErrForeignKeyViolation is returned when a document references a document
+	// that doesn't exist, or when deleting a document that is still referenced.
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+
+	// ErrNotNullViolation is returned when a required field is missing or null.
+	ErrNotNullViolation = errors.New("not null violation")
+
+	// ErrTypeMismatch is returned when the value of a typed field cannot be
+	// converted to the type of the field.
+	ErrTypeMismatch = errors.New("type mismatch")
+
+	// ErrUniqueViolation is returned when the value of a primary key, or of a field
+	// indexed by a unique index, is already associated with another document.
+	// Errors matching ErrUniqueViolation also match ErrDuplicateDocument.
+	ErrUniqueViolation = errors.New("unique violation")
 )
+
+// ConstraintError is returned when a document doesn't satisfy the constraint
+// associated with one of its fields.
+// The kind of constraint that is not satisfied can be checked using errors.Is
+// with ErrNotNullViolation, ErrTypeMismatch, ErrUniqueViolation or ErrForeignKeyViolation.
+type ConstraintError struct {
+	// Path of the field whose constraint is not satisfied.
+	Path document.ValuePath
+	// Value of the field. Its type is zero if the field is missing.
+	Value document.Value
+	// Violation is the kind of constraint that is not satisfied,
+	// or nil if the error is not caused by one of the kinds listed above.
+	Violation error
+	Err       error
+}
+
+func (e *ConstraintError) Error() string {
+	return e.Err.Error()
+}
+
+// Is reports whether target is the kind of constraint that is not satisfied.
+func (e *ConstraintError) Is(target error) bool {
+	return e.Violation != nil && target == e.Violation
+}
+
+// Unwrap returns the underlying error.
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,267 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/genjidb/genji/document"
+)
+
+// ForeignKeyAction is the action taken on the referencing documents
+// when the document they reference is deleted.
+type ForeignKeyAction int
+
+const (
+	// ForeignKeyRestrict prevents the deletion of a referenced document.
+	ForeignKeyRestrict ForeignKeyAction = iota
+	// ForeignKeyCascade deletes the referencing documents along with the referenced document.
+	ForeignKeyCascade
+)
+
+// ForeignKey describes a field whose values must be the primary key
+// of a document of the referenced table.
+type ForeignKey struct {
+	Path            document.ValuePath
+	ReferencedTable string
+	// ReferencedPath is the path of the primary key of the referenced table.
+	// If nil when the table is created, it is set to the primary key of the referenced table.
+	ReferencedPath document.ValuePath
+	OnDelete       ForeignKeyAction
+}
+
+// ToDocument returns a document from fk.
+func (fk *ForeignKey) ToDocument() document.Document {
+	buf := document.NewFieldBuffer()
+
+	buf.Add("path", document.NewArrayValue(valuePathToArray(fk.Path)))
+	buf.Add("referenced_table", document.NewTextValue(fk.ReferencedTable))
+	buf.Add("referenced_path", document.NewArrayValue(valuePathToArray(fk.ReferencedPath)))
+	buf.Add("on_delete", document.NewIntegerValue(int64(fk.OnDelete)))
+	return buf
+}
+
+// ScanDocument implements the document.Scanner interface.
+func (fk *ForeignKey) ScanDocument(d document.Document) error {
+	v, err := d.GetByField("path")
+	if err != nil {
+		return err
+	}
+	fk.Path, err = arrayToValuePath(v)
+	if err != nil {
+		return err
+	}
+
+	v, err = d.GetByField("referenced_table")
+	if err != nil {
+		return err
+	}
+	fk.ReferencedTable = v.V.(string)
+
+	v, err = d.GetByField("referenced_path")
+	if err != nil {
+		return err
+	}
+	fk.ReferencedPath, err = arrayToValuePath(v)
+	if err != nil {
+		return err
+	}
+
+	v, err = d.GetByField("on_delete")
+	if err != nil {
+		return err
+	}
+	fk.OnDelete = ForeignKeyAction(v.V.(int64))
+
+	return nil
+}
+
+// validateForeignKeys checks that the tables referenced by the foreign keys of the table
+// exist and sets the referenced paths to their primary key.
+// A table can reference itself.
+func (tx *Transaction) validateForeignKeys(tableName string, info *TableInfo) error {
+	for i, fk := range info.ForeignKeys {
+		refInfo := info
+		if fk.ReferencedTable != tableName {
+			var err error
+			refInfo, err = tx.tableInfoStore.Get(tx, fk.ReferencedTable)
+			if err != nil {
+				return err
+			}
+		}
+
+		pk := refInfo.GetPrimaryKey()
+		if pk == nil {
+			return fmt.Errorf("foreign key %q must reference a primary key, table %q has none", fk.Path, fk.ReferencedTable)
+		}
+
+		if fk.ReferencedPath == nil {
+			info.ForeignKeys[i].ReferencedPath = pk.Path
+		} else if !fk.ReferencedPath.IsEqual(pk.Path) {
+			return fmt.Errorf("foreign key %q must reference the primary key of table %q", fk.Path, fk.ReferencedTable)
+		}
+	}
+
+	return nil
+}
+
+// referencingForeignKey is a foreign key of another table.
+type referencingForeignKey struct {
+	tableName string
+	ForeignKey
+}
+
+// referencingForeignKeys returns the foreign keys referencing the given table.
+func (tx *Transaction) referencingForeignKeys(tableName string) []referencingForeignKey {
+	var fks []referencingForeignKey
+
+	for name, info := range tx.tableInfoStore.GetTableInfo() {
+		if info.transactionID != 0 && info.transactionID != tx.id {
+			continue
+		}
+
+		for _, fk := range info.ForeignKeys {
+			if fk.ReferencedTable == tableName {
+				fks = append(fks, referencingForeignKey{tableName: name, ForeignKey: fk})
+			}
+		}
+	}
+
+	return fks
+}
+
+// isReferenced returns whether the table is referenced by the foreign keys of other tables.
+func (tx *Transaction) isReferenced(tableName string) bool {
+	for _, fk := range tx.referencingForeignKeys(tableName) {
+		if fk.tableName != tableName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkForeignKeys returns an error if the document stored at key references
+// a document that doesn't exist. Null and missing values don't reference any document.
+func (t *Table) checkForeignKeys(info *TableInfo, key []byte, d document.Document) error {
+	for _, fk := range info.ForeignKeys {
+		v, err := t.getValue(fk.Path, d)
+		if err == document.ErrFieldNotFound || v.Type == document.NullValue {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		ref, err := t.tx.GetTable(fk.ReferencedTable)
+		if err != nil {
+			return err
+		}
+
+		violation := &ConstraintError{
+			Path:      fk.Path,
+			Value:     v,
+			Violation: ErrForeignKeyViolation,
+			Err:       fmt.Errorf("%w: no document of table %q has %s = %s", ErrForeignKeyViolation, fk.ReferencedTable, fk.ReferencedPath, v),
+		}
+
+		refKey, err := ref.EncodeKey(v)
+		if err != nil {
+			return violation
+		}
+
+		// a document can reference itself
+		if ref.name == t.name && string(refKey) == string(key) {
+			continue
+		}
+
+		_, err = ref.Store.Get(refKey)
+		if err != nil {
+			return violation
+		}
+	}
+
+	return nil
+}
+
+// referencingKeys returns the keys of the documents of the table whose field
+// at fk.Path references the document of the referenced table stored at refKey.
+func (t *Table) referencingKeys(fk ForeignKey, ref *Table, refKey []byte) ([][]byte, error) {
+	var keys [][]byte
+
+	err := t.Iterate(func(d document.Document) error {
+		v, err := t.getValue(fk.Path, d)
+		if err == document.ErrFieldNotFound || v.Type == document.NullValue {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		k, err := ref.EncodeKey(v)
+		if err != nil || string(k) != string(refKey) {
+			return nil
+		}
+
+		keys = append(keys, append([]byte(nil), d.(document.Keyer).Key()...))
+		return nil
+	})
+
+	return keys, err
+}
+
+// deleteReferencingDocuments enforces the foreign keys referencing the document
+// of the table stored at key when it is deleted.
+// If restrict is true, it returns an error if the document is referenced by a foreign key
+// using ForeignKeyRestrict. Otherwise, it deletes the documents referencing it
+// through a foreign key using ForeignKeyCascade.
+// The referencing tables are scanned entirely.
+func (t *Table) deleteReferencingDocuments(key []byte, restrict bool) error {
+	for _, fk := range t.tx.referencingForeignKeys(t.name) {
+		if (fk.OnDelete == ForeignKeyRestrict) != restrict {
+			continue
+		}
+
+		child, err := t.tx.GetTable(fk.tableName)
+		if err != nil {
+			return err
+		}
+
+		keys, err := child.referencingKeys(fk.ForeignKey, t, key)
+		if err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			// a document referencing itself can be deleted
+			if child.name == t.name && string(k) == string(key) {
+				continue
+			}
+
+			if restrict {
+				d, err := child.GetDocument(k)
+				if err != nil {
+					return err
+				}
+				v, err := child.getValue(fk.Path, d)
+				if err != nil {
+					return err
+				}
+
+				return &ConstraintError{
+					Path:      fk.Path,
+					Value:     v,
+					Violation: ErrForeignKeyViolation,
+					Err:       fmt.Errorf("%w: document is referenced by table %q", ErrForeignKeyViolation, fk.tableName),
+				}
+			}
+
+			// the document may have been deleted by another cascade
+			err = child.Delete(k)
+			if err != nil && !errors.Is(err, ErrDocumentNotFound) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,285 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/key"
+)
+
+// keyFormatKey is the key under which the version of the encoding
+// of primary keys and index keys is stored in the meta store.
+const keyFormatKey = "key_format"
+
+// keyFormatVersion is the version of the encoding of the keys written by this package.
+// Databases without version were written before the encoding of numbers, texts
+// and documents changed: their primary keys and indexes are rebuilt when they are opened.
+// Version 2 encodes the text primary keys according to their collation: only the tables
+// whose primary key has a collation and their indexes are rebuilt when migrating from version 1.
+const keyFormatVersion = 2
+
+// checkKeyFormat ensures the primary keys and index keys of the database
+// use the current encoding. If the database records an older version of its keys,
+// or no version at all, they are encoded again from the documents and the version is stored.
+// Databases written with a more recent encoding are refused.
+// A writable transaction is only opened if the keys must be migrated.
+func (db *Database) checkKeyFormat() error {
+	version, ok, err := db.keyFormat()
+	if err != nil {
+		return err
+	}
+	if ok {
+		if version > keyFormatVersion {
+			return fmt.Errorf("%w: keys encoded with version %d, only version %d is supported", ErrUnsupportedFormat, version, keyFormatVersion)
+		}
+		if version == keyFormatVersion {
+			return nil
+		}
+	}
+
+	tx, err := db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// version 1 only differs from the current version by the encoding
+	// of the primary keys that have a collation
+	err = tx.rebuildKeys(ok && version == 1)
+	if err != nil {
+		return fmt.Errorf("cannot migrate the keys of the database: %w", err)
+	}
+
+	st, err := tx.tx.GetStore([]byte(metaStoreName))
+	if err != nil {
+		return err
+	}
+
+	err = st.Put([]byte(keyFormatKey), key.AppendUint64(nil, keyFormatVersion))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// keyFormat returns the version of the encoding of the keys recorded in the meta store.
+// It returns false if no version is recorded.
+func (db *Database) keyFormat() (uint64, bool, error) {
+	tx, err := db.Begin(false)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	st, err := tx.tx.GetStore([]byte(metaStoreName))
+	if err != nil {
+		return 0, false, err
+	}
+
+	v, err := st.Get([]byte(keyFormatKey))
+	if err == engine.ErrKeyNotFound {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	version, err := key.DecodeUint64(v)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return version, true, nil
+}
+
+// rebuildKeys encodes the primary keys of the tables again from their documents,
+// then rebuilds their indexes. If collatedOnly is true, only the tables whose
+// primary key has a collation are rebuilt, otherwise all the tables are.
+func (tx *Transaction) rebuildKeys(collatedOnly bool) error {
+	infos := tx.tableInfoStore.GetTableInfo()
+	names := make([]string, 0, len(infos))
+	for name := range infos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		info := infos[name]
+		pk := info.GetPrimaryKey()
+		if pk == nil {
+			continue
+		}
+		if collatedOnly && pk.Collation == document.BinaryCollation {
+			continue
+		}
+
+		err := tx.rebuildPrimaryKeys(name)
+		if err != nil {
+			return err
+		}
+
+		if collatedOnly {
+			t, err := tx.GetTable(name)
+			if err != nil {
+				return err
+			}
+
+			err = t.ReIndex()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if collatedOnly {
+		return nil
+	}
+
+	return tx.ReIndexAll()
+}
+
+// rebuildPrimaryKeys moves the documents of the table and their external blobs
+// to temporary stores, then stores them back under their primary key,
+// encoded with the current encoding.
+func (tx *Transaction) rebuildPrimaryKeys(tableName string) error {
+	t, err := tx.getStoredTable(tableName)
+	if err != nil {
+		return err
+	}
+
+	name, tmp, err := tx.CreateTempStore()
+	if err != nil {
+		return err
+	}
+	defer tx.DropTempStore(name)
+
+	err = copyStore(tmp, t.Store)
+	if err != nil {
+		return err
+	}
+
+	err = t.Store.Truncate()
+	if err != nil {
+		return err
+	}
+
+	// the chunks of the external blobs are stored under the key of their document
+	blobs, err := t.blobStore(false)
+	if err != nil {
+		return err
+	}
+	var tmpBlobs engine.Store
+	if blobs != nil {
+		var blobsName []byte
+		blobsName, tmpBlobs, err = tx.CreateTempStore()
+		if err != nil {
+			return err
+		}
+		defer tx.DropTempStore(blobsName)
+
+		err = copyStore(tmpBlobs, blobs)
+		if err != nil {
+			return err
+		}
+
+		err = blobs.Truncate()
+		if err != nil {
+			return err
+		}
+	}
+
+	it := tmp.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	var v []byte
+	for it.Seek(nil); it.Valid(); it.Next() {
+		v, err = it.Item().ValueCopy(v[:0])
+		if err != nil {
+			return err
+		}
+
+		k, err := t.generateKey(tx.db.Codec.NewDocument(v))
+		if err != nil {
+			return err
+		}
+
+		// keys that were different may now be equal, for example if they only
+		// differed by the type of a number or by the case of a NOCASE text
+		_, err = t.Store.Get(k)
+		if err == nil {
+			return fmt.Errorf("table %q: %w", tableName, ErrDuplicateDocument)
+		}
+		if err != engine.ErrKeyNotFound {
+			return err
+		}
+
+		err = t.Store.Put(k, append([]byte(nil), v...))
+		if err != nil {
+			return err
+		}
+
+		if tmpBlobs != nil {
+			err = moveBlobs(blobs, tmpBlobs, it.Item().Key(), k)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// moveBlobs copies the chunks stored in src under the document key oldKey
+// to dst, under the document key newKey.
+func moveBlobs(dst, src engine.Store, oldKey, newKey []byte) error {
+	oldPrefix := blobKeyPrefix(oldKey)
+	newPrefix := blobKeyPrefix(newKey)
+
+	it := src.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	var v []byte
+	var err error
+	for it.Seek(oldPrefix); it.Valid() && bytes.HasPrefix(it.Item().Key(), oldPrefix); it.Next() {
+		item := it.Item()
+		v, err = item.ValueCopy(v[:0])
+		if err != nil {
+			return err
+		}
+
+		k := append(append([]byte(nil), newPrefix...), item.Key()[len(oldPrefix):]...)
+		err = dst.Put(k, append([]byte(nil), v...))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyStore copies all the key-value pairs of src to dst.
+func copyStore(dst, src engine.Store) error {
+	it := src.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	var v []byte
+	var err error
+	for it.Seek(nil); it.Valid(); it.Next() {
+		item := it.Item()
+		v, err = item.ValueCopy(v[:0])
+		if err != nil {
+			return err
+		}
+
+		err = dst.Put(append([]byte(nil), item.Key()...), append([]byte(nil), v...))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
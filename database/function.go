@@ -0,0 +1,79 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/genjidb/genji/document"
+)
+
+// A Function is a function registered by the user that can be called by SQL queries.
+// It receives the values of the arguments passed by the query and must check
+// their number and types itself.
+type Function func(args ...document.Value) (document.Value, error)
+
+// An Aggregate computes a value from the documents of a group, for an aggregate
+// function registered by the user.
+type Aggregate interface {
+	// Init is called before the documents of the group are added.
+	Init()
+	// Step is called for every document of the group with the values of the arguments
+	// passed by the query, evaluated against the document.
+	Step(args ...document.Value) error
+	// Result returns the value computed from the documents of the group.
+	// Without GROUP BY, the documents of the query form a single group,
+	// whose result is returned even if no document was added.
+	Result() (document.Value, error)
+}
+
+// An AggregateFunction is an aggregate function registered by the user.
+// It returns a new Aggregate for every group of documents.
+type AggregateFunction func() Aggregate
+
+// RegisterFunction registers fn under the given name, which is case-insensitive.
+// Registering a function under the name of another one replaces it.
+func (db *Database) RegisterFunction(name string, fn Function) {
+	db.functionsMu.Lock()
+	defer db.functionsMu.Unlock()
+
+	if db.functions == nil {
+		db.functions = make(map[string]Function)
+	}
+
+	name = strings.ToLower(name)
+	delete(db.aggregates, name)
+	db.functions[name] = fn
+}
+
+// GetFunction returns the function registered under the given name.
+func (db *Database) GetFunction(name string) (Function, bool) {
+	db.functionsMu.RLock()
+	defer db.functionsMu.RUnlock()
+
+	fn, ok := db.functions[strings.ToLower(name)]
+	return fn, ok
+}
+
+// RegisterAggregate registers the aggregate function fn under the given name,
+// which is case-insensitive.
+// Registering a function under the name of another one replaces it.
+func (db *Database) RegisterAggregate(name string, fn AggregateFunction) {
+	db.functionsMu.Lock()
+	defer db.functionsMu.Unlock()
+
+	if db.aggregates == nil {
+		db.aggregates = make(map[string]AggregateFunction)
+	}
+
+	name = strings.ToLower(name)
+	delete(db.functions, name)
+	db.aggregates[name] = fn
+}
+
+// GetAggregate returns the aggregate function registered under the given name.
+func (db *Database) GetAggregate(name string) (AggregateFunction, bool) {
+	db.functionsMu.RLock()
+	defer db.functionsMu.RUnlock()
+
+	fn, ok := db.aggregates[strings.ToLower(name)]
+	return fn, ok
+}
@@ -0,0 +1,75 @@
+package database
+
+import "fmt"
+
+// A Logger receives the events emitted by the database while running
+// queries and transactions. It is meant for debugging and must not block.
+type Logger interface {
+	Log(e Event)
+}
+
+// An EventKind describes the operation reported by an event.
+type EventKind uint8
+
+// List of events emitted by the database.
+const (
+	// EventTxBegin is emitted when a transaction is started.
+	EventTxBegin EventKind = iota + 1
+	// EventTxCommit is emitted when a transaction is committed.
+	EventTxCommit
+	// EventTxRollback is emitted when a transaction is rolled back.
+	EventTxRollback
+	// EventQueryParsed is emitted when a query is parsed.
+	EventQueryParsed
+	// EventPlanChosen is emitted when the plan of a statement has been optimized.
+	EventPlanChosen
+	// EventIndexUsed is emitted when an index is read by a statement.
+	EventIndexUsed
+	// EventRowsScanned is emitted once a table or an index has been read,
+	// with the number of documents read.
+	EventRowsScanned
+)
+
+var eventKindNames = map[EventKind]string{
+	EventTxBegin:     "tx begin",
+	EventTxCommit:    "tx commit",
+	EventTxRollback:  "tx rollback",
+	EventQueryParsed: "query parsed",
+	EventPlanChosen:  "plan chosen",
+	EventIndexUsed:   "index used",
+	EventRowsScanned: "rows scanned",
+}
+
+func (k EventKind) String() string {
+	if name, ok := eventKindNames[k]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("EventKind(%d)", k)
+}
+
+// An Event describes an operation of the database.
+// Only the fields relevant to its kind are set.
+type Event struct {
+	Kind EventKind
+	// ID of the transaction in which the operation happened, if any.
+	TxID int64
+	// Set for EventTxBegin.
+	Writable bool
+	// Set for EventQueryParsed.
+	Query string
+	// Set for EventPlanChosen.
+	Plan string
+	// Set for EventIndexUsed and EventRowsScanned.
+	TableName string
+	IndexName string
+	// Set for EventRowsScanned.
+	Rows int
+}
+
+// Log sends e to the logger of the database, if any.
+func (db *Database) Log(e Event) {
+	if db.Logger != nil {
+		db.Logger.Log(e)
+	}
+}
@@ -0,0 +1,302 @@
+package database
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+// maxPartitions is the maximum number of tables of a partition set.
+// The index of its partition is appended to the key of every document of the set on two bytes.
+const maxPartitions = math.MaxUint16 + 1
+
+// RegisterPartitionSet registers a read-only table made of the documents of the given tables,
+// or partitions, which are returned one partition after the other.
+// The partitions don't need to exist when the set is registered, but they must exist
+// and declare the same field constraints, in the same order, when the set is read.
+// Registering a partition set under the name of another one replaces it.
+func (db *Database) RegisterPartitionSet(name string, partitions ...string) error {
+	if name == "" || len(partitions) == 0 {
+		return errors.New("missing partition set name or partitions")
+	}
+	if strings.HasPrefix(name, internalPrefix) {
+		return fmt.Errorf("table name must not start with %s", internalPrefix)
+	}
+	if _, ok := virtualTables[name]; ok {
+		return fmt.Errorf("table name %q is reserved", name)
+	}
+	if info, ok := db.tableInfoStore.GetTableInfo()[name]; ok && info.transactionID == 0 {
+		return fmt.Errorf("%w: %q", ErrTableAlreadyExists, name)
+	}
+	if len(partitions) > maxPartitions {
+		return fmt.Errorf("a partition set cannot contain more than %d tables", maxPartitions)
+	}
+	for _, p := range partitions {
+		if p == name {
+			return fmt.Errorf("partition set %q cannot contain itself", name)
+		}
+	}
+
+	db.partitionSetsMu.Lock()
+	defer db.partitionSetsMu.Unlock()
+
+	if db.partitionSets == nil {
+		db.partitionSets = make(map[string][]string)
+	}
+
+	db.partitionSets[name] = append([]string(nil), partitions...)
+	return nil
+}
+
+// getPartitionSet returns the partitions of the partition set registered under the given name.
+func (db *Database) getPartitionSet(name string) ([]string, bool) {
+	db.partitionSetsMu.RLock()
+	defer db.partitionSetsMu.RUnlock()
+
+	partitions, ok := db.partitionSets[name]
+	return partitions, ok
+}
+
+// getPartitionSetTable returns the read-only table reading the documents of the given partitions.
+// The documents keep the keys of their partition, followed by the index of the partition.
+func (tx *Transaction) getPartitionSetTable(name string, partitions []string) (*Table, error) {
+	tables := make([]*Table, len(partitions))
+	stores := make([]engine.Store, len(partitions))
+	var info *TableInfo
+
+	for i, p := range partitions {
+		t, err := tx.getStoredTable(p)
+		if err != nil {
+			return nil, err
+		}
+
+		ti, err := t.Info()
+		if err != nil {
+			return nil, err
+		}
+		if info == nil {
+			info = ti
+		} else if !sameSchema(info, ti) {
+			return nil, fmt.Errorf("partition %q of %q doesn't have the same schema as %q", p, name, partitions[0])
+		}
+
+		tables[i] = t
+		stores[i] = t.Store
+	}
+
+	return &Table{
+		tx:    tx,
+		Store: &partitionStore{stores: stores},
+		name:  name,
+		info: &TableInfo{
+			tableName:        name,
+			readOnly:         true,
+			FieldConstraints: info.FieldConstraints,
+			Strict:           info.Strict,
+		},
+		partitions: tables,
+	}, nil
+}
+
+// sameSchema reports whether the tables declare the same field constraints.
+// Default values are ignored since they are only used when inserting documents.
+func sameSchema(a, b *TableInfo) bool {
+	if a.Strict != b.Strict || len(a.FieldConstraints) != len(b.FieldConstraints) {
+		return false
+	}
+
+	for i := range a.FieldConstraints {
+		fa, fb := a.FieldConstraints[i], b.FieldConstraints[i]
+		if !fa.Path.IsEqual(fb.Path) || fa.Type != fb.Type || fa.IsPrimaryKey != fb.IsPrimaryKey ||
+			fa.IsNotNull != fb.IsNotNull || fa.Collation != fb.Collation {
+			return false
+		}
+	}
+
+	return true
+}
+
+// appendPartitionKey appends the key of a document of the partition i to buf.
+func appendPartitionKey(buf []byte, i int, key []byte) []byte {
+	buf = append(buf, key...)
+	return append(buf, byte(i>>8), byte(i))
+}
+
+// splitPartitionKey returns the index of the partition and the key of the document
+// within its partition. It returns false if k is not the key of one of the n partitions of a set.
+func splitPartitionKey(k []byte, n int) (int, []byte, bool) {
+	if len(k) < 2 {
+		return 0, nil, false
+	}
+
+	i := int(binary.BigEndian.Uint16(k[len(k)-2:]))
+	if i >= n {
+		return 0, nil, false
+	}
+
+	return i, k[:len(k)-2], true
+}
+
+// iteratePartitions calls iterate for each partition, in order, starting with the partition
+// of the pivot if any. The documents passed to fn are given the key of the partition set.
+func (t *Table) iteratePartitions(pivot []byte, reverse bool, iterate func(p *Table, pivot []byte, fn func(d document.Document) error) error, fn func(d document.Document) error) error {
+	i, inner := 0, []byte(nil)
+	if reverse {
+		i = len(t.partitions) - 1
+	}
+	if j, k, ok := splitPartitionKey(pivot, len(t.partitions)); ok {
+		i, inner = j, k
+	}
+
+	var d encodedDocumentWithKey
+	for ; i >= 0 && i < len(t.partitions); inner = nil {
+		err := iterate(t.partitions[i], inner, func(doc document.Document) error {
+			d.Document = doc
+			d.key = appendPartitionKey(d.key[:0], i, doc.(document.Keyer).Key())
+			return fn(&d)
+		})
+		if err != nil {
+			return err
+		}
+
+		if reverse {
+			i--
+		} else {
+			i++
+		}
+	}
+
+	return nil
+}
+
+// getPartitionDocument returns the document of the partition set stored under key.
+func (t *Table) getPartitionDocument(key []byte) (document.Document, error) {
+	i, k, ok := splitPartitionKey(key, len(t.partitions))
+	if !ok {
+		return nil, ErrDocumentNotFound
+	}
+
+	d, err := t.partitions[i].GetDocument(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encodedDocumentWithKey{Document: d, key: key}, nil
+}
+
+// partitionStore is a read-only engine.Store made of the stores of the partitions of a set.
+// Its keys are the keys of the partitions followed by the index of their partition,
+// and are ordered by partition, then by key.
+type partitionStore struct {
+	stores []engine.Store
+}
+
+func (s *partitionStore) Get(k []byte) ([]byte, error) {
+	i, k, ok := splitPartitionKey(k, len(s.stores))
+	if !ok {
+		return nil, engine.ErrKeyNotFound
+	}
+
+	return s.stores[i].Get(k)
+}
+
+func (s *partitionStore) Put(k, v []byte) error         { return errVirtualStoreReadOnly }
+func (s *partitionStore) Delete(k []byte) error         { return errVirtualStoreReadOnly }
+func (s *partitionStore) Truncate() error               { return errVirtualStoreReadOnly }
+func (s *partitionStore) NextSequence() (uint64, error) { return 0, errVirtualStoreReadOnly }
+func (s *partitionStore) NewIterator(cfg engine.IteratorConfig) engine.Iterator {
+	return &partitionIterator{store: s, cfg: cfg}
+}
+
+// partitionIterator iterates over the stores of the partitions one after the other.
+type partitionIterator struct {
+	store *partitionStore
+	cfg   engine.IteratorConfig
+	// index of the current partition and iterator over its store.
+	i    int
+	it   engine.Iterator
+	item partitionItem
+}
+
+func (it *partitionIterator) Seek(k []byte) {
+	it.Close()
+
+	i, inner, ok := splitPartitionKey(k, len(it.store.stores))
+	switch {
+	case ok:
+	case it.cfg.Reverse:
+		i = len(it.store.stores) - 1
+	default:
+		i = 0
+	}
+
+	it.open(i, inner)
+}
+
+// open starts iterating over the partition i from k, or over the following partitions
+// if it doesn't contain any key after k.
+func (it *partitionIterator) open(i int, k []byte) {
+	for it.i = i; it.i >= 0 && it.i < len(it.store.stores); k = nil {
+		it.it = it.store.stores[it.i].NewIterator(it.cfg)
+		it.it.Seek(k)
+		if it.it.Valid() {
+			return
+		}
+
+		it.Close()
+		if it.cfg.Reverse {
+			it.i--
+		} else {
+			it.i++
+		}
+	}
+}
+
+func (it *partitionIterator) Next() {
+	it.it.Next()
+	if it.it.Valid() {
+		return
+	}
+
+	it.Close()
+	if it.cfg.Reverse {
+		it.open(it.i-1, nil)
+	} else {
+		it.open(it.i+1, nil)
+	}
+}
+
+func (it *partitionIterator) Valid() bool {
+	return it.it != nil && it.it.Valid()
+}
+
+func (it *partitionIterator) Item() engine.Item {
+	item := it.it.Item()
+	it.item.Item = item
+	it.item.key = appendPartitionKey(it.item.key[:0], it.i, item.Key())
+	return &it.item
+}
+
+func (it *partitionIterator) Close() error {
+	if it.it == nil {
+		return nil
+	}
+
+	err := it.it.Close()
+	it.it = nil
+	return err
+}
+
+// partitionItem is an item of a partition, with the key of the partition set.
+type partitionItem struct {
+	engine.Item
+
+	key []byte
+}
+
+func (i *partitionItem) Key() []byte { return i.key }
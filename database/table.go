@@ -5,6 +5,8 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
@@ -19,6 +21,10 @@ type Table struct {
 	Store     engine.Store
 	name      string
 	infoStore *tableInfoStore
+	// information of virtual tables, which are not stored in the infoStore.
+	info *TableInfo
+	// tables read by a partition set, in order.
+	partitions []*Table
 }
 
 // Tx returns the current transaction.
@@ -28,6 +34,10 @@ func (t *Table) Tx() *Transaction {
 
 // Info of the table.
 func (t *Table) Info() (*TableInfo, error) {
+	if t.info != nil {
+		return t.info, nil
+	}
+
 	return t.infoStore.Get(t.tx, t.name)
 }
 
@@ -36,9 +46,57 @@ func (t *Table) Name() string {
 	return t.name
 }
 
-// Truncate deletes all the documents from the table.
-func (t *Table) Truncate() error {
-	return t.Store.Truncate()
+// Truncate deletes all the documents from the table and the content of its indexes.
+// If restartIdentity is true, the sequence used to generate the keys of documents
+// inserted without primary key is restarted. Otherwise it continues from its current value.
+func (t *Table) Truncate(restartIdentity bool) error {
+	info, err := t.Info()
+	if err != nil {
+		return err
+	}
+
+	if info.readOnly {
+		return errors.New("cannot write to read-only table")
+	}
+
+	if t.tx.isReferenced(t.name) {
+		return fmt.Errorf("cannot truncate table %q: it is referenced by a foreign key", t.name)
+	}
+
+	indexes, err := t.Indexes()
+	if err != nil {
+		return err
+	}
+
+	for _, idx := range indexes {
+		err = idx.Truncate()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = t.tx.dropBlobStore(info)
+	if err != nil {
+		return err
+	}
+
+	if !restartIdentity {
+		return t.Store.Truncate()
+	}
+
+	// dropping the store also drops its sequence
+	err = t.tx.tx.DropStore(info.storeName)
+	if err != nil {
+		return err
+	}
+
+	err = t.tx.tx.CreateStore(info.storeName)
+	if err != nil {
+		return err
+	}
+
+	t.Store, err = t.tx.tx.GetStore(info.storeName)
+	return err
 }
 
 // Insert the document into the table.
@@ -46,6 +104,19 @@ func (t *Table) Truncate() error {
 // in the given document.
 // If no primary key has been selected, a monotonic autoincremented integer key will be generated.
 func (t *Table) Insert(d document.Document) ([]byte, error) {
+	keys, err := t.InsertBatch([]document.Document{d})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys[0], nil
+}
+
+// InsertBatch inserts the documents into the table like Insert and returns their keys.
+// The documents are stored first, then the entries of each index are written at once.
+// If a document cannot be inserted, the error is returned and the remaining
+// documents are not inserted.
+func (t *Table) InsertBatch(docs []document.Document) ([][]byte, error) {
 	info, err := t.Info()
 	if err != nil {
 		return nil, err
@@ -55,58 +126,119 @@ func (t *Table) Insert(d document.Document) ([]byte, error) {
 		return nil, errors.New("cannot write to read-only table")
 	}
 
-	d, err = t.ValidateConstraints(d)
+	indexes, err := t.Indexes()
 	if err != nil {
 		return nil, err
 	}
 
+	keys := make([][]byte, len(docs))
+	entries := make([][]index.Entry, len(indexes))
+	for i, d := range docs {
+		d, keys[i], err = t.store(info, d)
+		if err != nil {
+			return nil, err
+		}
+
+		for j, idx := range indexes {
+			v, err := t.getValue(idx.Opts.Path, d)
+			if err != nil {
+				v = document.NewNullValue()
+			}
+
+			entries[j] = append(entries[j], index.Entry{Value: v, Key: keys[i]})
+		}
+	}
+
+	for j, idx := range indexes {
+		err = idx.SetBatch(entries[j])
+		if err == index.ErrDuplicate {
+			return nil, duplicateEntryError(idx, entries[j])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+// duplicateEntryError returns the unique constraint violation reported when SetBatch
+// fails to index one of the entries because its value is already indexed.
+// The entries that precede it were indexed, the first one that wasn't is the duplicate.
+func duplicateEntryError(idx *Index, entries []index.Entry) error {
+	for _, e := range entries {
+		ok, err := idx.hasEntry(e.Value, e.Key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errUniqueViolation(idx.Opts.Path, e.Value)
+		}
+	}
+
+	return index.ErrDuplicate
+}
+
+// store validates d and stores it under its key, without indexing it.
+// It returns the validated document and its key.
+func (t *Table) store(info *TableInfo, d document.Document) (document.Document, []byte, error) {
+	d, err := t.ValidateConstraints(d)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	key, err := t.generateKey(d)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	_, err = t.Store.Get(key)
 	if err == nil {
-		return nil, ErrDuplicateDocument
+		pk := info.GetPrimaryKey()
+		if pk == nil {
+			return nil, nil, ErrDuplicateDocument
+		}
+
+		v, err := t.getValue(pk.Path, d)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return nil, nil, errUniqueViolation(pk.Path, v)
 	}
 
-	var buf bytes.Buffer
-	err = t.tx.db.Codec.NewEncoder(&buf).EncodeDocument(d)
+	err = t.checkForeignKeys(info, key, d)
 	if err != nil {
-		return nil, fmt.Errorf("failed to encode document: %w", err)
+		return nil, nil, err
 	}
 
-	err = t.Store.Put(key, buf.Bytes())
+	stored, err := t.encryptFields(d, info.encryptedPaths())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	indexes, err := t.Indexes()
+	stored, err = t.storeBlobs(key, stored, false)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(d)
-		if err != nil {
-			v = document.NewNullValue()
-		}
-
-		err = idx.Set(v, key)
-		if err != nil {
-			if err == index.ErrDuplicate {
-				return nil, ErrDuplicateDocument
-			}
+	var buf bytes.Buffer
+	err = t.tx.db.Codec.NewEncoder(&buf).EncodeDocument(stored)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode document: %w", err)
+	}
 
-			return nil, err
-		}
+	err = t.Store.Put(key, buf.Bytes())
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return key, nil
+	return d, key, nil
 }
 
 // Delete a document by key.
-// Indexes are automatically updated.
+// Indexes are automatically updated and the foreign keys referencing
+// the document are enforced.
 func (t *Table) Delete(key []byte) error {
 	info, err := t.Info()
 	if err != nil {
@@ -122,24 +254,64 @@ func (t *Table) Delete(key []byte) error {
 		return err
 	}
 
+	err = t.deleteReferencingDocuments(key, true)
+	if err != nil {
+		return err
+	}
+
 	indexes, err := t.Indexes()
 	if err != nil {
 		return err
 	}
 
-	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(d)
-		if err != nil {
-			return err
-		}
+	err = t.deleteFromIndexes(indexes, key, d)
+	if err != nil {
+		return err
+	}
 
-		err = idx.Delete(v, key)
-		if err != nil {
-			return err
+	err = t.Store.Delete(key)
+	if err != nil {
+		return err
+	}
+
+	err = t.deleteDocumentBlobs(key)
+	if err != nil {
+		return err
+	}
+
+	// cascading once the document is deleted ends cycles of references
+	return t.deleteReferencingDocuments(key, false)
+}
+
+// Lock acquires write intent on the document with the given key,
+// by storing it again unchanged. The transaction must be writable.
+// The guarantees depend on the engine:
+//   - bolt and memory engines only allow one writable transaction at a time:
+//     concurrent writers are blocked until the transaction is closed.
+//   - badger allows concurrent writable transactions: if another transaction
+//     updates the document, the transaction that commits last fails with engine.ErrConflict.
+//
+// Indexes are not modified.
+func (t *Table) Lock(key []byte) error {
+	info, err := t.Info()
+	if err != nil {
+		return err
+	}
+
+	if info.readOnly {
+		return errors.New("cannot write to read-only table")
+	}
+
+	v, err := t.Store.Get(key)
+	if err != nil {
+		if err == engine.ErrKeyNotFound {
+			return ErrDocumentNotFound
 		}
+		return fmt.Errorf("failed to fetch document %q: %w", key, err)
 	}
 
-	return t.Store.Delete(key)
+	// some engines only return values valid until the next write
+	return t.Store.Put(key, append([]byte(nil), v...))
 }
 
 // Replace a document by key.
@@ -160,6 +332,11 @@ func (t *Table) Replace(key []byte, d document.Document) error {
 		return err
 	}
 
+	err = t.checkForeignKeys(info, key, d)
+	if err != nil {
+		return err
+	}
+
 	indexes, err := t.Indexes()
 	if err != nil {
 		return err
@@ -168,7 +345,7 @@ func (t *Table) Replace(key []byte, d document.Document) error {
 	return t.replace(indexes, key, d)
 }
 
-func (t *Table) replace(indexes map[string]Index, key []byte, d document.Document) error {
+func (t *Table) replace(indexes []*Index, key []byte, d document.Document) error {
 	// make sure key exists
 	old, err := t.GetDocument(key)
 	if err != nil {
@@ -176,21 +353,29 @@ func (t *Table) replace(indexes map[string]Index, key []byte, d document.Documen
 	}
 
 	// remove key from indexes
-	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(old)
-		if err != nil {
-			return err
-		}
+	err = t.deleteFromIndexes(indexes, key, old)
+	if err != nil {
+		return err
+	}
 
-		err = idx.Delete(v, key)
-		if err != nil {
-			return err
-		}
+	info, err := t.Info()
+	if err != nil {
+		return err
+	}
+
+	stored, err := t.encryptFields(d, info.encryptedPaths())
+	if err != nil {
+		return err
+	}
+
+	stored, err = t.storeBlobs(key, stored, true)
+	if err != nil {
+		return err
 	}
 
 	// encode new document
 	var buf bytes.Buffer
-	err = t.tx.db.Codec.NewEncoder(&buf).EncodeDocument(d)
+	err = t.tx.db.Codec.NewEncoder(&buf).EncodeDocument(stored)
 	if err != nil {
 		return fmt.Errorf("failed to encode document: %w", err)
 	}
@@ -203,68 +388,47 @@ func (t *Table) replace(indexes map[string]Index, key []byte, d document.Documen
 
 	// update indexes
 	for _, idx := range indexes {
-		v, err := idx.Opts.Path.GetValue(d)
+		v, err := t.getValue(idx.Opts.Path, d)
 		if err != nil {
-			continue
+			v = document.NewNullValue()
 		}
 
 		err = idx.Set(v, key)
 		if err != nil {
+			if err == index.ErrDuplicate {
+				return errUniqueViolation(idx.Opts.Path, v)
+			}
+
 			return err
 		}
 	}
 
-	return err
+	return nil
 }
 
-// Indexes returns a map of all the indexes of a table.
-func (t *Table) Indexes() (map[string]Index, error) {
-	s, err := t.tx.tx.GetStore([]byte(indexStoreName))
-	if err != nil {
-		return nil, err
-	}
+// deleteFromIndexes removes the references to the given key from the indexes.
+// Documents that don't contain the indexed field are indexed as NULL.
+func (t *Table) deleteFromIndexes(indexes []*Index, key []byte, d document.Document) error {
+	for _, idx := range indexes {
+		v, err := t.getValue(idx.Opts.Path, d)
+		if err != nil {
+			v = document.NewNullValue()
+		}
 
-	tb := Table{
-		tx:    t.tx,
-		Store: s,
-		name:  indexStoreName,
+		err = idx.Delete(v, key)
+		if err != nil {
+			return err
+		}
 	}
 
-	indexes := make(map[string]Index)
-
-	err = document.NewStream(&tb).
-		Filter(func(d document.Document) (bool, error) {
-			v, err := d.GetByField("table_name")
-			if err != nil {
-				return false, err
-			}
-
-			return v.V.(string) == t.name, nil
-		}).
-		Iterate(func(d document.Document) error {
-			var opts IndexConfig
-			err := opts.ScanDocument(d)
-			if err != nil {
-				return err
-			}
-
-			idx := index.NewIndex(t.tx.tx, opts.IndexName, index.Options{
-				Unique: opts.Unique,
-				Type:   opts.Type,
-			})
-
-			indexes[opts.Path.String()] = Index{
-				Index: idx,
-				Opts:  opts,
-			}
-
-			return nil
-		})
-	if err != nil {
-		return nil, err
-	}
+	return nil
+}
 
-	return indexes, nil
+// Indexes returns the indexes of the table, ordered by name.
+// The list is cached by the transaction until an index is created or dropped
+// and must not be modified.
+func (t *Table) Indexes() ([]*Index, error) {
+	return t.tx.tableIndexes(t.name)
 }
 
 type encodedDocumentWithKey struct {
@@ -286,23 +450,24 @@ func (e encodedDocumentWithKey) Key() []byte {
 type lazilyDecodedDocument struct {
 	item  engine.Item
 	buf   []byte
+	doc   document.Document
 	codec encoding.Codec
 }
 
 func (d *lazilyDecodedDocument) GetByField(field string) (v document.Value, err error) {
-	if len(d.buf) == 0 {
+	if d.doc == nil {
 		d.copyFromItem()
 	}
 
-	return d.codec.NewDocument(d.buf).GetByField(field)
+	return d.doc.GetByField(field)
 }
 
 func (d *lazilyDecodedDocument) Iterate(fn func(field string, value document.Value) error) error {
-	if len(d.buf) == 0 {
+	if d.doc == nil {
 		d.copyFromItem()
 	}
 
-	return d.codec.NewDocument(d.buf).Iterate(fn)
+	return d.doc.Iterate(fn)
 }
 
 func (d *lazilyDecodedDocument) Key() []byte {
@@ -311,36 +476,289 @@ func (d *lazilyDecodedDocument) Key() []byte {
 
 func (d *lazilyDecodedDocument) Reset() {
 	d.buf = d.buf[:0]
+	d.doc = nil
 	d.item = nil
 }
 
 func (d *lazilyDecodedDocument) copyFromItem() error {
 	var err error
 	d.buf, err = d.item.ValueCopy(d.buf)
+	d.doc = d.codec.NewDocument(d.buf)
 
 	return err
 }
 
 // Iterate goes through all the documents of the table and calls the given function by passing each one of them.
+// Documents hidden by the Visibility function of the database are skipped.
 // If the given function returns an error, the iteration stops.
 func (t *Table) Iterate(fn func(d document.Document) error) error {
+	return t.AscendGreaterOrEqual(nil, fn)
+}
+
+// IterateInOrder goes through all the documents of the table in ascending order of primary key
+// and calls the given function by passing each one of them, like Iterate.
+// The keys of tables with a primary key are encoded so that they are stored in that order.
+// Documents of tables without a primary key are returned in the order of their generated
+// docid, which is the order in which they were inserted. Since docids are not stored in that
+// order, the keys of the table are read and sorted before any document is passed to fn.
+func (t *Table) IterateInOrder(fn func(d document.Document) error) error {
+	if t.partitions != nil {
+		return t.iteratePartitions(nil, false, func(p *Table, _ []byte, fn func(d document.Document) error) error {
+			return p.IterateInOrder(fn)
+		}, fn)
+	}
+
+	if t.infoStore == nil && t.info == nil {
+		return t.Iterate(fn)
+	}
+
+	info, err := t.Info()
+	if err != nil {
+		return err
+	}
+	if info.GetPrimaryKey() != nil {
+		return t.Iterate(fn)
+	}
+
+	type docidKey struct {
+		docid uint64
+		key   []byte
+	}
+
+	var keys []docidKey
+	it := t.Store.NewIterator(engine.IteratorConfig{})
+	for it.Seek(nil); it.Valid(); it.Next() {
+		k := append([]byte(nil), it.Item().Key()...)
+		docid, _ := binary.Uvarint(k)
+		keys = append(keys, docidKey{docid: docid, key: k})
+	}
+	err = it.Close()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].docid < keys[j].docid
+	})
+
+	for _, k := range keys {
+		d, err := t.GetDocument(k.key)
+		// the document may have been deleted by fn
+		if err == ErrDocumentNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		ok, err := t.isVisible(k.key, d)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		err = fn(d)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SizeEstimate returns the number of records of the table and an estimate of the
+// number of bytes used to store them, their external blobs and the entries of the table indexes.
+// The estimate is computed from the content of the underlying stores, which means
+// documents hidden by the Visibility function of the database are counted as well.
+func (t *Table) SizeEstimate() (records int64, size int64, err error) {
+	for _, p := range t.partitions {
+		r, n, err := p.SizeEstimate()
+		if err != nil {
+			return 0, 0, err
+		}
+		records += r
+		size += n
+	}
+	if t.partitions != nil {
+		return records, size, nil
+	}
+
+	records, size, err = engine.StoreSize(t.Store)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if t.infoStore == nil && t.info == nil {
+		return records, size, nil
+	}
+
+	st, err := t.blobStore(false)
+	if err != nil {
+		return 0, 0, err
+	}
+	if st != nil {
+		_, n, err := engine.StoreSize(st)
+		if err != nil {
+			return 0, 0, err
+		}
+		size += n
+	}
+
+	indexes, err := t.Indexes()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, idx := range indexes {
+		_, n, err := idx.Size()
+		if err != nil {
+			return 0, 0, err
+		}
+		size += n
+	}
+
+	return records, size, nil
+}
+
+// AscendGreaterOrEqual seeks for the pivot key and then goes through all the subsequent documents in increasing order
+// of key and calls the given function for each of them. If the pivot doesn't exist, the iteration starts at the
+// smallest key greater than the pivot.
+// If the given function returns an error, the iteration stops and returns that error.
+// If the pivot is empty, starts from the beginning.
+func (t *Table) AscendGreaterOrEqual(pivot []byte, fn func(d document.Document) error) error {
+	return t.iterate(pivot, false, fn)
+}
+
+// DescendLessOrEqual seeks for the pivot key and then goes through all the subsequent documents in decreasing order
+// of key and calls the given function for each of them. If the pivot doesn't exist, the iteration starts at the
+// greatest key smaller than the pivot.
+// If the given function returns an error, the iteration stops and returns that error.
+// If the pivot is empty, starts from the end.
+func (t *Table) DescendLessOrEqual(pivot []byte, fn func(d document.Document) error) error {
+	return t.iterate(pivot, true, fn)
+}
+
+func (t *Table) iterate(pivot []byte, reverse bool, fn func(d document.Document) error) error {
+	if t.partitions != nil {
+		return t.iteratePartitions(pivot, reverse, func(p *Table, pivot []byte, fn func(d document.Document) error) error {
+			return p.iterate(pivot, reverse, fn)
+		}, fn)
+	}
+
 	// To avoid unnecessary allocations, we create the struct once and reuse
 	// it during each iteration.
 	d := lazilyDecodedDocument{
 		codec: t.tx.db.Codec,
 	}
 
-	it := t.Store.NewIterator(engine.IteratorConfig{})
+	// d must be passed as pointer, not value,
+	// because passing a value to an interface
+	// requires an allocation, while it doesn't for a pointer.
+	var doc document.Document = &d
+
+	paths, err := t.encryptedPaths()
+	if err != nil {
+		return err
+	}
+	if t.infoStore != nil {
+		doc = &blobDocument{Document: doc, t: t}
+	}
+
+	var dd *decryptedDocument
+	if len(paths) > 0 {
+		dd = &decryptedDocument{Document: doc, db: t.tx.db, paths: paths}
+		doc = dd
+	}
+
+	if t.tx.db.CaseInsensitiveFields {
+		doc = document.NewCaseInsensitiveDocument(doc)
+	}
+
+	it := t.Store.NewIterator(engine.IteratorConfig{Reverse: reverse})
 	defer it.Close()
 
-	var err error
-	for it.Seek(nil); it.Valid(); it.Next() {
+	for it.Seek(pivot); it.Valid(); it.Next() {
 		d.Reset()
+		if dd != nil {
+			dd.Reset()
+		}
 		d.item = it.Item()
-		// d must be passed as pointer, not value,
-		// because passing a value to an interface
-		// requires an allocation, while it doesn't for a pointer.
-		err = fn(&d)
+
+		ok, err := t.isVisible(d.item.Key(), doc)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		err = fn(doc)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IterateFields goes through all the documents of the table like Iterate, but only decodes
+// the top-level fields targeted by the given paths if the codec supports it.
+// The other fields of the documents passed to fn may not be available.
+func (t *Table) IterateFields(fieldPaths []document.ValuePath, fn func(d document.Document) error) error {
+	if t.partitions != nil {
+		return t.iteratePartitions(nil, false, func(p *Table, _ []byte, fn func(d document.Document) error) error {
+			return p.IterateFields(fieldPaths, fn)
+		}, fn)
+	}
+
+	paths, err := t.encryptedPaths()
+	if err != nil {
+		return err
+	}
+
+	fd, ok := t.tx.db.Codec.(encoding.FieldsDecoder)
+	if !ok || t.tx.db.CaseInsensitiveFields || len(paths) > 0 {
+		return t.Iterate(fn)
+	}
+
+	it := t.Store.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	var d encodedDocumentWithKey
+	var doc document.Document = &d
+	if t.infoStore != nil {
+		doc = &blobDocument{Document: doc, t: t}
+	}
+
+	var buf []byte
+	for it.Seek(nil); it.Valid(); it.Next() {
+		item := it.Item()
+
+		buf, err = item.ValueCopy(buf[:0])
+		if err != nil {
+			return err
+		}
+
+		// the visibility of the document is checked before decoding its fields
+		if t.tx.db.Visibility != nil {
+			ok, err := t.isVisible(item.Key(), t.tx.db.Codec.NewDocument(buf))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		d.Document, err = fd.DecodeFields(buf, fieldPaths...)
+		if err != nil {
+			return err
+		}
+		d.key = item.Key()
+
+		err = fn(doc)
 		if err != nil {
 			return err
 		}
@@ -351,6 +769,10 @@ func (t *Table) Iterate(fn func(d document.Document) error) error {
 
 // GetDocument returns one document by key.
 func (t *Table) GetDocument(key []byte) (document.Document, error) {
+	if t.partitions != nil {
+		return t.getPartitionDocument(key)
+	}
+
 	v, err := t.Store.Get(key)
 	if err != nil {
 		if err == engine.ErrKeyNotFound {
@@ -362,13 +784,87 @@ func (t *Table) GetDocument(key []byte) (document.Document, error) {
 	var d encodedDocumentWithKey
 	d.Document = t.tx.db.Codec.NewDocument(v)
 	d.key = key
-	return &d, err
+
+	var doc document.Document = &d
+	if t.infoStore != nil {
+		doc = &blobDocument{Document: doc, t: t}
+	}
+
+	paths, err := t.encryptedPaths()
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) > 0 {
+		doc = &decryptedDocument{Document: doc, db: t.tx.db, paths: paths}
+	}
+
+	if t.tx.db.CaseInsensitiveFields {
+		return document.NewCaseInsensitiveDocument(doc), nil
+	}
+
+	return doc, nil
+}
+
+// encryptedPaths returns the paths of the encrypted fields of the table.
+// Internal tables don't have any.
+func (t *Table) encryptedPaths() ([]document.ValuePath, error) {
+	if t.infoStore == nil {
+		return nil, nil
+	}
+
+	info, err := t.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	return info.encryptedPaths(), nil
+}
+
+// EncodeKey encodes v the same way the primary key of a document is encoded
+// when it is inserted, which allows to fetch a document by primary key using GetDocument.
+// If the table doesn't have a primary key, v is expected to be a docid,
+// as returned by the pk() function.
+func (t *Table) EncodeKey(v document.Value) ([]byte, error) {
+	info, err := t.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	if pk := info.GetPrimaryKey(); pk != nil {
+		if pk.Type != 0 {
+			v, err = v.CastAs(pk.Type)
+			if err != nil {
+				return nil, err
+			}
+
+			v = pk.Collation.Collate(v)
+			return key.Append(nil, v.Type, v.V)
+		}
+
+		return key.KeyEncode(pk.Collation.Collate(v))
+	}
+
+	v, err = v.CastAsInteger()
+	if err != nil {
+		return nil, err
+	}
+
+	docid := v.V.(int64)
+	if docid < 0 {
+		return nil, fmt.Errorf("invalid docid %d", docid)
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, uint64(docid))
+	return buf[:n], nil
 }
 
 // generate a key for d based on the table configuration.
 // if the table has a primary key, it extracts the field from
 // the document, converts it to the targeted type and returns
-// its encoded version.
+// its encoded version. Text values are encoded according to
+// the collation of the primary key, like indexes do, so that
+// keys equal under the collation are duplicates.
 // if there are no primary key in the table, a default
 // key is generated, called the docid.
 func (t *Table) generateKey(d document.Document) ([]byte, error) {
@@ -378,13 +874,14 @@ func (t *Table) generateKey(d document.Document) ([]byte, error) {
 	}
 
 	if pk := ti.GetPrimaryKey(); pk != nil {
-		v, err := pk.Path.GetValue(d)
+		v, err := t.getValue(pk.Path, d)
 		if err == document.ErrFieldNotFound {
 			return nil, fmt.Errorf("missing primary key at path %q", pk.Path)
 		}
 		if err != nil {
 			return nil, err
 		}
+		v = pk.Collation.Collate(v)
 
 		// if a primary key type is specified,
 		// encode the key using the optimized encoding solution
@@ -394,7 +891,7 @@ func (t *Table) generateKey(d document.Document) ([]byte, error) {
 
 		// it no primary key type is specified,
 		// encode keys regardless of type.
-		return key.AppendValue(nil, v)
+		return key.KeyEncode(v)
 	}
 
 	docid, err := t.Store.NextSequence()
@@ -411,12 +908,21 @@ func (t *Table) generateKey(d document.Document) ([]byte, error) {
 // against them. If the types defined by the constraints are different than the ones found in
 // the document, the fields are converted to these types when possible. if the conversion
 // fails, an error is returned.
+// If the table is strict, an error is also returned if the document contains a top-level field
+// that is not declared by any of the constraints. Fields nested in a declared field are not checked.
 func (t *Table) ValidateConstraints(d document.Document) (document.Document, error) {
 	info, err := t.Info()
 	if err != nil {
 		return nil, err
 	}
 
+	if info.Strict {
+		err = t.validateDeclaredFields(d, info.FieldConstraints)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	pk := info.GetPrimaryKey()
 
 	if len(info.FieldConstraints) == 0 && pk == nil {
@@ -433,14 +939,14 @@ func (t *Table) ValidateConstraints(d document.Document) (document.Document, err
 	}
 
 	if pk != nil {
-		err = validateConstraint(&fb, pk)
+		err = t.validateConstraint(&fb, *pk)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	for _, fc := range info.FieldConstraints {
-		err := validateConstraint(&fb, &fc)
+		err := t.validateConstraint(&fb, fc)
 		if err != nil {
 			return nil, err
 		}
@@ -449,7 +955,82 @@ func (t *Table) ValidateConstraints(d document.Document) (document.Document, err
 	return &fb, err
 }
 
-func validateConstraint(d document.Document, c *FieldConstraint) error {
+// validateDeclaredFields returns an error if d contains a top-level field
+// that doesn't start the path of any of the constraints.
+func (t *Table) validateDeclaredFields(d document.Document, constraints []FieldConstraint) error {
+	return d.Iterate(func(field string, v document.Value) error {
+		for _, fc := range constraints {
+			name := fc.Path[0].FieldName
+			if name == field || (t.tx.db.CaseInsensitiveFields && strings.EqualFold(name, field)) {
+				return nil
+			}
+		}
+
+		return &ConstraintError{
+			Path:  document.ValuePath{document.ValuePathFragment{FieldName: field}},
+			Value: v,
+			Err:   fmt.Errorf("field %q is not declared in strict table %q", field, t.name),
+		}
+	})
+}
+
+func (t *Table) validateConstraint(fb *document.FieldBuffer, c FieldConstraint) error {
+	// the constraint must be applied to the field stored in the document,
+	// whose name may differ in case from the one of the constraint.
+	if t.tx.db.CaseInsensitiveFields {
+		c.Path = c.Path.ResolveFold(fb)
+	}
+
+	err := validateConstraint(t.tx, fb, &c)
+	if err == nil {
+		return nil
+	}
+
+	var cerr *ConstraintError
+	if errors.As(err, &cerr) {
+		return err
+	}
+
+	return &ConstraintError{Path: c.Path, Err: err}
+}
+
+// getValue returns the value of d at path p, ignoring the case of field names
+// if the database is configured to do so.
+func (t *Table) getValue(p document.ValuePath, d document.Document) (document.Value, error) {
+	if t.tx.db.CaseInsensitiveFields {
+		d = document.NewCaseInsensitiveDocument(d)
+	}
+
+	return p.GetValue(d)
+}
+
+// errRequired returns the error reported when the field of a not null
+// constraint is missing or null. v is the value of the field,
+// or an empty value if the field is missing.
+func errRequired(c *FieldConstraint, v document.Value) error {
+	err := &ConstraintError{Path: c.Path, Value: v, Violation: ErrNotNullViolation}
+	if c.IsPrimaryKey {
+		err.Err = fmt.Errorf("primary key %q is required and must be not null", c.Path)
+	} else {
+		err.Err = fmt.Errorf("field %q is required and must be not null", c.Path)
+	}
+
+	return err
+}
+
+// errUniqueViolation returns the error reported when the value of a primary key
+// or of a field indexed by a unique index is already associated with another document.
+func errUniqueViolation(path document.ValuePath, v document.Value) error {
+	return &ConstraintError{Path: path, Value: v, Violation: ErrUniqueViolation, Err: ErrDuplicateDocument}
+}
+
+// errTypeMismatch returns the error reported when the value of a typed field
+// cannot be converted to the type of the field.
+func errTypeMismatch(c *FieldConstraint, v document.Value, err error) error {
+	return &ConstraintError{Path: c.Path, Value: v, Violation: ErrTypeMismatch, Err: err}
+}
+
+func validateConstraint(tx *Transaction, d document.Document, c *FieldConstraint) error {
 	// get the parent buffer
 	parent, err := getParentValue(d, c.Path)
 	if err != nil {
@@ -466,27 +1047,38 @@ func validateConstraint(d document.Document, c *FieldConstraint) error {
 		if field.FieldName == "" {
 			// if the field is not found we make sure it is not required
 			if c.IsNotNull {
-				return fmt.Errorf("field %q is required and must be not null", c.Path)
+				return errRequired(c, document.Value{})
 			}
 			return nil
 		}
 
 		v, err := buf.GetByField(field.FieldName)
-		// if the field is not found we make sure it is not required
 		if err != nil {
-			if err == document.ErrFieldNotFound {
+			if err != document.ErrFieldNotFound {
+				return err
+			}
+
+			// if the field is not found and there is a default value,
+			// evaluate it and add it to the document
+			if c.DefaultValue == nil {
+				// if the field is not found we make sure it is not required
 				if c.IsNotNull {
-					return fmt.Errorf("field %q is required and must be not null", c.Path)
+					return errRequired(c, document.Value{})
 				}
 
 				return nil
 			}
 
-			return err
+			v, err = c.DefaultValue.Eval(tx)
+			if err != nil {
+				return err
+			}
+
+			buf.Add(field.FieldName, v)
 		}
 		// if the field is null we make sure it is not required
 		if v.Type == document.NullValue && c.IsNotNull {
-			return fmt.Errorf("field %q is required and must be not null", c.Path)
+			return errRequired(c, v)
 		}
 
 		// if not we convert it and replace it in the buffer
@@ -496,12 +1088,12 @@ func validateConstraint(d document.Document, c *FieldConstraint) error {
 			return nil
 		}
 
-		v, err = v.CastAs(c.Type)
+		cv, err := v.CastAs(c.Type)
 		if err != nil {
-			return err
+			return errTypeMismatch(c, v, err)
 		}
 
-		err = buf.Replace(field.FieldName, v)
+		err = buf.Replace(field.FieldName, cv)
 		if err != nil {
 			return err
 		}
@@ -521,7 +1113,11 @@ func validateConstraint(d document.Document, c *FieldConstraint) error {
 		if err != nil {
 			if err == document.ErrValueNotFound {
 				if c.IsNotNull {
-					return fmt.Errorf("value %q is required and must be not null", c.Path)
+					return &ConstraintError{
+						Path:      c.Path,
+						Violation: ErrNotNullViolation,
+						Err:       fmt.Errorf("value %q is required and must be not null", c.Path),
+					}
 				}
 
 				return nil
@@ -535,12 +1131,12 @@ func validateConstraint(d document.Document, c *FieldConstraint) error {
 			return nil
 		}
 
-		v, err = v.CastAs(c.Type)
+		cv, err := v.CastAs(c.Type)
 		if err != nil {
-			return err
+			return errTypeMismatch(c, v, err)
 		}
 
-		err = buf.Replace(frag.ArrayIndex, v)
+		err = buf.Replace(frag.ArrayIndex, cv)
 		if err != nil {
 			return err
 		}
@@ -586,3 +1182,29 @@ func (t *Table) ReIndex() error {
 
 	return nil
 }
+
+// Analyze recomputes the statistics of all the indexes of the table.
+func (t *Table) Analyze() error {
+	info, err := t.Info()
+	if err != nil {
+		return err
+	}
+
+	if info.readOnly {
+		return errors.New("cannot write to read-only table")
+	}
+
+	indexes, err := t.Indexes()
+	if err != nil {
+		return err
+	}
+
+	for _, idx := range indexes {
+		_, err = idx.Analyze()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -4,14 +4,20 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/boltengine"
 	"github.com/genjidb/genji/engine/memoryengine"
 	"github.com/genjidb/genji/key"
 	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/stretchr/testify/require"
 )
 
@@ -38,6 +44,21 @@ func newDocument() *document.FieldBuffer {
 		Add("fieldb", document.NewTextValue("b"))
 }
 
+func countIndexedKeys(t testing.TB, tx *database.Transaction, indexName string, key []byte) int {
+	idx, err := tx.GetIndex(indexName)
+	require.NoError(t, err)
+
+	var n int
+	err = idx.AscendGreaterOrEqual(document.Value{}, func(v, k []byte, isEqual bool) error {
+		if string(k) == string(key) {
+			n++
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return n
+}
+
 // TestTableIterate verifies Iterate behaviour.
 func TestTableIterate(t *testing.T) {
 	t.Run("Should not fail with no documents", func(t *testing.T) {
@@ -96,6 +117,316 @@ func TestTableIterate(t *testing.T) {
 	})
 }
 
+// TestTableIterateInOrder verifies IterateInOrder behaviour.
+func TestTableIterateInOrder(t *testing.T) {
+	collect := func(t *testing.T, tb *database.Table, field string) []document.Value {
+		var res []document.Value
+		err := tb.IterateInOrder(func(d document.Document) error {
+			v, err := d.GetByField(field)
+			if err != nil {
+				return err
+			}
+			res = append(res, v)
+			return nil
+		})
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("Should iterate in primary key order", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			typ      document.ValueType
+			values   []document.Value
+			expected []document.Value
+		}{
+			{"Integer", document.IntegerValue,
+				[]document.Value{document.NewIntegerValue(300), document.NewIntegerValue(-2), document.NewIntegerValue(5), document.NewIntegerValue(0)},
+				[]document.Value{document.NewIntegerValue(-2), document.NewIntegerValue(0), document.NewIntegerValue(5), document.NewIntegerValue(300)}},
+			{"Text", document.TextValue,
+				[]document.Value{document.NewTextValue("c"), document.NewTextValue("ab"), document.NewTextValue("b"), document.NewTextValue("a")},
+				[]document.Value{document.NewTextValue("a"), document.NewTextValue("ab"), document.NewTextValue("b"), document.NewTextValue("c")}},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				tx, cleanup := newTestDB(t)
+				defer cleanup()
+
+				err := tx.CreateTable("test", &database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "a"), Type: test.typ, IsPrimaryKey: true},
+					},
+				})
+				require.NoError(t, err)
+				tb, err := tx.GetTable("test")
+				require.NoError(t, err)
+
+				for _, v := range test.values {
+					_, err := tb.Insert(document.NewFieldBuffer().Add("a", v))
+					require.NoError(t, err)
+				}
+
+				require.Equal(t, test.expected, collect(t, tb, "a"))
+			})
+		}
+	})
+
+	t.Run("Should iterate in docid order without primary key", func(t *testing.T) {
+		tb, cleanup := newTestTable(t)
+		defer cleanup()
+
+		// docids greater than 127 are not stored in order
+		var keys [][]byte
+		for i := int64(0); i < 300; i++ {
+			k, err := tb.Insert(document.NewFieldBuffer().Add("i", document.NewIntegerValue(i)))
+			require.NoError(t, err)
+			keys = append(keys, k)
+		}
+		for i := 0; i < 300; i += 3 {
+			require.NoError(t, tb.Delete(keys[i]))
+		}
+
+		var expected []document.Value
+		for i := int64(0); i < 300; i++ {
+			if i%3 != 0 {
+				expected = append(expected, document.NewIntegerValue(i))
+			}
+		}
+
+		require.Equal(t, expected, collect(t, tb, "i"))
+	})
+
+	t.Run("Should stop if fn returns error", func(t *testing.T) {
+		tb, cleanup := newTestTable(t)
+		defer cleanup()
+
+		for i := 0; i < 10; i++ {
+			_, err := tb.Insert(newDocument())
+			require.NoError(t, err)
+		}
+
+		i := 0
+		err := tb.IterateInOrder(func(_ document.Document) error {
+			i++
+			if i >= 5 {
+				return errors.New("some error")
+			}
+			return nil
+		})
+		require.EqualError(t, err, "some error")
+		require.Equal(t, 5, i)
+	})
+}
+
+// TestTableAscendGreaterOrEqual verifies AscendGreaterOrEqual behaviour.
+func TestTableAscendGreaterOrEqual(t *testing.T) {
+	tx, cleanup := newTestDB(t)
+	defer cleanup()
+
+	err := tx.CreateTable("test", &database.TableInfo{
+		FieldConstraints: []database.FieldConstraint{
+			{Path: parsePath(t, "a"), Type: document.IntegerValue, IsPrimaryKey: true},
+		},
+	})
+	require.NoError(t, err)
+	tb, err := tx.GetTable("test")
+	require.NoError(t, err)
+
+	for i := int64(0); i < 10; i += 2 {
+		_, err := tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(i)))
+		require.NoError(t, err)
+	}
+
+	collect := func(pivot []byte) []int64 {
+		var res []int64
+		err := tb.AscendGreaterOrEqual(pivot, func(d document.Document) error {
+			v, err := d.GetByField("a")
+			if err != nil {
+				return err
+			}
+			res = append(res, v.V.(int64))
+			return nil
+		})
+		require.NoError(t, err)
+		return res
+	}
+
+	tests := []struct {
+		name     string
+		pivot    []byte
+		expected []int64
+	}{
+		{"Empty pivot", nil, []int64{0, 2, 4, 6, 8}},
+		{"Existing key", key.AppendInt64(nil, 4), []int64{4, 6, 8}},
+		{"Non-existing key", key.AppendInt64(nil, 5), []int64{6, 8}},
+		{"After last key", key.AppendInt64(nil, 10), nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, collect(test.pivot))
+		})
+	}
+}
+
+// TestTableDescendLessOrEqual verifies DescendLessOrEqual behaviour.
+func TestTableDescendLessOrEqual(t *testing.T) {
+	engines := []struct {
+		name    string
+		builder func(t *testing.T) (engine.Engine, func())
+	}{
+		{"memory", func(t *testing.T) (engine.Engine, func()) {
+			return memoryengine.NewEngine(), func() {}
+		}},
+		{"bolt", func(t *testing.T) (engine.Engine, func()) {
+			dir, err := ioutil.TempDir("", "genji")
+			require.NoError(t, err)
+
+			ng, err := boltengine.NewEngine(filepath.Join(dir, "test.db"), 0600, nil)
+			require.NoError(t, err)
+
+			return ng, func() {
+				ng.Close()
+				os.RemoveAll(dir)
+			}
+		}},
+	}
+
+	for _, e := range engines {
+		t.Run(e.name, func(t *testing.T) {
+			ng, cleanup := e.builder(t)
+			defer cleanup()
+
+			db, err := database.New(ng, database.Options{Codec: msgpack.NewCodec()})
+			require.NoError(t, err)
+
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			err = tx.CreateTable("test", &database.TableInfo{
+				FieldConstraints: []database.FieldConstraint{
+					{Path: parsePath(t, "a"), Type: document.IntegerValue, IsPrimaryKey: true},
+				},
+			})
+			require.NoError(t, err)
+			tb, err := tx.GetTable("test")
+			require.NoError(t, err)
+
+			for i := int64(0); i < 10; i += 2 {
+				_, err := tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(i)))
+				require.NoError(t, err)
+			}
+
+			collect := func(pivot []byte) []int64 {
+				var res []int64
+				err := tb.DescendLessOrEqual(pivot, func(d document.Document) error {
+					v, err := d.GetByField("a")
+					if err != nil {
+						return err
+					}
+					res = append(res, v.V.(int64))
+					return nil
+				})
+				require.NoError(t, err)
+				return res
+			}
+
+			require.Equal(t, []int64{8, 6, 4, 2, 0}, collect(nil))
+			require.Equal(t, []int64{4, 2, 0}, collect(key.AppendInt64(nil, 4)))
+			require.Equal(t, []int64{4, 2, 0}, collect(key.AppendInt64(nil, 5)))
+			require.Equal(t, []int64{8, 6, 4, 2, 0}, collect(key.AppendInt64(nil, 10)))
+			require.Empty(t, collect(key.AppendInt64(nil, -1)))
+		})
+	}
+}
+
+func TestTableSizeEstimate(t *testing.T) {
+	engines := []struct {
+		name    string
+		builder func(t *testing.T) (engine.Engine, func())
+	}{
+		{"memory", func(t *testing.T) (engine.Engine, func()) {
+			return memoryengine.NewEngine(), func() {}
+		}},
+		{"bolt", func(t *testing.T) (engine.Engine, func()) {
+			dir, err := ioutil.TempDir("", "genji")
+			require.NoError(t, err)
+
+			ng, err := boltengine.NewEngine(filepath.Join(dir, "test.db"), 0600, nil)
+			require.NoError(t, err)
+
+			return ng, func() {
+				ng.Close()
+				os.RemoveAll(dir)
+			}
+		}},
+	}
+
+	for _, e := range engines {
+		t.Run(e.name, func(t *testing.T) {
+			ng, cleanup := e.builder(t)
+			defer cleanup()
+
+			db, err := database.New(ng, database.Options{Codec: msgpack.NewCodec()})
+			require.NoError(t, err)
+
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			err = tx.CreateTable("test", nil)
+			require.NoError(t, err)
+			tb, err := tx.GetTable("test")
+			require.NoError(t, err)
+
+			records, size, err := tb.SizeEstimate()
+			require.NoError(t, err)
+			require.Zero(t, records)
+			require.Zero(t, size)
+
+			for i := 0; i < 10; i++ {
+				_, err := tb.Insert(newDocument())
+				require.NoError(t, err)
+			}
+
+			records, size, err = tb.SizeEstimate()
+			require.NoError(t, err)
+			require.EqualValues(t, 10, records)
+			require.NotZero(t, size)
+
+			err = tx.CreateIndex(database.IndexConfig{
+				IndexName: "idx_test_fielda", TableName: "test", Path: parsePath(t, "fielda"),
+			})
+			require.NoError(t, err)
+			err = tx.ReIndex("idx_test_fielda")
+			require.NoError(t, err)
+
+			// the size of the indexes is included
+			records, withIndex, err := tb.SizeEstimate()
+			require.NoError(t, err)
+			require.EqualValues(t, 10, records)
+			require.Greater(t, withIndex, size)
+
+			err = tx.Commit()
+			require.NoError(t, err)
+
+			tx, err = db.Begin(false)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			tb, err = tx.GetTable("test")
+			require.NoError(t, err)
+
+			records, size, err = tb.SizeEstimate()
+			require.NoError(t, err)
+			require.EqualValues(t, 10, records)
+			require.NotZero(t, size)
+		})
+	}
+}
+
 // TestTableGetDocument verifies GetDocument behaviour.
 func TestTableGetDocument(t *testing.T) {
 	t.Run("Should fail if not found", func(t *testing.T) {
@@ -131,6 +462,63 @@ func TestTableGetDocument(t *testing.T) {
 	})
 }
 
+// TestTableEncodeKey verifies that documents can be fetched using EncodeKey.
+func TestTableEncodeKey(t *testing.T) {
+	t.Run("Without primary key", func(t *testing.T) {
+		tb, cleanup := newTestTable(t)
+		defer cleanup()
+
+		k, err := tb.Insert(newDocument())
+		require.NoError(t, err)
+
+		docid, _ := binary.Uvarint(k)
+		enc, err := tb.EncodeKey(document.NewIntegerValue(int64(docid)))
+		require.NoError(t, err)
+		require.Equal(t, k, enc)
+
+		_, err = tb.GetDocument(enc)
+		require.NoError(t, err)
+
+		enc, err = tb.EncodeKey(document.NewIntegerValue(int64(docid) + 1))
+		require.NoError(t, err)
+		_, err = tb.GetDocument(enc)
+		require.Equal(t, database.ErrDocumentNotFound, err)
+
+		_, err = tb.EncodeKey(document.NewIntegerValue(-1))
+		require.Error(t, err)
+		_, err = tb.EncodeKey(document.NewTextValue("foo"))
+		require.Error(t, err)
+	})
+
+	t.Run("With primary key", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", &database.TableInfo{
+			FieldConstraints: []database.FieldConstraint{
+				{Path: parsePath(t, "foo"), Type: document.IntegerValue, IsPrimaryKey: true},
+			},
+		})
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		k, err := tb.Insert(document.NewFieldBuffer().Add("foo", document.NewIntegerValue(10)))
+		require.NoError(t, err)
+
+		// values are converted to the type of the primary key
+		enc, err := tb.EncodeKey(document.NewDoubleValue(10))
+		require.NoError(t, err)
+		require.Equal(t, k, enc)
+
+		d, err := tb.GetDocument(enc)
+		require.NoError(t, err)
+		v, err := d.GetByField("foo")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(10), v)
+	})
+}
+
 // TestTableInsert verifies Insert behaviour.
 func TestTableInsert(t *testing.T) {
 	t.Run("Should generate a key by default", func(t *testing.T) {
@@ -221,7 +609,7 @@ func TestTableInsert(t *testing.T) {
 
 		// insert again
 		k, err = tb.Insert(doc)
-		require.Equal(t, database.ErrDuplicateDocument, err)
+		require.True(t, errors.Is(err, database.ErrDuplicateDocument), err)
 	})
 
 	t.Run("Should convert values into the right types if there are constraints", func(t *testing.T) {
@@ -284,6 +672,57 @@ func TestTableInsert(t *testing.T) {
 		}
 	})
 
+	t.Run("Should fail if the primary key is missing or null", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", &database.TableInfo{
+			FieldConstraints: []database.FieldConstraint{
+				{Path: parsePath(t, "foo"), IsPrimaryKey: true},
+			},
+		})
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		_, err = tb.Insert(document.NewFieldBuffer().Add("bar", document.NewIntegerValue(1)))
+		require.EqualError(t, err, `primary key "foo" is required and must be not null`)
+
+		_, err = tb.Insert(document.NewFieldBuffer().Add("foo", document.NewNullValue()))
+		require.EqualError(t, err, `primary key "foo" is required and must be not null`)
+
+		key, err := tb.Insert(document.NewFieldBuffer().Add("foo", document.NewIntegerValue(1)))
+		require.NoError(t, err)
+		_, err = tb.GetDocument(key)
+		require.NoError(t, err)
+
+		// the primary key must stay not null after a replacement
+		err = tb.Replace(key, document.NewFieldBuffer().Add("bar", document.NewIntegerValue(1)))
+		require.Error(t, err)
+	})
+
+	t.Run("Should use the default value of a missing primary key", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", &database.TableInfo{
+			FieldConstraints: []database.FieldConstraint{
+				{Path: parsePath(t, "foo"), Type: document.IntegerValue, IsPrimaryKey: true, DefaultValue: expr.Constraint(expr.IntegerValue(10))},
+			},
+		})
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		key, err := tb.Insert(document.NewFieldBuffer().Add("bar", document.NewIntegerValue(1)))
+		require.NoError(t, err)
+		d, err := tb.GetDocument(key)
+		require.NoError(t, err)
+		v, err := d.GetByField("foo")
+		require.NoError(t, err)
+		require.Equal(t, document.NewIntegerValue(10), v)
+	})
+
 	t.Run("Should update indexes if there are indexed fields", func(t *testing.T) {
 		tx, cleanup := newTestDB(t)
 		defer cleanup()
@@ -337,8 +776,8 @@ func TestTableInsert(t *testing.T) {
 
 		err := tx.CreateTable("test", &database.TableInfo{
 			FieldConstraints: []database.FieldConstraint{
-				{parsePath(t, "foo"), document.IntegerValue, false, false},
-				{parsePath(t, "bar"), document.IntegerValue, false, false},
+				{Path: parsePath(t, "foo"), Type: document.IntegerValue, IsPrimaryKey: false, IsNotNull: false},
+				{Path: parsePath(t, "bar"), Type: document.IntegerValue, IsPrimaryKey: false, IsNotNull: false},
 			},
 		})
 		require.NoError(t, err)
@@ -375,7 +814,7 @@ func TestTableInsert(t *testing.T) {
 		// no enforced type, not null
 		err := tx.CreateTable("test1", &database.TableInfo{
 			FieldConstraints: []database.FieldConstraint{
-				{parsePath(t, "foo"), 0, false, true},
+				{Path: parsePath(t, "foo"), Type: 0, IsPrimaryKey: false, IsNotNull: true},
 			},
 		})
 		require.NoError(t, err)
@@ -385,7 +824,7 @@ func TestTableInsert(t *testing.T) {
 		// enforced type, not null
 		err = tx.CreateTable("test2", &database.TableInfo{
 			FieldConstraints: []database.FieldConstraint{
-				{parsePath(t, "foo"), document.IntegerValue, false, true},
+				{Path: parsePath(t, "foo"), Type: document.IntegerValue, IsPrimaryKey: false, IsNotNull: true},
 			},
 		})
 		require.NoError(t, err)
@@ -429,7 +868,7 @@ func TestTableInsert(t *testing.T) {
 
 		err := tx.CreateTable("test1", &database.TableInfo{
 			FieldConstraints: []database.FieldConstraint{
-				{parsePath(t, "foo[1]"), 0, false, true},
+				{Path: parsePath(t, "foo[1]"), Type: 0, IsPrimaryKey: false, IsNotNull: true},
 			},
 		})
 		require.NoError(t, err)
@@ -448,6 +887,69 @@ func TestTableInsert(t *testing.T) {
 }
 
 // TestTableDelete verifies Delete behaviour.
+func TestTableInsertBatch(t *testing.T) {
+	t.Run("Should insert and index every document", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+		err = tx.CreateIndex(database.IndexConfig{
+			IndexName: "idx_a",
+			TableName: "test",
+			Path:      parsePath(t, "a"),
+		})
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		var docs []document.Document
+		for i := int64(0); i < 10; i++ {
+			docs = append(docs, document.NewFieldBuffer().Add("a", document.NewIntegerValue(i)))
+		}
+
+		keys, err := tb.InsertBatch(docs)
+		require.NoError(t, err)
+		require.Len(t, keys, 10)
+
+		for i, key := range keys {
+			d, err := tb.GetDocument(key)
+			require.NoError(t, err)
+			v, err := d.GetByField("a")
+			require.NoError(t, err)
+			require.Equal(t, document.NewIntegerValue(int64(i)), v)
+			require.Equal(t, 1, countIndexedKeys(t, tx, "idx_a", key))
+		}
+	})
+
+	t.Run("Should report the duplicate value of a unique index", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+		err = tx.CreateIndex(database.IndexConfig{
+			IndexName: "idx_a",
+			TableName: "test",
+			Path:      parsePath(t, "a"),
+			Unique:    true,
+		})
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		_, err = tb.InsertBatch([]document.Document{
+			document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)),
+			document.NewFieldBuffer().Add("a", document.NewIntegerValue(2)),
+			document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)),
+		})
+		require.True(t, errors.Is(err, database.ErrDuplicateDocument), err)
+		var cErr *database.ConstraintError
+		require.True(t, errors.As(err, &cErr), err)
+		require.Equal(t, document.NewIntegerValue(1), cErr.Value)
+	})
+}
+
 func TestTableDelete(t *testing.T) {
 	t.Run("Should fail if not found", func(t *testing.T) {
 		tb, cleanup := newTestTable(t)
@@ -485,6 +987,41 @@ func TestTableDelete(t *testing.T) {
 		_, err = res.GetByField("fieldc")
 		require.Error(t, err)
 	})
+
+	t.Run("Should update indexes", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+		err = tx.CreateIndex(database.IndexConfig{
+			IndexName: "idx_fieldc",
+			TableName: "test",
+			Path:      parsePath(t, "fieldc"),
+		})
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		// doc2 doesn't contain the indexed field
+		doc1 := newDocument()
+		doc1.Add("fieldc", document.NewIntegerValue(40))
+		doc2 := newDocument()
+
+		key1, err := tb.Insert(doc1)
+		require.NoError(t, err)
+		key2, err := tb.Insert(doc2)
+		require.NoError(t, err)
+
+		err = tb.Delete(key1)
+		require.NoError(t, err)
+		require.Equal(t, 0, countIndexedKeys(t, tx, "idx_fieldc", key1))
+		require.Equal(t, 1, countIndexedKeys(t, tx, "idx_fieldc", key2))
+
+		err = tb.Delete(key2)
+		require.NoError(t, err)
+		require.Equal(t, 0, countIndexedKeys(t, tx, "idx_fieldc", key2))
+	})
 }
 
 // TestTableReplace verifies Replace behaviour.
@@ -535,6 +1072,53 @@ func TestTableReplace(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, "c", f.V.(string))
 	})
+
+	t.Run("Should update indexes", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+		err = tx.CreateIndex(database.IndexConfig{
+			IndexName: "idx_fieldc",
+			TableName: "test",
+			Path:      parsePath(t, "fieldc"),
+			Unique:    true,
+		})
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		// doc1 doesn't contain the indexed field
+		key1, err := tb.Insert(newDocument())
+		require.NoError(t, err)
+		key2, err := tb.Insert(newDocument().Add("fieldc", document.NewIntegerValue(10)))
+		require.NoError(t, err)
+
+		// replace in place, adding the indexed field
+		err = tb.Replace(key1, newDocument().Add("fieldc", document.NewIntegerValue(20)))
+		require.NoError(t, err)
+		require.Equal(t, 1, countIndexedKeys(t, tx, "idx_fieldc", key1))
+
+		idx, err := tx.GetIndex("idx_fieldc")
+		require.NoError(t, err)
+		var found bool
+		err = idx.AscendGreaterOrEqual(document.NewIntegerValue(20), func(v, k []byte, isEqual bool) error {
+			found = isEqual && string(k) == string(key1)
+			return errors.New("stop")
+		})
+		require.Error(t, err)
+		require.True(t, found)
+
+		// removing the indexed field
+		err = tb.Replace(key2, newDocument())
+		require.NoError(t, err)
+		require.Equal(t, 1, countIndexedKeys(t, tx, "idx_fieldc", key2))
+
+		// unique constraint is enforced
+		err = tb.Replace(key2, newDocument().Add("fieldc", document.NewIntegerValue(20)))
+		require.True(t, errors.Is(err, database.ErrDuplicateDocument), err)
+	})
 }
 
 // TestTableTruncate verifies Truncate behaviour.
@@ -543,7 +1127,7 @@ func TestTableTruncate(t *testing.T) {
 		tb, cleanup := newTestTable(t)
 		defer cleanup()
 
-		err := tb.Truncate()
+		err := tb.Truncate(false)
 		require.NoError(t, err)
 	})
 
@@ -560,7 +1144,7 @@ func TestTableTruncate(t *testing.T) {
 		_, err = tb.Insert(doc2)
 		require.NoError(t, err)
 
-		err = tb.Truncate()
+		err = tb.Truncate(false)
 		require.NoError(t, err)
 
 		err = tb.Iterate(func(_ document.Document) error {
@@ -569,6 +1153,62 @@ func TestTableTruncate(t *testing.T) {
 
 		require.NoError(t, err)
 	})
+
+	t.Run("Should keep or restart the sequence", func(t *testing.T) {
+		tests := []struct {
+			restartIdentity bool
+			expected        uint64
+		}{
+			{false, 3},
+			{true, 1},
+		}
+
+		for _, test := range tests {
+			tb, cleanup := newTestTable(t)
+			defer cleanup()
+
+			_, err := tb.Insert(newDocument())
+			require.NoError(t, err)
+			_, err = tb.Insert(newDocument())
+			require.NoError(t, err)
+
+			err = tb.Truncate(test.restartIdentity)
+			require.NoError(t, err)
+
+			key, err := tb.Insert(newDocument())
+			require.NoError(t, err)
+			docid, _ := binary.Uvarint(key)
+			require.Equal(t, test.expected, docid)
+		}
+	})
+
+	t.Run("Should truncate the indexes", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+		err = tx.CreateIndex(database.IndexConfig{
+			IndexName: "idx_test_a", TableName: "test", Path: parsePath(t, "a"),
+		})
+		require.NoError(t, err)
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		_, err = tb.Insert(newDocument().Add("a", document.NewIntegerValue(10)))
+		require.NoError(t, err)
+
+		err = tb.Truncate(false)
+		require.NoError(t, err)
+
+		idx, err := tx.GetIndex("idx_test_a")
+		require.NoError(t, err)
+		err = idx.AscendGreaterOrEqual(document.Value{}, func(val, key []byte, isEqual bool) error {
+			return errors.New("should not iterate")
+		})
+		require.NoError(t, err)
+	})
 }
 
 func TestTableReIndex(t *testing.T) {
@@ -657,6 +1297,43 @@ func TestTableReIndex(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, 0, countIndexElems(idx))
 	})
+	t.Run("Should reindex more documents than a batch", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		for i := int64(0); i < 2500; i++ {
+			doc := document.NewFieldBuffer().
+				Add("a", document.NewArrayValue(document.NewValueBuffer(document.NewIntegerValue(i), document.NewBlobValue([]byte{byte(i)}))))
+			_, err = tb.Insert(doc)
+			require.NoError(t, err)
+		}
+
+		err = tx.CreateIndex(database.IndexConfig{
+			IndexName: "idx_a",
+			TableName: "test",
+			Path:      parsePath(t, "a"),
+			Unique:    true,
+		})
+		require.NoError(t, err)
+
+		err = tb.ReIndex()
+		require.NoError(t, err)
+
+		idx, err := tx.GetIndex("idx_a")
+		require.NoError(t, err)
+		var i int
+		err = idx.AscendGreaterOrEqual(document.Value{Type: document.ArrayValue}, func(v, k []byte, isEqual bool) error {
+			i++
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2500, i)
+	})
 }
 
 func TestTableIndexes(t *testing.T) {
@@ -669,7 +1346,7 @@ func TestTableIndexes(t *testing.T) {
 		require.Empty(t, m)
 	})
 
-	t.Run("Should return a map of all the indexes", func(t *testing.T) {
+	t.Run("Should return all the indexes of the table", func(t *testing.T) {
 		tx, cleanup := newTestDB(t)
 		defer cleanup()
 
@@ -703,15 +1380,68 @@ func TestTableIndexes(t *testing.T) {
 		})
 		require.NoError(t, err)
 
-		m, err := tb.Indexes()
+		list, err := tb.Indexes()
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		require.Equal(t, "idx1a", list[0].Opts.IndexName)
+		require.True(t, list[0].Unique)
+		require.Equal(t, "idx1b", list[1].Opts.IndexName)
+		require.False(t, list[1].Unique)
+	})
+
+	t.Run("Should update the list when indexes are created or dropped", func(t *testing.T) {
+		tx, cleanup := newTestDB(t)
+		defer cleanup()
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		indexNames := func(tb *database.Table) []string {
+			t.Helper()
+
+			list, err := tb.Indexes()
+			require.NoError(t, err)
+
+			var names []string
+			for _, idx := range list {
+				names = append(names, idx.Opts.IndexName)
+			}
+			return names
+		}
+
+		require.Empty(t, indexNames(tb))
+
+		err = tx.CreateIndex(database.IndexConfig{IndexName: "idx_b", TableName: "test", Path: parsePath(t, "b")})
+		require.NoError(t, err)
+		require.Equal(t, []string{"idx_b"}, indexNames(tb))
+
+		err = tx.CreateIndex(database.IndexConfig{IndexName: "idx_a", TableName: "test", Path: parsePath(t, "a")})
+		require.NoError(t, err)
+		require.Equal(t, []string{"idx_a", "idx_b"}, indexNames(tb))
+
+		// the opened indexes are reused until the list changes
+		l1, err := tb.Indexes()
+		require.NoError(t, err)
+		l2, err := tb.Indexes()
+		require.NoError(t, err)
+		require.Same(t, l1[0], l2[0])
+
+		err = tx.DropIndex("idx_b")
+		require.NoError(t, err)
+		require.Equal(t, []string{"idx_a"}, indexNames(tb))
+
+		err = tx.RenameTable("test", "foo")
+		require.NoError(t, err)
+		require.Empty(t, indexNames(tb))
+		foo, err := tx.GetTable("foo")
+		require.NoError(t, err)
+		require.Equal(t, []string{"idx_a"}, indexNames(foo))
+
+		err = tx.DropTable("foo")
 		require.NoError(t, err)
-		require.Len(t, m, 2)
-		idx1a, ok := m["a"]
-		require.True(t, ok)
-		require.NotNil(t, idx1a)
-		idx1b, ok := m["b"]
-		require.True(t, ok)
-		require.NotNil(t, idx1b)
+		require.Empty(t, indexNames(foo))
 	})
 }
 
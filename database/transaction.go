@@ -1,9 +1,12 @@
 package database
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/engine"
@@ -14,6 +17,8 @@ var (
 	internalPrefix     = "__genji_"
 	tableInfoStoreName = internalPrefix + "tables"
 	indexStoreName     = internalPrefix + "indexes"
+	metaStoreName      = internalPrefix + "meta"
+	tempStorePrefix    = internalPrefix + "temp_"
 )
 
 // Transaction represents a database transaction. It provides methods for managing the
@@ -25,9 +30,16 @@ type Transaction struct {
 	db       *Database
 	tx       engine.Transaction
 	writable bool
+	// time at which the transaction was created.
+	startTime time.Time
+	// set to true once the transaction is committed or rolled back.
+	terminated bool
 
 	tableInfoStore *tableInfoStore
 	indexStore     *indexStore
+	// opened indexes of each table, by table name.
+	// reset whenever an index is created or dropped.
+	indexes map[string][]*Index
 }
 
 // DB returns the underlying database that created the transaction.
@@ -35,6 +47,13 @@ func (tx *Transaction) DB() *Database {
 	return tx.db
 }
 
+// StartTime returns the time at which the transaction was created.
+// It can be used by time related functions to return
+// consistent values during the lifetime of the transaction.
+func (tx *Transaction) StartTime() time.Time {
+	return tx.startTime
+}
+
 // Rollback the transaction. Can be used safely after commit.
 func (tx *Transaction) Rollback() error {
 	tx.db.attachedTxMu.Lock()
@@ -53,6 +72,11 @@ func (tx *Transaction) Rollback() error {
 		tx.db.attachedTransaction = nil
 	}
 
+	if !tx.terminated {
+		tx.terminated = true
+		tx.db.Log(Event{Kind: EventTxRollback, TxID: tx.id})
+	}
+
 	return nil
 }
 
@@ -61,11 +85,14 @@ func (tx *Transaction) Commit() error {
 	tx.db.attachedTxMu.Lock()
 	defer tx.db.attachedTxMu.Unlock()
 
+	// Tables created by the transaction must be visible to other transactions
+	// at the same time as the data stored by the engine.
+	var err error
 	if tx.writable {
-		tx.tableInfoStore.commit(tx)
+		err = tx.tableInfoStore.commit(tx, tx.tx.Commit)
+	} else {
+		err = tx.tx.Commit()
 	}
-
-	err := tx.tx.Commit()
 	if err != nil {
 		return err
 	}
@@ -74,8 +101,15 @@ func (tx *Transaction) Commit() error {
 		tx.db.attachedTransaction = nil
 	}
 
+	tx.terminated = true
+	tx.db.Log(Event{Kind: EventTxCommit, TxID: tx.id})
+
 	return nil
+}
 
+// ID returns the identifier of the transaction, unique for the lifetime of the database.
+func (tx *Transaction) ID() int64 {
+	return tx.id
 }
 
 // Writable indicates if the transaction is writable or not.
@@ -90,12 +124,41 @@ func (tx *Transaction) CreateTable(name string, info *TableInfo) error {
 		return fmt.Errorf("table name must not start with %s", internalPrefix)
 	}
 
+	if _, ok := virtualTables[name]; ok {
+		return fmt.Errorf("table name %q is reserved", name)
+	}
+
+	if _, ok := tx.db.getPartitionSet(name); ok {
+		return fmt.Errorf("table name %q is used by a partition set", name)
+	}
+
 	if info == nil {
 		info = new(TableInfo)
 	}
 
 	info.tableName = name
-	err := tx.tableInfoStore.Insert(tx, name, info)
+
+	for i, fc := range info.FieldConstraints {
+		if fc.Type == document.TextValue && !fc.ExplicitCollation {
+			info.FieldConstraints[i].Collation = tx.db.DefaultCollation
+		}
+
+		if fc.IsEncrypted && fc.IsPrimaryKey {
+			return fmt.Errorf("primary key %q cannot be encrypted", fc.Path)
+		}
+
+		// documents cannot be stored without a primary key
+		if fc.IsPrimaryKey {
+			info.FieldConstraints[i].IsNotNull = true
+		}
+	}
+
+	err := tx.validateForeignKeys(name, info)
+	if err != nil {
+		return err
+	}
+
+	err = tx.tableInfoStore.Insert(tx, name, info)
 	if err != nil {
 		return err
 	}
@@ -110,6 +173,19 @@ func (tx *Transaction) CreateTable(name string, info *TableInfo) error {
 
 // GetTable returns a table by name. The table instance is only valid for the lifetime of the transaction.
 func (tx *Transaction) GetTable(name string) (*Table, error) {
+	if vt, ok := virtualTables[name]; ok {
+		return tx.getVirtualTable(name, vt)
+	}
+
+	if partitions, ok := tx.db.getPartitionSet(name); ok {
+		return tx.getPartitionSetTable(name, partitions)
+	}
+
+	return tx.getStoredTable(name)
+}
+
+// getStoredTable returns the table whose documents are stored in the database.
+func (tx *Transaction) getStoredTable(name string) (*Table, error) {
 	ti, err := tx.tableInfoStore.Get(tx, name)
 	if err != nil {
 		return nil, err
@@ -140,7 +216,19 @@ func (tx *Transaction) RenameTable(oldName, newName string) error {
 		return errors.New("cannot write to read-only table")
 	}
 
+	if tx.isReferenced(oldName) {
+		return fmt.Errorf("cannot rename table %q: it is referenced by a foreign key", oldName)
+	}
+
 	ti.tableName = newName
+	// update the foreign keys referencing the table itself.
+	// the slice is copied to avoid modifying the info of the old name.
+	ti.ForeignKeys = append([]ForeignKey(nil), ti.ForeignKeys...)
+	for i := range ti.ForeignKeys {
+		if ti.ForeignKeys[i].ReferencedTable == oldName {
+			ti.ForeignKeys[i].ReferencedTable = newName
+		}
+	}
 	// Insert the TableInfo keyed by the newName name.
 	err = tx.tableInfoStore.Insert(tx, newName, ti)
 	if err != nil {
@@ -148,19 +236,18 @@ func (tx *Transaction) RenameTable(oldName, newName string) error {
 	}
 
 	// Update the indexes.
-	idxs, err := tx.ListIndexes()
+	idxs, err := tx.indexStore.ListByTable(oldName)
 	if err != nil {
 		return err
 	}
 	for _, idx := range idxs {
-		if idx.TableName == oldName {
-			idx.TableName = newName
-			err = tx.indexStore.Replace(idx.IndexName, *idx)
-			if err != nil {
-				return err
-			}
+		idx.TableName = newName
+		err = tx.indexStore.Replace(idx.IndexName, *idx)
+		if err != nil {
+			return err
 		}
 	}
+	tx.indexes = nil
 
 	// Delete the old reference from the tableInfoStore.
 	return tx.tableInfoStore.Delete(tx, oldName)
@@ -177,41 +264,27 @@ func (tx *Transaction) DropTable(name string) error {
 		return errors.New("cannot write to read-only table")
 	}
 
-	it := tx.indexStore.st.NewIterator(engine.IteratorConfig{})
-
-	var buf []byte
-	for it.Seek(nil); it.Valid(); it.Next() {
-		item := it.Item()
-		var opts IndexConfig
-		buf, err = item.ValueCopy(buf)
-		if err != nil {
-			it.Close()
-			return err
-		}
-
-		err = opts.ScanDocument(tx.db.Codec.NewDocument(buf))
-		if err != nil {
-			it.Close()
-			return err
-		}
-
-		// Remove only indexes associated with the target table.
-		if opts.TableName != name {
-			continue
-		}
+	if tx.isReferenced(name) {
+		return fmt.Errorf("cannot drop table %q: it is referenced by a foreign key", name)
+	}
 
+	idxs, err := tx.indexStore.ListByTable(name)
+	if err != nil {
+		return err
+	}
+	for _, opts := range idxs {
 		err = tx.DropIndex(opts.IndexName)
 		if err != nil {
-			it.Close()
 			return err
 		}
 	}
-	err = it.Close()
+
+	err = tx.tableInfoStore.Delete(tx, name)
 	if err != nil {
 		return err
 	}
 
-	err = tx.tableInfoStore.Delete(tx, name)
+	err = tx.dropBlobStore(ti)
 	if err != nil {
 		return err
 	}
@@ -219,6 +292,39 @@ func (tx *Transaction) DropTable(name string) error {
 	return tx.tx.DropStore(ti.storeName)
 }
 
+// CreateTempStore creates a store used to hold the intermediate results of a statement
+// that don't fit in memory, and returns its name.
+// The store must be dropped using DropTempStore before the end of the transaction.
+// If the transaction is read-only, it returns engine.ErrTransactionReadOnly.
+func (tx *Transaction) CreateTempStore() ([]byte, engine.Store, error) {
+	if !tx.writable {
+		return nil, nil, engine.ErrTransactionReadOnly
+	}
+
+	name := []byte(fmt.Sprintf("%s%d", tempStorePrefix, atomic.AddInt64(&tx.db.lastTempStoreID, 1)))
+
+	err := tx.tx.CreateStore(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	st, err := tx.tx.GetStore(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return name, st, nil
+}
+
+// DropTempStore drops a store created by CreateTempStore.
+func (tx *Transaction) DropTempStore(name []byte) error {
+	if !bytes.HasPrefix(name, []byte(tempStorePrefix)) {
+		return fmt.Errorf("%q is not a temporary store", name)
+	}
+
+	return tx.tx.DropStore(name)
+}
+
 // CreateIndex creates an index with the given name.
 // If it already exists, returns ErrIndexAlreadyExists.
 func (tx *Transaction) CreateIndex(opts IndexConfig) error {
@@ -232,19 +338,35 @@ func (tx *Transaction) CreateIndex(opts IndexConfig) error {
 		return err
 	}
 
+	if info.readOnly {
+		return errors.New("cannot create index on read-only table")
+	}
+
+	// indexes would store the values of encrypted fields in clear.
+	if info.isEncrypted(opts.Path) {
+		return fmt.Errorf("cannot index encrypted field %q", opts.Path)
+	}
+
 	// if the index is created on a field on which we know the type,
-	// create a typed index.
+	// create a typed index. the index also uses the collation of the field.
 	for _, fc := range info.FieldConstraints {
 		if fc.Path.IsEqual(opts.Path) {
 			if fc.Type != 0 {
 				opts.Type = fc.Type
 			}
+			opts.Collation = fc.Collation
 
 			break
 		}
 	}
 
-	return tx.indexStore.Insert(opts)
+	err = tx.indexStore.Insert(opts)
+	if err != nil {
+		return err
+	}
+
+	tx.indexes = nil
+	return nil
 }
 
 // GetIndex returns an index by name.
@@ -254,15 +376,47 @@ func (tx *Transaction) GetIndex(name string) (*Index, error) {
 		return nil, err
 	}
 
+	return tx.openIndex(opts), nil
+}
+
+// openIndex returns a handle on the index described by opts.
+func (tx *Transaction) openIndex(opts *IndexConfig) *Index {
 	idx := index.NewIndex(tx.tx, opts.IndexName, index.Options{
-		Unique: opts.Unique,
-		Type:   opts.Type,
+		Unique:     opts.Unique,
+		Type:       opts.Type,
+		Collation:  opts.Collation,
+		MaxKeySize: tx.db.MaxIndexKeySize,
 	})
 
 	return &Index{
 		Index: idx,
 		Opts:  *opts,
-	}, nil
+	}
+}
+
+// tableIndexes returns the indexes of the given table.
+// They are opened on first use, then cached until an index is created or dropped.
+func (tx *Transaction) tableIndexes(tableName string) ([]*Index, error) {
+	if indexes, ok := tx.indexes[tableName]; ok {
+		return indexes, nil
+	}
+
+	list, err := tx.indexStore.ListByTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make([]*Index, len(list))
+	for i, opts := range list {
+		indexes[i] = tx.openIndex(opts)
+	}
+
+	if tx.indexes == nil {
+		tx.indexes = make(map[string][]*Index)
+	}
+	tx.indexes[tableName] = indexes
+
+	return indexes, nil
 }
 
 // DropIndex deletes an index from the database.
@@ -275,10 +429,12 @@ func (tx *Transaction) DropIndex(name string) error {
 	if err != nil {
 		return err
 	}
+	tx.indexes = nil
 
 	idx := index.NewIndex(tx.tx, opts.IndexName, index.Options{
-		Unique: opts.Unique,
-		Type:   opts.Type,
+		Unique:    opts.Unique,
+		Type:      opts.Type,
+		Collation: opts.Collation,
 	})
 
 	return idx.Truncate()
@@ -289,7 +445,12 @@ func (tx *Transaction) ListIndexes() ([]*IndexConfig, error) {
 	return tx.indexStore.ListAll()
 }
 
-// ReIndex truncates and recreates selected index from scratch.
+// reindexBatchSize is the number of entries written at once to an index
+// when it is recreated.
+const reindexBatchSize = 1000
+
+// ReIndex truncates and recreates selected index from scratch,
+// then computes its statistics.
 func (tx *Transaction) ReIndex(indexName string) error {
 	idx, err := tx.GetIndex(indexName)
 	if err != nil {
@@ -306,7 +467,10 @@ func (tx *Transaction) ReIndex(indexName string) error {
 		return err
 	}
 
-	return tb.Iterate(func(d document.Document) error {
+	// the documents are only valid during the iteration,
+	// the entries of a batch are copied
+	entries := make([]index.Entry, 0, reindexBatchSize)
+	err = tb.Iterate(func(d document.Document) error {
 		v, err := idx.Opts.Path.GetValue(d)
 		if err == document.ErrFieldNotFound {
 			return nil
@@ -315,8 +479,57 @@ func (tx *Transaction) ReIndex(indexName string) error {
 			return err
 		}
 
-		return idx.Set(v, d.(document.Keyer).Key())
+		v, err = copyValue(v)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, index.Entry{Value: v, Key: append([]byte(nil), d.(document.Keyer).Key()...)})
+		if len(entries) < reindexBatchSize {
+			return nil
+		}
+
+		err = idx.SetBatch(entries)
+		entries = entries[:0]
+		return err
 	})
+	if err != nil {
+		return err
+	}
+
+	err = idx.SetBatch(entries)
+	if err != nil {
+		return err
+	}
+
+	_, err = idx.Analyze()
+	return err
+}
+
+// copyValue returns a deep copy of v, which doesn't reference
+// the memory of the document it was read from.
+func copyValue(v document.Value) (document.Value, error) {
+	switch v.Type {
+	case document.BlobValue:
+		return document.NewBlobValue(append([]byte(nil), v.V.([]byte)...)), nil
+	case document.DocumentValue:
+		var fb document.FieldBuffer
+		err := v.V.(document.Document).Iterate(func(field string, fv document.Value) error {
+			fv, err := copyValue(fv)
+			fb.Add(field, fv)
+			return err
+		})
+		return document.NewDocumentValue(&fb), err
+	case document.ArrayValue:
+		var vb document.ValueBuffer
+		err := v.V.(document.Array).Iterate(func(i int, av document.Value) error {
+			av, err := copyValue(av)
+			vb = vb.Append(av)
+			return err
+		})
+		return document.NewArrayValue(vb), err
+	}
+
+	return v, nil
 }
 
 // ReIndexAll truncates and recreates all indexes of the database from scratch.
@@ -342,6 +555,35 @@ func (tx *Transaction) ReIndexAll() error {
 	return nil
 }
 
+// Analyze recomputes the statistics of the selected index,
+// used by the query planner to estimate its selectivity.
+func (tx *Transaction) Analyze(indexName string) error {
+	idx, err := tx.GetIndex(indexName)
+	if err != nil {
+		return err
+	}
+
+	_, err = idx.Analyze()
+	return err
+}
+
+// AnalyzeAll recomputes the statistics of all the indexes of the database.
+func (tx *Transaction) AnalyzeAll() error {
+	list, err := tx.ListIndexes()
+	if err != nil {
+		return err
+	}
+
+	for _, opts := range list {
+		err = tx.Analyze(opts.IndexName)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (tx *Transaction) getIndexStore() (*indexStore, error) {
 	st, err := tx.tx.GetStore([]byte(indexStoreName))
 	if err != nil {
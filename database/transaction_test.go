@@ -2,12 +2,17 @@ package database_test
 
 import (
 	"errors"
+	"sync"
 	"testing"
+	"time"
+
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine"
 	"github.com/genjidb/genji/engine/memoryengine"
 	"github.com/genjidb/genji/key"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -107,6 +112,38 @@ func TestTxTable(t *testing.T) {
 		}
 	})
 
+	t.Run("Drop and rollback", func(t *testing.T) {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{Codec: msgpack.NewCodec()})
+		require.NoError(t, err)
+		defer db.Close()
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.CreateTable("test", nil)
+		require.NoError(t, err)
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		tx, err = db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.DropTable("test")
+		require.NoError(t, err)
+		err = tx.Rollback()
+		require.NoError(t, err)
+
+		// The table must still exist after the rollback.
+		tx, err = db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = tx.GetTable("test")
+		require.NoError(t, err)
+	})
+
 	t.Run("Rename", func(t *testing.T) {
 		tx, cleanup := newTestDB(t)
 		defer cleanup()
@@ -414,3 +451,214 @@ func TestReIndexAll(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestTxCreateTableConcurrently(t *testing.T) {
+	ng := memoryengine.NewEngine()
+	db, err := database.New(ng, database.Options{Codec: msgpack.NewCodec()})
+	require.NoError(t, err)
+
+	const workers = 10
+	names := []string{"a", "b", "c", "d", "e"}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	successes := make(map[string]int)
+
+	// every worker tries to create every table,
+	// while reading the tables created by the others.
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := range names {
+				name := names[(i+j)%len(names)]
+
+				tx, err := db.Begin(true)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				err = tx.CreateTable(name, nil)
+				if err != nil {
+					assert.True(t, errors.Is(err, database.ErrTableAlreadyExists))
+					assert.NoError(t, tx.Rollback())
+					continue
+				}
+
+				// the odd workers roll back, leaving the table name available
+				if i%2 == 1 {
+					assert.NoError(t, tx.Rollback())
+					continue
+				}
+
+				if assert.NoError(t, tx.Commit()) {
+					mu.Lock()
+					successes[name]++
+					mu.Unlock()
+				}
+			}
+		}(i)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for _, name := range names {
+				tx, err := db.Begin(false)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				tb, err := tx.GetTable(name)
+				if err == nil {
+					assert.NoError(t, tb.Iterate(func(document.Document) error { return nil }))
+				} else {
+					assert.True(t, errors.Is(err, database.ErrTableNotFound))
+				}
+				assert.NoError(t, tx.Rollback())
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for _, name := range names {
+		require.Equal(t, 1, successes[name], "table %q", name)
+	}
+
+	// reload the database and ensure every table was stored once
+	// and is usable.
+	db, err = database.New(ng, database.Options{Codec: msgpack.NewCodec()})
+	require.NoError(t, err)
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	for _, name := range names {
+		tb, err := tx.GetTable(name)
+		require.NoError(t, err)
+
+		_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)))
+		require.NoError(t, err)
+	}
+
+	// tables sharing the same store would contain more than one document
+	for _, name := range names {
+		tb, err := tx.GetTable(name)
+		require.NoError(t, err)
+
+		var count int
+		err = tb.Iterate(func(document.Document) error {
+			count++
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, count, "table %q", name)
+	}
+}
+
+// commitHookEngine calls onBegin before beginning a transaction
+// and onCommit after every successful commit of a transaction.
+type commitHookEngine struct {
+	engine.Engine
+	onBegin  func(writable bool)
+	onCommit func() error
+}
+
+func (ng *commitHookEngine) Begin(writable bool) (engine.Transaction, error) {
+	if ng.onBegin != nil {
+		ng.onBegin(writable)
+	}
+
+	tx, err := ng.Engine.Begin(writable)
+	if err != nil {
+		return nil, err
+	}
+
+	return &commitHookTx{Transaction: tx, ng: ng}, nil
+}
+
+type commitHookTx struct {
+	engine.Transaction
+	ng *commitHookEngine
+}
+
+func (tx *commitHookTx) Commit() error {
+	err := tx.Transaction.Commit()
+	if err != nil || tx.ng.onCommit == nil {
+		return err
+	}
+
+	return tx.ng.onCommit()
+}
+
+func TestTxCommitTableInfoConcurrently(t *testing.T) {
+	ng := &commitHookEngine{Engine: memoryengine.NewEngine()}
+	db, err := database.New(ng, database.Options{Codec: msgpack.NewCodec()})
+	require.NoError(t, err)
+
+	getTable := func(name string) error {
+		tx, err := db.Begin(false)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		_, err = tx.GetTable(name)
+		return err
+	}
+
+	t.Run("Should publish the table with the committed data", func(t *testing.T) {
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		err = tx.CreateTable("test", nil)
+		require.NoError(t, err)
+
+		// another transaction waits for the engine while the table is being created
+		// and looks for it as soon as the engine has committed.
+		begun := make(chan struct{})
+		ng.onBegin = func(writable bool) {
+			if !writable {
+				close(begun)
+			}
+		}
+		defer func() { ng.onBegin = nil }()
+
+		errc := make(chan error, 1)
+		go func() { errc <- getTable("test") }()
+		<-begun
+
+		// the table must be published before the other transaction can look for it
+		ng.onCommit = func() error {
+			select {
+			case err := <-errc:
+				errc <- err
+			case <-time.After(50 * time.Millisecond):
+			}
+			return nil
+		}
+		defer func() { ng.onCommit = nil }()
+
+		require.NoError(t, tx.Commit())
+		require.NoError(t, <-errc)
+	})
+
+	t.Run("Should forget the table if the commit fails", func(t *testing.T) {
+		errCommit := errors.New("commit failed")
+		ng.onCommit = func() error { return errCommit }
+		defer func() { ng.onCommit = nil }()
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		err = tx.CreateTable("failed", nil)
+		require.NoError(t, err)
+		require.Equal(t, errCommit, tx.Commit())
+
+		err = getTable("failed")
+		require.True(t, errors.Is(err, database.ErrTableNotFound), err)
+	})
+}
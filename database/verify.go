@@ -0,0 +1,183 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+var errStop = errors.New("stop")
+
+// An Inconsistency describes a problem found while verifying the integrity of the database.
+type Inconsistency struct {
+	TableName string
+	// IndexName is empty if the problem is not related to an index.
+	IndexName string
+	// Key of the document concerned by the problem.
+	Key     []byte
+	Message string
+}
+
+func (i Inconsistency) String() string {
+	if i.IndexName == "" {
+		return fmt.Sprintf("table %s, key %q: %s", i.TableName, i.Key, i.Message)
+	}
+
+	return fmt.Sprintf("table %s, index %s, key %q: %s", i.TableName, i.IndexName, i.Key, i.Message)
+}
+
+// Verify scans every table and index of the database and returns the list of inconsistencies found.
+// It checks that every document can be decoded, that every document is referenced by the indexes
+// of its table and that every index entry points to an existing document with the indexed value.
+// An error is only returned if the verification itself failed.
+func (tx *Transaction) Verify() ([]Inconsistency, error) {
+	var names []string
+	for name := range tx.tableInfoStore.GetTableInfo() {
+		if !strings.HasPrefix(name, internalPrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var list []Inconsistency
+	for _, name := range names {
+		t, err := tx.GetTable(name)
+		if errors.Is(err, ErrTableNotFound) {
+			// the table was created by another transaction
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		l, err := t.Verify()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, l...)
+	}
+
+	return list, nil
+}
+
+// Verify checks the integrity of the table and its indexes.
+// See Transaction.Verify for more details.
+func (t *Table) Verify() ([]Inconsistency, error) {
+	// indexes are ordered by name, which reports the inconsistencies in a predictable order
+	idxList, err := t.Indexes()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Inconsistency
+	report := func(indexName string, key []byte, format string, a ...interface{}) {
+		list = append(list, Inconsistency{
+			TableName: t.name,
+			IndexName: indexName,
+			Key:       append([]byte{}, key...),
+			Message:   fmt.Sprintf(format, a...),
+		})
+	}
+
+	// every document must be decodable and indexed
+	it := t.Store.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	var buf []byte
+	for it.Seek(nil); it.Valid(); it.Next() {
+		item := it.Item()
+		key := item.Key()
+
+		buf, err = item.ValueCopy(buf[:0])
+		if err != nil {
+			return nil, err
+		}
+
+		var fb document.FieldBuffer
+		err = fb.Copy(t.tx.db.Codec.NewDocument(buf))
+		if err != nil {
+			report("", key, "cannot decode document: %v", err)
+			continue
+		}
+
+		for _, idx := range idxList {
+			v, err := t.getValue(idx.Opts.Path, &fb)
+			if err != nil {
+				v = document.NewNullValue()
+			}
+
+			ok, err := idx.hasEntry(v, key)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				report(idx.Opts.IndexName, key, "missing index entry for value %s", v)
+			}
+		}
+	}
+
+	// every index entry must point to an existing document
+	// with the same value
+	for _, idx := range idxList {
+		err = idx.AscendGreaterOrEqual(document.Value{}, func(val, key []byte, isEqual bool) error {
+			d, err := t.GetDocument(key)
+			if err == ErrDocumentNotFound {
+				report(idx.Opts.IndexName, key, "index entry points to a missing document")
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			v, err := t.getValue(idx.Opts.Path, d)
+			if err != nil {
+				v = document.NewNullValue()
+			}
+
+			enc, err := idx.EncodeValue(v)
+			if err != nil || !bytes.Equal(enc, val) {
+				report(idx.Opts.IndexName, key, "index entry doesn't match the value %s of the document", v)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return list, nil
+}
+
+// hasEntry returns whether the index associates v with the given key.
+func (idx *Index) hasEntry(v document.Value, key []byte) (bool, error) {
+	// a value that can't be encoded can't be indexed either
+	enc, err := idx.EncodeValue(v)
+	if err != nil {
+		return false, nil
+	}
+
+	var found bool
+	err = idx.AscendGreaterOrEqual(v, func(val, k []byte, isEqual bool) error {
+		if !bytes.Equal(val, enc) {
+			return errStop
+		}
+
+		if bytes.Equal(k, key) {
+			found = true
+			return errStop
+		}
+
+		return nil
+	})
+	if err != nil && err != errStop {
+		return false, err
+	}
+
+	return found, nil
+}
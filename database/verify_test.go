@@ -0,0 +1,98 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionVerify(t *testing.T) {
+	setup := func(t *testing.T) (*database.Transaction, *database.Table, [][]byte, func()) {
+		tx, cleanup := newTestDB(t)
+
+		err := tx.CreateTable("test", nil)
+		require.NoError(t, err)
+		err = tx.CreateIndex(database.IndexConfig{IndexName: "idx_a", TableName: "test", Path: parsePath(t, "a")})
+		require.NoError(t, err)
+		err = tx.CreateIndex(database.IndexConfig{IndexName: "idx_b", TableName: "test", Path: parsePath(t, "b"), Unique: true})
+		require.NoError(t, err)
+
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+
+		var keys [][]byte
+		for i := int64(1); i <= 3; i++ {
+			k, err := tb.Insert(document.NewFieldBuffer().
+				Add("a", document.NewIntegerValue(i)).
+				Add("b", document.NewTextValue(string(rune('a'+i)))))
+			require.NoError(t, err)
+			keys = append(keys, k)
+		}
+
+		return tx, tb, keys, cleanup
+	}
+
+	t.Run("No inconsistencies", func(t *testing.T) {
+		tx, _, _, cleanup := setup(t)
+		defer cleanup()
+
+		list, err := tx.Verify()
+		require.NoError(t, err)
+		require.Empty(t, list)
+	})
+
+	t.Run("Dangling index entry", func(t *testing.T) {
+		tx, _, _, cleanup := setup(t)
+		defer cleanup()
+
+		idx, err := tx.GetIndex("idx_a")
+		require.NoError(t, err)
+		err = idx.Set(document.NewIntegerValue(10), []byte("unknown"))
+		require.NoError(t, err)
+
+		list, err := tx.Verify()
+		require.NoError(t, err)
+		require.Equal(t, []database.Inconsistency{
+			{TableName: "test", IndexName: "idx_a", Key: []byte("unknown"), Message: "index entry points to a missing document"},
+		}, list)
+	})
+
+	t.Run("Missing and mismatched index entries", func(t *testing.T) {
+		tx, _, keys, cleanup := setup(t)
+		defer cleanup()
+
+		idx, err := tx.GetIndex("idx_b")
+		require.NoError(t, err)
+		err = idx.Delete(document.NewTextValue("b"), keys[0])
+		require.NoError(t, err)
+		err = idx.Set(document.NewTextValue("z"), keys[1])
+		require.NoError(t, err)
+
+		list, err := tx.Verify()
+		require.NoError(t, err)
+		require.Equal(t, []database.Inconsistency{
+			{TableName: "test", IndexName: "idx_b", Key: keys[0], Message: `missing index entry for value "b"`},
+			{TableName: "test", IndexName: "idx_b", Key: keys[1], Message: `index entry doesn't match the value "c" of the document`},
+		}, list)
+	})
+
+	t.Run("Corrupted document", func(t *testing.T) {
+		tx, tb, keys, cleanup := setup(t)
+		defer cleanup()
+
+		err := tb.Store.Put(keys[2], []byte{0xff, 0x00})
+		require.NoError(t, err)
+
+		list, err := tx.Verify()
+		require.NoError(t, err)
+		require.Len(t, list, 3)
+		require.Equal(t, "", list[0].IndexName)
+		require.Equal(t, keys[2], list[0].Key)
+		require.Contains(t, list[0].Message, "cannot decode document")
+		// the index entries of the corrupted document don't match anymore
+		require.Equal(t, "idx_a", list[1].IndexName)
+		require.Equal(t, "idx_b", list[2].IndexName)
+	})
+}
@@ -0,0 +1,287 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+)
+
+// A virtualTable is a read-only table whose documents are generated
+// from the information of the database every time it is read.
+type virtualTable struct {
+	// columns of the documents returned by the table.
+	fields []FieldConstraint
+	// documents returns the content of the table.
+	documents func(tx *Transaction) ([]document.Document, error)
+}
+
+// virtualTables describe the schema of the database.
+// They can be queried like any other table but cannot be modified.
+var virtualTables = map[string]virtualTable{
+	// __tables__ lists the tables of the database.
+	"__tables__": {
+		fields: []FieldConstraint{
+			virtualColumn("name", document.TextValue),
+			virtualColumn("primary_key", 0),
+			virtualColumn("strict", document.BoolValue),
+		},
+		documents: tablesDocuments,
+	},
+	// __indexes__ lists the indexes of the database.
+	"__indexes__": {
+		fields: []FieldConstraint{
+			virtualColumn("name", document.TextValue),
+			virtualColumn("table_name", document.TextValue),
+			virtualColumn("path", document.TextValue),
+			virtualColumn("unique", document.BoolValue),
+			virtualColumn("type", 0),
+		},
+		documents: indexesDocuments,
+	},
+	// __columns__ lists the fields declared by the tables of the database.
+	"__columns__": {
+		fields: []FieldConstraint{
+			virtualColumn("table_name", document.TextValue),
+			virtualColumn("name", document.TextValue),
+			virtualColumn("type", 0),
+			virtualColumn("primary_key", document.BoolValue),
+			virtualColumn("not_null", document.BoolValue),
+			virtualColumn("default", 0),
+		},
+		documents: columnsDocuments,
+	},
+}
+
+// virtualColumn returns the constraint of a top-level field of a virtual table.
+// Fields without type may be null.
+func virtualColumn(name string, tp document.ValueType) FieldConstraint {
+	return FieldConstraint{
+		Path:      document.ValuePath{document.ValuePathFragment{FieldName: name}},
+		Type:      tp,
+		IsNotNull: tp != 0,
+	}
+}
+
+// getVirtualTable returns the virtual table with the given name, filled with the
+// current information of the database.
+func (tx *Transaction) getVirtualTable(name string, vt virtualTable) (*Table, error) {
+	docs, err := vt.documents(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var st virtualStore
+	for i, d := range docs {
+		var buf bytes.Buffer
+		err = tx.db.Codec.NewEncoder(&buf).EncodeDocument(d)
+		if err != nil {
+			return nil, err
+		}
+
+		k := make([]byte, binary.MaxVarintLen64)
+		n := binary.PutUvarint(k, uint64(i+1))
+		st.items = append(st.items, virtualItem{key: k[:n], value: buf.Bytes()})
+	}
+	sort.Slice(st.items, func(i, j int) bool {
+		return bytes.Compare(st.items[i].key, st.items[j].key) < 0
+	})
+
+	return &Table{
+		tx:    tx,
+		Store: &st,
+		name:  name,
+		info: &TableInfo{
+			tableName:        name,
+			readOnly:         true,
+			FieldConstraints: vt.fields,
+			Strict:           true,
+		},
+	}, nil
+}
+
+// tableNames returns the sorted names of the user tables visible by the transaction.
+func (tx *Transaction) tableNames() []string {
+	var names []string
+	for name, info := range tx.tableInfoStore.GetTableInfo() {
+		if strings.HasPrefix(name, internalPrefix) {
+			continue
+		}
+		if info.transactionID != 0 && info.transactionID != tx.id {
+			continue
+		}
+
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// nullableText returns a null value if s is empty, otherwise a text value.
+func nullableText(s string) document.Value {
+	if s == "" {
+		return document.NewNullValue()
+	}
+
+	return document.NewTextValue(s)
+}
+
+func tablesDocuments(tx *Transaction) ([]document.Document, error) {
+	var docs []document.Document
+
+	for _, name := range tx.tableNames() {
+		info, err := tx.tableInfoStore.Get(tx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		var pk string
+		if fc := info.GetPrimaryKey(); fc != nil {
+			pk = fc.Path.String()
+		}
+
+		docs = append(docs, document.NewFieldBuffer().
+			Add("name", document.NewTextValue(name)).
+			Add("primary_key", nullableText(pk)).
+			Add("strict", document.NewBoolValue(info.Strict)))
+	}
+
+	return docs, nil
+}
+
+func indexesDocuments(tx *Transaction) ([]document.Document, error) {
+	list, err := tx.ListIndexes()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].IndexName < list[j].IndexName
+	})
+
+	docs := make([]document.Document, 0, len(list))
+	for _, opts := range list {
+		docs = append(docs, document.NewFieldBuffer().
+			Add("name", document.NewTextValue(opts.IndexName)).
+			Add("table_name", document.NewTextValue(opts.TableName)).
+			Add("path", document.NewTextValue(opts.Path.String())).
+			Add("unique", document.NewBoolValue(opts.Unique)).
+			Add("type", nullableText(opts.Type.String())))
+	}
+
+	return docs, nil
+}
+
+func columnsDocuments(tx *Transaction) ([]document.Document, error) {
+	var docs []document.Document
+
+	for _, name := range tx.tableNames() {
+		info, err := tx.tableInfoStore.Get(tx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fc := range info.FieldConstraints {
+			var def string
+			if fc.DefaultValue != nil {
+				def = fmt.Sprintf("%v", fc.DefaultValue)
+			}
+
+			docs = append(docs, document.NewFieldBuffer().
+				Add("table_name", document.NewTextValue(name)).
+				Add("name", document.NewTextValue(fc.Path.String())).
+				Add("type", nullableText(fc.Type.String())).
+				Add("primary_key", document.NewBoolValue(fc.IsPrimaryKey)).
+				Add("not_null", document.NewBoolValue(fc.IsNotNull)).
+				Add("default", nullableText(def)))
+		}
+	}
+
+	return docs, nil
+}
+
+// virtualStore is a read-only engine.Store holding the documents
+// of a virtual table in memory, sorted by key.
+type virtualStore struct {
+	items []virtualItem
+}
+
+type virtualItem struct {
+	key, value []byte
+}
+
+func (i *virtualItem) Key() []byte { return i.key }
+
+func (i *virtualItem) ValueCopy(buf []byte) ([]byte, error) {
+	return append(buf[:0], i.value...), nil
+}
+
+var errVirtualStoreReadOnly = errors.New("cannot write to read-only table")
+
+func (s *virtualStore) Get(k []byte) ([]byte, error) {
+	i := s.search(k)
+	if i < len(s.items) && bytes.Equal(s.items[i].key, k) {
+		return s.items[i].value, nil
+	}
+
+	return nil, engine.ErrKeyNotFound
+}
+
+func (s *virtualStore) Put(k, v []byte) error         { return errVirtualStoreReadOnly }
+func (s *virtualStore) Delete(k []byte) error         { return errVirtualStoreReadOnly }
+func (s *virtualStore) Truncate() error               { return errVirtualStoreReadOnly }
+func (s *virtualStore) NextSequence() (uint64, error) { return 0, errVirtualStoreReadOnly }
+func (s *virtualStore) NewIterator(cfg engine.IteratorConfig) engine.Iterator {
+	return &virtualIterator{store: s, reverse: cfg.Reverse}
+}
+
+// search returns the index of the first item whose key is greater or equal to k.
+func (s *virtualStore) search(k []byte) int {
+	return sort.Search(len(s.items), func(i int) bool {
+		return bytes.Compare(s.items[i].key, k) >= 0
+	})
+}
+
+type virtualIterator struct {
+	store   *virtualStore
+	reverse bool
+	i       int
+}
+
+func (it *virtualIterator) Seek(k []byte) {
+	switch {
+	case !it.reverse:
+		it.i = it.store.search(k)
+	case k == nil:
+		it.i = len(it.store.items) - 1
+	default:
+		it.i = it.store.search(k)
+		if it.i == len(it.store.items) || !bytes.Equal(it.store.items[it.i].key, k) {
+			it.i--
+		}
+	}
+}
+
+func (it *virtualIterator) Next() {
+	if it.reverse {
+		it.i--
+	} else {
+		it.i++
+	}
+}
+
+func (it *virtualIterator) Valid() bool {
+	return it.i >= 0 && it.i < len(it.store.items)
+}
+
+func (it *virtualIterator) Item() engine.Item {
+	return &it.store.items[it.i]
+}
+
+func (it *virtualIterator) Close() error { return nil }
@@ -0,0 +1,22 @@
+package database
+
+import (
+	"github.com/genjidb/genji/document"
+)
+
+// A VisibilityFunc reports whether the document stored under key in the given table
+// is visible. Table scans skip the documents that are not, which allows to hide
+// soft-deleted or expired documents from every query.
+// The document is decoded lazily: only the fields read by the function are decoded.
+// Documents fetched by key, for instance using GetDocument, are not filtered.
+type VisibilityFunc func(tableName string, key []byte, d document.Document) (bool, error)
+
+// isVisible reports whether the document stored under key must be returned
+// by the scans of the table. Internal and virtual tables are always visible.
+func (t *Table) isVisible(key []byte, d document.Document) (bool, error) {
+	if t.tx.db.Visibility == nil || t.infoStore == nil {
+		return true, nil
+	}
+
+	return t.tx.db.Visibility(t.name, key, d)
+}
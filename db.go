@@ -2,11 +2,18 @@ package genji
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
 	"github.com/genjidb/genji/sql/parser"
 	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
 )
 
 // DB represents a collection of tables stored in the underlying engine.
@@ -44,7 +51,35 @@ func (db *DB) View(fn func(tx *Tx) error) error {
 }
 
 // Update starts a read-write transaction, runs fn and automatically commits it.
+// If fn or the commit return engine.ErrConflict, because the transaction conflicts
+// with a concurrent one, the transaction is rolled back and fn is run again
+// in a new transaction after a delay, which doubles after each attempt.
+// It gives up after the number of attempts set by the MaxUpdateAttempts option.
+// Other errors are returned immediately.
 func (db *DB) Update(fn func(tx *Tx) error) error {
+	attempts := db.DB.MaxUpdateAttempts
+	if attempts <= 0 {
+		attempts = defaultMaxUpdateAttempts
+	}
+
+	delay := updateRetryDelay
+	for i := 1; ; i++ {
+		err := db.update(fn)
+		if i == attempts || !errors.Is(err, engine.ErrConflict) {
+			return err
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+const (
+	defaultMaxUpdateAttempts = 5
+	updateRetryDelay         = 5 * time.Millisecond
+)
+
+func (db *DB) update(fn func(tx *Tx) error) error {
 	tx, err := db.Begin(true)
 	if err != nil {
 		return err
@@ -76,10 +111,23 @@ func (db *DB) Query(ctx context.Context, q string, args ...interface{}) (*query.
 	if err != nil {
 		return nil, err
 	}
+	db.DB.Log(database.Event{Kind: database.EventQueryParsed, Query: q})
 
 	return pq.Run(ctx, db.DB, argsToParams(args))
 }
 
+// QueryEach parses the query and returns an iterator over the result of each of its statements.
+// The returned iterator must always be closed after usage.
+func (db *DB) QueryEach(ctx context.Context, q string, args ...interface{}) (*query.Results, error) {
+	pq, err := parser.ParseQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	db.DB.Log(database.Event{Kind: database.EventQueryParsed, Query: q})
+
+	return pq.RunEach(ctx, db.DB, argsToParams(args)), nil
+}
+
 // QueryDocument runs the query and returns the first document.
 // If the query returns no error, QueryDocument returns database.ErrDocumentNotFound.
 func (db *DB) QueryDocument(ctx context.Context, q string, args ...interface{}) (document.Document, error) {
@@ -107,6 +155,101 @@ func (db *DB) QueryDocument(ctx context.Context, q string, args ...interface{})
 	return &fb, nil
 }
 
+// RegisterFunction registers fn under the given name, which is case-insensitive,
+// allowing queries to call it like any other function:
+//
+//	SELECT myfunc(a, 10) FROM foo
+//
+// fn is called with the values of the arguments passed by the query and must
+// check their number and types. Functions are looked up by name when they are called,
+// so they can be registered and replaced at any time.
+// It returns an error if the name is the name of a builtin function.
+func (db *DB) RegisterFunction(name string, fn func(args ...document.Value) (document.Value, error)) error {
+	if name == "" || fn == nil {
+		return errors.New("missing function name or implementation")
+	}
+	if _, ok := expr.BuiltinFunctions()[strings.ToLower(name)]; ok {
+		return fmt.Errorf("cannot replace builtin function %q", name)
+	}
+
+	db.DB.RegisterFunction(name, fn)
+	return nil
+}
+
+// RegisterAggregate registers an aggregate function under the given name, which is case-insensitive,
+// allowing queries to call it in their projection like the builtin aggregate functions:
+//
+//	SELECT b, myagg(a) FROM foo GROUP BY b
+//
+// fn is called to create a new aggregate for every group of documents, which is passed
+// the values of the arguments of every document of the group.
+// If the function is called with DISTINCT, for example myagg(DISTINCT a), the aggregate
+// is passed each distinct combination of values only once.
+// It returns an error if the name is the name of a builtin function.
+func (db *DB) RegisterAggregate(name string, fn func() database.Aggregate) error {
+	if name == "" || fn == nil {
+		return errors.New("missing function name or implementation")
+	}
+	if _, ok := expr.BuiltinFunctions()[strings.ToLower(name)]; ok {
+		return fmt.Errorf("cannot replace builtin function %q", name)
+	}
+
+	db.DB.RegisterAggregate(name, fn)
+	return nil
+}
+
+// RegisterPartitionSet registers a read-only table made of the documents of several tables
+// sharing the same schema, such as tables partitioned by time, which can then be queried as one:
+//
+//	err := db.RegisterPartitionSet("events", "events_2021", "events_2022")
+//	res, err := db.Query(ctx, "SELECT * FROM events WHERE type = 'click'")
+//
+// Unlike a cross join, which would combine every document of a table with every document
+// of the other, a partition set concatenates the documents of its partitions: they are returned
+// one partition after the other, unchanged.
+// The partitions must exist and declare the same field constraints when the set is queried.
+// Registering a partition set under the name of another one replaces it.
+func (db *DB) RegisterPartitionSet(name string, partitions ...string) error {
+	return db.DB.RegisterPartitionSet(name, partitions...)
+}
+
+// LoadCSV reads CSV records from r and inserts them in the given table, within a single
+// read-write transaction. The first record is the header and contains the name of the field
+// associated with each column. Cells are converted to the types declared by the table,
+// otherwise their type is inferred.
+// It returns the number of loaded documents. If a record cannot be loaded, the transaction
+// is rolled back, no document is loaded and the error, a *database.CSVError,
+// reports the line of the record.
+// Unlike Update, it is not attempted again if it conflicts with another transaction,
+// because r cannot be read twice.
+func (db *DB) LoadCSV(tableName string, r io.Reader) (int, error) {
+	var n int
+	err := db.update(func(tx *Tx) error {
+		var err error
+		n, err = tx.LoadCSV(tableName, r)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// Verify checks the integrity of the database within a read-only transaction
+// and returns the list of inconsistencies found.
+func (db *DB) Verify() ([]database.Inconsistency, error) {
+	var list []database.Inconsistency
+
+	err := db.View(func(tx *Tx) error {
+		var err error
+		list, err = tx.Verify()
+		return err
+	})
+
+	return list, err
+}
+
 // Tx represents a database transaction. It provides methods for managing the
 // collection of tables and the transaction itself.
 // Tx is either read-only or read/write. Read-only can be used to read tables
@@ -122,6 +265,7 @@ func (tx *Tx) Query(ctx context.Context, q string, args ...interface{}) (*query.
 	if err != nil {
 		return nil, err
 	}
+	tx.DB().Log(database.Event{Kind: database.EventQueryParsed, TxID: tx.ID(), Query: q})
 
 	return pq.Exec(ctx, tx.Transaction, argsToParams(args))
 }
@@ -1,14 +1,27 @@
 package genji_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/genjidb/genji"
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/querytest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -70,6 +83,219 @@ func ExampleTx() {
 	// 10 foo 15
 }
 
+func TestOpenWithOptions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Default options", func(t *testing.T) {
+		db, err := genji.OpenWithOptions(":memory:", nil)
+		require.NoError(t, err)
+		defer db.Close()
+
+		// the documents are not compressed
+		fb := document.NewFieldBuffer().Add("a", document.NewTextValue(strings.Repeat("a", 1000)))
+		var raw, encoded bytes.Buffer
+		require.NoError(t, msgpack.NewCodec().NewEncoder(&raw).EncodeDocument(fb))
+		require.NoError(t, db.DB.Codec.NewEncoder(&encoded).EncodeDocument(fb))
+		require.Equal(t, raw.Bytes(), encoded.Bytes())
+	})
+
+	t.Run("Non-default options", func(t *testing.T) {
+		ng := memoryengine.NewEngine()
+		db, err := genji.OpenWithOptions("ignored", &genji.Options{
+			Engine:           ng,
+			Compression:      database.CompressionOptions{Enabled: true, MinSize: 1},
+			DefaultCollation: document.NoCaseCollation,
+		})
+		require.NoError(t, err)
+		defer db.Close()
+
+		// the documents are compressed
+		fb := document.NewFieldBuffer().Add("a", document.NewTextValue(strings.Repeat("a", 100)))
+		var raw, compressed bytes.Buffer
+		require.NoError(t, msgpack.NewCodec().NewEncoder(&raw).EncodeDocument(fb))
+		require.NoError(t, db.DB.Codec.NewEncoder(&compressed).EncodeDocument(fb))
+		require.Less(t, compressed.Len(), raw.Len())
+
+		// the default collation only applies to fields without an explicit collation
+		err = db.Exec(ctx, `
+			CREATE TABLE test (a TEXT, b TEXT COLLATE BINARY);
+			INSERT INTO test (a, b) VALUES ('B', 'B'), ('a', 'a')
+		`)
+		require.NoError(t, err)
+
+		orderBy := func(field string) string {
+			res, err := db.Query(ctx, "SELECT "+field+" FROM test ORDER BY "+field)
+			require.NoError(t, err)
+			defer res.Close()
+
+			var buf bytes.Buffer
+			require.NoError(t, document.IteratorToJSONArray(&buf, res))
+			return buf.String()
+		}
+		require.Equal(t, `[{"a": "a"}, {"a": "B"}]`, orderBy("a"))
+		require.Equal(t, `[{"b": "B"}, {"b": "a"}]`, orderBy("b"))
+
+		// the database was stored in the given engine
+		tx, err := ng.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		_, err = tx.GetStore([]byte("__genji_tables"))
+		require.NoError(t, err)
+	})
+
+	t.Run("NoSync", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "genji")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "test.db")
+		db, err := genji.OpenWithOptions(path, &genji.Options{NoSync: true})
+		require.NoError(t, err)
+		err = db.Exec(ctx, "CREATE TABLE test; INSERT INTO test (a) VALUES (1)")
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		// the data is written to disk when the database is closed
+		db, err = genji.Open(path)
+		require.NoError(t, err)
+		defer db.Close()
+
+		d, err := db.QueryDocument(ctx, "SELECT a FROM test")
+		require.NoError(t, err)
+		var a int
+		require.NoError(t, document.Scan(d, &a))
+		require.Equal(t, 1, a)
+	})
+}
+
+// openFixture opens a copy of a database stored in the testdata directory.
+func openFixture(t *testing.T, name string) *genji.DB {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "genji")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+	err = ioutil.WriteFile(path, data, 0600)
+	require.NoError(t, err)
+
+	db, err := genji.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// TestKeyFormatMigration opens a database whose keys were encoded before the encoding
+// of numbers, texts and documents changed, which must be migrated when it is opened.
+func TestKeyFormatMigration(t *testing.T) {
+	ctx := context.Background()
+
+	query := func(t *testing.T, db *genji.DB, q string) string {
+		t.Helper()
+
+		res, err := db.Query(ctx, q)
+		require.NoError(t, err)
+		defer res.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, res)
+		require.NoError(t, err)
+		return buf.String()
+	}
+
+	t.Run("Typed numeric indexes", func(t *testing.T) {
+		db := openFixture(t, "keyformat-v0.db")
+
+		require.JSONEq(t, `[{"id": "alice"}]`, query(t, db, "SELECT id FROM users WHERE age = 30"))
+		require.JSONEq(t, `[{"id": "alice"}]`, query(t, db, "SELECT id FROM users WHERE score = 1.0"))
+		require.JSONEq(t, `[{"id": "bob"}]`, query(t, db, "SELECT id FROM users WHERE score > 1"))
+		require.JSONEq(t, `[{"id": 1.0}, {"id": 2.5}]`, query(t, db, "SELECT id FROM items"))
+		require.JSONEq(t, `[{"n": 1}, {"n": 2}]`, query(t, db, "SELECT n FROM events"))
+	})
+
+	t.Run("Typed text primary keys", func(t *testing.T) {
+		db := openFixture(t, "keyformat-v0.db")
+
+		require.JSONEq(t, `[{"age": 40}]`, query(t, db, "SELECT age FROM users WHERE id = 'bob'"))
+		require.JSONEq(t, `[{"id": "alice"}, {"id": "bob"}]`, query(t, db, "SELECT id FROM users WHERE id > ''"))
+
+		err := db.Exec(ctx, "INSERT INTO users (id, age) VALUES ('alice', 31)")
+		require.True(t, errors.Is(err, database.ErrDuplicateDocument), err)
+		require.JSONEq(t, `[{"n": 2}]`, query(t, db, "SELECT COUNT(*) AS n FROM users"))
+	})
+
+	t.Run("Document indexes", func(t *testing.T) {
+		db := openFixture(t, "keyformat-v0.db")
+
+		require.JSONEq(t, `[{"id": 1.0}]`, query(t, db, "SELECT id FROM items WHERE attrs = {a: 2, b: 1}"))
+		require.JSONEq(t, `[{"id": 2.5}]`, query(t, db, "SELECT id FROM items WHERE attrs = {a: 1}"))
+		require.JSONEq(t, `[{"plan": "Index(idx_items_attrs) -> ∏(id)"}]`, query(t, db, "EXPLAIN SELECT id FROM items WHERE attrs = {a: 1}"))
+	})
+
+	t.Run("Consistency", func(t *testing.T) {
+		db := openFixture(t, "keyformat-v0.db")
+
+		list, err := db.Verify()
+		require.NoError(t, err)
+		require.Empty(t, list)
+	})
+}
+
+func TestMaxIndexKeySize(t *testing.T) {
+	ctx := context.Background()
+
+	test := func(t *testing.T, db *genji.DB, size int) {
+		err := db.Exec(ctx, "CREATE TABLE test; CREATE INDEX idx_a ON test(a)")
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, "INSERT INTO test (a) VALUES (?)", strings.Repeat("a", size/2))
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, "INSERT INTO test (a) VALUES (?)", strings.Repeat("a", size))
+		require.True(t, errors.Is(err, database.ErrIndexKeyTooLong), "%v", err)
+		require.Contains(t, err.Error(), "field a")
+		require.Contains(t, err.Error(), "idx_a")
+
+		// the document was not inserted
+		d, err := db.QueryDocument(ctx, "SELECT COUNT(*) FROM test")
+		require.NoError(t, err)
+		var count int
+		require.NoError(t, document.Scan(d, &count))
+		require.Equal(t, 1, count)
+
+		// existing values can't be reindexed either
+		err = db.Exec(ctx, "CREATE TABLE foo; INSERT INTO foo (b) VALUES (?); CREATE INDEX idx_b ON foo(b)", strings.Repeat("b", size))
+		require.NoError(t, err)
+		err = db.Exec(ctx, "REINDEX idx_b")
+		require.True(t, errors.Is(err, database.ErrIndexKeyTooLong), "%v", err)
+	}
+
+	t.Run("Engine limit", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "genji")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		db, err := genji.Open(filepath.Join(dir, "test.db"))
+		require.NoError(t, err)
+		defer db.Close()
+
+		test(t, db, 40000)
+	})
+
+	t.Run("Option", func(t *testing.T) {
+		db, err := genji.OpenWithOptions(":memory:", &genji.Options{MaxIndexKeySize: 100})
+		require.NoError(t, err)
+		defer db.Close()
+
+		test(t, db, 100)
+	})
+}
+
 func TestQueryDocument(t *testing.T) {
 	db, err := genji.Open(":memory:")
 	require.NoError(t, err)
@@ -121,3 +347,1357 @@ func TestQueryDocument(t *testing.T) {
 		require.Nil(t, r)
 	})
 }
+
+func TestQueryEach(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, "CREATE TABLE test; INSERT INTO test (a) VALUES (1), (2)")
+	require.NoError(t, err)
+
+	t.Run("Should return one result per statement", func(t *testing.T) {
+		res, err := db.QueryEach(ctx, `
+			SELECT a FROM test;
+			INSERT INTO test (a) VALUES (3), (4);
+			SELECT a FROM test WHERE a > 2
+		`)
+		require.NoError(t, err)
+		defer res.Close()
+
+		var stmts []string
+		var rowsAffected []int64
+		var docs []string
+		for res.Next() {
+			stmts = append(stmts, fmt.Sprintf("%T", res.Statement()))
+			rowsAffected = append(rowsAffected, res.Result().RowsAffected)
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, res.Result())
+			require.NoError(t, err)
+			docs = append(docs, buf.String())
+		}
+		require.NoError(t, res.Err())
+
+		require.Equal(t, []string{"*planner.Tree", "query.InsertStmt", "*planner.Tree"}, stmts)
+		require.Equal(t, []int64{0, 2, 0}, rowsAffected)
+		require.Equal(t, []string{`[{"a": 1}, {"a": 2}]`, `[]`, `[{"a": 3}, {"a": 4}]`}, docs)
+		require.NoError(t, res.Close())
+	})
+
+	t.Run("Should stop on the first error", func(t *testing.T) {
+		res, err := db.QueryEach(ctx, `
+			INSERT INTO test (a) VALUES (5);
+			SELECT * FROM unknown;
+			INSERT INTO test (a) VALUES (6)
+		`)
+		require.NoError(t, err)
+		defer res.Close()
+
+		require.True(t, res.Next())
+		require.Equal(t, int64(1), res.Result().RowsAffected)
+		require.False(t, res.Next())
+		require.Error(t, res.Err())
+		require.False(t, res.Next())
+		require.NoError(t, res.Close())
+
+		// the first statement was committed, the last one was never run
+		d, err := db.QueryDocument(ctx, "SELECT COUNT(*) FROM test WHERE a >= 5")
+		require.NoError(t, err)
+		var count int
+		require.NoError(t, document.Scan(d, &count))
+		require.Equal(t, 1, count)
+	})
+}
+
+func TestPrepare(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, "CREATE TABLE test(a INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+
+	insert, err := db.Prepare(ctx, "INSERT INTO test (a) VALUES (?)")
+	require.NoError(t, err)
+	count, err := db.Prepare(ctx, "SELECT COUNT(*) FROM test WHERE a > ?")
+	require.NoError(t, err)
+
+	countDocs := func(t *testing.T, run func(args ...interface{}) (*query.Result, error)) int {
+		t.Helper()
+
+		res, err := run(0)
+		require.NoError(t, err)
+		defer res.Close()
+
+		d, err := res.First()
+		require.NoError(t, err)
+		var n int
+		require.NoError(t, document.Scan(d, &n))
+		return n
+	}
+
+	t.Run("Should run in its own transaction", func(t *testing.T) {
+		require.NoError(t, insert.Exec(ctx, 1))
+		require.NoError(t, insert.Exec(ctx, 2))
+
+		require.Equal(t, 2, countDocs(t, func(args ...interface{}) (*query.Result, error) {
+			return count.Query(ctx, args...)
+		}))
+	})
+
+	t.Run("Should run within the transaction", func(t *testing.T) {
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		res, err := insert.Run(ctx, tx, 3)
+		require.NoError(t, err)
+		require.NoError(t, res.Close())
+
+		// manual operations share the same transaction
+		tb, err := tx.GetTable("test")
+		require.NoError(t, err)
+		_, err = tb.Insert(document.NewFieldBuffer().Add("a", document.NewIntegerValue(4)))
+		require.NoError(t, err)
+
+		res, err = insert.Run(ctx, tx, 5)
+		require.NoError(t, err)
+		require.NoError(t, res.Close())
+
+		require.Equal(t, 5, countDocs(t, func(args ...interface{}) (*query.Result, error) {
+			return count.Run(ctx, tx, args...)
+		}))
+
+		// the transaction is still open and owned by the caller
+		require.NoError(t, tx.Rollback())
+
+		require.Equal(t, 2, countDocs(t, func(args ...interface{}) (*query.Result, error) {
+			return count.Query(ctx, args...)
+		}))
+	})
+
+	t.Run("Should fail to write in a read-only transaction", func(t *testing.T) {
+		tx, err := db.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = insert.Run(ctx, tx, 6)
+		require.True(t, errors.Is(err, engine.ErrTransactionReadOnly), "%v", err)
+
+		res, err := count.Run(ctx, tx, 1)
+		require.NoError(t, err)
+		require.NoError(t, res.Close())
+	})
+
+	t.Run("Should not keep the plan of a previous run", func(t *testing.T) {
+		sorted, err := db.Prepare(ctx, "SELECT a FROM test ORDER BY a DESC LIMIT ?")
+		require.NoError(t, err)
+
+		countRows := func(limit int) int {
+			res, err := sorted.Query(ctx, limit)
+			require.NoError(t, err)
+			defer res.Close()
+
+			var n int
+			err = res.Iterate(func(d document.Document) error {
+				n++
+				return nil
+			})
+			require.NoError(t, err)
+			return n
+		}
+
+		// the first run sorts only the top document
+		require.Equal(t, 1, countRows(1))
+		require.Equal(t, 2, countRows(20000))
+		require.Equal(t, 1, countRows(1))
+	})
+
+	t.Run("Should fail to begin a transaction", func(t *testing.T) {
+		begin, err := db.Prepare(ctx, "BEGIN")
+		require.NoError(t, err)
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = begin.Run(ctx, tx)
+		require.Error(t, err)
+	})
+}
+
+func TestCaseInsensitiveFields(t *testing.T) {
+	newDB := func(t *testing.T, caseInsensitive bool) *genji.DB {
+		db, err := database.New(memoryengine.NewEngine(), database.Options{
+			Codec:                 msgpack.NewCodec(),
+			CaseInsensitiveFields: caseInsensitive,
+		})
+		require.NoError(t, err)
+
+		err = (&genji.DB{DB: db}).Exec(context.Background(), `
+			CREATE TABLE test (name TEXT NOT NULL);
+			CREATE INDEX idx_test_age ON test (age);
+		`)
+		require.NoError(t, err)
+
+		return &genji.DB{DB: db}
+	}
+
+	query := func(t *testing.T, db *genji.DB, q string) string {
+		st, err := db.Query(context.Background(), q)
+		require.NoError(t, err)
+		defer st.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, st)
+		require.NoError(t, err)
+		return buf.String()
+	}
+
+	t.Run("Case sensitive", func(t *testing.T) {
+		db := newDB(t, false)
+		defer db.Close()
+
+		err := db.Exec(context.Background(), "INSERT INTO test (Name) VALUES ('foo')")
+		require.Error(t, err)
+
+		err = db.Exec(context.Background(), "INSERT INTO test (name, Age) VALUES ('foo', 10.0)")
+		require.NoError(t, err)
+
+		require.JSONEq(t, `[{"name": "foo", "Age": 10.0}]`, query(t, db, "SELECT * FROM test WHERE Age = 10"))
+		require.JSONEq(t, `[]`, query(t, db, "SELECT * FROM test WHERE age = 10"))
+		require.JSONEq(t, `[{"NAME": null}]`, query(t, db, "SELECT NAME FROM test"))
+	})
+
+	t.Run("Case insensitive", func(t *testing.T) {
+		db := newDB(t, true)
+		defer db.Close()
+
+		err := db.Exec(context.Background(), "INSERT INTO test (Age) VALUES (10)")
+		require.Error(t, err)
+
+		err = db.Exec(context.Background(), "INSERT INTO test (Name, AGE) VALUES (10, 10)")
+		require.NoError(t, err)
+
+		// field names are stored as is, constraints are applied regardless of the case
+		require.JSONEq(t, `[{"Name": "10", "AGE": 10}]`, query(t, db, "SELECT * FROM test"))
+		require.JSONEq(t, `[{"name": "10", "age": 10}]`, query(t, db, "SELECT name, age FROM test WHERE age = 10"))
+		require.JSONEq(t, `[{"Name": "10", "AGE": 10}]`, query(t, db, "SELECT * FROM test WHERE aGe > 5 AND NAME = '10'"))
+		require.JSONEq(t, `[]`, query(t, db, "SELECT * FROM test WHERE age = 11"))
+
+		err = db.Exec(context.Background(), "DELETE FROM test WHERE age = 10")
+		require.NoError(t, err)
+		require.JSONEq(t, `[]`, query(t, db, "SELECT * FROM test WHERE age = 10"))
+	})
+}
+
+type capturingLogger struct {
+	events []database.Event
+}
+
+func (l *capturingLogger) Log(e database.Event) {
+	l.events = append(l.events, e)
+}
+
+func TestLogger(t *testing.T) {
+	ctx := context.Background()
+
+	var logger capturingLogger
+	db, err := database.New(memoryengine.NewEngine(), database.Options{
+		Codec:  msgpack.NewCodec(),
+		Logger: &logger,
+	})
+	require.NoError(t, err)
+	gdb := genji.DB{DB: db}
+	defer gdb.Close()
+
+	err = gdb.Exec(ctx, `
+		CREATE TABLE test;
+		CREATE INDEX idx_test_a ON test(a);
+		INSERT INTO test (a, b) VALUES (1, 1), (2, 2), (3, 3);
+	`)
+	require.NoError(t, err)
+
+	// ignore the transaction ids, which depend on the previous queries
+	selectEvents := func(q string) []database.Event {
+		logger.events = nil
+
+		res, err := gdb.Query(ctx, q)
+		require.NoError(t, err)
+		_, err = res.Count()
+		require.NoError(t, err)
+		require.NoError(t, res.Close())
+
+		events := logger.events
+		for i := range events {
+			events[i].TxID = 0
+		}
+		return events
+	}
+
+	t.Run("Without index", func(t *testing.T) {
+		q := "SELECT * FROM test WHERE b = 2"
+		require.Equal(t, []database.Event{
+			{Kind: database.EventQueryParsed, Query: q},
+			{Kind: database.EventTxBegin, Writable: true},
+			{Kind: database.EventPlanChosen, Plan: "Table(test) -> σ(cond: b = 2) -> ∏(*)"},
+			{Kind: database.EventRowsScanned, TableName: "test", Rows: 3},
+			{Kind: database.EventTxCommit},
+		}, selectEvents(q))
+	})
+
+	t.Run("With index", func(t *testing.T) {
+		q := "SELECT * FROM test WHERE a = 2"
+		require.Equal(t, []database.Event{
+			{Kind: database.EventQueryParsed, Query: q},
+			{Kind: database.EventTxBegin, Writable: true},
+			{Kind: database.EventPlanChosen, Plan: "Index(idx_test_a) -> ∏(*)"},
+			{Kind: database.EventIndexUsed, TableName: "test", IndexName: "idx_test_a"},
+			{Kind: database.EventRowsScanned, TableName: "test", IndexName: "idx_test_a", Rows: 1},
+			{Kind: database.EventTxCommit},
+		}, selectEvents(q))
+	})
+
+	t.Run("Read-only transaction", func(t *testing.T) {
+		tx, err := gdb.Begin(false)
+		require.NoError(t, err)
+		logger.events = nil
+		require.NoError(t, tx.Rollback())
+		// rolling back a terminated transaction doesn't emit any event
+		require.NoError(t, tx.Rollback())
+
+		require.Equal(t, []database.Event{
+			{Kind: database.EventTxRollback, TxID: tx.ID()},
+		}, logger.events)
+	})
+}
+
+func TestStatementMemoryLimit(t *testing.T) {
+	ctx := context.Background()
+
+	var logger capturingLogger
+	db, err := database.New(memoryengine.NewEngine(), database.Options{
+		Codec:                msgpack.NewCodec(),
+		Logger:               &logger,
+		StatementMemoryLimit: 50 * 1024,
+	})
+	require.NoError(t, err)
+	gdb := genji.DB{DB: db}
+	defer gdb.Close()
+
+	// more than 100KB of data, mostly in the field b
+	const n = 1000
+	err = gdb.Exec(ctx, "CREATE TABLE test")
+	require.NoError(t, err)
+	tx, err := gdb.Begin(true)
+	require.NoError(t, err)
+	for i := 0; i < n; i++ {
+		err = tx.Exec(ctx, "INSERT INTO test (a, b) VALUES (?, ?)", i, strings.Repeat("x", 100))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit())
+
+	// the limit can be exceeded when the statement is run or when its result is read
+	count := func(q string, readOnly bool) (int, error) {
+		tx, err := gdb.Begin(!readOnly)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		res, err := tx.Query(ctx, q)
+		if err != nil {
+			return 0, err
+		}
+		defer res.Close()
+
+		return res.Count()
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		readOnly bool
+		fails    bool
+	}{
+		{"Stream", "SELECT * FROM test", true, false},
+		{"Sort", "SELECT * FROM test ORDER BY a DESC", true, true},
+		{"Sort with spilling", "SELECT * FROM test ORDER BY a DESC", false, false},
+		{"Sort projected field", "SELECT a FROM test ORDER BY a DESC", true, false},
+		{"Top N", "SELECT * FROM test ORDER BY a DESC LIMIT 10", true, false},
+		{"CTE", "WITH t AS (SELECT * FROM test) SELECT * FROM t", false, true},
+		{"Group by", "SELECT a, COUNT(*) FROM test GROUP BY a", false, true},
+		{"Group by few groups", "SELECT COUNT(*) FROM test GROUP BY b", false, false},
+		{"Count distinct", "SELECT COUNT(DISTINCT a, b) FROM test", false, true},
+		{"Count few distinct", "SELECT COUNT(DISTINCT b) FROM test", false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logger.events = nil
+
+			c, err := count(test.query, test.readOnly)
+			if !test.fails {
+				require.NoError(t, err)
+				return
+			}
+
+			require.True(t, errors.Is(err, database.ErrResultTooLarge), err)
+			require.Zero(t, c)
+
+			// the table is not read entirely once the limit is exceeded
+			var scanned bool
+			for _, e := range logger.events {
+				if e.Kind == database.EventRowsScanned {
+					scanned = true
+					require.Less(t, e.Rows, n)
+				}
+			}
+			require.True(t, scanned)
+		})
+	}
+
+	// the memory is given back at the end of each statement
+	for i := 0; i < 3; i++ {
+		c, err := count("SELECT a FROM test ORDER BY a DESC", true)
+		require.NoError(t, err)
+		require.Equal(t, n, c)
+	}
+}
+
+func TestStatementTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.OpenWithOptions(":memory:", &genji.Options{StatementTimeout: time.Nanosecond})
+	require.NoError(t, err)
+	defer db.Close()
+
+	const n = 10000
+	err = db.Exec(ctx, "CREATE TABLE test")
+	require.NoError(t, err)
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+	for i := 0; i < n; i++ {
+		err = tx.Exec(ctx, "INSERT INTO test (a) VALUES (?)", i)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit())
+
+	// the timeout can expire when the statement is run or when its result is read
+	count := func(q string, args ...interface{}) (int, error) {
+		res, err := db.Query(ctx, q, args...)
+		if err != nil {
+			return 0, err
+		}
+		defer res.Close()
+
+		return res.Count()
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		for _, q := range []string{
+			"SELECT * FROM test",
+			"SELECT * FROM test WHERE a < 0",
+			"SELECT * FROM test ORDER BY a DESC",
+			"WITH t AS (SELECT * FROM test) SELECT * FROM t",
+		} {
+			_, err := count(q)
+			require.True(t, errors.Is(err, context.DeadlineExceeded), err)
+		}
+
+		res, err := db.QueryEach(ctx, "SELECT 1; SELECT * FROM test")
+		require.NoError(t, err)
+		defer res.Close()
+		require.True(t, res.Next())
+		require.True(t, res.Next())
+		_, err = res.Result().Count()
+		require.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	})
+
+	t.Run("Override", func(t *testing.T) {
+		c, err := count("SELECT * FROM test WHERE a >= ?", 0, query.Timeout(time.Minute))
+		require.NoError(t, err)
+		require.Equal(t, n, c)
+
+		// a timeout that is not positive disables the default one
+		c, err = count("SELECT * FROM test", query.Timeout(0))
+		require.NoError(t, err)
+		require.Equal(t, n, c)
+	})
+}
+
+func TestSortSpilling(t *testing.T) {
+	ctx := context.Background()
+
+	ng := memoryengine.NewEngine()
+	db, err := database.New(ng, database.Options{
+		Codec:                msgpack.NewCodec(),
+		StatementMemoryLimit: 2 * 1024,
+	})
+	require.NoError(t, err)
+	gdb := genji.DB{DB: db}
+	defer gdb.Close()
+
+	ref, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer ref.Close()
+
+	// values of every type, with duplicates, inserted in a random order
+	values := []string{`null`, `true`, `false`, `'foo'`, `'bar'`, `[1, 2]`, `[1]`, `{a: 1}`, `{a: 0, b: 1}`, `1.5`}
+	for i := 0; i < 100; i++ {
+		values = append(values, fmt.Sprintf("%d", (i*37)%50), fmt.Sprintf("'%c'", 'a'+i%26))
+	}
+	for _, d := range []*genji.DB{&gdb, ref} {
+		err = d.Exec(ctx, "CREATE TABLE test")
+		require.NoError(t, err)
+		for i, v := range values {
+			err = d.Exec(ctx, fmt.Sprintf("INSERT INTO test (a, b, c) VALUES (%s, %d, '%s')", v, i, strings.Repeat("x", 50)))
+			require.NoError(t, err)
+		}
+	}
+
+	t.Run("Temporary stores are dropped", func(t *testing.T) {
+		// this is the first statement that spills documents, which are
+		// written to stores named after a counter.
+		// the transaction is committed once the result is closed.
+		res, err := gdb.Query(ctx, "SELECT * FROM test ORDER BY a")
+		require.NoError(t, err)
+		n, err := res.Count()
+		require.NoError(t, err)
+		require.Equal(t, len(values), n)
+		require.NoError(t, res.Close())
+
+		tx, err := ng.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = tx.GetStore([]byte("__genji_temp_1"))
+		require.Equal(t, engine.ErrStoreNotFound, err)
+	})
+
+	t.Run("Paginated", func(t *testing.T) {
+		page := func(readOnly bool, q string, token query.PageToken) ([]int, query.PageToken) {
+			tx, err := gdb.Begin(!readOnly)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			res, err := tx.Query(ctx, q, token)
+			require.NoError(t, err)
+			defer res.Close()
+
+			var ids []int
+			err = res.Iterate(func(d document.Document) error {
+				var b int
+				err := document.Scan(d, &b)
+				ids = append(ids, b)
+				return err
+			})
+			require.NoError(t, err)
+
+			next, err := res.NextToken()
+			require.NoError(t, err)
+			return ids, query.PageToken(next)
+		}
+
+		// the first page is sorted in memory, the rest of the table is spilled
+		first, token := page(true, "SELECT b FROM test ORDER BY a LIMIT 10", "")
+		require.Len(t, first, 10)
+		rest, token := page(false, "SELECT b FROM test ORDER BY a", token)
+		require.Len(t, rest, len(values)-10)
+
+		ids := append(first, rest...)
+		sort.Ints(ids)
+		for i := range ids {
+			require.Equal(t, i, ids[i])
+		}
+
+		// the last token doesn't return any document
+		last, _ := page(false, "SELECT b FROM test ORDER BY a", token)
+		require.Empty(t, last)
+	})
+
+	query := func(d *genji.DB, readOnly bool, q string) (string, error) {
+		tx, err := d.Begin(!readOnly)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		res, err := tx.Query(ctx, q)
+		require.NoError(t, err)
+		defer res.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, res)
+		return buf.String(), err
+	}
+
+	// the sort key is the only field compared, since documents
+	// with the same key can be returned in any order
+	for _, q := range []string{
+		"SELECT a FROM test ORDER BY a",
+		"SELECT a FROM test ORDER BY a DESC",
+		"SELECT a, c FROM test WHERE b % 2 = 0 ORDER BY a",
+		"SELECT a AS z FROM test ORDER BY b DESC",
+	} {
+		t.Run(q, func(t *testing.T) {
+			// the documents don't fit in memory
+			_, err := query(&gdb, true, q)
+			require.True(t, errors.Is(err, database.ErrResultTooLarge), err)
+
+			got, err := query(&gdb, false, q)
+			require.NoError(t, err)
+			expected, err := query(ref, true, q)
+			require.NoError(t, err)
+			require.JSONEq(t, expected, got)
+		})
+	}
+
+}
+
+func TestUpdateRetry(t *testing.T) {
+	db, err := genji.OpenWithOptions(":memory:", &genji.Options{MaxUpdateAttempts: 3})
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(context.Background(), "CREATE TABLE test")
+	require.NoError(t, err)
+
+	count := func() int {
+		d, err := db.QueryDocument(context.Background(), "SELECT COUNT(*) FROM test")
+		require.NoError(t, err)
+		var n int
+		err = document.Scan(d, &n)
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("Conflict then success", func(t *testing.T) {
+		var attempts int
+		err := db.Update(func(tx *genji.Tx) error {
+			attempts++
+			err := tx.Exec(context.Background(), "INSERT INTO test (a) VALUES (?)", attempts)
+			if err != nil {
+				return err
+			}
+
+			if attempts == 1 {
+				return fmt.Errorf("commit failed: %w", engine.ErrConflict)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, attempts)
+
+		// the first attempt was rolled back
+		d, err := db.QueryDocument(context.Background(), "SELECT a FROM test")
+		require.NoError(t, err)
+		var a int
+		err = document.Scan(d, &a)
+		require.NoError(t, err)
+		require.Equal(t, 2, a)
+		require.Equal(t, 1, count())
+	})
+
+	t.Run("Non retryable error", func(t *testing.T) {
+		var attempts int
+		errFoo := errors.New("foo")
+		err := db.Update(func(tx *genji.Tx) error {
+			attempts++
+			return errFoo
+		})
+		require.Equal(t, errFoo, err)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("Max attempts", func(t *testing.T) {
+		var attempts int
+		err := db.Update(func(tx *genji.Tx) error {
+			attempts++
+			err := tx.Exec(context.Background(), "INSERT INTO test (a) VALUES (?)", attempts)
+			if err != nil {
+				return err
+			}
+			return engine.ErrConflict
+		})
+		require.Equal(t, engine.ErrConflict, err)
+		require.Equal(t, 3, attempts)
+		require.Equal(t, 1, count())
+	})
+}
+
+func TestEncryptedFields(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.OpenWithOptions(":memory:", &genji.Options{EncryptionKey: bytes.Repeat([]byte("k"), 16)})
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test (name TEXT, ssn TEXT ENCRYPTED NOT NULL);
+		INSERT INTO test (name, ssn) VALUES ('a', '111-11-1111'), ('b', '222-22-2222');
+		UPDATE test SET ssn = '333-33-3333' WHERE name = 'b';
+	`)
+	require.NoError(t, err)
+
+	d, err := db.QueryDocument(ctx, "SELECT name FROM test WHERE ssn = '333-33-3333'")
+	require.NoError(t, err)
+	var name string
+	err = document.Scan(d, &name)
+	require.NoError(t, err)
+	require.Equal(t, "b", name)
+
+	err = db.Exec(ctx, "CREATE INDEX idx_ssn ON test(ssn)")
+	require.Error(t, err)
+}
+
+func TestForeignKeys(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, onDelete string) *genji.DB {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `
+			CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+			CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER, FOREIGN KEY (user_id) REFERENCES users(id) `+onDelete+`);
+			INSERT INTO users (id, name) VALUES (1, 'a'), (2, 'b');
+			INSERT INTO posts (id, user_id) VALUES (10, 1), (11, 1), (12, 2), (13, NULL);
+		`)
+		require.NoError(t, err)
+		return db
+	}
+
+	count := func(t *testing.T, db *genji.DB, q string) int {
+		st, err := db.Query(ctx, q)
+		require.NoError(t, err)
+		defer st.Close()
+
+		n, err := st.Count()
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("insert", func(t *testing.T) {
+		db := setup(t, "")
+		defer db.Close()
+
+		err := db.Exec(ctx, `INSERT INTO posts (id, user_id) VALUES (14, 3)`)
+		require.True(t, errors.Is(err, database.ErrForeignKeyViolation))
+
+		err = db.Exec(ctx, `UPDATE posts SET user_id = 3 WHERE id = 10`)
+		require.True(t, errors.Is(err, database.ErrForeignKeyViolation))
+
+		// values are converted to the type of the primary key
+		err = db.Exec(ctx, `INSERT INTO posts (id, user_id) VALUES (14, 2.0)`)
+		require.NoError(t, err)
+	})
+
+	t.Run("on delete restrict", func(t *testing.T) {
+		db := setup(t, "")
+		defer db.Close()
+
+		err := db.Exec(ctx, `DELETE FROM users WHERE id = 1`)
+		require.True(t, errors.Is(err, database.ErrForeignKeyViolation))
+
+		err = db.Exec(ctx, `DELETE FROM posts WHERE user_id = 1; DELETE FROM users WHERE id = 1`)
+		require.NoError(t, err)
+		require.Equal(t, 1, count(t, db, `SELECT * FROM users`))
+	})
+
+	t.Run("on delete cascade", func(t *testing.T) {
+		db := setup(t, "ON DELETE CASCADE")
+		defer db.Close()
+
+		err := db.Exec(ctx, `DELETE FROM users WHERE id = 1`)
+		require.NoError(t, err)
+		require.Equal(t, 1, count(t, db, `SELECT * FROM users`))
+		require.Equal(t, 2, count(t, db, `SELECT * FROM posts`))
+		require.Equal(t, 0, count(t, db, `SELECT * FROM posts WHERE user_id = 1`))
+	})
+
+	t.Run("self reference", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE nodes (id INTEGER PRIMARY KEY, parent INTEGER, FOREIGN KEY (parent) REFERENCES nodes ON DELETE CASCADE);
+			INSERT INTO nodes (id, parent) VALUES (1, 1), (2, 1), (3, 2);
+			UPDATE nodes SET parent = 3 WHERE id = 1;
+		`)
+		require.NoError(t, err)
+
+		// deleting any node of the cycle deletes all of them
+		err = db.Exec(ctx, `DELETE FROM nodes WHERE id = 2`)
+		require.NoError(t, err)
+		require.Equal(t, 0, count(t, db, `SELECT * FROM nodes`))
+	})
+
+	t.Run("referenced table", func(t *testing.T) {
+		db := setup(t, "")
+		defer db.Close()
+
+		err := db.Exec(ctx, `DROP TABLE users`)
+		require.Error(t, err)
+		err = db.Exec(ctx, `ALTER TABLE users RENAME TO people`)
+		require.Error(t, err)
+		err = db.Exec(ctx, `TRUNCATE TABLE users`)
+		require.Error(t, err)
+
+		err = db.Exec(ctx, `DROP TABLE posts; DROP TABLE users`)
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid reference", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `CREATE TABLE posts (user_id INTEGER, FOREIGN KEY (user_id) REFERENCES users)`)
+		require.True(t, errors.Is(err, database.ErrTableNotFound))
+
+		err = db.Exec(ctx, `
+			CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+			CREATE TABLE logs;
+		`)
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `CREATE TABLE posts (FOREIGN KEY (user_id) REFERENCES users(name))`)
+		require.Error(t, err)
+		err = db.Exec(ctx, `CREATE TABLE posts (FOREIGN KEY (log_id) REFERENCES logs)`)
+		require.Error(t, err)
+	})
+}
+
+func TestConstraintErrors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		query     string
+		violation error
+		path      string
+		value     document.Value
+	}{
+		{"not null/missing", `INSERT INTO posts (id) VALUES (20)`, database.ErrNotNullViolation, "title", document.Value{}},
+		{"not null/null", `INSERT INTO posts (id, title) VALUES (20, NULL)`, database.ErrNotNullViolation, "title", document.NewNullValue()},
+		{"not null/primary key", `INSERT INTO posts (title) VALUES ('a')`, database.ErrNotNullViolation, "id", document.Value{}},
+		{"not null/update", `UPDATE posts SET title = NULL`, database.ErrNotNullViolation, "title", document.NewNullValue()},
+		{"type mismatch", `INSERT INTO posts (id, title, user_id) VALUES (20, 'a', 'foo')`, database.ErrTypeMismatch, "user_id", document.NewTextValue("foo")},
+		{"unique/primary key", `INSERT INTO posts (id, title) VALUES (10, 'a')`, database.ErrUniqueViolation, "id", document.NewIntegerValue(10)},
+		{"unique/index", `INSERT INTO posts (id, title, slug) VALUES (20, 'a', 'hello')`, database.ErrUniqueViolation, "slug", document.NewTextValue("hello")},
+		{"unique/update", `UPDATE posts SET slug = 'hello' WHERE id = 11`, database.ErrUniqueViolation, "slug", document.NewTextValue("hello")},
+		{"foreign key/insert", `INSERT INTO posts (id, title, user_id) VALUES (20, 'a', 3)`, database.ErrForeignKeyViolation, "user_id", document.NewIntegerValue(3)},
+		{"foreign key/delete", `DELETE FROM users WHERE id = 1`, database.ErrForeignKeyViolation, "user_id", document.NewIntegerValue(1)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE users (id INTEGER PRIMARY KEY);
+				CREATE TABLE posts (
+					id INTEGER PRIMARY KEY, title TEXT NOT NULL, user_id INTEGER,
+					FOREIGN KEY (user_id) REFERENCES users(id)
+				);
+				CREATE UNIQUE INDEX idx_posts_slug ON posts(slug);
+				INSERT INTO users (id) VALUES (1);
+				INSERT INTO posts (id, title, user_id, slug) VALUES (10, 'a', 1, 'hello'), (11, 'b', NULL, 'world');
+			`)
+			require.NoError(t, err)
+
+			err = db.Exec(ctx, test.query)
+			require.True(t, errors.Is(err, test.violation), err)
+
+			var cerr *database.ConstraintError
+			require.True(t, errors.As(err, &cerr), err)
+			require.Equal(t, test.path, cerr.Path.String())
+			require.Equal(t, test.value, cerr.Value)
+
+			// unique violations are also reported as duplicate documents
+			require.Equal(t, test.violation == database.ErrUniqueViolation, errors.Is(err, database.ErrDuplicateDocument))
+		})
+	}
+}
+
+func TestRegisterFunction(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, `CREATE TABLE test; INSERT INTO test (a, b) VALUES ('foo', 1), ('bar', 2), ('bazz', 3)`)
+	require.NoError(t, err)
+
+	err = db.RegisterFunction("strlen", func(args ...document.Value) (document.Value, error) {
+		if len(args) != 1 {
+			return document.Value{}, errors.New("strlen() takes 1 argument")
+		}
+		if args[0].Type != document.TextValue {
+			return document.NewNullValue(), nil
+		}
+		return document.NewIntegerValue(int64(len(args[0].V.(string)))), nil
+	})
+	require.NoError(t, err)
+
+	query := func(t *testing.T, q string, args ...interface{}) (string, error) {
+		t.Helper()
+
+		res, err := db.Query(ctx, q, args...)
+		if err != nil {
+			return "", err
+		}
+		defer res.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, res)
+		return buf.String(), err
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"Projection", "SELECT a, strlen(a) AS l FROM test", `[{"a": "foo", "l": 3}, {"a": "bar", "l": 3}, {"a": "bazz", "l": 4}]`},
+		{"Where", "SELECT a FROM test WHERE STRLEN(a) > ?", `[{"a": "bazz"}]`},
+		{"Order by", "SELECT a FROM test ORDER BY strlen(a) DESC LIMIT 1", `[{"a": "bazz"}]`},
+		{"Nested", "SELECT strlen(a) + b AS n FROM test WHERE strlen(b) IS NULL", `[{"n": 4}, {"n": 5}, {"n": 7}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := query(t, test.query, 3)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, got)
+		})
+	}
+
+	t.Run("Should return the errors of the function", func(t *testing.T) {
+		_, err := query(t, "SELECT strlen(a, b) FROM test")
+		require.EqualError(t, err, "strlen() takes 1 argument")
+	})
+
+	t.Run("Should fail if the function is unknown", func(t *testing.T) {
+		_, err := query(t, "SELECT unknown(a) FROM test")
+		require.EqualError(t, err, `no such function: "unknown"`)
+	})
+
+	t.Run("Should not replace builtin functions", func(t *testing.T) {
+		err := db.RegisterFunction("ABS", func(args ...document.Value) (document.Value, error) {
+			return document.NewNullValue(), nil
+		})
+		require.Error(t, err)
+	})
+}
+
+// concatAggregate concatenates the text values of a group, separated by commas.
+type concatAggregate struct {
+	values []string
+}
+
+func (c *concatAggregate) Init() {
+	c.values = nil
+}
+
+func (c *concatAggregate) Step(args ...document.Value) error {
+	if len(args) != 1 {
+		return errors.New("concat() takes 1 argument")
+	}
+	if args[0].Type == document.TextValue {
+		c.values = append(c.values, args[0].V.(string))
+	}
+	return nil
+}
+
+func (c *concatAggregate) Result() (document.Value, error) {
+	return document.NewTextValue(strings.Join(c.values, ",")), nil
+}
+
+func TestRegisterAggregate(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, `CREATE TABLE test(id INTEGER PRIMARY KEY);
+		INSERT INTO test (id, a, b) VALUES (1, 'foo', 1), (2, 'bar', 2), (3, 'foo', 1), (4, 'baz', 1), (5, NULL, 2)`)
+	require.NoError(t, err)
+
+	err = db.RegisterAggregate("concat", func() database.Aggregate {
+		return new(concatAggregate)
+	})
+	require.NoError(t, err)
+
+	query := func(t *testing.T, q string) (string, error) {
+		t.Helper()
+
+		res, err := db.Query(ctx, q)
+		if err != nil {
+			return "", err
+		}
+		defer res.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, res)
+		return buf.String(), err
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"No group", "SELECT concat(a) FROM test", `[{"concat(a)": "foo,bar,foo,baz"}]`},
+		{"Group by", "SELECT CONCAT(a) AS s FROM test GROUP BY b", `[{"s": "foo,foo,baz"}, {"s": "bar"}]`},
+		{"Distinct", "SELECT concat(DISTINCT a) AS s FROM test GROUP BY b", `[{"s": "foo,baz"}, {"s": "bar"}]`},
+		{"With builtin aggregates", "SELECT COUNT(*) AS n, concat(a) AS s FROM test WHERE b = 1", `[{"n": 3, "s": "foo,foo,baz"}]`},
+		{"No documents", "SELECT concat(a) AS s FROM test WHERE b > 10", `[{"s": ""}]`},
+		{"No groups", "SELECT concat(a) AS s FROM test WHERE b > 10 GROUP BY b", `[]`},
+		{"Filter", "SELECT concat(a) FILTER (WHERE b = 1) AS s, concat(a) AS t FROM test", `[{"s": "foo,foo,baz", "t": "foo,bar,foo,baz"}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := query(t, test.query)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, got)
+		})
+	}
+
+	t.Run("Should return the errors of the aggregate", func(t *testing.T) {
+		_, err := query(t, "SELECT concat(a, b) FROM test")
+		require.EqualError(t, err, "concat() takes 1 argument")
+	})
+
+	t.Run("Should fail outside of the projection", func(t *testing.T) {
+		_, err := query(t, "SELECT a FROM test WHERE concat(a) = 'foo'")
+		require.Error(t, err)
+	})
+
+	t.Run("Should fail to call a builtin function with DISTINCT", func(t *testing.T) {
+		_, err := query(t, "SELECT SUM(DISTINCT b) FROM test")
+		require.Error(t, err)
+	})
+
+	t.Run("Should fail to filter a function that is not an aggregate", func(t *testing.T) {
+		err := db.RegisterFunction("twice", func(args ...document.Value) (document.Value, error) {
+			return args[0], nil
+		})
+		require.NoError(t, err)
+
+		_, err = query(t, "SELECT twice(b) FILTER (WHERE b = 1) FROM test")
+		require.Error(t, err)
+	})
+}
+
+func TestRegisterPartitionSet(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE events_2021(id INTEGER PRIMARY KEY, type TEXT);
+		CREATE TABLE events_2022(id INTEGER PRIMARY KEY, type TEXT);
+		INSERT INTO events_2021 (id, type) VALUES (1, 'click'), (2, 'view');
+		INSERT INTO events_2022 (id, type) VALUES (1, 'view'), (3, 'click');
+	`)
+	require.NoError(t, err)
+
+	err = db.RegisterPartitionSet("events", "events_2021", "events_2022")
+	require.NoError(t, err)
+
+	query := func(t *testing.T, q string) (string, error) {
+		t.Helper()
+
+		res, err := db.Query(ctx, q)
+		if err != nil {
+			return "", err
+		}
+		defer res.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, res)
+		return buf.String(), err
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"All", "SELECT * FROM events", `[{"id": 1, "type": "click"}, {"id": 2, "type": "view"}, {"id": 1, "type": "view"}, {"id": 3, "type": "click"}]`},
+		{"Where", "SELECT id FROM events WHERE type = 'click'", `[{"id": 1}, {"id": 3}]`},
+		{"Order by", "SELECT id FROM events ORDER BY id DESC", `[{"id": 3}, {"id": 2}, {"id": 1}, {"id": 1}]`},
+		{"Aggregate", "SELECT COUNT(*) AS n FROM events", `[{"n": 4}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := query(t, test.query)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, got)
+		})
+	}
+
+	t.Run("Should fetch documents by key", func(t *testing.T) {
+		err := db.View(func(tx *genji.Tx) error {
+			tb, err := tx.GetTable("events")
+			require.NoError(t, err)
+
+			var keys [][]byte
+			err = tb.Iterate(func(d document.Document) error {
+				keys = append(keys, append([]byte(nil), d.(document.Keyer).Key()...))
+				return nil
+			})
+			require.NoError(t, err)
+			require.Len(t, keys, 4)
+
+			d, err := tb.GetDocument(keys[2])
+			require.NoError(t, err)
+			v, err := d.GetByField("type")
+			require.NoError(t, err)
+			require.Equal(t, document.NewTextValue("view"), v)
+
+			// the store of the set returns the same keys
+			var reversed [][]byte
+			it := tb.Store.NewIterator(engine.IteratorConfig{Reverse: true})
+			defer it.Close()
+			for it.Seek(nil); it.Valid(); it.Next() {
+				reversed = append([][]byte{append([]byte(nil), it.Item().Key()...)}, reversed...)
+			}
+			require.Equal(t, keys, reversed)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Should be read-only", func(t *testing.T) {
+		err := db.Exec(ctx, "INSERT INTO events (id, type) VALUES (4, 'view')")
+		require.Error(t, err)
+		err = db.Exec(ctx, "DELETE FROM events")
+		require.Error(t, err)
+		err = db.Exec(ctx, "CREATE TABLE events")
+		require.Error(t, err)
+	})
+
+	t.Run("Should fail if the partitions have different schemas", func(t *testing.T) {
+		err := db.Exec(ctx, "CREATE TABLE other(id TEXT PRIMARY KEY)")
+		require.NoError(t, err)
+		err = db.RegisterPartitionSet("mixed", "events_2021", "other")
+		require.NoError(t, err)
+
+		_, err = query(t, "SELECT * FROM mixed")
+		require.Error(t, err)
+
+		err = db.RegisterPartitionSet("other", "events_2021")
+		require.True(t, errors.Is(err, database.ErrTableAlreadyExists), err)
+	})
+}
+
+func TestVirtualTables(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL DEFAULT 'anonymous', age INT);
+		CREATE TABLE user_roles (user_id INTEGER, role TEXT);
+		CREATE TABLE posts;
+		CREATE UNIQUE INDEX idx_users_name ON users (name);
+		CREATE INDEX idx_posts_user_id ON posts (user_id);
+		INSERT INTO users (id) VALUES (1);
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT * FROM __tables__", `[
+			{"name": "posts", "primary_key": null, "strict": false},
+			{"name": "user_roles", "primary_key": null, "strict": false},
+			{"name": "users", "primary_key": "id", "strict": false}
+		]`},
+		{"SELECT name FROM __tables__ WHERE primary_key IS NULL AND name >= 'user'", `[{"name": "user_roles"}]`},
+		{"SELECT * FROM __indexes__", `[
+			{"name": "idx_posts_user_id", "table_name": "posts", "path": "user_id", "unique": false, "type": null},
+			{"name": "idx_users_name", "table_name": "users", "path": "name", "unique": true, "type": "text"}
+		]`},
+		{"SELECT name FROM __indexes__ WHERE `unique`", `[{"name": "idx_users_name"}]`},
+		{"SELECT * FROM __columns__ WHERE table_name = 'users'", `[
+			{"table_name": "users", "name": "id", "type": "integer", "primary_key": true, "not_null": true, "default": null},
+			{"table_name": "users", "name": "name", "type": "text", "primary_key": false, "not_null": true, "default": "\"anonymous\""},
+			{"table_name": "users", "name": "age", "type": "integer", "primary_key": false, "not_null": false, "default": null}
+		]`},
+		{"SELECT COUNT(*) AS n FROM __columns__ GROUP BY table_name", `[{"n": 2}, {"n": 3}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			st, err := db.Query(ctx, test.query)
+			require.NoError(t, err)
+			defer st.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+		})
+	}
+
+	t.Run("read-only", func(t *testing.T) {
+		for _, q := range []string{
+			`INSERT INTO __tables__ (name) VALUES ('foo')`,
+			`DELETE FROM __indexes__`,
+			`UPDATE __columns__ SET name = 'foo'`,
+			`CREATE INDEX idx_foo ON __tables__ (name)`,
+			`CREATE TABLE __columns__`,
+		} {
+			require.Error(t, db.Exec(ctx, q), q)
+		}
+	})
+
+	t.Run("uncommitted tables", func(t *testing.T) {
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.Exec(ctx, `CREATE TABLE foo`)
+		require.NoError(t, err)
+
+		d, err := tx.QueryDocument(ctx, `SELECT name FROM __tables__ WHERE name = 'foo'`)
+		require.NoError(t, err)
+		var name string
+		err = document.Scan(d, &name)
+		require.NoError(t, err)
+		require.Equal(t, "foo", name)
+	})
+}
+
+func TestVisibility(t *testing.T) {
+	ctx := context.Background()
+
+	// documents with a deleted field set to true are soft-deleted
+	var calls int
+	db, err := genji.OpenWithOptions(":memory:", &genji.Options{
+		Visibility: func(tableName string, key []byte, d document.Document) (bool, error) {
+			calls++
+			if tableName != "test" {
+				return true, nil
+			}
+
+			v, err := d.GetByField("deleted")
+			if err == document.ErrFieldNotFound {
+				return true, nil
+			}
+			if err != nil {
+				return false, err
+			}
+
+			deleted, err := v.IsTruthy()
+			return !deleted, err
+		},
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test;
+		CREATE TABLE other;
+		INSERT INTO test (a) VALUES (1);
+		INSERT INTO test (a, deleted) VALUES (2, true), (3, false);
+		INSERT INTO test (a, deleted) VALUES (4, true);
+		INSERT INTO other (a, deleted) VALUES (5, true);
+	`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT * FROM test", `[{"a": 3, "deleted": false}, {"a": 1}]`},
+		{"SELECT a FROM test", `[{"a": 3}, {"a": 1}]`},
+		{"SELECT a FROM test WHERE a > 0 ORDER BY a DESC", `[{"a": 3}, {"a": 1}]`},
+		{"SELECT COUNT(*) AS n FROM test", `[{"n": 2}]`},
+		{"SELECT a FROM other", `[{"a": 5}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			calls = 0
+
+			st, err := db.Query(ctx, test.query)
+			require.NoError(t, err)
+			defer st.Close()
+
+			if strings.Contains(test.query, "ORDER BY") {
+				querytest.AssertOrderedResultsEqual(t, st, test.expected)
+			} else {
+				querytest.AssertResultsEqual(t, st, test.expected)
+			}
+			require.NotZero(t, calls)
+		})
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, "CREATE TABLE typed (name TEXT, age INTEGER); CREATE TABLE schemaless")
+	require.NoError(t, err)
+
+	count := func(table string) int {
+		d, err := db.QueryDocument(ctx, "SELECT COUNT(*) FROM "+table)
+		require.NoError(t, err)
+		var n int
+		require.NoError(t, document.Scan(d, &n))
+		return n
+	}
+
+	n, err := db.LoadCSV("typed", strings.NewReader("name,age\n\"Doe, John\",42\n10,7\n"))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	d, err := db.QueryDocument(ctx, "SELECT name, age FROM typed WHERE age = 7")
+	require.NoError(t, err)
+	data, err := document.MarshalJSON(d)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name": "10", "age": 7}`, string(data))
+
+	n, err = db.LoadCSV("schemaless", strings.NewReader("a,b,c\n1,2.5,true\n"))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	d, err = db.QueryDocument(ctx, "SELECT a, b, c FROM schemaless")
+	require.NoError(t, err)
+	data, err = document.MarshalJSON(d)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"a": 1, "b": 2.5, "c": true}`, string(data))
+
+	// nothing is loaded if a record fails
+	n, err = db.LoadCSV("typed", strings.NewReader("name,age\nfoo,1\nbar,x\n"))
+	var cerr *database.CSVError
+	require.True(t, errors.As(err, &cerr), err)
+	require.Equal(t, 3, cerr.Line)
+	require.Equal(t, 0, n)
+	require.Equal(t, 2, count("typed"))
+
+	_, err = db.LoadCSV("unknown", strings.NewReader("a\n1\n"))
+	require.True(t, errors.Is(err, database.ErrTableNotFound), err)
+}
@@ -3,6 +3,7 @@ package document
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"sort"
 
 	"github.com/buger/jsonparser"
@@ -86,7 +87,7 @@ func (vb ValueBuffer) Iterate(fn func(i int, value Value) error) error {
 // GetByIndex returns a value set at the given index. If the index is out of range it returns an error.
 func (vb ValueBuffer) GetByIndex(i int) (Value, error) {
 	if i >= len(vb) {
-		return Value{}, ErrFieldNotFound
+		return Value{}, ErrValueNotFound
 	}
 
 	return vb[i], nil
@@ -97,6 +98,27 @@ func (vb ValueBuffer) Append(v Value) ValueBuffer {
 	return append(vb, v)
 }
 
+// AppendTyped appends v to the buffer if its type is equal to expected.
+// Otherwise it returns an error and leaves the buffer unchanged.
+func (vb *ValueBuffer) AppendTyped(v Value, expected ValueType) error {
+	if v.Type != expected {
+		return fmt.Errorf("cannot append %s value to array: expected %s", v.Type, expected)
+	}
+
+	*vb = append(*vb, v)
+	return nil
+}
+
+// Types returns the type of each value of the buffer.
+func (vb ValueBuffer) Types() []ValueType {
+	types := make([]ValueType, len(vb))
+	for i, v := range vb {
+		types[i] = v.Type
+	}
+
+	return types
+}
+
 // ScanArray copies all the values of a to the buffer.
 func (vb *ValueBuffer) ScanArray(a Array) error {
 	return a.Iterate(func(i int, v Value) error {
@@ -203,9 +225,10 @@ var typeSortOrder = map[ValueType]int{
 	NullValue:     0,
 	BoolValue:     1,
 	DoubleValue:   2,
-	TextValue:     3,
-	ArrayValue:    4,
-	DocumentValue: 5,
+	PointValue:    3,
+	TextValue:     4,
+	ArrayValue:    5,
+	DocumentValue: 6,
 }
 
 func (a *sortableArray) Less(i, j int) (ok bool) {
@@ -242,6 +265,7 @@ func (a *sortableArray) Less(i, j int) (ok bool) {
 //   - NULL
 //   - Booleans
 //   - Numbers
+//   - Points
 //   - Text / Blob
 //   - Arrays
 //   - Documents
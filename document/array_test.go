@@ -54,6 +54,46 @@ func TestSortArray(t *testing.T) {
 	}
 }
 
+func TestValueBufferAppendTyped(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    Value
+		expected ValueType
+		fails    bool
+	}{
+		{"integer", NewIntegerValue(10), IntegerValue, false},
+		{"text", NewTextValue("foo"), TextValue, false},
+		{"null", NewNullValue(), NullValue, false},
+		{"integer as double", NewIntegerValue(10), DoubleValue, true},
+		{"text as blob", NewTextValue("foo"), BlobValue, true},
+		{"null as text", NewNullValue(), TextValue, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vb := NewValueBuffer(NewBoolValue(true))
+
+			err := vb.AppendTyped(test.value, test.expected)
+			if test.fails {
+				require.Error(t, err)
+				require.Equal(t, []ValueType{BoolValue}, vb.Types())
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, []ValueType{BoolValue, test.expected}, vb.Types())
+			require.Equal(t, test.value, vb[1])
+		})
+	}
+}
+
+func TestValueBufferTypes(t *testing.T) {
+	require.Empty(t, NewValueBuffer().Types())
+
+	vb := NewValueBuffer(NewIntegerValue(1), NewTextValue("a"), NewArrayValue(NewValueBuffer()))
+	require.Equal(t, []ValueType{IntegerValue, TextValue, ArrayValue}, vb.Types())
+}
+
 func TestValueBufferCopy(t *testing.T) {
 	tests := []struct {
 		name string
@@ -0,0 +1,52 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Collation determines how text values are compared and ordered.
+type Collation uint8
+
+// List of supported collations.
+const (
+	// BinaryCollation compares text values byte per byte.
+	// It is the default collation.
+	BinaryCollation Collation = iota
+	// NoCaseCollation compares text values regardless of their case.
+	NoCaseCollation
+)
+
+var collationNames = map[Collation]string{
+	BinaryCollation: "BINARY",
+	NoCaseCollation: "NOCASE",
+}
+
+func (c Collation) String() string {
+	if name, ok := collationNames[c]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Collation(%d)", c)
+}
+
+// ParseCollation returns the collation whose name is equal to name, ignoring case.
+func ParseCollation(name string) (Collation, error) {
+	for c, n := range collationNames {
+		if strings.EqualFold(n, name) {
+			return c, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown collation %q", name)
+}
+
+// Collate returns the value used to compare v under the collation c.
+// Values that are not text values are returned unchanged.
+func (c Collation) Collate(v Value) Value {
+	if c == NoCaseCollation && v.Type == TextValue {
+		return NewTextValue(strings.ToLower(v.V.(string)))
+	}
+
+	return v
+}
@@ -81,6 +81,10 @@ func compare(op operator, l, r Value, compareDifferentTypes bool) (bool, error)
 	case l.Type == TextValue && r.Type == TextValue:
 		return compareTexts(op, l.V.(string), r.V.(string)), nil
 
+	// compare points together
+	case l.Type == PointValue && r.Type == PointValue:
+		return comparePoints(op, l.V.(Point), r.V.(Point)), nil
+
 	// compare blobs together
 	case r.Type == BlobValue && l.Type == BlobValue:
 		return compareBlobs(op, l.V.([]byte), r.V.([]byte)), nil
@@ -178,6 +182,19 @@ func compareBlobs(op operator, l, r []byte) bool {
 	return false
 }
 
+// comparePoints compares the latitudes of the points first, then their longitudes.
+func comparePoints(op operator, l, r Point) bool {
+	if op == operatorEq {
+		return l == r
+	}
+
+	if l.Lat != r.Lat {
+		return compareFloats(op, l.Lat, r.Lat)
+	}
+
+	return compareFloats(op, l.Lng, r.Lng)
+}
+
 func compareIntegers(op operator, l, r int64) bool {
 	switch op {
 	case operatorEq:
@@ -207,25 +224,24 @@ func compareNumbers(op operator, l, r Value) (bool, error) {
 		return false, err
 	}
 
-	af := l.V.(float64)
-	bf := r.V.(float64)
-
-	var ok bool
+	return compareFloats(op, l.V.(float64), r.V.(float64)), nil
+}
 
+func compareFloats(op operator, l, r float64) bool {
 	switch op {
 	case operatorEq:
-		ok = af == bf
+		return l == r
 	case operatorGt:
-		ok = af > bf
+		return l > r
 	case operatorGte:
-		ok = af >= bf
+		return l >= r
 	case operatorLt:
-		ok = af < bf
+		return l < r
 	case operatorLte:
-		ok = af <= bf
+		return l <= r
 	}
 
-	return ok, nil
+	return false
 }
 
 func compareArrays(op operator, l Array, r Array) (bool, error) {
@@ -385,3 +401,154 @@ func compareDocuments(op operator, l, r Document) (bool, error) {
 		}
 	}
 }
+
+// Compare returns an integer comparing v and other, which is 0 if v == other,
+// -1 if v < other and +1 if v > other.
+// Unlike the other comparison methods, it defines a total ordering over all values:
+// values of different types are ordered by type, in the order of their ValueType,
+// except for integers and doubles which are compared as numbers.
+// Arrays are compared element by element, and documents are compared by
+// their (field, value) pairs, sorted by field name.
+// In both cases, if one is a prefix of the other, the shortest comes first.
+func (v Value) Compare(other Value) (int, error) {
+	if v.Type.IsNumber() && other.Type.IsNumber() {
+		if v.Type == IntegerValue && other.Type == IntegerValue {
+			return compareInt64(v.V.(int64), other.V.(int64)), nil
+		}
+
+		return compareNumbersOrdering(v, other)
+	}
+
+	if v.Type != other.Type {
+		if v.Type < other.Type {
+			return -1, nil
+		}
+		return 1, nil
+	}
+
+	switch v.Type {
+	case BoolValue:
+		a, b := v.V.(bool), other.V.(bool)
+		switch {
+		case a == b:
+			return 0, nil
+		case b:
+			return -1, nil
+		}
+		return 1, nil
+	case TextValue:
+		return strings.Compare(v.V.(string), other.V.(string)), nil
+	case BlobValue:
+		return bytes.Compare(v.V.([]byte), other.V.([]byte)), nil
+	case PointValue:
+		l, r := v.V.(Point), other.V.(Point)
+		if cmp := compareFloat64(l.Lat, r.Lat); cmp != 0 {
+			return cmp, nil
+		}
+		return compareFloat64(l.Lng, r.Lng), nil
+	case ArrayValue:
+		return compareArraysOrdering(v.V.(Array), other.V.(Array))
+	case DocumentValue:
+		return compareDocumentsOrdering(v.V.(Document), other.V.(Document))
+	}
+
+	// null values
+	return 0, nil
+}
+
+func compareInt64(l, r int64) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+
+	return 0
+}
+
+func compareFloat64(l, r float64) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+
+	return 0
+}
+
+func compareNumbersOrdering(l, r Value) (int, error) {
+	l, err := l.CastAsDouble()
+	if err != nil {
+		return 0, err
+	}
+	r, err = r.CastAsDouble()
+	if err != nil {
+		return 0, err
+	}
+
+	return compareFloat64(l.V.(float64), r.V.(float64)), nil
+}
+
+func compareArraysOrdering(l, r Array) (int, error) {
+	ll, err := ArrayLength(l)
+	if err != nil {
+		return 0, err
+	}
+	rl, err := ArrayLength(r)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < ll && i < rl; i++ {
+		lv, err := l.GetByIndex(i)
+		if err != nil {
+			return 0, err
+		}
+		rv, err := r.GetByIndex(i)
+		if err != nil {
+			return 0, err
+		}
+
+		cmp, err := lv.Compare(rv)
+		if err != nil || cmp != 0 {
+			return cmp, err
+		}
+	}
+
+	return compareInt64(int64(ll), int64(rl)), nil
+}
+
+func compareDocumentsOrdering(l, r Document) (int, error) {
+	lf, err := Fields(l)
+	if err != nil {
+		return 0, err
+	}
+	rf, err := Fields(r)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(lf) && i < len(rf); i++ {
+		if cmp := strings.Compare(lf[i], rf[i]); cmp != 0 {
+			return cmp, nil
+		}
+
+		lv, err := l.GetByField(lf[i])
+		if err != nil {
+			return 0, err
+		}
+		rv, err := r.GetByField(rf[i])
+		if err != nil {
+			return 0, err
+		}
+
+		cmp, err := lv.Compare(rv)
+		if err != nil || cmp != 0 {
+			return cmp, err
+		}
+	}
+
+	return compareInt64(int64(len(lf)), int64(len(rf))), nil
+}
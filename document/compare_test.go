@@ -249,3 +249,64 @@ func TestCompare(t *testing.T) {
 		})
 	}
 }
+
+func TestValueCompare(t *testing.T) {
+	jsonToValue := func(t testing.TB, x string) document.Value {
+		var fb document.FieldBuffer
+		err := json.Unmarshal([]byte(`{"v": `+x+`}`), &fb)
+		require.NoError(t, err)
+
+		v, err := fb.GetByField("v")
+		require.NoError(t, err)
+		return v
+	}
+
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		// scalars
+		{`null`, `null`, 0},
+		{`null`, `false`, -1},
+		{`true`, `false`, 1},
+		{`1`, `1.0`, 0},
+		{`2`, `1.5`, 1},
+		{`10`, `"a"`, -1},
+		{`"b"`, `"a"`, 1},
+
+		// arrays
+		{`[]`, `[]`, 0},
+		{`[1, 2]`, `[1, 2]`, 0},
+		{`[1, 2]`, `[1, 3]`, -1},
+		{`[1, 2]`, `[1]`, 1},
+		{`[]`, `[1]`, -1},
+		{`[1, "a"]`, `[1, 2]`, 1},
+		{`[[1, 2]]`, `[[1, 1]]`, 1},
+		{`"z"`, `[]`, -1},
+
+		// documents
+		{`{}`, `{}`, 0},
+		{`{"a": 1, "b": 2}`, `{"b": 2, "a": 1}`, 0},
+		{`{"a": 1}`, `{"a": 2}`, -1},
+		{`{"a": 1}`, `{"b": 0}`, -1},
+		{`{"a": 1}`, `{"a": 1, "b": 0}`, -1},
+		{`{"a": [1, 2]}`, `{"a": [1]}`, 1},
+		{`{"a": {"b": 1}}`, `{"a": {"b": 0}}`, 1},
+		{`[1]`, `{}`, -1},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%s", test.a, test.b), func(t *testing.T) {
+			a, b := jsonToValue(t, test.a), jsonToValue(t, test.b)
+
+			cmp, err := a.Compare(b)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, cmp)
+
+			// the ordering must be antisymmetric
+			cmp, err = b.Compare(a)
+			require.NoError(t, err)
+			require.Equal(t, -test.expected, cmp)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 )
@@ -31,6 +32,100 @@ func NewFromMap(m interface{}) (Document, error) {
 	return mapDocument(M), nil
 }
 
+// FromMap converts m into a document, which is the reverse operation of ToMap.
+// Unlike NewFromMap, all the values are converted immediately and stored in memory:
+// nested maps become documents, slices other than []byte become arrays
+// and fields are sorted by name.
+// Other values are converted like NewValue does, ints to integers, floats to doubles,
+// strings to texts, bools to booleans and []byte to blobs.
+// If a value can't be converted, for example a channel or a function,
+// it returns an *ErrUnsupportedType.
+func FromMap(m map[string]interface{}) (Document, error) {
+	fields := make([]string, 0, len(m))
+	for f := range m {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+
+	fb := NewFieldBuffer()
+	for _, f := range fields {
+		v, err := fromInterface(m[f])
+		if err != nil {
+			return nil, err
+		}
+
+		fb.Add(f, v)
+	}
+
+	return fb, nil
+}
+
+func fromInterface(x interface{}) (Value, error) {
+	if m, ok := x.(map[string]interface{}); ok {
+		d, err := FromMap(m)
+		if err != nil {
+			return Value{}, err
+		}
+		return NewDocumentValue(d), nil
+	}
+
+	ref := reflect.ValueOf(x)
+	switch ref.Kind() {
+	case reflect.Slice:
+		if ref.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		if ref.IsNil() {
+			return NewNullValue(), nil
+		}
+		fallthrough
+	case reflect.Array:
+		vb := NewValueBuffer()
+		for i := 0; i < ref.Len(); i++ {
+			v, err := fromInterface(ref.Index(i).Interface())
+			if err != nil {
+				return Value{}, err
+			}
+			vb = vb.Append(v)
+		}
+		return NewArrayValue(vb), nil
+	case reflect.Map:
+		if ref.Type().Key().Kind() != reflect.String {
+			return Value{}, &ErrUnsupportedType{x, "map key must be a string"}
+		}
+
+		m := make(map[string]interface{}, ref.Len())
+		it := ref.MapRange()
+		for it.Next() {
+			m[it.Key().String()] = it.Value().Interface()
+		}
+		return fromInterface(m)
+	}
+
+	v, err := NewValue(x)
+	if err != nil {
+		return Value{}, err
+	}
+
+	// documents and arrays created by NewValue, from structs or pointers,
+	// are converted lazily: copy them to store them in memory
+	switch v.Type {
+	case DocumentValue:
+		var fb FieldBuffer
+		err = fb.Copy(v.V.(Document))
+		v = NewDocumentValue(&fb)
+	case ArrayValue:
+		var vb ValueBuffer
+		err = vb.Copy(v.V.(Array))
+		v = NewArrayValue(vb)
+	}
+	if err != nil {
+		return Value{}, err
+	}
+
+	return v, nil
+}
+
 type mapDocument reflect.Value
 
 var _ Document = (*mapDocument)(nil)
@@ -165,10 +260,14 @@ func (s structDocument) MarshalJSON() ([]byte, error) {
 func NewValue(x interface{}) (Value, error) {
 	// Attempt exact matches first:
 	switch v := x.(type) {
+	case Value:
+		return v, nil
 	case time.Duration:
 		return NewIntegerValue(v.Nanoseconds()), nil
 	case time.Time:
 		return NewTextValue(v.Format(time.RFC3339Nano)), nil
+	case Point:
+		return NewPointValue(v), nil
 	case nil:
 		return NewNullValue(), nil
 	case Document:
@@ -4,6 +4,7 @@ package document
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"sort"
 	"strconv"
 	"strings"
@@ -75,6 +76,74 @@ func Fields(d Document) ([]string, error) {
 	return fields, nil
 }
 
+// ToMap converts the document into a map of natural Go types, which is convenient
+// to consume documents whose fields are not known in advance.
+// Values are converted as follows:
+//
+//	null     -> nil
+//	bool     -> bool
+//	integer  -> int64
+//	double   -> float64
+//	point    -> Point
+//	text     -> string
+//	blob     -> []byte
+//	array    -> []interface{}
+//	document -> map[string]interface{}
+//
+// Blobs are copied, so the map remains valid once d is modified or released.
+func ToMap(d Document) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+
+	err := d.Iterate(func(f string, v Value) error {
+		x, err := toInterface(v)
+		if err != nil {
+			return err
+		}
+
+		m[f] = x
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func toSlice(a Array) ([]interface{}, error) {
+	s := []interface{}{}
+
+	err := a.Iterate(func(i int, v Value) error {
+		x, err := toInterface(v)
+		if err != nil {
+			return err
+		}
+
+		s = append(s, x)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func toInterface(v Value) (interface{}, error) {
+	switch v.Type {
+	case NullValue:
+		return nil, nil
+	case BlobValue:
+		return append([]byte{}, v.V.([]byte)...), nil
+	case ArrayValue:
+		return toSlice(v.V.(Array))
+	case DocumentValue:
+		return ToMap(v.V.(Document))
+	}
+
+	return v.V, nil
+}
+
 // FieldBuffer stores a group of fields in memory. It implements the Document interface.
 type FieldBuffer struct {
 	fields []fieldValue
@@ -154,10 +223,14 @@ func (fb *FieldBuffer) setFieldValue(field string, reqValue Value) error {
 }
 
 // setValueAtPath deep replaces or creates a field
-// at the given path
+// at the given path. Array elements must already exist.
 func setValueAtPath(v Value, p ValuePath, newValue Value) (Value, error) {
 	switch v.Type {
 	case DocumentValue:
+		if p[0].FieldName == "" {
+			return v, fmt.Errorf("cannot use index %d on a document", p[0].ArrayIndex)
+		}
+
 		var buf FieldBuffer
 		err := buf.ScanDocument(v.V.(Document))
 		if err != nil {
@@ -182,6 +255,10 @@ func setValueAtPath(v Value, p ValuePath, newValue Value) (Value, error) {
 		err = buf.setFieldValue(p[0].FieldName, va)
 		return NewDocumentValue(&buf), err
 	case ArrayValue:
+		if p[0].FieldName != "" {
+			return v, nil
+		}
+
 		var vb ValueBuffer
 		err := vb.ScanArray(v.V.(Array))
 		if err != nil {
@@ -189,6 +266,9 @@ func setValueAtPath(v Value, p ValuePath, newValue Value) (Value, error) {
 		}
 
 		va, err := vb.GetByIndex(p[0].ArrayIndex)
+		if err == ErrValueNotFound {
+			return v, fmt.Errorf("array index %d out of range", p[0].ArrayIndex)
+		}
 		if err != nil {
 			return v, err
 		}
@@ -199,6 +279,10 @@ func setValueAtPath(v Value, p ValuePath, newValue Value) (Value, error) {
 		}
 
 		va, err = setValueAtPath(va, p[1:], newValue)
+		if err != nil {
+			return v, err
+		}
+
 		err = vb.Replace(p[0].ArrayIndex, va)
 		return NewArrayValue(&vb), err
 	}
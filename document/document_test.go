@@ -108,10 +108,12 @@ func TestFieldBuffer(t *testing.T) {
 			{"number field", `{"a": {"0": [1, 2, 3]}}`, "a.`0`[0]", document.NewIntegerValue(6), `{"a": {"0": [6, 2, 3]}}`, false},
 			{"document in array", `{"a": [{"b":"foo"}, 2, 3]}`, `a[0].b`, document.NewTextValue("bar"), `{"a": [{"b": "bar"}, 2, 3]}`, false},
 			// with errors or request ignored doc unchanged
-			{"field not found", `{"a": {"b": [1, 2, 3]}}`, `a.b.c`, document.NewIntegerValue(1), `{"a": {"b": [1, 2, 3]}}`, false},
+			{"field not found", `{"a": {"b": [1, 2, 3]}}`, `a.b.c`, document.NewIntegerValue(10), `{"a": {"b": [1, 2, 3]}}`, false},
+			{"nested index out of range", `{"a": [[1], [2]]}`, `a[1][3]`, document.NewIntegerValue(10), ``, true},
 			{"unknown path", `{"a": {"b": [1, 2, 3]}}`, `a.e.f`, document.NewIntegerValue(1), ``, true},
 			{"index out of range", `{"a": {"b": [1, 2, 3]}}`, `a.b[1000]`, document.NewIntegerValue(1), ``, true},
 			{"document not array", `{"a": {"b": "foo"}}`, `a[0].b`, document.NewTextValue("bar"), ``, true},
+			{"index on document", `{"a": [{"b": 1}]}`, `a[0][1]`, document.NewIntegerValue(10), ``, true},
 		}
 
 		for _, tt := range tests {
@@ -284,6 +286,146 @@ func TestNewFromMap(t *testing.T) {
 	})
 }
 
+func TestToMap(t *testing.T) {
+	blob := []byte("blob")
+	doc := document.NewFieldBuffer().
+		Add("a", document.NewNullValue()).
+		Add("b", document.NewBoolValue(true)).
+		Add("c", document.NewIntegerValue(10)).
+		Add("d", document.NewDoubleValue(10.5)).
+		Add("e", document.NewPointValue(document.Point{Lat: 1, Lng: 2})).
+		Add("f", document.NewTextValue("foo")).
+		Add("g", document.NewBlobValue(blob)).
+		Add("h", document.NewArrayValue(document.NewValueBuffer().
+			Append(document.NewIntegerValue(1)).
+			Append(document.NewArrayValue(document.NewValueBuffer().
+				Append(document.NewTextValue("bar")))).
+			Append(document.NewDocumentValue(document.NewFieldBuffer().
+				Add("i", document.NewDoubleValue(1.5)))))).
+		Add("j", document.NewArrayValue(document.NewValueBuffer())).
+		Add("k", document.NewDocumentValue(document.NewFieldBuffer().
+			Add("l", document.NewTextValue("baz")).
+			Add("m", document.NewDocumentValue(document.NewFieldBuffer().
+				Add("n", document.NewArrayValue(document.NewValueBuffer().
+					Append(document.NewBoolValue(false))))))))
+
+	m, err := document.ToMap(doc)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"a": nil,
+		"b": true,
+		"c": int64(10),
+		"d": 10.5,
+		"e": document.Point{Lat: 1, Lng: 2},
+		"f": "foo",
+		"g": []byte("blob"),
+		"h": []interface{}{
+			int64(1),
+			[]interface{}{"bar"},
+			map[string]interface{}{"i": 1.5},
+		},
+		"j": []interface{}{},
+		"k": map[string]interface{}{
+			"l": "baz",
+			"m": map[string]interface{}{
+				"n": []interface{}{false},
+			},
+		},
+	}, m)
+
+	// blobs are copied
+	blob[0] = 'x'
+	require.Equal(t, []byte("blob"), m["g"])
+}
+
+func TestFromMap(t *testing.T) {
+	m := map[string]interface{}{
+		"a": nil,
+		"b": true,
+		"c": int64(10),
+		"d": 10.5,
+		"e": document.Point{Lat: 1, Lng: 2},
+		"f": "foo",
+		"g": []byte("blob"),
+		"h": []interface{}{
+			int64(1),
+			[]interface{}{"bar"},
+			map[string]interface{}{"i": 1.5},
+		},
+		"j": []interface{}{},
+		"k": map[string]interface{}{
+			"l": "baz",
+			"m": map[string]interface{}{
+				"n": []interface{}{false},
+			},
+		},
+	}
+
+	t.Run("Round trip", func(t *testing.T) {
+		d, err := document.FromMap(m)
+		require.NoError(t, err)
+
+		fields, err := document.Fields(d)
+		require.NoError(t, err)
+		var got []string
+		err = d.Iterate(func(f string, _ document.Value) error {
+			got = append(got, f)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, fields, got)
+
+		res, err := document.ToMap(d)
+		require.NoError(t, err)
+		require.Equal(t, m, res)
+	})
+
+	t.Run("Types", func(t *testing.T) {
+		type text string
+
+		d, err := document.FromMap(map[string]interface{}{
+			"a": 10,
+			"b": uint8(10),
+			"c": float32(1.5),
+			"d": text("foo"),
+			"e": []int{1, 2},
+			"f": map[string]int{"g": 1},
+		})
+		require.NoError(t, err)
+
+		res, err := document.ToMap(d)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{
+			"a": int64(10),
+			"b": int64(10),
+			"c": 1.5,
+			"d": "foo",
+			"e": []interface{}{int64(1), int64(2)},
+			"f": map[string]interface{}{"g": int64(1)},
+		}, res)
+	})
+
+	t.Run("Unsupported types", func(t *testing.T) {
+		tests := []struct {
+			name string
+			m    map[string]interface{}
+		}{
+			{"channel", map[string]interface{}{"a": make(chan int)}},
+			{"function", map[string]interface{}{"a": func() {}}},
+			{"nested", map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{make(chan int)}}}},
+			{"nested typed", map[string]interface{}{"a": []func(){func() {}}}},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				_, err := document.FromMap(test.m)
+				var e *document.ErrUnsupportedType
+				require.True(t, errors.As(err, &e), "got %v", err)
+			})
+		}
+	})
+}
+
 func TestNewFromStruct(t *testing.T) {
 	type group struct {
 		A int
@@ -665,3 +807,52 @@ func BenchmarkDocumentIterate(b *testing.B) {
 		}
 	})
 }
+
+func TestCaseInsensitiveDocument(t *testing.T) {
+	data := `{"Name": "foo", "name": "bar", "Address": {"City": "Lyon"}, "Tags": [{"Label": "a"}]}`
+
+	tests := []struct {
+		name     string
+		path     string
+		result   string
+		resolved string
+		fails    bool
+	}{
+		{"exact match", `Name`, `"foo"`, `Name`, false},
+		{"exact match preferred", `name`, `"bar"`, `name`, false},
+		{"different case", `NAME`, `"foo"`, `Name`, false},
+		{"nested doc", `address.city`, `"Lyon"`, `Address.City`, false},
+		{"nested array", `tags[0].label`, `"a"`, `Tags[0].Label`, false},
+		{"unknown field", `address.country`, ``, `Address.country`, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf document.FieldBuffer
+
+			err := json.Unmarshal([]byte(data), &buf)
+			require.NoError(t, err)
+			p, err := parser.ParsePath(test.path)
+			require.NoError(t, err)
+
+			// paths are case sensitive by default
+			if test.path != test.resolved {
+				_, err = p.GetValue(&buf)
+				require.Equal(t, document.ErrFieldNotFound, err)
+			}
+
+			require.Equal(t, test.resolved, p.ResolveFold(&buf).String())
+
+			v, err := p.GetValue(document.NewCaseInsensitiveDocument(&buf))
+			if test.fails {
+				require.Equal(t, document.ErrFieldNotFound, err)
+				return
+			}
+
+			require.NoError(t, err)
+			res, err := json.Marshal(v)
+			require.NoError(t, err)
+			require.JSONEq(t, test.result, string(res))
+		})
+	}
+}
@@ -12,6 +12,10 @@ import (
 
 // A Codec is able to create encoders and decoders for a specific encoding format.
 type Codec interface {
+	// Name returns the identifier of the encoding format.
+	// It is stored by the database to ensure that documents are always
+	// decoded with the codec that encoded them.
+	Name() string
 	NewEncoder(io.Writer) Encoder
 	// NewDocument returns a document without decoding its given binary representation.
 	// The returned document should ideally support random-access, i.e. decoding one path
@@ -19,6 +23,15 @@ type Codec interface {
 	NewDocument([]byte) document.Document
 }
 
+// A FieldsDecoder is a codec able to decode a subset of the fields of a document
+// in a single pass. It allows queries that only read a few fields of wide documents
+// to avoid decoding the other fields.
+type FieldsDecoder interface {
+	// DecodeFields decodes the top-level fields targeted by the given paths.
+	// Fields that are not found in the document are ignored.
+	DecodeFields(data []byte, paths ...document.ValuePath) (document.Document, error)
+}
+
 // An Encoder encodes one document to the underlying writer.
 type Encoder interface {
 	EncodeDocument(d document.Document) error
@@ -19,6 +19,11 @@ func NewCodec() Codec {
 	return Codec{}
 }
 
+// Name implements the encoding.Codec interface.
+func (c Codec) Name() string {
+	return "custom"
+}
+
 // NewEncoder implements the encoding.Codec interface.
 func (c Codec) NewEncoder(w io.Writer) encoding.Encoder {
 	return NewEncoder(w)
@@ -127,6 +132,8 @@ func EncodeValue(v document.Value) ([]byte, error) {
 		return encodeInt64(v.V.(int64)), nil
 	case document.DoubleValue:
 		key.AppendFloat64(nil, v.V.(float64))
+	case document.PointValue:
+		return key.AppendPoint(nil, v.V.(document.Point)), nil
 	case document.NullValue:
 		return nil, nil
 	}
@@ -147,7 +154,12 @@ type EncodedDocument []byte
 
 // GetByField decodes the selected field.
 func (e EncodedDocument) GetByField(field string) (document.Value, error) {
-	return decodeValueFromDocument(e, field)
+	v, err := decodeValueFromDocument(e, field)
+	if err == document.ErrValueNotFound {
+		return v, document.ErrFieldNotFound
+	}
+
+	return v, err
 }
 
 // Iterate decodes each fields one by one and passes them to fn until the end of the document
@@ -328,6 +340,12 @@ func DecodeValue(t document.ValueType, data []byte) (document.Value, error) {
 			return document.Value{}, err
 		}
 		return document.NewDoubleValue(x), nil
+	case document.PointValue:
+		p, err := key.DecodePoint(data)
+		if err != nil {
+			return document.Value{}, err
+		}
+		return document.NewPointValue(p), nil
 	case document.NullValue:
 		return document.NewNullValue(), nil
 	}
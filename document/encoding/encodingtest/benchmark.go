@@ -21,6 +21,8 @@ func BenchmarkCodec(b *testing.B, codecBuilder func() encoding.Codec) {
 		{"Codec/Decode", benchmarkDecodeDocument},
 		{"Codec/Document/GetByField", benchmarkDocumentGetByField},
 		{"Codec/Document/Iterate", benchmarkDocumentIterate},
+		{"Codec/Wide/DecodeAll", benchmarkWideDocumentDecodeAll},
+		{"Codec/Wide/DecodeFields", benchmarkWideDocumentDecodeFields},
 		{"ComparedWithJSON/Encode", benchmarkEncodeDocumentJSON},
 		{"ComparedWithJSON/Decode", benchmarkDecodeDocumentJSON},
 	}
@@ -138,3 +140,51 @@ func benchmarkDecodeDocumentJSON(b *testing.B, codecBuilder func() encoding.Code
 		json.Unmarshal(d, &mm)
 	}
 }
+
+// encodeWideDocument encodes a document with 100 fields,
+// each one containing a text value.
+func encodeWideDocument(b *testing.B, codec encoding.Codec) []byte {
+	var fb document.FieldBuffer
+
+	for i := int64(0); i < 100; i++ {
+		fb.Add(fmt.Sprintf("name-%d", i), document.NewTextValue(fmt.Sprintf("value-%d", i)))
+	}
+
+	var buf bytes.Buffer
+	err := codec.NewEncoder(&buf).EncodeDocument(&fb)
+	require.NoError(b, err)
+
+	return buf.Bytes()
+}
+
+// benchmarkWideDocumentDecodeAll decodes an entire wide document, to be compared with
+// benchmarkWideDocumentDecodeFields which only decodes two of its fields.
+func benchmarkWideDocumentDecodeAll(b *testing.B, codecBuilder func() encoding.Codec) {
+	codec := codecBuilder()
+	data := encodeWideDocument(b, codec)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var fb document.FieldBuffer
+		fb.Copy(codec.NewDocument(data))
+	}
+}
+
+func benchmarkWideDocumentDecodeFields(b *testing.B, codecBuilder func() encoding.Codec) {
+	codec := codecBuilder()
+	fd, ok := codec.(encoding.FieldsDecoder)
+	if !ok {
+		b.Skip("codec doesn't implement encoding.FieldsDecoder")
+	}
+	data := encodeWideDocument(b, codec)
+
+	paths := []document.ValuePath{
+		{document.ValuePathFragment{FieldName: "name-10"}},
+		{document.ValuePathFragment{FieldName: "name-50"}},
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fd.DecodeFields(data, paths...)
+	}
+}
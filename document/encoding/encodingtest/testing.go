@@ -19,6 +19,7 @@ func TestCodec(t *testing.T, codecBuilder func() encoding.Codec) {
 		{"EncodeDecode", testEncodeDecode},
 		{"NewDocument", testDecodeDocument},
 		{"Array/GetByIndex", testArrayGetByIndex},
+		{"DecodeFields", testDecodeFields},
 	}
 
 	for _, test := range tests {
@@ -74,6 +75,13 @@ func testEncodeDecode(t *testing.T, codecBuilder func() encoding.Codec) {
 				Add("array", document.NewArrayValue(complexArray)),
 			`{"age": 10, "name": "john", "address": {"city": "Ajaccio", "country": "France"}, "array": [-40, true, "hello", {"city": "Ajaccio", "country": "France"}, [11]]}`,
 		},
+		{
+			"Point",
+			document.NewFieldBuffer().
+				Add("location", document.NewPointValue(document.Point{Lat: 41.9192, Lng: 8.7386})).
+				Add("name", document.NewTextValue("john")),
+			`{"location": {"lat": 41.9192, "lng": 8.7386}, "name": "john"}`,
+		},
 	}
 
 	var buf bytes.Buffer
@@ -156,6 +164,8 @@ func testDecodeDocument(t *testing.T, codecBuilder func() encoding.Codec) {
 	actual, err := document.MarshalJSON(document.NewFieldBuffer().Add("address", v))
 	require.NoError(t, err)
 	require.JSONEq(t, string(expected), string(actual))
+	_, err = ec.GetByField("unknown")
+	require.Equal(t, document.ErrFieldNotFound, err)
 
 	var i int
 	err = ec.Iterate(func(f string, v document.Value) error {
@@ -177,3 +187,40 @@ func testDecodeDocument(t *testing.T, codecBuilder func() encoding.Codec) {
 	require.NoError(t, err)
 	require.Equal(t, 3, i)
 }
+
+func testDecodeFields(t *testing.T, codecBuilder func() encoding.Codec) {
+	codec := codecBuilder()
+	fd, ok := codec.(encoding.FieldsDecoder)
+	if !ok {
+		t.Skip("codec doesn't implement encoding.FieldsDecoder")
+	}
+
+	doc, err := document.NewFromJSON([]byte(`{"age": 10, "name": "john", "address": {"city": "Ajaccio", "country": "France"}, "tags": ["a", "b"]}`))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = codec.NewEncoder(&buf).EncodeDocument(doc)
+	require.NoError(t, err)
+
+	path := func(fragments ...document.ValuePathFragment) document.ValuePath {
+		return document.ValuePath(fragments)
+	}
+
+	d, err := fd.DecodeFields(buf.Bytes(),
+		path(document.ValuePathFragment{FieldName: "address"}, document.ValuePathFragment{FieldName: "city"}),
+		path(document.ValuePathFragment{FieldName: "age"}),
+		path(document.ValuePathFragment{FieldName: "age"}),
+		path(document.ValuePathFragment{FieldName: "unknown"}),
+	)
+	require.NoError(t, err)
+
+	data, err := document.MarshalJSON(d)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"age": 10, "address": {"city": "Ajaccio", "country": "France"}}`, string(data))
+
+	d, err = fd.DecodeFields(buf.Bytes())
+	require.NoError(t, err)
+	data, err = document.MarshalJSON(d)
+	require.NoError(t, err)
+	require.JSONEq(t, `{}`, string(data))
+}
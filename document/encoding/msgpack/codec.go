@@ -6,10 +6,15 @@ import (
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
+	"github.com/genjidb/genji/key"
 	"github.com/vmihailenco/msgpack/v5"
 	"github.com/vmihailenco/msgpack/v5/codes"
 )
 
+// pointExtType is the MessagePack extension type used to encode points.
+// Points are encoded on 16 bytes, using key.AppendPoint.
+const pointExtType int8 = 1
+
 // A Codec is a MessagePack implementation of an encoding.Codec.
 type Codec struct{}
 
@@ -18,6 +23,11 @@ func NewCodec() Codec {
 	return Codec{}
 }
 
+// Name implements the encoding.Codec interface.
+func (c Codec) Name() string {
+	return "msgpack"
+}
+
 // NewEncoder implements the encoding.Codec interface.
 func (c Codec) NewEncoder(w io.Writer) encoding.Encoder {
 	return NewEncoder(w)
@@ -28,10 +38,16 @@ func (c Codec) NewDocument(data []byte) document.Document {
 	return EncodedDocument(data)
 }
 
+// DecodeFields implements the encoding.FieldsDecoder interface.
+func (c Codec) DecodeFields(data []byte, paths ...document.ValuePath) (document.Document, error) {
+	return DecodeFields(data, paths...)
+}
+
 // Encoder encodes Genji documents and values
 // in MessagePack.
 type Encoder struct {
-	enc *msgpack.Encoder
+	enc       *msgpack.Encoder
+	canonical bool
 }
 
 // NewEncoder creates an Encoder that writes in the given writer.
@@ -45,6 +61,14 @@ func NewEncoder(w io.Writer) *Encoder {
 	}
 }
 
+// SetCanonical causes the encoder to write the fields of documents,
+// including nested ones, sorted by name instead of in the order they were added.
+// Documents with the same fields and values are then always encoded the same way.
+func (e *Encoder) SetCanonical(on bool) *Encoder {
+	e.canonical = on
+	return e
+}
+
 // EncodeDocument encodes d as a MessagePack map.
 func (e *Encoder) EncodeDocument(d document.Document) error {
 	var dlen int
@@ -64,6 +88,29 @@ func (e *Encoder) EncodeDocument(d document.Document) error {
 		return err
 	}
 
+	if e.canonical {
+		fields, err := document.Fields(d)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range fields {
+			v, err := d.GetByField(f)
+			if err != nil {
+				return err
+			}
+
+			if err := e.enc.EncodeString(f); err != nil {
+				return err
+			}
+			if err := e.EncodeValue(v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
 	return d.Iterate(func(f string, v document.Value) error {
 		if err := e.enc.EncodeString(f); err != nil {
 			return err
@@ -109,6 +156,7 @@ func (e *Encoder) EncodeArray(a document.Array) error {
 // - int32 -> int32
 // - int64 -> int64
 // - float64 -> float64
+// - point -> fixext16
 func (e *Encoder) EncodeValue(v document.Value) error {
 	switch v.Type {
 	case document.DocumentValue:
@@ -127,6 +175,13 @@ func (e *Encoder) EncodeValue(v document.Value) error {
 		return e.enc.EncodeInt64(v.V.(int64))
 	case document.DoubleValue:
 		return e.enc.EncodeFloat64(v.V.(float64))
+	case document.PointValue:
+		err := e.enc.EncodeExtHeader(pointExtType, 16)
+		if err != nil {
+			return err
+		}
+		_, err = e.enc.Writer().Write(key.AppendPoint(nil, v.V.(document.Point)))
+		return err
 	}
 
 	return e.enc.Encode(v.V)
@@ -232,6 +287,30 @@ func (d *Decoder) DecodeValue() (v document.Value, err error) {
 		}
 		v.Type = document.DoubleValue
 		return
+	case codes.FixExt16:
+		var extType int8
+		extType, _, err = d.dec.DecodeExtHeader()
+		if err != nil {
+			return
+		}
+		if extType != pointExtType {
+			err = fmt.Errorf("unsupported extension type %d", extType)
+			return
+		}
+
+		var buf [16]byte
+		err = d.dec.ReadFull(buf[:])
+		if err != nil {
+			return
+		}
+
+		var p document.Point
+		p, err = key.DecodePoint(buf[:])
+		if err != nil {
+			return
+		}
+		v = document.NewPointValue(p)
+		return
 	}
 
 	panic(fmt.Sprintf("unsupported type %v", c))
@@ -160,6 +160,88 @@ func (e EncodedDocument) GetByField(field string) (v document.Value, err error)
 	return
 }
 
+// DecodeFields decodes the top-level fields targeted by the given paths
+// in a single pass, skipping the other fields without decoding them.
+// Fields that are not found in the document are ignored.
+func DecodeFields(data []byte, paths ...document.ValuePath) (*document.FieldBuffer, error) {
+	var fb document.FieldBuffer
+
+	var fields [][]byte
+	for _, p := range paths {
+		if len(p) == 0 || p[0].FieldName == "" {
+			continue
+		}
+
+		fields = append(fields, []byte(p[0].FieldName))
+	}
+
+	dec := NewDecoder(bytes.NewReader(data))
+	defer dec.Close()
+
+	l, err := dec.dec.DecodeMapLen()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 32)
+	remaining := len(fields)
+	for i := 0; i < l && remaining > 0; i++ {
+		// decode the field name without allocating,
+		// like GetByField does.
+		c, err := dec.dec.PeekCode()
+		if err != nil {
+			return nil, err
+		}
+
+		err = dec.dec.ReadFull(buf[:1])
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := bytesLen(c, dec.dec)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(buf) < n {
+			buf = make([]byte, n)
+		}
+
+		err = dec.dec.ReadFull(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+
+		var wanted bool
+		for j := range fields {
+			if fields[j] != nil && bytes.Equal(buf[:n], fields[j]) {
+				// mark the field as found, in case it
+				// was requested by multiple paths
+				fields[j] = nil
+				remaining--
+				wanted = true
+			}
+		}
+
+		if !wanted {
+			err = dec.dec.Skip()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		v, err := dec.DecodeValue()
+		if err != nil {
+			return nil, err
+		}
+
+		fb.Add(string(buf[:n]), v)
+	}
+
+	return &fb, nil
+}
+
 // Iterate decodes each fields one by one and passes them to fn
 // until the end of the document or until fn returns an error.
 func (e EncodedDocument) Iterate(fn func(field string, value document.Value) error) error {
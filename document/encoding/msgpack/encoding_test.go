@@ -1,10 +1,13 @@
 package msgpack
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/document/encoding"
 	"github.com/genjidb/genji/document/encoding/encodingtest"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCodec(t *testing.T) {
@@ -13,6 +16,37 @@ func TestCodec(t *testing.T) {
 	})
 }
 
+func TestEncoderCanonical(t *testing.T) {
+	d1 := document.NewFieldBuffer().
+		Add("a", document.NewIntegerValue(1)).
+		Add("b", document.NewDocumentValue(document.NewFieldBuffer().
+			Add("c", document.NewTextValue("c")).
+			Add("d", document.NewArrayValue(document.NewValueBuffer(document.NewBoolValue(true))))))
+	d2 := document.NewFieldBuffer().
+		Add("b", document.NewDocumentValue(document.NewFieldBuffer().
+			Add("d", document.NewArrayValue(document.NewValueBuffer(document.NewBoolValue(true)))).
+			Add("c", document.NewTextValue("c")))).
+		Add("a", document.NewIntegerValue(1))
+
+	encode := func(d document.Document, canonical bool) []byte {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf).SetCanonical(canonical)
+		defer enc.Close()
+
+		err := enc.EncodeDocument(d)
+		require.NoError(t, err)
+		return buf.Bytes()
+	}
+
+	require.NotEqual(t, encode(d1, false), encode(d2, false))
+	require.Equal(t, encode(d1, true), encode(d2, true))
+
+	// the canonical encoding is decoded as any other document
+	ok, err := document.NewDocumentValue(EncodedDocument(encode(d2, true))).IsEqual(document.NewDocumentValue(d1))
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
 func BenchmarkCodec(b *testing.B) {
 	encodingtest.BenchmarkCodec(b, func() encoding.Codec {
 		return NewCodec()
@@ -0,0 +1,117 @@
+package document
+
+import "strings"
+
+// GetByFieldFold returns the value of the first field of d whose name is equal
+// to field, ignoring case. The exact name of the field is returned as well.
+// If d contains a field whose name is exactly equal to field, it is always preferred.
+// Returns ErrFieldNotFound if no field matches.
+func GetByFieldFold(d Document, field string) (string, Value, error) {
+	v, err := d.GetByField(field)
+	if err != ErrFieldNotFound {
+		return field, v, err
+	}
+
+	var name string
+	err = d.Iterate(func(f string, value Value) error {
+		if strings.EqualFold(f, field) {
+			name, v = f, value
+			return errStop
+		}
+
+		return nil
+	})
+	if err != nil && err != errStop {
+		return "", Value{}, err
+	}
+	if err == nil {
+		return "", Value{}, ErrFieldNotFound
+	}
+
+	return name, v, nil
+}
+
+// NewCaseInsensitiveDocument returns a document whose GetByField method ignores the case
+// of field names. Documents and arrays returned by GetByField are wrapped as well, which makes
+// path resolution case-insensitive at every level.
+// The name of the fields returned by Iterate are left unchanged.
+func NewCaseInsensitiveDocument(d Document) Document {
+	return &caseInsensitiveDocument{Document: d}
+}
+
+type caseInsensitiveDocument struct {
+	Document
+}
+
+func (d *caseInsensitiveDocument) GetByField(field string) (Value, error) {
+	_, v, err := GetByFieldFold(d.Document, field)
+	if err != nil {
+		return Value{}, err
+	}
+
+	return caseInsensitiveValue(v), nil
+}
+
+// Key returns the key of the wrapped document, if any.
+func (d *caseInsensitiveDocument) Key() []byte {
+	if k, ok := d.Document.(Keyer); ok {
+		return k.Key()
+	}
+
+	return nil
+}
+
+type caseInsensitiveArray struct {
+	Array
+}
+
+func (a *caseInsensitiveArray) GetByIndex(i int) (Value, error) {
+	v, err := a.Array.GetByIndex(i)
+	if err != nil {
+		return Value{}, err
+	}
+
+	return caseInsensitiveValue(v), nil
+}
+
+func caseInsensitiveValue(v Value) Value {
+	switch v.Type {
+	case DocumentValue:
+		return NewDocumentValue(NewCaseInsensitiveDocument(v.V.(Document)))
+	case ArrayValue:
+		return NewArrayValue(&caseInsensitiveArray{Array: v.V.(Array)})
+	}
+
+	return v
+}
+
+// ResolveFold returns the path of the value of d referenced by p, ignoring the case of field names.
+// Every field name of p is replaced by the exact name of the matching field in d.
+// If a field cannot be found, the remaining fragments of p are returned unchanged.
+func (p ValuePath) ResolveFold(d Document) ValuePath {
+	res := make(ValuePath, len(p))
+	copy(res, p)
+
+	v := NewDocumentValue(d)
+	for i, frag := range p {
+		var err error
+
+		switch {
+		case frag.FieldName != "" && v.Type == DocumentValue:
+			res[i].FieldName, v, err = GetByFieldFold(v.V.(Document), frag.FieldName)
+			if err != nil {
+				res[i].FieldName = frag.FieldName
+				return res
+			}
+		case frag.FieldName == "" && v.Type == ArrayValue:
+			v, err = v.V.(Array).GetByIndex(frag.ArrayIndex)
+			if err != nil {
+				return res
+			}
+		default:
+			return res
+		}
+	}
+
+	return res
+}
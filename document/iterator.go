@@ -2,6 +2,8 @@ package document
 
 import (
 	"bufio"
+	"encoding/csv"
+	"encoding/hex"
 	"errors"
 	"io"
 )
@@ -91,6 +93,91 @@ func IteratorToJSONArray(w io.Writer, s Iterator) error {
 	return buf.Flush()
 }
 
+// IteratorToCSV encodes all the documents of an iterator to CSV, as defined by RFC 4180.
+// The first record is a header containing the given columns, followed by one record per document
+// containing the value of each column. If columns is empty, the fields of the first document are used.
+// Missing fields and null values are written as empty cells, blobs are hex encoded and
+// arrays and documents are encoded as JSON.
+func IteratorToCSV(w io.Writer, columns []string, s Iterator) error {
+	cw := csv.NewWriter(w)
+
+	var record []string
+	first := true
+	err := s.Iterate(func(d Document) error {
+		if first {
+			first = false
+
+			if len(columns) == 0 {
+				err := d.Iterate(func(field string, _ Value) error {
+					columns = append(columns, field)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			err := cw.Write(columns)
+			if err != nil {
+				return err
+			}
+
+			record = make([]string, len(columns))
+		}
+
+		for i, c := range columns {
+			v, err := d.GetByField(c)
+			if err == ErrFieldNotFound {
+				record[i] = ""
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			record[i], err = csvCell(v)
+			if err != nil {
+				return err
+			}
+		}
+
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	// write the header even if the iterator is empty
+	if first && len(columns) > 0 {
+		err = cw.Write(columns)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell returns the representation of v used in a CSV cell.
+func csvCell(v Value) (string, error) {
+	switch v.Type {
+	case NullValue:
+		return "", nil
+	case TextValue:
+		return v.V.(string), nil
+	case BlobValue:
+		return hex.EncodeToString(v.V.([]byte)), nil
+	}
+
+	t, err := v.CastAsText()
+	if err != nil {
+		return "", err
+	}
+
+	return t.V.(string), nil
+}
+
 // Stream reads documents of an iterator one by one and passes them
 // through a list of functions for transformation.
 type Stream struct {
@@ -280,9 +367,42 @@ func (s Stream) GroupBy(groupFn func(d Document) (Value, error)) Stream {
 }
 
 // Aggregate builds a list of aggregators for each group of documents and passes each document of the stream to them.
+// It returns one document per group, containing the result of the aggregators. Fields that are not the result of
+// an aggregator are read from the first document of the group, whose fields are the same for all the documents
+// of the group if they were used to group them.
+// An empty stream has no group and returns no document.
 func (s Stream) Aggregate(aggregatorBuilders ...AggregatorBuilder) Stream {
+	return s.aggregate(false, aggregatorBuilders)
+}
+
+// AggregateAll aggregates the documents of the stream like Aggregate, but always returns
+// at least one document: if the stream is empty, the aggregators of a single group
+// are returned without having been passed any document.
+// It is used when the documents are not grouped and the stream is aggregated as a whole.
+func (s Stream) AggregateAll(aggregatorBuilders ...AggregatorBuilder) Stream {
+	return s.aggregate(true, aggregatorBuilders)
+}
+
+// aggregateGroup holds the aggregators of a group and a copy of its first document.
+type aggregateGroup struct {
+	aggs  []Aggregator
+	first FieldBuffer
+}
+
+func newAggregateGroup(group Value, aggregatorBuilders []AggregatorBuilder) *aggregateGroup {
+	g := aggregateGroup{
+		aggs: make([]Aggregator, len(aggregatorBuilders)),
+	}
+	for i, builder := range aggregatorBuilders {
+		g.aggs[i] = builder.NewAggregator(group)
+	}
+
+	return &g
+}
+
+func (s Stream) aggregate(always bool, aggregatorBuilders []AggregatorBuilder) Stream {
 	return NewStream(IteratorFunc(func(fn func(d Document) error) error {
-		aggregates := make(map[Value][]Aggregator)
+		aggregates := make(map[Value]*aggregateGroup)
 		var groups []Value
 
 		nullValue := NewNullValue()
@@ -292,20 +412,22 @@ func (s Stream) Aggregate(aggregatorBuilders ...AggregatorBuilder) Stream {
 
 			if gd, ok := d.(*groupedDocument); ok {
 				group = gd.group
+				d = gd.Document
 			}
 
-			aggs, ok := aggregates[group]
+			g, ok := aggregates[group]
 			if !ok {
 				groups = append(groups, group)
-				aggs = make([]Aggregator, len(aggregatorBuilders))
-				for i, builder := range aggregatorBuilders {
-					aggs[i] = builder.NewAggregator(group)
+				g = newAggregateGroup(group, aggregatorBuilders)
+				err := g.first.Copy(d)
+				if err != nil {
+					return err
 				}
-				aggregates[group] = aggs
+				aggregates[group] = g
 			}
 
 			var err error
-			for _, agg := range aggs {
+			for _, agg := range g.aggs {
 				err = agg.Add(d)
 				if err != nil {
 					return err
@@ -318,17 +440,22 @@ func (s Stream) Aggregate(aggregatorBuilders ...AggregatorBuilder) Stream {
 			return err
 		}
 
+		if always && len(groups) == 0 {
+			aggregates[nullValue] = newAggregateGroup(nullValue, aggregatorBuilders)
+			groups = append(groups, nullValue)
+		}
+
 		for _, group := range groups {
-			fb := NewFieldBuffer()
-			aggs := aggregates[group]
-			for _, agg := range aggs {
-				err = agg.Aggregate(fb)
+			g := aggregates[group]
+			ad := aggregatedDocument{first: &g.first}
+			for _, agg := range g.aggs {
+				err = agg.Aggregate(&ad.results)
 				if err != nil {
 					return err
 				}
 			}
 
-			err = fn(fb)
+			err = fn(&ad)
 			if err != nil {
 				return err
 			}
@@ -338,6 +465,38 @@ func (s Stream) Aggregate(aggregatorBuilders ...AggregatorBuilder) Stream {
 	}))
 }
 
+// aggregatedDocument is the document returned by Aggregate for a group.
+// Its fields are the results of the aggregators followed by the fields of the first
+// document of the group that don't have the name of a result.
+type aggregatedDocument struct {
+	results FieldBuffer
+	first   Document
+}
+
+func (a *aggregatedDocument) GetByField(field string) (Value, error) {
+	v, err := a.results.GetByField(field)
+	if err != ErrFieldNotFound {
+		return v, err
+	}
+
+	return a.first.GetByField(field)
+}
+
+func (a *aggregatedDocument) Iterate(fn func(field string, value Value) error) error {
+	err := a.results.Iterate(fn)
+	if err != nil {
+		return err
+	}
+
+	return a.first.Iterate(func(field string, value Value) error {
+		if _, err := a.results.GetByField(field); err != ErrFieldNotFound {
+			return err
+		}
+
+		return fn(field, value)
+	})
+}
+
 // An Aggregator aggregates documents into a single one.
 type Aggregator interface {
 	Add(d Document) error
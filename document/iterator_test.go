@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"testing"
@@ -170,3 +171,182 @@ func TestIteratorToJSONArray(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, `[{"a": 0}, {"a": 1}, {"a": 2}]`, buf.String())
 }
+
+func TestIteratorToCSV(t *testing.T) {
+	parse := func(t testing.TB, s string) document.Document {
+		fb := document.NewFieldBuffer()
+		err := json.Unmarshal([]byte(s), fb)
+		require.NoError(t, err)
+		return fb
+	}
+
+	tests := []struct {
+		name     string
+		columns  []string
+		docs     []string
+		expected string
+	}{
+		{"Empty", []string{"a", "b"}, nil, "a,b\n"},
+		{"No columns", nil, []string{`{"a": 1, "b": "x"}`, `{"b": "y", "a": 2}`}, "a,b\n1,x\n2,y\n"},
+		{"Scalars", []string{"a", "b", "c", "d", "e"}, []string{
+			`{"a": 1, "b": 1.5, "c": true, "d": null, "e": "foo"}`,
+		}, "a,b,c,d,e\n1,1.5,true,,foo\n"},
+		{"Missing fields", []string{"a", "b"}, []string{`{"b": 1}`, `{"a": 1}`}, "a,b\n,1\n1,\n"},
+		{"Quoting", []string{"a"}, []string{
+			`{"a": "hello, world"}`,
+			`{"a": "say \"hi\""}`,
+			`{"a": "multi\nline"}`,
+		}, "a\n\"hello, world\"\n\"say \"\"hi\"\"\"\n\"multi\nline\"\n"},
+		{"Nested", []string{"a", "b"}, []string{
+			`{"a": [1, "x", {"c": true}], "b": {"c": [1, 2], "d": "y"}}`,
+		}, "a,b\n\"[1, \"\"x\"\", {\"\"c\"\": true}]\",\"{\"\"c\"\": [1, 2], \"\"d\"\": \"\"y\"\"}\"\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var docs []document.Document
+			for _, d := range test.docs {
+				docs = append(docs, parse(t, d))
+			}
+
+			var buf bytes.Buffer
+			err := document.IteratorToCSV(&buf, test.columns, document.NewIterator(docs...))
+			require.NoError(t, err)
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+
+	t.Run("Blob", func(t *testing.T) {
+		fb := document.NewFieldBuffer().Add("a", document.NewBlobValue([]byte{0xca, 0xfe}))
+
+		var buf bytes.Buffer
+		err := document.IteratorToCSV(&buf, nil, document.NewIterator(fb))
+		require.NoError(t, err)
+		require.Equal(t, "a\ncafe\n", buf.String())
+	})
+}
+
+func TestStream(t *testing.T) {
+	newStream := func(t testing.TB) document.Stream {
+		var docs []document.Document
+		for i := 0; i < 5; i++ {
+			docs = append(docs, document.NewFieldBuffer().Add("a", document.NewIntegerValue(int64(i))))
+		}
+
+		return document.NewStream(document.NewIterator(docs...))
+	}
+
+	toJSON := func(t testing.TB, st document.Stream) string {
+		var buf bytes.Buffer
+		err := document.IteratorToJSONArray(&buf, st)
+		require.NoError(t, err)
+		return buf.String()
+	}
+
+	isEven := func(d document.Document) (bool, error) {
+		v, err := d.GetByField("a")
+		if err != nil {
+			return false, err
+		}
+		return v.V.(int64)%2 == 0, nil
+	}
+
+	double := func(d document.Document) (document.Document, error) {
+		v, err := d.GetByField("a")
+		if err != nil {
+			return nil, err
+		}
+		return document.NewFieldBuffer().Add("b", document.NewIntegerValue(v.V.(int64)*2)), nil
+	}
+
+	t.Run("Filter", func(t *testing.T) {
+		st := newStream(t).Filter(isEven)
+		require.JSONEq(t, `[{"a": 0}, {"a": 2}, {"a": 4}]`, toJSON(t, st))
+	})
+
+	t.Run("Map", func(t *testing.T) {
+		st := newStream(t).Map(double)
+		require.JSONEq(t, `[{"b": 0}, {"b": 2}, {"b": 4}, {"b": 6}, {"b": 8}]`, toJSON(t, st))
+	})
+
+	t.Run("Composition", func(t *testing.T) {
+		st := newStream(t).Filter(isEven).Map(double).Limit(2)
+		require.JSONEq(t, `[{"b": 0}, {"b": 4}]`, toJSON(t, st))
+
+		// streams can be iterated more than once
+		require.JSONEq(t, `[{"b": 0}, {"b": 4}]`, toJSON(t, st))
+
+		n, err := newStream(t).Offset(1).Filter(isEven).Count()
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		tests := []struct {
+			limit    int
+			expected string
+		}{
+			{0, `[]`},
+			{2, `[{"a": 0}, {"a": 1}]`},
+			{5, `[{"a": 0}, {"a": 1}, {"a": 2}, {"a": 3}, {"a": 4}]`},
+			{10, `[{"a": 0}, {"a": 1}, {"a": 2}, {"a": 3}, {"a": 4}]`},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("%d", test.limit), func(t *testing.T) {
+				require.JSONEq(t, test.expected, toJSON(t, newStream(t).Limit(test.limit)))
+			})
+		}
+	})
+
+	t.Run("Offset", func(t *testing.T) {
+		tests := []struct {
+			offset, limit int
+			expected      string
+		}{
+			{0, 2, `[{"a": 0}, {"a": 1}]`},
+			{2, 2, `[{"a": 2}, {"a": 3}]`},
+			{3, 10, `[{"a": 3}, {"a": 4}]`},
+			{5, 2, `[]`},
+			{10, 2, `[]`},
+		}
+
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("%d/%d", test.offset, test.limit), func(t *testing.T) {
+				st := newStream(t).Offset(test.offset).Limit(test.limit)
+				require.JSONEq(t, test.expected, toJSON(t, st))
+			})
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		errTest := errors.New("test")
+
+		err := newStream(t).Filter(func(d document.Document) (bool, error) {
+			return false, errTest
+		}).Iterate(func(d document.Document) error { return nil })
+		require.Equal(t, errTest, err)
+
+		err = newStream(t).Map(func(d document.Document) (document.Document, error) {
+			return nil, errTest
+		}).Iterate(func(d document.Document) error { return nil })
+		require.Equal(t, errTest, err)
+
+		// errors of the underlying iterator are returned by limit and offset
+		it := document.IteratorFunc(func(fn func(d document.Document) error) error {
+			for i := 0; i < 2; i++ {
+				err := fn(document.NewFieldBuffer())
+				if err != nil {
+					return err
+				}
+			}
+			return errTest
+		})
+		err = document.NewStream(it).Offset(1).Limit(2).Iterate(func(d document.Document) error { return nil })
+		require.Equal(t, errTest, err)
+
+		// unless the stream is closed once the limit is reached
+		err = document.NewStream(it).Limit(1).Iterate(func(d document.Document) error { return nil })
+		require.NoError(t, err)
+	})
+}
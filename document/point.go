@@ -0,0 +1,31 @@
+package document
+
+import "math"
+
+// earthRadius is the mean radius of the Earth, in meters.
+const earthRadius = 6371008.8
+
+// A Point is a geographic location, expressed in decimal degrees.
+type Point struct {
+	Lat, Lng float64
+}
+
+// Distance returns the great-circle distance between p and q in meters,
+// computed using the haversine formula.
+func (p Point) Distance(q Point) float64 {
+	lat1 := p.Lat * math.Pi / 180
+	lat2 := q.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (q.Lng - p.Lng) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// IsValid returns whether the latitude is between -90 and 90
+// and the longitude between -180 and 180.
+func (p Point) IsValid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
@@ -0,0 +1,55 @@
+package document_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPointDistance(t *testing.T) {
+	paris := document.Point{Lat: 48.8566, Lng: 2.3522}
+	london := document.Point{Lat: 51.5074, Lng: -0.1278}
+	sydney := document.Point{Lat: -33.8688, Lng: 151.2093}
+
+	tests := []struct {
+		name     string
+		a, b     document.Point
+		expected float64
+	}{
+		{"same point", paris, paris, 0},
+		{"Paris - London", paris, london, 343.5e3},
+		{"London - Paris", london, paris, 343.5e3},
+		{"Paris - Sydney", paris, sydney, 16960e3},
+		{"antipodes", document.Point{Lat: 0, Lng: 0}, document.Point{Lat: 0, Lng: 180}, 20015e3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// allow an error of 0.1%
+			require.InDelta(t, test.expected, test.a.Distance(test.b), test.expected*0.001)
+		})
+	}
+}
+
+func TestPointValue(t *testing.T) {
+	v := document.NewPointValue(document.Point{Lat: 48.8566, Lng: -2})
+
+	require.Equal(t, "POINT(48.8566, -2)", v.String())
+
+	data, err := v.MarshalJSON()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"lat": 48.8566, "lng": -2}`, string(data))
+
+	ok, err := v.IsEqual(document.NewPointValue(document.Point{Lat: 48.8566, Lng: -2}))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = v.IsLesserThan(document.NewPointValue(document.Point{Lat: 48.8566, Lng: 2}))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.True(t, document.Point{Lat: 90, Lng: -180}.IsValid())
+	require.False(t, document.Point{Lat: 91, Lng: 0}.IsValid())
+	require.False(t, document.Point{Lat: 0, Lng: 180.5}.IsValid())
+}
@@ -324,6 +324,11 @@ func scanValue(v Value, ref reflect.Value) error {
 			ref.Set(reflect.ValueOf(parsed))
 			return nil
 		}
+	case "document.Point":
+		if v.Type == PointValue {
+			ref.Set(reflect.ValueOf(v.V))
+			return nil
+		}
 	}
 
 	switch ref.Kind() {
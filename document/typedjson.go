@@ -0,0 +1,330 @@
+package document
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/buger/jsonparser"
+)
+
+// Typed JSON represents every value of a document by an object containing
+// its type and its JSON representation, which allows to distinguish types that
+// share the same representation in plain JSON, like integers and doubles or text and blobs:
+//
+//	{"age": {"type": "integer", "value": 10}, "height": {"type": "double", "value": 1.8}}
+//
+// Types are named as returned by ValueType.String. The values of arrays are lists of typed values,
+// the values of documents are objects of typed values, points are objects with a lat and a lng
+// field and blobs are base64 encoded.
+
+// MarshalTypedJSON encodes d to typed JSON.
+func MarshalTypedJSON(d Document) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := appendTypedJSONDocument(&buf, d)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// NewFromTypedJSON creates a document from an object encoded to typed JSON.
+func NewFromTypedJSON(data []byte) (Document, error) {
+	fb := NewFieldBuffer()
+
+	err := unmarshalTypedJSONDocument(fb, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return fb, nil
+}
+
+// IteratorToTypedJSON encodes all the documents of an iterator to typed JSON,
+// one document per line.
+func IteratorToTypedJSON(w io.Writer, s Iterator) error {
+	buf := bufio.NewWriter(w)
+
+	var tmp bytes.Buffer
+	err := s.Iterate(func(d Document) error {
+		tmp.Reset()
+
+		err := appendTypedJSONDocument(&tmp, d)
+		if err != nil {
+			return err
+		}
+		tmp.WriteByte('\n')
+
+		_, err = buf.Write(tmp.Bytes())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return buf.Flush()
+}
+
+// NewTypedJSONIterator creates an iterator that decodes the documents written by IteratorToTypedJSON.
+// Empty lines are ignored.
+func NewTypedJSONIterator(r io.Reader) Iterator {
+	return IteratorFunc(func(fn func(d Document) error) error {
+		rd := bufio.NewReader(r)
+
+		for {
+			line, err := rd.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return err
+			}
+
+			if data := bytes.TrimSpace(line); len(data) > 0 {
+				d, perr := NewFromTypedJSON(data)
+				if perr != nil {
+					return perr
+				}
+
+				perr = fn(d)
+				if perr != nil {
+					return perr
+				}
+			}
+
+			if err == io.EOF {
+				return nil
+			}
+		}
+	})
+}
+
+func appendTypedJSONDocument(buf *bytes.Buffer, d Document) error {
+	buf.WriteByte('{')
+
+	first := true
+	err := d.Iterate(func(f string, v Value) error {
+		if !first {
+			buf.WriteString(", ")
+		} else {
+			first = false
+		}
+
+		buf.WriteString(strconv.Quote(f))
+		buf.WriteString(": ")
+
+		return appendTypedJSONValue(buf, v)
+	})
+	if err != nil {
+		return err
+	}
+
+	buf.WriteByte('}')
+	return nil
+}
+
+func appendTypedJSONValue(buf *bytes.Buffer, v Value) error {
+	buf.WriteString(`{"type": `)
+	buf.WriteString(strconv.Quote(v.Type.String()))
+	buf.WriteString(`, "value": `)
+
+	switch v.Type {
+	case ArrayValue:
+		buf.WriteByte('[')
+
+		first := true
+		err := v.V.(Array).Iterate(func(i int, value Value) error {
+			if !first {
+				buf.WriteString(", ")
+			} else {
+				first = false
+			}
+
+			return appendTypedJSONValue(buf, value)
+		})
+		if err != nil {
+			return err
+		}
+
+		buf.WriteByte(']')
+	case DocumentValue:
+		err := appendTypedJSONDocument(buf, v.V.(Document))
+		if err != nil {
+			return err
+		}
+	default:
+		data, err := v.MarshalJSON()
+		if err != nil {
+			return err
+		}
+
+		buf.Write(data)
+	}
+
+	buf.WriteByte('}')
+	return nil
+}
+
+func unmarshalTypedJSONDocument(fb *FieldBuffer, data []byte) error {
+	return jsonparser.ObjectEach(data, func(key []byte, value []byte, dataType jsonparser.ValueType, offset int) error {
+		field, err := jsonparser.ParseString(key)
+		if err != nil {
+			return err
+		}
+
+		v, err := parseTypedJSONValue(dataType, value)
+		if err != nil {
+			return fmt.Errorf("field %q: %w", field, err)
+		}
+
+		fb.Add(field, v)
+		return nil
+	})
+}
+
+func parseTypedJSONValue(dataType jsonparser.ValueType, data []byte) (Value, error) {
+	if dataType != jsonparser.Object {
+		return Value{}, errors.New("typed value must be an object")
+	}
+
+	name, err := jsonparser.GetString(data, "type")
+	if err != nil {
+		return Value{}, errors.New("typed value must have a type")
+	}
+
+	raw, rawType, _, err := jsonparser.Get(data, "value")
+	if err != nil {
+		return Value{}, errors.New("typed value must have a value")
+	}
+
+	t, err := parseValueType(name)
+	if err != nil {
+		return Value{}, err
+	}
+
+	if rawType == jsonparser.Null {
+		if t != NullValue {
+			return Value{}, fmt.Errorf("unexpected null value for type %s", t)
+		}
+
+		return NewNullValue(), nil
+	}
+
+	switch t {
+	case BoolValue:
+		if rawType == jsonparser.Boolean {
+			b, err := jsonparser.ParseBoolean(raw)
+			if err != nil {
+				return Value{}, err
+			}
+
+			return NewBoolValue(b), nil
+		}
+	case IntegerValue:
+		if rawType == jsonparser.Number {
+			i, err := strconv.ParseInt(string(raw), 10, 64)
+			if err != nil {
+				return Value{}, err
+			}
+
+			return NewIntegerValue(i), nil
+		}
+	case DoubleValue:
+		if rawType == jsonparser.Number {
+			f, err := jsonparser.ParseFloat(raw)
+			if err != nil {
+				return Value{}, err
+			}
+
+			return NewDoubleValue(f), nil
+		}
+	case PointValue:
+		if rawType == jsonparser.Object {
+			lat, err := jsonparser.GetFloat(raw, "lat")
+			if err != nil {
+				return Value{}, err
+			}
+			lng, err := jsonparser.GetFloat(raw, "lng")
+			if err != nil {
+				return Value{}, err
+			}
+
+			return NewPointValue(Point{Lat: lat, Lng: lng}), nil
+		}
+	case TextValue:
+		if rawType == jsonparser.String {
+			s, err := jsonparser.ParseString(raw)
+			if err != nil {
+				return Value{}, err
+			}
+
+			return NewTextValue(s), nil
+		}
+	case BlobValue:
+		if rawType == jsonparser.String {
+			b, err := base64.StdEncoding.DecodeString(string(raw))
+			if err != nil {
+				return Value{}, err
+			}
+
+			return NewBlobValue(b), nil
+		}
+	case ArrayValue:
+		if rawType == jsonparser.Array {
+			vb := NewValueBuffer()
+
+			var perr error
+			_, err := jsonparser.ArrayEach(raw, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+				if perr != nil {
+					return
+				}
+
+				v, err := parseTypedJSONValue(dataType, value)
+				if err != nil {
+					perr = err
+					return
+				}
+
+				vb = vb.Append(v)
+			})
+			if err != nil {
+				return Value{}, err
+			}
+			if perr != nil {
+				return Value{}, perr
+			}
+
+			return NewArrayValue(vb), nil
+		}
+	case DocumentValue:
+		if rawType == jsonparser.Object {
+			fb := NewFieldBuffer()
+
+			err := unmarshalTypedJSONDocument(fb, raw)
+			if err != nil {
+				return Value{}, err
+			}
+
+			return NewDocumentValue(fb), nil
+		}
+	}
+
+	return Value{}, fmt.Errorf("invalid value for type %s: %s", t, raw)
+}
+
+// parseValueType returns the type whose name, as returned by ValueType.String, is name.
+func parseValueType(name string) (ValueType, error) {
+	for _, t := range []ValueType{
+		NullValue, BoolValue, IntegerValue, DoubleValue, PointValue,
+		TextValue, BlobValue, ArrayValue, DocumentValue,
+	} {
+		if t.String() == name {
+			return t, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown type %q", name)
+}
@@ -0,0 +1,95 @@
+package document_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedJSON(t *testing.T) {
+	nested := document.NewFieldBuffer().
+		Add("a", document.NewIntegerValue(1)).
+		Add("b", document.NewDoubleValue(1))
+
+	docs := []document.Document{
+		document.NewFieldBuffer().
+			Add("int", document.NewIntegerValue(10)).
+			Add("double", document.NewDoubleValue(10)).
+			Add("text", document.NewTextValue("aGk=")).
+			Add("blob", document.NewBlobValue([]byte("hi"))),
+		document.NewFieldBuffer().
+			Add("null", document.NewNullValue()).
+			Add("bool", document.NewBoolValue(true)).
+			Add("point", document.NewPointValue(document.Point{Lat: 1.5, Lng: -2})).
+			Add("array", document.NewArrayValue(document.NewValueBuffer().
+				Append(document.NewIntegerValue(1)).
+				Append(document.NewDoubleValue(1.5)).
+				Append(document.NewDocumentValue(nested)))).
+			Add("document", document.NewDocumentValue(nested)),
+		document.NewFieldBuffer(),
+	}
+
+	var buf bytes.Buffer
+	err := document.IteratorToTypedJSON(&buf, document.NewIterator(docs...))
+	require.NoError(t, err)
+
+	// one document per line
+	lines := bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n"))
+	require.Len(t, lines, len(docs))
+	require.Equal(t,
+		`{"int": {"type": "integer", "value": 10}, "double": {"type": "double", "value": 10}, "text": {"type": "text", "value": "aGk="}, "blob": {"type": "blob", "value": "aGk="}}`,
+		string(lines[0]))
+	require.Equal(t, `{}`, string(lines[2]))
+
+	t.Run("Round trip", func(t *testing.T) {
+		var got []document.Document
+		err := document.NewTypedJSONIterator(&buf).Iterate(func(d document.Document) error {
+			got = append(got, d)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, got, len(docs))
+
+		for i := range docs {
+			require.Equal(t, docs[i].(*document.FieldBuffer), got[i])
+		}
+
+		// types that share the same representation in plain JSON are preserved
+		for field, tp := range map[string]document.ValueType{
+			"int":    document.IntegerValue,
+			"double": document.DoubleValue,
+			"text":   document.TextValue,
+			"blob":   document.BlobValue,
+		} {
+			v, err := got[0].GetByField(field)
+			require.NoError(t, err)
+			require.Equal(t, tp, v.Type)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		tests := []struct {
+			name string
+			data string
+		}{
+			{"Not typed", `{"a": 1}`},
+			{"Missing type", `{"a": {"value": 1}}`},
+			{"Missing value", `{"a": {"type": "integer"}}`},
+			{"Unknown type", `{"a": {"type": "foo", "value": 1}}`},
+			{"Mismatch", `{"a": {"type": "integer", "value": "1"}}`},
+			{"Double as integer", `{"a": {"type": "integer", "value": 1.5}}`},
+			{"Unexpected null", `{"a": {"type": "text", "value": null}}`},
+			{"Invalid blob", `{"a": {"type": "blob", "value": "!"}}`},
+			{"Untyped array element", `{"a": {"type": "array", "value": [1]}}`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				_, err := document.NewFromTypedJSON([]byte(test.data))
+				require.Error(t, err)
+			})
+		}
+	})
+}
@@ -15,12 +15,17 @@ var (
 	boolZeroValue     = NewZeroValue(BoolValue)
 	integerZeroValue  = NewZeroValue(IntegerValue)
 	doubleZeroValue   = NewZeroValue(DoubleValue)
+	pointZeroValue    = NewZeroValue(PointValue)
 	blobZeroValue     = NewZeroValue(BlobValue)
 	textZeroValue     = NewZeroValue(TextValue)
 	arrayZeroValue    = NewZeroValue(ArrayValue)
 	documentZeroValue = NewZeroValue(DocumentValue)
 )
 
+// ErrIntegerOverflow is returned when the result of an arithmetic operation
+// between two integers doesn't fit in an integer.
+var ErrIntegerOverflow = errors.New("integer overflow")
+
 // ErrUnsupportedType is used to skip struct or array fields that are not supported.
 type ErrUnsupportedType struct {
 	Value interface{}
@@ -48,6 +53,9 @@ const (
 	// double family: 0xA0 to 0xAF
 	DoubleValue ValueType = 0xA0
 
+	// point family: 0xB0 to 0xBF
+	PointValue ValueType = 0xB0
+
 	// string family: 0xC0 to 0xCF
 	TextValue ValueType = 0xC0
 
@@ -71,6 +79,8 @@ func (t ValueType) String() string {
 		return "integer"
 	case DoubleValue:
 		return "double"
+	case PointValue:
+		return "point"
 	case BlobValue:
 		return "blob"
 	case TextValue:
@@ -127,6 +137,14 @@ func NewDoubleValue(x float64) Value {
 	}
 }
 
+// NewPointValue encodes p and returns a value.
+func NewPointValue(p Point) Value {
+	return Value{
+		Type: PointValue,
+		V:    p,
+	}
+}
+
 // NewBlobValue encodes x and returns a value.
 func NewBlobValue(x []byte) Value {
 	return Value{
@@ -171,6 +189,8 @@ func NewZeroValue(t ValueType) Value {
 		return NewIntegerValue(0)
 	case DoubleValue:
 		return NewDoubleValue(0)
+	case PointValue:
+		return NewPointValue(Point{})
 	case BlobValue:
 		return NewBlobValue(nil)
 	case TextValue:
@@ -185,6 +205,10 @@ func NewZeroValue(t ValueType) Value {
 }
 
 // IsTruthy returns whether v is not equal to the zero value of its type.
+// It defines how non-boolean values are interpreted when a boolean is expected,
+// such as in a WHERE clause or with the AND and OR operators:
+// NULL, false, 0, 0.0, empty texts, blobs, arrays and documents, and
+// the zero point are false, every other value is true.
 func (v Value) IsTruthy() (bool, error) {
 	if v.Type == NullValue {
 		return false, nil
@@ -204,6 +228,8 @@ func (v Value) IsZeroValue() (bool, error) {
 		return v.V == integerZeroValue.V, nil
 	case DoubleValue:
 		return v.V == doubleZeroValue.V, nil
+	case PointValue:
+		return v.V == pointZeroValue.V, nil
 	case BlobValue:
 		return bytes.Compare(v.V.([]byte), blobZeroValue.V.([]byte)) == 0, nil
 	case TextValue:
@@ -259,6 +285,15 @@ func (v Value) MarshalJSON() ([]byte, error) {
 		}
 
 		return strconv.AppendFloat(nil, v.V.(float64), fmt, -1, 64), nil
+	case PointValue:
+		p := v.V.(Point)
+		buf := append([]byte(nil), `{"lat": `...)
+		lat, _ := NewDoubleValue(p.Lat).MarshalJSON()
+		buf = append(buf, lat...)
+		buf = append(buf, `, "lng": `...)
+		lng, _ := NewDoubleValue(p.Lng).MarshalJSON()
+		buf = append(buf, lng...)
+		return append(buf, '}'), nil
 	case TextValue:
 		return []byte(strconv.Quote(v.V.(string))), nil
 	case BlobValue:
@@ -286,12 +321,24 @@ func (v Value) String() string {
 		return strconv.Quote(v.V.(string))
 	case BlobValue:
 		return fmt.Sprintf("%v", v.V)
+	case PointValue:
+		p := v.V.(Point)
+		return fmt.Sprintf("POINT(%s, %s)", NewDoubleValue(p.Lat), NewDoubleValue(p.Lng))
 	}
 
 	d, _ := v.MarshalJSON()
 	return string(d)
 }
 
+// The arithmetic operators below follow the same rules:
+//   - if any of the operands is NULL, a boolean or is not a number, the result is NULL
+//   - if both operands are integers, the result is an integer
+//   - if one of the operands is a double, the other operand is converted to a double
+//     and the result is a double, except for bitwise operators whose result is always an integer
+//   - a division or a modulo by zero returns NULL
+//   - if the result of an operation between two integers doesn't fit in an integer,
+//     ErrIntegerOverflow is returned
+
 // Add u to v and return the result.
 // Only numeric values and booleans can be added together.
 func (v Value) Add(u Value) (res Value, err error) {
@@ -405,15 +452,18 @@ func calculateIntegers(a, b Value, operator byte) (res Value, err error) {
 	var xr int64
 
 	switch operator {
-	case '-':
-		xb = -xb
-		fallthrough
 	case '+':
 		xr = xa + xb
-		// if there is an integer overflow
-		// convert to float
-		if (xr > xa) != (xb > 0) {
-			return NewDoubleValue(float64(xa) + float64(xb)), nil
+		// the sum of two numbers of the same sign
+		// can't have a different sign
+		if (xa >= 0) == (xb >= 0) && (xr >= 0) != (xa >= 0) {
+			return NewNullValue(), ErrIntegerOverflow
+		}
+		return NewIntegerValue(xr), nil
+	case '-':
+		xr = xa - xb
+		if (xb > 0 && xr > xa) || (xb < 0 && xr < xa) {
+			return NewNullValue(), ErrIntegerOverflow
 		}
 		return NewIntegerValue(xr), nil
 	case '*':
@@ -422,20 +472,21 @@ func calculateIntegers(a, b Value, operator byte) (res Value, err error) {
 		}
 
 		xr = xa * xb
-		// if there is no integer overflow
-		// return an int, otherwise
-		// convert to float
-		if (xr < 0) == ((xa < 0) != (xb < 0)) {
-			if xr/xb == xa {
-				return NewIntegerValue(xr), nil
-			}
+		// MinInt64 * -1 is the only overflowing product
+		// that the division can't detect
+		if xr/xb != xa || (xa == math.MinInt64 && xb == -1) {
+			return NewNullValue(), ErrIntegerOverflow
 		}
-		return NewDoubleValue(float64(xa) * float64(xb)), nil
+		return NewIntegerValue(xr), nil
 	case '/':
 		if xb == 0 {
 			return NewNullValue(), nil
 		}
 
+		if xa == math.MinInt64 && xb == -1 {
+			return NewNullValue(), ErrIntegerOverflow
+		}
+
 		return NewIntegerValue(xa / xb), nil
 	case '%':
 		if xb == 0 {
@@ -74,6 +74,7 @@ func TestNewValue(t *testing.T) {
 		{"document", document.NewFieldBuffer().Add("a", document.NewIntegerValue(10)), document.NewFieldBuffer().Add("a", document.NewIntegerValue(10))},
 		{"array", document.NewValueBuffer(document.NewIntegerValue(10)), document.NewValueBuffer(document.NewIntegerValue(10))},
 		{"time", now, now.Format(time.RFC3339Nano)},
+		{"value", document.NewDoubleValue(10), float64(10)},
 		{"bytes", myBytes("bar"), []byte("bar")},
 		{"string", myString("bar"), "bar"},
 		{"myUint", myUint(10), int64(10)},
@@ -111,8 +112,11 @@ func TestValueAdd(t *testing.T) {
 		{"integer(120)+integer(120)", document.NewIntegerValue(120), document.NewIntegerValue(120), document.NewIntegerValue(240), false},
 		{"integer(120)+float64(120)", document.NewIntegerValue(120), document.NewDoubleValue(120), document.NewDoubleValue(240), false},
 		{"integer(120)+float64(120.1)", document.NewIntegerValue(120), document.NewDoubleValue(120.1), document.NewDoubleValue(240.1), false},
-		{"int64(max)+integer(10)", document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(10), document.NewDoubleValue(math.MaxInt64 + 10), false},
-		{"int64(min)+integer(-10)", document.NewIntegerValue(math.MinInt64), document.NewIntegerValue(-10), document.NewDoubleValue(math.MinInt64 - 10), false},
+		{"int64(max)+integer(0)", document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(0), document.NewIntegerValue(math.MaxInt64), false},
+		{"int64(min)+int64(max)", document.NewIntegerValue(math.MinInt64), document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(-1), false},
+		{"int64(max)+integer(10)", document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(10), document.NewNullValue(), true},
+		{"int64(min)+integer(-10)", document.NewIntegerValue(math.MinInt64), document.NewIntegerValue(-10), document.NewNullValue(), true},
+		{"int64(max)+float64(10)", document.NewIntegerValue(math.MaxInt64), document.NewDoubleValue(10), document.NewDoubleValue(math.MaxInt64 + 10), false},
 		{"integer(120)+text('120')", document.NewIntegerValue(120), document.NewTextValue("120"), document.NewNullValue(), false},
 		{"text('120')+text('120')", document.NewTextValue("120"), document.NewTextValue("120"), document.NewNullValue(), false},
 		{"document+document", document.NewDocumentValue(document.NewFieldBuffer().Add("a", document.NewIntegerValue(10))), document.NewDocumentValue(document.NewFieldBuffer().Add("a", document.NewIntegerValue(10))), document.NewNullValue(), false},
@@ -147,8 +151,12 @@ func TestValueSub(t *testing.T) {
 		{"int16(250)-int16(220)", document.NewIntegerValue(250), document.NewIntegerValue(220), document.NewIntegerValue(30), false},
 		{"integer(120)-float64(620)", document.NewIntegerValue(120), document.NewDoubleValue(620), document.NewDoubleValue(-500), false},
 		{"integer(120)-float64(120.1)", document.NewIntegerValue(120), document.NewDoubleValue(120.1), document.NewDoubleValue(-0.09999999999999432), false},
-		{"int64(min)-integer(10)", document.NewIntegerValue(math.MinInt64), document.NewIntegerValue(10), document.NewDoubleValue(math.MinInt64 - 10), false},
-		{"int64(max)-integer(-10)", document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(-10), document.NewDoubleValue(math.MaxInt64 + 10), false},
+		{"int64(max)-int64(max)", document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(0), false},
+		{"integer(-1)-int64(max)", document.NewIntegerValue(-1), document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(math.MinInt64), false},
+		{"int64(min)-integer(10)", document.NewIntegerValue(math.MinInt64), document.NewIntegerValue(10), document.NewNullValue(), true},
+		{"int64(max)-integer(-10)", document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(-10), document.NewNullValue(), true},
+		{"integer(0)-int64(min)", document.NewIntegerValue(0), document.NewIntegerValue(math.MinInt64), document.NewNullValue(), true},
+		{"int64(min)-float64(10)", document.NewIntegerValue(math.MinInt64), document.NewDoubleValue(10), document.NewDoubleValue(math.MinInt64 - 10), false},
 		{"integer(120)-text('120')", document.NewIntegerValue(120), document.NewTextValue("120"), document.NewNullValue(), false},
 		{"text('120')-text('120')", document.NewTextValue("120"), document.NewTextValue("120"), document.NewNullValue(), false},
 		{"document-document", document.NewDocumentValue(document.NewFieldBuffer().Add("a", document.NewIntegerValue(10))), document.NewDocumentValue(document.NewFieldBuffer().Add("a", document.NewIntegerValue(10))), document.NewNullValue(), false},
@@ -182,7 +190,12 @@ func TestValueMult(t *testing.T) {
 		{"integer(10)*integer(10)", document.NewIntegerValue(10), document.NewIntegerValue(10), document.NewIntegerValue(100), false},
 		{"integer(10)*integer(80)", document.NewIntegerValue(10), document.NewIntegerValue(80), document.NewIntegerValue(800), false},
 		{"integer(10)*float64(80)", document.NewIntegerValue(10), document.NewDoubleValue(80), document.NewDoubleValue(800), false},
-		{"int64(max)*int64(max)", document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(math.MaxInt64), document.NewDoubleValue(math.MaxInt64 * math.MaxInt64), false},
+		{"int64(max)*integer(-1)", document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(-1), document.NewIntegerValue(-math.MaxInt64), false},
+		{"int64(max)*int64(max)", document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(math.MaxInt64), document.NewNullValue(), true},
+		{"int64(max)*integer(2)", document.NewIntegerValue(math.MaxInt64), document.NewIntegerValue(2), document.NewNullValue(), true},
+		{"int64(min)*integer(-1)", document.NewIntegerValue(math.MinInt64), document.NewIntegerValue(-1), document.NewNullValue(), true},
+		{"integer(-1)*int64(min)", document.NewIntegerValue(-1), document.NewIntegerValue(math.MinInt64), document.NewNullValue(), true},
+		{"int64(max)*float64(max)", document.NewIntegerValue(math.MaxInt64), document.NewDoubleValue(math.MaxInt64), document.NewDoubleValue(math.MaxInt64 * math.MaxInt64), false},
 		{"integer(120)*text('120')", document.NewIntegerValue(120), document.NewTextValue("120"), document.NewNullValue(), false},
 		{"text('120')*text('120')", document.NewTextValue("120"), document.NewTextValue("120"), document.NewNullValue(), false},
 		{"document*document", document.NewDocumentValue(document.NewFieldBuffer().Add("a", document.NewIntegerValue(10))), document.NewDocumentValue(document.NewFieldBuffer().Add("a", document.NewIntegerValue(10))), document.NewNullValue(), false},
@@ -218,6 +231,8 @@ func TestValueDiv(t *testing.T) {
 		{"integer(10)/integer(8)", document.NewIntegerValue(10), document.NewIntegerValue(8), document.NewIntegerValue(1), false},
 		{"integer(10)/float64(8)", document.NewIntegerValue(10), document.NewDoubleValue(8), document.NewDoubleValue(1.25), false},
 		{"int64(maxint)/float64(maxint)", document.NewIntegerValue(math.MaxInt64), document.NewDoubleValue(math.MaxInt64), document.NewDoubleValue(1), false},
+		{"int64(min)/integer(-1)", document.NewIntegerValue(math.MinInt64), document.NewIntegerValue(-1), document.NewNullValue(), true},
+		{"int64(min)/float64(-1)", document.NewIntegerValue(math.MinInt64), document.NewDoubleValue(-1), document.NewDoubleValue(-math.MinInt64), false},
 		{"integer(120)/text('120')", document.NewIntegerValue(120), document.NewTextValue("120"), document.NewNullValue(), false},
 		{"text('120')/text('120')", document.NewTextValue("120"), document.NewTextValue("120"), document.NewNullValue(), false},
 		{"document/document", document.NewDocumentValue(document.NewFieldBuffer().Add("a", document.NewIntegerValue(10))), document.NewDocumentValue(document.NewFieldBuffer().Add("a", document.NewIntegerValue(10))), document.NewNullValue(), false},
@@ -237,6 +252,53 @@ func TestValueDiv(t *testing.T) {
 	}
 }
 
+func TestValueArithmeticPromotion(t *testing.T) {
+	integer := document.NewIntegerValue(6)
+	double := document.NewDoubleValue(4)
+	others := []document.Value{
+		document.NewNullValue(),
+		document.NewBoolValue(true),
+		document.NewTextValue("6"),
+		document.NewBlobValue([]byte("6")),
+		document.NewArrayValue(document.NewValueBuffer(integer)),
+		document.NewDocumentValue(document.NewFieldBuffer().Add("a", integer)),
+	}
+
+	ops := map[string]func(v, u document.Value) (document.Value, error){
+		"+": document.Value.Add,
+		"-": document.Value.Sub,
+		"*": document.Value.Mul,
+		"/": document.Value.Div,
+		"%": document.Value.Mod,
+	}
+
+	type test struct {
+		v, u     document.Value
+		expected document.ValueType
+	}
+
+	tests := []test{
+		{integer, integer, document.IntegerValue},
+		{integer, double, document.DoubleValue},
+		{double, integer, document.DoubleValue},
+		{double, double, document.DoubleValue},
+	}
+	for _, o := range others {
+		tests = append(tests, test{integer, o, document.NullValue}, test{o, double, document.NullValue})
+	}
+
+	for name, op := range ops {
+		t.Run(name, func(t *testing.T) {
+
+			for _, test := range tests {
+				res, err := op(test.v, test.u)
+				require.NoError(t, err)
+				require.Equal(t, test.expected, res.Type, "%s %s %s", test.v, name, test.u)
+			}
+		})
+	}
+}
+
 func TestValueMod(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -372,3 +434,48 @@ func TestValueBitwiseXor(t *testing.T) {
 		})
 	}
 }
+
+func TestValueIsTruthy(t *testing.T) {
+	tests := []struct {
+		v        document.Value
+		expected bool
+	}{
+		{document.NewNullValue(), false},
+		{document.NewBoolValue(false), false},
+		{document.NewBoolValue(true), true},
+		{document.NewIntegerValue(0), false},
+		{document.NewIntegerValue(-1), true},
+		{document.NewDoubleValue(0), false},
+		{document.NewDoubleValue(0.5), true},
+		{document.NewTextValue(""), false},
+		{document.NewTextValue("a"), true},
+		{document.NewBlobValue([]byte{}), false},
+		{document.NewBlobValue([]byte{0}), true},
+		{document.NewArrayValue(document.NewValueBuffer()), false},
+		{document.NewArrayValue(document.NewValueBuffer(document.NewNullValue())), true},
+		{document.NewDocumentValue(document.NewFieldBuffer()), false},
+		{document.NewDocumentValue(document.NewFieldBuffer().Add("a", document.NewNullValue())), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.v.String(), func(t *testing.T) {
+			ok, err := test.v.IsTruthy()
+			require.NoError(t, err)
+			require.Equal(t, test.expected, ok)
+		})
+	}
+}
+
+func TestCollation(t *testing.T) {
+	c, err := document.ParseCollation("nocase")
+	require.NoError(t, err)
+	require.Equal(t, document.NoCaseCollation, c)
+	require.Equal(t, "NOCASE", c.String())
+
+	_, err = document.ParseCollation("foo")
+	require.Error(t, err)
+
+	require.Equal(t, document.NewTextValue("hello"), document.NoCaseCollation.Collate(document.NewTextValue("HeLLo")))
+	require.Equal(t, document.NewTextValue("HeLLo"), document.BinaryCollation.Collate(document.NewTextValue("HeLLo")))
+	require.Equal(t, document.NewIntegerValue(10), document.NoCaseCollation.Collate(document.NewIntegerValue(10)))
+}
@@ -17,6 +17,8 @@ func NewValue(x interface{}) (Value, error) {
 		return NewDoubleValue(v), nil
 	case string:
 		return NewTextValue(v), nil
+	case Point:
+		return NewPointValue(v), nil
 	}
 
 	return Value{}, &ErrUnsupportedType{x, ""}
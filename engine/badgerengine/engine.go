@@ -53,6 +53,9 @@ type Transaction struct {
 	tx        *badger.Txn
 	writable  bool
 	discarded bool
+	// names of the stores dropped by the transaction,
+	// whose sequences restart within the transaction.
+	dropped map[string]struct{}
 }
 
 // Rollback the transaction. Can be used safely after commit.
@@ -74,7 +77,11 @@ func (t *Transaction) Commit() error {
 	}
 
 	t.discarded = true
-	return t.tx.Commit()
+	err := t.tx.Commit()
+	if err == badger.ErrConflict {
+		return engine.ErrConflict
+	}
+	return err
 }
 
 func buildStoreKey(name []byte) []byte {
@@ -114,6 +121,7 @@ func (t *Transaction) GetStore(name []byte) (engine.Store, error) {
 	return &Store{
 		ng:       t.ng,
 		tx:       t.tx,
+		txn:      t,
 		prefix:   pkey,
 		writable: t.writable,
 		name:     name,
@@ -159,6 +167,21 @@ func (t *Transaction) DropStore(name []byte) error {
 	if err == badger.ErrKeyNotFound {
 		return engine.ErrStoreNotFound
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	// delete the sequence of the store within the transaction.
+	// until it is committed, the numbers of the sequence are generated
+	// by the transaction, see Store.NextSequence
+	err = t.tx.Delete([]byte(name))
+	if err != nil {
+		return err
+	}
+
+	if t.dropped == nil {
+		t.dropped = make(map[string]struct{})
+	}
+	t.dropped[string(name)] = struct{}{}
+	return nil
 }
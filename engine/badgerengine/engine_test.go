@@ -1,12 +1,15 @@
 package badgerengine_test
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path"
 	"testing"
 
 	"github.com/dgraph-io/badger/v2"
+	"github.com/genjidb/genji"
 	"github.com/genjidb/genji/engine"
 	"github.com/genjidb/genji/engine/badgerengine"
 	"github.com/genjidb/genji/engine/enginetest"
@@ -29,6 +32,191 @@ func TestBadgerEngine(t *testing.T) {
 	enginetest.TestSuite(t, builder(t))
 }
 
+func TestBadgerEngineConflict(t *testing.T) {
+	ng, cleanup := builder(t)()
+	defer cleanup()
+	defer ng.Close()
+
+	tx, err := ng.Begin(true)
+	require.NoError(t, err)
+	err = tx.CreateStore([]byte("test"))
+	require.NoError(t, err)
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	tx1, err := ng.Begin(true)
+	require.NoError(t, err)
+	defer tx1.Rollback()
+	tx2, err := ng.Begin(true)
+	require.NoError(t, err)
+	defer tx2.Rollback()
+
+	// tx1 reads a key written by tx2
+	st1, err := tx1.GetStore([]byte("test"))
+	require.NoError(t, err)
+	_, err = st1.Get([]byte("a"))
+	require.Equal(t, engine.ErrKeyNotFound, err)
+
+	st2, err := tx2.GetStore([]byte("test"))
+	require.NoError(t, err)
+	err = st2.Put([]byte("a"), []byte("2"))
+	require.NoError(t, err)
+	err = tx2.Commit()
+	require.NoError(t, err)
+
+	err = st1.Put([]byte("a"), []byte("1"))
+	require.NoError(t, err)
+	err = tx1.Commit()
+	require.Equal(t, engine.ErrConflict, err)
+}
+
+func TestBadgerEngineSequenceConcurrency(t *testing.T) {
+	ng, cleanup := builder(t)()
+	defer cleanup()
+	defer ng.Close()
+
+	tx, err := ng.Begin(true)
+	require.NoError(t, err)
+	err = tx.CreateStore([]byte("test"))
+	require.NoError(t, err)
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	next := func(tx engine.Transaction) uint64 {
+		st, err := tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		seq, err := st.NextSequence()
+		require.NoError(t, err)
+		err = st.Put([]byte{byte(seq)}, nil)
+		require.NoError(t, err)
+		return seq
+	}
+
+	// concurrent transactions generating keys don't conflict
+	tx1, err := ng.Begin(true)
+	require.NoError(t, err)
+	defer tx1.Rollback()
+	tx2, err := ng.Begin(true)
+	require.NoError(t, err)
+	defer tx2.Rollback()
+
+	require.Equal(t, uint64(1), next(tx1))
+	require.Equal(t, uint64(2), next(tx2))
+	require.Equal(t, uint64(3), next(tx1))
+	require.NoError(t, tx2.Commit())
+	require.NoError(t, tx1.Commit())
+
+	// the sequence of a dropped store restarts once the transaction is committed
+	tx, err = ng.Begin(true)
+	require.NoError(t, err)
+	require.NoError(t, tx.DropStore([]byte("test")))
+	require.NoError(t, tx.CreateStore([]byte("test")))
+	require.Equal(t, uint64(1), next(tx))
+	require.NoError(t, tx.Rollback())
+
+	tx, err = ng.Begin(true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), next(tx))
+	require.NoError(t, tx.DropStore([]byte("test")))
+	require.NoError(t, tx.CreateStore([]byte("test")))
+	require.Equal(t, uint64(1), next(tx))
+	require.Equal(t, uint64(2), next(tx))
+	require.NoError(t, tx.Commit())
+
+	tx, err = ng.Begin(true)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	require.Equal(t, uint64(3), next(tx))
+}
+
+func TestBadgerEngineSelectForUpdate(t *testing.T) {
+	ng, cleanup := builder(t)()
+	defer cleanup()
+
+	db, err := genji.New(ng)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	err = db.Exec(ctx, `CREATE TABLE test(a INTEGER PRIMARY KEY, b INTEGER); INSERT INTO test (a, b) VALUES (1, 1)`)
+	require.NoError(t, err)
+
+	tx1, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx1.Rollback()
+	tx2, err := db.Begin(true)
+	require.NoError(t, err)
+	defer tx2.Rollback()
+
+	err = tx1.Exec(ctx, `SELECT * FROM test WHERE a = 1 FOR UPDATE`)
+	require.NoError(t, err)
+	err = tx2.Exec(ctx, `UPDATE test SET b = 2 WHERE a = 1`)
+	require.NoError(t, err)
+
+	// the locked document was updated concurrently
+	require.NoError(t, tx1.Commit())
+	err = tx2.Commit()
+	require.True(t, errors.Is(err, engine.ErrConflict))
+}
+
+func TestBadgerEngineSelectForUpdateWithLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		locked []int
+	}{
+		{"Limit", `SELECT * FROM test LIMIT 1 FOR UPDATE`, []int{1}},
+		{"Offset", `SELECT * FROM test LIMIT 1 OFFSET 1 FOR UPDATE`, []int{2}},
+		{"Sorted", `SELECT a FROM test ORDER BY b DESC LIMIT 2 FOR UPDATE`, []int{2, 3}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ng, cleanup := builder(t)()
+			defer cleanup()
+
+			db, err := genji.New(ng)
+			require.NoError(t, err)
+			defer db.Close()
+
+			ctx := context.Background()
+			err = db.Exec(ctx, `CREATE TABLE test(a INTEGER PRIMARY KEY, b INTEGER); INSERT INTO test (a, b) VALUES (1, 1), (2, 2), (3, 3)`)
+			require.NoError(t, err)
+
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			err = tx.Exec(ctx, test.query)
+			require.NoError(t, err)
+
+			// every document is updated by a concurrent transaction:
+			// only the updates of the locked ones conflict
+			txs := make([]*genji.Tx, 3)
+			for i := range txs {
+				txs[i], err = db.Begin(true)
+				require.NoError(t, err)
+				defer txs[i].Rollback()
+
+				err = txs[i].Exec(ctx, `UPDATE test SET b = 10 WHERE pk() = ?`, i+1)
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, tx.Commit())
+
+			var locked []int
+			for i := range txs {
+				err = txs[i].Commit()
+				if err != nil {
+					require.True(t, errors.Is(err, engine.ErrConflict), err)
+					locked = append(locked, i+1)
+				}
+			}
+			require.Equal(t, test.locked, locked)
+		})
+	}
+}
+
 func BenchmarkBadgerEngineStorePut(b *testing.B) {
 	enginetest.BenchmarkStorePut(b, builder(b))
 }
@@ -2,6 +2,7 @@ package badgerengine
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 
 	"github.com/dgraph-io/badger/v2"
@@ -12,6 +13,7 @@ import (
 type Store struct {
 	ng       *Engine
 	tx       *badger.Txn
+	txn      *Transaction
 	prefix   []byte
 	writable bool
 	name     []byte
@@ -104,11 +106,19 @@ func (s *Store) Truncate() error {
 }
 
 // NextSequence returns a monotonically increasing integer.
+// It uses a Badger sequence, which is leased outside of the transaction
+// and never causes conflicts between concurrent transactions.
+// If the store was dropped by the transaction, the sequence restarts
+// and is incremented within the transaction instead, to be discarded on rollback.
 func (s *Store) NextSequence() (uint64, error) {
 	if !s.writable {
 		return 0, engine.ErrTransactionReadOnly
 	}
 
+	if _, ok := s.txn.dropped[string(s.name)]; ok {
+		return s.nextTxSequence()
+	}
+
 	// TODO: this is an ineficient way of generating sequences.
 	// use a bigger lease in the future.
 	seq, err := s.ng.DB.GetSequence([]byte(s.name), 1)
@@ -127,6 +137,36 @@ func (s *Store) NextSequence() (uint64, error) {
 	return nb + 1, nil
 }
 
+// nextTxSequence reads and increments the sequence within the transaction,
+// using the same format as Badger sequences, in which the key
+// holds the next number of the sequence as a big endian uint64.
+func (s *Store) nextTxSequence() (uint64, error) {
+	var nb uint64
+	it, err := s.tx.Get(s.name)
+	switch err {
+	case nil:
+		v, err := it.ValueCopy(nil)
+		if err != nil {
+			return 0, err
+		}
+		nb = binary.BigEndian.Uint64(v)
+	case badger.ErrKeyNotFound:
+	default:
+		return 0, err
+	}
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], nb+1)
+	err = s.tx.Set(s.name, buf[:])
+	if err != nil {
+		return 0, err
+	}
+
+	// the first number in a Badger sequence is always zero
+	// but Genji expects the first to be 1.
+	return nb + 1, nil
+}
+
 // NewIterator uses a Badger iterator with default options.
 // Only one iterator is allowed per read-write transaction.
 func (s *Store) NewIterator(cfg engine.IteratorConfig) engine.Iterator {
@@ -42,6 +42,12 @@ func (e *Engine) Begin(writable bool) (engine.Transaction, error) {
 	}, nil
 }
 
+// MaxKeySize returns the maximum size of the keys accepted by Bolt.
+// It implements the engine.KeySizeLimiter interface.
+func (e *Engine) MaxKeySize() int {
+	return bolt.MaxKeySize
+}
+
 // Close the engine and underlying Bolt database.
 func (e *Engine) Close() error {
 	return e.DB.Close()
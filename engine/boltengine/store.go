@@ -53,13 +53,20 @@ func (s *Store) Truncate() error {
 		return engine.ErrTransactionReadOnly
 	}
 
+	// the sequence is stored in the bucket and would be lost
+	seq := s.bucket.Sequence()
+
 	err := s.tx.DeleteBucket(s.name)
 	if err != nil {
 		return err
 	}
 
-	_, err = s.tx.CreateBucket(s.name)
-	return err
+	s.bucket, err = s.tx.CreateBucket(s.name)
+	if err != nil {
+		return err
+	}
+
+	return s.bucket.SetSequence(seq)
 }
 
 // NextSequence returns a monotonically increasing integer.
@@ -71,6 +78,26 @@ func (s *Store) NextSequence() (uint64, error) {
 	return s.bucket.NextSequence()
 }
 
+// Size returns the number of key-value pairs of the bucket and the number of bytes
+// used by its leaf pages, or by the bucket itself if it is stored inline,
+// as reported by the bucket statistics.
+// Statistics don't account for changes that are not committed yet, so the
+// content of buckets of writable transactions is iterated over instead.
+// It implements the engine.StoreSizer interface.
+func (s *Store) Size() (count int64, size int64, err error) {
+	if s.bucket.Writable() {
+		err = s.bucket.ForEach(func(k, v []byte) error {
+			count++
+			size += int64(len(k) + len(v))
+			return nil
+		})
+		return count, size, err
+	}
+
+	stats := s.bucket.Stats()
+	return int64(stats.KeyN), int64(stats.LeafInuse + stats.InlineBucketInuse), nil
+}
+
 // NewIterator uses the bucket cursor.
 func (s *Store) NewIterator(cfg engine.IteratorConfig) engine.Iterator {
 	return &iterator{
@@ -97,10 +124,14 @@ func (it *iterator) Seek(pivot []byte) {
 	}
 
 	it.item.k, it.item.v = it.c.Seek(pivot)
-	if it.item.k != nil {
-		for bytes.Compare(it.item.k, pivot) > 0 {
-			it.item.k, it.item.v = it.c.Prev()
-		}
+	// if the pivot is greater than every key, start from the last one
+	if it.item.k == nil {
+		it.item.k, it.item.v = it.c.Last()
+		return
+	}
+
+	for bytes.Compare(it.item.k, pivot) > 0 {
+		it.item.k, it.item.v = it.c.Prev()
 	}
 }
 
@@ -19,6 +19,11 @@ var (
 
 	// ErrKeyNotFound is returned when the targeted key doesn't exist.
 	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrConflict must be returned by Commit when the transaction conflicts
+	// with another transaction committed concurrently.
+	// The transaction can be run again.
+	ErrConflict = errors.New("transaction conflict")
 )
 
 // An Engine is responsible for storing data.
@@ -34,6 +39,13 @@ type Engine interface {
 	Close() error
 }
 
+// A KeySizeLimiter is an engine that limits the size of the keys of its stores.
+// Engines that don't implement it are expected to accept keys of any size.
+type KeySizeLimiter interface {
+	// MaxKeySize returns the maximum size of a key, in bytes.
+	MaxKeySize() int
+}
+
 // A Transaction provides methods for managing the collection of stores and the transaction itself.
 // The transaction is either read-only or read/write. Read-only transactions can be used to read stores
 // and read/write ones can be used to read, create, delete and modify stores.
@@ -50,7 +62,7 @@ type Transaction interface {
 	// Create a store with the given name. If the store already exists, it returns ErrStoreAlreadyExists.
 	CreateStore(name []byte) error
 	// Drop a store by name. If the store doesn't exist, it returns ErrStoreNotFound.
-	// It deletes all the values stored in it.
+	// It deletes all the values stored in it, as well as its sequence.
 	DropStore(name []byte) error
 }
 
@@ -64,6 +76,7 @@ type Store interface {
 	// Delete a key value pair. If the key is not found, returns ErrKeyNotFound.
 	Delete(k []byte) error
 	// Truncate deletes all the key value pairs from the store.
+	// The sequence of the store is left untouched.
 	Truncate() error
 	// NewIterator creates an iterator with the given config.
 	NewIterator(IteratorConfig) Iterator
@@ -71,17 +84,57 @@ type Store interface {
 	NextSequence() (uint64, error)
 }
 
+// A StoreSizer is a store that can estimate its size without reading all of its values.
+// Stores that don't implement it are measured by iterating over their content.
+type StoreSizer interface {
+	// Size returns the number of key-value pairs of the store and an estimate
+	// of the number of bytes they occupy.
+	Size() (count int64, bytes int64, err error)
+}
+
+// StoreSize returns the number of key-value pairs of the store and an estimate
+// of the number of bytes they occupy.
+// If the store implements the StoreSizer interface, its Size method is used,
+// otherwise the size is the sum of the lengths of all the keys and values of the store.
+func StoreSize(st Store) (count int64, bytes int64, err error) {
+	if s, ok := st.(StoreSizer); ok {
+		return s.Size()
+	}
+
+	var buf []byte
+	it := st.NewIterator(IteratorConfig{})
+	for it.Seek(nil); it.Valid(); it.Next() {
+		item := it.Item()
+		buf, err = item.ValueCopy(buf[:0])
+		if err != nil {
+			it.Close()
+			return 0, 0, err
+		}
+
+		count++
+		bytes += int64(len(item.Key()) + len(buf))
+	}
+
+	return count, bytes, it.Close()
+}
+
 // IteratorConfig is used to configure an iterator upon creation.
 type IteratorConfig struct {
+	// If true, the iterator goes through the keys in descending order.
 	Reverse bool
 }
 
-// An Iterator iterates on keys of a store in lexicographic order.
+// An Iterator iterates on keys of a store in lexicographic order,
+// or in reverse lexicographic order if created with the Reverse option.
 type Iterator interface {
 	// Seek moves the iterator to the selected key. If the key doesn't exist, it must move to the
 	// next smallest key greater than k.
+	// In reverse mode, if the key doesn't exist, it must move to the next greatest key
+	// lower than k.
+	// If k is nil, the iterator must move to the first key, or to the last key in reverse mode.
+	// Seek can be called again at any time to restart the iteration from another key.
 	Seek(k []byte)
-	// Next moves the iterator to the next item.
+	// Next moves the iterator to the next item, which is the previous key in reverse mode.
 	Next()
 	// Valid returns whether the iterator is positioned on a valid item or not.
 	Valid() bool
@@ -423,6 +423,66 @@ func TestTransactionDropStore(t *testing.T, builder Builder) {
 		err = tx.DropStore([]byte("store"))
 		require.Equal(t, engine.ErrStoreNotFound, err)
 	})
+
+	t.Run("Should drop the sequence", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.CreateStore([]byte("store"))
+		require.NoError(t, err)
+
+		st, err := tx.GetStore([]byte("store"))
+		require.NoError(t, err)
+
+		_, err = st.NextSequence()
+		require.NoError(t, err)
+
+		err = tx.DropStore([]byte("store"))
+		require.NoError(t, err)
+
+		err = tx.CreateStore([]byte("store"))
+		require.NoError(t, err)
+
+		st, err = tx.GetStore([]byte("store"))
+		require.NoError(t, err)
+
+		seq, err := st.NextSequence()
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), seq)
+	})
+
+	t.Run("Rollback should not restore a store created and dropped in the same transaction", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+
+		err = tx.CreateStore([]byte("store"))
+		require.NoError(t, err)
+
+		st, err := tx.GetStore([]byte("store"))
+		require.NoError(t, err)
+		err = st.Put([]byte("foo"), []byte("bar"))
+		require.NoError(t, err)
+
+		err = tx.DropStore([]byte("store"))
+		require.NoError(t, err)
+
+		err = tx.Rollback()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		_, err = tx.GetStore([]byte("store"))
+		require.Equal(t, engine.ErrStoreNotFound, err)
+	})
 }
 
 func storeBuilder(t testing.TB, builder Builder) (engine.Store, func()) {
@@ -619,6 +679,107 @@ func TestStoreIterator(t *testing.T, builder Builder) {
 		require.True(t, called)
 	})
 
+	t.Run("With reverse true, if pivot is greater than every key, should start from the last item", func(t *testing.T) {
+		st, cleanup := storeBuilder(t, builder)
+		defer cleanup()
+
+		err := st.Put([]byte{1}, []byte{1})
+		require.NoError(t, err)
+
+		err = st.Put([]byte{3}, []byte{3})
+		require.NoError(t, err)
+
+		var keys [][]byte
+		it := st.NewIterator(engine.IteratorConfig{Reverse: true})
+		defer it.Close()
+
+		for it.Seek([]byte{4}); it.Valid(); it.Next() {
+			keys = append(keys, append([]byte{}, it.Item().Key()...))
+		}
+		require.Equal(t, [][]byte{{3}, {1}}, keys)
+	})
+
+	t.Run("With reverse true, if pivot is lower than every key, should not be valid", func(t *testing.T) {
+		st, cleanup := storeBuilder(t, builder)
+		defer cleanup()
+
+		err := st.Put([]byte{2}, []byte{2})
+		require.NoError(t, err)
+
+		err = st.Put([]byte{3}, []byte{3})
+		require.NoError(t, err)
+
+		it := st.NewIterator(engine.IteratorConfig{Reverse: true})
+		defer it.Close()
+
+		it.Seek([]byte{1})
+		require.False(t, it.Valid())
+	})
+
+	t.Run("With reverse true and no pivot, should start from the last key", func(t *testing.T) {
+		st, cleanup := storeBuilder(t, builder)
+		defer cleanup()
+
+		// keys made of 0xFF bytes are the ones most likely to be skipped
+		// by an implementation seeking past the end of the store.
+		keys := [][]byte{{0x00}, {0xFF}, {0xFF, 0x00}, {0xFF, 0xFF}, {0xFF, 0xFF, 0xFF, 0x01}}
+		for _, k := range keys {
+			err := st.Put(k, k)
+			require.NoError(t, err)
+		}
+
+		var got [][]byte
+		it := st.NewIterator(engine.IteratorConfig{Reverse: true})
+		defer it.Close()
+
+		for it.Seek(nil); it.Valid(); it.Next() {
+			got = append(got, append([]byte{}, it.Item().Key()...))
+		}
+
+		require.Equal(t, [][]byte{{0xFF, 0xFF, 0xFF, 0x01}, {0xFF, 0xFF}, {0xFF, 0x00}, {0xFF}, {0x00}}, got)
+	})
+
+	t.Run("Seek should restart the iteration", func(t *testing.T) {
+		fn := func(t *testing.T, reverse bool, first, second []byte) {
+			st, cleanup := storeBuilder(t, builder)
+			defer cleanup()
+
+			for i := 1; i <= 3; i++ {
+				err := st.Put([]byte{uint8(i)}, []byte{uint8(i)})
+				require.NoError(t, err)
+			}
+
+			it := st.NewIterator(engine.IteratorConfig{Reverse: reverse})
+			defer it.Close()
+
+			it.Seek(nil)
+			require.True(t, it.Valid())
+			require.Equal(t, first, it.Item().Key())
+
+			// go to the end
+			for ; it.Valid(); it.Next() {
+			}
+
+			it.Seek([]byte{2})
+			require.True(t, it.Valid())
+			require.Equal(t, []byte{2}, it.Item().Key())
+			it.Next()
+			require.True(t, it.Valid())
+			require.Equal(t, second, it.Item().Key())
+
+			it.Seek(nil)
+			require.True(t, it.Valid())
+			require.Equal(t, first, it.Item().Key())
+		}
+
+		t.Run("Reverse: false", func(t *testing.T) {
+			fn(t, false, []byte{1}, []byte{3})
+		})
+		t.Run("Reverse: true", func(t *testing.T) {
+			fn(t, true, []byte{3}, []byte{1})
+		})
+	})
+
 	t.Run("With reverse true, one key in the store, and no pivot, should return that key", func(t *testing.T) {
 		st, cleanup := storeBuilder(t, builder)
 		defer cleanup()
@@ -635,6 +796,59 @@ func TestStoreIterator(t *testing.T, builder Builder) {
 		require.True(t, it.Valid())
 		require.Equal(t, it.Item().Key(), k)
 	})
+
+	t.Run("Should not return keys deleted in the same transaction", func(t *testing.T) {
+		fn := func(t *testing.T, reverse bool) {
+			st, cleanup := storeBuilder(t, builder)
+			defer cleanup()
+
+			// interleave puts and deletes, including keys
+			// that are deleted then put again.
+			for i := 1; i <= 10; i++ {
+				err := st.Put([]byte{uint8(i)}, []byte{uint8(i)})
+				require.NoError(t, err)
+
+				if i%3 == 0 {
+					err = st.Delete([]byte{uint8(i - 1)})
+					require.NoError(t, err)
+				}
+			}
+			err := st.Delete([]byte{10})
+			require.NoError(t, err)
+			err = st.Put([]byte{2}, []byte{22})
+			require.NoError(t, err)
+
+			want := [][]byte{{1}, {2}, {3}, {4}, {6}, {7}, {9}}
+			if reverse {
+				for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+					want[i], want[j] = want[j], want[i]
+				}
+			}
+
+			var got [][]byte
+			it := st.NewIterator(engine.IteratorConfig{Reverse: reverse})
+			defer it.Close()
+
+			for it.Seek(nil); it.Valid(); it.Next() {
+				got = append(got, append([]byte{}, it.Item().Key()...))
+			}
+			require.Equal(t, want, got)
+
+			// the value of a key put again after deletion is the new one
+			it.Seek([]byte{2})
+			require.True(t, it.Valid())
+			v, err := it.Item().ValueCopy(nil)
+			require.NoError(t, err)
+			require.Equal(t, []byte{22}, v)
+		}
+
+		t.Run("Reverse: false", func(t *testing.T) {
+			fn(t, false)
+		})
+		t.Run("Reverse: true", func(t *testing.T) {
+			fn(t, true)
+		})
+	})
 }
 
 // TestStorePut verifies Put behaviour.
@@ -755,6 +969,47 @@ func TestStoreDelete(t *testing.T, builder Builder) {
 		require.NoError(t, err)
 		require.Equal(t, []byte("BAR"), v)
 	})
+
+	t.Run("Should keep a key put again after being deleted", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.CreateStore([]byte("test"))
+		require.NoError(t, err)
+		st, err := tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		err = st.Put([]byte("foo"), []byte("FOO"))
+		require.NoError(t, err)
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		st, err = tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		err = st.Delete([]byte("foo"))
+		require.NoError(t, err)
+		err = st.Put([]byte("foo"), []byte("BAR"))
+		require.NoError(t, err)
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		st, err = tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		v, err := st.Get([]byte("foo"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("BAR"), v)
+	})
 }
 
 // TestStoreTruncate verifies Truncate behaviour.
@@ -784,6 +1039,60 @@ func TestStoreTruncate(t *testing.T, builder Builder) {
 		it.Seek(nil)
 		require.False(t, it.Valid())
 	})
+
+	t.Run("Should keep the sequence", func(t *testing.T) {
+		st, cleanup := storeBuilder(t, builder)
+		defer cleanup()
+
+		_, err := st.NextSequence()
+		require.NoError(t, err)
+		_, err = st.NextSequence()
+		require.NoError(t, err)
+
+		err = st.Truncate()
+		require.NoError(t, err)
+
+		// the store must still be usable
+		err = st.Put([]byte("foo"), []byte("FOO"))
+		require.NoError(t, err)
+
+		seq, err := st.NextSequence()
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), seq)
+	})
+
+	t.Run("Should persist after commit", func(t *testing.T) {
+		ng, cleanup := builder()
+		defer cleanup()
+
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		err = tx.CreateStore([]byte("test"))
+		require.NoError(t, err)
+		st, err := tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		err = st.Put([]byte("foo"), []byte("FOO"))
+		require.NoError(t, err)
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(true)
+		require.NoError(t, err)
+		st, err = tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		err = st.Truncate()
+		require.NoError(t, err)
+		err = tx.Commit()
+		require.NoError(t, err)
+
+		tx, err = ng.Begin(false)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		st, err = tx.GetStore([]byte("test"))
+		require.NoError(t, err)
+		_, err = st.Get([]byte("foo"))
+		require.Equal(t, engine.ErrKeyNotFound, err)
+	})
 }
 
 // TestStoreNextSequence verifies NextSequence behaviour.
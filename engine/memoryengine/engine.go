@@ -69,8 +69,8 @@ type transaction struct {
 }
 
 // If the transaction is writable, rollback calls
-// every function stored in the onRollback slice
-// to undo every mutation done since the beginning
+// every function stored in the onRollback slice,
+// in reverse order, to undo every mutation done since the beginning
 // of the transaction.
 func (tx *transaction) Rollback() error {
 	if tx.terminated {
@@ -82,8 +82,8 @@ func (tx *transaction) Rollback() error {
 	tx.wg.Wait()
 
 	if tx.writable {
-		for _, undo := range tx.onRollback {
-			undo()
+		for i := len(tx.onRollback) - 1; i >= 0; i-- {
+			tx.onRollback[i]()
 		}
 		tx.ng.mu.Unlock()
 	} else {
@@ -161,10 +161,13 @@ func (tx *transaction) DropStore(name []byte) error {
 	}
 
 	delete(tx.ng.stores, string(name))
+	seq := tx.ng.sequences[string(name)]
+	delete(tx.ng.sequences, string(name))
 
-	// on rollback put back the btree to the list of stores
+	// on rollback put back the btree and the sequence
 	tx.onRollback = append(tx.onRollback, func() {
 		tx.ng.stores[string(name)] = rb
+		tx.ng.sequences[string(name)] = seq
 	})
 
 	return nil
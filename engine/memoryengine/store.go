@@ -134,9 +134,12 @@ func (s *storeTx) Delete(k []byte) error {
 		i.deleted = false
 	})
 
-	// on commit, remove the item from the tree.
+	// on commit, remove the item from the tree,
+	// unless it was put again after being deleted.
 	s.tx.onCommit = append(s.tx.onCommit, func() {
-		s.tr.Delete(i)
+		if i.deleted {
+			s.tr.Delete(i)
+		}
 	})
 	return nil
 }
@@ -151,10 +154,12 @@ func (s *storeTx) Truncate() error {
 
 	old := s.tr
 	s.tr = btree.New(btreeDegree)
+	s.tx.ng.stores[s.name] = s.tr
 
 	// on rollback replace the new tree by the old one.
 	s.tx.onRollback = append(s.tx.onRollback, func() {
 		s.tr = old
+		s.tx.ng.stores[s.name] = old
 	})
 
 	return nil
@@ -14,12 +14,20 @@ import (
 const (
 	// storePrefix is the prefix used to name the index stores.
 	storePrefix = "i"
+
+	// statsStorePrefix is the prefix used to name the stores
+	// holding the statistics of the indexes.
+	statsStorePrefix = "s"
 )
 
+// key under which the statistics are stored in the stats store.
+var statsKey = []byte("stats")
+
 var valueTypes = []document.ValueType{
 	document.NullValue,
 	document.BoolValue,
 	document.DoubleValue,
+	document.PointValue,
 	document.TextValue,
 	document.BlobValue,
 	document.ArrayValue,
@@ -31,14 +39,27 @@ var (
 	ErrDuplicate = errors.New("duplicate")
 )
 
+// A KeyTooLongError is returned when the key of a value is longer than the MaxKeySize of the index.
+type KeyTooLongError struct {
+	Size    int
+	MaxSize int
+}
+
+func (e *KeyTooLongError) Error() string {
+	return fmt.Sprintf("index key too long: %d bytes, the maximum is %d", e.Size, e.MaxSize)
+}
+
 // An Index associates encoded values with keys.
 // It is sorted by value following the lexicographic order.
 type Index struct {
-	Unique bool
-	Type   document.ValueType
-
-	tx        engine.Transaction
-	storeName []byte
+	Unique     bool
+	Type       document.ValueType
+	Collation  document.Collation
+	MaxKeySize int
+
+	tx             engine.Transaction
+	storeName      []byte
+	statsStoreName []byte
 }
 
 // Options of the index.
@@ -47,15 +68,26 @@ type Options struct {
 
 	// If specified, the indexed expects only one type.
 	Type document.ValueType
+
+	// Collation used to encode text values.
+	Collation document.Collation
+
+	// Maximum size of the keys of the index store, in bytes.
+	// Values whose keys would be longer can't be indexed.
+	// If zero, the size of the keys is not limited.
+	MaxKeySize int
 }
 
 // NewIndex creates an index that associates a value with a list of keys.
 func NewIndex(tx engine.Transaction, idxName string, opts Options) *Index {
 	return &Index{
-		tx:        tx,
-		storeName: append([]byte(storePrefix), idxName...),
-		Unique:    opts.Unique,
-		Type:      opts.Type,
+		tx:             tx,
+		storeName:      append([]byte(storePrefix), idxName...),
+		statsStoreName: append([]byte(statsStorePrefix), idxName...),
+		Unique:         opts.Unique,
+		Type:           opts.Type,
+		Collation:      opts.Collation,
+		MaxKeySize:     opts.MaxKeySize,
 	}
 }
 
@@ -65,19 +97,52 @@ var errStop = errors.New("stop")
 // possible to associate multiple keys for the same value
 // but a key can be associated to only one value.
 func (idx *Index) Set(v document.Value, k []byte) error {
-	var err error
-
-	if len(k) == 0 {
-		return errors.New("cannot index value without a key")
+	st, err := getOrCreateStore(idx.tx, idx.storeName)
+	if err != nil {
+		return err
 	}
 
-	if idx.Type != 0 && idx.Type != v.Type {
-		return fmt.Errorf("cannot index value of type %s in %s index", v.Type, idx.Type)
+	return idx.set(st, v, k)
+}
+
+// An Entry associates a value with the key of a document.
+type Entry struct {
+	Value document.Value
+	Key   []byte
+}
+
+// SetBatch associates every value of the given entries with its key.
+// It behaves like calling Set for each entry but only looks up the index store once.
+// If one of the entries cannot be indexed, the error is returned and the remaining
+// entries are not indexed.
+func (idx *Index) SetBatch(entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
 	}
 
 	st, err := getOrCreateStore(idx.tx, idx.storeName)
 	if err != nil {
-		return nil
+		return err
+	}
+
+	for _, e := range entries {
+		err = idx.set(st, e.Value, e.Key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// set associates v with k.
+func (idx *Index) set(st engine.Store, v document.Value, k []byte) error {
+	if len(k) == 0 {
+		return errors.New("cannot index value without a key")
+	}
+
+	if !idx.accepts(v.Type) {
+		return fmt.Errorf("cannot index value of type %s in %s index", v.Type, idx.Type)
 	}
 
 	// encode the value we are going to use as a key
@@ -86,6 +151,19 @@ func (idx *Index) Set(v document.Value, k []byte) error {
 		return err
 	}
 
+	// the keys of non-unique indexes end with a suffix of up to
+	// binary.MaxVarintLen64 + 1 bytes. it is taken into account for all
+	// the keys, to accept or reject a value regardless of its duplicates.
+	if idx.MaxKeySize > 0 {
+		size := len(buf)
+		if !idx.Unique {
+			size += binary.MaxVarintLen64 + 1
+		}
+		if size > idx.MaxKeySize {
+			return &KeyTooLongError{Size: size, MaxSize: idx.MaxKeySize}
+		}
+	}
+
 	// lookup for an already existing value in the index.
 	var lookupKey = buf
 
@@ -125,22 +203,77 @@ func (idx *Index) Set(v document.Value, k []byte) error {
 	return st.Put(buf, k)
 }
 
-// Delete all the references to the key from the index.
+// Delete removes the entry associating the value v with the key k.
+// Other keys associated with the same value are left untouched.
+// If the entry doesn't exist, Delete does nothing.
 func (idx *Index) Delete(v document.Value, k []byte) error {
 	st, err := getOrCreateStore(idx.tx, idx.storeName)
 	if err != nil {
+		return err
+	}
+
+	return idx.delete(st, v, k)
+}
+
+// DeleteBatch removes the references of every entry from the index.
+// It behaves like calling Delete for each entry but only looks up the index store once.
+// Other keys associated with the same values are left untouched.
+func (idx *Index) DeleteBatch(entries []Entry) error {
+	if len(entries) == 0 {
 		return nil
 	}
 
+	st, err := getOrCreateStore(idx.tx, idx.storeName)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		err = idx.delete(st, e.Value, e.Key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// delete removes the entry associating v with k, if any.
+func (idx *Index) delete(st engine.Store, v document.Value, k []byte) error {
+	enc, err := idx.encodeValue(v)
+	if err != nil {
+		return err
+	}
+
 	var toDelete []byte
 	var buf []byte
 	err = idx.iterate(st, v, false, func(item engine.Item) error {
+		ik := item.Key()
+
+		// keys are sorted, stop as soon as the iterator reaches
+		// a key that doesn't start with the encoded value
+		if !bytes.HasPrefix(ik, enc) {
+			return errStop
+		}
+
+		// the last byte of the key of a non-unique index is the size of the varint.
+		if !idx.Unique {
+			n := ik[len(ik)-1]
+			ik = ik[:len(ik)-int(n)-1]
+		}
+
+		// ignore other values sharing the same prefix
+		if !bytes.Equal(ik, enc) {
+			return nil
+		}
+
+		var err error
 		buf, err = item.ValueCopy(buf[:0])
 		if err != nil {
 			return err
 		}
 		if bytes.Equal(buf, k) {
-			toDelete = item.Key()
+			toDelete = append([]byte{}, item.Key()...)
 			return errStop
 		}
 
@@ -150,11 +283,11 @@ func (idx *Index) Delete(v document.Value, k []byte) error {
 		return err
 	}
 
-	if toDelete != nil {
-		return st.Delete(toDelete)
+	if toDelete == nil {
+		return nil
 	}
 
-	return engine.ErrKeyNotFound
+	return st.Delete(toDelete)
 }
 
 // AscendGreaterOrEqual seeks for the pivot and then goes through all the subsequent key value pairs in increasing order and calls the given function for each pair.
@@ -172,7 +305,7 @@ func (idx *Index) DescendLessOrEqual(pivot document.Value, fn func(val, key []by
 }
 
 func (idx *Index) iterateOnStore(pivot document.Value, reverse bool, fn func(val, key []byte, isEqual bool) error) error {
-	if idx.Type != 0 && pivot.Type != 0 && idx.Type != pivot.Type {
+	if pivot.Type != 0 && !idx.accepts(pivot.Type) {
 		return nil
 	}
 
@@ -196,14 +329,7 @@ func (idx *Index) iterateOnStore(pivot document.Value, reverse bool, fn func(val
 	return idx.iterate(st, pivot, reverse, func(item engine.Item) error {
 		var err error
 
-		k := item.Key()
-
-		// the last byte of the key of a non-unique index is the size of the varint.
-		// if that byte is 0, it means that key is not duplicated.
-		if !idx.Unique {
-			n := k[len(k)-1]
-			k = k[:len(k)-int(n)-1]
-		}
+		k := idx.trimKey(item.Key())
 
 		buf, err = item.ValueCopy(buf[:0])
 		if err != nil {
@@ -214,25 +340,229 @@ func (idx *Index) iterateOnStore(pivot document.Value, reverse bool, fn func(val
 	})
 }
 
-// Truncate deletes all the index data.
+// SeekRange seeks for low and then goes through all the key value pairs whose value is lower than or equal
+// to high, in increasing order, and calls the given function for each pair.
+// If low is empty, starts from the beginning. If high is empty, goes through all the subsequent pairs.
+// If the given function returns an error, the iteration stops and returns that error.
+func (idx *Index) SeekRange(low, high document.Value, fn func(val, key []byte) error) error {
+	if (low.Type != 0 && !idx.accepts(low.Type)) || (high.Type != 0 && !idx.accepts(high.Type)) {
+		return nil
+	}
+
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err != nil && err != engine.ErrStoreNotFound {
+		return err
+	}
+	if st == nil {
+		return nil
+	}
+
+	var seek, end []byte
+	if low.Type != 0 {
+		seek, err = idx.encodeValue(low)
+		if err != nil {
+			return err
+		}
+	}
+	if high.Type != 0 {
+		end, err = idx.encodeValue(high)
+		if err != nil {
+			return err
+		}
+	}
+
+	it := st.NewIterator(engine.IteratorConfig{})
+	defer it.Close()
+
+	var buf []byte
+	for it.Seek(seek); it.Valid(); it.Next() {
+		item := it.Item()
+
+		k := idx.trimKey(item.Key())
+		if end != nil && bytes.Compare(k, end) > 0 {
+			return nil
+		}
+
+		buf, err = item.ValueCopy(buf[:0])
+		if err != nil {
+			return err
+		}
+
+		err = fn(k, buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// trimKey returns the encoded value of a key of the index store.
+func (idx *Index) trimKey(k []byte) []byte {
+	// the last byte of the key of a non-unique index is the size of the varint.
+	// if that byte is 0, it means that key is not duplicated.
+	if !idx.Unique {
+		n := k[len(k)-1]
+		k = k[:len(k)-int(n)-1]
+	}
+
+	return k
+}
+
+// Truncate deletes all the index data, including its statistics.
 func (idx *Index) Truncate() error {
 	err := idx.tx.DropStore(idx.storeName)
 	if err != nil && err != engine.ErrStoreNotFound {
 		return err
 	}
 
+	err = idx.tx.DropStore(idx.statsStoreName)
+	if err != nil && err != engine.ErrStoreNotFound {
+		return err
+	}
+
 	return nil
 }
 
+// Size returns the number of entries of the index and an estimate of the number
+// of bytes they occupy in the underlying store.
+func (idx *Index) Size() (entries int64, size int64, err error) {
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err == engine.ErrStoreNotFound {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return engine.StoreSize(st)
+}
+
+// Stats holds approximate statistics about the content of an index.
+// They are computed by Analyze and are not updated when entries are added or removed,
+// to keep the writes to the index cheap and free of conflicts.
+// They are used by the query planner to estimate the selectivity of the index.
+type Stats struct {
+	// Entries is the number of entries of the index.
+	Entries int64
+	// DistinctValues is the number of distinct values stored in the index.
+	DistinctValues int64
+}
+
+// Stats returns the statistics of the index, as computed by the last call to Analyze.
+// Indexes that were never analyzed return empty statistics.
+func (idx *Index) Stats() (Stats, error) {
+	var stats Stats
+
+	st, err := idx.tx.GetStore(idx.statsStoreName)
+	if err == engine.ErrStoreNotFound {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+
+	v, err := st.Get(statsKey)
+	if err == engine.ErrKeyNotFound {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+
+	var n int
+	stats.Entries, n = binary.Varint(v)
+	if n <= 0 {
+		return stats, errors.New("cannot decode index statistics")
+	}
+	stats.DistinctValues, n = binary.Varint(v[n:])
+	if n <= 0 {
+		return stats, errors.New("cannot decode index statistics")
+	}
+
+	return stats, nil
+}
+
+// Analyze recomputes the statistics of the index by reading all of its entries once,
+// stores them and returns them.
+// It allows to fix statistics that are missing or out of date.
+func (idx *Index) Analyze() (Stats, error) {
+	var stats Stats
+
+	st, err := idx.tx.GetStore(idx.storeName)
+	if err != nil && err != engine.ErrStoreNotFound {
+		return stats, err
+	}
+
+	if st != nil {
+		// the entries of a value are not always contiguous, since values
+		// sharing the same prefix may be stored between them.
+		distinct := make(map[string]struct{})
+
+		it := st.NewIterator(engine.IteratorConfig{})
+		for it.Seek(nil); it.Valid(); it.Next() {
+			stats.Entries++
+			distinct[string(idx.trimKey(it.Item().Key()))] = struct{}{}
+		}
+		err = it.Close()
+		if err != nil {
+			return stats, err
+		}
+
+		stats.DistinctValues = int64(len(distinct))
+	}
+
+	return stats, idx.putStats(stats)
+}
+
+// putStats stores the statistics of the index.
+func (idx *Index) putStats(stats Stats) error {
+	st, err := getOrCreateStore(idx.tx, idx.statsStoreName)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 2*binary.MaxVarintLen64)
+	n := binary.PutVarint(buf, stats.Entries)
+	n += binary.PutVarint(buf[n:], stats.DistinctValues)
+
+	return st.Put(statsKey, buf[:n])
+}
+
+// accepts returns whether values of type t can be stored in the index.
+// Numbers are encoded the same way regardless of their type, which allows
+// integers and doubles to be compared in numeric indexes.
+func (idx *Index) accepts(t document.ValueType) bool {
+	if idx.Type == 0 || idx.Type == t {
+		return true
+	}
+
+	return idx.Type.IsNumber() && t.IsNumber()
+}
+
+// EncodeValue encodes v the same way it is encoded by Set, which allows
+// to compare it with the values returned by AscendGreaterOrEqual and DescendLessOrEqual.
+func (idx *Index) EncodeValue(v document.Value) ([]byte, error) {
+	return idx.encodeValue(v)
+}
+
 // encode the value we are going to use as a key
 // if the index is typed, encode the value without expecting
 // the presence of other types.
 // if not, encode so that order is preserved regardless of the type.
+// numbers are always encoded using the same form regardless of their type,
+// so that integers and doubles that are equal have the same encoding.
+// text values are encoded according to the collation of the index.
 func (idx *Index) encodeValue(v document.Value) (buf []byte, err error) {
-	if idx.Type != 0 {
+	v = idx.Collation.Collate(v)
+
+	switch {
+	case idx.Type.IsNumber():
+		buf, err = key.AppendNumber(buf, v)
+	case idx.Type != 0:
 		buf, err = key.Append(buf, v.Type, v.V)
-	} else {
-		buf, err = key.AppendValue(buf, v)
+	default:
+		buf, err = key.KeyEncode(v)
 	}
 	return
 }
@@ -274,7 +604,8 @@ func (idx *Index) iterate(st engine.Store, pivot document.Value, reverse bool, f
 		pivot.Type = document.DoubleValue
 	}
 
-	if pivot.Type != 0 && pivot.V == nil {
+	// values of typed indexes are not prefixed by their type
+	if idx.Type == 0 && pivot.Type != 0 && pivot.V == nil {
 		seek = []byte{byte(pivot.Type)}
 
 		if reverse {
@@ -4,7 +4,9 @@ package index_test
 import (
 	"errors"
 	"fmt"
+	"math/rand"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/genjidb/genji/document"
@@ -61,6 +63,97 @@ func TestIndexSet(t *testing.T) {
 		require.NoError(t, idx.Set(document.NewIntegerValue(11), []byte("key")))
 		require.Equal(t, index.ErrDuplicate, idx.Set(document.NewIntegerValue(10), []byte("key")))
 	})
+
+	t.Run("Unique: true, Documents with different field orders", func(t *testing.T) {
+		idx, cleanup := getIndex(t, true)
+		defer cleanup()
+
+		d1 := document.NewFieldBuffer().Add("a", document.NewIntegerValue(1)).Add("b", document.NewIntegerValue(2))
+		d2 := document.NewFieldBuffer().Add("b", document.NewIntegerValue(2)).Add("a", document.NewIntegerValue(1))
+
+		require.NoError(t, idx.Set(document.NewDocumentValue(d1), []byte("key1")))
+		require.Equal(t, index.ErrDuplicate, idx.Set(document.NewDocumentValue(d2), []byte("key2")))
+	})
+
+	for _, unique := range []bool{true, false} {
+		t.Run(fmt.Sprintf("Unique: %v, MaxKeySize", unique), func(t *testing.T) {
+			idx, cleanup := getIndex(t, unique)
+			defer cleanup()
+			idx.MaxKeySize = 32
+
+			require.NoError(t, idx.Set(document.NewTextValue("short"), []byte("key1")))
+
+			err := idx.Set(document.NewTextValue(strings.Repeat("a", 32)), []byte("key2"))
+			var kErr *index.KeyTooLongError
+			require.True(t, errors.As(err, &kErr), "%v", err)
+			require.Equal(t, 32, kErr.MaxSize)
+			require.Greater(t, kErr.Size, 32)
+
+			// the value wasn't indexed
+			var count int
+			err = idx.AscendGreaterOrEqual(document.Value{Type: document.TextValue}, func(val, key []byte, isEqual bool) error {
+				count++
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+		})
+	}
+}
+
+func TestIndexSetBatch(t *testing.T) {
+	for _, unique := range []bool{true, false} {
+		text := fmt.Sprintf("Unique: %v, ", unique)
+
+		t.Run(text+"Empty batch succeeds", func(t *testing.T) {
+			idx, cleanup := getIndex(t, unique)
+			defer cleanup()
+
+			require.NoError(t, idx.SetBatch(nil))
+		})
+
+		t.Run(text+"Set all entries", func(t *testing.T) {
+			idx, cleanup := getIndex(t, unique)
+			defer cleanup()
+
+			err := idx.SetBatch([]index.Entry{
+				{Value: document.NewIntegerValue(12), Key: []byte("key3")},
+				{Value: document.NewIntegerValue(10), Key: []byte("key1")},
+				{Value: document.NewIntegerValue(11), Key: []byte("key2")},
+			})
+			require.NoError(t, err)
+
+			var keys []string
+			err = idx.AscendGreaterOrEqual(document.Value{}, func(v, k []byte, isEqual bool) error {
+				keys = append(keys, string(k))
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, []string{"key1", "key2", "key3"}, keys)
+		})
+
+		t.Run(text+"Nil key fails", func(t *testing.T) {
+			idx, cleanup := getIndex(t, unique)
+			defer cleanup()
+
+			err := idx.SetBatch([]index.Entry{
+				{Value: document.NewIntegerValue(10), Key: []byte("key1")},
+				{Value: document.NewIntegerValue(11)},
+			})
+			require.Error(t, err)
+		})
+	}
+
+	t.Run("Unique: true, Duplicate", func(t *testing.T) {
+		idx, cleanup := getIndex(t, true)
+		defer cleanup()
+
+		err := idx.SetBatch([]index.Entry{
+			{Value: document.NewIntegerValue(10), Key: []byte("key1")},
+			{Value: document.NewIntegerValue(10), Key: []byte("key2")},
+		})
+		require.Equal(t, index.ErrDuplicate, err)
+	})
 }
 
 func TestIndexDelete(t *testing.T) {
@@ -129,11 +222,269 @@ func TestIndexDelete(t *testing.T) {
 	for _, unique := range []bool{true, false} {
 		text := fmt.Sprintf("Unique: %v, ", unique)
 
-		t.Run(text+"Delete non existing key fails", func(t *testing.T) {
+		t.Run(text+"Delete non existing key is a no-op", func(t *testing.T) {
+			idx, cleanup := getIndex(t, unique)
+			defer cleanup()
+
+			require.NoError(t, idx.Delete(document.NewTextValue("foo"), []byte("foo")))
+		})
+
+		t.Run(text+"Delete only removes the exact entry", func(t *testing.T) {
+			idx, cleanup := getIndex(t, unique)
+			defer cleanup()
+
+			require.NoError(t, idx.Set(document.NewTextValue("a"), []byte("key1")))
+			require.NoError(t, idx.Set(document.NewTextValue("b"), []byte("key2")))
+			require.NoError(t, idx.Set(document.NewTextValue("c"), []byte("key1")))
+
+			// the key exists but is associated with other values
+			require.NoError(t, idx.Delete(document.NewTextValue("b"), []byte("key1")))
+			require.NoError(t, idx.Delete(document.NewTextValue("c"), []byte("key1")))
+
+			var keys []string
+			err := idx.AscendGreaterOrEqual(document.Value{}, func(v, k []byte, isEqual bool) error {
+				keys = append(keys, string(k))
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, []string{"key1", "key2"}, keys)
+		})
+	}
+}
+
+func TestIndexDeleteBatch(t *testing.T) {
+	for _, unique := range []bool{true, false} {
+		text := fmt.Sprintf("Unique: %v, ", unique)
+
+		t.Run(text+"Delete only the specified entries", func(t *testing.T) {
+			idx, cleanup := getIndex(t, unique)
+			defer cleanup()
+
+			for i := 0; i < 5; i++ {
+				require.NoError(t, idx.Set(document.NewIntegerValue(int64(i)), []byte("key"+strconv.Itoa(i))))
+			}
+
+			err := idx.DeleteBatch([]index.Entry{
+				{Value: document.NewIntegerValue(1), Key: []byte("key1")},
+				{Value: document.NewIntegerValue(3), Key: []byte("key3")},
+			})
+			require.NoError(t, err)
+
+			var keys []string
+			err = idx.AscendGreaterOrEqual(document.Value{}, func(v, k []byte, isEqual bool) error {
+				keys = append(keys, string(k))
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, []string{"key0", "key2", "key4"}, keys)
+		})
+	}
+
+	t.Run("Unique: false, Delete only the specified keys of a duplicated value", func(t *testing.T) {
+		idx, cleanup := getIndex(t, false)
+		defer cleanup()
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, idx.Set(document.NewIntegerValue(10), []byte("key"+strconv.Itoa(i))))
+		}
+
+		err := idx.DeleteBatch([]index.Entry{
+			{Value: document.NewIntegerValue(10), Key: []byte("key0")},
+			{Value: document.NewIntegerValue(10), Key: []byte("key2")},
+		})
+		require.NoError(t, err)
+
+		var keys []string
+		err = idx.AscendGreaterOrEqual(document.Value{}, func(v, k []byte, isEqual bool) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"key1"}, keys)
+	})
+}
+
+func TestIndexStats(t *testing.T) {
+	t.Run("Empty index", func(t *testing.T) {
+		idx, cleanup := getIndex(t, false)
+		defer cleanup()
+
+		stats, err := idx.Stats()
+		require.NoError(t, err)
+		require.Equal(t, index.Stats{}, stats)
+	})
+
+	t.Run("Writes", func(t *testing.T) {
+		idx, cleanup := getIndex(t, false)
+		defer cleanup()
+
+		requireStats := func(entries, distinct int64) {
+			t.Helper()
+			stats, err := idx.Stats()
+			require.NoError(t, err)
+			require.Equal(t, index.Stats{Entries: entries, DistinctValues: distinct}, stats)
+		}
+
+		// the statistics are only computed by Analyze
+		require.NoError(t, idx.Set(document.NewIntegerValue(10), []byte("a")))
+		require.NoError(t, idx.Set(document.NewIntegerValue(10), []byte("b")))
+		require.NoError(t, idx.SetBatch([]index.Entry{{Value: document.NewIntegerValue(20), Key: []byte("c")}}))
+		requireStats(0, 0)
+
+		_, err := idx.Analyze()
+		require.NoError(t, err)
+		requireStats(3, 2)
+
+		require.NoError(t, idx.Delete(document.NewIntegerValue(10), []byte("a")))
+		require.NoError(t, idx.DeleteBatch([]index.Entry{{Value: document.NewIntegerValue(10), Key: []byte("b")}}))
+		requireStats(3, 2)
+
+		_, err = idx.Analyze()
+		require.NoError(t, err)
+		requireStats(1, 1)
+
+		require.NoError(t, idx.Truncate())
+		requireStats(0, 0)
+	})
+
+	t.Run("Analyze", func(t *testing.T) {
+		ng := memoryengine.NewEngine()
+		tx, err := ng.Begin(true)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		idx := index.NewIndex(tx, "foo", index.Options{})
+
+		// analyzing an empty index
+		stats, err := idx.Analyze()
+		require.NoError(t, err)
+		require.Equal(t, index.Stats{}, stats)
+
+		// values sharing the same prefix
+		for i, v := range []string{"a", "ab", "a", "b", "ab", "a"} {
+			require.NoError(t, idx.Set(document.NewTextValue(v), []byte{byte(i)}))
+		}
+
+		// the statistics of the empty index are kept until it is analyzed again
+		stats, err = idx.Stats()
+		require.NoError(t, err)
+		require.Equal(t, index.Stats{}, stats)
+
+		stats, err = idx.Analyze()
+		require.NoError(t, err)
+		require.Equal(t, index.Stats{Entries: 6, DistinctValues: 3}, stats)
+
+		stats, err = idx.Stats()
+		require.NoError(t, err)
+		require.Equal(t, index.Stats{Entries: 6, DistinctValues: 3}, stats)
+	})
+}
+
+func TestIndexIterationAfterDelete(t *testing.T) {
+	// values sharing a prefix are stored next to each other
+	values := []document.Value{
+		document.NewTextValue("a"),
+		document.NewTextValue("ab"),
+		document.NewTextValue("abc"),
+		document.NewTextValue("b"),
+		document.NewIntegerValue(1),
+		document.NewIntegerValue(10),
+		document.NewDoubleValue(1.5),
+		document.NewBoolValue(true),
+	}
+
+	type entry struct {
+		val string
+		key string
+	}
+
+	for _, unique := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Unique: %v", unique), func(t *testing.T) {
 			idx, cleanup := getIndex(t, unique)
 			defer cleanup()
 
-			require.Error(t, idx.Delete(document.NewTextValue("foo"), []byte("foo")))
+			rnd := rand.New(rand.NewSource(42))
+
+			// live entries, by key
+			live := make(map[string]document.Value)
+			var keys []string
+
+			requireLiveEntries := func() {
+				t.Helper()
+
+				var want []entry
+				distinct := make(map[string]bool)
+				for k, v := range live {
+					enc, err := idx.EncodeValue(v)
+					require.NoError(t, err)
+					want = append(want, entry{string(enc), k})
+					distinct[string(enc)] = true
+				}
+
+				for _, reverse := range []bool{false, true} {
+					var got []entry
+					fn := func(val, key []byte, isEqual bool) error {
+						if len(got) > 0 {
+							cmp := strings.Compare(got[len(got)-1].val, string(val))
+							if reverse {
+								require.GreaterOrEqual(t, cmp, 0)
+							} else {
+								require.LessOrEqual(t, cmp, 0)
+							}
+						}
+						got = append(got, entry{string(val), string(key)})
+						return nil
+					}
+
+					var err error
+					if reverse {
+						err = idx.DescendLessOrEqual(document.Value{}, fn)
+					} else {
+						err = idx.AscendGreaterOrEqual(document.Value{}, fn)
+					}
+					require.NoError(t, err)
+					require.ElementsMatch(t, want, got)
+				}
+
+				stats, err := idx.Analyze()
+				require.NoError(t, err)
+				require.Equal(t, index.Stats{Entries: int64(len(live)), DistinctValues: int64(len(distinct))}, stats)
+			}
+
+			for i := 0; i < 500; i++ {
+				if len(keys) == 0 || rnd.Intn(5) < 3 {
+					v := values[rnd.Intn(len(values))]
+					k := strconv.Itoa(i)
+
+					err := idx.Set(v, []byte(k))
+					if err == index.ErrDuplicate {
+						continue
+					}
+					require.NoError(t, err)
+					live[k] = v
+					keys = append(keys, k)
+				} else {
+					j := rnd.Intn(len(keys))
+					k := keys[j]
+
+					require.NoError(t, idx.Delete(live[k], []byte(k)))
+					delete(live, k)
+					keys = append(keys[:j], keys[j+1:]...)
+				}
+
+				if i%50 == 0 {
+					requireLiveEntries()
+				}
+			}
+
+			requireLiveEntries()
+
+			// deleting every entry must leave the index empty
+			for k, v := range live {
+				require.NoError(t, idx.Delete(v, []byte(k)))
+				delete(live, k)
+			}
+			requireLiveEntries()
 		})
 	}
 }
@@ -238,7 +589,7 @@ func TestIndexAscendGreaterThan(t *testing.T) {
 
 			for i := int64(0); i < 10; i++ {
 				require.NoError(t, idx.Set(document.NewIntegerValue(i), []byte{'i', 'a' + byte(i)}))
-				require.NoError(t, idx.Set(document.NewDoubleValue(float64(i)), []byte{'d', 'a' + byte(i)}))
+				require.NoError(t, idx.Set(document.NewDoubleValue(float64(i)+0.5), []byte{'d', 'a' + byte(i)}))
 				require.NoError(t, idx.Set(document.NewTextValue(strconv.Itoa(int(i+10))), []byte{'s', 'a' + byte(i)}))
 			}
 
@@ -250,7 +601,7 @@ func TestIndexAscendGreaterThan(t *testing.T) {
 					require.Equal(t, []byte{'i', 'a' + byte(ints)}, rid)
 					ints++
 				} else if count < 20 {
-					requireEqualEncoded(t, document.NewDoubleValue(float64(doubles)), val)
+					requireEqualEncoded(t, document.NewDoubleValue(float64(doubles)+0.5), val)
 					require.Equal(t, []byte{'d', 'a' + byte(doubles)}, rid)
 					doubles++
 				} else {
@@ -278,7 +629,8 @@ func TestIndexAscendGreaterThan(t *testing.T) {
 
 			var ints int
 			err := idx.AscendGreaterOrEqual(document.Value{}, func(val, rid []byte, isEqual bool) error {
-				enc, err := key.Append(nil, document.IntegerValue, int64(ints))
+				// numbers are encoded the same way regardless of their type
+				enc, err := key.AppendNumber(nil, document.NewIntegerValue(int64(ints)))
 				require.NoError(t, err)
 				require.Equal(t, enc, val)
 				require.Equal(t, []byte{'i', 'a' + byte(ints)}, rid)
@@ -462,3 +814,137 @@ func BenchmarkIndexIteration(b *testing.B) {
 		})
 	}
 }
+
+func TestIndexSeekRange(t *testing.T) {
+	for _, typ := range []document.ValueType{0, document.IntegerValue} {
+		for _, unique := range []bool{true, false} {
+			text := fmt.Sprintf("Type: %v, Unique: %v, ", typ, unique)
+
+			t.Run(text, func(t *testing.T) {
+				idx, cleanup := getIndex(t, unique)
+				idx.Type = typ
+				defer cleanup()
+
+				for i := 0; i < 10; i++ {
+					require.NoError(t, idx.Set(document.NewIntegerValue(int64(i)), []byte{'a' + byte(i)}))
+				}
+				if !unique {
+					require.NoError(t, idx.Set(document.NewIntegerValue(5), []byte("z")))
+				}
+
+				tests := []struct {
+					name      string
+					low, high document.Value
+					keys      string
+				}{
+					{"point", document.NewIntegerValue(3), document.NewIntegerValue(3), "d"},
+					{"point / duplicated", document.NewIntegerValue(5), document.NewIntegerValue(5), "fz"},
+					{"point / missing", document.NewIntegerValue(20), document.NewIntegerValue(20), ""},
+					{"range", document.NewIntegerValue(2), document.NewIntegerValue(4), "cde"},
+					{"range / doubles", document.NewDoubleValue(1.5), document.NewDoubleValue(4.5), "cde"},
+					{"range / empty", document.NewIntegerValue(4), document.NewIntegerValue(2), ""},
+					{"range / between values", document.NewDoubleValue(2.2), document.NewDoubleValue(2.8), ""},
+					{"no low", document.Value{}, document.NewIntegerValue(1), "ab"},
+					{"no high", document.NewIntegerValue(8), document.Value{}, "ij"},
+					{"no bounds", document.Value{}, document.Value{}, "abcdefzghij"},
+					{"other type", document.NewTextValue("a"), document.NewTextValue("b"), ""},
+				}
+
+				for _, test := range tests {
+					var keys []byte
+					err := idx.SeekRange(test.low, test.high, func(val, key []byte) error {
+						keys = append(keys, key...)
+						return nil
+					})
+					require.NoError(t, err)
+
+					// z is only associated with a duplicated value
+					expected := test.keys
+					if unique {
+						expected = strings.ReplaceAll(expected, "z", "")
+					}
+					require.Equal(t, expected, string(keys), test.name)
+				}
+			})
+		}
+	}
+
+	t.Run("Stops on error", func(t *testing.T) {
+		idx, cleanup := getIndex(t, false)
+		defer cleanup()
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, idx.Set(document.NewIntegerValue(int64(i)), []byte{'a' + byte(i)}))
+		}
+
+		var count int
+		err := idx.SeekRange(document.NewIntegerValue(2), document.NewIntegerValue(8), func(val, key []byte) error {
+			count++
+			if count == 2 {
+				return errors.New("some error")
+			}
+			return nil
+		})
+		require.EqualError(t, err, "some error")
+		require.Equal(t, 2, count)
+	})
+}
+
+func TestIndexNumbers(t *testing.T) {
+	for _, typ := range []document.ValueType{0, document.IntegerValue, document.DoubleValue} {
+		for _, unique := range []bool{true, false} {
+			text := fmt.Sprintf("Type: %v, Unique: %v, ", typ, unique)
+
+			getTypedIndex := func(t *testing.T) (*index.Index, func()) {
+				idx, cleanup := getIndex(t, unique)
+				idx.Type = typ
+				return idx, cleanup
+			}
+
+			t.Run(text+"Integers and doubles are compared by value", func(t *testing.T) {
+				idx, cleanup := getTypedIndex(t)
+				defer cleanup()
+
+				require.NoError(t, idx.Set(document.NewIntegerValue(10), []byte("a")))
+				require.NoError(t, idx.Set(document.NewDoubleValue(10.5), []byte("b")))
+				require.NoError(t, idx.Set(document.NewDoubleValue(20), []byte("c")))
+
+				tests := []struct {
+					pivot   document.Value
+					keys    []string
+					isEqual bool
+				}{
+					{document.NewDoubleValue(10), []string{"a", "b", "c"}, true},
+					{document.NewIntegerValue(10), []string{"a", "b", "c"}, true},
+					{document.NewDoubleValue(10.2), []string{"b", "c"}, false},
+					{document.NewIntegerValue(11), []string{"c"}, false},
+					{document.NewIntegerValue(20), []string{"c"}, true},
+					{document.NewDoubleValue(-1.5), []string{"a", "b", "c"}, false},
+				}
+
+				for _, test := range tests {
+					var keys []string
+					err := idx.AscendGreaterOrEqual(test.pivot, func(val, key []byte, isEqual bool) error {
+						if len(keys) == 0 {
+							require.Equal(t, test.isEqual, isEqual, "pivot %v", test.pivot)
+						}
+						keys = append(keys, string(key))
+						return nil
+					})
+					require.NoError(t, err)
+					require.Equal(t, test.keys, keys, "pivot %v", test.pivot)
+				}
+			})
+
+			if unique {
+				t.Run(text+"Equal integers and doubles are duplicates", func(t *testing.T) {
+					idx, cleanup := getTypedIndex(t)
+					defer cleanup()
+
+					require.NoError(t, idx.Set(document.NewIntegerValue(10), []byte("a")))
+					require.Equal(t, index.ErrDuplicate, idx.Set(document.NewDoubleValue(10), []byte("b")))
+				})
+			}
+		}
+	}
+}
@@ -93,6 +93,32 @@ func DecodeFloat64(buf []byte) (float64, error) {
 	return math.Float64frombits(x), nil
 }
 
+// AppendPoint takes a point and returns its binary representation on 16 bytes:
+// the latitude followed by the longitude, both encoded using AppendFloat64.
+func AppendPoint(buf []byte, p document.Point) []byte {
+	buf = AppendFloat64(buf, p.Lat)
+	return AppendFloat64(buf, p.Lng)
+}
+
+// DecodePoint takes a byte slice and decodes it into a point.
+func DecodePoint(buf []byte) (document.Point, error) {
+	if len(buf) < 16 {
+		return document.Point{}, errors.New("cannot decode point: not enough bytes")
+	}
+
+	lat, err := DecodeFloat64(buf[:8])
+	if err != nil {
+		return document.Point{}, err
+	}
+
+	lng, err := DecodeFloat64(buf[8:16])
+	if err != nil {
+		return document.Point{}, err
+	}
+
+	return document.Point{Lat: lat, Lng: lng}, nil
+}
+
 // AppendBase64 encodes data into a custom base64 encoding. The resulting slice respects
 // natural sort-ordering.
 func AppendBase64(buf []byte, data []byte) ([]byte, error) {
@@ -122,28 +148,87 @@ func DecodeBase64(data []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// textEnd terminates encoded texts. Zero bytes of the text itself
+// are followed by textEscape so that they can't be mistaken for the end of the text.
+const textEnd = 0x00
+const textEscape = 0xff
+
+// AppendText encodes a text so that the result follows the ordering of texts,
+// and ends with a terminator so that the encoded text can be followed by other data:
+// a text is always lower than the texts it is a prefix of, and texts containing
+// zero bytes never collide with other texts.
+// The empty text is encoded as the terminator alone.
+func AppendText(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		buf = append(buf, s[i])
+		if s[i] == textEnd {
+			buf = append(buf, textEscape)
+		}
+	}
+
+	return append(buf, textEnd)
+}
+
+// DecodeText decodes a text encoded with AppendText.
+// It returns the text and the number of bytes read.
+func DecodeText(buf []byte) (string, int, error) {
+	b := make([]byte, 0, len(buf))
+
+	for i := 0; i < len(buf); i++ {
+		if buf[i] != textEnd {
+			b = append(b, buf[i])
+			continue
+		}
+
+		// a zero byte of the text
+		if i+1 < len(buf) && buf[i+1] == textEscape {
+			b = append(b, textEnd)
+			i++
+			continue
+		}
+
+		return string(b), i + 1, nil
+	}
+
+	return "", 0, errors.New("invalid end of text")
+}
+
 // AppendNumber takes a number value, integer or double, and encodes it in 16 bytes
 // so that encoded integers and doubles are naturally ordered.
-// Integers will fist be encoded using AppendInt64 on 8 bytes, then 8 zero-bytes will be
-// appended to them.
-// Doubles will first be converted to integer, encoded using AppendInt64,
-// then AppendFloat64 will be called with the float value.
+// Numbers that compare equal are encoded the same way regardless of their type:
+// integers and doubles without fractional part are encoded using AppendInt64 on 8 bytes,
+// followed by 8 zero-bytes.
+// Other doubles are encoded by calling AppendInt64 with the greatest integer lower than them,
+// then AppendFloat64 with the float value.
 func AppendNumber(buf []byte, v document.Value) ([]byte, error) {
 	if !v.Type.IsNumber() {
 		return nil, errors.New("expected number type")
 	}
 
 	if v.Type == document.IntegerValue {
-		// appending 8 zero bytes so that the integer has the same size as the double
-		// but always lower for the same value.
-		return append(AppendInt64(buf, v.V.(int64)), 0, 0, 0, 0, 0, 0, 0, 0), nil
+		return appendInteger(buf, v.V.(int64)), nil
 	}
 
 	x := v.V.(float64)
-	if x > math.MaxInt64 {
+	switch {
+	case x >= math.MaxInt64:
 		return AppendFloat64(AppendInt64(buf, math.MaxInt64), x), nil
+	case x < math.MinInt64:
+		return AppendFloat64(AppendInt64(buf, math.MinInt64), x), nil
 	}
-	return AppendFloat64(AppendInt64(buf, int64(x)), x), nil
+
+	f := math.Floor(x)
+	if f == x {
+		return appendInteger(buf, int64(x)), nil
+	}
+
+	return AppendFloat64(AppendInt64(buf, int64(f)), x), nil
+}
+
+func appendInteger(buf []byte, x int64) []byte {
+	// appending 8 zero bytes so that the integer has the same size as the double
+	// but always lower for the same value.
+	return append(AppendInt64(buf, x), 0, 0, 0, 0, 0, 0, 0, 0)
 }
 
 // AppendArray encodes an array into a sort-ordered binary representation.
@@ -193,7 +278,7 @@ func decodeValue(data []byte, delim, end byte) (document.Value, int, error) {
 	case document.NullValue:
 	case document.BoolValue:
 		i++
-	case document.DoubleValue:
+	case document.DoubleValue, document.PointValue:
 		i += 16
 	case document.BlobValue, document.TextValue:
 		for i < len(data) && data[i] != delim && data[i] != end {
@@ -242,32 +327,36 @@ func decodeArray(data []byte) (document.Array, int, error) {
 }
 
 // AppendDocument encodes a document into a sort-ordered binary representation.
+// Fields are encoded sorted by name, regardless of the order in which they were added
+// to the document, so that equal documents always have the same encoding
+// and are ordered the same way as document.Value.Compare orders them.
 func AppendDocument(buf []byte, d document.Document) ([]byte, error) {
-	var i int
-	err := d.Iterate(func(field string, value document.Value) error {
-		var err error
+	fields, err := document.Fields(d)
+	if err != nil {
+		return nil, err
+	}
 
+	for i, field := range fields {
 		if i > 0 {
 			buf = append(buf, documentValueDelim)
 		}
 
 		buf, err = AppendBase64(buf, []byte(field))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		buf = append(buf, documentValueDelim)
 
-		buf, err = AppendValue(buf, value)
+		v, err := d.GetByField(field)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		i++
-		return nil
-	})
-	if err != nil {
-		return nil, err
+		buf, err = AppendValue(buf, v)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	buf = append(buf, documentEnd)
@@ -349,6 +438,8 @@ func AppendValue(buf []byte, v document.Value) ([]byte, error) {
 		return AppendBool(buf, v.V.(bool)), nil
 	case document.IntegerValue, document.DoubleValue:
 		return AppendNumber(buf, v)
+	case document.PointValue:
+		return AppendPoint(buf, v.V.(document.Point)), nil
 	case document.NullValue:
 		return buf, nil
 	case document.ArrayValue:
@@ -393,6 +484,12 @@ func DecodeValue(data []byte) (document.Value, error) {
 			return document.Value{}, err
 		}
 		return document.NewDoubleValue(x), nil
+	case document.PointValue:
+		p, err := DecodePoint(data)
+		if err != nil {
+			return document.Value{}, err
+		}
+		return document.NewPointValue(p), nil
 	case document.NullValue:
 		return document.NewNullValue(), nil
 	case document.ArrayValue:
@@ -412,20 +509,81 @@ func DecodeValue(data []byte) (document.Value, error) {
 	return document.Value{}, errors.New("unknown type")
 }
 
+// KeyEncode encodes v as a key that preserves the ordering of values,
+// regardless of their type: if vA < vB, then KeyEncode(vA) < KeyEncode(vB).
+// Values are ordered by type first, in the order of the document.ValueType constants,
+// except for integers and doubles which are both encoded as numbers
+// using AppendNumber, so that they can be compared with each other.
+// Doubles lower than the minimum integer are the only numbers that are not ordered:
+// they all sort right after it.
+// It is the same encoding as AppendValue: the type of the value is encoded first,
+// then blobs and texts are encoded with AppendBase64, without terminator.
+// Primary keys and indexes only use it when they don't have a type: typed primary keys
+// and indexes are encoded with Append instead, which doesn't encode the type
+// and encodes texts with AppendText.
+func KeyEncode(v document.Value) ([]byte, error) {
+	return AppendValue(nil, v)
+}
+
+// KeyEncodeMulti encodes a list of values as a single key, that orders lists the same way
+// as KeyEncode orders arrays: value by value, then by length.
+// Each value is encoded with KeyEncode and separated from the next one by a byte
+// that is lower than any byte that can start or follow an encoded value of variable size,
+// which guarantees that two different lists never have the same encoding.
+func KeyEncodeMulti(vs ...document.Value) ([]byte, error) {
+	var buf []byte
+	var err error
+
+	for i, v := range vs {
+		if i > 0 {
+			buf = append(buf, arrayValueDelim)
+		}
+
+		buf, err = AppendValue(buf, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf, nil
+}
+
+// DecodeMulti decodes a key encoded with KeyEncodeMulti.
+func DecodeMulti(data []byte) ([]document.Value, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	a, _, err := decodeArray(append(data[:len(data):len(data)], arrayEnd))
+	if err != nil {
+		return nil, err
+	}
+
+	var vs []document.Value
+	err = a.Iterate(func(i int, v document.Value) error {
+		vs = append(vs, v)
+		return nil
+	})
+	return vs, err
+}
+
 // Append encodes a value of the type t as a key.
 // The encoded key doesn't include type information.
+// Texts are encoded with AppendText, so that they can't collide when followed by other data.
 func Append(buf []byte, t document.ValueType, v interface{}) ([]byte, error) {
 	switch t {
 	case document.BlobValue:
 		return append(buf, v.([]byte)...), nil
 	case document.TextValue:
-		return append(buf, v.(string)...), nil
+		return AppendText(buf, v.(string)), nil
 	case document.BoolValue:
 		return AppendBool(buf, v.(bool)), nil
 	case document.IntegerValue:
 		return AppendInt64(buf, v.(int64)), nil
 	case document.DoubleValue:
 		return AppendFloat64(buf, v.(float64)), nil
+	case document.PointValue:
+		return AppendPoint(buf, v.(document.Point)), nil
 	case document.NullValue:
 		return buf, nil
 	case document.ArrayValue:
@@ -443,7 +601,11 @@ func Decode(t document.ValueType, data []byte) (document.Value, error) {
 	case document.BlobValue:
 		return document.NewBlobValue(data), nil
 	case document.TextValue:
-		return document.NewTextValue(string(data)), nil
+		t, _, err := DecodeText(data)
+		if err != nil {
+			return document.Value{}, err
+		}
+		return document.NewTextValue(t), nil
 	case document.BoolValue:
 		return document.NewBoolValue(DecodeBool(data)), nil
 	case document.IntegerValue:
@@ -459,6 +621,12 @@ func Decode(t document.ValueType, data []byte) (document.Value, error) {
 			return document.Value{}, err
 		}
 		return document.NewDoubleValue(x), nil
+	case document.PointValue:
+		p, err := DecodePoint(data)
+		if err != nil {
+			return document.Value{}, err
+		}
+		return document.NewPointValue(p), nil
 	case document.NullValue:
 		return document.NewNullValue(), nil
 	case document.ArrayValue:
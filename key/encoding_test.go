@@ -21,6 +21,7 @@ func TestAppendDecode(t *testing.T) {
 		{"double", document.NewDoubleValue(-3.14)},
 		{"text", document.NewTextValue("foo")},
 		{"blob", document.NewBlobValue([]byte("bar"))},
+		{"point", document.NewPointValue(document.Point{Lat: -33.8688, Lng: 151.2093})},
 		{"array", document.NewArrayValue(document.NewValueBuffer(
 			document.NewBoolValue(true),
 			document.NewIntegerValue(55),
@@ -87,6 +88,7 @@ func TestAppendValueDecodeValue(t *testing.T) {
 		{"double", document.NewDoubleValue(-3.14)},
 		{"text", document.NewTextValue("foo")},
 		{"blob", document.NewBlobValue([]byte("bar"))},
+		{"point", document.NewPointValue(document.Point{Lat: -33.8688, Lng: 151.2093})},
 		{"array", document.NewArrayValue(document.NewValueBuffer(
 			document.NewBoolValue(true),
 			document.NewIntegerValue(55),
@@ -142,6 +144,74 @@ func TestAppendValueDecodeValue(t *testing.T) {
 	}
 }
 
+func TestAppendText(t *testing.T) {
+	// texts in logical order
+	texts := []string{
+		"",
+		"\x00",
+		"\x00\x00",
+		"\x00\xff",
+		"\x01",
+		"a",
+		"a\x00",
+		"a\x00\x00",
+		"a\x00b",
+		"a\x01",
+		"ab",
+		"abc",
+		"b",
+		"\xff",
+	}
+
+	var prev []byte
+	for i, s := range texts {
+		cur := AppendText(nil, s)
+		if i > 0 {
+			require.Equal(t, -1, bytes.Compare(prev, cur), "%q", s)
+		}
+		prev = cur
+
+		got, n, err := DecodeText(append(cur, "rest"...))
+		require.NoError(t, err)
+		require.Equal(t, s, got)
+		require.Equal(t, len(cur), n)
+
+		v, err := Decode(document.TextValue, cur)
+		require.NoError(t, err)
+		require.Equal(t, document.NewTextValue(s), v)
+	}
+
+	t.Run("Concatenated texts", func(t *testing.T) {
+		// the same bytes split differently must not collide
+		pairs := [][2]string{
+			{"a", "\x00b"},
+			{"a\x00", "b"},
+			{"a\x00b", ""},
+			{"", "a\x00b"},
+		}
+
+		seen := make(map[string]bool)
+		for _, p := range pairs {
+			k := string(AppendText(AppendText(nil, p[0]), p[1]))
+			require.False(t, seen[k], "%q", p)
+			seen[k] = true
+
+			first, n, err := DecodeText([]byte(k))
+			require.NoError(t, err)
+			second, _, err := DecodeText([]byte(k)[n:])
+			require.NoError(t, err)
+			require.Equal(t, p, [2]string{first, second})
+		}
+	})
+
+	t.Run("Missing end of text", func(t *testing.T) {
+		_, _, err := DecodeText([]byte("a"))
+		require.Error(t, err)
+		_, _, err = DecodeText([]byte("a\x00\xff"))
+		require.Error(t, err)
+	})
+}
+
 const Rng = 1000
 
 func TestOrdering(t *testing.T) {
@@ -201,7 +271,8 @@ func TestOrdering(t *testing.T) {
 		want := []interface{}{
 			-1000.4,
 			int64(40),
-			float64(40),
+			// doubles without fractional part are encoded like integers
+			int64(40),
 			int64(7000),
 			7000.3,
 			int64(math.MaxInt64 - 1),
@@ -214,13 +285,149 @@ func TestOrdering(t *testing.T) {
 		var x interface{}
 
 		for i, enc := range encoded {
-			if len(enc) == 16 {
+			if len(enc) == 16 && !bytes.Equal(enc[8:], make([]byte, 8)) {
 				x, err = DecodeFloat64(enc[8:])
 			} else {
-				x, err = DecodeInt64(enc)
+				x, err = DecodeInt64(enc[:8])
 			}
 			require.NoError(t, err)
 			require.Equal(t, want[i], x)
 		}
 	})
+
+	t.Run("Equal integers and doubles", func(t *testing.T) {
+		for _, nb := range []int64{math.MinInt64, -10, 0, 10, 1 << 53} {
+			i, err := AppendNumber(nil, document.NewIntegerValue(nb))
+			require.NoError(t, err)
+			d, err := AppendNumber(nil, document.NewDoubleValue(float64(nb)))
+			require.NoError(t, err)
+			require.Equal(t, i, d)
+		}
+	})
+
+	t.Run("Ordered negative numbers", func(t *testing.T) {
+		values := []document.Value{
+			document.NewIntegerValue(-11),
+			document.NewDoubleValue(-10.5),
+			document.NewIntegerValue(-10),
+			document.NewDoubleValue(-9.5),
+			document.NewDoubleValue(-0.5),
+			document.NewIntegerValue(0),
+			document.NewDoubleValue(0.5),
+		}
+
+		var prev []byte
+		for _, v := range values {
+			cur, err := AppendNumber(nil, v)
+			require.NoError(t, err)
+			if prev != nil {
+				require.Equal(t, -1, bytes.Compare(prev, cur), "%v", v)
+			}
+			prev = cur
+		}
+	})
+}
+
+func TestKeyEncodeOrdering(t *testing.T) {
+	// values in logical order, across and within types
+	values := []document.Value{
+		document.NewNullValue(),
+		document.NewBoolValue(false),
+		document.NewBoolValue(true),
+		document.NewIntegerValue(math.MinInt64),
+		document.NewDoubleValue(-1e15 - 0.5),
+		document.NewIntegerValue(-10),
+		document.NewDoubleValue(-9.5),
+		document.NewIntegerValue(0),
+		document.NewDoubleValue(0.5),
+		document.NewIntegerValue(1),
+		document.NewDoubleValue(1.5),
+		document.NewIntegerValue(math.MaxInt64),
+		document.NewDoubleValue(math.MaxFloat64),
+		document.NewPointValue(document.Point{Lat: -1, Lng: 0}),
+		document.NewPointValue(document.Point{Lat: 1, Lng: 0}),
+		document.NewTextValue(""),
+		document.NewTextValue("a"),
+		document.NewTextValue("a\x00"),
+		document.NewTextValue("ab"),
+		document.NewTextValue("b"),
+		document.NewBlobValue([]byte{}),
+		document.NewBlobValue([]byte{0}),
+		document.NewBlobValue([]byte{0, 0}),
+		document.NewBlobValue([]byte{0xff}),
+	}
+
+	var prev []byte
+	for _, v := range values {
+		cur, err := KeyEncode(v)
+		require.NoError(t, err)
+		if prev != nil {
+			require.Equal(t, -1, bytes.Compare(prev, cur), "%v", v)
+		}
+		prev = cur
+	}
+
+	t.Run("Equal integers and doubles", func(t *testing.T) {
+		i, err := KeyEncode(document.NewIntegerValue(10))
+		require.NoError(t, err)
+		d, err := KeyEncode(document.NewDoubleValue(10))
+		require.NoError(t, err)
+		require.Equal(t, i, d)
+	})
+
+	t.Run("Documents with different field orders", func(t *testing.T) {
+		a, err := KeyEncode(document.NewDocumentValue(document.NewFieldBuffer().
+			Add("a", document.NewIntegerValue(1)).
+			Add("b", document.NewDocumentValue(document.NewFieldBuffer().
+				Add("c", document.NewTextValue("c")).
+				Add("d", document.NewBoolValue(true))))))
+		require.NoError(t, err)
+		b, err := KeyEncode(document.NewDocumentValue(document.NewFieldBuffer().
+			Add("b", document.NewDocumentValue(document.NewFieldBuffer().
+				Add("d", document.NewBoolValue(true)).
+				Add("c", document.NewTextValue("c")))).
+			Add("a", document.NewIntegerValue(1))))
+		require.NoError(t, err)
+		require.Equal(t, a, b)
+	})
+}
+
+func TestKeyEncodeMulti(t *testing.T) {
+	// lists in logical order: value by value, then by length
+	lists := [][]document.Value{
+		{},
+		{document.NewNullValue()},
+		{document.NewNullValue(), document.NewIntegerValue(1)},
+		{document.NewBoolValue(true)},
+		{document.NewIntegerValue(1)},
+		{document.NewIntegerValue(1), document.NewNullValue()},
+		{document.NewIntegerValue(1), document.NewTextValue("a")},
+		{document.NewIntegerValue(1), document.NewTextValue("a"), document.NewIntegerValue(-1)},
+		{document.NewIntegerValue(1), document.NewTextValue("a\x00")},
+		{document.NewIntegerValue(1), document.NewTextValue("b")},
+		{document.NewDoubleValue(1.5)},
+		{document.NewTextValue("a")},
+		{document.NewTextValue("a"), document.NewTextValue("a")},
+		{document.NewTextValue("aa")},
+		{document.NewTextValue("aa"), document.NewBlobValue([]byte{0})},
+	}
+
+	var prev []byte
+	for i, vs := range lists {
+		cur, err := KeyEncodeMulti(vs...)
+		require.NoError(t, err)
+		if i > 0 {
+			require.Equal(t, -1, bytes.Compare(prev, cur), "%v", vs)
+		}
+		prev = cur
+
+		got, err := DecodeMulti(cur)
+		require.NoError(t, err)
+		require.Len(t, got, len(vs))
+		for j := range vs {
+			ok, err := got[j].IsEqual(vs[j])
+			require.NoError(t, err)
+			require.True(t, ok, "%v != %v", got[j], vs[j])
+		}
+	}
 }
@@ -10,7 +10,16 @@ import (
 
 // New initializes the DB using the given engine.
 func New(ng engine.Engine) (*DB, error) {
-	db, err := database.New(ng, database.Options{Codec: msgpack.NewCodec()})
+	return newDB(ng, new(Options))
+}
+
+func newDB(ng engine.Engine, opts *Options) (*DB, error) {
+	dbOpts := opts.databaseOptions()
+	if dbOpts.Codec == nil {
+		dbOpts.Codec = msgpack.NewCodec()
+	}
+
+	db, err := database.New(ng, dbOpts)
 	if err != nil {
 		return nil, err
 	}
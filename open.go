@@ -3,27 +3,43 @@
 package genji
 
 import (
-	"github.com/genjidb/genji/engine"
 	"github.com/genjidb/genji/engine/boltengine"
 	"github.com/genjidb/genji/engine/memoryengine"
+	bolt "go.etcd.io/bbolt"
 )
 
 // Open creates a Genji database at the given path.
 // If path is equal to ":memory:" it will open an in-memory database,
 // otherwise it will create an on-disk database using the BoltDB engine.
 func Open(path string) (*DB, error) {
-	var ng engine.Engine
-	var err error
+	return OpenWithOptions(path, nil)
+}
 
-	switch path {
-	case ":memory:":
-		ng = memoryengine.NewEngine()
-	default:
-		ng, err = boltengine.NewEngine(path, 0660, nil)
+// OpenWithOptions creates a Genji database at the given path using the given options.
+// If opts.Engine is set, path is ignored and the database is stored in that engine,
+// otherwise the engine is chosen like in Open.
+// If opts is nil, it behaves like Open.
+func OpenWithOptions(path string, opts *Options) (*DB, error) {
+	if opts == nil {
+		opts = new(Options)
 	}
-	if err != nil {
-		return nil, err
+
+	ng := opts.Engine
+	if ng == nil {
+		var err error
+
+		switch path {
+		case ":memory:":
+			ng = memoryengine.NewEngine()
+		default:
+			bopts := *bolt.DefaultOptions
+			bopts.NoSync = opts.NoSync
+			ng, err = boltengine.NewEngine(path, 0660, &bopts)
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return New(ng)
+	return newDB(ng, opts)
 }
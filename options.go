@@ -0,0 +1,87 @@
+package genji
+
+import (
+	"time"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding"
+	"github.com/genjidb/genji/engine"
+)
+
+// Options are the options used to open a database.
+// The zero value opens a database with the default behaviour.
+type Options struct {
+	// Engine used to store the data.
+	// If nil, the engine is chosen depending on the path given to OpenWithOptions.
+	Engine engine.Engine
+	// Codec used to encode documents. Defaults to MessagePack.
+	Codec encoding.Codec
+	// Compression of the documents stored in tables.
+	// Disabled by default. A database written with compression enabled
+	// must be opened with compression enabled.
+	Compression database.CompressionOptions
+	// Collation of the TEXT fields created without an explicit collation.
+	// Defaults to document.BinaryCollation.
+	DefaultCollation document.Collation
+	// If true, the on-disk engine doesn't sync its file after each commit.
+	// It speeds up writes at the risk of losing the last transactions
+	// if the system crashes. Ignored if Engine is set.
+	NoSync bool
+	// If true, field names are matched regardless of their case.
+	CaseInsensitiveFields bool
+	// Logger receives the events emitted by the database.
+	// Optional.
+	Logger database.Logger
+	// Maximum number of bytes a statement can keep in memory while sorting,
+	// grouping or materializing documents. Sorts run in read/write transactions write
+	// the documents that don't fit to temporary stores, other statements exceeding
+	// the limit fail with database.ErrResultTooLarge. Defaults to no limit.
+	StatementMemoryLimit int64
+	// Maximum number of times DB.Update runs its function when its transaction
+	// conflicts with another one. Defaults to 5.
+	MaxUpdateAttempts int
+	// Key used to encrypt the fields declared as ENCRYPTED with AES-GCM.
+	// It must be 16, 24 or 32 bytes long, to use AES-128, AES-192 or AES-256.
+	// Encrypted fields can't be read or written without it.
+	EncryptionKey []byte
+	// Number of documents read from the source of an INSERT ... SELECT statement
+	// before they are inserted. Larger batches read the source fewer times but
+	// use more memory. Defaults to 100.
+	InsertBatchSize int
+	// Visibility is called by table scans for every document and hides the documents
+	// for which it returns false, such as soft-deleted or expired ones. Optional.
+	Visibility database.VisibilityFunc
+	// Maximum size in bytes of the keys of the indexes. Documents with a value
+	// that is too long to be indexed can't be inserted and return database.ErrIndexKeyTooLong.
+	// Defaults to the limit of the engine, if any, such as the maximum key size of Bolt.
+	MaxIndexKeySize int
+	// Size in bytes above which the blobs stored in top-level fields are split in chunks
+	// stored outside of their document, to avoid loading them with the rest of the document.
+	// They can be read and written as streams using the BlobReader and BlobWriter methods of tables.
+	// Defaults to database.DefaultExternalBlobMinSize.
+	ExternalBlobMinSize int
+	// Maximum duration of the statements run by DB.Query, DB.Exec and their variants,
+	// so that they are bounded even if their context has no deadline. Statements
+	// running longer fail with context.DeadlineExceeded. A query can override it by passing
+	// a query.Timeout alongside its arguments. Defaults to no limit.
+	StatementTimeout time.Duration
+}
+
+func (o *Options) databaseOptions() database.Options {
+	return database.Options{
+		Codec:                 o.Codec,
+		CaseInsensitiveFields: o.CaseInsensitiveFields,
+		Compression:           o.Compression,
+		DefaultCollation:      o.DefaultCollation,
+		Logger:                o.Logger,
+		StatementMemoryLimit:  o.StatementMemoryLimit,
+		MaxUpdateAttempts:     o.MaxUpdateAttempts,
+		EncryptionKey:         o.EncryptionKey,
+		InsertBatchSize:       o.InsertBatchSize,
+		Visibility:            o.Visibility,
+		MaxIndexKeySize:       o.MaxIndexKeySize,
+		ExternalBlobMinSize:   o.ExternalBlobMinSize,
+		StatementTimeout:      o.StatementTimeout,
+	}
+}
@@ -6,31 +6,31 @@ import (
 	"database/sql"
 	"database/sql/driver"
 
+	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
 )
 
 func argsToParams(args []interface{}) []expr.Param {
-	nv := make([]expr.Param, len(args))
+	nv := make([]expr.Param, 0, len(args))
 	for i := range args {
 		switch t := args[i].(type) {
 		case sql.NamedArg:
-			nv[i].Name = t.Name
-			nv[i].Value = t.Value
+			nv = append(nv, expr.Param{Name: t.Name, Value: t.Value})
 		case *sql.NamedArg:
-			nv[i].Name = t.Name
-			nv[i].Value = t.Value
+			nv = append(nv, expr.Param{Name: t.Name, Value: t.Value})
 		case driver.NamedValue:
-			nv[i].Name = t.Name
-			nv[i].Value = t.Value
+			nv = append(nv, expr.Param{Name: t.Name, Value: t.Value})
 		case *driver.NamedValue:
-			nv[i].Name = t.Name
-			nv[i].Value = t.Value
+			nv = append(nv, expr.Param{Name: t.Name, Value: t.Value})
 		case *expr.Param:
-			nv[i] = *t
+			nv = append(nv, *t)
 		case expr.Param:
-			nv[i] = t
+			nv = append(nv, t)
+		case query.ParamSource:
+			// typed parameters are inserted in place of the source
+			nv = append(nv, t.Params()...)
 		default:
-			nv[i].Value = args[i]
+			nv = append(nv, expr.Param{Value: args[i]})
 		}
 	}
 
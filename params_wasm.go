@@ -1,19 +1,23 @@
 package genji
 
 import (
+	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
 )
 
 func argsToParams(args []interface{}) []expr.Param {
-	nv := make([]expr.Param, len(args))
+	nv := make([]expr.Param, 0, len(args))
 	for i := range args {
 		switch t := args[i].(type) {
 		case *expr.Param:
-			nv[i] = *t
+			nv = append(nv, *t)
 		case expr.Param:
-			nv[i] = t
+			nv = append(nv, t)
+		case query.ParamSource:
+			// typed parameters are inserted in place of the source
+			nv = append(nv, t.Params()...)
 		default:
-			nv[i].Value = args[i]
+			nv = append(nv, expr.Param{Value: args[i]})
 		}
 	}
 
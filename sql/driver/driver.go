@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/parser"
 	"github.com/genjidb/genji/sql/planner"
@@ -96,6 +97,7 @@ func (c *conn) PrepareContext(ctx context.Context, q string) (driver.Stmt, error
 	if err != nil {
 		return nil, err
 	}
+	c.db.DB.Log(database.Event{Kind: database.EventQueryParsed, Query: q})
 
 	return stmt{
 		db: c.db,
@@ -276,7 +278,16 @@ func (s stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (drive
 		return rs, nil
 	}
 
-	if pn, ok := tree.Root.(*planner.ProjectionNode); ok && len(pn.Expressions) > 0 {
+	// the projection node may be followed by sort, offset and limit nodes
+	n := tree.Root
+	for n != nil {
+		if _, ok := n.(*planner.ProjectionNode); ok {
+			break
+		}
+		n = n.Left()
+	}
+
+	if pn, ok := n.(*planner.ProjectionNode); ok && len(pn.Expressions) > 0 {
 		rs.fields = make([]string, len(pn.Expressions))
 		for i := range pn.Expressions {
 			rs.fields[i] = pn.Expressions[i].Name()
@@ -132,6 +132,32 @@ func TestDriver(t *testing.T) {
 		require.Equal(t, 1, count)
 	})
 
+	t.Run("Limit and offset params", func(t *testing.T) {
+		stmt, err := db.Prepare("SELECT a FROM test LIMIT ? OFFSET ?")
+		require.NoError(t, err)
+		defer stmt.Close()
+
+		for _, limit := range []int{2, 5} {
+			rows, err := stmt.Query(limit, 3)
+			require.NoError(t, err)
+
+			var as []int
+			for rows.Next() {
+				var a int
+				err = rows.Scan(&a)
+				require.NoError(t, err)
+				as = append(as, a)
+			}
+			require.NoError(t, rows.Err())
+			require.NoError(t, rows.Close())
+			require.Len(t, as, limit)
+			require.Equal(t, 3, as[0])
+		}
+
+		_, err = stmt.Query(-1, 0)
+		require.Error(t, err)
+	})
+
 	t.Run("Transactions", func(t *testing.T) {
 		tx, err := db.Begin()
 		require.NoError(t, err)
@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/sql/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserAnalyze(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected query.Statement
+		errored  bool
+	}{
+		{"All", "ANALYZE", query.AnalyzeStmt{}, false},
+		{"With ident", "ANALYZE tableOrIndex", query.AnalyzeStmt{TableOrIndexName: "tableOrIndex"}, false},
+		{"With extra", "ANALYZE tableOrIndex tableOrIndex", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+			require.EqualValues(t, test.expected, q.Statements[0])
+		})
+	}
+}
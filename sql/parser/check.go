@@ -0,0 +1,20 @@
+package parser
+
+import (
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// parseCheckStatement parses a check statement.
+// This function assumes the CHECK token has already been consumed.
+func (p *Parser) parseCheckStatement() (query.Statement, error) {
+	var stmt query.CheckStmt
+
+	tok, _, lit := p.ScanIgnoreWhitespace()
+	if tok == scanner.IDENT {
+		stmt.TableName = lit
+	} else {
+		p.Unscan()
+	}
+	return stmt, nil
+}
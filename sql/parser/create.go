@@ -4,7 +4,9 @@ import (
 	"fmt"
 
 	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
 )
 
@@ -13,6 +15,23 @@ import (
 func (p *Parser) parseCreateStatement() (query.Statement, error) {
 	tok, pos, lit := p.ScanIgnoreWhitespace()
 	switch tok {
+	case scanner.OR:
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.REPLACE {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"REPLACE"}, pos)
+		}
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.TABLE {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TABLE"}, pos)
+		}
+
+		stmt, err := p.parseCreateTableStatement()
+		if err != nil {
+			return stmt, err
+		}
+		if stmt.IfNotExists {
+			return nil, &ParseError{Message: "cannot use IF NOT EXISTS with OR REPLACE"}
+		}
+		stmt.OrReplace = true
+		return stmt, nil
 	case scanner.TABLE:
 		return p.parseCreateTableStatement()
 	case scanner.UNIQUE:
@@ -25,7 +44,7 @@ func (p *Parser) parseCreateStatement() (query.Statement, error) {
 		return p.parseCreateIndexStatement(false)
 	}
 
-	return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TABLE", "INDEX"}, pos)
+	return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TABLE", "INDEX", "OR REPLACE"}, pos)
 }
 
 // parseCreateTableStatement parses a create table string and returns a Statement AST object.
@@ -52,6 +71,13 @@ func (p *Parser) parseCreateTableStatement() (query.CreateTableStmt, error) {
 		return stmt, err
 	}
 
+	// Parse optional STRICT
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.STRICT {
+		stmt.Info.Strict = true
+	} else {
+		p.Unscan()
+	}
+
 	return stmt, nil
 }
 
@@ -86,6 +112,24 @@ func (p *Parser) parseFieldConstraints(info *database.TableInfo) error {
 
 	// Parse constraints.
 	for {
+		// Parse table constraint: "FOREIGN KEY (path) REFERENCES table [(path)] [ON DELETE action]"
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.FOREIGN {
+			fk, err := p.parseForeignKey()
+			if err != nil {
+				return err
+			}
+
+			info.ForeignKeys = append(info.ForeignKeys, fk)
+
+			if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
+				p.Unscan()
+				break
+			}
+
+			continue
+		}
+		p.Unscan()
+
 		var fc database.FieldConstraint
 
 		fc.Path, err = p.parsePath()
@@ -132,6 +176,8 @@ func (p *Parser) parseFieldConstraints(info *database.TableInfo) error {
 }
 
 func (p *Parser) parseFieldConstraint(fc *database.FieldConstraint) error {
+	var hasCollation bool
+
 	for {
 		tok, pos, lit := p.ScanIgnoreWhitespace()
 		switch tok {
@@ -159,6 +205,59 @@ func (p *Parser) parseFieldConstraint(fc *database.FieldConstraint) error {
 			}
 
 			fc.IsNotNull = true
+		case scanner.DEFAULT:
+			// if it already has a default value we return an error
+			if fc.DefaultValue != nil {
+				return newParseError(scanner.Tokstr(tok, lit), []string{"CONSTRAINT", ")"}, pos)
+			}
+
+			// Parse default value expression.
+			// NOT ends the expression to avoid any ambiguity with the following
+			// constraints (i.e. DEFAULT 10 NOT NULL): the NOT IN and NOT BETWEEN
+			// operators must be used within parentheses.
+			p.notEndsExpr = true
+			e, err := p.parseExprWithMinPrecedence(0)
+			p.notEndsExpr = false
+			if err != nil {
+				return err
+			}
+
+			if !expr.IsConstraintExpr(e) {
+				return &ParseError{Message: fmt.Sprintf("unsupported default value %v", e), Pos: pos}
+			}
+
+			fc.DefaultValue = expr.Constraint(e)
+		case scanner.COLLATE:
+			// if it already has a collation we return an error
+			if hasCollation {
+				return newParseError(scanner.Tokstr(tok, lit), []string{"CONSTRAINT", ")"}, pos)
+			}
+
+			if fc.Type != document.TextValue {
+				return &ParseError{Message: "collations can only be used on TEXT fields", Pos: pos}
+			}
+
+			// Parse collation name.
+			name, err := p.parseIdent()
+			if err != nil {
+				pErr := err.(*ParseError)
+				pErr.Expected = []string{"collation_name"}
+				return pErr
+			}
+
+			fc.Collation, err = document.ParseCollation(name)
+			if err != nil {
+				return &ParseError{Message: err.Error(), Pos: pos}
+			}
+			fc.ExplicitCollation = true
+			hasCollation = true
+		case scanner.ENCRYPTED:
+			// if it's already encrypted we return an error
+			if fc.IsEncrypted {
+				return newParseError(scanner.Tokstr(tok, lit), []string{"CONSTRAINT", ")"}, pos)
+			}
+
+			fc.IsEncrypted = true
 		default:
 			p.Unscan()
 			return nil
@@ -166,6 +265,75 @@ func (p *Parser) parseFieldConstraint(fc *database.FieldConstraint) error {
 	}
 }
 
+// parseForeignKey parses a foreign key table constraint.
+// This function assumes the FOREIGN token has already been consumed.
+func (p *Parser) parseForeignKey() (database.ForeignKey, error) {
+	var fk database.ForeignKey
+
+	// Parse "KEY"
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.KEY {
+		return fk, newParseError(scanner.Tokstr(tok, lit), []string{"KEY"}, pos)
+	}
+
+	// Parse "(path)"
+	paths, err := p.parsePathList()
+	if err != nil {
+		return fk, err
+	}
+	if len(paths) == 0 {
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		return fk, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+	if len(paths) != 1 {
+		return fk, &ParseError{Message: "foreign keys on more than one path are not supported"}
+	}
+	fk.Path = paths[0]
+
+	// Parse "REFERENCES"
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.REFERENCES {
+		return fk, newParseError(scanner.Tokstr(tok, lit), []string{"REFERENCES"}, pos)
+	}
+
+	// Parse referenced table name
+	fk.ReferencedTable, err = p.parseIdent()
+	if err != nil {
+		return fk, err
+	}
+
+	// Parse optional referenced path, which defaults to the primary key of the table
+	paths, err = p.parsePathList()
+	if err != nil {
+		return fk, err
+	}
+	if len(paths) > 1 {
+		return fk, &ParseError{Message: "foreign keys on more than one path are not supported"}
+	}
+	if len(paths) == 1 {
+		fk.ReferencedPath = paths[0]
+	}
+
+	// Parse optional "ON DELETE RESTRICT" or "ON DELETE CASCADE"
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.ON {
+		p.Unscan()
+		return fk, nil
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.DELETE {
+		return fk, newParseError(scanner.Tokstr(tok, lit), []string{"DELETE"}, pos)
+	}
+
+	switch tok, pos, lit := p.ScanIgnoreWhitespace(); tok {
+	case scanner.RESTRICT:
+		fk.OnDelete = database.ForeignKeyRestrict
+	case scanner.CASCADE:
+		fk.OnDelete = database.ForeignKeyCascade
+	default:
+		return fk, newParseError(scanner.Tokstr(tok, lit), []string{"RESTRICT", "CASCADE"}, pos)
+	}
+
+	return fk, nil
+}
+
 // parseCreateIndexStatement parses a create index string and returns a Statement AST object.
 // This function assumes the CREATE INDEX or CREATE UNIQUE INDEX tokens have already been consumed.
 func (p *Parser) parseCreateIndexStatement(unique bool) (query.CreateIndexStmt, error) {
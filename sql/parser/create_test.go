@@ -7,6 +7,7 @@ import (
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,6 +20,32 @@ func TestParserCreateTable(t *testing.T) {
 	}{
 		{"Basic", "CREATE TABLE test", query.CreateTableStmt{TableName: "test"}, false},
 		{"If not exists", "CREATE TABLE IF NOT EXISTS test", query.CreateTableStmt{TableName: "test", IfNotExists: true}, false},
+		{"Or replace", "CREATE OR REPLACE TABLE test(foo INTEGER)",
+			query.CreateTableStmt{
+				TableName: "test",
+				OrReplace: true,
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "foo"), Type: document.IntegerValue},
+					},
+				},
+			}, false},
+		{"Or replace with if not exists", "CREATE OR REPLACE TABLE IF NOT EXISTS test", nil, true},
+		{"Or without replace", "CREATE OR TABLE test", nil, true},
+		{"Or replace index", "CREATE OR REPLACE INDEX idx ON test (foo)", nil, true},
+		{"Strict", "CREATE TABLE test STRICT", query.CreateTableStmt{TableName: "test", Info: database.TableInfo{Strict: true}}, false},
+		{"Strict with fields", "CREATE TABLE test(foo INTEGER, bar.baz TEXT) STRICT",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "foo"), Type: document.IntegerValue},
+						{Path: parsePath(t, "bar.baz"), Type: document.TextValue},
+					},
+					Strict: true,
+				},
+			}, false},
+		{"Strict before fields", "CREATE TABLE test STRICT (foo INTEGER)", query.CreateTableStmt{}, true},
 		{"With primary key", "CREATE TABLE test(foo INTEGER PRIMARY KEY)",
 			query.CreateTableStmt{
 				TableName: "test",
@@ -88,6 +115,62 @@ func TestParserCreateTable(t *testing.T) {
 					},
 				},
 			}, false},
+		{"With default", "CREATE TABLE test(foo TEXT DEFAULT uuid(), bar INTEGER DEFAULT 10 NOT NULL)",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "foo"), Type: document.TextValue, DefaultValue: expr.Constraint(&expr.UUIDFunc{})},
+						{Path: parsePath(t, "bar"), Type: document.IntegerValue, IsNotNull: true, DefaultValue: expr.Constraint(expr.IntegerValue(10))},
+					},
+				},
+			}, false},
+		{"With default expression", "CREATE TABLE test(foo INTEGER DEFAULT 1 + 2 NOT NULL, bar BOOL DEFAULT (1 NOT IN (2, 3)))",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "foo"), Type: document.IntegerValue, IsNotNull: true, DefaultValue: expr.Constraint(expr.Add(expr.IntegerValue(1), expr.IntegerValue(2)))},
+						{Path: parsePath(t, "bar"), Type: document.BoolValue, DefaultValue: expr.Constraint(expr.Parentheses{E: expr.NotIn(expr.IntegerValue(1), expr.LiteralExprList{expr.IntegerValue(2), expr.IntegerValue(3)})})},
+					},
+				},
+			}, false},
+		{"With collation", "CREATE TABLE test(foo TEXT COLLATE NOCASE NOT NULL, bar TEXT COLLATE binary)",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "foo"), Type: document.TextValue, IsNotNull: true, Collation: document.NoCaseCollation, ExplicitCollation: true},
+						{Path: parsePath(t, "bar"), Type: document.TextValue, Collation: document.BinaryCollation, ExplicitCollation: true},
+					},
+				},
+			}, false},
+		{"With encryption", "CREATE TABLE test(foo TEXT ENCRYPTED NOT NULL, bar ENCRYPTED)",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "foo"), Type: document.TextValue, IsNotNull: true, IsEncrypted: true},
+						{Path: parsePath(t, "bar"), IsEncrypted: true},
+					},
+				},
+			}, false},
+		{"With duplicate encryption", "CREATE TABLE test(foo ENCRYPTED ENCRYPTED)",
+			query.CreateTableStmt{}, true},
+		{"With collation on non-text field", "CREATE TABLE test(foo INTEGER COLLATE NOCASE)",
+			query.CreateTableStmt{}, true},
+		{"With unknown collation", "CREATE TABLE test(foo TEXT COLLATE foo)",
+			query.CreateTableStmt{}, true},
+		{"With duplicate collation", "CREATE TABLE test(foo TEXT COLLATE NOCASE COLLATE BINARY)",
+			query.CreateTableStmt{}, true},
+		{"With duplicate default", "CREATE TABLE test(foo TEXT DEFAULT 'a' DEFAULT 'b')",
+			query.CreateTableStmt{}, true},
+		{"With unsupported default", "CREATE TABLE test(foo DEFAULT bar)",
+			query.CreateTableStmt{}, true},
+		{"With unsupported default expression", "CREATE TABLE test(foo DEFAULT 1 + bar)",
+			query.CreateTableStmt{}, true},
+		{"With NOT IN default outside parentheses", "CREATE TABLE test(foo DEFAULT 1 NOT IN (2))",
+			query.CreateTableStmt{}, true},
 		{"With multiple primary keys", "CREATE TABLE test(foo PRIMARY KEY, bar PRIMARY KEY)",
 			query.CreateTableStmt{}, true},
 		{"With all supported fixed size data types",
@@ -168,6 +251,32 @@ func TestParserCreateTable(t *testing.T) {
 					},
 				},
 			}, true},
+		{"With foreign key", "CREATE TABLE test(a INTEGER, FOREIGN KEY (a) REFERENCES foo(b))",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					FieldConstraints: []database.FieldConstraint{
+						{Path: parsePath(t, "a"), Type: document.IntegerValue},
+					},
+					ForeignKeys: []database.ForeignKey{
+						{Path: parsePath(t, "a"), ReferencedTable: "foo", ReferencedPath: parsePath(t, "b")},
+					},
+				},
+			}, false},
+		{"With foreign keys and actions", "CREATE TABLE test(FOREIGN KEY (a.b) REFERENCES foo ON DELETE CASCADE, FOREIGN KEY (c) REFERENCES bar ON DELETE RESTRICT)",
+			query.CreateTableStmt{
+				TableName: "test",
+				Info: database.TableInfo{
+					ForeignKeys: []database.ForeignKey{
+						{Path: parsePath(t, "a.b"), ReferencedTable: "foo", OnDelete: database.ForeignKeyCascade},
+						{Path: parsePath(t, "c"), ReferencedTable: "bar", OnDelete: database.ForeignKeyRestrict},
+					},
+				},
+			}, false},
+		{"With foreign key without path", "CREATE TABLE test(FOREIGN KEY REFERENCES foo)", query.CreateTableStmt{}, true},
+		{"With foreign key on multiple paths", "CREATE TABLE test(FOREIGN KEY (a, b) REFERENCES foo)", query.CreateTableStmt{}, true},
+		{"With foreign key without table", "CREATE TABLE test(FOREIGN KEY (a) REFERENCES)", query.CreateTableStmt{}, true},
+		{"With foreign key and unknown action", "CREATE TABLE test(FOREIGN KEY (a) REFERENCES foo ON DELETE SET NULL)", query.CreateTableStmt{}, true},
 	}
 
 	for _, test := range tests {
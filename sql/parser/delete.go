@@ -31,6 +31,12 @@ func (p *Parser) parseDeleteStatement() (*planner.Tree, error) {
 		return nil, err
 	}
 
+	// Parse limit: "LIMIT expr"
+	cfg.LimitExpr, err = p.parseLimit()
+	if err != nil {
+		return nil, err
+	}
+
 	return cfg.ToTree(), nil
 }
 
@@ -38,6 +44,7 @@ func (p *Parser) parseDeleteStatement() (*planner.Tree, error) {
 type deleteConfig struct {
 	TableName string
 	WhereExpr expr.Expr
+	LimitExpr expr.Expr
 }
 
 // ToTree turns the statement into an expression tree.
@@ -48,6 +55,10 @@ func (cfg deleteConfig) ToTree() *planner.Tree {
 		t = planner.NewSelectionNode(t, cfg.WhereExpr)
 	}
 
+	if cfg.LimitExpr != nil {
+		t = planner.NewLimitExprNode(t, cfg.LimitExpr)
+	}
+
 	t = planner.NewDeletionNode(t, cfg.TableName)
 
 	return &planner.Tree{Root: t}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
 )
@@ -33,32 +34,67 @@ func (p *Parser) ParseExpr() (e expr.Expr, lit string, err error) {
 		defer func() { p.buf = nil }()
 	}
 
+	// nested expressions, such as the ones within parentheses,
+	// can always use the NOT IN and NOT BETWEEN operators
+	if p.notEndsExpr {
+		p.notEndsExpr = false
+		defer func() { p.notEndsExpr = true }()
+	}
+
+	e, err = p.parseExprWithMinPrecedence(0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return e, strings.TrimSpace(p.buf.String()), nil
+}
+
+// parseExprWithMinPrecedence parses an expression whose operators
+// have a precedence strictly greater than the given one.
+// It stops before the first operator whose precedence is lower or equal.
+func (p *Parser) parseExprWithMinPrecedence(precedence int) (expr.Expr, error) {
 	// Dummy root node.
 	var root expr.Operator = new(dummyOperator)
 
 	// Parse a non-binary expression type to start.
 	// This variable will always be the root of the expression tree.
-	e, err = p.parseUnaryExpr()
+	e, err := p.parseUnaryExpr()
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 	root.SetRightHandExpr(e)
 
 	// Loop over operations and unary exprs and build a tree based on precedence.
 	for {
+		if precedence > 0 {
+			tok, _, _ := p.ScanIgnoreWhitespace()
+			p.Unscan()
+			if tok.IsOperator() && tok.Precedence() <= precedence {
+				return root.RightHand(), nil
+			}
+		}
+
 		// If the next token is NOT an operator then return the expression.
 		op, tok, err := p.parseOperator()
 		if err != nil {
-			return nil, "", err
+			return nil, err
 		}
 		if tok == 0 {
-			return root.RightHand(), strings.TrimSpace(p.buf.String()), nil
+			return root.RightHand(), nil
 		}
 
 		var rhs expr.Expr
 
-		if rhs, err = p.parseUnaryExpr(); err != nil {
-			return nil, "", err
+		// the right operand of IN and NOT IN can be a subquery
+		if tok == scanner.IN || tok == scanner.NOT {
+			rhs, err = p.parseInOperand()
+		} else if tok == scanner.BETWEEN {
+			rhs, err = p.parseBetweenOperand()
+		} else {
+			rhs, err = p.parseUnaryExpr()
+		}
+		if err != nil {
+			return nil, err
 		}
 
 		// Find the right spot in the tree to add the new expression by
@@ -79,7 +115,7 @@ func (p *Parser) ParseExpr() (e expr.Expr, lit string, err error) {
 
 func (p *Parser) parseOperator() (func(lhs, rhs expr.Expr) expr.Expr, scanner.Token, error) {
 	op, _, _ := p.ScanIgnoreWhitespace()
-	if !op.IsOperator() && op != scanner.NOT {
+	if !op.IsOperator() && (op != scanner.NOT || p.notEndsExpr) {
 		p.Unscan()
 		return nil, 0, nil
 	}
@@ -120,21 +156,113 @@ func (p *Parser) parseOperator() (func(lhs, rhs expr.Expr) expr.Expr, scanner.To
 	case scanner.IN:
 		return expr.In, op, nil
 	case scanner.IS:
+		not := false
 		if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.NOT {
-			return expr.IsNot, op, nil
+			not = true
+		} else {
+			p.Unscan()
+		}
+
+		// IS [NOT] DISTINCT FROM
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.DISTINCT {
+			if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.FROM {
+				return nil, 0, newParseError(scanner.Tokstr(tok, lit), []string{"FROM"}, pos)
+			}
+
+			if not {
+				return expr.IsNotDistinctFrom, op, nil
+			}
+			return expr.IsDistinctFrom, op, nil
 		}
 		p.Unscan()
+
+		if not {
+			return expr.IsNot, op, nil
+		}
 		return expr.Is, op, nil
+	case scanner.BETWEEN:
+		return betweenFunc(expr.Between), op, nil
+	case scanner.ANY:
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		switch tok {
+		case scanner.EQ, scanner.NEQ, scanner.GT, scanner.GTE, scanner.LT, scanner.LTE:
+			return func(lhs, rhs expr.Expr) expr.Expr {
+				return expr.Any(lhs, rhs, tok)
+			}, op, nil
+		}
+		return nil, 0, newParseError(scanner.Tokstr(tok, lit), []string{"=", "!=", ">", ">=", "<", "<="}, pos)
 	case scanner.NOT:
-		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.IN {
-			return nil, 0, newParseError(scanner.Tokstr(tok, lit), []string{"IN"}, pos)
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		switch tok {
+		case scanner.IN:
+			return expr.NotIn, op, nil
+		case scanner.BETWEEN:
+			return betweenFunc(expr.NotBetween), tok, nil
 		}
-		return expr.NotIn, op, nil
+		return nil, 0, newParseError(scanner.Tokstr(tok, lit), []string{"IN", "BETWEEN"}, pos)
 	}
 
 	panic(fmt.Sprintf("unknown operator %q", op))
 }
 
+// parseInOperand parses the right operand of the IN and NOT IN operators,
+// which is either a subquery or a non-binary expression.
+func (p *Parser) parseInOperand() (expr.Expr, error) {
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+		p.Unscan()
+		return p.parseUnaryExpr()
+	}
+
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.SELECT {
+		p.Unscan()
+		p.Unscan()
+		return p.parseUnaryExpr()
+	}
+
+	tree, refs, err := p.parseSubquery()
+	if err != nil {
+		return nil, err
+	}
+
+	sq, err := planner.NewSubquery(tree, refs...)
+	if err != nil {
+		return nil, &ParseError{Message: err.Error()}
+	}
+
+	return sq, nil
+}
+
+// parseBetweenOperand parses the bounds of the BETWEEN and NOT BETWEEN operators,
+// i.e. "expr AND expr", and returns them as a list of two expressions.
+// The lower bound must be a non-binary expression while the upper bound
+// can be followed by operators whose precedence is higher than comparisons.
+func (p *Parser) parseBetweenOperand() (expr.Expr, error) {
+	low, err := p.parseUnaryExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.AND {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"AND"}, pos)
+	}
+
+	high, err := p.parseUnaryExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	return expr.LiteralExprList{low, high}, nil
+}
+
+// betweenFunc turns a constructor of the BETWEEN operator into a function
+// whose right operand is the list returned by parseBetweenOperand.
+func betweenFunc(fn func(a, low, high expr.Expr) expr.Expr) func(lhs, rhs expr.Expr) expr.Expr {
+	return func(lhs, rhs expr.Expr) expr.Expr {
+		bounds := rhs.(expr.LiteralExprList)
+		return fn(lhs, bounds[0], bounds[1])
+	}
+}
+
 // parseUnaryExpr parses an non-binary expression.
 func (p *Parser) parseUnaryExpr() (expr.Expr, error) {
 	tok, pos, lit := p.ScanIgnoreWhitespace()
@@ -142,6 +270,17 @@ func (p *Parser) parseUnaryExpr() (expr.Expr, error) {
 	case scanner.CAST:
 		p.Unscan()
 		return p.parseCastExpression()
+	case scanner.CASE:
+		p.Unscan()
+		return p.parseCaseExpression()
+	case scanner.NOT:
+		// NOT has a lower precedence than comparisons but a higher one than AND:
+		// NOT a = 1 AND b is parsed as (NOT (a = 1)) AND b
+		e, err := p.parseExprWithMinPrecedence(scanner.AND.Precedence())
+		if err != nil {
+			return nil, err
+		}
+		return expr.Not(e), nil
 	case scanner.IDENT:
 		// if the next token is a left parenthesis, this is a function
 		if tok1, _, _ := p.Scan(); tok1 == scanner.LPAREN {
@@ -155,20 +294,19 @@ func (p *Parser) parseUnaryExpr() (expr.Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		fs := expr.FieldSelector(field)
-		return fs, nil
+		return p.fieldExpr(field), nil
 	case scanner.NAMEDPARAM:
 		if len(lit) == 1 {
 			return nil, &ParseError{Message: "missing param name"}
 		}
 		if p.orderedParams > 0 {
-			return nil, &ParseError{Message: "cannot mix positional arguments with named arguments"}
+			return nil, &ErrMixedParameters{Pos: pos}
 		}
 		p.namedParams++
 		return expr.NamedParam(lit[1:]), nil
 	case scanner.POSITIONALPARAM:
 		if p.namedParams > 0 {
-			return nil, &ParseError{Message: "cannot mix positional arguments with named arguments"}
+			return nil, &ErrMixedParameters{Pos: pos}
 		}
 		p.orderedParams++
 		return expr.PositionalParam(p.orderedParams), nil
@@ -202,6 +340,21 @@ func (p *Parser) parseUnaryExpr() (expr.Expr, error) {
 		p.Unscan()
 		return p.parseExprList(scanner.LSBRACKET, scanner.RSBRACKET)
 	case scanner.LPAREN:
+		// a SELECT statement in parentheses is a scalar subquery
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.SELECT {
+			tree, refs, err := p.parseSubquery()
+			if err != nil {
+				return nil, err
+			}
+
+			sq, err := planner.NewScalarSubquery(tree, refs...)
+			if err != nil {
+				return nil, &ParseError{Message: err.Error()}
+			}
+			return sq, nil
+		}
+		p.Unscan()
+
 		e, _, err := p.ParseExpr()
 		if err != nil {
 			return nil, err
@@ -264,20 +417,20 @@ func (p *Parser) parseIdentList() ([]string, error) {
 
 // parseParam parses a positional or named param.
 func (p *Parser) parseParam() (expr.Expr, error) {
-	tok, _, lit := p.ScanIgnoreWhitespace()
+	tok, pos, lit := p.ScanIgnoreWhitespace()
 	switch tok {
 	case scanner.NAMEDPARAM:
 		if len(lit) == 1 {
 			return nil, &ParseError{Message: "missing param name"}
 		}
 		if p.orderedParams > 0 {
-			return nil, &ParseError{Message: "cannot mix positional arguments with named arguments"}
+			return nil, &ErrMixedParameters{Pos: pos}
 		}
 		p.namedParams++
 		return expr.NamedParam(lit[1:]), nil
 	case scanner.POSITIONALPARAM:
 		if p.namedParams > 0 {
-			return nil, &ParseError{Message: "cannot mix positional arguments with named arguments"}
+			return nil, &ErrMixedParameters{Pos: pos}
 		}
 		p.orderedParams++
 		return expr.PositionalParam(p.orderedParams), nil
@@ -293,7 +446,7 @@ func (p *Parser) parseType() (document.ValueType, error) {
 		return document.ArrayValue, nil
 	case scanner.TYPEBLOB:
 		return document.BlobValue, nil
-	case scanner.TYPEBOOL:
+	case scanner.TYPEBOOL, scanner.TYPEBOOLEAN:
 		return document.BoolValue, nil
 	case scanner.TYPEBYTES:
 		return document.BlobValue, nil
@@ -488,10 +641,49 @@ func (p *Parser) parseExprList(leftToken, rightToken scanner.Token) (expr.Litera
 	return p.parseExprListUntil(rightToken)
 }
 
-// parseFunction parses a function call.
+// parseFunction parses a function call, followed by an optional
+// FILTER (WHERE expr) clause if the function is an aggregate function.
+func (p *Parser) parseFunction() (expr.Expr, error) {
+	e, err := p.parseFunctionCall()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.FILTER {
+		p.Unscan()
+		return e, nil
+	}
+
+	// functions registered by the user are only known to be aggregate
+	// functions once the statement is bound to a transaction
+	_, isAggregate := e.(document.AggregatorBuilder)
+	if _, ok := e.(expr.UserFunc); !ok && !isAggregate {
+		return nil, fmt.Errorf("FILTER can only be used with aggregate functions, got %s", e)
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.WHERE {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"WHERE"}, pos)
+	}
+
+	filter, _, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	return &expr.FilteredAggregateFunc{Agg: e, Filter: filter}, nil
+}
+
+// parseFunctionCall parses a function call.
 // a function is an identifier followed by a parenthesis,
 // an optional coma-separated list of expressions and a closing parenthesis.
-func (p *Parser) parseFunction() (expr.Expr, error) {
+func (p *Parser) parseFunctionCall() (expr.Expr, error) {
 	// Parse function name.
 	fname, err := p.parseIdent()
 	if err != nil {
@@ -513,12 +705,48 @@ func (p *Parser) parseFunction() (expr.Expr, error) {
 	}
 	p.Unscan()
 
+	// Special case: COUNT(DISTINCT expr, ...) counts distinct combinations of values.
+	// Aggregate functions registered by the user can also be called with DISTINCT.
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.DISTINCT {
+		exprs, err := p.parseFunctionArgs()
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.EqualFold(fname, "count") {
+			return &expr.CountDistinctFunc{Exprs: exprs}, nil
+		}
+
+		e, err := p.functions.GetFunc(fname, exprs...)
+		if err != nil {
+			return nil, err
+		}
+		uf, ok := e.(expr.UserFunc)
+		if !ok {
+			return nil, fmt.Errorf("%s() cannot be called with DISTINCT", fname)
+		}
+		uf.Distinct = true
+		return uf, nil
+	}
+	p.Unscan()
+
 	// Check if the function is called without arguments.
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.RPAREN {
 		return p.functions.GetFunc(fname)
 	}
 	p.Unscan()
 
+	exprs, err := p.parseFunctionArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.functions.GetFunc(fname, exprs...)
+}
+
+// parseFunctionArgs parses a non-empty coma-separated list of expressions
+// followed by a closing parenthesis.
+func (p *Parser) parseFunctionArgs() ([]expr.Expr, error) {
 	var exprs []expr.Expr
 
 	// Parse expressions.
@@ -541,7 +769,7 @@ func (p *Parser) parseFunction() (expr.Expr, error) {
 		return nil, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
 	}
 
-	return p.functions.GetFunc(fname, exprs...)
+	return exprs, nil
 }
 
 // parseCastExpression parses a string of the form CAST(expr AS type).
@@ -585,4 +813,72 @@ func (p *Parser) parseCastExpression() (expr.Expr, error) {
 	}
 
 	return expr.CastFunc{Expr: e, CastAs: tp}, nil
-}
\ No newline at end of file
+}
+// parseCaseExpression parses a CASE expression, in its simple or searched form:
+// CASE [operand] WHEN expr THEN expr [WHEN expr THEN expr ...] [ELSE expr] END.
+func (p *Parser) parseCaseExpression() (expr.Expr, error) {
+	// Parse required CASE token.
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.CASE {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"CASE"}, pos)
+	}
+
+	var c expr.CaseExpr
+
+	// Parse optional operand.
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.WHEN {
+		p.Unscan()
+		e, _, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		c.Operand = e
+	} else {
+		p.Unscan()
+	}
+
+	// Parse required WHEN clauses.
+	for {
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		if tok != scanner.WHEN {
+			if len(c.Whens) == 0 {
+				return nil, newParseError(scanner.Tokstr(tok, lit), []string{"WHEN"}, pos)
+			}
+			p.Unscan()
+			break
+		}
+
+		cond, _, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.THEN {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"THEN"}, pos)
+		}
+
+		then, _, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		c.Whens = append(c.Whens, expr.WhenClause{Cond: cond, Then: then})
+	}
+
+	// Parse optional ELSE clause.
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.ELSE {
+		e, _, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		c.Else = e
+	} else {
+		p.Unscan()
+	}
+
+	// Parse required END token.
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.END {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"END"}, pos)
+	}
+
+	return c, nil
+}
@@ -1,11 +1,13 @@
 package parser
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/genjidb/genji/sql/scanner"
 	"github.com/stretchr/testify/require"
 )
 
@@ -130,6 +132,39 @@ func TestParserExpr(t *testing.T) {
 		{"IN", "age IN ages", expr.In(expr.FieldSelector(parsePath(t, "age")), expr.FieldSelector(parsePath(t, "ages"))), false},
 		{"IS", "age IS NULL", expr.Is(expr.FieldSelector(parsePath(t, "age")), expr.NullValue()), false},
 		{"IS NOT", "age IS NOT NULL", expr.IsNot(expr.FieldSelector(parsePath(t, "age")), expr.NullValue()), false},
+		{"IS DISTINCT FROM", "age IS DISTINCT FROM NULL", expr.IsDistinctFrom(expr.FieldSelector(parsePath(t, "age")), expr.NullValue()), false},
+		{"IS NOT DISTINCT FROM", "age IS NOT DISTINCT FROM 10", expr.IsNotDistinctFrom(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)), false},
+		{"IS DISTINCT without FROM", "age IS DISTINCT 10", nil, true},
+		{"BETWEEN", "age BETWEEN 10 AND $max",
+			expr.And(
+				expr.Gte(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)),
+				expr.Lte(expr.FieldSelector(parsePath(t, "age")), expr.NamedParam("max")),
+			), false},
+		{"NOT BETWEEN", "age NOT BETWEEN 10 AND 20",
+			expr.Or(
+				expr.Lt(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)),
+				expr.Gt(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(20)),
+			), false},
+		{"BETWEEN then AND", "age + 1 BETWEEN 10 AND 20 + 1 AND a",
+			expr.And(
+				expr.And(
+					expr.Gte(expr.Add(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(1)), expr.IntegerValue(10)),
+					expr.Lte(expr.Add(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(1)), expr.Add(expr.IntegerValue(20), expr.IntegerValue(1))),
+				),
+				expr.FieldSelector(parsePath(t, "a")),
+			), false},
+		{"BETWEEN without AND", "age BETWEEN 10 OR 20", nil, true},
+		{"NOT without IN or BETWEEN", "age NOT 10", nil, true},
+		{"IN with path on the right", "'go' IN tags", expr.In(expr.TextValue("go"), expr.FieldSelector(parsePath(t, "tags"))), false},
+		{"ANY =", "tags ANY = 'go'", expr.Any(expr.FieldSelector(parsePath(t, "tags")), expr.TextValue("go"), scanner.EQ), false},
+		{"ANY >=", "ages ANY >= 10 + 1", expr.Any(expr.FieldSelector(parsePath(t, "ages")), expr.Add(expr.IntegerValue(10), expr.IntegerValue(1)), scanner.GTE), false},
+		{"ANY with AND", "tags ANY != 'go' AND a",
+			expr.And(
+				expr.Any(expr.FieldSelector(parsePath(t, "tags")), expr.TextValue("go"), scanner.NEQ),
+				expr.FieldSelector(parsePath(t, "a")),
+			), false},
+		{"ANY without comparison operator", "tags ANY 10", nil, true},
+		{"ANY with invalid operator", "tags ANY + 10", nil, true},
 		{"precedence", "4 > 1 + 2", expr.Gt(
 			expr.IntegerValue(4),
 			expr.Add(
@@ -155,11 +190,60 @@ func TestParserExpr(t *testing.T) {
 				),
 				expr.Lt(expr.FieldSelector(parsePath(t, "age")), expr.DoubleValue(10.4)),
 			), false},
+		{"NOT", "NOT active", expr.Not(expr.FieldSelector(parsePath(t, "active"))), false},
+		{"NOT then comparison", "NOT age = 10",
+			expr.Not(expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10))), false},
+		{"NOT then AND", "NOT active AND age = 10",
+			expr.And(
+				expr.Not(expr.FieldSelector(parsePath(t, "active"))),
+				expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)),
+			), false},
+		{"AND then NOT", "age = 10 AND NOT active OR b",
+			expr.Or(
+				expr.And(
+					expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)),
+					expr.Not(expr.FieldSelector(parsePath(t, "active"))),
+				),
+				expr.FieldSelector(parsePath(t, "b")),
+			), false},
+		{"NOT NOT", "NOT NOT active", expr.Not(expr.Not(expr.FieldSelector(parsePath(t, "active")))), false},
+		{"NOT without operand", "NOT", nil, true},
 		{"with NULL", "age > NULL", expr.Gt(expr.FieldSelector(parsePath(t, "age")), expr.NullValue()), false},
 		{"pk() function", "pk()", &expr.PKFunc{}, false},
 		{"count(expr) function", "count(a)", &expr.CountFunc{Expr: expr.FieldSelector(parsePath(t, "a"))}, false},
 		{"count(*) function", "count(*)", &expr.CountFunc{Wildcard: true}, false},
+		{"count(DISTINCT expr) function", "count(DISTINCT a)", &expr.CountDistinctFunc{Exprs: []expr.Expr{expr.FieldSelector(parsePath(t, "a"))}}, false},
+		{"count(DISTINCT expr, expr) function", "COUNT(DISTINCT a, b.c)", &expr.CountDistinctFunc{Exprs: []expr.Expr{expr.FieldSelector(parsePath(t, "a")), expr.FieldSelector(parsePath(t, "b.c"))}}, false},
+		{"count(DISTINCT) function", "count(DISTINCT)", nil, true},
+		{"DISTINCT in other functions", "max(DISTINCT a)", nil, true},
+		{"user function", "MyFunc(a, 1)", expr.UserFunc{FuncName: "myfunc", Exprs: []expr.Expr{expr.FieldSelector(parsePath(t, "a")), expr.IntegerValue(1)}}, false},
+		{"user function with DISTINCT", "myagg(DISTINCT a)", expr.UserFunc{FuncName: "myagg", Exprs: []expr.Expr{expr.FieldSelector(parsePath(t, "a"))}, Distinct: true}, false},
+		{"aggregate with FILTER", "COUNT(*) FILTER (WHERE a > 1)", &expr.FilteredAggregateFunc{Agg: &expr.CountFunc{Wildcard: true}, Filter: expr.Gt(expr.FieldSelector(parsePath(t, "a")), expr.IntegerValue(1))}, false},
+		{"user function with FILTER", "myagg(a) FILTER (WHERE b)", &expr.FilteredAggregateFunc{Agg: expr.UserFunc{FuncName: "myagg", Exprs: []expr.Expr{expr.FieldSelector(parsePath(t, "a"))}}, Filter: expr.FieldSelector(parsePath(t, "b"))}, false},
+		{"FILTER without WHERE", "COUNT(*) FILTER (a > 1)", nil, true},
+		{"FILTER on scalar function", "pk() FILTER (WHERE a > 1)", nil, true},
+		{"POINT", "POINT(48.8566, -2)", expr.PointFunc{Lat: expr.DoubleValue(48.8566), Lng: expr.IntegerValue(-2)}, false},
+		{"POINT with wrong number of arguments", "POINT(48.8566)", nil, true},
+		{"distance", "distance(a, b)", expr.DistanceFunc{A: expr.FieldSelector(parsePath(t, "a")), B: expr.FieldSelector(parsePath(t, "b"))}, false},
 		{"CAST", "CAST(a.b[1][0] AS TEXT)", expr.CastFunc{Expr: expr.FieldSelector(parsePath(t, "a.b[1][0]")), CastAs: document.TextValue}, false},
+
+		// case
+		{"CASE", "CASE WHEN a = 1 THEN 'one' WHEN a > 1 THEN 'many' ELSE 'none' END",
+			expr.CaseExpr{
+				Whens: []expr.WhenClause{
+					{Cond: expr.Eq(expr.FieldSelector(parsePath(t, "a")), expr.IntegerValue(1)), Then: expr.TextValue("one")},
+					{Cond: expr.Gt(expr.FieldSelector(parsePath(t, "a")), expr.IntegerValue(1)), Then: expr.TextValue("many")},
+				},
+				Else: expr.TextValue("none"),
+			}, false},
+		{"CASE with operand", "CASE a WHEN 1 THEN 'one' END",
+			expr.CaseExpr{
+				Operand: expr.FieldSelector(parsePath(t, "a")),
+				Whens:   []expr.WhenClause{{Cond: expr.IntegerValue(1), Then: expr.TextValue("one")}},
+			}, false},
+		{"CASE without WHEN", "CASE ELSE 1 END", nil, true},
+		{"CASE without THEN", "CASE WHEN a 1 END", nil, true},
+		{"CASE without END", "CASE WHEN a THEN 1", nil, true},
 	}
 
 	for _, test := range tests {
@@ -174,6 +258,23 @@ func TestParserExpr(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("mixed error", func(t *testing.T) {
+		tests := []struct {
+			s   string
+			pos scanner.Pos
+		}{
+			{"age >= ? AND age > $foo OR age < ?", scanner.Pos{Line: 0, Char: 19}},
+			{"age = $foo AND\n  age < ?", scanner.Pos{Line: 1, Char: 8}},
+		}
+
+		for _, test := range tests {
+			_, _, err := NewParser(strings.NewReader(test.s)).ParseExpr()
+			var mErr *ErrMixedParameters
+			require.True(t, errors.As(err, &mErr), "%v", err)
+			require.Equal(t, test.pos, mErr.Pos)
+		}
+	})
 }
 
 func TestParserPath(t *testing.T) {
@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 
+	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
@@ -10,7 +11,7 @@ import (
 
 // parseInsertStatement parses an insert string and returns a Statement AST object.
 // This function assumes the INSERT token has already been consumed.
-func (p *Parser) parseInsertStatement() (query.InsertStmt, error) {
+func (p *Parser) parseInsertStatement() (query.Statement, error) {
 	var stmt query.InsertStmt
 	var err error
 
@@ -27,6 +28,17 @@ func (p *Parser) parseInsertStatement() (query.InsertStmt, error) {
 		return stmt, pErr
 	}
 
+	// Parse DEFAULT VALUES
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.DEFAULT {
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.VALUES {
+			return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"VALUES"}, pos)
+		}
+
+		stmt.DefaultValues = true
+		return stmt, nil
+	}
+	p.Unscan()
+
 	valueParser := p.parseParamOrDocument
 
 	// Parse path list: (a, b, c)
@@ -42,6 +54,18 @@ func (p *Parser) parseInsertStatement() (query.InsertStmt, error) {
 		stmt.FieldNames = fields
 	}
 
+	// Parse SELECT ...
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.SELECT {
+		tree, err := p.parseCompoundSelectStatement()
+		if err != nil {
+			return nil, err
+		}
+
+		tree.Root = planner.NewInsertionNode(tree.Root, stmt.TableName, stmt.FieldNames)
+		return tree, nil
+	}
+	p.Unscan()
+
 	// Parse VALUES (v1, v2, v3)
 	values, err := p.parseValues(valueParser)
 	if err != nil {
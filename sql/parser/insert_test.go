@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/stretchr/testify/require"
@@ -76,6 +77,39 @@ func TestParserInsert(t *testing.T) {
 			nil, true},
 		{"Values / Without fields / Wrong values", "INSERT INTO test VALUES {a: 1}, ('e', 'f')",
 			nil, true},
+		{"Default values", "INSERT INTO test DEFAULT VALUES",
+			query.InsertStmt{
+				TableName:     "test",
+				DefaultValues: true,
+			}, false},
+		{"Default values / Without VALUES", "INSERT INTO test DEFAULT",
+			nil, true},
+		{"Default values / With fields", "INSERT INTO test (a) DEFAULT VALUES",
+			nil, true},
+		{"Select", "INSERT INTO test SELECT * FROM foo",
+			planner.NewTree(planner.NewInsertionNode(
+				planner.NewProjectionNode(
+					planner.NewTableInputNode("foo"),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"foo",
+				), "test", nil)),
+			false},
+		{"Select / With fields", "INSERT INTO test (a, b) SELECT c, d FROM foo WHERE a > 1",
+			planner.NewTree(planner.NewInsertionNode(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(
+						planner.NewTableInputNode("foo"),
+						expr.Gt(expr.FieldSelector(parsePath(t, "a")), expr.IntegerValue(1)),
+					),
+					[]planner.ProjectedField{
+						planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "c")), ExprName: "c"},
+						planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "d")), ExprName: "d"},
+					},
+					"foo",
+				), "test", []string{"a", "b"})),
+			false},
+		{"Select / Invalid", "INSERT INTO test SELECT FROM foo",
+			nil, true},
 	}
 
 	for _, test := range tests {
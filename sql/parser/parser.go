@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
@@ -20,6 +21,15 @@ type Parser struct {
 	namedParams   int
 	buf           *bytes.Buffer
 	functions     expr.Functions
+	// common table expressions in the scope
+	// of the statement being parsed
+	ctes map[string]*planner.Tree
+	// scopes of the select statements being parsed,
+	// from the outermost to the innermost one
+	scopes []*selectScope
+	// if true, NOT ends the expression being parsed instead of
+	// introducing the NOT IN and NOT BETWEEN operators
+	notEndsExpr bool
 }
 
 // NewParser returns a new instance of Parser.
@@ -85,10 +95,12 @@ func (p *Parser) ParseStatement() (query.Statement, error) {
 		return p.parseAlterStatement()
 	case scanner.BEGIN:
 		return p.parseBeginStatement()
+	case scanner.CHECK:
+		return p.parseCheckStatement()
 	case scanner.COMMIT:
 		return p.parseCommitStatement()
 	case scanner.SELECT:
-		return p.parseSelectStatement()
+		return p.parseCompoundSelectStatement()
 	case scanner.DELETE:
 		return p.parseDeleteStatement()
 	case scanner.UPDATE:
@@ -103,12 +115,20 @@ func (p *Parser) ParseStatement() (query.Statement, error) {
 		return p.parseExplainStatement()
 	case scanner.REINDEX:
 		return p.parseReIndexStatement()
+	case scanner.ANALYZE:
+		return p.parseAnalyzeStatement()
 	case scanner.ROLLBACK:
 		return p.parseRollbackStatement()
+	case scanner.SHOW:
+		return p.parseShowStatement()
+	case scanner.TRUNCATE:
+		return p.parseTruncateStatement()
+	case scanner.WITH:
+		return p.parseWithStatement()
 	}
 
 	return nil, newParseError(scanner.Tokstr(tok, lit), []string{
-		"ALTER", "BEGIN", "COMMIT", "SELECT", "DELETE", "UPDATE", "INSERT", "CREATE", "DROP", "EXPLAIN", "REINDEX", "ROLLBACK",
+		"ALTER", "ANALYZE", "BEGIN", "CHECK", "COMMIT", "SELECT", "DELETE", "UPDATE", "INSERT", "CREATE", "DROP", "EXPLAIN", "REINDEX", "ROLLBACK", "SHOW", "TRUNCATE", "WITH",
 	}, pos)
 }
 
@@ -221,3 +241,14 @@ func (e *ParseError) Error() string {
 	}
 	return fmt.Sprintf("found %s, expected %s at line %d, char %d", e.Found, strings.Join(e.Expected, ", "), e.Pos.Line+1, e.Pos.Char+1)
 }
+
+// ErrMixedParameters is returned when a statement uses both positional and named parameters.
+// Pos is the position of the first parameter whose style differs from the previous ones.
+type ErrMixedParameters struct {
+	Pos scanner.Pos
+}
+
+// Error returns the string representation of the error.
+func (e *ErrMixedParameters) Error() string {
+	return fmt.Sprintf("cannot mix positional arguments with named arguments at line %d, char %d", e.Pos.Line+1, e.Pos.Char+1)
+}
@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// A selectScope holds the alias of the table of a select statement being parsed
+// and the qualified paths that may refer to it.
+// Since the projection is parsed before the FROM clause, and subqueries can refer
+// to the tables of the statements enclosing them, these paths are parsed as references
+// which are resolved once the alias is known.
+type selectScope struct {
+	// alias of the table, set once the FROM clause is parsed.
+	alias      string
+	fromParsed bool
+
+	refs []scopedRef
+}
+
+// scopedRef is a reference used by a select statement or by one of its subqueries.
+type scopedRef struct {
+	ref *planner.FieldRef
+	// number of statements between the one using the reference and the one of the scope.
+	level int
+}
+
+func (p *Parser) pushScope() {
+	p.scopes = append(p.scopes, &selectScope{})
+}
+
+// currentScope returns the scope of the innermost select statement being parsed, if any.
+func (p *Parser) currentScope() *selectScope {
+	if len(p.scopes) == 0 {
+		return nil
+	}
+
+	return p.scopes[len(p.scopes)-1]
+}
+
+// popScope ends the scope of the current select statement. The references qualified by
+// the alias of its table are resolved, while the other ones are passed to the enclosing
+// statement, if any. The projected references which end up reading the documents of the
+// statement are replaced by field selectors.
+func (p *Parser) popScope(fields []planner.ProjectedField) {
+	s := p.currentScope()
+	p.scopes = p.scopes[:len(p.scopes)-1]
+	parent := p.currentScope()
+
+	for _, r := range s.refs {
+		switch {
+		case s.alias != "" && r.ref.Path[0].FieldName == s.alias:
+			r.ref.Qualified = true
+			r.ref.Depth = r.level
+		case parent != nil:
+			parent.refs = append(parent.refs, scopedRef{ref: r.ref, level: r.level + 1})
+		}
+	}
+
+	for i, f := range fields {
+		pe, ok := f.(planner.ProjectedExpr)
+		if !ok {
+			continue
+		}
+
+		ref, ok := pe.Expr.(*planner.FieldRef)
+		if ok && ref.Depth == 0 && (ref.Qualified || parent == nil) {
+			fs := expr.FieldSelector(ref.Field())
+			// the alias is not part of the name of the field
+			if pe.ExprName == ref.String() {
+				pe.ExprName = fs.Name()
+			}
+			pe.Expr = fs
+			fields[i] = pe
+		}
+	}
+}
+
+// fieldExpr returns the expression selecting the given path in the current select statement.
+// Paths qualified by the alias of its table select the fields of its documents.
+// Other qualified paths are returned as references if they may be qualified by an alias
+// that is not known yet.
+func (p *Parser) fieldExpr(path document.ValuePath) expr.Expr {
+	s := p.currentScope()
+	if s == nil || len(path) < 2 || path[0].FieldName == "" {
+		return expr.FieldSelector(path)
+	}
+
+	if s.fromParsed {
+		if s.alias != "" && path[0].FieldName == s.alias {
+			return expr.FieldSelector(path[1:])
+		}
+
+		// only subqueries can refer to other statements
+		if len(p.scopes) == 1 {
+			return expr.FieldSelector(path)
+		}
+	}
+
+	ref := planner.FieldRef{Path: path}
+	s.refs = append(s.refs, scopedRef{ref: &ref})
+	return &ref
+}
+
+// parseSubquery parses a select statement followed by a right parenthesis.
+// It returns the tree of the statement and the references it uses that may
+// refer to the enclosing statements.
+// This function assumes the left parenthesis and the SELECT token have already been consumed.
+func (p *Parser) parseSubquery() (*planner.Tree, []*planner.FieldRef, error) {
+	outer := p.currentScope()
+	var n int
+	if outer != nil {
+		n = len(outer.refs)
+	}
+
+	tree, err := p.parseSelectStatement()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return nil, nil, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	var refs []*planner.FieldRef
+	if outer != nil {
+		for _, r := range outer.refs[n:] {
+			refs = append(refs, r.ref)
+		}
+	}
+
+	return tree, refs, nil
+}
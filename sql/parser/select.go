@@ -2,65 +2,181 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/genjidb/genji/sql/scanner"
 )
 
+// parseCompoundSelectStatement parses a select statement optionally combined with other
+// select statements using the INTERSECT and EXCEPT operators, with or without ALL.
+// INTERSECT has a higher precedence than EXCEPT, and operators of the same precedence
+// are evaluated from left to right.
+// The clauses of each select statement, including ORDER BY and LIMIT, only apply to
+// that statement.
+// This function assumes the SELECT token has already been consumed.
+func (p *Parser) parseCompoundSelectStatement() (*planner.Tree, error) {
+	return p.parseSetOperation(scanner.EXCEPT, func() (*planner.Tree, error) {
+		return p.parseSetOperation(scanner.INTERSECT, p.parseSelectStatement)
+	})
+}
+
+// parseSetOperation parses a list of statements, parsed by parseOperand, separated by op.
+func (p *Parser) parseSetOperation(op scanner.Token, parseOperand func() (*planner.Tree, error)) (*planner.Tree, error) {
+	tree, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != op {
+			p.Unscan()
+			return tree, nil
+		}
+
+		var all bool
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.ALL {
+			all = true
+		} else {
+			p.Unscan()
+		}
+
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.SELECT {
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"SELECT"}, pos)
+		}
+
+		right, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := planner.NewSetOperationNode(op, all, tree, right)
+		if err != nil {
+			return nil, &ParseError{Message: err.Error()}
+		}
+		tree = planner.NewTree(n)
+	}
+}
+
 // parseSelectStatement parses a select string and returns a Statement AST object.
 // This function assumes the SELECT token has already been consumed.
 func (p *Parser) parseSelectStatement() (*planner.Tree, error) {
+	p.pushScope()
+	cfg, err := p.parseSelectClauses()
+	p.popScope(cfg.ProjectionExprs)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.ToTree()
+}
+
+// parseSelectClauses parses the clauses of a select statement within the scope of the statement.
+func (p *Parser) parseSelectClauses() (selectConfig, error) {
 	var cfg selectConfig
 	var err error
 
 	// Parse path list or query.Wildcard
 	cfg.ProjectionExprs, err = p.parseResultFields()
 	if err != nil {
-		return nil, err
+		return cfg, err
 	}
 
 	// Parse "FROM".
 	var found bool
 	cfg.TableName, found, err = p.parseFrom()
 	if err != nil {
-		return nil, err
+		return cfg, err
 	}
 	if !found {
-		return cfg.ToTree()
+		p.currentScope().fromParsed = true
+		return cfg, nil
+	}
+
+	// Parse table alias: "[AS] alias"
+	alias, err := p.parseTableAlias()
+	if err != nil {
+		return cfg, err
+	}
+	p.currentScope().alias = alias
+	p.currentScope().fromParsed = true
+
+	// the alias can qualify the wildcard
+	for i, f := range cfg.ProjectionExprs {
+		if w, ok := f.(planner.Wildcard); ok && alias != "" && w.TableName == alias {
+			w.TableName = cfg.TableName
+			cfg.ProjectionExprs[i] = w
+		}
+	}
+
+	// common table expressions shadow the tables with the same name
+	cfg.CTE = p.ctes[cfg.TableName]
+
+	// Parse index hint: "USE INDEX (index_name, ...)" or "IGNORE INDEX (index_name, ...)"
+	cfg.IndexHint, err = p.parseIndexHint()
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.IndexHint != nil && cfg.CTE != nil {
+		return cfg, &ParseError{Message: fmt.Sprintf("index hints cannot be used on common table expression %q", cfg.TableName)}
+	}
+
+	// Parse table sample: "TABLESAMPLE (expr PERCENT) [REPEATABLE (expr)]"
+	cfg.SampleExpr, cfg.SeedExpr, err = p.parseTableSample()
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.SampleExpr != nil && cfg.CTE != nil {
+		return cfg, &ParseError{Message: fmt.Sprintf("common table expression %q cannot be sampled", cfg.TableName)}
+	}
+	if cfg.SampleExpr != nil && cfg.IndexHint != nil && cfg.IndexHint.Force {
+		return cfg, &ParseError{Message: "USE INDEX cannot be used with TABLESAMPLE"}
 	}
 
 	// Parse condition: "WHERE expr".
 	cfg.WhereExpr, err = p.parseCondition()
 	if err != nil {
-		return nil, err
+		return cfg, err
 	}
 
-	// Parse group by: "GROUP BY expr"
-	cfg.GroupByExpr, err = p.parseGroupBy()
+	// Parse group by: "GROUP BY expr [, expr]*"
+	cfg.GroupByExprs, err = p.parseGroupBy()
 	if err != nil {
-		return nil, err
+		return cfg, err
 	}
 
 	// Parse order by: "ORDER BY path [ASC|DESC]?"
-	cfg.OrderBy, cfg.OrderByDirection, err = p.parseOrderBy()
+	cfg.OrderBy, cfg.OrderByDirection, cfg.OrderByNulls, err = p.parseOrderBy()
 	if err != nil {
-		return nil, err
+		return cfg, err
 	}
 
 	// Parse limit: "LIMIT expr"
 	cfg.LimitExpr, err = p.parseLimit()
 	if err != nil {
-		return nil, err
+		return cfg, err
 	}
 
 	// Parse offset: "OFFSET expr"
 	cfg.OffsetExpr, err = p.parseOffset()
 	if err != nil {
-		return nil, err
+		return cfg, err
 	}
 
-	return cfg.ToTree()
+	// Parse row locking: "FOR UPDATE"
+	cfg.ForUpdate, err = p.parseForUpdate()
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.ForUpdate && cfg.CTE != nil {
+		return cfg, &ParseError{Message: fmt.Sprintf("FOR UPDATE cannot be used on common table expression %q", cfg.TableName)}
+	}
+	if cfg.ForUpdate && len(cfg.GroupByExprs) > 0 {
+		return cfg, &ParseError{Message: "FOR UPDATE cannot be used with GROUP BY"}
+	}
+
+	return cfg, nil
 }
 
 // parseResultFields parses the list of result fields.
@@ -95,6 +211,11 @@ func (p *Parser) parseResultField() (planner.ProjectedField, error) {
 	}
 	p.Unscan()
 
+	// Check if the tablename.* tokens exist.
+	if w, ok := p.parseQualifiedWildcard(); ok {
+		return w, nil
+	}
+
 	e, lit, err := p.ParseExpr()
 	if err != nil {
 		return nil, err
@@ -102,8 +223,11 @@ func (p *Parser) parseResultField() (planner.ProjectedField, error) {
 
 	// FieldSelectors may be quoted, we make sure we name the result path
 	// with the unquoted name instead.
-	if fs, ok := e.(expr.FieldSelector); ok {
-		lit = fs.String()
+	switch t := e.(type) {
+	case expr.FieldSelector:
+		lit = t.Name()
+	case *planner.FieldRef:
+		lit = t.String()
 	}
 
 	rf := planner.ProjectedExpr{Expr: e, ExprName: lit}
@@ -122,6 +246,31 @@ func (p *Parser) parseResultField() (planner.ProjectedField, error) {
 	return rf, nil
 }
 
+// parseQualifiedWildcard parses a wildcard prefixed by a table name, i.e. "tablename.*".
+// If the next tokens don't match, they are unscanned.
+func (p *Parser) parseQualifiedWildcard() (planner.Wildcard, bool) {
+	tok, _, lit := p.ScanIgnoreWhitespace()
+	if tok != scanner.IDENT {
+		p.Unscan()
+		return planner.Wildcard{}, false
+	}
+
+	if tok, _, _ := p.Scan(); tok != scanner.DOT {
+		p.Unscan()
+		p.Unscan()
+		return planner.Wildcard{}, false
+	}
+
+	if tok, _, _ := p.Scan(); tok != scanner.MUL {
+		p.Unscan()
+		p.Unscan()
+		p.Unscan()
+		return planner.Wildcard{}, false
+	}
+
+	return planner.Wildcard{TableName: lit}, true
+}
+
 func (p *Parser) parseFrom() (string, bool, error) {
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.FROM {
 		p.Unscan()
@@ -139,7 +288,101 @@ func (p *Parser) parseFrom() (string, bool, error) {
 	return ident, true, nil
 }
 
-func (p *Parser) parseGroupBy() (expr.Expr, error) {
+// parseTableAlias parses the optional alias of the table of the FROM clause: "[AS] alias".
+func (p *Parser) parseTableAlias() (string, error) {
+	tok, _, lit := p.ScanIgnoreWhitespace()
+	switch tok {
+	case scanner.AS:
+		return p.parseIdent()
+	case scanner.IDENT:
+		return lit, nil
+	}
+	p.Unscan()
+
+	return "", nil
+}
+
+func (p *Parser) parseIndexHint() (*planner.IndexHint, error) {
+	var hint planner.IndexHint
+
+	// parse USE or IGNORE token
+	switch tok, _, _ := p.ScanIgnoreWhitespace(); tok {
+	case scanner.USE:
+		hint.Force = true
+	case scanner.IGNORE:
+	default:
+		p.Unscan()
+		return nil, nil
+	}
+
+	// parse INDEX token
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.INDEX {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"INDEX"}, pos)
+	}
+
+	// parse index names
+	indexes, found, err := p.parseFieldList()
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+	hint.Indexes = indexes
+
+	return &hint, nil
+}
+
+// parseTableSample parses "TABLESAMPLE (expr PERCENT) [REPEATABLE (expr)]"
+// and returns the percentage and seed expressions.
+func (p *Parser) parseTableSample() (percent, seed expr.Expr, err error) {
+	// parse TABLESAMPLE token
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.TABLESAMPLE {
+		p.Unscan()
+		return nil, nil, nil
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+		return nil, nil, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+
+	percent, _, err = p.ParseExpr()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.PERCENT {
+		return nil, nil, newParseError(scanner.Tokstr(tok, lit), []string{"PERCENT"}, pos)
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return nil, nil, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	// parse optional REPEATABLE token
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.REPEATABLE {
+		p.Unscan()
+		return percent, nil, nil
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+		return nil, nil, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+
+	seed, _, err = p.ParseExpr()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return nil, nil, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	return percent, seed, nil
+}
+
+func (p *Parser) parseGroupBy() ([]expr.Expr, error) {
 	// parse GROUP token
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.GROUP {
 		p.Unscan()
@@ -151,36 +394,73 @@ func (p *Parser) parseGroupBy() (expr.Expr, error) {
 		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"BY"}, pos)
 	}
 
-	// parse expr
-	e, _, err := p.ParseExpr()
-	return e, err
+	// parse comma separated list of exprs
+	var exprs []expr.Expr
+	for {
+		e, _, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
+			p.Unscan()
+			return exprs, nil
+		}
+	}
 }
 
-func (p *Parser) parseOrderBy() (expr.FieldSelector, scanner.Token, error) {
+func (p *Parser) parseOrderBy() (expr.Expr, scanner.Token, planner.NullsPosition, error) {
 	// parse ORDER token
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.ORDER {
 		p.Unscan()
-		return nil, 0, nil
+		return nil, 0, 0, nil
 	}
 
 	// parse BY token
 	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.BY {
-		return nil, 0, newParseError(scanner.Tokstr(tok, lit), []string{"BY"}, pos)
+		return nil, 0, 0, newParseError(scanner.Tokstr(tok, lit), []string{"BY"}, pos)
 	}
 
-	// parse path
-	ref, err := p.parsePath()
+	// parse expr
+	e, _, err := p.ParseExpr()
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, 0, err
 	}
 
 	// parse optional ASC or DESC
+	var dir scanner.Token
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.ASC || tok == scanner.DESC {
-		return expr.FieldSelector(ref), tok, nil
+		dir = tok
+	} else {
+		p.Unscan()
 	}
-	p.Unscan()
 
-	return expr.FieldSelector(ref), 0, nil
+	nulls, err := p.parseNullsPosition()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	return e, dir, nulls, nil
+}
+
+// parseNullsPosition parses an optional NULLS FIRST or NULLS LAST clause.
+// NULLS, FIRST and LAST are not keywords to allow using them as identifiers.
+func (p *Parser) parseNullsPosition() (planner.NullsPosition, error) {
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENT || !strings.EqualFold(lit, "NULLS") {
+		p.Unscan()
+		return planner.NullsDefault, nil
+	}
+
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	switch {
+	case tok == scanner.IDENT && strings.EqualFold(lit, "FIRST"):
+		return planner.NullsFirst, nil
+	case tok == scanner.IDENT && strings.EqualFold(lit, "LAST"):
+		return planner.NullsLast, nil
+	}
+
+	return 0, newParseError(scanner.Tokstr(tok, lit), []string{"FIRST", "LAST"}, pos)
 }
 
 func (p *Parser) parseLimit() (expr.Expr, error) {
@@ -190,6 +470,12 @@ func (p *Parser) parseLimit() (expr.Expr, error) {
 		return nil, nil
 	}
 
+	// LIMIT ALL is the same as no limit
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.ALL {
+		return nil, nil
+	}
+	p.Unscan()
+
 	e, _, err := p.ParseExpr()
 	return e, err
 }
@@ -205,16 +491,37 @@ func (p *Parser) parseOffset() (expr.Expr, error) {
 	return e, err
 }
 
+func (p *Parser) parseForUpdate() (bool, error) {
+	// parse FOR token
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.FOR {
+		p.Unscan()
+		return false, nil
+	}
+
+	// parse UPDATE token
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.UPDATE {
+		return false, newParseError(scanner.Tokstr(tok, lit), []string{"UPDATE"}, pos)
+	}
+
+	return true, nil
+}
+
 // SelectConfig holds SELECT configuration.
 type selectConfig struct {
 	TableName        string
+	CTE              *planner.Tree
+	IndexHint        *planner.IndexHint
+	SampleExpr       expr.Expr
+	SeedExpr         expr.Expr
 	WhereExpr        expr.Expr
-	GroupByExpr      expr.Expr
-	OrderBy          expr.FieldSelector
+	GroupByExprs     []expr.Expr
+	OrderBy          expr.Expr
 	OrderByDirection scanner.Token
+	OrderByNulls     planner.NullsPosition
 	OffsetExpr       expr.Expr
 	LimitExpr        expr.Expr
 	ProjectionExprs  []planner.ProjectedField
+	ForUpdate        bool
 }
 
 // ToTree turns the statement into an expression tree.
@@ -222,58 +529,95 @@ func (cfg selectConfig) ToTree() (*planner.Tree, error) {
 	var n planner.Node
 
 	if cfg.TableName != "" {
-		n = planner.NewTableInputNode(cfg.TableName)
+		if cfg.CTE != nil {
+			n = planner.NewCTEInputNode(cfg.TableName, cfg.CTE)
+		} else if cfg.SampleExpr != nil {
+			sample, err := cfg.tableSample()
+			if err != nil {
+				return nil, err
+			}
+
+			n = planner.NewSampledTableInputNode(cfg.TableName, cfg.IndexHint, sample)
+		} else if cfg.IndexHint != nil {
+			n = planner.NewTableInputNodeWithIndexHint(cfg.TableName, *cfg.IndexHint)
+		} else {
+			n = planner.NewTableInputNode(cfg.TableName)
+		}
 	}
 
 	if cfg.WhereExpr != nil {
 		n = planner.NewSelectionNode(n, cfg.WhereExpr)
 	}
 
-	if cfg.GroupByExpr != nil {
-		n = planner.NewGroupingNode(n, cfg.GroupByExpr)
+	if len(cfg.GroupByExprs) > 0 {
+		n = planner.NewGroupingNode(n, cfg.GroupByExprs...)
 	}
 
 	n = planner.NewProjectionNode(n, cfg.ProjectionExprs, cfg.TableName)
 
 	if cfg.OrderBy != nil {
-		n = planner.NewSortNode(n, cfg.OrderBy, cfg.OrderByDirection)
+		n = planner.NewSortNodeWithNulls(n, cfg.OrderBy, cfg.OrderByDirection, cfg.OrderByNulls)
 	}
 
+	// the offset and the limit are evaluated when the statement is executed,
+	// which allows them to be parameters.
 	if cfg.OffsetExpr != nil {
-		v, err := cfg.OffsetExpr.Eval(expr.EvalStack{})
-		if err != nil {
-			return nil, err
-		}
+		n = planner.NewOffsetExprNode(n, cfg.OffsetExpr)
+	}
 
-		if !v.Type.IsNumber() {
-			return nil, fmt.Errorf("offset expression must evaluate to a number, got %q", v.Type)
-		}
+	if cfg.LimitExpr != nil {
+		n = planner.NewLimitExprNode(n, cfg.LimitExpr)
+	}
 
-		v, err = v.CastAsInteger()
-		if err != nil {
-			return nil, err
-		}
+	// only the documents returned by the statement are locked
+	if cfg.ForUpdate {
+		n = planner.NewLockNode(n, cfg.TableName)
+	}
+
+	return &planner.Tree{Root: n}, nil
+}
+
+// tableSample evaluates the percentage and seed expressions of the TABLESAMPLE clause.
+func (cfg selectConfig) tableSample() (planner.TableSample, error) {
+	var sample planner.TableSample
 
-		n = planner.NewOffsetNode(n, int(v.V.(int64)))
+	v, err := cfg.SampleExpr.Eval(expr.EvalStack{})
+	if err != nil {
+		return sample, err
 	}
 
-	if cfg.LimitExpr != nil {
-		v, err := cfg.LimitExpr.Eval(expr.EvalStack{})
-		if err != nil {
-			return nil, err
-		}
+	if !v.Type.IsNumber() {
+		return sample, fmt.Errorf("sample percentage must evaluate to a number, got %q", v.Type)
+	}
 
-		if !v.Type.IsNumber() {
-			return nil, fmt.Errorf("limit expression must evaluate to a number, got %q", v.Type)
-		}
+	v, err = v.CastAsDouble()
+	if err != nil {
+		return sample, err
+	}
 
-		v, err = v.CastAsInteger()
-		if err != nil {
-			return nil, err
-		}
+	sample.Percent = v.V.(float64)
+	if sample.Percent < 0 || sample.Percent > 100 {
+		return sample, fmt.Errorf("sample percentage must be between 0 and 100, got %v", sample.Percent)
+	}
 
-		n = planner.NewLimitNode(n, int(v.V.(int64)))
+	if cfg.SeedExpr == nil {
+		return sample, nil
 	}
 
-	return &planner.Tree{Root: n}, nil
+	v, err = cfg.SeedExpr.Eval(expr.EvalStack{})
+	if err != nil {
+		return sample, err
+	}
+
+	if !v.Type.IsNumber() {
+		return sample, fmt.Errorf("sample seed must evaluate to a number, got %q", v.Type)
+	}
+
+	v, err = v.CastAsInteger()
+	if err != nil {
+		return sample, err
+	}
+
+	sample.Seed = v.V.(int64)
+	return sample, nil
 }
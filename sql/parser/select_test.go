@@ -90,6 +90,22 @@ func TestParserSelect(t *testing.T) {
 					"test",
 				)),
 			false},
+		{"WithQualifiedWildcard", "SELECT test.*, a.b FROM test",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewTableInputNode("test"),
+					[]planner.ProjectedField{planner.Wildcard{TableName: "test"}, planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a.b")), ExprName: "a.b"}},
+					"test",
+				)),
+			false},
+		{"WithQualifiedWildcard and quotes", "SELECT `my table`.* FROM `my table`",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewTableInputNode("my table"),
+					[]planner.ProjectedField{planner.Wildcard{TableName: "my table"}},
+					"my table",
+				)),
+			false},
 		{"WithFields and wildcard", "SELECT a, b, * FROM test",
 			planner.NewTree(
 				planner.NewProjectionNode(
@@ -131,6 +147,65 @@ func TestParserSelect(t *testing.T) {
 					"test",
 				)),
 			false},
+		{"WithGroupBy multiple exprs", "SELECT a, COUNT(*) FROM test GROUP BY a, b.c",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewGroupingNode(
+						planner.NewTableInputNode("test"),
+						expr.FieldSelector(parsePath(t, "a")),
+						expr.FieldSelector(parsePath(t, "b.c")),
+					),
+					[]planner.ProjectedField{
+						planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a")), ExprName: "a"},
+						planner.ProjectedExpr{Expr: &expr.CountFunc{Wildcard: true}, ExprName: "COUNT(*)"},
+					},
+					"test",
+				)),
+			false},
+		{"WithUseIndex", "SELECT * FROM test USE INDEX (idx_a) WHERE age = 10",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(
+						planner.NewTableInputNodeWithIndexHint("test", planner.IndexHint{Force: true, Indexes: []string{"idx_a"}}),
+						expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)),
+					),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"test",
+				)),
+			false},
+		{"WithIgnoreIndex", "SELECT * FROM test IGNORE INDEX (idx_a, `idx b`)",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewTableInputNodeWithIndexHint("test", planner.IndexHint{Indexes: []string{"idx_a", "idx b"}}),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"test",
+				)),
+			false},
+		{"WithUseIndex without index names", "SELECT * FROM test USE INDEX", nil, true},
+		{"WithUseIndex without INDEX", "SELECT * FROM test USE (idx_a)", nil, true},
+		{"WithTableSample", "SELECT * FROM test TABLESAMPLE (10 PERCENT) WHERE age = 10",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(
+						planner.NewSampledTableInputNode("test", nil, planner.TableSample{Percent: 10}),
+						expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)),
+					),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"test",
+				)),
+			false},
+		{"WithTableSample and seed", "SELECT * FROM test IGNORE INDEX (idx_a) TABLESAMPLE (2.5 PERCENT) REPEATABLE (42)",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSampledTableInputNode("test", &planner.IndexHint{Indexes: []string{"idx_a"}}, planner.TableSample{Percent: 2.5, Seed: 42}),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"test",
+				)),
+			false},
+		{"WithTableSample without PERCENT", "SELECT * FROM test TABLESAMPLE (10)", nil, true},
+		{"WithTableSample out of range", "SELECT * FROM test TABLESAMPLE (110 PERCENT)", nil, true},
+		{"WithTableSample not a number", "SELECT * FROM test TABLESAMPLE ('a' PERCENT)", nil, true},
+		{"WithTableSample and USE INDEX", "SELECT * FROM test USE INDEX (idx_a) TABLESAMPLE (10 PERCENT)", nil, true},
 		{"WithOrderBy", "SELECT * FROM test WHERE age = 10 ORDER BY a.b.c",
 			planner.NewTree(
 				planner.NewSortNode(
@@ -176,9 +251,53 @@ func TestParserSelect(t *testing.T) {
 					scanner.DESC,
 				)),
 			false},
+		{"WithOrderBy CASE", "SELECT * FROM test ORDER BY CASE WHEN a = 1 THEN 0 ELSE 1 END DESC",
+			planner.NewTree(
+				planner.NewSortNode(
+					planner.NewProjectionNode(
+						planner.NewTableInputNode("test"),
+						[]planner.ProjectedField{planner.Wildcard{}},
+						"test",
+					),
+					expr.CaseExpr{
+						Whens: []expr.WhenClause{
+							{Cond: expr.Eq(expr.FieldSelector(parsePath(t, "a")), expr.IntegerValue(1)), Then: expr.IntegerValue(0)},
+						},
+						Else: expr.IntegerValue(1),
+					},
+					scanner.DESC,
+				)),
+			false},
+		{"WithOrderBy DESC NULLS LAST", "SELECT * FROM test ORDER BY a DESC NULLS LAST",
+			planner.NewTree(
+				planner.NewSortNodeWithNulls(
+					planner.NewProjectionNode(
+						planner.NewTableInputNode("test"),
+						[]planner.ProjectedField{planner.Wildcard{}},
+						"test",
+					),
+					expr.FieldSelector(parsePath(t, "a")),
+					scanner.DESC,
+					planner.NullsLast,
+				)),
+			false},
+		{"WithOrderBy NULLS FIRST", "SELECT * FROM test ORDER BY a nulls first",
+			planner.NewTree(
+				planner.NewSortNodeWithNulls(
+					planner.NewProjectionNode(
+						planner.NewTableInputNode("test"),
+						[]planner.ProjectedField{planner.Wildcard{}},
+						"test",
+					),
+					expr.FieldSelector(parsePath(t, "a")),
+					scanner.ASC,
+					planner.NullsFirst,
+				)),
+			false},
+		{"WithOrderBy NULLS without position", "SELECT * FROM test ORDER BY a NULLS", nil, true},
 		{"WithLimit", "SELECT * FROM test WHERE age = 10 LIMIT 20",
 			planner.NewTree(
-				planner.NewLimitNode(
+				planner.NewLimitExprNode(
 					planner.NewProjectionNode(
 						planner.NewSelectionNode(
 							planner.NewTableInputNode("test"),
@@ -187,12 +306,23 @@ func TestParserSelect(t *testing.T) {
 						[]planner.ProjectedField{planner.Wildcard{}},
 						"test",
 					),
-					20,
+					expr.IntegerValue(20),
+				)),
+			false},
+		{"WithLimitAll", "SELECT * FROM test WHERE age = 10 LIMIT ALL",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(
+						planner.NewTableInputNode("test"),
+						expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)),
+					),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"test",
 				)),
 			false},
 		{"WithOffset", "SELECT * FROM test WHERE age = 10 OFFSET 20",
 			planner.NewTree(
-				planner.NewOffsetNode(
+				planner.NewOffsetExprNode(
 					planner.NewProjectionNode(
 						planner.NewSelectionNode(
 							planner.NewTableInputNode("test"),
@@ -201,13 +331,13 @@ func TestParserSelect(t *testing.T) {
 						[]planner.ProjectedField{planner.Wildcard{}},
 						"test",
 					),
-					20,
+					expr.IntegerValue(20),
 				)),
 			false},
 		{"WithLimitThenOffset", "SELECT * FROM test WHERE age = 10 LIMIT 10 OFFSET 20",
 			planner.NewTree(
-				planner.NewLimitNode(
-					planner.NewOffsetNode(
+				planner.NewLimitExprNode(
+					planner.NewOffsetExprNode(
 						planner.NewProjectionNode(
 							planner.NewSelectionNode(
 								planner.NewTableInputNode("test"),
@@ -216,12 +346,156 @@ func TestParserSelect(t *testing.T) {
 							[]planner.ProjectedField{planner.Wildcard{}},
 							"test",
 						),
-						20,
+						expr.IntegerValue(20),
 					),
-					10,
+					expr.IntegerValue(10),
 				)),
 			false},
 		{"WithOffsetThenLimit", "SELECT * FROM test WHERE age = 10 OFFSET 20 LIMIT 10", nil, true},
+		{"WithForUpdate", "SELECT * FROM test WHERE age = 10 LIMIT 10 FOR UPDATE",
+			planner.NewTree(
+				planner.NewLockNode(
+					planner.NewLimitExprNode(
+						planner.NewProjectionNode(
+							planner.NewSelectionNode(
+								planner.NewTableInputNode("test"),
+								expr.Eq(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)),
+							),
+							[]planner.ProjectedField{planner.Wildcard{}},
+							"test",
+						),
+						expr.IntegerValue(10),
+					),
+					"test",
+				)),
+			false},
+		{"WithForWithoutUpdate", "SELECT * FROM test FOR", nil, true},
+		{"WithForUpdateThenLimit", "SELECT * FROM test FOR UPDATE LIMIT 10", nil, true},
+		{"WithForUpdateAndGroupBy", "SELECT a FROM test GROUP BY a FOR UPDATE", nil, true},
+		{"WithForUpdateWithoutTable", "SELECT 1 FOR UPDATE", nil, true},
+		{"WithInSubquery", "SELECT * FROM test WHERE a IN (SELECT b FROM foo)",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(
+						planner.NewTableInputNode("test"),
+						expr.In(expr.FieldSelector(parsePath(t, "a")), newSubquery(t, planner.NewTree(
+							planner.NewProjectionNode(
+								planner.NewTableInputNode("foo"),
+								[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "b")), ExprName: "b"}},
+								"foo",
+							)))),
+					),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"test",
+				)),
+			false},
+		{"WithNotInSubquery", "SELECT * FROM test WHERE a NOT IN (SELECT b FROM foo WHERE c > 1)",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(
+						planner.NewTableInputNode("test"),
+						expr.NotIn(expr.FieldSelector(parsePath(t, "a")), newSubquery(t, planner.NewTree(
+							planner.NewProjectionNode(
+								planner.NewSelectionNode(
+									planner.NewTableInputNode("foo"),
+									expr.Gt(expr.FieldSelector(parsePath(t, "c")), expr.IntegerValue(1)),
+								),
+								[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "b")), ExprName: "b"}},
+								"foo",
+							)))),
+					),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"test",
+				)),
+			false},
+		{"WithTableAlias", "SELECT f.a, f.* FROM foo AS f WHERE f.b > 1",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewSelectionNode(
+						planner.NewTableInputNode("foo"),
+						expr.Gt(expr.FieldSelector(parsePath(t, "b")), expr.IntegerValue(1)),
+					),
+					[]planner.ProjectedField{
+						planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a")), ExprName: "a"},
+						planner.Wildcard{TableName: "foo"},
+					},
+					"foo",
+				)),
+			false},
+		{"WithCorrelatedSubquery", "SELECT (SELECT b FROM foo f WHERE f.c = t.a) AS x FROM test t",
+			func() *planner.Tree {
+				ref := &planner.FieldRef{Path: parsePath(t, "t.a"), Qualified: true, Depth: 1}
+
+				return planner.NewTree(
+					planner.NewProjectionNode(
+						planner.NewTableInputNode("test"),
+						[]planner.ProjectedField{planner.ProjectedExpr{Expr: newScalarSubquery(t, planner.NewTree(
+							planner.NewProjectionNode(
+								planner.NewSelectionNode(
+									planner.NewTableInputNode("foo"),
+									expr.Eq(expr.FieldSelector(parsePath(t, "c")), ref),
+								),
+								[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "b")), ExprName: "b"}},
+								"foo",
+							)), ref), ExprName: "x"}},
+						"test",
+					))
+			}(),
+			false},
+		{"WithInSubqueryAndWildcard", "SELECT * FROM test WHERE a IN (SELECT * FROM foo)", nil, true},
+		{"WithInSubqueryAndMultipleColumns", "SELECT * FROM test WHERE a IN (SELECT b, c FROM foo)", nil, true},
+		{"WithInSubqueryNotClosed", "SELECT * FROM test WHERE a IN (SELECT b FROM foo", nil, true},
+		{"WithIntersect", "SELECT a FROM test INTERSECT SELECT b FROM foo",
+			newSetOperationTree(t, scanner.INTERSECT, false,
+				planner.NewTree(planner.NewProjectionNode(
+					planner.NewTableInputNode("test"),
+					[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a")), ExprName: "a"}},
+					"test",
+				)),
+				planner.NewTree(planner.NewProjectionNode(
+					planner.NewTableInputNode("foo"),
+					[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "b")), ExprName: "b"}},
+					"foo",
+				)),
+			),
+			false},
+		{"WithExceptAll", "SELECT a FROM test EXCEPT ALL SELECT b FROM foo",
+			newSetOperationTree(t, scanner.EXCEPT, true,
+				planner.NewTree(planner.NewProjectionNode(
+					planner.NewTableInputNode("test"),
+					[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a")), ExprName: "a"}},
+					"test",
+				)),
+				planner.NewTree(planner.NewProjectionNode(
+					planner.NewTableInputNode("foo"),
+					[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "b")), ExprName: "b"}},
+					"foo",
+				)),
+			),
+			false},
+		{"WithIntersectPrecedence", "SELECT a FROM test EXCEPT SELECT b FROM foo INTERSECT SELECT c FROM bar",
+			newSetOperationTree(t, scanner.EXCEPT, false,
+				planner.NewTree(planner.NewProjectionNode(
+					planner.NewTableInputNode("test"),
+					[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a")), ExprName: "a"}},
+					"test",
+				)),
+				newSetOperationTree(t, scanner.INTERSECT, false,
+					planner.NewTree(planner.NewProjectionNode(
+						planner.NewTableInputNode("foo"),
+						[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "b")), ExprName: "b"}},
+						"foo",
+					)),
+					planner.NewTree(planner.NewProjectionNode(
+						planner.NewTableInputNode("bar"),
+						[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "c")), ExprName: "c"}},
+						"bar",
+					)),
+				),
+			),
+			false},
+		{"WithIntersectDifferentFieldCount", "SELECT a, b FROM test INTERSECT SELECT b FROM foo", nil, true},
+		{"WithExceptNoSelect", "SELECT a FROM test EXCEPT foo", nil, true},
 	}
 
 	for _, test := range tests {
@@ -237,3 +511,21 @@ func TestParserSelect(t *testing.T) {
 		})
 	}
 }
+
+func newSubquery(t testing.TB, tree *planner.Tree) *planner.Subquery {
+	sq, err := planner.NewSubquery(tree)
+	require.NoError(t, err)
+	return sq
+}
+
+func newScalarSubquery(t testing.TB, tree *planner.Tree, refs ...*planner.FieldRef) *planner.Subquery {
+	sq, err := planner.NewScalarSubquery(tree, refs...)
+	require.NoError(t, err)
+	return sq
+}
+
+func newSetOperationTree(t testing.TB, op scanner.Token, all bool, left, right *planner.Tree) *planner.Tree {
+	n, err := planner.NewSetOperationNode(op, all, left, right)
+	require.NoError(t, err)
+	return planner.NewTree(n)
+}
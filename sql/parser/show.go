@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// parseShowStatement parses a show statement.
+// This function assumes the SHOW token has already been consumed.
+func (p *Parser) parseShowStatement() (query.Statement, error) {
+	// SIZE is not a keyword to allow using it as an identifier.
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	if tok != scanner.IDENT || !strings.EqualFold(lit, "SIZE") {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"SIZE"}, pos)
+	}
+
+	return p.parseShowSizeStatement()
+}
+
+// parseShowSizeStatement parses a show size statement.
+// This function assumes the SHOW SIZE tokens have already been consumed.
+func (p *Parser) parseShowSizeStatement() (query.Statement, error) {
+	var stmt query.ShowSizeStmt
+	var err error
+
+	stmt.TableName, err = p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/sql/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserShow(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected query.Statement
+		errored  bool
+	}{
+		{"Size", "SHOW SIZE test", query.ShowSizeStmt{TableName: "test"}, false},
+		{"Lowercase", "show size test", query.ShowSizeStmt{TableName: "test"}, false},
+		{"No table", "SHOW SIZE", nil, true},
+		{"Unknown", "SHOW test", nil, true},
+		{"With extra", "SHOW SIZE test test", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+			require.EqualValues(t, test.expected, q.Statements[0])
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// parseTruncateStatement parses a truncate string and returns a Statement AST object.
+// This function assumes the TRUNCATE token has already been consumed.
+func (p *Parser) parseTruncateStatement() (query.TruncateTableStmt, error) {
+	var stmt query.TruncateTableStmt
+	var err error
+
+	// Parse "TABLE"
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.TABLE {
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"TABLE"}, pos)
+	}
+
+	// Parse table name
+	stmt.TableName, err = p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"table_name"}
+		return stmt, pErr
+	}
+
+	// Parse optional "RESTART IDENTITY" or "CONTINUE IDENTITY"
+	tok, _, _ := p.ScanIgnoreWhitespace()
+	switch tok {
+	case scanner.RESTART:
+		stmt.RestartIdentity = true
+	case scanner.CONTINUE:
+	default:
+		p.Unscan()
+		return stmt, nil
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.IDENTITY {
+		return stmt, newParseError(scanner.Tokstr(tok, lit), []string{"IDENTITY"}, pos)
+	}
+
+	return stmt, nil
+}
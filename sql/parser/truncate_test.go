@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/sql/query"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserTruncate(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected query.Statement
+		errored  bool
+	}{
+		{"Basic", "TRUNCATE TABLE test", query.TruncateTableStmt{TableName: "test"}, false},
+		{"Restart identity", "TRUNCATE TABLE test RESTART IDENTITY", query.TruncateTableStmt{TableName: "test", RestartIdentity: true}, false},
+		{"Continue identity", "TRUNCATE TABLE test CONTINUE IDENTITY", query.TruncateTableStmt{TableName: "test"}, false},
+		{"No table keyword", "TRUNCATE test", nil, true},
+		{"No table name", "TRUNCATE TABLE", nil, true},
+		{"No identity keyword", "TRUNCATE TABLE test RESTART", nil, true},
+		{"With extra", "TRUNCATE TABLE test test", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, q.Statements, 1)
+			require.EqualValues(t, test.expected, q.Statements[0])
+		})
+	}
+}
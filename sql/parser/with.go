@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// parseWithStatement parses a select statement preceded by a list of common table expressions
+// and returns a Statement AST object.
+// Common table expressions are only visible within the statement,
+// and each of them is visible to the ones that follow it.
+// This function assumes the WITH token has already been consumed.
+func (p *Parser) parseWithStatement() (*planner.Tree, error) {
+	if tok, pos, _ := p.ScanIgnoreWhitespace(); tok == scanner.RECURSIVE {
+		return nil, &ParseError{Message: "recursive common table expressions are not supported", Pos: pos}
+	}
+	p.Unscan()
+
+	// the expressions go out of scope once the statement is parsed
+	p.ctes = make(map[string]*planner.Tree)
+	defer func() {
+		p.ctes = nil
+	}()
+
+	for {
+		name, tree, err := p.parseCommonTableExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := p.ctes[name]; ok {
+			return nil, &ParseError{Message: fmt.Sprintf("common table expression %q specified more than once", name)}
+		}
+		p.ctes[name] = tree
+
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
+			p.Unscan()
+			break
+		}
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.SELECT {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"SELECT"}, pos)
+	}
+
+	return p.parseCompoundSelectStatement()
+}
+
+// parseCommonTableExpr parses a common table expression in the form: name AS (SELECT ...).
+func (p *Parser) parseCommonTableExpr() (string, *planner.Tree, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		pErr := err.(*ParseError)
+		pErr.Expected = []string{"cte_name"}
+		return "", nil, pErr
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.AS {
+		return "", nil, newParseError(scanner.Tokstr(tok, lit), []string{"AS"}, pos)
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
+		return "", nil, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.SELECT {
+		return "", nil, newParseError(scanner.Tokstr(tok, lit), []string{"SELECT"}, pos)
+	}
+
+	tree, err := p.parseCompoundSelectStatement()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		return "", nil, newParseError(scanner.Tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	return name, tree, nil
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji/sql/planner"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserWith(t *testing.T) {
+	cte := func(tableName string) *planner.Tree {
+		return planner.NewTree(
+			planner.NewProjectionNode(
+				planner.NewSelectionNode(
+					planner.NewTableInputNode(tableName),
+					expr.Gt(expr.FieldSelector(parsePath(t, "age")), expr.IntegerValue(10)),
+				),
+				[]planner.ProjectedField{planner.Wildcard{}},
+				tableName,
+			))
+	}
+
+	tests := []struct {
+		name     string
+		s        string
+		expected *planner.Tree
+		mustFail bool
+	}{
+		{"One", "WITH old AS (SELECT * FROM test WHERE age > 10) SELECT a FROM old",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewCTEInputNode("old", cte("test")),
+					[]planner.ProjectedField{planner.ProjectedExpr{Expr: expr.FieldSelector(parsePath(t, "a")), ExprName: "a"}},
+					"old",
+				)),
+			false},
+		{"Shadowing", "WITH test AS (SELECT * FROM test WHERE age > 10) SELECT * FROM test",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewCTEInputNode("test", cte("test")),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"test",
+				)),
+			false},
+		{"Multiple", "WITH a AS (SELECT * FROM test WHERE age > 10), b AS (SELECT * FROM a) SELECT * FROM b",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewCTEInputNode("b", planner.NewTree(
+						planner.NewProjectionNode(
+							planner.NewCTEInputNode("a", cte("test")),
+							[]planner.ProjectedField{planner.Wildcard{}},
+							"a",
+						))),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"b",
+				)),
+			false},
+		{"Table", "WITH a AS (SELECT * FROM test WHERE age > 10) SELECT * FROM foo",
+			planner.NewTree(
+				planner.NewProjectionNode(
+					planner.NewTableInputNode("foo"),
+					[]planner.ProjectedField{planner.Wildcard{}},
+					"foo",
+				)),
+			false},
+		{"Recursive", "WITH RECURSIVE a AS (SELECT * FROM test) SELECT * FROM a", nil, true},
+		{"Duplicate", "WITH a AS (SELECT * FROM test), a AS (SELECT * FROM test) SELECT * FROM a", nil, true},
+		{"Missing AS", "WITH a (SELECT * FROM test) SELECT * FROM a", nil, true},
+		{"Missing parentheses", "WITH a AS SELECT * FROM test SELECT * FROM a", nil, true},
+		{"Not a select", "WITH a AS (DELETE FROM test) SELECT * FROM a", nil, true},
+		{"Missing select", "WITH a AS (SELECT * FROM test)", nil, true},
+		{"Update", "WITH a AS (SELECT * FROM test) UPDATE a SET b = 1", nil, true},
+		{"Index hint", "WITH a AS (SELECT * FROM test) SELECT * FROM a USE INDEX (idx)", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := ParseQuery(context.Background(), test.s)
+			if !test.mustFail {
+				require.NoError(t, err)
+				require.Len(t, q.Statements, 1)
+				require.EqualValues(t, test.expected, q.Statements[0])
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+
+	t.Run("Scope", func(t *testing.T) {
+		q, err := ParseQuery(context.Background(), "WITH a AS (SELECT * FROM test) SELECT * FROM a; SELECT * FROM a")
+		require.NoError(t, err)
+		require.Len(t, q.Statements, 2)
+
+		// the expression is not visible to the second statement
+		require.EqualValues(t, planner.NewTree(
+			planner.NewProjectionNode(
+				planner.NewTableInputNode("a"),
+				[]planner.ProjectedField{planner.Wildcard{}},
+				"a",
+			)), q.Statements[1])
+	})
+}
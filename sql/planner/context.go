@@ -0,0 +1,46 @@
+package planner
+
+import (
+	"context"
+
+	"github.com/genjidb/genji/document"
+)
+
+// setContext shares the context of the statement with every node of the tree that reads
+// documents from a table, including the nodes of common table expressions and set operations,
+// so that they stop once the context is canceled or expires.
+func setContext(t *Tree, ctx context.Context) {
+	for n := t.Root; n != nil; n = n.Left() {
+		switch nn := n.(type) {
+		case *tableInputNode:
+			nn.ctx = ctx
+		case *indexInputNode:
+			nn.ctx = ctx
+		case *cteInputNode:
+			setContext(nn.tree, ctx)
+		case *setOperationNode:
+			setContext(nn.leftTree, ctx)
+			setContext(nn.rightTree, ctx)
+		}
+	}
+}
+
+// checkContext returns an iterator that returns the error of ctx
+// before reading a document if ctx is done.
+// If ctx is nil, it returns it unchanged.
+func checkContext(ctx context.Context, it document.Iterator) document.Iterator {
+	if ctx == nil {
+		return it
+	}
+
+	return document.IteratorFunc(func(fn func(d document.Document) error) error {
+		return it.Iterate(func(d document.Document) error {
+			err := ctx.Err()
+			if err != nil {
+				return err
+			}
+
+			return fn(d)
+		})
+	})
+}
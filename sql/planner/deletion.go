@@ -46,14 +46,24 @@ func (n *deletionNode) Bind(tx *database.Transaction, params []expr.Param) (err
 // left to delete.
 // Increasing deleteBufferSize will occasionate less key searches (O(log n) for most engines) but will take more memory.
 func (n *deletionNode) toStream(st document.Stream) (document.Stream, error) {
-	st = st.Limit(deleteBufferSize)
+	// the stream is read again after each batch, which restarts the limit of the statement,
+	// so the limit is applied to the total number of deleted documents instead.
+	limit := -1
+	if ln, ok := n.left.(*limitNode); ok {
+		limit = ln.limit
+	}
 
 	keys := make([][]byte, deleteBufferSize)
 
-	for {
+	for limit != 0 {
+		size := deleteBufferSize
+		if limit > 0 && limit < size {
+			size = limit
+		}
+
 		var i int
 
-		err := st.Iterate(func(d document.Document) error {
+		err := st.Limit(size).Iterate(func(d document.Document) error {
 			k, ok := d.(document.Keyer)
 			if !ok {
 				return errors.New("attempt to delete document without key")
@@ -67,16 +77,18 @@ func (n *deletionNode) toStream(st document.Stream) (document.Stream, error) {
 			return document.Stream{}, err
 		}
 
-		keys = keys[:i]
-
-		for _, key := range keys {
+		for _, key := range keys[:i] {
 			err = n.table.Delete(key)
 			if err != nil {
 				return document.Stream{}, err
 			}
 		}
 
-		if i < deleteBufferSize {
+		if limit > 0 {
+			limit -= i
+		}
+
+		if i < size {
 			break
 		}
 	}
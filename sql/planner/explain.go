@@ -3,6 +3,7 @@ package planner
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
@@ -20,10 +21,16 @@ type ExplainStmt struct {
 // Run analyses the inner statement and displays its execution plan.
 // If the statement is a tree, Bind and Optimize will be called prior to
 // displaying all the operations.
-// Explain currently only works on SELECT, UPDATE and DELETE statements.
+// If the statement writes to a table, the indexes of the table maintained
+// by the statement are listed as well.
+// Explain currently only works on SELECT, INSERT, UPDATE and DELETE statements.
 func (s *ExplainStmt) Run(ctx context.Context, tx *database.Transaction, params []expr.Param) (query.Result, error) {
 	switch t := s.Statement.(type) {
 	case *Tree:
+		// the root of the tree can be removed by the optimizer
+		tableName, ops := writtenTable(t)
+
+		t = t.clone()
 		err := Bind(t, tx, params)
 		if err != nil {
 			return query.Result{}, err
@@ -34,21 +41,80 @@ func (s *ExplainStmt) Run(ctx context.Context, tx *database.Transaction, params
 			return query.Result{}, err
 		}
 
-		return s.createResult(t.String())
+		return s.createResult(tx, t.String(), tableName, ops)
+	case query.InsertStmt:
+		plan := fmt.Sprintf("Insert(%s)", t.TableName)
+		if len(t.FieldNames) > 0 {
+			plan = fmt.Sprintf("Insert(%s, %v)", t.TableName, t.FieldNames)
+		}
+
+		return s.createResult(tx, plan, t.TableName, 1)
 	}
 
-	return query.Result{}, errors.New("EXPLAIN only works on SELECT, UPDATE AND DELETE statements")
+	return query.Result{}, errors.New("EXPLAIN only works on SELECT, INSERT, UPDATE AND DELETE statements")
 }
 
-func (s *ExplainStmt) createResult(text string) (query.Result, error) {
+// writtenTable returns the name of the table written by the tree, and the number
+// of operations run on each of its indexes for every document written:
+// inserting and deleting a document adds or removes one entry from every index,
+// replacing it removes the old entry then adds the new one.
+// If the tree doesn't write anything, it returns an empty table name.
+func writtenTable(t *Tree) (string, int) {
+	switch n := t.Root.(type) {
+	case *insertionNode:
+		return n.tableName, 1
+	case *deletionNode:
+		return n.tableName, 1
+	case *replacementNode:
+		return n.tableName, 2
+	}
+
+	return "", 0
+}
+
+func (s *ExplainStmt) createResult(tx *database.Transaction, text, tableName string, ops int) (query.Result, error) {
+	fb := document.NewFieldBuffer().
+		Add("plan", document.NewTextValue(text))
+
+	if tableName != "" {
+		indexes, err := explainIndexes(tx, tableName, ops)
+		if err != nil {
+			return query.Result{}, err
+		}
+
+		fb.Add("indexes", document.NewArrayValue(indexes))
+	}
+
 	return query.Result{
-		Stream: document.NewStream(
-			document.NewIterator(
-				document.NewFieldBuffer().
-					Add("plan", document.NewTextValue(text)))),
+		Stream: document.NewStream(document.NewIterator(fb)),
 	}, nil
 }
 
+// explainIndexes describes the indexes of the table and the number of
+// operations run on each of them for every document written.
+func explainIndexes(tx *database.Transaction, tableName string, ops int) (document.ValueBuffer, error) {
+	tb, err := tx.GetTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := tb.Indexes()
+	if err != nil {
+		return nil, err
+	}
+
+	vb := document.ValueBuffer{}
+	for _, idx := range indexes {
+		vb = vb.Append(document.NewDocumentValue(document.NewFieldBuffer().
+			Add("index_name", document.NewTextValue(idx.Opts.IndexName)).
+			Add("path", document.NewTextValue(idx.Opts.Path.String())).
+			Add("unique", document.NewBoolValue(idx.Opts.Unique)).
+			Add("operations_per_row", document.NewIntegerValue(int64(ops)))))
+	}
+
+	return vb, nil
+}
+
 // IsReadOnly indicates that this statement doesn't write anything into
 // the database.
 func (s *ExplainStmt) IsReadOnly() bool {
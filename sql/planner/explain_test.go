@@ -2,9 +2,11 @@ package planner_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
 	"github.com/stretchr/testify/require"
 )
 
@@ -17,21 +19,45 @@ func TestExplainStmt(t *testing.T) {
 		{"EXPLAIN SELECT 1 + 1", false, `"∏(1 + 1)"`},
 		{"EXPLAIN SELECT * FROM noexist", true, ``},
 		{"EXPLAIN SELECT * FROM test", false, `"Table(test) -> ∏(*)"`},
-		{"EXPLAIN SELECT a + 1 FROM test", false, `"Table(test) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 10", false, `"Table(test) -> σ(cond: c > 10) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 10 AND d > 20", false, `"Table(test) -> σ(cond: d > 20) -> σ(cond: c > 10) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 10 OR d > 20", false, `"Table(test) -> σ(cond: c > 10 OR d > 20) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c IN [1 + 1, 2 + 2]", false, `"Table(test) -> σ(cond: c IN [2, 4]) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test", false, `"Table(test, fields: a) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 10", false, `"Table(test, fields: a, c) -> σ(cond: c > 10) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 10 AND d > 20", false, `"Table(test, fields: a, d, c) -> σ(cond: c > 10) -> σ(cond: d > 20) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 10 OR d > 20", false, `"Table(test, fields: a, c, d) -> σ(cond: c > 10 OR d > 20) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c IN [1 + 1, 2 + 2]", false, `"Table(test, fields: a, c) -> σ(cond: c IN [2, 4]) -> ∏(a + 1)"`},
 		{"EXPLAIN SELECT a + 1 FROM test WHERE a > 10", false, `"Index(idx_a) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE a > 10 AND b > 20 AND c > 30", false, `"Index(idx_b) -> σ(cond: c > 30) -> σ(cond: a > 10) -> ∏(a + 1)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 30 ORDER BY a DESC LIMIT 10 OFFSET 20", false, `"Table(test) -> σ(cond: c > 30) -> ∏(a + 1) -> Sort(a DESC) -> Offset(20) -> Limit(10)"`},
-		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 30 GROUP BY b ORDER BY a DESC LIMIT 10 OFFSET 20", false, `"Table(test) -> σ(cond: c > 30) -> G(b) -> ∏(a + 1) -> Sort(a DESC) -> Offset(20) -> Limit(10)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE a > 10 AND b > 20 AND c > 30", false, `"Index(idx_b) -> σ(cond: a > 10) -> σ(cond: c > 30) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE a BETWEEN 10 AND 20 AND c > 30", false, `"Index(idx_a) -> σ(cond: c > 30) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE a NOT BETWEEN 10 AND 20", false, `"Table(test, fields: a) -> σ(cond: a < 10 OR a > 20) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test USE INDEX (idx_a) WHERE a > 10 AND b > 20", false, `"Index(idx_a) -> σ(cond: b > 20) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test USE INDEX (idx_a) WHERE k = 10 AND a > 10", false, `"Index(idx_a) -> σ(cond: k = 10) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test USE INDEX (idx_a) WHERE c > 10", true, ``},
+		{"EXPLAIN SELECT * FROM test USE INDEX (idx_a) ORDER BY a", false, `"Index(idx_a) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test USE INDEX (idx_a) WHERE c > 10 ORDER BY a DESC", false, `"Index(idx_a DESC) -> σ(cond: c > 10) -> ∏(*)"`},
+		{"EXPLAIN SELECT * FROM test USE INDEX (idx_a) ORDER BY c", true, ``},
+		{"EXPLAIN SELECT a + 1 FROM test IGNORE INDEX (idx_a) WHERE a > 10", false, `"Table(test, fields: a) IGNORE INDEX (idx_a) -> σ(cond: a > 10) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test IGNORE INDEX (noexist) WHERE a > 10", true, ``},
+		{"EXPLAIN SELECT a + 1 FROM test TABLESAMPLE (10 PERCENT) REPEATABLE (42) WHERE a > 10", false, `"Table(test, fields: a) TABLESAMPLE (10 PERCENT) REPEATABLE (42) -> σ(cond: a > 10) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 30 ORDER BY a DESC LIMIT 10 OFFSET 20", false, `"Index(idx_a DESC) -> σ(cond: c > 30) -> ∏(a + 1) -> Offset(20) -> Limit(10)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 30 ORDER BY c DESC LIMIT 10 OFFSET 20", false, `"Table(test, fields: c, a) -> σ(cond: c > 30) -> ∏(a + 1) -> Sort(c DESC, top 30) -> Offset(20) -> Limit(10)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE a BETWEEN 10 AND 20 ORDER BY a", false, `"Index(idx_a) -> ∏(a + 1)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE a BETWEEN 10 AND 20 ORDER BY a DESC", false, `"Index(idx_a) -> ∏(a + 1) -> Sort(a DESC)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE a IN [1, 2] ORDER BY a", false, `"Index(idx_a) -> ∏(a + 1) -> Sort(a ASC)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE b > 30 ORDER BY a", false, `"Index(idx_b) -> ∏(a + 1) -> Sort(a ASC)"`},
+		{"EXPLAIN SELECT a + 1 FROM test WHERE c > 30 GROUP BY b ORDER BY a DESC LIMIT 10 OFFSET 20", false, `"Table(test) -> σ(cond: c > 30) -> G(b) -> ∏(a + 1) -> Sort(a DESC, top 30) -> Offset(20) -> Limit(10)"`},
+		{"EXPLAIN WITH t AS (SELECT * FROM test WHERE a > 10) SELECT b FROM t WHERE c > 10", false, `"CTE(t: Index(idx_a) -> ∏(*)) -> σ(cond: c > 10) -> ∏(b)"`},
+		{"EXPLAIN WITH t AS (SELECT * FROM noexist) SELECT b FROM t", true, ``},
+		{"EXPLAIN SELECT * FROM test WHERE c > 10 FOR UPDATE", false, `"Table(test) -> σ(cond: c > 10) -> ∏(*) -> Lock(test)"`},
+		{"EXPLAIN SELECT * FROM test ORDER BY c LIMIT 1 FOR UPDATE", false, `"Table(test) -> ∏(*) -> Sort(c ASC, top 1) -> Limit(1) -> Lock(test)"`},
 		{"EXPLAIN UPDATE test SET a = 10", false, `"Table(test) -> Set(a = 10) -> Replace(test)"`},
 		{"EXPLAIN UPDATE test SET a = 10 WHERE c > 10", false, `"Table(test) -> σ(cond: c > 10) -> Set(a = 10) -> Replace(test)"`},
 		{"EXPLAIN UPDATE test SET a = 10 WHERE a > 10", false, `"Index(idx_a) -> Set(a = 10) -> Replace(test)"`},
 		{"EXPLAIN DELETE FROM test", false, `"Table(test) -> Delete(test)"`},
 		{"EXPLAIN DELETE FROM test WHERE c > 10", false, `"Table(test) -> σ(cond: c > 10) -> Delete(test)"`},
 		{"EXPLAIN DELETE FROM test WHERE a > 10", false, `"Index(idx_a) -> Delete(test)"`},
+		{"EXPLAIN INSERT INTO test (k, a) VALUES (1, 2)", false, `"Insert(test, [k a])"`},
+		{"EXPLAIN INSERT INTO test VALUES {k: 1}", false, `"Insert(test)"`},
+		{"EXPLAIN INSERT INTO noexist VALUES {k: 1}", true, ``},
+		{"EXPLAIN INSERT INTO test SELECT * FROM test WHERE a > 10", false, `"Index(idx_a) -> ∏(*) -> Insert(test)"`},
 	}
 
 	for _, test := range tests {
@@ -64,3 +90,58 @@ func TestExplainStmt(t *testing.T) {
 		})
 	}
 }
+
+func TestExplainIndexes(t *testing.T) {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test (k INTEGER PRIMARY KEY);
+		CREATE INDEX idx_a ON test (a);
+		CREATE UNIQUE INDEX idx_b ON test (b);
+		CREATE INDEX idx_c_d ON test (c.d);
+		CREATE TABLE other;
+	`)
+	require.NoError(t, err)
+
+	indexes := func(ops int) string {
+		return fmt.Sprintf(`[
+			{"index_name": "idx_a", "path": "a", "unique": false, "operations_per_row": %[1]d},
+			{"index_name": "idx_b", "path": "b", "unique": true, "operations_per_row": %[1]d},
+			{"index_name": "idx_c_d", "path": "c.d", "unique": false, "operations_per_row": %[1]d}
+		]`, ops)
+	}
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"EXPLAIN INSERT INTO test (k, a, b) VALUES (1, 2, 3)", indexes(1)},
+		{"EXPLAIN INSERT INTO test SELECT * FROM test", indexes(1)},
+		{"EXPLAIN UPDATE test SET a = 10 WHERE b > 10", indexes(2)},
+		{"EXPLAIN DELETE FROM test", indexes(1)},
+		{"EXPLAIN INSERT INTO other VALUES {a: 1}", `[]`},
+		{"EXPLAIN SELECT * FROM test", ``},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			d, err := db.QueryDocument(ctx, test.query)
+			require.NoError(t, err)
+
+			v, err := d.GetByField("indexes")
+			if test.expected == "" {
+				require.Equal(t, document.ErrFieldNotFound, err)
+				return
+			}
+			require.NoError(t, err)
+
+			data, err := v.MarshalJSON()
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, string(data))
+		})
+	}
+}
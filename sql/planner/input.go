@@ -1,8 +1,14 @@
 package planner
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
@@ -14,9 +20,16 @@ type tableInputNode struct {
 	node
 
 	tableName string
-	table     *database.Table
-	tx        *database.Transaction
-	params    []expr.Param
+	hint      *IndexHint
+	sample    *TableSample
+	// if not empty, only these top-level fields
+	// of the documents are decoded.
+	fields []document.ValuePath
+	table  *database.Table
+	tx     *database.Transaction
+	params []expr.Param
+	// context of the statement, checked before reading each document.
+	ctx context.Context
 }
 
 var _ inputNode = (*tableInputNode)(nil)
@@ -32,6 +45,121 @@ func NewTableInputNode(tableName string) Node {
 	}
 }
 
+// NewTableInputNodeWithIndexHint creates an input node that can be used to read documents
+// from a table. The optimizer will choose the index used to read the table according to the hint.
+func NewTableInputNodeWithIndexHint(tableName string, hint IndexHint) Node {
+	return &tableInputNode{
+		node: node{
+			op: Input,
+		},
+		tableName: tableName,
+		hint:      &hint,
+	}
+}
+
+// NewSampledTableInputNode creates an input node that only returns a deterministic sample
+// of the documents of a table. The hint is optional.
+func NewSampledTableInputNode(tableName string, hint *IndexHint, sample TableSample) Node {
+	return &tableInputNode{
+		node: node{
+			op: Input,
+		},
+		tableName: tableName,
+		hint:      hint,
+		sample:    &sample,
+	}
+}
+
+// An IndexHint tells the optimizer which indexes it can use to read a table.
+type IndexHint struct {
+	// If Force is true, the optimizer must use one of the indexes,
+	// otherwise it must not use any of them.
+	Force   bool
+	Indexes []string
+}
+
+func (h IndexHint) String() string {
+	if h.Force {
+		return fmt.Sprintf("USE INDEX (%s)", strings.Join(h.Indexes, ", "))
+	}
+
+	return fmt.Sprintf("IGNORE INDEX (%s)", strings.Join(h.Indexes, ", "))
+}
+
+// allows returns true if the hint allows the use of the given index.
+func (h IndexHint) allows(indexName string) bool {
+	for _, name := range h.Indexes {
+		if name == indexName {
+			return h.Force
+		}
+	}
+
+	return !h.Force
+}
+
+// A TableSample selects approximately a percentage of the documents of a table.
+// Documents are selected using a hash of their key and of the seed, which means
+// that the same seed always selects the same documents.
+type TableSample struct {
+	// Percent of documents to return, between 0 and 100.
+	Percent float64
+	Seed    int64
+}
+
+func (s TableSample) String() string {
+	return fmt.Sprintf("TABLESAMPLE (%v PERCENT) REPEATABLE (%d)", s.Percent, s.Seed)
+}
+
+// sampleIterator filters the documents returned by an iterator
+// according to a table sample.
+type sampleIterator struct {
+	it     document.Iterator
+	sample TableSample
+}
+
+func (it *sampleIterator) Iterate(fn func(d document.Document) error) error {
+	if it.sample.Percent >= 100 {
+		return it.it.Iterate(fn)
+	}
+	if it.sample.Percent <= 0 {
+		return nil
+	}
+
+	threshold := it.sample.Percent / 100 * math.MaxUint64
+
+	var seed [8]byte
+	binary.BigEndian.PutUint64(seed[:], uint64(it.sample.Seed))
+	h := fnv.New64a()
+
+	return it.it.Iterate(func(d document.Document) error {
+		k, ok := d.(document.Keyer)
+		if !ok {
+			return errors.New("cannot sample documents without a key")
+		}
+
+		h.Reset()
+		h.Write(seed[:])
+		h.Write(k.Key())
+
+		if float64(mix64(h.Sum64())) >= threshold {
+			return nil
+		}
+
+		return fn(d)
+	})
+}
+
+// mix64 spreads the bits of x, to make sure keys that only differ
+// by their last bytes don't end up with similar hashes.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
 func (n *tableInputNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
 	n.tx = tx
 	n.params = params
@@ -40,11 +168,172 @@ func (n *tableInputNode) Bind(tx *database.Transaction, params []expr.Param) (er
 }
 
 func (n *tableInputNode) String() string {
-	return fmt.Sprintf("Table(%s)", n.tableName)
+	var s string
+	if len(n.fields) > 0 {
+		fields := make([]string, len(n.fields))
+		for i := range n.fields {
+			fields[i] = n.fields[i].String()
+		}
+
+		s = fmt.Sprintf("Table(%s, fields: %s)", n.tableName, strings.Join(fields, ", "))
+	} else {
+		s = fmt.Sprintf("Table(%s)", n.tableName)
+	}
+
+	if n.hint != nil {
+		s = fmt.Sprintf("%s %s", s, n.hint)
+	}
+
+	if n.sample != nil {
+		s = fmt.Sprintf("%s %s", s, n.sample)
+	}
+
+	return s
 }
 
 func (n *tableInputNode) buildStream() (document.Stream, error) {
-	return document.NewStream(n.table), nil
+	var it document.Iterator = n.table
+	if len(n.fields) > 0 {
+		it = &fieldsIterator{
+			table:  n.table,
+			fields: n.fields,
+		}
+	}
+
+	it = logScan(n.tx, n.tableName, "", it)
+	it = checkContext(n.ctx, it)
+
+	if n.sample != nil {
+		it = &sampleIterator{
+			it:     it,
+			sample: *n.sample,
+		}
+	}
+
+	return document.NewStream(it), nil
+}
+
+// fieldsIterator iterates over the documents of a table,
+// only decoding the given fields.
+type fieldsIterator struct {
+	table  *database.Table
+	fields []document.ValuePath
+}
+
+func (it *fieldsIterator) Iterate(fn func(d document.Document) error) error {
+	return it.table.IterateFields(it.fields, fn)
+}
+
+// logScan returns an iterator that logs the number of documents
+// read from it at the end of each iteration, if the database has a logger.
+func logScan(tx *database.Transaction, tableName, indexName string, it document.Iterator) document.Iterator {
+	if tx.DB().Logger == nil {
+		return it
+	}
+
+	return document.IteratorFunc(func(fn func(d document.Document) error) error {
+		var n int
+		err := it.Iterate(func(d document.Document) error {
+			n++
+			return fn(d)
+		})
+
+		tx.DB().Log(database.Event{
+			Kind:      database.EventRowsScanned,
+			TxID:      tx.ID(),
+			TableName: tableName,
+			IndexName: indexName,
+			Rows:      n,
+		})
+		return err
+	})
+}
+
+type cteInputNode struct {
+	node
+
+	name string
+	tree *Tree
+	// documents returned by the tree,
+	// materialized upon the first read.
+	documents []document.Document
+	// memory budget of the statement, shared with the other nodes.
+	budget *memoryBudget
+}
+
+var _ inputNode = (*cteInputNode)(nil)
+
+// NewCTEInputNode creates an input node that reads the documents returned by
+// the tree of a common table expression.
+// The tree is executed once and its result is kept in memory.
+func NewCTEInputNode(name string, tree *Tree) Node {
+	return &cteInputNode{
+		node: node{
+			op: Input,
+		},
+		name: name,
+		tree: tree,
+	}
+}
+
+func (n *cteInputNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	n.documents = nil
+
+	err = Bind(n.tree, tx, params)
+	if err != nil {
+		return
+	}
+
+	n.tree, err = Optimize(n.tree)
+	return
+}
+
+func (n *cteInputNode) String() string {
+	return fmt.Sprintf("CTE(%s: %s)", n.name, n.tree)
+}
+
+func (n *cteInputNode) buildStream() (document.Stream, error) {
+	if n.documents == nil {
+		err := n.materialize()
+		if err != nil {
+			return document.Stream{}, err
+		}
+	}
+
+	return document.NewStream(document.NewIterator(n.documents...)), nil
+}
+
+func (n *cteInputNode) materialize() error {
+	res, err := n.tree.execute()
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	n.documents = []document.Document{}
+	return res.Iterate(func(d document.Document) error {
+		var fb document.FieldBuffer
+		err := fb.Copy(d)
+		if err != nil {
+			return err
+		}
+
+		// the documents are kept until the end of the statement
+		if n.budget != nil {
+			size, err := documentSize(&fb)
+			if err != nil {
+				return err
+			}
+
+			err = n.budget.reserve(size)
+			if err != nil {
+				return err
+			}
+		}
+
+		n.documents = append(n.documents, &fb)
+		return nil
+	})
 }
 
 type indexInputNode struct {
@@ -60,6 +349,8 @@ type indexInputNode struct {
 	iop              IndexIteratorOperator
 	e                expr.Expr
 	orderByDirection scanner.Token
+	// context of the statement, checked before reading each document.
+	ctx context.Context
 }
 
 var _ inputNode = (*indexInputNode)(nil)
@@ -99,17 +390,30 @@ func (n *indexInputNode) Bind(tx *database.Transaction, params []expr.Param) (er
 }
 
 func (n *indexInputNode) buildStream() (document.Stream, error) {
-	return document.NewStream(&indexIterator{
+	n.tx.DB().Log(database.Event{
+		Kind:      database.EventIndexUsed,
+		TxID:      n.tx.ID(),
+		TableName: n.tableName,
+		IndexName: n.indexName,
+	})
+
+	return document.NewStream(checkContext(n.ctx, logScan(n.tx, n.tableName, n.indexName, &indexIterator{
 		tx:     n.tx,
 		tb:     n.table,
 		params: n.params,
 		index:  n.index,
 		e:      n.e,
 		iop:    n.iop,
-	}), nil
+
+		orderByDirection: n.orderByDirection,
+	}))), nil
 }
 
 func (n *indexInputNode) String() string {
+	if n.orderByDirection == scanner.DESC {
+		return fmt.Sprintf("Index(%s DESC)", n.indexName)
+	}
+
 	return fmt.Sprintf("Index(%s)", n.indexName)
 }
 
@@ -119,6 +423,68 @@ type IndexIteratorOperator interface {
 	IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error
 }
 
+// indexRangeOperator iterates over the documents whose indexed value is between
+// a lower and an upper bound. It expects the value it receives to be an array
+// containing both bounds, in that order.
+type indexRangeOperator struct {
+	lowInclusive, highInclusive bool
+}
+
+func (op indexRangeOperator) IterateIndex(idx *database.Index, tb *database.Table, v document.Value, fn func(d document.Document) error) error {
+	if v.Type != document.ArrayValue {
+		return nil
+	}
+
+	low, err := v.V.(document.Array).GetByIndex(0)
+	if err != nil {
+		return err
+	}
+	high, err := v.V.(document.Array).GetByIndex(1)
+	if err != nil {
+		return err
+	}
+
+	// values of different types are never between each other,
+	// except numbers which are compared regardless of their type.
+	if low.Type == document.NullValue || high.Type == document.NullValue {
+		return nil
+	}
+	if low.Type != high.Type && !(low.Type.IsNumber() && high.Type.IsNumber()) {
+		return nil
+	}
+
+	lowEnc, err := idx.EncodeValue(low)
+	if err != nil {
+		return err
+	}
+	highEnc, err := idx.EncodeValue(high)
+	if err != nil {
+		return err
+	}
+
+	err = idx.SeekRange(low, high, func(val, key []byte) error {
+		if !op.lowInclusive && bytes.Equal(val, lowEnc) {
+			return nil
+		}
+		if !op.highInclusive && bytes.Equal(val, highEnc) {
+			return errStop
+		}
+
+		d, err := tb.GetDocument(key)
+		if err != nil {
+			return err
+		}
+
+		return fn(d)
+	})
+
+	if err != nil && err != errStop {
+		return err
+	}
+
+	return nil
+}
+
 type indexIterator struct {
 	tx               *database.Transaction
 	tb               *database.Table
@@ -168,3 +534,114 @@ func (it indexIterator) Iterate(fn func(d document.Document) error) error {
 
 	return it.iop.IterateIndex(it.index, it.tb, v, fn)
 }
+
+type primaryKeyInputNode struct {
+	node
+
+	tableName string
+
+	tx     *database.Transaction
+	params []expr.Param
+	table  *database.Table
+	e      expr.Expr
+}
+
+var _ inputNode = (*primaryKeyInputNode)(nil)
+
+// NewPrimaryKeyInputNode creates a node that reads the document whose primary key,
+// as returned by the pk() function, is equal to the result of the evaluation of e.
+func NewPrimaryKeyInputNode(tableName string, e expr.Expr) Node {
+	return &primaryKeyInputNode{
+		node: node{
+			op: Input,
+		},
+		tableName: tableName,
+		e:         e,
+	}
+}
+
+func (n *primaryKeyInputNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	if n.table == nil {
+		n.table, err = tx.GetTable(n.tableName)
+		if err != nil {
+			return
+		}
+	}
+
+	n.tx = tx
+	n.params = params
+	return
+}
+
+func (n *primaryKeyInputNode) buildStream() (document.Stream, error) {
+	return document.NewStream(logScan(n.tx, n.tableName, "", &primaryKeyIterator{
+		tx:     n.tx,
+		tb:     n.table,
+		params: n.params,
+		e:      n.e,
+	})), nil
+}
+
+func (n *primaryKeyInputNode) String() string {
+	return fmt.Sprintf("PrimaryKey(%s)", n.tableName)
+}
+
+type primaryKeyIterator struct {
+	tx     *database.Transaction
+	tb     *database.Table
+	params []expr.Param
+	e      expr.Expr
+}
+
+func (it primaryKeyIterator) Iterate(fn func(d document.Document) error) error {
+	v, err := it.e.Eval(expr.EvalStack{
+		Tx:     it.tx,
+		Params: it.params,
+	})
+	if err != nil {
+		return err
+	}
+
+	// if the value cannot be converted to a key,
+	// no document can match
+	k, err := it.tb.EncodeKey(v)
+	if err != nil {
+		return nil
+	}
+
+	d, err := it.tb.GetDocument(k)
+	if err == database.ErrDocumentNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// the value may have been converted to be encoded,
+	// make sure the primary key of the document is equal to it
+	info, err := it.tb.Info()
+	if err != nil {
+		return err
+	}
+
+	pk, err := expr.PKFunc{}.Eval(expr.EvalStack{
+		Document: d,
+		Info:     info,
+	})
+	if err != nil {
+		return err
+	}
+
+	// text keys are compared according to the collation of the primary key,
+	// like they were encoded
+	if fc := info.GetPrimaryKey(); fc != nil {
+		pk, v = fc.Collation.Collate(pk), fc.Collation.Collate(v)
+	}
+
+	ok, err := pk.IsEqual(v)
+	if err != nil || !ok {
+		return err
+	}
+
+	return fn(d)
+}
@@ -0,0 +1,151 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// defaultInsertBatchSize is the number of documents inserted per batch
+// if the InsertBatchSize option of the database is not set.
+const defaultInsertBatchSize = 100
+
+type insertionNode struct {
+	node
+
+	tableName  string
+	fieldNames []string
+	table      *database.Table
+	batchSize  int
+	// memory budget of the statement, shared with the other nodes.
+	budget *memoryBudget
+}
+
+var _ operationNode = (*insertionNode)(nil)
+
+// NewInsertionNode creates a node that inserts every document of a stream in the given table,
+// as requested by INSERT ... SELECT.
+// If fieldNames is not empty, the fields of each document are renamed after it, by position.
+func NewInsertionNode(n Node, tableName string, fieldNames []string) Node {
+	return &insertionNode{
+		node: node{
+			op:   Insertion,
+			left: n,
+		},
+		tableName:  tableName,
+		fieldNames: fieldNames,
+	}
+}
+
+func (n *insertionNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	n.batchSize = tx.DB().InsertBatchSize
+	if n.batchSize <= 0 {
+		n.batchSize = defaultInsertBatchSize
+	}
+
+	n.table, err = tx.GetTable(n.tableName)
+	return
+}
+
+// toStream inserts the documents of the stream by batches of batchSize documents.
+// Some engines can't create more than one iterator per read-write transaction (https://github.com/dgraph-io/badger/issues/1093).
+// To deal with these limitations, the documents of a batch are copied to a buffer and inserted once the iteration
+// is complete, then the stream is read again, skipping the documents already inserted, until there is no document left.
+// Only one batch is kept in memory at a time.
+// If the stream reads the table the documents are inserted into, it would read the inserted documents
+// when run again: the whole stream is copied to the buffer before inserting anything.
+func (n *insertionNode) toStream(st document.Stream) (document.Stream, error) {
+	batchSize := n.batchSize
+	if inputTableName(n.left) == n.tableName {
+		batchSize = 0
+	}
+
+	var docs []document.FieldBuffer
+	var offset int
+	for {
+		batch := st.Offset(offset)
+		if batchSize > 0 {
+			batch = batch.Limit(batchSize)
+		}
+
+		var i int
+		var reserved int64
+		err := batch.Iterate(func(d document.Document) error {
+			if i == len(docs) {
+				docs = append(docs, document.FieldBuffer{})
+			}
+
+			fb := &docs[i]
+			fb.Reset()
+			err := n.copyDocument(fb, d)
+			if err != nil {
+				return err
+			}
+
+			size, err := documentSize(fb)
+			if err != nil {
+				return err
+			}
+			err = n.budget.reserve(size)
+			if err != nil {
+				return err
+			}
+			reserved += size
+
+			i++
+			return nil
+		})
+		if err == nil {
+			batchDocs := make([]document.Document, i)
+			for j := range batchDocs {
+				batchDocs[j] = &docs[j]
+			}
+			_, err = n.table.InsertBatch(batchDocs)
+		}
+		n.budget.release(reserved)
+		if err != nil {
+			return document.Stream{}, err
+		}
+
+		offset += i
+		if batchSize == 0 || i < batchSize {
+			break
+		}
+	}
+
+	return document.Stream{}, nil
+}
+
+// copyDocument copies d to fb, renaming its fields after the field names of the node, if any.
+func (n *insertionNode) copyDocument(fb *document.FieldBuffer, d document.Document) error {
+	if len(n.fieldNames) == 0 {
+		return fb.Copy(d)
+	}
+
+	var cp document.FieldBuffer
+	err := cp.Copy(d)
+	if err != nil {
+		return err
+	}
+
+	if cp.Len() != len(n.fieldNames) {
+		return fmt.Errorf("%d values for %d fields", cp.Len(), len(n.fieldNames))
+	}
+
+	var i int
+	return cp.Iterate(func(_ string, v document.Value) error {
+		fb.Add(n.fieldNames[i], v)
+		i++
+		return nil
+	})
+}
+
+func (n *insertionNode) String() string {
+	if len(n.fieldNames) == 0 {
+		return fmt.Sprintf("Insert(%s)", n.tableName)
+	}
+
+	return fmt.Sprintf("Insert(%s, %v)", n.tableName, n.fieldNames)
+}
@@ -0,0 +1,97 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+type lockNode struct {
+	node
+
+	tableName string
+	tx        *database.Transaction
+	table     *database.Table
+}
+
+var _ operationNode = (*lockNode)(nil)
+
+// NewLockNode creates a node that acquires write intent on every document of a stream,
+// as requested by SELECT ... FOR UPDATE.
+// The stream is returned unchanged. The node is placed after the LIMIT and OFFSET
+// clauses, so that only the returned documents are locked.
+func NewLockNode(n Node, tableName string) Node {
+	return &lockNode{
+		node: node{
+			op:   Lock,
+			left: n,
+		},
+		tableName: tableName,
+	}
+}
+
+// Bind asks the sort node of the stream, if any, to keep the keys
+// of the documents it copies, which are needed to lock them.
+func (n *lockNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	n.tx = tx
+
+	for nn := n.left; nn != nil; nn = nn.Left() {
+		if sn, ok := nn.(*sortNode); ok {
+			sn.keepKeys = true
+			break
+		}
+	}
+
+	n.table, err = tx.GetTable(n.tableName)
+	return
+}
+
+// toStream locks the documents when the statement is run rather than when they are read,
+// so that they are locked even if the result is never iterated.
+// Some engines don't support writing while iterating, so the keys of the stream
+// are collected first and the documents locked once the iteration is complete.
+// The stream is then read again by the caller.
+// It returns engine.ErrTransactionReadOnly if the transaction is read-only,
+// since locking documents requires writing them. This is only checked when
+// the statement is run, which allows it to be explained in any transaction.
+func (n *lockNode) toStream(st document.Stream) (document.Stream, error) {
+	if !n.tx.Writable() {
+		return document.Stream{}, fmt.Errorf("SELECT ... FOR UPDATE requires a read-write transaction: %w", engine.ErrTransactionReadOnly)
+	}
+
+	var keys [][]byte
+
+	err := st.Iterate(func(d document.Document) error {
+		// projected documents keep the document they were read from
+		if dm, ok := d.(*documentMask); ok {
+			d = dm.d
+		}
+		k, ok := d.(document.Keyer)
+		if !ok || k.Key() == nil {
+			return errors.New("attempt to lock document without key")
+		}
+
+		keys = append(keys, append([]byte(nil), k.Key()...))
+		return nil
+	})
+	if err != nil {
+		return document.Stream{}, err
+	}
+
+	for _, key := range keys {
+		err = n.table.Lock(key)
+		if err != nil {
+			return document.Stream{}, err
+		}
+	}
+
+	return st, nil
+}
+
+func (n *lockNode) String() string {
+	return fmt.Sprintf("Lock(%s)", n.tableName)
+}
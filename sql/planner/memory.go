@@ -0,0 +1,137 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// memoryBudget limits the amount of memory used by the nodes of a statement
+// that keep documents in memory, like sort nodes, or values, like grouping nodes
+// and DISTINCT aggregate functions.
+// The budget is shared by all the nodes of the statement.
+// A nil budget doesn't limit anything.
+type memoryBudget struct {
+	limit int64
+	used  int64
+}
+
+// newMemoryBudget returns a budget of limit bytes, or nil if limit is not positive.
+func newMemoryBudget(limit int64) *memoryBudget {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &memoryBudget{limit: limit}
+}
+
+// reserve n bytes of the budget. It returns database.ErrResultTooLarge
+// if the budget is exceeded.
+func (b *memoryBudget) reserve(n int64) error {
+	if b == nil {
+		return nil
+	}
+
+	if b.used+n > b.limit {
+		return fmt.Errorf("%w: statement needs more than %d bytes of memory", database.ErrResultTooLarge, b.limit)
+	}
+
+	b.used += n
+	return nil
+}
+
+// release n bytes previously reserved.
+func (b *memoryBudget) release(n int64) {
+	if b == nil {
+		return
+	}
+
+	b.used -= n
+}
+
+// setMemoryBudget shares b with every node of the tree that keeps documents in memory,
+// including the nodes of common table expressions.
+func setMemoryBudget(t *Tree, b *memoryBudget) {
+	for n := t.Root; n != nil; n = n.Left() {
+		switch nn := n.(type) {
+		case *sortNode:
+			nn.budget = b
+		case *insertionNode:
+			nn.budget = b
+		case *GroupingNode:
+			nn.budget = b
+		case *ProjectionNode:
+			for _, e := range nn.Expressions {
+				if pe, ok := e.(ProjectedExpr); ok {
+					setAggregateBudget(pe.Expr, b)
+				}
+			}
+		case *cteInputNode:
+			nn.budget = b
+			setMemoryBudget(nn.tree, b)
+		case *setOperationNode:
+			nn.budget = b
+			setMemoryBudget(nn.leftTree, b)
+			setMemoryBudget(nn.rightTree, b)
+		}
+	}
+}
+
+// setAggregateBudget reserves the memory used by the combinations of values
+// kept by the DISTINCT aggregate function e, if any, from b.
+// The budget is replaced every time the statement is run.
+func setAggregateBudget(e expr.Expr, b *memoryBudget) {
+	var reserve func(size int64) error
+	if b != nil {
+		reserve = b.reserve
+	}
+
+	switch t := e.(type) {
+	case *expr.CountDistinctFunc:
+		t.Reserve = reserve
+	case *expr.UserAggregateFunc:
+		if t.Distinct {
+			t.Reserve = reserve
+		}
+	case *expr.FilteredAggregateFunc:
+		setAggregateBudget(t.Agg, b)
+	}
+}
+
+// documentSize returns an estimation of the number of bytes used by d
+// once copied in memory.
+func documentSize(d document.Document) (int64, error) {
+	var size int64
+
+	err := d.Iterate(func(f string, v document.Value) error {
+		vs, err := valueSize(v)
+		size += int64(len(f)) + vs
+		return err
+	})
+
+	return size, err
+}
+
+func valueSize(v document.Value) (int64, error) {
+	switch v.Type {
+	case document.TextValue:
+		return int64(len(v.V.(string))), nil
+	case document.BlobValue:
+		return int64(len(v.V.([]byte))), nil
+	case document.DocumentValue:
+		return documentSize(v.V.(document.Document))
+	case document.ArrayValue:
+		var size int64
+		err := v.V.(document.Array).Iterate(func(i int, value document.Value) error {
+			vs, err := valueSize(value)
+			size += vs
+			return err
+		})
+		return size, err
+	}
+
+	// null, booleans, numbers and points
+	return 16, nil
+}
@@ -19,11 +19,13 @@ func _() {
 	_ = x[Sort-8]
 	_ = x[Set-9]
 	_ = x[Unset-10]
+	_ = x[Lock-11]
+	_ = x[Insertion-12]
 }
 
-const _Operation_name = "InputSelectionProjectionRenameDeletionReplacementLimitSkipSortSetUnset"
+const _Operation_name = "InputSelectionProjectionRenameDeletionReplacementLimitSkipSortSetUnsetLockInsertion"
 
-var _Operation_index = [...]uint8{0, 5, 14, 24, 30, 38, 49, 54, 58, 62, 65, 70}
+var _Operation_index = [...]uint8{0, 5, 14, 24, 30, 38, 49, 54, 58, 62, 65, 70, 74, 83}
 
 func (i Operation) String() string {
 	if i < 0 || i >= Operation(len(_Operation_index)-1) {
@@ -1,6 +1,8 @@
 package planner
 
 import (
+	"fmt"
+
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/query/expr"
@@ -11,7 +13,12 @@ var optimizerRules = []func(t *Tree) (*Tree, error){
 	SplitANDConditionRule,
 	PrecalculateExprRule,
 	RemoveUnnecessarySelectionNodesRule,
+	UsePrimaryKeyBasedOnSelectionNodeRule,
 	UseIndexBasedOnSelectionNodeRule,
+	UseIndexBasedOnSortNodeRule,
+	CheckIndexHintRule,
+	UseTopNSortRule,
+	DecodeProjectedFieldsRule,
 }
 
 // Optimize takes a tree, applies a list of optimization rules
@@ -35,13 +42,16 @@ func Optimize(t *Tree) (*Tree, error) {
 // is one or more AND operators into one or more selection nodes.
 // The condition won't be split if the expression tree contains an OR
 // operation.
+// The conditions are still evaluated from left to right, the first one being
+// the closest to the input, so that a condition isn't evaluated for documents
+// filtered by the previous ones, like the AND operator does.
 // Example:
 //   this:
 //     σ(a > 2 AND b != 3 AND c < 2)
 //   becomes this:
-//     σ(a > 2)
-//     σ(b != 3)
 //     σ(c < 2)
+//     σ(b != 3)
+//     σ(a > 2)
 func SplitANDConditionRule(t *Tree) (*Tree, error) {
 	n := t.Root
 	var prev Node
@@ -59,17 +69,14 @@ func SplitANDConditionRule(t *Tree) (*Tree, error) {
 					exprs := splitANDExpr(cond)
 
 					cur := n.Left()
-					i := len(exprs) - 1
 					var newNode Node
-					for i >= 0 {
-						newNode = NewSelectionNode(cur, exprs[i])
+					for _, e := range exprs {
+						newNode = NewSelectionNode(cur, e)
 						err := newNode.Bind(sn.tx, sn.params)
 						if err != nil {
 							return nil, err
 						}
 						cur = newNode
-
-						i--
 					}
 
 					if prev != nil {
@@ -176,6 +183,17 @@ func precalculateExpr(e expr.Expr) expr.Expr {
 
 			return expr.LiteralValue(document.NewDocumentValue(&fb))
 		}
+	case expr.NotOp:
+		t.E = precalculateExpr(t.E)
+		if _, ok := t.E.(expr.LiteralValue); ok {
+			v, err := t.Eval(expr.EvalStack{})
+			// any error encountered here is unexpected
+			if err != nil {
+				panic(err)
+			}
+			return expr.LiteralValue(v)
+		}
+		return t
 	case expr.Operator:
 		// since expr.Operator is an interface,
 		// this optimization must only be applied to
@@ -248,12 +266,162 @@ func RemoveUnnecessarySelectionNodesRule(t *Tree) (*Tree, error) {
 	return t, nil
 }
 
+// maxTopNSortLimit is the greatest number of documents a sort node
+// can keep in memory when UseTopNSortRule is applied.
+const maxTopNSortLimit = 10000
+
+// UseTopNSortRule looks for a sort node followed by a limit node, with an optional
+// offset node in between. If found, and if the sum of the limit and the offset is small enough,
+// the sort node is configured to only keep that many documents in memory while reading
+// the stream, instead of sorting the entire stream.
+// Example:
+//   this:
+//     Sort(a DESC)
+//     Limit(10)
+//   becomes this:
+//     Sort(a DESC, top 10)
+//     Limit(10)
+func UseTopNSortRule(t *Tree) (*Tree, error) {
+	n := t.Root
+
+	// the documents are locked once they are limited
+	if lk, ok := n.(*lockNode); ok {
+		n = lk.Left()
+	}
+
+	ln, ok := n.(*limitNode)
+	if !ok {
+		return t, nil
+	}
+	k := ln.limit
+	n = n.Left()
+
+	if on, ok := n.(*offsetNode); ok {
+		k += on.offset
+		n = n.Left()
+	}
+
+	sn, ok := n.(*sortNode)
+	if !ok || k <= 0 || k > maxTopNSortLimit {
+		return t, nil
+	}
+
+	sn.limit = k
+	return t, nil
+}
+
+// UsePrimaryKeyBasedOnSelectionNodeRule scans the tree for the first selection node whose condition is
+// an equality between the pk() function and a literal value or a parameter.
+// If found, it will replace the table input node by a primaryKeyInputNode, which fetches the document
+// directly by key instead of reading the entire table.
+// The selection node is then removed from the tree.
+// Example:
+//   this:
+//     σ(pk() = 10)
+//     Table(foo)
+//   becomes this:
+//     PrimaryKey(foo)
+// The rule is not applied if the table input node has a hint forcing the use of an index,
+// or if the table is sampled.
+func UsePrimaryKeyBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
+	n := t.Root
+	var prev, selPrev, sel Node
+	var pkExpr expr.Expr
+
+	for n != nil {
+		if n.Operation() == Input {
+			break
+		}
+
+		if pkExpr == nil && n.Operation() == Selection {
+			pkExpr = pkEqualityOperand(n.(*selectionNode).cond)
+			if pkExpr != nil {
+				sel, selPrev = n, prev
+			}
+		}
+
+		prev = n
+		n = n.Left()
+	}
+
+	if n == nil || pkExpr == nil {
+		return t, nil
+	}
+
+	inpn, ok := n.(*tableInputNode)
+	if !ok || (inpn.hint != nil && inpn.hint.Force) || inpn.sample != nil {
+		return t, nil
+	}
+
+	in := NewPrimaryKeyInputNode(inpn.tableName, pkExpr)
+	if err := in.Bind(inpn.tx, inpn.params); err != nil {
+		return nil, err
+	}
+
+	// we replace the table input node by the primaryKeyInputNode
+	if prev == nil {
+		t.Root = in
+	} else {
+		prev.SetLeft(in)
+	}
+
+	// we remove the selection node from the tree
+	if selPrev == nil {
+		t.Root = sel.Left()
+	} else {
+		selPrev.SetLeft(sel.Left())
+	}
+
+	return t, nil
+}
+
+// pkEqualityOperand returns the operand compared to the pk() function
+// if cond is of the form pk() = expr or expr = pk(), and if that operand
+// is a literal or a param.
+func pkEqualityOperand(cond expr.Expr) expr.Expr {
+	op, ok := cond.(expr.Operator)
+	if !ok || op.Token() != scanner.EQ {
+		return nil
+	}
+
+	var e expr.Expr
+	switch {
+	case isPKFunc(op.LeftHand()):
+		e = op.RightHand()
+	case isPKFunc(op.RightHand()):
+		e = op.LeftHand()
+	default:
+		return nil
+	}
+
+	if !isLiteralOrParam(e) {
+		return nil
+	}
+
+	return e
+}
+
+func isPKFunc(e expr.Expr) bool {
+	switch e.(type) {
+	case expr.PKFunc, *expr.PKFunc:
+		return true
+	}
+
+	return false
+}
+
 // UseIndexBasedOnSelectionNodeRule scans the tree for the first selection node whose condition is an
 // operator that satisfies the following criterias:
 // - implements the indexIteratorOperator interface
 // - one of its operands is path selector that is indexed
 // - the other operand is a literal value or a parameter
 // If found, it will replace the input node by an indexInputNode using this index.
+// A lower and an upper bound on the same indexed path, like the ones created by BETWEEN,
+// are satisfied together by reading the range of the index between them.
+// If several selection nodes can use an index, the statistics of the indexes are used
+// to select the one that is expected to return the fewest documents.
+// If the table input node has an index hint, only the indexes allowed by the hint are considered.
+// Sampled tables are always read entirely.
 func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 	n := t.Root
 	var prev Node
@@ -273,59 +441,120 @@ func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 		return t, nil
 	}
 
-	// then we get the table indexes. here we will assume that at this point
-	// inputNodes can only be instances of tableInputNode.
-	inpn := inputNode.(*tableInputNode)
+	// then we get the table indexes. if the input node was already
+	// replaced by another rule, there is nothing to do.
+	inpn, ok := inputNode.(*tableInputNode)
+	if !ok || inpn.sample != nil {
+		return t, nil
+	}
 	indexes, err := inpn.table.Indexes()
 	if err != nil {
 		return nil, err
 	}
 
+	if inpn.hint != nil {
+		indexes, err = filterIndexesWithHint(indexes, *inpn.hint)
+		if err != nil {
+			return nil, err
+		}
+	}
+	byPath := indexesByPath(indexes)
+
 	type candidate struct {
-		prevNode, nextNode Node
-		in                 *indexInputNode
+		// selection nodes replaced by the index input node
+		nodes []Node
+		in    *indexInputNode
 	}
 
 	var candidates []candidate
+	var lows, highs []rangeBound
 
 	n = t.Root
 	// look for all selection nodes that satisfy our requirements
 	for n != nil {
 		if n.Operation() == Selection {
 			sn := n.(*selectionNode)
-			indexedNode := selectionNodeValidForIndex(sn, inpn.tableName, indexes)
+			indexedNode := selectionNodeValidForIndex(sn, inpn.tableName, byPath)
 			if indexedNode != nil {
 				candidates = append(candidates, candidate{
-					prevNode: prev,
-					nextNode: n.Left(),
-					in:       indexedNode,
+					nodes: []Node{n},
+					in:    indexedNode,
 				})
+
+				if b, ok := selectionNodeRangeBound(sn); ok {
+					if b.low {
+						lows = append(lows, b)
+					} else {
+						highs = append(highs, b)
+					}
+				}
 			}
 		}
 
-		prev = n
 		n = n.Left()
 	}
 
+	// a lower and an upper bound on the same path
+	// can be satisfied by reading a range of the index.
+	// ranges are considered first, to be preferred over
+	// a single bound when the estimations are equal.
+	var ranges []candidate
+	for _, low := range lows {
+		for _, high := range highs {
+			if !low.path.IsEqual(high.path) {
+				continue
+			}
+
+			idx := byPath[low.path.String()]
+			in := NewIndexInputNode(
+				inpn.tableName,
+				idx.Opts.IndexName,
+				indexRangeOperator{lowInclusive: low.inclusive, highInclusive: high.inclusive},
+				expr.LiteralExprList{low.e, high.e},
+				scanner.ASC,
+			).(*indexInputNode)
+			in.index = idx
+
+			ranges = append(ranges, candidate{
+				nodes: []Node{low.node, high.node},
+				in:    in,
+			})
+			break
+		}
+	}
+	candidates = append(ranges, candidates...)
+
 	// determine which index is the most interesting and replace it in the tree.
-	// we will assume that unique indexes are more interesting than list indexes
+	// the statistics of the indexes are used to select the one that is expected
+	// to return the fewest documents. if the estimations are equal, we will assume
+	// that unique indexes are more interesting than list indexes
 	// because they usually have less elements.
 	var selectedCandidate *candidate
+	var selectedRows float64
 
 	for i, candidate := range candidates {
+		rows, err := estimateIndexRows(candidate.in)
+		if err != nil {
+			return nil, err
+		}
+
 		if selectedCandidate == nil {
 			selectedCandidate = &candidates[i]
+			selectedRows = rows
 			continue
 		}
 
-		// if the candidate's related index is a unique index,
-		// select it.
+		// if the candidate's related index is more selective
+		// or is a unique index, select it.
 		idx := candidate.in.index
-		if idx.Unique {
+		if rows < selectedRows || (rows == selectedRows && idx.Unique) {
 			selectedCandidate = &candidates[i]
+			selectedRows = rows
 		}
 	}
 
+	// if the hint forces the use of an index, the sort node may still use one:
+	// this is checked by CheckIndexHintRule
 	if selectedCandidate == nil {
 		return t, nil
 	}
@@ -335,11 +564,9 @@ func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 		return nil, err
 	}
 
-	// we remove the selection node from the tree
-	if selectedCandidate.prevNode == nil {
-		t.Root = selectedCandidate.nextNode
-	} else {
-		selectedCandidate.prevNode.SetLeft(selectedCandidate.nextNode)
+	// we remove the selection nodes from the tree
+	for _, sn := range selectedCandidate.nodes {
+		removeNode(t, sn)
 	}
 
 	n = t.Root
@@ -364,7 +591,327 @@ func UseIndexBasedOnSelectionNodeRule(t *Tree) (*Tree, error) {
 	return t, nil
 }
 
-func selectionNodeValidForIndex(sn *selectionNode, tableName string, indexes map[string]database.Index) *indexInputNode {
+// rangeBound is a selection node whose condition compares a path
+// with a lower or an upper bound.
+type rangeBound struct {
+	node      *selectionNode
+	path      document.ValuePath
+	e         expr.Expr
+	low       bool
+	inclusive bool
+}
+
+// selectionNodeRangeBound returns the bound set by the condition of the selection node
+// if it is a >, >=, < or <= operator. It must only be called on selection nodes that
+// are valid for an index.
+func selectionNodeRangeBound(sn *selectionNode) (rangeBound, bool) {
+	op := sn.cond.(expr.Operator)
+	b := rangeBound{node: sn}
+
+	switch op.Token() {
+	case scanner.GT:
+		b.low = true
+	case scanner.GTE:
+		b.low, b.inclusive = true, true
+	case scanner.LT:
+	case scanner.LTE:
+		b.inclusive = true
+	default:
+		return b, false
+	}
+
+	// expr OP path: the bound is on the other side
+	fs, ok := op.LeftHand().(expr.FieldSelector)
+	if ok {
+		b.e = op.RightHand()
+	} else {
+		fs = op.RightHand().(expr.FieldSelector)
+		b.e = op.LeftHand()
+		b.low = !b.low
+	}
+	b.path = document.ValuePath(fs)
+
+	return b, true
+}
+
+// removeNode removes the target node from the tree.
+func removeNode(t *Tree, target Node) {
+	var prev Node
+
+	for n := t.Root; n != nil; n = n.Left() {
+		if n == target {
+			if prev == nil {
+				t.Root = n.Left()
+			} else {
+				prev.SetLeft(n.Left())
+			}
+			return
+		}
+
+		prev = n
+	}
+}
+
+// UseIndexBasedOnSortNodeRule looks for a sort node whose path is indexed and reading a table
+// through selection and projection nodes only. If found, the table input node is replaced by
+// an indexInputNode that iterates over the entire index in the direction of the sort,
+// and the sort node is removed from the tree.
+// Example:
+//   this:
+//     Sort(a DESC)
+//     ∏(a, b)
+//     σ(b > 10)
+//     Table(foo)
+//   becomes this:
+//     ∏(a, b)
+//     σ(b > 10)
+//     Index(idx_foo_a DESC)
+// If the input was already replaced by an index input node reading the sorted path in ascending order,
+// for example to satisfy a range, only the sort node is removed.
+// The rule is not applied if the index doesn't order values like the sort node would,
+// i.e. if their collations differ, if nulls are not placed where the index puts them
+// or if the projection replaces the sorted field.
+// Only the indexes allowed by the hint of the table are considered. Since an index
+// used to sort the stream satisfies a hint forcing the use of an index,
+// the rule runs before CheckIndexHintRule.
+func UseIndexBasedOnSortNodeRule(t *Tree) (*Tree, error) {
+	var prev Node
+	var sn *sortNode
+
+	n := t.Root
+	for n != nil {
+		if s, ok := n.(*sortNode); ok {
+			sn = s
+			break
+		}
+
+		prev = n
+		n = n.Left()
+	}
+
+	// documents with the same value are not sorted by key in indexes,
+	// which paginated statements rely on
+	if sn == nil || sn.cursor != nil || sn.nullsHigh() {
+		return t, nil
+	}
+
+	// only paths can be read from an index
+	fs, ok := sn.sortField.(expr.FieldSelector)
+	if !ok {
+		return t, nil
+	}
+	path := document.ValuePath(fs)
+
+	// only selection and projection nodes preserve the order of the stream
+	var inputPrev Node = sn
+	n = sn.Left()
+	for n != nil && n.Operation() != Input {
+		switch nn := n.(type) {
+		case *selectionNode:
+		case *ProjectionNode:
+			if projectionReplacesPath(nn, path) {
+				return t, nil
+			}
+		default:
+			return t, nil
+		}
+
+		inputPrev = n
+		n = n.Left()
+	}
+
+	// if the input already reads an index of the sorted path in ascending order,
+	// the stream is already sorted
+	if in, ok := n.(*indexInputNode); ok {
+		if sortedByIndexInput(in, sn) {
+			removeNode(t, sn)
+		}
+
+		return t, nil
+	}
+
+	inpn, ok := n.(*tableInputNode)
+	if !ok || inpn.sample != nil || inpn.tx.DB().CaseInsensitiveFields {
+		return t, nil
+	}
+
+	indexes, err := inpn.table.Indexes()
+	if err != nil {
+		return nil, err
+	}
+
+	if inpn.hint != nil {
+		indexes, err = filterIndexesWithHint(indexes, *inpn.hint)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	idx, ok := indexesByPath(indexes)[path.String()]
+	if !ok || idx.Opts.Collation != sn.collation {
+		return t, nil
+	}
+
+	in := NewIndexInputNode(inpn.tableName, idx.Opts.IndexName, nil, nil, sn.direction).(*indexInputNode)
+	in.index = idx
+	if err := in.Bind(inpn.tx, inpn.params); err != nil {
+		return nil, err
+	}
+
+	inputPrev.SetLeft(in)
+
+	// we remove the sort node from the tree
+	if prev == nil {
+		t.Root = sn.Left()
+	} else {
+		prev.SetLeft(sn.Left())
+	}
+
+	return t, nil
+}
+
+// CheckIndexHintRule returns an error if the table input node has a hint forcing the use
+// of an index, and if it wasn't replaced by an index input node, either to satisfy
+// a selection node or to sort the stream.
+// It must run after the rules that can use an index.
+func CheckIndexHintRule(t *Tree) (*Tree, error) {
+	n := t.Root
+	for n != nil && n.Operation() != Input {
+		n = n.Left()
+	}
+
+	// sampled tables are always read entirely
+	inpn, ok := n.(*tableInputNode)
+	if ok && inpn.sample == nil && inpn.hint != nil && inpn.hint.Force {
+		return nil, fmt.Errorf("cannot satisfy the query using %s", inpn.hint)
+	}
+
+	return t, nil
+}
+
+// sortedByIndexInput returns whether the index input node returns documents in the order of the sort node.
+// Except for IN, whose documents are returned in the order of the list, operators
+// read the index in ascending order.
+func sortedByIndexInput(in *indexInputNode, sn *sortNode) bool {
+	if sn.direction != scanner.ASC || sn.nullsHigh() || in.tx.DB().CaseInsensitiveFields {
+		return false
+	}
+
+	if e, ok := in.iop.(expr.Expr); ok && expr.IsInOperator(e) {
+		return false
+	}
+
+	fs, ok := sn.sortField.(expr.FieldSelector)
+	if !ok {
+		return false
+	}
+
+	idx := in.index
+	return idx.Opts.Path.IsEqual(document.ValuePath(fs)) && idx.Opts.Collation == sn.collation
+}
+
+// projectionReplacesPath returns whether the projection creates a field that would be read
+// instead of the given path of the original document when sorting the stream.
+func projectionReplacesPath(n *ProjectionNode, path document.ValuePath) bool {
+	for _, e := range n.Expressions {
+		pe, ok := e.(ProjectedExpr)
+		if !ok {
+			continue
+		}
+
+		if pe.Name() != path[0].FieldName && pe.Name() != path.String() {
+			continue
+		}
+
+		fs, ok := pe.Expr.(expr.FieldSelector)
+		if !ok || !document.ValuePath(fs).IsEqual(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// estimateIndexRows returns the approximate number of documents returned by the index input node,
+// based on the statistics of its index. Equality returns the average number of documents per value,
+// IN returns that average for every value of the list, ranges are assumed to return a ninth
+// of the index and the other operators are assumed to return a third of the index.
+func estimateIndexRows(in *indexInputNode) (float64, error) {
+	stats, err := in.index.Stats()
+	if err != nil {
+		return 0, err
+	}
+
+	if stats.Entries == 0 || stats.DistinctValues == 0 {
+		return 0, nil
+	}
+
+	perValue := float64(stats.Entries) / float64(stats.DistinctValues)
+
+	if _, ok := in.iop.(indexRangeOperator); ok {
+		return float64(stats.Entries) / 9, nil
+	}
+
+	op, ok := in.iop.(expr.Operator)
+	if !ok {
+		return float64(stats.Entries), nil
+	}
+
+	switch op.Token() {
+	case scanner.EQ:
+		return perValue, nil
+	case scanner.IN:
+		n := 1
+		if lv, ok := in.e.(expr.LiteralValue); ok && lv.Type == document.ArrayValue {
+			n, err = document.ArrayLength(lv.V.(document.Array))
+			if err != nil {
+				return 0, err
+			}
+		}
+		return perValue * float64(n), nil
+	}
+
+	return float64(stats.Entries) / 3, nil
+}
+
+// filterIndexesWithHint returns the indexes allowed by the hint.
+// It returns an error if the hint references an index that doesn't exist.
+func filterIndexesWithHint(indexes []*database.Index, hint IndexHint) ([]*database.Index, error) {
+	for _, name := range hint.Indexes {
+		var found bool
+		for _, idx := range indexes {
+			if idx.Opts.IndexName == name {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("%w: %q", database.ErrIndexNotFound, name)
+		}
+	}
+
+	filtered := make([]*database.Index, 0, len(indexes))
+	for _, idx := range indexes {
+		if hint.allows(idx.Opts.IndexName) {
+			filtered = append(filtered, idx)
+		}
+	}
+
+	return filtered, nil
+}
+
+// indexesByPath returns the indexes keyed by the path they index.
+func indexesByPath(indexes []*database.Index) map[string]*database.Index {
+	m := make(map[string]*database.Index, len(indexes))
+	for _, idx := range indexes {
+		m[idx.Opts.Path.String()] = idx
+	}
+
+	return m
+}
+
+func selectionNodeValidForIndex(sn *selectionNode, tableName string, indexes map[string]*database.Index) *indexInputNode {
 	if sn.cond == nil {
 		return nil
 	}
@@ -398,7 +945,7 @@ func selectionNodeValidForIndex(sn *selectionNode, tableName string, indexes map
 	}
 
 	in := NewIndexInputNode(tableName, idx.Opts.IndexName, iop, e, scanner.ASC).(*indexInputNode)
-	in.index = &idx
+	in.index = idx
 
 	return in
 }
@@ -413,7 +960,8 @@ func opCanUseIndex(op expr.Operator) (bool, expr.FieldSelector, expr.Expr) {
 	}
 
 	// expr OP path
-	if rightIsField && !leftIsField {
+	// the right operand of IN is an array of values, which can't be read from an index
+	if rightIsField && !leftIsField && !expr.IsInOperator(op) {
 		return true, rf, op.LeftHand()
 	}
 
@@ -428,3 +976,133 @@ func isLiteralOrParam(e expr.Expr) (ok bool) {
 
 	return false
 }
+
+// DecodeProjectedFieldsRule looks for a projection of the documents of a table whose
+// result only depends on a known list of fields, read by the projection itself and
+// by the nodes surrounding it. If found, the table input node only decodes these fields
+// instead of entire documents.
+// Example:
+//   this:
+//     Sort(c)
+//     ∏(a, b + 1)
+//     σ(b > 10)
+//     Table(foo)
+//   becomes this:
+//     Sort(c)
+//     ∏(a, b + 1)
+//     σ(b > 10)
+//     Table(foo, fields: c, a, b)
+// The rule is not applied if a node might read any field of the documents, for example
+// if the projection contains a wildcard or a function call.
+func DecodeProjectedFieldsRule(t *Tree) (*Tree, error) {
+	var paths []document.ValuePath
+	var hasProjection bool
+	var ok bool
+
+	for n := t.Root; n != nil; n = n.Left() {
+		switch nn := n.(type) {
+		case *limitNode, *offsetNode, *lockNode:
+			ok = true
+		case *sortNode:
+			paths, ok = appendExprPaths(paths, nn.sortField)
+		case *ProjectionNode:
+			hasProjection = true
+			for _, e := range nn.Expressions {
+				pe, isExpr := e.(ProjectedExpr)
+				if !isExpr {
+					return t, nil
+				}
+
+				paths, ok = appendExprPaths(paths, pe.Expr)
+				if !ok {
+					return t, nil
+				}
+			}
+		case *selectionNode:
+			paths, ok = appendExprPaths(paths, nn.cond)
+		case *tableInputNode:
+			if hasProjection {
+				nn.fields = topLevelFields(paths)
+			}
+			return t, nil
+		default:
+			ok = false
+		}
+
+		if !ok {
+			return t, nil
+		}
+	}
+
+	return t, nil
+}
+
+// appendExprPaths appends the paths read by e to paths.
+// It returns false if e might read paths that can't be determined,
+// for example if e is a function call.
+func appendExprPaths(paths []document.ValuePath, e expr.Expr) ([]document.ValuePath, bool) {
+	switch t := e.(type) {
+	case expr.FieldSelector:
+		return append(paths, document.ValuePath(t)), true
+	case expr.LiteralValue, expr.NamedParam, expr.PositionalParam, expr.PKFunc:
+		return paths, true
+	case expr.Parentheses:
+		return appendExprPaths(paths, t.E)
+	case expr.NotOp:
+		return appendExprPaths(paths, t.E)
+	case expr.CaseExpr:
+		l := make(expr.LiteralExprList, 0, len(t.Whens)*2+2)
+		if t.Operand != nil {
+			l = append(l, t.Operand)
+		}
+		for _, w := range t.Whens {
+			l = append(l, w.Cond, w.Then)
+		}
+		if t.Else != nil {
+			l = append(l, t.Else)
+		}
+		return appendExprPaths(paths, l)
+	case expr.LiteralExprList:
+		var ok bool
+		for _, e := range t {
+			paths, ok = appendExprPaths(paths, e)
+			if !ok {
+				return paths, false
+			}
+		}
+		return paths, true
+	case expr.Operator:
+		paths, ok := appendExprPaths(paths, t.LeftHand())
+		if !ok {
+			return paths, false
+		}
+		return appendExprPaths(paths, t.RightHand())
+	}
+
+	return paths, false
+}
+
+// topLevelFields returns the list of distinct top-level fields targeted by the paths.
+func topLevelFields(paths []document.ValuePath) []document.ValuePath {
+	var fields []document.ValuePath
+
+	for _, p := range paths {
+		if len(p) == 0 || p[0].FieldName == "" {
+			continue
+		}
+
+		var found bool
+		for _, f := range fields {
+			if f[0].FieldName == p[0].FieldName {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			fields = append(fields, document.ValuePath{p[0]})
+		}
+	}
+
+	return fields
+}
@@ -2,9 +2,11 @@ package planner_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/planner"
 	"github.com/genjidb/genji/sql/query/expr"
@@ -33,8 +35,8 @@ func TestSplitANDConditionRule(t *testing.T) {
 			planner.NewSelectionNode(
 				planner.NewSelectionNode(
 					planner.NewTableInputNode("foo"),
-					expr.BoolValue(false)),
-				expr.BoolValue(true)),
+					expr.BoolValue(true)),
+				expr.BoolValue(false)),
 		},
 		{
 			"and / middle-level selection node",
@@ -49,8 +51,8 @@ func TestSplitANDConditionRule(t *testing.T) {
 				planner.NewSelectionNode(
 					planner.NewSelectionNode(
 						planner.NewTableInputNode("foo"),
-						expr.BoolValue(false)),
-					expr.BoolValue(true),
+						expr.BoolValue(true)),
+					expr.BoolValue(false),
 				), 1),
 		},
 		{
@@ -74,10 +76,10 @@ func TestSplitANDConditionRule(t *testing.T) {
 						planner.NewSelectionNode(
 							planner.NewSelectionNode(
 								planner.NewTableInputNode("foo"),
-								expr.IntegerValue(4)),
-							expr.IntegerValue(3)),
-						expr.IntegerValue(2)),
-					expr.IntegerValue(1)),
+								expr.IntegerValue(1)),
+							expr.IntegerValue(2)),
+						expr.IntegerValue(3)),
+					expr.IntegerValue(4)),
 				10,
 			),
 		},
@@ -206,6 +208,101 @@ func TestRemoveUnnecessarySelectionNodesRule(t *testing.T) {
 	}
 }
 
+func TestUsePrimaryKeyBasedOnSelectionNodeRule(t *testing.T) {
+	tests := []struct {
+		name           string
+		root, expected planner.Node
+	}{
+		{
+			"FROM foo WHERE a = 1",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.Eq(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+					expr.IntegerValue(1),
+				)),
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.Eq(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+					expr.IntegerValue(1),
+				)),
+		},
+		{
+			"FROM foo WHERE pk() > 1",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.Gt(
+					new(expr.PKFunc),
+					expr.IntegerValue(1),
+				)),
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.Gt(
+					new(expr.PKFunc),
+					expr.IntegerValue(1),
+				)),
+		},
+		{
+			"FROM foo WHERE pk() = a",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.Eq(
+					new(expr.PKFunc),
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+				)),
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+				expr.Eq(
+					new(expr.PKFunc),
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+				)),
+		},
+		{
+			"FROM foo WHERE a = 1 AND pk() = $p1",
+			planner.NewSelectionNode(
+				planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+					expr.Eq(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+						expr.IntegerValue(1),
+					),
+				),
+				expr.Eq(
+					new(expr.PKFunc),
+					expr.NamedParam("p1"),
+				),
+			),
+			planner.NewSelectionNode(planner.NewPrimaryKeyInputNode("foo", expr.NamedParam("p1")),
+				expr.Eq(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+					expr.IntegerValue(1),
+				),
+			),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			err = tx.Exec(context.Background(), `
+				CREATE TABLE foo;
+				INSERT INTO foo (a) VALUES (1), (2), (3)
+			`)
+			require.NoError(t, err)
+
+			err = planner.Bind(planner.NewTree(test.root), tx.Transaction, []expr.Param{
+				{Name: "p1", Value: 1},
+			})
+			require.NoError(t, err)
+
+			res, err := planner.UsePrimaryKeyBasedOnSelectionNodeRule(planner.NewTree(test.root))
+			require.NoError(t, err)
+			require.Equal(t, planner.NewTree(test.expected).String(), res.String())
+		})
+	}
+}
+
 func TestUseIndexBasedOnSelectionNodeRule(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -335,6 +432,72 @@ func TestUseIndexBasedOnSelectionNodeRule(t *testing.T) {
 				"foo",
 			),
 		},
+		{
+			"FROM foo WHERE a >= 1 AND a < 3",
+			planner.NewSelectionNode(
+				planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+					expr.Gte(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+						expr.IntegerValue(1),
+					),
+				),
+				expr.Lt(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+					expr.IntegerValue(3),
+				),
+			),
+			planner.NewIndexInputNode("foo", "idx_foo_a", nil, nil, scanner.ASC),
+		},
+		{
+			"FROM foo WHERE 3 > a AND b = 2 AND 1 < a",
+			planner.NewSelectionNode(
+				planner.NewSelectionNode(
+					planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+						expr.Gt(
+							expr.IntegerValue(3),
+							expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+						),
+					),
+					expr.Eq(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "b"}},
+						expr.IntegerValue(2),
+					),
+				),
+				expr.Lt(
+					expr.IntegerValue(1),
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+				),
+			),
+			planner.NewSelectionNode(
+				planner.NewIndexInputNode("foo", "idx_foo_a", nil, nil, scanner.ASC),
+				expr.Eq(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "b"}},
+					expr.IntegerValue(2),
+				),
+			),
+		},
+		{
+			"FROM foo WHERE a > 1 AND a > 2",
+			planner.NewSelectionNode(
+				planner.NewSelectionNode(planner.NewTableInputNode("foo"),
+					expr.Gt(
+						expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+						expr.IntegerValue(1),
+					),
+				),
+				expr.Gt(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+					expr.IntegerValue(2),
+				),
+			),
+			planner.NewSelectionNode(
+				planner.NewIndexInputNode("foo", "idx_foo_a", nil, nil, scanner.ASC),
+				expr.Gt(
+					expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+					expr.IntegerValue(1),
+				),
+			),
+		},
 	}
 
 	for _, test := range tests {
@@ -375,3 +538,483 @@ func TestUseIndexBasedOnSelectionNodeRule(t *testing.T) {
 		})
 	}
 }
+
+func TestUseIndexBasedOnSelectionNodeRuleWithStats(t *testing.T) {
+	eqA := expr.Eq(
+		expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+		expr.IntegerValue(1),
+	)
+	eqB := expr.Eq(
+		expr.FieldSelector{document.ValuePathFragment{FieldName: "b"}},
+		expr.IntegerValue(2),
+	)
+	gtB := expr.Gt(
+		expr.FieldSelector{document.ValuePathFragment{FieldName: "b"}},
+		expr.IntegerValue(2),
+	)
+
+	tests := []struct {
+		name           string
+		root, expected planner.Node
+	}{
+		{
+			"FROM foo WHERE a = 1 AND b = 2",
+			planner.NewSelectionNode(planner.NewSelectionNode(planner.NewTableInputNode("foo"), eqB), eqA),
+			planner.NewSelectionNode(
+				planner.NewIndexInputNode("foo", "idx_foo_b", expr.Eq(nil, nil).(planner.IndexIteratorOperator), expr.IntegerValue(2), scanner.ASC),
+				eqA,
+			),
+		},
+		{
+			"FROM foo WHERE b = 2 AND a = 1",
+			planner.NewSelectionNode(planner.NewSelectionNode(planner.NewTableInputNode("foo"), eqA), eqB),
+			planner.NewSelectionNode(
+				planner.NewIndexInputNode("foo", "idx_foo_b", expr.Eq(nil, nil).(planner.IndexIteratorOperator), expr.IntegerValue(2), scanner.ASC),
+				eqA,
+			),
+		},
+		{
+			"FROM foo WHERE a = 1 AND b > 2",
+			planner.NewSelectionNode(planner.NewSelectionNode(planner.NewTableInputNode("foo"), eqA), gtB),
+			planner.NewSelectionNode(
+				planner.NewIndexInputNode("foo", "idx_foo_b", expr.Gt(nil, nil).(planner.IndexIteratorOperator), expr.IntegerValue(2), scanner.ASC),
+				eqA,
+			),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			// a only has 2 distinct values while b has 50
+			err = tx.Exec(context.Background(), `
+				CREATE TABLE foo;
+				CREATE INDEX idx_foo_a ON foo(a);
+				CREATE INDEX idx_foo_b ON foo(b);
+			`)
+			require.NoError(t, err)
+			for i := 0; i < 100; i++ {
+				err = tx.Exec(context.Background(), "INSERT INTO foo (a, b) VALUES (?, ?)", i%2, i%50)
+				require.NoError(t, err)
+			}
+			err = tx.Exec(context.Background(), "ANALYZE foo")
+			require.NoError(t, err)
+
+			err = planner.Bind(planner.NewTree(test.root), tx.Transaction, nil)
+			require.NoError(t, err)
+
+			res, err := planner.UseIndexBasedOnSelectionNodeRule(planner.NewTree(test.root))
+			require.NoError(t, err)
+			require.Equal(t, planner.NewTree(test.expected).String(), res.String())
+		})
+	}
+}
+
+func TestUseIndexBasedOnSelectionNodeRuleWithIndexHint(t *testing.T) {
+	eqA := expr.Eq(
+		expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}},
+		expr.IntegerValue(1),
+	)
+	eqB := expr.Eq(
+		expr.FieldSelector{document.ValuePathFragment{FieldName: "b"}},
+		expr.IntegerValue(2),
+	)
+	eqD := expr.Eq(
+		expr.FieldSelector{document.ValuePathFragment{FieldName: "d"}},
+		expr.IntegerValue(1),
+	)
+
+	tests := []struct {
+		name     string
+		root     planner.Node
+		expected planner.Node
+		fails    bool
+	}{
+		{
+			"USE INDEX forces a non-unique index",
+			planner.NewSelectionNode(
+				planner.NewSelectionNode(
+					planner.NewTableInputNodeWithIndexHint("foo", planner.IndexHint{Force: true, Indexes: []string{"idx_foo_a"}}),
+					eqA,
+				),
+				eqB,
+			),
+			planner.NewSelectionNode(
+				planner.NewIndexInputNode("foo", "idx_foo_a", expr.Eq(nil, nil).(planner.IndexIteratorOperator), expr.IntegerValue(1), scanner.ASC),
+				eqB,
+			),
+			false,
+		},
+		{
+			"IGNORE INDEX",
+			planner.NewSelectionNode(
+				planner.NewSelectionNode(
+					planner.NewTableInputNodeWithIndexHint("foo", planner.IndexHint{Indexes: []string{"idx_foo_b"}}),
+					eqA,
+				),
+				eqB,
+			),
+			planner.NewSelectionNode(
+				planner.NewIndexInputNode("foo", "idx_foo_a", expr.Eq(nil, nil).(planner.IndexIteratorOperator), expr.IntegerValue(1), scanner.ASC),
+				eqB,
+			),
+			false,
+		},
+		{
+			"IGNORE INDEX on every usable index",
+			planner.NewSelectionNode(
+				planner.NewTableInputNodeWithIndexHint("foo", planner.IndexHint{Indexes: []string{"idx_foo_a"}}),
+				eqA,
+			),
+			planner.NewSelectionNode(
+				planner.NewTableInputNodeWithIndexHint("foo", planner.IndexHint{Indexes: []string{"idx_foo_a"}}),
+				eqA,
+			),
+			false,
+		},
+		{
+			"USE INDEX that cannot be used",
+			planner.NewSelectionNode(
+				planner.NewTableInputNodeWithIndexHint("foo", planner.IndexHint{Force: true, Indexes: []string{"idx_foo_a"}}),
+				eqD,
+			),
+			nil,
+			true,
+		},
+		{
+			"USE INDEX without selection",
+			planner.NewTableInputNodeWithIndexHint("foo", planner.IndexHint{Force: true, Indexes: []string{"idx_foo_a"}}),
+			nil,
+			true,
+		},
+		{
+			"unknown index",
+			planner.NewSelectionNode(
+				planner.NewTableInputNodeWithIndexHint("foo", planner.IndexHint{Indexes: []string{"idx_foo_z"}}),
+				eqA,
+			),
+			nil,
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			err = tx.Exec(context.Background(), `
+				CREATE TABLE foo;
+				CREATE INDEX idx_foo_a ON foo(a);
+				CREATE UNIQUE INDEX idx_foo_b ON foo(b);
+				INSERT INTO foo (a, b, d) VALUES
+					(1, 1, 1),
+					(2, 2, 2)
+			`)
+			require.NoError(t, err)
+
+			err = planner.Bind(planner.NewTree(test.root), tx.Transaction, nil)
+			require.NoError(t, err)
+
+			res, err := planner.UseIndexBasedOnSelectionNodeRule(planner.NewTree(test.root))
+			if err == nil {
+				res, err = planner.CheckIndexHintRule(res)
+			}
+			if test.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, planner.NewTree(test.expected).String(), res.String())
+		})
+	}
+}
+
+func TestUseTopNSortRule(t *testing.T) {
+	sortField := expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}}
+
+	tests := []struct {
+		name     string
+		root     planner.Node
+		expected string
+	}{
+		{
+			"no limit",
+			planner.NewSortNode(planner.NewTableInputNode("foo"), sortField, scanner.DESC),
+			"Table(foo) -> Sort(a DESC)",
+		},
+		{
+			"limit without sort",
+			planner.NewLimitNode(planner.NewTableInputNode("foo"), 10),
+			"Table(foo) -> Limit(10)",
+		},
+		{
+			"limit",
+			planner.NewLimitNode(planner.NewSortNode(planner.NewTableInputNode("foo"), sortField, scanner.DESC), 10),
+			"Table(foo) -> Sort(a DESC, top 10) -> Limit(10)",
+		},
+		{
+			"limit and offset",
+			planner.NewLimitNode(planner.NewOffsetNode(planner.NewSortNode(planner.NewTableInputNode("foo"), sortField, scanner.ASC), 5), 10),
+			"Table(foo) -> Sort(a ASC, top 15) -> Offset(5) -> Limit(10)",
+		},
+		{
+			"limit too high",
+			planner.NewLimitNode(planner.NewSortNode(planner.NewTableInputNode("foo"), sortField, scanner.ASC), 100000),
+			"Table(foo) -> Sort(a ASC) -> Limit(100000)",
+		},
+		{
+			"limit zero",
+			planner.NewLimitNode(planner.NewSortNode(planner.NewTableInputNode("foo"), sortField, scanner.ASC), 0),
+			"Table(foo) -> Sort(a ASC) -> Limit(0)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := planner.UseTopNSortRule(planner.NewTree(test.root))
+			require.NoError(t, err)
+			require.Equal(t, test.expected, res.String())
+		})
+	}
+}
+
+func TestUseIndexBasedOnSortNodeRule(t *testing.T) {
+	field := func(name string) expr.FieldSelector {
+		return expr.FieldSelector{document.ValuePathFragment{FieldName: name}}
+	}
+	project := func(n planner.Node, name string, e expr.Expr) planner.Node {
+		return planner.NewProjectionNode(n, []planner.ProjectedField{planner.ProjectedExpr{Expr: e, ExprName: name}}, "foo")
+	}
+
+	tests := []struct {
+		name     string
+		root     planner.Node
+		expected string
+	}{
+		{
+			"ASC",
+			planner.NewSortNode(planner.NewTableInputNode("foo"), field("a"), scanner.ASC),
+			"Index(idx_foo_a)",
+		},
+		{
+			"DESC",
+			planner.NewSortNode(planner.NewTableInputNode("foo"), field("a"), scanner.DESC),
+			"Index(idx_foo_a DESC)",
+		},
+		{
+			"DESC NULLS LAST",
+			planner.NewSortNodeWithNulls(planner.NewTableInputNode("foo"), field("a"), scanner.DESC, planner.NullsLast),
+			"Index(idx_foo_a DESC)",
+		},
+		{
+			"DESC NULLS FIRST",
+			planner.NewSortNodeWithNulls(planner.NewTableInputNode("foo"), field("a"), scanner.DESC, planner.NullsFirst),
+			"Table(foo) -> Sort(a DESC NULLS FIRST)",
+		},
+		{
+			"ASC NULLS LAST",
+			planner.NewSortNodeWithNulls(planner.NewTableInputNode("foo"), field("a"), scanner.ASC, planner.NullsLast),
+			"Table(foo) -> Sort(a ASC NULLS LAST)",
+		},
+		{
+			"with selection, projection and limit",
+			planner.NewLimitNode(
+				planner.NewSortNode(
+					project(planner.NewSelectionNode(planner.NewTableInputNode("foo"), expr.Gt(field("b"), expr.IntegerValue(1))), "a", field("a")),
+					field("a"), scanner.DESC),
+				10),
+			"Index(idx_foo_a DESC) -> σ(cond: b > 1) -> ∏(a) -> Limit(10)",
+		},
+		{
+			"not indexed",
+			planner.NewSortNode(planner.NewTableInputNode("foo"), field("b"), scanner.ASC),
+			"Table(foo) -> Sort(b ASC)",
+		},
+		{
+			"same collation",
+			planner.NewSortNode(planner.NewTableInputNode("foo"), field("c"), scanner.ASC),
+			"Index(idx_foo_c)",
+		},
+		{
+			"different collation",
+			planner.NewSortNode(planner.NewTableInputNode("foo"), field("d"), scanner.ASC),
+			"Table(foo) -> Sort(d ASC)",
+		},
+		{
+			"projection replacing the sorted field",
+			planner.NewSortNode(project(planner.NewTableInputNode("foo"), "a", field("b")), field("a"), scanner.ASC),
+			"Table(foo) -> ∏(b) -> Sort(a ASC)",
+		},
+		{
+			"ignored index",
+			planner.NewSortNode(
+				planner.NewTableInputNodeWithIndexHint("foo", planner.IndexHint{Indexes: []string{"idx_foo_a"}}),
+				field("a"), scanner.ASC),
+			"Table(foo) IGNORE INDEX (idx_foo_a) -> Sort(a ASC)",
+		},
+		{
+			"forced index",
+			planner.NewSortNode(
+				planner.NewSelectionNode(
+					planner.NewTableInputNodeWithIndexHint("foo", planner.IndexHint{Force: true, Indexes: []string{"idx_foo_a"}}),
+					expr.Gt(field("b"), expr.IntegerValue(1))),
+				field("a"), scanner.DESC),
+			"Index(idx_foo_a DESC) -> σ(cond: b > 1)",
+		},
+		{
+			"forced index on another field",
+			planner.NewSortNode(
+				planner.NewTableInputNodeWithIndexHint("foo", planner.IndexHint{Force: true, Indexes: []string{"idx_foo_c"}}),
+				field("a"), scanner.ASC),
+			"Table(foo) USE INDEX (idx_foo_c) -> Sort(a ASC)",
+		},
+		{
+			"sampled table",
+			planner.NewSortNode(
+				planner.NewSampledTableInputNode("foo", nil, planner.TableSample{Percent: 50}),
+				field("a"), scanner.ASC),
+			"Table(foo) TABLESAMPLE (50 PERCENT) REPEATABLE (0) -> Sort(a ASC)",
+		},
+		{
+			"index input on the sorted field",
+			planner.NewSortNode(
+				planner.NewIndexInputNode("foo", "idx_foo_a", expr.Gt(nil, nil).(planner.IndexIteratorOperator), expr.IntegerValue(1), scanner.ASC),
+				field("a"), scanner.ASC),
+			"Index(idx_foo_a)",
+		},
+		{
+			"index input on the sorted field DESC",
+			planner.NewSortNode(
+				planner.NewIndexInputNode("foo", "idx_foo_a", expr.Gt(nil, nil).(planner.IndexIteratorOperator), expr.IntegerValue(1), scanner.ASC),
+				field("a"), scanner.DESC),
+			"Index(idx_foo_a) -> Sort(a DESC)",
+		},
+		{
+			"index input on the sorted field with IN",
+			planner.NewSortNode(
+				planner.NewIndexInputNode("foo", "idx_foo_a", expr.In(nil, nil).(planner.IndexIteratorOperator), expr.LiteralExprList{expr.IntegerValue(2), expr.IntegerValue(1)}, scanner.ASC),
+				field("a"), scanner.ASC),
+			"Index(idx_foo_a) -> Sort(a ASC)",
+		},
+		{
+			"index input on another field",
+			planner.NewSortNode(
+				planner.NewIndexInputNode("foo", "idx_foo_c", expr.Gt(nil, nil).(planner.IndexIteratorOperator), expr.TextValue("a"), scanner.ASC),
+				field("a"), scanner.ASC),
+			"Index(idx_foo_c) -> Sort(a ASC)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			err = tx.Exec(context.Background(), `
+				CREATE TABLE foo (c TEXT COLLATE NOCASE);
+				CREATE INDEX idx_foo_a ON foo(a);
+				CREATE INDEX idx_foo_c ON foo(c);
+			`)
+			require.NoError(t, err)
+
+			// the index orders text values regardless of their case
+			// while the field is sorted using the binary collation
+			err = tx.Transaction.CreateIndex(database.IndexConfig{
+				TableName: "foo",
+				IndexName: "idx_foo_d",
+				Path:      document.ValuePath{document.ValuePathFragment{FieldName: "d"}},
+				Collation: document.NoCaseCollation,
+			})
+			require.NoError(t, err)
+
+			err = planner.Bind(planner.NewTree(test.root), tx.Transaction, nil)
+			require.NoError(t, err)
+
+			res, err := planner.UseIndexBasedOnSortNodeRule(planner.NewTree(test.root))
+			require.NoError(t, err)
+			require.Equal(t, test.expected, res.String())
+		})
+	}
+}
+
+func TestDecodeProjectedFieldsRule(t *testing.T) {
+	field := func(name string) expr.FieldSelector {
+		return expr.FieldSelector{document.ValuePathFragment{FieldName: name}}
+	}
+	project := func(n planner.Node, exprs ...expr.Expr) planner.Node {
+		var fields []planner.ProjectedField
+		for _, e := range exprs {
+			fields = append(fields, planner.ProjectedExpr{Expr: e, ExprName: fmt.Sprintf("%v", e)})
+		}
+		return planner.NewProjectionNode(n, fields, "foo")
+	}
+
+	tests := []struct {
+		name     string
+		root     planner.Node
+		expected string
+	}{
+		{
+			"no projection",
+			planner.NewSelectionNode(planner.NewTableInputNode("foo"), expr.Gt(field("a"), expr.IntegerValue(1))),
+			"Table(foo) -> σ(cond: a > 1)",
+		},
+		{
+			"fields",
+			project(planner.NewTableInputNode("foo"), field("a"), field("b")),
+			"Table(foo, fields: a, b) -> ∏(a, b)",
+		},
+		{
+			"nested fields and duplicates",
+			project(planner.NewTableInputNode("foo"),
+				expr.FieldSelector{document.ValuePathFragment{FieldName: "a"}, document.ValuePathFragment{FieldName: "b"}},
+				expr.Add(field("a"), expr.IntegerValue(1))),
+			"Table(foo, fields: a) -> ∏(a.b, a + 1)",
+		},
+		{
+			"with selection, sort and limit",
+			planner.NewLimitNode(
+				planner.NewSortNode(
+					project(planner.NewSelectionNode(planner.NewTableInputNode("foo"), expr.Gt(field("c"), expr.IntegerValue(1))), field("a")),
+					field("b"), scanner.ASC),
+				10),
+			"Table(foo, fields: b, a, c) -> σ(cond: c > 1) -> ∏(a) -> Sort(b ASC) -> Limit(10)",
+		},
+		{
+			"wildcard",
+			planner.NewProjectionNode(planner.NewTableInputNode("foo"), []planner.ProjectedField{planner.Wildcard{}}, "foo"),
+			"Table(foo) -> ∏(*)",
+		},
+		{
+			"function",
+			project(planner.NewTableInputNode("foo"), field("a"), expr.CastFunc{Expr: field("b"), CastAs: document.TextValue}),
+			"Table(foo) -> ∏(a, CAST(b AS text))",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := planner.DecodeProjectedFieldsRule(planner.NewTree(test.root))
+			require.NoError(t, err)
+			require.Equal(t, test.expected, res.String())
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package planner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/document/encoding"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// Fields of the documents encoded in page tokens.
+const (
+	tokenStatementField = "statement"
+	tokenValueField     = "value"
+	tokenCompositeField = "composite"
+	tokenKeyField       = "key"
+)
+
+// A pageCursor tracks the position of the documents returned by a paginated statement.
+// The documents of a paginated statement are sorted by sort key, then by key, which gives
+// every document a unique position that can be encoded in a token to resume the statement.
+// It implements the query.Cursor interface.
+type pageCursor struct {
+	// hash of the statement, stored in the tokens to detect
+	// tokens used to resume a different statement.
+	statement uint64
+	direction scanner.Token
+	codec     encoding.Codec
+
+	// token the statement was resumed from and its position, if any.
+	// only the documents that follow that position are returned.
+	token string
+	start *sortPosition
+
+	// position of the document being returned by the sort,
+	// and of the last document returned by the statement.
+	pending, last sortPosition
+	returned      bool
+}
+
+var _ query.Cursor = (*pageCursor)(nil)
+
+// splitPageToken removes the page token from the parameters of a query.
+// It returns false if the query is not paginated.
+func splitPageToken(params []expr.Param) ([]expr.Param, string, bool) {
+	for i, p := range params {
+		if token, ok := p.Value.(query.PageToken); ok {
+			rest := make([]expr.Param, 0, len(params)-1)
+			rest = append(rest, params[:i]...)
+			rest = append(rest, params[i+1:]...)
+			return rest, string(token), true
+		}
+	}
+
+	return params, "", false
+}
+
+// paginate prepares a bound tree to return the documents that follow the position
+// encoded in the token, or all of them if the token is empty.
+// The token must have been returned by the statement whose hash is given.
+// The documents are sorted by the sort node of the tree, and by key, which requires
+// a sort node to be added to the tree if the statement has no ORDER BY clause.
+func paginate(t *Tree, statement uint64, tx *database.Transaction, params []expr.Param, token string) (*pageCursor, error) {
+	var prev, projPrev Node
+	var pn *ProjectionNode
+	var sn *sortNode
+
+	for n := t.Root; n != nil; n = n.Left() {
+		switch nn := n.(type) {
+		case *sortNode:
+			sn = nn
+		case *ProjectionNode:
+			pn, projPrev = nn, prev
+		case *GroupingNode:
+			return nil, errors.New("cannot paginate a statement with a GROUP BY clause")
+		}
+
+		prev = n
+	}
+
+	if pn == nil {
+		return nil, errors.New("only SELECT statements can be paginated")
+	}
+	if inputTableName(t.Root) == "" {
+		return nil, errors.New("cannot paginate a statement that doesn't read a table")
+	}
+	for _, e := range pn.Expressions {
+		if pe, ok := e.(ProjectedExpr); ok {
+			if _, ok := pe.Expr.(AggregatorBuilder); ok {
+				return nil, errors.New("cannot paginate a statement using aggregate functions")
+			}
+		}
+	}
+
+	c := pageCursor{
+		statement: statement,
+		codec:     tx.DB().Codec,
+		token:     token,
+	}
+
+	// without ORDER BY, documents are only sorted by key
+	if sn == nil {
+		sn = NewSortNode(pn, expr.PKFunc{}, scanner.ASC).(*sortNode)
+		err := sn.Bind(tx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		if projPrev == nil {
+			t.Root = sn
+		} else {
+			projPrev.SetLeft(sn)
+		}
+	}
+
+	sn.cursor = &c
+	c.direction = sn.direction
+
+	if token != "" {
+		err := c.decode(token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &c, nil
+}
+
+// statementHash returns a hash of the tree and of the parameters of the statement.
+// The tree must be the statement as parsed, which is never bound nor optimized,
+// for the hash not to depend on a previous run.
+// LIMIT and OFFSET clauses are ignored to allow the size of the pages to change,
+// as well as the FOR UPDATE clause, which follows them.
+func statementHash(t *Tree, params []expr.Param) uint64 {
+	n := t.Root
+	for n != nil && (n.Operation() == Limit || n.Operation() == Lock) {
+		n = n.Left()
+	}
+
+	h := fnv.New64a()
+	if n != nil {
+		_, _ = io.WriteString(h, nodeToString(n))
+	}
+	for _, p := range params {
+		fmt.Fprintf(h, "\x00%s=%v", p.Name, p.Value)
+	}
+
+	return h.Sum64()
+}
+
+// follows reports whether pos comes after the position the statement was resumed from.
+func (c *pageCursor) follows(pos sortPosition) bool {
+	if c.start == nil {
+		return true
+	}
+
+	cmp := pos.compare(*c.start)
+	if c.direction == scanner.DESC {
+		return cmp < 0
+	}
+
+	return cmp > 0
+}
+
+// commit records the position of the document being returned as the last one.
+func (c *pageCursor) commit() {
+	c.last = c.pending
+	c.returned = true
+}
+
+// Token returns a token encoding the position of the last document returned by the statement.
+// If no document was returned, it returns the token the statement was resumed from.
+func (c *pageCursor) Token() (string, error) {
+	if !c.returned {
+		return c.token, nil
+	}
+
+	fb := document.NewFieldBuffer().
+		Add(tokenStatementField, document.NewIntegerValue(int64(c.statement))).
+		Add(tokenValueField, document.NewBlobValue(c.last.value)).
+		Add(tokenKeyField, document.NewBlobValue(c.last.key))
+	if c.last.composite.Type != 0 {
+		fb.Add(tokenCompositeField, c.last.composite)
+	}
+
+	var buf bytes.Buffer
+	err := c.codec.NewEncoder(&buf).EncodeDocument(fb)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decode the position encoded in the token. It returns query.ErrInvalidToken
+// if the token is malformed or if it was returned by a different statement.
+func (c *pageCursor) decode(token string) error {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return query.ErrInvalidToken
+	}
+	d := c.codec.NewDocument(data)
+
+	v, err := d.GetByField(tokenStatementField)
+	if err != nil || v.Type != document.IntegerValue {
+		return query.ErrInvalidToken
+	}
+	if uint64(v.V.(int64)) != c.statement {
+		return fmt.Errorf("%w: the token was returned by a different statement", query.ErrInvalidToken)
+	}
+
+	var pos sortPosition
+
+	v, err = d.GetByField(tokenValueField)
+	if err != nil || v.Type != document.BlobValue {
+		return query.ErrInvalidToken
+	}
+	pos.value = v.V.([]byte)
+
+	v, err = d.GetByField(tokenKeyField)
+	if err != nil || v.Type != document.BlobValue {
+		return query.ErrInvalidToken
+	}
+	pos.key = v.V.([]byte)
+
+	v, err = d.GetByField(tokenCompositeField)
+	if err != nil && err != document.ErrFieldNotFound {
+		return query.ErrInvalidToken
+	}
+	if err == nil {
+		// copied arrays and documents can be compared without error
+		switch v.Type {
+		case document.ArrayValue:
+			var vb document.ValueBuffer
+			err = vb.Copy(v.V.(document.Array))
+			pos.composite = document.NewArrayValue(vb)
+		case document.DocumentValue:
+			var fb document.FieldBuffer
+			err = fb.Copy(v.V.(document.Document))
+			pos.composite = document.NewDocumentValue(&fb)
+		default:
+			err = query.ErrInvalidToken
+		}
+		if err != nil {
+			return query.ErrInvalidToken
+		}
+	}
+
+	c.start = &pos
+	return nil
+}
@@ -19,8 +19,9 @@ type ProjectionNode struct {
 	Expressions []ProjectedField
 	tableName   string
 
-	info *database.TableInfo
-	tx   *database.Transaction
+	info   *database.TableInfo
+	tx     *database.Transaction
+	params []expr.Param
 }
 
 var _ operationNode = (*ProjectionNode)(nil)
@@ -40,7 +41,31 @@ func NewProjectionNode(n Node, expressions []ProjectedField, tableName string) N
 // Bind database resources to this node.
 func (n *ProjectionNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
 	n.tx = tx
-	if n.tableName == "" {
+	n.params = params
+
+	for i, e := range n.Expressions {
+		if w, ok := e.(Wildcard); ok && w.TableName != "" && w.TableName != n.tableName {
+			return fmt.Errorf("unknown table %q in %s", w.TableName, w.Name())
+		}
+
+		// calls to aggregate functions registered by the user can only
+		// be distinguished from other function calls by looking them up
+		if pe, ok := e.(ProjectedExpr); ok {
+			switch t := pe.Expr.(type) {
+			case expr.UserFunc:
+				pe.Expr = bindUserAggregate(tx, t)
+			case *expr.FilteredAggregateFunc:
+				t.Agg = bindUserAggregate(tx, t.Agg)
+				if _, ok := t.Agg.(document.AggregatorBuilder); !ok {
+					return fmt.Errorf("FILTER can only be used with aggregate functions, got %s", t.Agg)
+				}
+			}
+			n.Expressions[i] = pe
+		}
+	}
+
+	// the input might be a common table expression
+	if inputTableName(n) == "" {
 		return
 	}
 
@@ -53,6 +78,22 @@ func (n *ProjectionNode) Bind(tx *database.Transaction, params []expr.Param) (er
 	return
 }
 
+// bindUserAggregate turns e into a call to an aggregate function if it calls
+// an aggregate function registered by the user. Otherwise, it returns e.
+func bindUserAggregate(tx *database.Transaction, e expr.Expr) expr.Expr {
+	uf, ok := e.(expr.UserFunc)
+	if !ok {
+		return e
+	}
+
+	fn, ok := tx.DB().GetAggregate(uf.FuncName)
+	if !ok {
+		return e
+	}
+
+	return &expr.UserAggregateFunc{FuncName: uf.FuncName, Exprs: uf.Exprs, Distinct: uf.Distinct, Fn: fn}
+}
+
 // An AggregatorBuilder can build aggregators on demand.
 type AggregatorBuilder interface {
 	document.AggregatorBuilder
@@ -76,12 +117,21 @@ func (n *ProjectionNode) toStream(st document.Stream) (document.Stream, error) {
 		}
 	}
 
-	if len(aggBuilders) > 0 {
+	// grouped documents are aggregated even without aggregate functions,
+	// to return one document per group.
+	// Without GROUP BY, the whole stream is a single group,
+	// which is aggregated even if it is empty
+	switch {
+	case isGrouped(n):
 		st = st.Aggregate(aggBuilders...)
+	case len(aggBuilders) > 0:
+		st = st.AggregateAll(aggBuilders...)
 	}
 
 	if st.IsEmpty() {
 		d := documentMask{
+			tx:           n.tx,
+			params:       n.params,
 			resultFields: n.Expressions,
 		}
 		var fb document.FieldBuffer
@@ -95,6 +145,8 @@ func (n *ProjectionNode) toStream(st document.Stream) (document.Stream, error) {
 		var dm documentMask
 		st = st.Map(func(d document.Document) (document.Document, error) {
 			dm.info = n.info
+			dm.tx = n.tx
+			dm.params = n.params
 			dm.d = d
 			dm.resultFields = n.Expressions
 
@@ -105,6 +157,17 @@ func (n *ProjectionNode) toStream(st document.Stream) (document.Stream, error) {
 	return st, nil
 }
 
+// isGrouped returns whether the documents projected by n are grouped by a GroupingNode.
+func isGrouped(n Node) bool {
+	for n = n.Left(); n != nil; n = n.Left() {
+		if _, ok := n.(*GroupingNode); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (n *ProjectionNode) String() string {
 	var b strings.Builder
 
@@ -120,6 +183,8 @@ func (n *ProjectionNode) String() string {
 
 type documentMask struct {
 	info         *database.TableInfo
+	tx           *database.Transaction
+	params       []expr.Param
 	d            document.Document
 	resultFields []ProjectedField
 }
@@ -138,7 +203,9 @@ func (r documentMask) GetByField(field string) (document.Value, error) {
 
 func (r documentMask) Iterate(fn func(field string, value document.Value) error) error {
 	stack := expr.EvalStack{
+		Tx:       r.tx,
 		Document: r.d,
+		Params:   r.params,
 		Info:     r.info,
 	}
 
@@ -190,10 +257,18 @@ func (r ProjectedExpr) String() string {
 }
 
 // A Wildcard is a ResultField that iterates over all the fields of a document.
-type Wildcard struct{}
+type Wildcard struct {
+	// If set, the wildcard only selects the fields
+	// of the given table, i.e. tablename.*
+	TableName string
+}
 
-// Name returns the "*" character.
+// Name returns the "*" character, prefixed by the table name if any.
 func (w Wildcard) Name() string {
+	if w.TableName != "" {
+		return w.TableName + ".*"
+	}
+
 	return "*"
 }
 
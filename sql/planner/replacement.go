@@ -81,6 +81,9 @@ func (n *replacementNode) toStream(st document.Stream) (document.Stream, error)
 
 			return nil
 		})
+		if err != nil {
+			return document.Stream{}, err
+		}
 
 		for j := 0; j < i; j++ {
 			err = n.table.Replace(keys[j], docs[j])
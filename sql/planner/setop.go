@@ -0,0 +1,235 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/key"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// A setOperationNode combines the documents returned by the trees of two SELECT statements.
+// The documents returned by the right tree are kept in memory, then the documents returned
+// by the left tree are filtered according to the operator.
+// Documents are compared using the values of their fields, in order, regardless of their names.
+type setOperationNode struct {
+	node
+
+	op        scanner.Token
+	all       bool
+	leftTree  *Tree
+	rightTree *Tree
+
+	// memory budget of the statement, shared with the other nodes.
+	budget *memoryBudget
+}
+
+var _ inputNode = (*setOperationNode)(nil)
+var _ operationNode = (*setOperationNode)(nil)
+
+// NewSetOperationNode creates a node that combines the documents returned by the left and right trees,
+// depending on op:
+//   - INTERSECT returns the documents returned by both trees
+//   - EXCEPT returns the documents returned by the left tree but not by the right tree
+//
+// Duplicates are removed, unless all is true, in which case a document returned m times
+// by the left tree and n times by the right tree is returned min(m, n) times by INTERSECT
+// and max(m - n, 0) times by EXCEPT.
+// It returns an error if both statements don't select the same number of fields, unless
+// one of them uses a wildcard.
+func NewSetOperationNode(op scanner.Token, all bool, left, right *Tree) (Node, error) {
+	if op != scanner.INTERSECT && op != scanner.EXCEPT {
+		return nil, fmt.Errorf("unsupported set operator %s", op)
+	}
+
+	ln, rn := projectedFieldCount(left), projectedFieldCount(right)
+	if ln >= 0 && rn >= 0 && ln != rn {
+		return nil, fmt.Errorf("each %s query must select the same number of fields", op)
+	}
+
+	return &setOperationNode{
+		node: node{
+			op: Input,
+		},
+		op:        op,
+		all:       all,
+		leftTree:  left,
+		rightTree: right,
+	}, nil
+}
+
+// projectedFieldCount returns the number of fields selected by the tree,
+// or -1 if it can't be known before running it.
+func projectedFieldCount(t *Tree) int {
+	n := t.Root
+	for n != nil && n.Operation() != Projection {
+		n = n.Left()
+	}
+
+	pn, ok := n.(*ProjectionNode)
+	if !ok {
+		return -1
+	}
+
+	for _, e := range pn.Expressions {
+		if _, ok := e.(Wildcard); ok {
+			return -1
+		}
+	}
+
+	return len(pn.Expressions)
+}
+
+func (n *setOperationNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	for _, t := range []**Tree{&n.leftTree, &n.rightTree} {
+		err = Bind(*t, tx, params)
+		if err != nil {
+			return
+		}
+
+		*t, err = Optimize(*t)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (n *setOperationNode) String() string {
+	name := "Intersect"
+	if n.op == scanner.EXCEPT {
+		name = "Except"
+	}
+	if n.all {
+		name += "All"
+	}
+
+	return fmt.Sprintf("%s(%s, %s)", name, n.leftTree, n.rightTree)
+}
+
+func (n *setOperationNode) toStream(st document.Stream) (document.Stream, error) {
+	return n.buildStream()
+}
+
+func (n *setOperationNode) buildStream() (document.Stream, error) {
+	counts, err := n.materializeRight()
+	if err != nil {
+		return document.Stream{}, err
+	}
+
+	// an optimized tree with no root doesn't return any document
+	if n.leftTree.Root == nil {
+		return document.NewStream(document.NewIterator()), nil
+	}
+
+	res, err := n.leftTree.execute()
+	if err != nil {
+		return document.Stream{}, err
+	}
+
+	// keys of the documents already returned, used to remove duplicates
+	seen := make(map[string]struct{})
+
+	return res.Stream.Filter(func(d document.Document) (bool, error) {
+		k, err := documentHashKey(d)
+		if err != nil {
+			return false, err
+		}
+
+		if !n.all {
+			if _, ok := seen[k]; ok {
+				return false, nil
+			}
+		}
+
+		c, ok := counts[k]
+		if n.all && ok {
+			counts[k] = c - 1
+			if c == 1 {
+				delete(counts, k)
+			}
+		}
+
+		keep := ok
+		if n.op == scanner.EXCEPT {
+			keep = !ok
+		}
+		if !keep || n.all {
+			return keep, nil
+		}
+
+		err = n.budget.reserve(int64(len(k)))
+		if err != nil {
+			return false, err
+		}
+		seen[k] = struct{}{}
+		return true, nil
+	}), nil
+}
+
+// materializeRight runs the right tree and returns the number of times each document was returned,
+// indexed by the hash key of the document.
+func (n *setOperationNode) materializeRight() (map[string]int, error) {
+	counts := make(map[string]int)
+
+	if n.rightTree.Root == nil {
+		return counts, nil
+	}
+
+	res, err := n.rightTree.execute()
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	err = res.Iterate(func(d document.Document) error {
+		k, err := documentHashKey(d)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := counts[k]; !ok {
+			// the keys are kept until the end of the statement
+			err = n.budget.reserve(int64(len(k)))
+			if err != nil {
+				return err
+			}
+		}
+
+		counts[k]++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// documentHashKey encodes the values of the fields of d, in order, so that
+// documents with equal values have the same key.
+func documentHashKey(d document.Document) (string, error) {
+	var values []document.Value
+
+	err := d.Iterate(func(_ string, v document.Value) error {
+		values = append(values, v)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	k, err := key.KeyEncodeMulti(values...)
+	if err != nil {
+		return "", fmt.Errorf("cannot compare documents: %w", err)
+	}
+
+	return string(k), nil
+}
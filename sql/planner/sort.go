@@ -3,6 +3,7 @@ package planner
 import (
 	"bytes"
 	"container/heap"
+	"errors"
 	"fmt"
 
 	"github.com/genjidb/genji/database"
@@ -15,15 +16,57 @@ import (
 type sortNode struct {
 	node
 
-	sortField expr.FieldSelector
+	sortField expr.Expr
 	direction scanner.Token
+	nulls     NullsPosition
+	// if greater than zero, only the first limit documents
+	// of the sorted stream are returned.
+	limit int
+	// collation of the sorted field, as defined by the field constraints of the table.
+	collation document.Collation
+	// memory budget of the statement, shared with the other nodes.
+	budget *memoryBudget
+	// if true, the sorted documents keep the key of the document they were copied from,
+	// which is needed to lock them.
+	keepKeys bool
+	// if set, the statement is paginated: documents with the same sort key
+	// are sorted by key and only the ones that follow the position of the cursor
+	// are returned.
+	cursor *pageCursor
+	tx     *database.Transaction
+	params []expr.Param
 }
 
 var _ operationNode = (*sortNode)(nil)
 
+// NullsPosition defines where documents whose sorted value is null,
+// or missing, are placed in a sorted stream.
+type NullsPosition uint8
+
+const (
+	// NullsDefault sorts nulls like indexes do, before any other value:
+	// they come first in ascending order and last in descending order.
+	NullsDefault NullsPosition = iota
+	// NullsFirst places nulls before the other values, regardless of the direction.
+	NullsFirst
+	// NullsLast places nulls after the other values, regardless of the direction.
+	NullsLast
+)
+
 // NewSortNode creates a node that sorts a stream according to a given
-// document path and a sort direction.
-func NewSortNode(n Node, sortField expr.FieldSelector, direction scanner.Token) Node {
+// expression and a sort direction.
+// If the expression is a document path, the documents can be sorted by any
+// projected field or field of the original document. Otherwise, the expression
+// is evaluated against the original document.
+// The sort is stable: documents with equal values are returned in the order
+// in which they were read.
+func NewSortNode(n Node, sortField expr.Expr, direction scanner.Token) Node {
+	return NewSortNodeWithNulls(n, sortField, direction, NullsDefault)
+}
+
+// NewSortNodeWithNulls creates a sort node like NewSortNode, which places
+// the documents whose sorted value is null according to nulls.
+func NewSortNodeWithNulls(n Node, sortField expr.Expr, direction scanner.Token, nulls NullsPosition) Node {
 	if direction == 0 {
 		direction = scanner.ASC
 	}
@@ -35,18 +78,91 @@ func NewSortNode(n Node, sortField expr.FieldSelector, direction scanner.Token)
 		},
 		sortField: sortField,
 		direction: direction,
+		nulls:     nulls,
 	}
 }
 
+// nullsHigh returns whether nulls must be sorted as if they were greater
+// than any other value to be placed where the sort node expects them.
+func (n *sortNode) nullsHigh() bool {
+	switch n.nulls {
+	case NullsFirst:
+		return n.direction == scanner.DESC
+	case NullsLast:
+		return n.direction != scanner.DESC
+	}
+
+	return false
+}
+
 func (n *sortNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
+	n.tx = tx
+	n.params = params
+
+	// the collation only applies when sorting by a field of the table
+	fs, ok := n.sortField.(expr.FieldSelector)
+	if !ok {
+		return
+	}
+
+	tableName := inputTableName(n)
+	if tableName == "" {
+		return
+	}
+
+	table, err := tx.GetTable(tableName)
+	if err != nil {
+		return err
+	}
+
+	info, err := table.Info()
+	if err != nil {
+		return err
+	}
+
+	path := document.ValuePath(fs)
+	for _, fc := range info.FieldConstraints {
+		if fc.Path.IsEqual(path) {
+			n.collation = fc.Collation
+			break
+		}
+	}
+
 	return
 }
 
+// inputTableName returns the name of the table read by the input node of the tree
+// whose root is n. It returns an empty string if there is no input node.
+func inputTableName(n Node) string {
+	for n != nil {
+		switch t := n.(type) {
+		case *tableInputNode:
+			return t.tableName
+		case *indexInputNode:
+			return t.tableName
+		case *primaryKeyInputNode:
+			return t.tableName
+		}
+
+		n = n.Left()
+	}
+
+	return ""
+}
+
 func (n *sortNode) toStream(st document.Stream) (document.Stream, error) {
 	return document.NewStream(&sortIterator{
 		st:        st,
 		sortField: n.sortField,
 		direction: n.direction,
+		nullsHigh: n.nullsHigh(),
+		limit:     n.limit,
+		collation: n.collation,
+		budget:    n.budget,
+		cursor:    n.cursor,
+		keepKeys:  n.keepKeys,
+		tx:        n.tx,
+		params:    n.params,
 	}), nil
 }
 
@@ -56,24 +172,77 @@ func (n *sortNode) String() string {
 		dir = "DESC"
 	}
 
+	switch n.nulls {
+	case NullsFirst:
+		dir += " NULLS FIRST"
+	case NullsLast:
+		dir += " NULLS LAST"
+	}
+
+	if n.limit > 0 {
+		return fmt.Sprintf("Sort(%s %s, top %d)", n.sortField, dir, n.limit)
+	}
+
 	return fmt.Sprintf("Sort(%s %s)", n.sortField, dir)
 }
 
 type sortIterator struct {
 	st        document.Stream
-	sortField expr.FieldSelector
+	sortField expr.Expr
 	direction scanner.Token
+	// if true, nulls are sorted after any other value
+	nullsHigh bool
+	limit     int
+	collation document.Collation
+	budget    *memoryBudget
+	// number of bytes reserved from the budget
+	reserved int64
+	cursor   *pageCursor
+	keepKeys bool
+	tx       *database.Transaction
+	params   []expr.Param
+	// temporary stores containing the sorted runs
+	// of documents that didn't fit in memory
+	runs []sortRun
+	// number of documents read from the stream
+	seq uint64
 }
 
-func (it *sortIterator) Iterate(fn func(d document.Document) error) error {
+func (it *sortIterator) Iterate(fn func(d document.Document) error) (err error) {
+	defer func() {
+		it.budget.release(it.reserved)
+		it.reserved = 0
+
+		if dropErr := it.dropRuns(); err == nil {
+			err = dropErr
+		}
+	}()
+
+	if it.limit > 0 {
+		return it.iterateTopN(fn)
+	}
+
 	h, err := it.sortStream(it.st)
 	if err != nil {
 		return err
 	}
 
+	// if some documents were spilled, the remaining ones
+	// are spilled as well and all the runs are merged
+	if len(it.runs) > 0 {
+		if h.Len() > 0 {
+			err = it.spill(h)
+			if err != nil {
+				return err
+			}
+		}
+
+		return it.mergeRuns(fn)
+	}
+
 	for h.Len() > 0 {
 		node := heap.Pop(h).(heapNode)
-		err := fn(&(node.data))
+		err := it.emit(node.sortPosition, &(node.data), fn)
 		if err != nil {
 			return err
 		}
@@ -91,111 +260,381 @@ func (it *sortIterator) Iterate(fn func(d document.Document) error) error {
 // During iteration, the stream will pop the k-smallest or k-largest elements, depending on
 // the chosen sorting order (ASC or DESC).
 // This function is not memory efficient as it's loading the entire stream in memory before
-// returning the k-smallest or k-largest elements.
+// returning the k-smallest or k-largest elements. The memory used by the documents is
+// reserved from the budget of the statement. When it is exceeded, the documents of the heap
+// are written to a temporary store, as a sorted run, to make room for the next ones.
+// If the transaction is read-only, no store can be created and the iteration stops
+// with the error returned by the budget.
 func (it *sortIterator) sortStream(st document.Stream) (heap.Interface, error) {
-	path := document.ValuePath(it.sortField)
-
-	var h heap.Interface
-	if it.direction == scanner.ASC {
-		h = new(minHeap)
-	} else {
-		h = new(maxHeap)
-	}
-
+	h := &sortHeap{desc: it.direction == scanner.DESC}
 	heap.Init(h)
 
 	return h, st.Iterate(func(d document.Document) error {
-		// It is possible to sort by any projected field
-		// or field of the original document.
-		v, err := path.GetValue(d)
-		if err != nil && err != document.ErrFieldNotFound {
+		pos, ok, err := it.position(d)
+		if err != nil || !ok {
 			return err
 		}
 
-		// If a field is not found in the projected fields
-		// Look for fields in the original document.
-		if err == document.ErrFieldNotFound {
-			if dm, ok := d.(*documentMask); ok {
-				v, err = path.GetValue(dm.d)
-				if err != nil && err != document.ErrFieldNotFound {
-					return err
-				}
-				if err == document.ErrFieldNotFound {
-					v = document.NewNullValue()
-				}
-			} else {
-				v = document.NewNullValue()
-			}
+		node := heapNode{sortPosition: pos}
+		err = node.data.Copy(d)
+		if err != nil {
+			return err
 		}
 
-		// We need to make sure sort behaviour
-		// if the same with or without indexes.
-		// To achieve that, the value must be encoded using the same method
-		// as what the index package would do.
-		if v.Type == document.IntegerValue {
-			v, err = v.CastAsDouble()
+		err = it.reserve(&node)
+		if errors.Is(err, database.ErrResultTooLarge) && h.Len() > 0 && it.tx.Writable() {
+			err = it.spill(h)
 			if err != nil {
 				return err
 			}
+
+			err = it.reserve(&node)
+		}
+		if err != nil {
+			return err
 		}
 
-		var value []byte
-		if v.Type != document.ArrayValue && v.Type != document.DocumentValue {
-			value, err = key.AppendValue(nil, v)
+		heap.Push(h, node)
+
+		return nil
+	})
+}
+
+// reserve the memory used by the node from the budget of the statement.
+// The memory is released once the iteration is over.
+func (it *sortIterator) reserve(node *heapNode) error {
+	if it.budget == nil {
+		return nil
+	}
+
+	size, err := documentSize(&node.data)
+	if err != nil {
+		return err
+	}
+	size += int64(len(node.value) + len(node.key))
+
+	err = it.budget.reserve(size)
+	if err != nil {
+		return err
+	}
+
+	node.size = size
+	it.reserved += size
+	return nil
+}
+
+// release the memory reserved for a node that is evicted.
+func (it *sortIterator) release(node *heapNode) {
+	it.budget.release(node.size)
+	it.reserved -= node.size
+	node.size = 0
+}
+
+// iterateTopN only keeps the first it.limit documents of the sorted stream in memory,
+// which ensures a O(n log k) time complexity and a O(k) memory usage, where k is the limit.
+// The documents are stored in a heap ordered in the opposite direction of the sort
+// so that its root is always the document to evict when a better one is found.
+func (it *sortIterator) iterateTopN(fn func(d document.Document) error) error {
+	h := &sortHeap{desc: it.direction == scanner.DESC, reverse: true}
+
+	err := it.st.Iterate(func(d document.Document) error {
+		pos, ok, err := it.position(d)
+		if err != nil || !ok {
+			return err
+		}
+
+		if h.Len() < it.limit {
+			node := heapNode{sortPosition: pos}
+			err = node.data.Copy(d)
 			if err != nil {
 				return err
 			}
-		}
 
-		// to ensure ordering of values based on their types
-		// (i.e. booleans < numbers < text, ...,
-		// see index package for more info)
-		// we will prepend the encoded value with one byte
-		// representing the type of the value.
-		// integer will be considered as double
-		value = append([]byte{byte(v.Type)}, value...)
+			err = it.reserve(&node)
+			if err != nil {
+				return err
+			}
 
-		node := heapNode{
-			value: value,
+			heap.Push(h, node)
+			return nil
 		}
-		err = node.data.Copy(d)
+
+		// ignore the document if it doesn't come before
+		// the last document of the heap
+		if !pos.before(h.nodes[0].sortPosition, h.desc) {
+			return nil
+		}
+
+		root := &h.nodes[0]
+		root.sortPosition = pos
+		root.data.Reset()
+		err = root.data.Copy(d)
 		if err != nil {
 			return err
 		}
 
-		heap.Push(h, node)
+		it.release(root)
+		err = it.reserve(root)
+		if err != nil {
+			return err
+		}
 
+		heap.Fix(h, 0)
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	// the heap returns documents in reverse order
+	sorted := make([]heapNode, h.Len())
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(h).(heapNode)
+	}
+
+	for i := range sorted {
+		err = it.emit(sorted[i].sortPosition, &sorted[i].data, fn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sortKey returns the value of the sort field of d, encoded so that
+// the result of bytes.Compare follows the ordering of values.
+// Arrays and documents are only encoded by their type, and are returned
+// as a copy alongside the key so that they can be compared using Value.Compare.
+func (it *sortIterator) sortKey(d document.Document) ([]byte, document.Value, error) {
+	v, err := it.sortValue(d)
+	if err != nil {
+		return nil, document.Value{}, err
+	}
+
+	// nulls are encoded with a type byte that is greater than the type of any value
+	if v.Type == document.NullValue && it.nullsHigh {
+		return []byte{nullsHighType}, document.Value{}, nil
+	}
+
+	v = it.collation.Collate(v)
+
+	// We need to make sure sort behaviour
+	// if the same with or without indexes.
+	// To achieve that, the value must be encoded using the same method
+	// as what the index package would do.
+	if v.Type == document.IntegerValue {
+		v, err = v.CastAsDouble()
+		if err != nil {
+			return nil, document.Value{}, err
+		}
+	}
+
+	var value []byte
+	var composite document.Value
+	switch v.Type {
+	case document.ArrayValue:
+		var vb document.ValueBuffer
+		err = vb.Copy(v.V.(document.Array))
+		if err != nil {
+			return nil, document.Value{}, err
+		}
+		composite = document.NewArrayValue(vb)
+	case document.DocumentValue:
+		var fb document.FieldBuffer
+		err = fb.Copy(v.V.(document.Document))
+		if err != nil {
+			return nil, document.Value{}, err
+		}
+		composite = document.NewDocumentValue(&fb)
+	default:
+		value, err = key.AppendValue(nil, v)
+		if err != nil {
+			return nil, document.Value{}, err
+		}
+	}
+
+	// to ensure ordering of values based on their types
+	// (i.e. booleans < numbers < text, ...,
+	// see index package for more info)
+	// we will prepend the encoded value with one byte
+	// representing the type of the value.
+	// integer will be considered as double
+	return append([]byte{byte(v.Type)}, value...), composite, nil
+}
+
+// nullsHighType is the type byte of the sort key of nulls
+// that are sorted after any other value.
+const nullsHighType = 0xFF
+
+// position returns the position of d in the sorted stream.
+// If the sort is paginated, it returns false if d doesn't follow
+// the position of the cursor.
+func (it *sortIterator) position(d document.Document) (sortPosition, bool, error) {
+	pos := sortPosition{seq: it.seq}
+	it.seq++
+
+	var err error
+	pos.value, pos.composite, err = it.sortKey(d)
+	if err != nil || (it.cursor == nil && !it.keepKeys) {
+		return pos, true, err
+	}
+
+	if dm, ok := d.(*documentMask); ok {
+		d = dm.d
+	}
+	k, ok := d.(document.Keyer)
+	if !ok || k.Key() == nil {
+		return pos, false, errors.New("cannot paginate or lock documents without a key")
+	}
+	pos.key = append([]byte(nil), k.Key()...)
+
+	if it.cursor == nil {
+		return pos, true, nil
+	}
+	return pos, it.cursor.follows(pos), nil
+}
+
+// emit passes d to fn, after recording its position if the sort is paginated.
+// If the keys are kept, d is returned with the key of the document it was copied from.
+func (it *sortIterator) emit(pos sortPosition, d document.Document, fn func(d document.Document) error) error {
+	if it.cursor != nil {
+		it.cursor.pending = pos
+	}
+	if it.keepKeys {
+		d = encodedDocumentWithKey{Document: d, key: pos.key}
+	}
+
+	return fn(d)
 }
 
 type heapNode struct {
+	sortPosition
+	data document.FieldBuffer
+	// number of bytes reserved from the memory budget
+	size int64
+}
+
+// sortPosition is the position of a document in the sorted stream.
+type sortPosition struct {
 	value []byte
-	data  document.FieldBuffer
+	// copy of the sorted value if it is an array or a document
+	composite document.Value
+	// key of the document, only set if the sort is paginated or keeps the keys
+	key []byte
+	// order in which the document was read, used to keep
+	// the documents with the same sort key in that order
+	seq uint64
 }
 
-type minHeap []heapNode
+// compare the positions by sort key, then by document key.
+func (p sortPosition) compare(other sortPosition) int {
+	cmp := compareSortKeys(p.value, p.composite, other.value, other.composite)
+	if cmp != 0 {
+		return cmp
+	}
+
+	return bytes.Compare(p.key, other.key)
+}
 
-func (h minHeap) Len() int           { return len(h) }
-func (h minHeap) Less(i, j int) bool { return bytes.Compare(h[i].value, h[j].value) < 0 }
-func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+// before reports whether p comes before other in a sort in the given direction.
+// Documents with the same sort key and document key are sorted in the order
+// in which they were read, regardless of the direction, so that the sort is stable.
+func (p sortPosition) before(other sortPosition, desc bool) bool {
+	cmp := p.compare(other)
+	if desc {
+		cmp = -cmp
+	}
+	if cmp != 0 {
+		return cmp < 0
+	}
+
+	return p.seq < other.seq
+}
+
+// compareSortKeys compares two keys returned by sortKey.
+// Arrays and documents of the same type are compared using Value.Compare.
+func compareSortKeys(a []byte, ca document.Value, b []byte, cb document.Value) int {
+	cmp := bytes.Compare(a, b)
+	if cmp != 0 || ca.Type == 0 || cb.Type == 0 {
+		return cmp
+	}
 
-func (h *minHeap) Push(x interface{}) {
-	*h = append(*h, x.(heapNode))
+	// copied arrays and documents are buffers, which cannot fail to be compared
+	cmp, _ = ca.Compare(cb)
+	return cmp
 }
 
-func (h *minHeap) Pop() interface{} {
-	old := *h
+// sortHeap orders nodes in the direction of the sort, so that its root
+// is the node that comes first. If reverse is true, the root is the node
+// that comes last instead.
+type sortHeap struct {
+	nodes   []heapNode
+	desc    bool
+	reverse bool
+}
+
+func (h sortHeap) Len() int { return len(h.nodes) }
+func (h sortHeap) Less(i, j int) bool {
+	if h.reverse {
+		return h.nodes[j].before(h.nodes[i].sortPosition, h.desc)
+	}
+	return h.nodes[i].before(h.nodes[j].sortPosition, h.desc)
+}
+func (h sortHeap) Swap(i, j int) { h.nodes[i], h.nodes[j] = h.nodes[j], h.nodes[i] }
+
+func (h *sortHeap) Push(x interface{}) {
+	h.nodes = append(h.nodes, x.(heapNode))
+}
+
+func (h *sortHeap) Pop() interface{} {
+	old := h.nodes
 	n := len(old)
 	x := old[n-1]
-	*h = old[0 : n-1]
+	h.nodes = old[0 : n-1]
 	return x
 }
 
-type maxHeap struct {
-	minHeap
-}
+// sortValue returns the value d is sorted by.
+func (it *sortIterator) sortValue(d document.Document) (document.Value, error) {
+	fs, ok := it.sortField.(expr.FieldSelector)
+	if !ok {
+		stack := expr.EvalStack{
+			Tx:       it.tx,
+			Document: d,
+			Params:   it.params,
+		}
+		if dm, ok := d.(*documentMask); ok {
+			stack.Document, stack.Info = dm.d, dm.info
+		}
+
+		return it.sortField.Eval(stack)
+	}
+
+	path := document.ValuePath(fs)
+
+	// It is possible to sort by any projected field
+	// or field of the original document.
+	v, err := path.GetValue(d)
+	if err != nil && err != document.ErrFieldNotFound {
+		return document.Value{}, err
+	}
+
+	// If a field is not found in the projected fields
+	// Look for fields in the original document.
+	if err == document.ErrFieldNotFound {
+		if dm, ok := d.(*documentMask); ok {
+			v, err = path.GetValue(dm.d)
+			if err != nil && err != document.ErrFieldNotFound {
+				return document.Value{}, err
+			}
+			if err == document.ErrFieldNotFound {
+				v = document.NewNullValue()
+			}
+		} else {
+			v = document.NewNullValue()
+		}
+	}
 
-func (h maxHeap) Less(i, j int) bool {
-	return bytes.Compare(h.minHeap[i].value, h.minHeap[j].value) > 0
+	return v, nil
 }
@@ -0,0 +1,218 @@
+package planner_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func newSortTestDB(t testing.TB, n int) *genji.DB {
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = db.Exec(ctx, "CREATE TABLE test")
+	require.NoError(t, err)
+
+	r := rand.New(rand.NewSource(42))
+	err = db.Update(func(tx *genji.Tx) error {
+		for i := 0; i < n; i++ {
+			// scores are not unique to make sure documents
+			// with equal values are handled properly
+			err := tx.Exec(ctx, "INSERT INTO test (id, score) VALUES (?, ?)", i, r.Intn(n/2))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	return db
+}
+
+func queryScores(t testing.TB, db *genji.DB, q string) []int {
+	st, err := db.Query(context.Background(), q)
+	require.NoError(t, err)
+	defer st.Close()
+
+	var scores []int
+	err = st.Iterate(func(d document.Document) error {
+		var score int
+		err := document.Scan(d, &score)
+		scores = append(scores, score)
+		return err
+	})
+	require.NoError(t, err)
+
+	return scores
+}
+
+func TestSortTopN(t *testing.T) {
+	db := newSortTestDB(t, 1000)
+	defer db.Close()
+
+	for _, dir := range []string{"ASC", "DESC"} {
+		// with no limit, the whole table is sorted
+		all := queryScores(t, db, fmt.Sprintf("SELECT score FROM test ORDER BY score %s", dir))
+		require.Len(t, all, 1000)
+
+		for _, test := range []struct{ limit, offset int }{{1, 0}, {10, 0}, {10, 5}, {100, 990}, {2000, 0}} {
+			t.Run(fmt.Sprintf("%s LIMIT %d OFFSET %d", dir, test.limit, test.offset), func(t *testing.T) {
+				q := fmt.Sprintf("SELECT score FROM test ORDER BY score %s LIMIT %d OFFSET %d", dir, test.limit, test.offset)
+
+				var buf bytes.Buffer
+				st, err := db.Query(context.Background(), "EXPLAIN "+q)
+				require.NoError(t, err)
+				err = document.IteratorToJSON(&buf, st)
+				st.Close()
+				require.NoError(t, err)
+				require.Contains(t, buf.String(), fmt.Sprintf("top %d", test.limit+test.offset))
+
+				end := test.offset + test.limit
+				if end > len(all) {
+					end = len(all)
+				}
+				require.Equal(t, all[test.offset:end], queryScores(t, db, q))
+			})
+		}
+	}
+}
+
+func TestSortStable(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, "CREATE TABLE test")
+	require.NoError(t, err)
+
+	const n = 500
+	r := rand.New(rand.NewSource(42))
+	err = db.Update(func(tx *genji.Tx) error {
+		for i := 0; i < n; i++ {
+			err := tx.Exec(ctx, "INSERT INTO test (id, a) VALUES (?, {b: ?})", i, r.Intn(10))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// position of each document in the table
+	scanned := make(map[int]int)
+	for i, id := range queryScores(t, db, "SELECT id FROM test") {
+		scanned[id] = i
+	}
+
+	// documents with the same value must be returned in the order
+	// in which they were read from the table
+	check := func(t *testing.T, tx *genji.Tx, q string, desc bool, expected int) {
+		st, err := tx.Query(ctx, q)
+		require.NoError(t, err)
+		defer st.Close()
+
+		var count int
+		var prevB, prevID int
+		err = st.Iterate(func(d document.Document) error {
+			var id, b int
+			err := document.Scan(d, &id, &b)
+			if err != nil {
+				return err
+			}
+
+			if count > 0 {
+				if b == prevB {
+					require.Greater(t, scanned[id], scanned[prevID])
+				} else {
+					require.Equal(t, desc, b < prevB)
+				}
+			}
+
+			count++
+			prevB, prevID = b, id
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, count)
+	}
+
+	for _, dir := range []string{"ASC", "DESC"} {
+		for _, limit := range []int{0, 5, 50} {
+			q := fmt.Sprintf("SELECT id, a.b FROM test ORDER BY a.b %s", dir)
+			expected := n
+			if limit > 0 {
+				q = fmt.Sprintf("%s LIMIT %d", q, limit)
+				expected = limit
+			}
+
+			t.Run(q, func(t *testing.T) {
+				tx, err := db.Begin(false)
+				require.NoError(t, err)
+				defer tx.Rollback()
+
+				check(t, tx, q, dir == "DESC", expected)
+			})
+		}
+
+		t.Run(fmt.Sprintf("%s with spilling", dir), func(t *testing.T) {
+			// sorts run in read-write transactions write the documents
+			// that exceed the limit to temporary stores
+			db.DB.StatementMemoryLimit = 8 * 1024
+			defer func() { db.DB.StatementMemoryLimit = 0 }()
+
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			check(t, tx, fmt.Sprintf("SELECT id, a.b FROM test ORDER BY a.b %s", dir), dir == "DESC", n)
+		})
+	}
+}
+
+func benchmarkSort(b *testing.B, q string) {
+	db := newSortTestDB(b, 100000)
+	defer db.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		st, err := db.Query(context.Background(), q)
+		require.NoError(b, err)
+
+		var count int
+		err = st.Iterate(func(d document.Document) error {
+			count++
+			if count == 10 {
+				return errStop
+			}
+			return nil
+		})
+		if err != errStop {
+			require.NoError(b, err)
+		}
+		st.Close()
+	}
+}
+
+var errStop = fmt.Errorf("stop")
+
+// BenchmarkSortTopN reads the 10 first documents of a sorted table
+// using a heap bounded to 10 elements.
+func BenchmarkSortTopN(b *testing.B) {
+	benchmarkSort(b, "SELECT * FROM test ORDER BY score DESC LIMIT 10")
+}
+
+// BenchmarkSortFull reads the 10 first documents of a sorted table
+// after sorting the entire table.
+func BenchmarkSortFull(b *testing.B) {
+	benchmarkSort(b, "SELECT * FROM test ORDER BY score DESC")
+}
@@ -0,0 +1,234 @@
+package planner
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/engine"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// Documents written to a sorted run are wrapped in a document
+// that also contains their sort key, which can't be computed again
+// once projected documents are copied.
+const (
+	runKeyField         = "key"
+	runCompositeField   = "composite"
+	runDocumentField    = "document"
+	runDocumentKeyField = "document_key"
+)
+
+// spill empties the heap by writing its documents in order to a new temporary store
+// and releases the memory they used.
+func (it *sortIterator) spill(h heap.Interface) error {
+	name, st, err := it.tx.CreateTempStore()
+	if err != nil {
+		return err
+	}
+	it.runs = append(it.runs, sortRun{name: name, st: st})
+
+	codec := it.tx.DB().Codec
+	for i := uint64(0); h.Len() > 0; i++ {
+		node := heap.Pop(h).(heapNode)
+
+		fb := document.NewFieldBuffer().
+			Add(runKeyField, document.NewBlobValue(node.value)).
+			Add(runDocumentField, document.NewDocumentValue(&node.data))
+		if node.composite.Type != 0 {
+			fb.Add(runCompositeField, node.composite)
+		}
+		if node.key != nil {
+			fb.Add(runDocumentKeyField, document.NewBlobValue(node.key))
+		}
+
+		var buf bytes.Buffer
+		err = codec.NewEncoder(&buf).EncodeDocument(fb)
+		if err != nil {
+			return err
+		}
+
+		// runs are read in the order in which they were written
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, i)
+
+		err = st.Put(k, buf.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	it.budget.release(it.reserved)
+	it.reserved = 0
+	return nil
+}
+
+// mergeRuns reads all the sorted runs at the same time and returns their documents in order,
+// using a heap containing the current document of each run.
+func (it *sortIterator) mergeRuns(fn func(d document.Document) error) error {
+	rh := runHeap{desc: it.direction == scanner.DESC}
+	defer func() {
+		for _, c := range rh.cursors {
+			c.it.Close()
+		}
+	}()
+
+	for i, run := range it.runs {
+		c := runCursor{it: run.st.NewIterator(engine.IteratorConfig{}), run: uint64(i)}
+		c.it.Seek(nil)
+		ok, err := c.read(it)
+		if err != nil {
+			c.it.Close()
+			return err
+		}
+		if !ok {
+			c.it.Close()
+			continue
+		}
+
+		rh.cursors = append(rh.cursors, &c)
+	}
+
+	heap.Init(&rh)
+
+	for rh.Len() > 0 {
+		c := rh.cursors[0]
+
+		err := it.emit(c.sortPosition, c.d, fn)
+		if err != nil {
+			return err
+		}
+
+		c.it.Next()
+		ok, err := c.read(it)
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&rh, 0)
+		} else {
+			c.it.Close()
+			heap.Pop(&rh)
+		}
+	}
+
+	return nil
+}
+
+// dropRuns drops the temporary stores created by the sort.
+func (it *sortIterator) dropRuns() error {
+	var err error
+
+	for _, run := range it.runs {
+		if dropErr := it.tx.DropTempStore(run.name); err == nil {
+			err = dropErr
+		}
+	}
+	it.runs = nil
+
+	return err
+}
+
+// sortRun is a temporary store containing documents in the order of the sort.
+type sortRun struct {
+	name []byte
+	st   engine.Store
+}
+
+// runCursor reads the documents of a sorted run.
+type runCursor struct {
+	it engine.Iterator
+	// index of the run. The documents of a run were all read
+	// before the documents of the runs that follow it.
+	run uint64
+
+	// position and document of the current item
+	sortPosition
+	d document.Document
+}
+
+// read decodes the current item of the run. It returns false if the run has no more items.
+func (c *runCursor) read(it *sortIterator) (bool, error) {
+	if !c.it.Valid() {
+		return false, nil
+	}
+
+	// the decoded document references the data until it is returned
+	data, err := c.it.Item().ValueCopy(nil)
+	if err != nil {
+		return false, err
+	}
+	d := it.tx.DB().Codec.NewDocument(data)
+
+	v, err := d.GetByField(runKeyField)
+	if err != nil {
+		return false, err
+	}
+	c.value = v.V.([]byte)
+	c.seq = c.run
+
+	v, err = d.GetByField(runDocumentField)
+	if err != nil {
+		return false, err
+	}
+	c.d = v.V.(document.Document)
+
+	c.key = nil
+	v, err = d.GetByField(runDocumentKeyField)
+	if err != nil && err != document.ErrFieldNotFound {
+		return false, err
+	}
+	if err == nil {
+		c.key = v.V.([]byte)
+	}
+
+	c.composite = document.Value{}
+	v, err = d.GetByField(runCompositeField)
+	if err != nil && err != document.ErrFieldNotFound {
+		return false, err
+	}
+	if err == nil {
+		// copied arrays and documents can be compared without error
+		switch v.Type {
+		case document.ArrayValue:
+			var vb document.ValueBuffer
+			err = vb.Copy(v.V.(document.Array))
+			c.composite = document.NewArrayValue(vb)
+		case document.DocumentValue:
+			var fb document.FieldBuffer
+			err = fb.Copy(v.V.(document.Document))
+			c.composite = document.NewDocumentValue(&fb)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// runHeap orders the cursors of the runs by their current document,
+// in the direction of the sort, then by run.
+type runHeap struct {
+	cursors []*runCursor
+	desc    bool
+}
+
+func (h runHeap) Len() int { return len(h.cursors) }
+func (h runHeap) Less(i, j int) bool {
+	return h.cursors[i].before(h.cursors[j].sortPosition, h.desc)
+}
+func (h runHeap) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+
+func (h *runHeap) Push(x interface{}) {
+	h.cursors = append(h.cursors, x.(*runCursor))
+}
+
+func (h *runHeap) Pop() interface{} {
+	old := h.cursors
+	n := len(old)
+	x := old[n-1]
+	h.cursors = old[0 : n-1]
+	return x
+}
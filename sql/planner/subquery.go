@@ -0,0 +1,276 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// A Subquery is an expression that evaluates to an array containing the values
+// of the single column returned by a SELECT statement.
+// The statement is executed once per transaction and its result is kept in memory,
+// unless the subquery is correlated: if it reads the fields of the documents of
+// an enclosing statement, it is executed again every time it is evaluated.
+type Subquery struct {
+	tree *Tree
+	// scalar subqueries evaluate to the value of their single row.
+	scalar bool
+	// references to the fields of the enclosing statements.
+	refs []*FieldRef
+
+	// transaction in which the result was materialized
+	tx     *database.Transaction
+	values document.ValueBuffer
+}
+
+// NewSubquery creates a subquery from the tree of a SELECT statement.
+// refs are the paths used by the statement that may be qualified by the alias
+// of the table of an enclosing statement.
+// It returns an error if the statement doesn't project exactly one column.
+func NewSubquery(t *Tree, refs ...*FieldRef) (*Subquery, error) {
+	n := t.Root
+	for n != nil && n.Operation() != Projection {
+		n = n.Left()
+	}
+
+	if n == nil {
+		return nil, errors.New("subquery must return exactly one column")
+	}
+
+	pn := n.(*ProjectionNode)
+	if len(pn.Expressions) != 1 {
+		return nil, errors.New("subquery must return exactly one column")
+	}
+	if _, ok := pn.Expressions[0].(Wildcard); ok {
+		return nil, errors.New("subquery must return exactly one column")
+	}
+
+	return &Subquery{tree: t, refs: refs}, nil
+}
+
+// NewScalarSubquery creates a subquery that evaluates to the value returned by
+// a SELECT statement, or NULL if it doesn't return any document.
+// Evaluating it fails if the statement returns more than one document.
+func NewScalarSubquery(t *Tree, refs ...*FieldRef) (*Subquery, error) {
+	s, err := NewSubquery(t, refs...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.scalar = true
+	return s, nil
+}
+
+// Eval executes the subquery, unless its result was already
+// computed within the same transaction, and returns it as an array.
+// Scalar subqueries return the value of their single row instead.
+func (s *Subquery) Eval(stack expr.EvalStack) (document.Value, error) {
+	if s.correlated() {
+		// the current document is the innermost outer document of the subquery
+		params := make([]expr.Param, len(stack.Params), len(stack.Params)+1)
+		copy(params, stack.Params)
+		params = append(params, expr.Param{Value: &outerScope{d: stack.Document, outer: outerScopeOf(stack.Params)}})
+
+		err := s.materialize(stack.Tx, params)
+		if err != nil {
+			return document.Value{}, err
+		}
+	} else if s.values == nil || s.tx != stack.Tx {
+		err := s.materialize(stack.Tx, stack.Params)
+		if err != nil {
+			return document.Value{}, err
+		}
+	}
+
+	if !s.scalar {
+		return document.NewArrayValue(s.values), nil
+	}
+
+	switch len(s.values) {
+	case 0:
+		return document.NewNullValue(), nil
+	case 1:
+		return s.values[0], nil
+	}
+
+	return document.Value{}, errors.New("subquery used as an expression must not return more than one document")
+}
+
+// correlated reports whether the subquery reads the documents of an enclosing statement.
+func (s *Subquery) correlated() bool {
+	for _, r := range s.refs {
+		if r.Depth > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Subquery) materialize(tx *database.Transaction, params []expr.Param) error {
+	t := s.tree.clone()
+	err := Bind(t, tx, params)
+	if err != nil {
+		return err
+	}
+
+	t, err = Optimize(t)
+	if err != nil {
+		return err
+	}
+
+	// an optimized tree with no root doesn't return any document
+	values := document.ValueBuffer{}
+	if t.Root != nil {
+		res, err := t.execute()
+		if err != nil {
+			return err
+		}
+		defer res.Close()
+
+		err = res.Iterate(func(d document.Document) error {
+			var fb document.FieldBuffer
+			err := fb.Copy(d)
+			if err != nil {
+				return err
+			}
+
+			if fb.Len() != 1 {
+				return errors.New("subquery must return exactly one column")
+			}
+
+			return fb.Iterate(func(_ string, v document.Value) error {
+				values = values.Append(v)
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	s.tx, s.values = tx, values
+	return nil
+}
+
+// reset discards the materialized result of the subquery.
+func (s *Subquery) reset() {
+	s.tx, s.values = nil, nil
+}
+
+func (s *Subquery) String() string {
+	return fmt.Sprintf("(%s)", s.tree)
+}
+
+// A FieldRef is a path whose first field may be the alias of the table of a select statement,
+// like u.id in SELECT u.id FROM users u.
+// References are created by the parser when the alias they refer to is not known yet,
+// for instance in subqueries, and resolved once the FROM clause of the statement
+// declaring the alias is parsed.
+// If the alias is declared by an enclosing statement, the reference selects the field
+// of the document being processed by that statement, which makes the subquery correlated.
+// Unresolved references select the whole path in the current document.
+type FieldRef struct {
+	Path document.ValuePath
+	// If Qualified is true, the first field of the path is the alias of a table.
+	Qualified bool
+	// Number of statements between the statement using the reference and the statement
+	// declaring the alias. Zero if the reference reads the current document.
+	Depth int
+}
+
+// Field returns the path of the field selected by the reference, without the alias.
+func (r *FieldRef) Field() document.ValuePath {
+	if r.Qualified {
+		return r.Path[1:]
+	}
+
+	return r.Path
+}
+
+// Eval returns the value of the field in the document selected by the reference.
+// Like field selectors, it returns NULL if the field doesn't exist.
+func (r *FieldRef) Eval(stack expr.EvalStack) (document.Value, error) {
+	if r.Depth == 0 {
+		return expr.FieldSelector(r.Field()).Eval(stack)
+	}
+
+	sc := outerScopeOf(stack.Params)
+	for i := 1; i < r.Depth && sc != nil; i++ {
+		sc = sc.outer
+	}
+	if sc == nil {
+		return document.Value{}, fmt.Errorf("no outer document for %s", r)
+	}
+
+	return expr.FieldSelector(r.Field()).Eval(expr.EvalStack{Tx: stack.Tx, Document: sc.d, Params: stack.Params})
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (r *FieldRef) IsEqual(other expr.Expr) bool {
+	o, ok := other.(*FieldRef)
+	if !ok {
+		return false
+	}
+
+	return r.Path.IsEqual(o.Path) && r.Qualified == o.Qualified && r.Depth == o.Depth
+}
+
+func (r *FieldRef) String() string {
+	return expr.FieldSelector(r.Path).String()
+}
+
+// outerScope holds the document processed by the statement enclosing a correlated subquery.
+// It is passed to the subquery with the parameters of the query, which are available
+// to every expression of the statement.
+type outerScope struct {
+	d document.Document
+	// scope of the statement enclosing the enclosing statement, if any.
+	outer *outerScope
+}
+
+// outerScopeOf returns the innermost outer scope passed with the given parameters, if any.
+func outerScopeOf(params []expr.Param) *outerScope {
+	for i := len(params) - 1; i >= 0; i-- {
+		if sc, ok := params[i].Value.(*outerScope); ok {
+			return sc
+		}
+	}
+
+	return nil
+}
+
+// resetSubqueries discards the result of the subqueries found in e,
+// so that they are executed again on the next evaluation.
+func resetSubqueries(e expr.Expr) {
+	switch t := e.(type) {
+	case *Subquery:
+		t.reset()
+	case expr.Parentheses:
+		resetSubqueries(t.E)
+	case expr.NotOp:
+		resetSubqueries(t.E)
+	case expr.CaseExpr:
+		if t.Operand != nil {
+			resetSubqueries(t.Operand)
+		}
+		for _, w := range t.Whens {
+			resetSubqueries(w.Cond)
+			resetSubqueries(w.Then)
+		}
+		if t.Else != nil {
+			resetSubqueries(t.Else)
+		}
+	case expr.LiteralExprList:
+		for _, e := range t {
+			resetSubqueries(e)
+		}
+	case expr.Operator:
+		resetSubqueries(t.LeftHand())
+		resetSubqueries(t.RightHand())
+	}
+}
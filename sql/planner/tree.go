@@ -7,10 +7,14 @@ package planner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/key"
 	"github.com/genjidb/genji/sql/query"
 	"github.com/genjidb/genji/sql/query/expr"
 )
@@ -42,6 +46,10 @@ const (
 	Set
 	// Unset is an operation that removes a path from every document of a stream
 	Unset
+	// Lock is an operation that acquires write intent on every document of a stream.
+	Lock
+	// Insertion is an operation that inserts every document of a stream in a table.
+	Insertion
 	// Group is an operation that groups documents based on a given path.
 )
 
@@ -58,18 +66,51 @@ func NewTree(n Node) *Tree {
 
 // Run implements the query.Statement interface.
 // It binds the tree to the database resources and executes it.
+// If a query.PageToken is passed alongside the parameters, the statement is paginated.
+// The tree is left untouched: a copy of it is bound and optimized, which allows running
+// prepared statements several times.
 func (t *Tree) Run(ctx context.Context, tx *database.Transaction, params []expr.Param) (query.Result, error) {
+	params, token, paginated := splitPageToken(params)
+
+	orig := t
+	t = t.clone()
 	err := Bind(t, tx, params)
 	if err != nil {
 		return query.Result{}, err
 	}
 
+	var c *pageCursor
+	if paginated {
+		c, err = paginate(t, statementHash(orig, params), tx, params, token)
+		if err != nil {
+			return query.Result{}, err
+		}
+	}
+
 	t, err = Optimize(t)
 	if err != nil {
 		return query.Result{}, err
 	}
 
-	return t.execute()
+	// avoid building the representation of the plan if nobody reads it
+	if tx.DB().Logger != nil {
+		tx.DB().Log(database.Event{Kind: database.EventPlanChosen, TxID: tx.ID(), Plan: t.String()})
+	}
+
+	setMemoryBudget(t, newMemoryBudget(tx.DB().StatementMemoryLimit))
+	setContext(t, ctx)
+
+	res, err := t.execute()
+	if err != nil || c == nil {
+		return res, err
+	}
+
+	res.Stream = res.Stream.Map(func(d document.Document) (document.Document, error) {
+		c.commit()
+		return d, nil
+	})
+	res.Cursor = c
+	return res, nil
 }
 
 func (t *Tree) execute() (query.Result, error) {
@@ -103,6 +144,84 @@ func (t *Tree) String() string {
 	return nodeToString(t.Root)
 }
 
+// clone returns a copy of the tree whose nodes can be bound and optimized
+// without modifying the nodes of t.
+// Expressions are not copied, they must not be modified by the optimizer.
+func (t *Tree) clone() *Tree {
+	return &Tree{Root: cloneNode(t.Root)}
+}
+
+// cloneNode returns a copy of n and of its children.
+func cloneNode(n Node) Node {
+	if n == nil {
+		return nil
+	}
+
+	var c Node
+	switch t := n.(type) {
+	case *tableInputNode:
+		cp := *t
+		c = &cp
+	case *indexInputNode:
+		cp := *t
+		c = &cp
+	case *primaryKeyInputNode:
+		cp := *t
+		c = &cp
+	case *cteInputNode:
+		cp := *t
+		cp.tree = t.tree.clone()
+		c = &cp
+	case *setOperationNode:
+		cp := *t
+		cp.leftTree = t.leftTree.clone()
+		cp.rightTree = t.rightTree.clone()
+		c = &cp
+	case *selectionNode:
+		cp := *t
+		c = &cp
+	case *ProjectionNode:
+		cp := *t
+		c = &cp
+	case *GroupingNode:
+		cp := *t
+		c = &cp
+	case *sortNode:
+		cp := *t
+		c = &cp
+	case *limitNode:
+		cp := *t
+		c = &cp
+	case *offsetNode:
+		cp := *t
+		c = &cp
+	case *lockNode:
+		cp := *t
+		c = &cp
+	case *setNode:
+		cp := *t
+		c = &cp
+	case *unsetNode:
+		cp := *t
+		c = &cp
+	case *deletionNode:
+		cp := *t
+		c = &cp
+	case *replacementNode:
+		cp := *t
+		c = &cp
+	case *insertionNode:
+		cp := *t
+		c = &cp
+	default:
+		panic(fmt.Sprintf("cannot clone node %T", n))
+	}
+
+	c.SetLeft(cloneNode(n.Left()))
+	c.SetRight(cloneNode(n.Right()))
+	return c
+}
+
 func nodeToString(n Node) string {
 	var s string
 
@@ -122,6 +241,112 @@ func (t *Tree) IsReadOnly() bool {
 	return false
 }
 
+// Validate implements the query.Validator interface.
+// It checks that the table read by the tree exists and that the fields modified
+// by Set and Unset nodes would still satisfy the constraints of the table.
+// The values of Set nodes are only checked if they are literals or parameters.
+func (t *Tree) Validate(tx *database.Transaction, params []expr.Param) error {
+	var modifiers []Node
+	var tableName string
+
+	for n := t.Root; n != nil; n = n.Left() {
+		switch n.Operation() {
+		case Set, Unset:
+			modifiers = append(modifiers, n)
+		case Input:
+			if in, ok := n.(*tableInputNode); ok {
+				tableName = in.tableName
+			}
+		}
+	}
+
+	if tableName == "" {
+		return nil
+	}
+
+	tb, err := tx.GetTable(tableName)
+	if errors.Is(err, database.ErrTableNotFound) {
+		return query.ValidationErrors{{TableName: tableName, Err: database.ErrTableNotFound}}
+	}
+	if err != nil {
+		return err
+	}
+
+	info, err := tb.Info()
+	if err != nil {
+		return err
+	}
+
+	var errs query.ValidationErrors
+	for _, n := range modifiers {
+		var path document.ValuePath
+		var e expr.Expr
+
+		switch m := n.(type) {
+		case *setNode:
+			path, e = m.path, m.e
+		case *unsetNode:
+			path = document.ValuePath{document.ValuePathFragment{FieldName: m.field}}
+		}
+
+		err := validateModifiedField(info, path, e, expr.EvalStack{Tx: tx, Params: params})
+		if err != nil {
+			errs = append(errs, &query.ValidationError{TableName: tableName, Path: path, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// validateModifiedField checks that setting the field at the given path to e,
+// or removing it if e is nil, satisfies the field constraints of the table.
+func validateModifiedField(info *database.TableInfo, path document.ValuePath, e expr.Expr, stack expr.EvalStack) error {
+	for _, fc := range info.FieldConstraints {
+		if !fc.Path.IsEqual(path) {
+			continue
+		}
+
+		if e == nil {
+			if fc.IsPrimaryKey {
+				return errors.New("cannot unset the primary key")
+			}
+			if fc.IsNotNull {
+				return errors.New("field is required and must be not null")
+			}
+
+			return nil
+		}
+
+		if !isLiteralOrParam(e) {
+			return nil
+		}
+
+		v, err := e.Eval(stack)
+		if err != nil {
+			return err
+		}
+
+		if v.Type == document.NullValue {
+			if fc.IsNotNull || fc.IsPrimaryKey {
+				return errors.New("field is required and must be not null")
+			}
+
+			return nil
+		}
+
+		if fc.Type != 0 {
+			_, err = v.CastAs(fc.Type)
+			return err
+		}
+	}
+
+	return nil
+}
+
 func nodeToStream(n Node) (st document.Stream, err error) {
 	l := n.Left()
 	if l != nil {
@@ -215,6 +440,7 @@ func NewSelectionNode(n Node, cond expr.Expr) Node {
 func (n *selectionNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
 	n.tx = tx
 	n.params = params
+	resetSubqueries(n.cond)
 	return
 }
 
@@ -250,7 +476,11 @@ func (n *selectionNode) String() string {
 type limitNode struct {
 	node
 
-	limit  int
+	limit int
+	// if set, limit is the result of this expression,
+	// evaluated when the node is bound.
+	limitExpr expr.Expr
+
 	tx     *database.Transaction
 	params []expr.Param
 }
@@ -268,9 +498,26 @@ func NewLimitNode(n Node, limit int) Node {
 	}
 }
 
+// NewLimitExprNode creates a node that limits the number of documents processed by the stream
+// to the result of e. The expression can use the parameters of the query and is evaluated
+// when the node is bound. It must evaluate to a non-negative number.
+func NewLimitExprNode(n Node, e expr.Expr) Node {
+	return &limitNode{
+		node: node{
+			op:   Limit,
+			left: n,
+		},
+		limitExpr: e,
+	}
+}
+
 func (n *limitNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
 	n.tx = tx
 	n.params = params
+
+	if n.limitExpr != nil {
+		n.limit, err = evalLimitExpr("limit", n.limitExpr, tx, params)
+	}
 	return
 }
 
@@ -279,12 +526,19 @@ func (n *limitNode) toStream(st document.Stream) (document.Stream, error) {
 }
 
 func (n *limitNode) String() string {
+	if n.limitExpr != nil && n.tx == nil {
+		return fmt.Sprintf("Limit(%s)", n.limitExpr)
+	}
+
 	return fmt.Sprintf("Limit(%d)", n.limit)
 }
 
 type offsetNode struct {
 	node
 	offset int
+	// if set, offset is the result of this expression,
+	// evaluated when the node is bound.
+	offsetExpr expr.Expr
 
 	tx     *database.Transaction
 	params []expr.Param
@@ -303,13 +557,34 @@ func NewOffsetNode(n Node, offset int) Node {
 	}
 }
 
+// NewOffsetExprNode creates a node that skips as many documents as the result of e.
+// The expression can use the parameters of the query and is evaluated
+// when the node is bound. It must evaluate to a non-negative number.
+func NewOffsetExprNode(n Node, e expr.Expr) Node {
+	return &offsetNode{
+		node: node{
+			op:   Limit,
+			left: n,
+		},
+		offsetExpr: e,
+	}
+}
+
 func (n *offsetNode) String() string {
+	if n.offsetExpr != nil && n.tx == nil {
+		return fmt.Sprintf("Offset(%s)", n.offsetExpr)
+	}
+
 	return fmt.Sprintf("Offset(%d)", n.offset)
 }
 
 func (n *offsetNode) Bind(tx *database.Transaction, params []expr.Param) (err error) {
 	n.tx = tx
 	n.params = params
+
+	if n.offsetExpr != nil {
+		n.offset, err = evalLimitExpr("offset", n.offsetExpr, tx, params)
+	}
 	return
 }
 
@@ -317,6 +592,38 @@ func (n *offsetNode) toStream(st document.Stream) (document.Stream, error) {
 	return st.Offset(n.offset), nil
 }
 
+// evalLimitExpr evaluates the expression of a LIMIT or OFFSET clause.
+// The result must be a non-negative integer. Doubles are only accepted
+// if they don't have a fractional part.
+func evalLimitExpr(clause string, e expr.Expr, tx *database.Transaction, params []expr.Param) (int, error) {
+	v, err := e.Eval(expr.EvalStack{Tx: tx, Params: params})
+	if err != nil {
+		return 0, err
+	}
+
+	if !v.Type.IsNumber() {
+		return 0, fmt.Errorf("%s expression must evaluate to a number, got %q", clause, v.Type)
+	}
+
+	if (v.Type == document.IntegerValue && v.V.(int64) < 0) ||
+		(v.Type == document.DoubleValue && v.V.(float64) < 0) {
+		return 0, fmt.Errorf("%s expression must not be negative, got %v", clause, v)
+	}
+
+	if v.Type == document.DoubleValue {
+		if f := v.V.(float64); f != math.Trunc(f) {
+			return 0, fmt.Errorf("%s expression must be an integer, got %v", clause, v)
+		}
+	}
+
+	v, err = v.CastAsInteger()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(v.V.(int64)), nil
+}
+
 type setNode struct {
 	node
 
@@ -438,25 +745,28 @@ func (n *unsetNode) String() string {
 	return fmt.Sprintf("Unset(%s)", n.field)
 }
 
-// A GroupingNode is a node that groups documents by a given path.
+// A GroupingNode is a node that groups documents by the values of a list of expressions.
 type GroupingNode struct {
 	node
 
-	Expr   expr.Expr
+	Exprs  []expr.Expr
 	Tx     *database.Transaction
 	Params []expr.Param
+
+	// memory budget of the statement, shared with the other nodes.
+	budget *memoryBudget
 }
 
 var _ operationNode = (*GroupingNode)(nil)
 
 // NewGroupingNode creates a GroupingNode.
-func NewGroupingNode(n Node, e expr.Expr) Node {
+func NewGroupingNode(n Node, exprs ...expr.Expr) Node {
 	return &GroupingNode{
 		node: node{
 			op:   Projection,
 			left: n,
 		},
-		Expr: e,
+		Exprs: exprs,
 	}
 }
 
@@ -467,16 +777,68 @@ func (n *GroupingNode) Bind(tx *database.Transaction, params []expr.Param) (err
 	return
 }
 
+// toStream groups the documents by the concatenation of the encoded values of the expressions,
+// which compares numbers by value and is comparable even for arrays and documents.
+// A missing field is grouped with the null values.
+// The aggregation keeps a copy of the first document of every group in memory,
+// which is reserved from the budget of the statement.
 func (n *GroupingNode) toStream(st document.Stream) (document.Stream, error) {
+	values := make([]document.Value, len(n.Exprs))
+	groups := make(map[string]struct{})
+
 	return st.GroupBy(func(d document.Document) (document.Value, error) {
-		return n.Expr.Eval(expr.EvalStack{
+		stack := expr.EvalStack{
 			Tx:       n.Tx,
 			Params:   n.Params,
 			Document: d,
-		})
+		}
+
+		for i, e := range n.Exprs {
+			v, err := e.Eval(stack)
+			if err != nil && err != document.ErrFieldNotFound {
+				return document.Value{}, err
+			}
+			if err == document.ErrFieldNotFound {
+				v = document.NewNullValue()
+			}
+
+			values[i] = v
+		}
+
+		k, err := key.KeyEncodeMulti(values...)
+		if err != nil {
+			return document.Value{}, err
+		}
+
+		if n.budget != nil {
+			if _, ok := groups[string(k)]; !ok {
+				// the groups are kept until the end of the statement
+				size, err := documentSize(d)
+				if err != nil {
+					return document.Value{}, err
+				}
+				err = n.budget.reserve(int64(len(k)) + size)
+				if err != nil {
+					return document.Value{}, err
+				}
+				groups[string(k)] = struct{}{}
+			}
+		}
+
+		// the key is stored as a text so that the group can be used as a map key
+		return document.NewTextValue(string(k)), nil
 	}), nil
 }
 
 func (n *GroupingNode) String() string {
-	return fmt.Sprintf("G(%s)", n.Expr)
+	var b strings.Builder
+
+	for i, e := range n.Exprs {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(fmt.Sprintf("%v", e))
+	}
+
+	return fmt.Sprintf("G(%s)", b.String())
 }
@@ -39,3 +39,22 @@ func (stmt AlterStmt) Run(ctx context.Context, tx *database.Transaction, _ []exp
 	err := tx.RenameTable(stmt.TableName, stmt.NewTableName)
 	return res, err
 }
+
+// Validate checks that the table exists and that no table already uses the new name.
+// It implements the Validator interface.
+func (stmt AlterStmt) Validate(tx *database.Transaction, _ []expr.Param) error {
+	_, err := validateTable(tx, stmt.TableName)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.GetTable(stmt.NewTableName)
+	if err == nil {
+		return ValidationErrors{{TableName: stmt.NewTableName, Err: database.ErrTableAlreadyExists}}
+	}
+	if errors.Is(err, database.ErrTableNotFound) {
+		return nil
+	}
+
+	return err
+}
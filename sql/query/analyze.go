@@ -0,0 +1,42 @@
+package query
+
+import (
+	"context"
+	"errors"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// AnalyzeStmt is a DSL that allows creating a full ANALYZE statement.
+// It recomputes the statistics of all the indexes of the database,
+// of the indexes of a table or of a single index.
+type AnalyzeStmt struct {
+	TableOrIndexName string
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt AnalyzeStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run runs the Analyze statement in the given transaction.
+// It implements the Statement interface.
+func (stmt AnalyzeStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	if stmt.TableOrIndexName == "" {
+		return res, tx.AnalyzeAll()
+	}
+
+	t, err := tx.GetTable(stmt.TableOrIndexName)
+	if err == nil {
+		return res, t.Analyze()
+	}
+	if !errors.Is(err, database.ErrTableNotFound) {
+		return res, err
+	}
+
+	err = tx.Analyze(stmt.TableOrIndexName)
+	return res, err
+}
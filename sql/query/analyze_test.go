@@ -0,0 +1,124 @@
+package query_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/engine/memoryengine"
+	"github.com/genjidb/genji/index"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) *genji.DB {
+		ng := memoryengine.NewEngine()
+		db, err := genji.New(ng)
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test1;
+			CREATE TABLE test2;
+			CREATE INDEX idx_test1_a ON test1(a);
+			CREATE INDEX idx_test1_b ON test1(b);
+			CREATE INDEX idx_test2_a ON test2(a);
+		`)
+		require.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			err = db.Exec(ctx, `INSERT INTO test1(a, b) VALUES (1, ?)`, i)
+			require.NoError(t, err)
+		}
+		err = db.Exec(ctx, `INSERT INTO test2(a) VALUES (1), (2)`)
+		require.NoError(t, err)
+
+		// the statistics are not updated by the inserts
+		return db
+	}
+
+	stats := func(t *testing.T, db *genji.DB) map[string]index.Stats {
+		m := make(map[string]index.Stats)
+
+		err := db.View(func(tx *genji.Tx) error {
+			list, err := tx.ListIndexes()
+			require.NoError(t, err)
+
+			for _, opts := range list {
+				idx, err := tx.GetIndex(opts.IndexName)
+				require.NoError(t, err)
+				m[opts.IndexName], err = idx.Stats()
+				require.NoError(t, err)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		return m
+	}
+
+	analyzed := map[string]index.Stats{
+		"idx_test1_a": {Entries: 10, DistinctValues: 1},
+		"idx_test1_b": {Entries: 10, DistinctValues: 10},
+		"idx_test2_a": {Entries: 2, DistinctValues: 2},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectAnalyzed []string
+		fails          bool
+	}{
+		{"Analyze all", `ANALYZE`, []string{"idx_test1_a", "idx_test1_b", "idx_test2_a"}, false},
+		{"Analyze table", `ANALYZE test1`, []string{"idx_test1_a", "idx_test1_b"}, false},
+		{"Analyze index", `ANALYZE idx_test1_b`, []string{"idx_test1_b"}, false},
+		{"Analyze unknown", `ANALYZE doesntexist`, nil, true},
+		{"Analyze read-only", `ANALYZE __genji_tables`, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := setup(t)
+			defer db.Close()
+
+			err := db.Exec(ctx, test.query)
+			if test.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			expected := map[string]index.Stats{
+				"idx_test1_a": {},
+				"idx_test1_b": {},
+				"idx_test2_a": {},
+			}
+			for _, name := range test.expectAnalyzed {
+				expected[name] = analyzed[name]
+			}
+			require.Equal(t, expected, stats(t, db))
+		})
+	}
+
+	t.Run("Index selection", func(t *testing.T) {
+		db := setup(t)
+		defer db.Close()
+
+		explain := func() string {
+			d, err := db.QueryDocument(ctx, `EXPLAIN SELECT * FROM test1 WHERE b = 5 AND a = 1`)
+			require.NoError(t, err)
+			v, err := d.GetByField("plan")
+			require.NoError(t, err)
+			return v.V.(string)
+		}
+
+		// without statistics, the first candidate index is used
+		require.Contains(t, explain(), "Index(idx_test1_a)")
+
+		err := db.Exec(ctx, `ANALYZE test1`)
+		require.NoError(t, err)
+
+		require.Contains(t, explain(), "Index(idx_test1_b)")
+	})
+}
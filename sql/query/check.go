@@ -0,0 +1,61 @@
+package query
+
+import (
+	"context"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// CheckStmt is a DSL that allows creating a full CHECK statement.
+// It verifies the integrity of a table, or of the whole database if TableName is empty,
+// and returns one document per inconsistency found.
+type CheckStmt struct {
+	TableName string
+}
+
+// IsReadOnly always returns true. It implements the Statement interface.
+func (stmt CheckStmt) IsReadOnly() bool {
+	return true
+}
+
+// Run runs the Check statement in the given transaction.
+// It implements the Statement interface.
+func (stmt CheckStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+	var list []database.Inconsistency
+	var err error
+
+	if stmt.TableName == "" {
+		list, err = tx.Verify()
+	} else {
+		var t *database.Table
+		t, err = tx.GetTable(stmt.TableName)
+		if err != nil {
+			return res, err
+		}
+		list, err = t.Verify()
+	}
+	if err != nil {
+		return res, err
+	}
+
+	docs := make([]document.Document, len(list))
+	for i, inc := range list {
+		fb := document.NewFieldBuffer().
+			Add("table_name", document.NewTextValue(inc.TableName))
+		if inc.IndexName != "" {
+			fb.Add("index_name", document.NewTextValue(inc.IndexName))
+		} else {
+			fb.Add("index_name", document.NewNullValue())
+		}
+		fb.Add("key", document.NewBlobValue(inc.Key)).
+			Add("message", document.NewTextValue(inc.Message))
+
+		docs[i] = fb
+	}
+
+	res.Stream = document.NewStream(document.NewIterator(docs...))
+	return res, nil
+}
@@ -0,0 +1,74 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckStmt(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test(a INTEGER PRIMARY KEY);
+		CREATE TABLE foo;
+		CREATE INDEX idx_test_b ON test(b);
+		INSERT INTO test (a, b) VALUES (1, 'x'), (2, 'y');
+		INSERT INTO foo (a) VALUES (1)
+	`)
+	require.NoError(t, err)
+
+	check := func(q string) string {
+		res, err := db.Query(ctx, q)
+		require.NoError(t, err)
+		defer res.Close()
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, res)
+		require.NoError(t, err)
+		return buf.String()
+	}
+
+	require.Equal(t, `[]`, check("CHECK"))
+
+	// inject a dangling index entry
+	err = db.Update(func(tx *genji.Tx) error {
+		idx, err := tx.GetIndex("idx_test_b")
+		if err != nil {
+			return err
+		}
+
+		tb, err := tx.GetTable("test")
+		if err != nil {
+			return err
+		}
+		k, err := tb.EncodeKey(document.NewIntegerValue(3))
+		if err != nil {
+			return err
+		}
+
+		return idx.Set(document.NewTextValue("z"), k)
+	})
+	require.NoError(t, err)
+
+	res := check("CHECK")
+	require.JSONEq(t, `[{"table_name": "test", "index_name": "idx_test_b", "key": "gAAAAAAAAAM=", "message": "index entry points to a missing document"}]`, res)
+	require.Equal(t, res, check("CHECK test"))
+	require.Equal(t, `[]`, check("CHECK foo"))
+
+	_, err = db.Query(ctx, "CHECK unknown")
+	require.Error(t, err)
+
+	list, err := db.Verify()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "idx_test_b", list[0].IndexName)
+}
@@ -13,7 +13,10 @@ import (
 type CreateTableStmt struct {
 	TableName   string
 	IfNotExists bool
-	Info        database.TableInfo
+	// if true, the table is dropped before being created,
+	// along with its documents and indexes.
+	OrReplace bool
+	Info      database.TableInfo
 }
 
 // IsReadOnly always returns false. It implements the Statement interface.
@@ -30,6 +33,13 @@ func (stmt CreateTableStmt) Run(ctx context.Context, tx *database.Transaction, a
 		return res, errors.New("missing table name")
 	}
 
+	if stmt.OrReplace {
+		err := tx.DropTable(stmt.TableName)
+		if err != nil && !errors.Is(err, database.ErrTableNotFound) {
+			return res, err
+		}
+	}
+
 	err := tx.CreateTable(stmt.TableName, &stmt.Info)
 	if stmt.IfNotExists && err == database.ErrTableAlreadyExists {
 		err = nil
@@ -38,6 +48,27 @@ func (stmt CreateTableStmt) Run(ctx context.Context, tx *database.Transaction, a
 	return res, err
 }
 
+// Validate checks that the table doesn't already exist, unless IfNotExists or OrReplace is true.
+// It implements the Validator interface.
+func (stmt CreateTableStmt) Validate(tx *database.Transaction, args []expr.Param) error {
+	if stmt.TableName == "" {
+		return ValidationErrors{{Err: errors.New("missing table name")}}
+	}
+	if stmt.OrReplace {
+		return nil
+	}
+
+	_, err := tx.GetTable(stmt.TableName)
+	if err == nil && !stmt.IfNotExists {
+		return ValidationErrors{{TableName: stmt.TableName, Err: database.ErrTableAlreadyExists}}
+	}
+	if errors.Is(err, database.ErrTableNotFound) {
+		return nil
+	}
+
+	return err
+}
+
 // CreateIndexStmt is a DSL that allows creating a full CREATE INDEX statement.
 // It is typically created using the CreateIndex function.
 type CreateIndexStmt struct {
@@ -82,3 +113,27 @@ func (stmt CreateIndexStmt) Run(ctx context.Context, tx *database.Transaction, a
 
 	return res, err
 }
+
+// Validate checks that the table exists and that the index doesn't already exist,
+// unless IfNotExists is true.
+// It implements the Validator interface.
+func (stmt CreateIndexStmt) Validate(tx *database.Transaction, args []expr.Param) error {
+	if stmt.IndexName == "" {
+		return ValidationErrors{{TableName: stmt.TableName, Err: errors.New("missing index name")}}
+	}
+
+	_, err := validateTable(tx, stmt.TableName)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.GetIndex(stmt.IndexName)
+	if err == nil && !stmt.IfNotExists {
+		return ValidationErrors{{TableName: stmt.TableName, Path: stmt.Path, Err: database.ErrIndexAlreadyExists}}
+	}
+	if err == database.ErrIndexNotFound {
+		return nil
+	}
+
+	return err
+}
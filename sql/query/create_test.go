@@ -29,6 +29,8 @@ func TestCreateTable(t *testing.T) {
 		{"Exists", "CREATE TABLE test;CREATE TABLE test", true},
 		{"If not exists", "CREATE TABLE IF NOT EXISTS test", false},
 		{"If not exists, twice", "CREATE TABLE IF NOT EXISTS test;CREATE TABLE IF NOT EXISTS test", false},
+		{"Or replace", "CREATE OR REPLACE TABLE test", false},
+		{"Or replace, twice", "CREATE OR REPLACE TABLE test;CREATE OR REPLACE TABLE test(a INTEGER)", false},
 		{"With primary key", "CREATE TABLE test(foo TEXT PRIMARY KEY)", false},
 		{"With field constraints", "CREATE TABLE test(foo.a[1][2] TEXT primary key, bar[4][0].bat INTEGER not null, baz not null)", false},
 		{"With no constraints", "CREATE TABLE test(a, b)", false},
@@ -55,6 +57,77 @@ func TestCreateTable(t *testing.T) {
 		})
 	}
 
+	t.Run("or replace", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test(a INTEGER PRIMARY KEY);
+			CREATE INDEX test_b ON test(b);
+			INSERT INTO test (a, b) VALUES (1, 'foo'), (2, 'bar');
+		`)
+		require.NoError(t, err)
+
+		// the documents and the indexes of the existing table are dropped
+		err = db.Exec(ctx, "CREATE OR REPLACE TABLE test(b TEXT PRIMARY KEY)")
+		require.NoError(t, err)
+
+		_, err = db.QueryDocument(ctx, "SELECT * FROM test")
+		require.Equal(t, database.ErrDocumentNotFound, err)
+
+		err = db.View(func(tx *genji.Tx) error {
+			tb, err := tx.GetTable("test")
+			require.NoError(t, err)
+
+			info, err := tb.Info()
+			require.NoError(t, err)
+			require.Equal(t, parsePath(t, "b"), info.GetPrimaryKey().Path)
+
+			indexes, err := tb.Indexes()
+			require.NoError(t, err)
+			require.Empty(t, indexes)
+
+			_, err = tx.GetIndex("test_b")
+			require.Equal(t, database.ErrIndexNotFound, err)
+			return nil
+		})
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, "INSERT INTO test (b) VALUES ('foo')")
+		require.NoError(t, err)
+
+		t.Run("rolled back", func(t *testing.T) {
+			tx, err := db.Begin(true)
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			err = tx.Exec(ctx, "CREATE OR REPLACE TABLE test(c INTEGER PRIMARY KEY)")
+			require.NoError(t, err)
+			require.NoError(t, tx.Rollback())
+
+			d, err := db.QueryDocument(ctx, "SELECT * FROM test")
+			require.NoError(t, err)
+			data, err := document.MarshalJSON(d)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"b": "foo"}`, string(data))
+		})
+
+		t.Run("referenced by a foreign key", func(t *testing.T) {
+			err := db.Exec(ctx, "CREATE TABLE child(id INTEGER PRIMARY KEY, b TEXT, FOREIGN KEY (b) REFERENCES test(b))")
+			require.NoError(t, err)
+
+			err = db.Exec(ctx, "CREATE OR REPLACE TABLE test")
+			require.Error(t, err)
+
+			d, err := db.QueryDocument(ctx, "SELECT * FROM test")
+			require.NoError(t, err)
+			data, err := document.MarshalJSON(d)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"b": "foo"}`, string(data))
+		})
+	})
+
 	t.Run("constraints", func(t *testing.T) {
 		db, err := genji.Open(":memory:")
 		require.NoError(t, err)
@@ -104,7 +177,7 @@ func TestCreateTable(t *testing.T) {
 				}
 
 				require.Equal(t, []database.FieldConstraint{
-					{Path: parsePath(t, "foo.bar[1].hello"), Type: document.BlobValue, IsPrimaryKey: true},
+					{Path: parsePath(t, "foo.bar[1].hello"), Type: document.BlobValue, IsPrimaryKey: true, IsNotNull: true},
 					{Path: parsePath(t, "foo.a[1][2]"), Type: document.TextValue, IsNotNull: true},
 					{Path: parsePath(t, "bar[4][0].bat"), Type: document.IntegerValue},
 					{Path: parsePath(t, "b"), Type: document.BlobValue},
@@ -124,7 +197,7 @@ func TestCreateTable(t *testing.T) {
 			err = db.Exec(ctx, `
 				CREATE TABLE test2(
 					foo.bar[1].hello bytes PRIMARY KEY, foo.a[1][2] VARCHAR(255) NOT NULL, bar[4][0].bat tinyint,
-				 	dp double precision, r real, b bigint, m mediumint, eight int8, ii int2, c character(64)
+				 	dp double precision, r real, b bigint, m mediumint, eight int8, ii int2, c character(64), bo boolean
 				)
 			`)
 			require.NoError(t, err)
@@ -140,7 +213,7 @@ func TestCreateTable(t *testing.T) {
 				}
 
 				require.Equal(t, []database.FieldConstraint{
-					{Path: parsePath(t, "foo.bar[1].hello"), Type: document.BlobValue, IsPrimaryKey: true},
+					{Path: parsePath(t, "foo.bar[1].hello"), Type: document.BlobValue, IsPrimaryKey: true, IsNotNull: true},
 					{Path: parsePath(t, "foo.a[1][2]"), Type: document.TextValue, IsNotNull: true},
 					{Path: parsePath(t, "bar[4][0].bat"), Type: document.IntegerValue},
 					{Path: parsePath(t, "dp"), Type: document.DoubleValue},
@@ -150,6 +223,7 @@ func TestCreateTable(t *testing.T) {
 					{Path: parsePath(t, "eight"), Type: document.IntegerValue},
 					{Path: parsePath(t, "ii"), Type: document.IntegerValue},
 					{Path: parsePath(t, "c"), Type: document.TextValue},
+					{Path: parsePath(t, "bo"), Type: document.BoolValue},
 				}, info.FieldConstraints)
 				return nil
 			})
@@ -1,12 +1,12 @@
 package query_test
 
 import (
-	"bytes"
 	"context"
 	"testing"
 
 	"github.com/genjidb/genji"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/querytest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -20,10 +20,18 @@ func TestDeleteStmt(t *testing.T) {
 		expected string
 		params   []interface{}
 	}{
-		{"No cond", `DELETE FROM test`, false, "", nil},
-		{"With cond", "DELETE FROM test WHERE b = 'bar1'", false, `{"d": "foo3", "b": "bar2", "e": "bar3"}`, nil},
+		{"No cond", `DELETE FROM test`, false, `[]`, nil},
+		{"With cond", "DELETE FROM test WHERE b = 'bar1'", false, `[{"d": "foo3", "b": "bar2", "e": "bar3"}]`, nil},
+		{"With cond on missing field", "DELETE FROM test WHERE c IS NULL", false, `[{"a": "foo1", "b": "bar1", "c": "baz1"}]`, nil},
 		{"Table not found", "DELETE FROM foo WHERE b = 'bar1'", true, "", nil},
 		{"Read-only table", "DELETE FROM __genji_tables", true, "", nil},
+		{"With limit", "DELETE FROM test LIMIT 2", false, `[{"d": "foo3", "b": "bar2", "e": "bar3"}]`, nil},
+		{"With cond and limit param", "DELETE FROM test WHERE b = 'bar1' LIMIT ?", false, `[{"a": "foo2", "b": "bar1"}, {"d": "foo3", "b": "bar2", "e": "bar3"}]`, []interface{}{1}},
+		{"With limit all", "DELETE FROM test LIMIT ALL", false, `[]`, nil},
+		{"With negative limit", "DELETE FROM test LIMIT -1", true, "", nil},
+		{"With text limit", "DELETE FROM test LIMIT 'a'", true, "", nil},
+		{"With double limit", "DELETE FROM test LIMIT 1.5", true, "", nil},
+		{"With whole double limit param", "DELETE FROM test LIMIT ?", false, `[{"d": "foo3", "b": "bar2", "e": "bar3"}]`, []interface{}{2.0}},
 	}
 
 	for _, test := range tests {
@@ -52,14 +60,29 @@ func TestDeleteStmt(t *testing.T) {
 			require.NoError(t, err)
 			defer st.Close()
 
-			var buf bytes.Buffer
-			err = document.IteratorToJSON(&buf, st)
-			require.NoError(t, err)
-			if len(test.expected) == 0 {
-				require.Equal(t, 0, buf.Len())
-			} else {
-				require.JSONEq(t, test.expected, buf.String())
-			}
+			querytest.AssertResultsEqual(t, st, test.expected)
 		})
 	}
+
+	t.Run("With limit greater than a batch", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test")
+		require.NoError(t, err)
+		for i := 0; i < 250; i++ {
+			err = db.Exec(ctx, "INSERT INTO test (a) VALUES (?)", i)
+			require.NoError(t, err)
+		}
+
+		err = db.Exec(ctx, "DELETE FROM test LIMIT 150")
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument(ctx, "SELECT COUNT(*) FROM test")
+		require.NoError(t, err)
+		var count int
+		require.NoError(t, document.Scan(d, &count))
+		require.Equal(t, 100, count)
+	})
 }
@@ -3,6 +3,7 @@ package query
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/sql/query/expr"
@@ -36,6 +37,17 @@ func (stmt DropTableStmt) Run(ctx context.Context, tx *database.Transaction, arg
 	return res, err
 }
 
+// Validate checks that the table exists, unless IfExists is true.
+// It implements the Validator interface.
+func (stmt DropTableStmt) Validate(tx *database.Transaction, args []expr.Param) error {
+	if stmt.IfExists {
+		return nil
+	}
+
+	_, err := validateTable(tx, stmt.TableName)
+	return err
+}
+
 // DropIndexStmt is a DSL that allows creating a DROP INDEX query.
 type DropIndexStmt struct {
 	IndexName string
@@ -63,3 +75,18 @@ func (stmt DropIndexStmt) Run(ctx context.Context, tx *database.Transaction, arg
 
 	return res, err
 }
+
+// Validate checks that the index exists, unless IfExists is true.
+// It implements the Validator interface.
+func (stmt DropIndexStmt) Validate(tx *database.Transaction, args []expr.Param) error {
+	if stmt.IfExists {
+		return nil
+	}
+
+	_, err := tx.GetIndex(stmt.IndexName)
+	if err == database.ErrIndexNotFound {
+		return ValidationErrors{{Err: fmt.Errorf("%w: %q", err, stmt.IndexName)}}
+	}
+
+	return err
+}
@@ -0,0 +1,109 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/genjidb/genji/document"
+)
+
+// WhenClause is a WHEN ... THEN ... branch of a CASE expression.
+type WhenClause struct {
+	Cond Expr
+	Then Expr
+}
+
+// CaseExpr represents the CASE expression.
+// If Operand is set, it is the simple form (CASE operand WHEN value THEN result ... END)
+// and the operand is compared to the value of each WHEN clause.
+// Otherwise, it is the searched form (CASE WHEN cond THEN result ... END)
+// and the condition of each WHEN clause is evaluated.
+type CaseExpr struct {
+	Operand Expr
+	Whens   []WhenClause
+	Else    Expr
+}
+
+// Eval returns the result of the first WHEN clause that matches,
+// or the ELSE result if none of them do.
+// If there is no ELSE clause, it returns NULL.
+func (c CaseExpr) Eval(ctx EvalStack) (document.Value, error) {
+	var operand document.Value
+	if c.Operand != nil {
+		var err error
+		operand, err = c.Operand.Eval(ctx)
+		if err != nil {
+			return nullLitteral, err
+		}
+	}
+
+	for _, w := range c.Whens {
+		v, err := w.Cond.Eval(ctx)
+		if err != nil {
+			return nullLitteral, err
+		}
+
+		var ok bool
+		if c.Operand != nil {
+			ok, err = operand.IsEqual(v)
+		} else {
+			ok, err = v.IsTruthy()
+		}
+		if err != nil {
+			return nullLitteral, err
+		}
+
+		if ok {
+			return w.Then.Eval(ctx)
+		}
+	}
+
+	if c.Else == nil {
+		return nullLitteral, nil
+	}
+
+	return c.Else.Eval(ctx)
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (c CaseExpr) IsEqual(other Expr) bool {
+	o, ok := other.(CaseExpr)
+	if !ok {
+		return false
+	}
+
+	if !Equal(c.Operand, o.Operand) || !Equal(c.Else, o.Else) {
+		return false
+	}
+
+	if len(c.Whens) != len(o.Whens) {
+		return false
+	}
+
+	for i := range c.Whens {
+		if !Equal(c.Whens[i].Cond, o.Whens[i].Cond) || !Equal(c.Whens[i].Then, o.Whens[i].Then) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c CaseExpr) String() string {
+	var b strings.Builder
+
+	b.WriteString("CASE")
+	if c.Operand != nil {
+		fmt.Fprintf(&b, " %v", c.Operand)
+	}
+	for _, w := range c.Whens {
+		fmt.Fprintf(&b, " WHEN %v THEN %v", w.Cond, w.Then)
+	}
+	if c.Else != nil {
+		fmt.Fprintf(&b, " ELSE %v", c.Else)
+	}
+	b.WriteString(" END")
+
+	return b.String()
+}
@@ -0,0 +1,31 @@
+package expr_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/document"
+)
+
+func TestCaseExpr(t *testing.T) {
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"CASE WHEN a = 1 THEN 'one' ELSE 'other' END", document.NewTextValue("one"), false},
+		{"CASE WHEN a = 2 THEN 'two' ELSE 'other' END", document.NewTextValue("other"), false},
+		{"CASE WHEN a > 5 THEN 'big' WHEN a > 0 THEN 'small' END", document.NewTextValue("small"), false},
+		{"CASE WHEN a > 5 THEN 'big' END", nullLitteral, false},
+		{"CASE WHEN notFound THEN 1 ELSE 2 END", document.NewIntegerValue(2), false},
+		{"CASE a WHEN 2 THEN 'two' WHEN 1 THEN 'one' END", document.NewTextValue("one"), false},
+		{"CASE a WHEN 2 THEN 'two' ELSE a + 1 END", document.NewIntegerValue(2), false},
+		{"CASE notFound WHEN NULL THEN 1 ELSE 2 END", document.NewIntegerValue(1), false},
+		{"CASE WHEN a = 1 THEN 1 / 0 END", nullLitteral, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, stackWithDoc, test.res, test.fails)
+		})
+	}
+}
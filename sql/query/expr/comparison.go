@@ -60,7 +60,7 @@ func (op eqOp) IteratePK(tb *database.Table, v document.Value, pkType document.V
 		return nil
 	}
 
-	data, err := key.AppendValue(nil, v)
+	data, err := key.KeyEncode(v)
 	if err != nil {
 		return err
 	}
@@ -129,7 +129,7 @@ func (op gtOp) IteratePK(tb *database.Table, v document.Value, pkType document.V
 		return err
 	}
 
-	data, err := key.AppendValue(nil, v)
+	data, err := key.KeyEncode(v)
 	if err != nil {
 		return err
 	}
@@ -192,7 +192,7 @@ func (op gteOp) IteratePK(tb *database.Table, v document.Value, pkType document.
 		return err
 	}
 
-	data, err := key.AppendValue(nil, v)
+	data, err := key.KeyEncode(v)
 	if err != nil {
 		return err
 	}
@@ -239,7 +239,7 @@ func (op ltOp) IterateIndex(idx *database.Index, tb *database.Table, v document.
 		}
 	}
 
-	enc, err := key.AppendValue(nil, v)
+	enc, err := idx.EncodeValue(v)
 	if err != nil {
 		return err
 	}
@@ -269,7 +269,7 @@ func (op ltOp) IteratePK(tb *database.Table, v document.Value, pkType document.V
 		return err
 	}
 
-	data, err := key.AppendValue(nil, v)
+	data, err := key.KeyEncode(v)
 	if err != nil {
 		return err
 	}
@@ -319,7 +319,7 @@ func (op lteOp) IterateIndex(idx *database.Index, tb *database.Table, v document
 		}
 	}
 
-	enc, err := key.AppendValue(nil, v)
+	enc, err := idx.EncodeValue(v)
 	if err != nil {
 		return err
 	}
@@ -350,7 +350,7 @@ func (op lteOp) IteratePK(tb *database.Table, v document.Value, pkType document.
 		return err
 	}
 
-	data, err := key.AppendValue(nil, v)
+	data, err := key.KeyEncode(v)
 	if err != nil {
 		return err
 	}
@@ -381,6 +381,19 @@ func (op lteOp) String() string {
 	return fmt.Sprintf("%v <= %v", op.a, op.b)
 }
 
+// Between creates an expression that returns true if a is greater than or equal to low
+// and lesser than or equal to high. It is expressed using the >= and <= operators,
+// so that it benefits from the same optimizations.
+func Between(a, low, high Expr) Expr {
+	return And(Gte(a, low), Lte(a, high))
+}
+
+// NotBetween creates an expression that returns true if a is lesser than low
+// or greater than high.
+func NotBetween(a, low, high Expr) Expr {
+	return Or(Lt(a, low), Gt(a, high))
+}
+
 // Eval compares a and b together using the operator specified when constructing the CmpOp
 // and returns the result of the comparison.
 // Comparing with NULL always evaluates to NULL.
@@ -403,7 +416,12 @@ func (op cmpOp) Eval(ctx EvalStack) (document.Value, error) {
 }
 
 func (op cmpOp) compare(l, r document.Value) (bool, error) {
-	switch op.Tok {
+	return compare(op.Tok, l, r)
+}
+
+// compare l and r using the comparison operator represented by tok.
+func compare(tok scanner.Token, l, r document.Value) (bool, error) {
+	switch tok {
 	case scanner.EQ:
 		return l.IsEqual(r)
 	case scanner.NEQ:
@@ -417,16 +435,16 @@ func (op cmpOp) compare(l, r document.Value) (bool, error) {
 	case scanner.LTE:
 		return l.IsLesserThanOrEqual(r)
 	default:
-		panic(fmt.Sprintf("unknown token %v", op.Tok))
+		panic(fmt.Sprintf("unknown token %v", tok))
 	}
 }
 
 // IsComparisonOperator returns true if e is one of
-// =, !=, >, >=, <, <=, IS, IS NOT, IN, or NOT IN operators.
+// =, !=, >, >=, <, <=, IS, IS NOT, IS DISTINCT FROM, IS NOT DISTINCT FROM, IN, NOT IN or ANY operators.
 func IsComparisonOperator(op Operator) bool {
 	switch op.(type) {
 	case eqOp, neqOp, gtOp, gteOp, ltOp, lteOp,
-		isOp, isNotOp, inOp, notInOp:
+		isOp, isNotOp, *isDistinctFromOp, *isNotDistinctFromOp, inOp, notInOp, *anyOp:
 		return true
 	}
 
@@ -509,7 +527,7 @@ func (op inOp) IteratePK(tb *database.Table, v document.Value, pkType document.V
 			return nil
 		}
 
-		data, err := key.AppendValue(nil, val)
+		data, err := key.KeyEncode(val)
 		if err != nil {
 			return err
 		}
@@ -557,6 +575,71 @@ func (op notInOp) String() string {
 	return fmt.Sprintf("%v NOT IN %v", op.a, op.b)
 }
 
+type anyOp struct {
+	*simpleOperator
+	cmp scanner.Token
+}
+
+// Any creates an expression that evaluates to true if at least one element of the array a
+// satisfies the comparison with b, using the operator represented by cmp,
+// which must be one of =, !=, >, >=, < or <=.
+// NULL elements never satisfy the comparison. If a is not an array, it evaluates to false.
+func Any(a, b Expr, cmp scanner.Token) Expr {
+	return &anyOp{&simpleOperator{a, b, scanner.ANY}, cmp}
+}
+
+func (op anyOp) Eval(ctx EvalStack) (document.Value, error) {
+	a, b, err := op.simpleOperator.eval(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	if a.Type != document.ArrayValue {
+		return falseLitteral, nil
+	}
+
+	if b.Type == document.NullValue {
+		return nullLitteral, nil
+	}
+
+	var found bool
+	err = a.V.(document.Array).Iterate(func(i int, v document.Value) error {
+		if v.Type == document.NullValue {
+			return nil
+		}
+
+		ok, err := compare(op.cmp, v, b)
+		if err != nil {
+			return err
+		}
+		if ok {
+			found = true
+			return errStop
+		}
+
+		return nil
+	})
+	if err != nil && err != errStop {
+		return nullLitteral, err
+	}
+
+	if found {
+		return trueLitteral, nil
+	}
+	return falseLitteral, nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (op anyOp) IsEqual(other Expr) bool {
+	o, ok := other.(*anyOp)
+	return ok && op.cmp == o.cmp && op.simpleOperator.IsEqual(o)
+}
+
+func (op anyOp) String() string {
+	return fmt.Sprintf("%v ANY %v %v", op.a, op.cmp, op.b)
+}
+
 type isOp struct {
 	*simpleOperator
 }
@@ -616,3 +699,93 @@ func (op isNotOp) Eval(ctx EvalStack) (document.Value, error) {
 func (op isNotOp) String() string {
 	return fmt.Sprintf("%v IS NOT %v", op.a, op.b)
 }
+
+type isDistinctFromOp struct {
+	*simpleOperator
+}
+
+// IsDistinctFrom creates an expression that evaluates to the result of a IS DISTINCT FROM b.
+// Unlike the != operator, NULL values are compared like any other value: two NULL values
+// are not distinct from each other but they are distinct from any non-NULL value.
+// It always evaluates to either true or false.
+func IsDistinctFrom(a, b Expr) Expr {
+	return &isDistinctFromOp{&simpleOperator{a, b, scanner.IS}}
+}
+
+func (op isDistinctFromOp) Eval(ctx EvalStack) (document.Value, error) {
+	a, b, err := op.simpleOperator.eval(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	ok, err := isNotDistinct(a, b)
+	if err != nil {
+		return nullLitteral, err
+	}
+	if ok {
+		return falseLitteral, nil
+	}
+
+	return trueLitteral, nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (op isDistinctFromOp) IsEqual(other Expr) bool {
+	o, ok := other.(*isDistinctFromOp)
+	return ok && op.simpleOperator.IsEqual(o)
+}
+
+func (op isDistinctFromOp) String() string {
+	return fmt.Sprintf("%v IS DISTINCT FROM %v", op.a, op.b)
+}
+
+type isNotDistinctFromOp struct {
+	*simpleOperator
+}
+
+// IsNotDistinctFrom creates an expression that evaluates to the result of a IS NOT DISTINCT FROM b.
+// Unlike the = operator, two NULL values are considered equal and a NULL value is never equal
+// to a non-NULL value.
+// It always evaluates to either true or false.
+func IsNotDistinctFrom(a, b Expr) Expr {
+	return &isNotDistinctFromOp{&simpleOperator{a, b, scanner.IS}}
+}
+
+func (op isNotDistinctFromOp) Eval(ctx EvalStack) (document.Value, error) {
+	a, b, err := op.simpleOperator.eval(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	ok, err := isNotDistinct(a, b)
+	if err != nil {
+		return nullLitteral, err
+	}
+	if ok {
+		return trueLitteral, nil
+	}
+
+	return falseLitteral, nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (op isNotDistinctFromOp) IsEqual(other Expr) bool {
+	o, ok := other.(*isNotDistinctFromOp)
+	return ok && op.simpleOperator.IsEqual(o)
+}
+
+func (op isNotDistinctFromOp) String() string {
+	return fmt.Sprintf("%v IS NOT DISTINCT FROM %v", op.a, op.b)
+}
+
+// isNotDistinct reports whether a and b are equal, treating
+// NULL as a regular value.
+func isNotDistinct(a, b document.Value) (bool, error) {
+	if a.Type == document.NullValue || b.Type == document.NullValue {
+		return a.Type == b.Type, nil
+	}
+
+	return a.IsEqual(b)
+}
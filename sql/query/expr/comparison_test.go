@@ -89,6 +89,37 @@ func TestComparisonNOTINExpr(t *testing.T) {
 	}
 }
 
+func TestComparisonANYExpr(t *testing.T) {
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"[1, 2, 3] ANY = 2", document.NewBoolValue(true), false},
+		{"[1, 2, 3] ANY = 4", document.NewBoolValue(false), false},
+		{"[1, 2, 3] ANY != 1", document.NewBoolValue(true), false},
+		{"[1, 1] ANY != 1", document.NewBoolValue(false), false},
+		{"[1, 2, 3] ANY > 2", document.NewBoolValue(true), false},
+		{"[1, 2, 3] ANY >= 4", document.NewBoolValue(false), false},
+		{"[1, 2, 3] ANY < 2", document.NewBoolValue(true), false},
+		{"[1, 2, 3] ANY <= 0", document.NewBoolValue(false), false},
+		{"['go', 'rust'] ANY = 'go'", document.NewBoolValue(true), false},
+		{"[[1], [2]] ANY = [2]", document.NewBoolValue(true), false},
+		{"[] ANY = 1", document.NewBoolValue(false), false},
+		{"[NULL, 1] ANY != 1", document.NewBoolValue(false), false},
+		{"1 ANY = 1", document.NewBoolValue(false), false},
+		{"{a: 1} ANY = 1", document.NewBoolValue(false), false},
+		{"NULL ANY = 1", document.NewBoolValue(false), false},
+		{"[1, 2] ANY = NULL", nullLitteral, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, stackWithDoc, test.res, test.fails)
+		})
+	}
+}
+
 func TestComparisonISExpr(t *testing.T) {
 	tests := []struct {
 		expr  string
@@ -129,6 +160,54 @@ func TestComparisonISNOTExpr(t *testing.T) {
 	}
 }
 
+func TestComparisonISDISTINCTFROMExpr(t *testing.T) {
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"NULL IS DISTINCT FROM NULL", document.NewBoolValue(false), false},
+		{"NULL IS DISTINCT FROM 1", document.NewBoolValue(true), false},
+		{"1 IS DISTINCT FROM NULL", document.NewBoolValue(true), false},
+		{"1 IS DISTINCT FROM 1", document.NewBoolValue(false), false},
+		{"1 IS DISTINCT FROM 1.0", document.NewBoolValue(false), false},
+		{"1 IS DISTINCT FROM 2", document.NewBoolValue(true), false},
+		{"1 IS DISTINCT FROM 'a'", document.NewBoolValue(true), false},
+		{"a IS DISTINCT FROM 1", document.NewBoolValue(false), false},
+		{"notFound IS DISTINCT FROM NULL", document.NewBoolValue(false), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, stackWithDoc, test.res, test.fails)
+		})
+	}
+}
+
+func TestComparisonISNOTDISTINCTFROMExpr(t *testing.T) {
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"NULL IS NOT DISTINCT FROM NULL", document.NewBoolValue(true), false},
+		{"NULL IS NOT DISTINCT FROM 1", document.NewBoolValue(false), false},
+		{"1 IS NOT DISTINCT FROM NULL", document.NewBoolValue(false), false},
+		{"1 IS NOT DISTINCT FROM 1", document.NewBoolValue(true), false},
+		{"1 IS NOT DISTINCT FROM 1.0", document.NewBoolValue(true), false},
+		{"1 IS NOT DISTINCT FROM 2", document.NewBoolValue(false), false},
+		{"1 IS NOT DISTINCT FROM 'a'", document.NewBoolValue(false), false},
+		{"a IS NOT DISTINCT FROM 1", document.NewBoolValue(true), false},
+		{"notFound IS NOT DISTINCT FROM NULL", document.NewBoolValue(true), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, stackWithDoc, test.res, test.fails)
+		})
+	}
+}
+
 func TestComparisonExprNodocument(t *testing.T) {
 	tests := []struct {
 		expr  string
@@ -0,0 +1,118 @@
+package expr
+
+import (
+	"fmt"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+)
+
+func init() {
+	database.RegisterTableExpressionDecoder(func(d document.Document) (database.TableExpression, error) {
+		e, err := FromDocument(d)
+		if err != nil {
+			return nil, err
+		}
+
+		return Constraint(e), nil
+	})
+}
+
+// A ConstraintExpr is an expression used as a table constraint, such as
+// the default value of a field.
+// It implements the database.TableExpression interface.
+type ConstraintExpr struct {
+	Expr Expr
+}
+
+// Constraint creates a constraint expression from e.
+func Constraint(e Expr) *ConstraintExpr {
+	return &ConstraintExpr{Expr: e}
+}
+
+// Eval evaluates the underlying expression within the given transaction.
+func (t *ConstraintExpr) Eval(tx *database.Transaction) (document.Value, error) {
+	return t.Expr.Eval(EvalStack{
+		Tx: tx,
+	})
+}
+
+// ToDocument encodes the underlying expression in a document.
+func (t *ConstraintExpr) ToDocument() (document.Document, error) {
+	return ToDocument(t.Expr)
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (t *ConstraintExpr) IsEqual(other *ConstraintExpr) bool {
+	if other == nil {
+		return false
+	}
+
+	return Equal(t.Expr, other.Expr)
+}
+
+// String implements the fmt.Stringer interface.
+func (t *ConstraintExpr) String() string {
+	return fmt.Sprintf("%v", t.Expr)
+}
+
+// IsConstraintExpr reports whether e can be stored as a table constraint.
+// Constraints are evaluated without any document, so they cannot
+// reference fields, parameters or aggregate functions.
+func IsConstraintExpr(e Expr) bool {
+	switch t := e.(type) {
+	case LiteralValue:
+		return true
+	case FieldSelector, NamedParam, PositionalParam, PKFunc, *PKFunc,
+		*CountFunc, *CountDistinctFunc, *MinFunc, *MaxFunc, *SumFunc, *AvgFunc, *UserAggregateFunc:
+		return false
+	case Parentheses:
+		return IsConstraintExpr(t.E)
+	case NotOp:
+		return IsConstraintExpr(t.E)
+	case CastFunc:
+		return IsConstraintExpr(t.Expr)
+	case CaseExpr:
+		if t.Operand != nil && !IsConstraintExpr(t.Operand) {
+			return false
+		}
+		if t.Else != nil && !IsConstraintExpr(t.Else) {
+			return false
+		}
+		for _, w := range t.Whens {
+			if !IsConstraintExpr(w.Cond) || !IsConstraintExpr(w.Then) {
+				return false
+			}
+		}
+		return true
+	case LiteralExprList:
+		return areConstraintExprs(t)
+	case KVPairs:
+		for _, kv := range t {
+			if !IsConstraintExpr(kv.V) {
+				return false
+			}
+		}
+		return true
+	case Function:
+		return areConstraintExprs(t.Args())
+	case Operator:
+		if _, ok := operatorName(t); !ok {
+			return false
+		}
+		return IsConstraintExpr(t.LeftHand()) && IsConstraintExpr(t.RightHand())
+	}
+
+	return false
+}
+
+func areConstraintExprs(l []Expr) bool {
+	for _, e := range l {
+		if !IsConstraintExpr(e) {
+			return false
+		}
+	}
+
+	return true
+}
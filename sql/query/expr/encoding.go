@@ -0,0 +1,510 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/scanner"
+)
+
+// operators maps the name of every binary operator, as stored by ToDocument,
+// to the function used to create it.
+var operators = map[string]func(a, b Expr) Expr{
+	"=":                    Eq,
+	"!=":                   Neq,
+	">":                    Gt,
+	">=":                   Gte,
+	"<":                    Lt,
+	"<=":                   Lte,
+	"IN":                   In,
+	"NOT IN":               NotIn,
+	"ANY =":                anyOperator(scanner.EQ),
+	"ANY !=":               anyOperator(scanner.NEQ),
+	"ANY >":                anyOperator(scanner.GT),
+	"ANY >=":               anyOperator(scanner.GTE),
+	"ANY <":                anyOperator(scanner.LT),
+	"ANY <=":               anyOperator(scanner.LTE),
+	"IS":                   Is,
+	"IS NOT":               IsNot,
+	"IS DISTINCT FROM":     IsDistinctFrom,
+	"IS NOT DISTINCT FROM": IsNotDistinctFrom,
+	"AND":                  And,
+	"OR":                   Or,
+	"+":                    Add,
+	"-":                    Sub,
+	"*":                    Mul,
+	"/":                    Div,
+	"%":                    Mod,
+	"&":                    BitwiseAnd,
+	"|":                    BitwiseOr,
+	"^":                    BitwiseXor,
+}
+
+// anyOperator returns a function creating ANY operators using the cmp comparison operator.
+func anyOperator(cmp scanner.Token) func(a, b Expr) Expr {
+	return func(a, b Expr) Expr {
+		return Any(a, b, cmp)
+	}
+}
+
+// operatorName returns the name under which op is stored.
+func operatorName(op Operator) (string, bool) {
+	switch t := op.(type) {
+	case eqOp:
+		return "=", true
+	case neqOp:
+		return "!=", true
+	case gtOp:
+		return ">", true
+	case gteOp:
+		return ">=", true
+	case ltOp:
+		return "<", true
+	case lteOp:
+		return "<=", true
+	case inOp:
+		return "IN", true
+	case *notInOp:
+		return "NOT IN", true
+	case *anyOp:
+		return "ANY " + t.cmp.String(), true
+	case *isOp:
+		return "IS", true
+	case *isNotOp:
+		return "IS NOT", true
+	case *isDistinctFromOp:
+		return "IS DISTINCT FROM", true
+	case *isNotDistinctFromOp:
+		return "IS NOT DISTINCT FROM", true
+	case *AndOp:
+		return "AND", true
+	case *OrOp:
+		return "OR", true
+	case *addOp:
+		return "+", true
+	case *subOp:
+		return "-", true
+	case *mulOp:
+		return "*", true
+	case *divOp:
+		return "/", true
+	case *modOp:
+		return "%", true
+	case *bitwiseAndOp:
+		return "&", true
+	case *bitwiseOrOp:
+		return "|", true
+	case *bitwiseXorOp:
+		return "^", true
+	}
+
+	return "", false
+}
+
+// ToDocument encodes an expression tree in a document, which allows
+// expressions to be stored alongside the configuration of tables and indexes.
+// Every node is encoded as a document whose first field describes its kind.
+// The expression can be decoded using FromDocument.
+func ToDocument(e Expr) (document.Document, error) {
+	fb := document.NewFieldBuffer()
+
+	switch t := e.(type) {
+	case LiteralValue:
+		fb.Add("literal", document.Value(t))
+	case FieldSelector:
+		fb.Add("path", document.NewArrayValue(valuePathToArray(document.ValuePath(t))))
+	case NamedParam:
+		fb.Add("named_param", document.NewTextValue(string(t)))
+	case PositionalParam:
+		fb.Add("positional_param", document.NewIntegerValue(int64(t)))
+	case Parentheses:
+		d, err := ToDocument(t.E)
+		if err != nil {
+			return nil, err
+		}
+		fb.Add("parentheses", document.NewDocumentValue(d))
+	case NotOp:
+		d, err := ToDocument(t.E)
+		if err != nil {
+			return nil, err
+		}
+		fb.Add("not", document.NewDocumentValue(d))
+	case LiteralExprList:
+		list, err := exprListToArray(t)
+		if err != nil {
+			return nil, err
+		}
+		fb.Add("list", document.NewArrayValue(list))
+	case KVPairs:
+		pairs := document.NewValueBuffer()
+		for _, kv := range t {
+			d, err := ToDocument(kv.V)
+			if err != nil {
+				return nil, err
+			}
+
+			pair := document.NewFieldBuffer().
+				Add("key", document.NewTextValue(kv.K)).
+				Add("value", document.NewDocumentValue(d))
+			pairs = pairs.Append(document.NewDocumentValue(pair))
+		}
+		fb.Add("document", document.NewArrayValue(pairs))
+	case CastFunc:
+		d, err := ToDocument(t.Expr)
+		if err != nil {
+			return nil, err
+		}
+		fb.Add("cast", document.NewDocumentValue(d))
+		fb.Add("as", document.NewIntegerValue(int64(t.CastAs)))
+	case CaseExpr:
+		whens := document.NewValueBuffer()
+		for _, w := range t.Whens {
+			cond, err := ToDocument(w.Cond)
+			if err != nil {
+				return nil, err
+			}
+			then, err := ToDocument(w.Then)
+			if err != nil {
+				return nil, err
+			}
+
+			clause := document.NewFieldBuffer().
+				Add("when", document.NewDocumentValue(cond)).
+				Add("then", document.NewDocumentValue(then))
+			whens = whens.Append(document.NewDocumentValue(clause))
+		}
+		fb.Add("case", document.NewArrayValue(whens))
+
+		if t.Operand != nil {
+			d, err := ToDocument(t.Operand)
+			if err != nil {
+				return nil, err
+			}
+			fb.Add("operand", document.NewDocumentValue(d))
+		}
+		if t.Else != nil {
+			d, err := ToDocument(t.Else)
+			if err != nil {
+				return nil, err
+			}
+			fb.Add("else", document.NewDocumentValue(d))
+		}
+	case *CountFunc:
+		if !t.Wildcard {
+			return encodeFunction(fb, t)
+		}
+		fb.Add("function", document.NewTextValue(t.Name()))
+		fb.Add("wildcard", document.NewBoolValue(true))
+	case *CountDistinctFunc:
+		_, err := encodeFunction(fb, t)
+		if err != nil {
+			return nil, err
+		}
+		fb.Add("distinct", document.NewBoolValue(true))
+	case UserFunc:
+		_, err := encodeFunction(fb, t)
+		if err != nil {
+			return nil, err
+		}
+		if t.Distinct {
+			fb.Add("distinct", document.NewBoolValue(true))
+		}
+	case *UserAggregateFunc:
+		// the function is looked up again when the decoded expression is bound
+		return ToDocument(UserFunc{FuncName: t.FuncName, Exprs: t.Exprs, Distinct: t.Distinct})
+	case Function:
+		return encodeFunction(fb, t)
+	case Operator:
+		name, ok := operatorName(t)
+		if !ok {
+			return nil, fmt.Errorf("cannot encode expression %v", e)
+		}
+
+		a, err := ToDocument(t.LeftHand())
+		if err != nil {
+			return nil, err
+		}
+		b, err := ToDocument(t.RightHand())
+		if err != nil {
+			return nil, err
+		}
+
+		fb.Add("operator", document.NewTextValue(name))
+		fb.Add("a", document.NewDocumentValue(a))
+		fb.Add("b", document.NewDocumentValue(b))
+	default:
+		return nil, fmt.Errorf("cannot encode expression %v", e)
+	}
+
+	return fb, nil
+}
+
+func encodeFunction(fb *document.FieldBuffer, f Function) (document.Document, error) {
+	args, err := exprListToArray(f.Args())
+	if err != nil {
+		return nil, err
+	}
+
+	fb.Add("function", document.NewTextValue(f.Name()))
+	fb.Add("args", document.NewArrayValue(args))
+	return fb, nil
+}
+
+func exprListToArray(l []Expr) (document.Array, error) {
+	vb := document.NewValueBuffer()
+	for _, e := range l {
+		d, err := ToDocument(e)
+		if err != nil {
+			return nil, err
+		}
+		vb = vb.Append(document.NewDocumentValue(d))
+	}
+
+	return vb, nil
+}
+
+// FromDocument decodes an expression encoded with ToDocument.
+func FromDocument(d document.Document) (Expr, error) {
+	// the kind of the expression is determined by the first field
+	var kind string
+	var v document.Value
+	err := d.Iterate(func(field string, value document.Value) error {
+		kind, v = field, value
+		return errStop
+	})
+	if err != nil && err != errStop {
+		return nil, err
+	}
+
+	switch kind {
+	case "literal":
+		// the value may be backed by the buffer of the decoder,
+		// make a copy to make sure it remains valid
+		return LiteralValue(copyValue(v)), nil
+	case "path":
+		path, err := arrayToValuePath(v)
+		if err != nil {
+			return nil, err
+		}
+		return FieldSelector(path), nil
+	case "named_param":
+		return NamedParam(v.V.(string)), nil
+	case "positional_param":
+		return PositionalParam(v.V.(int64)), nil
+	case "parentheses":
+		e, err := FromDocument(v.V.(document.Document))
+		if err != nil {
+			return nil, err
+		}
+		return Parentheses{E: e}, nil
+	case "not":
+		e, err := FromDocument(v.V.(document.Document))
+		if err != nil {
+			return nil, err
+		}
+		return Not(e), nil
+	case "list":
+		return arrayToExprList(v)
+	case "document":
+		var kvp KVPairs
+		err := v.V.(document.Array).Iterate(func(_ int, value document.Value) error {
+			pair := value.V.(document.Document)
+
+			k, err := pair.GetByField("key")
+			if err != nil {
+				return err
+			}
+			vv, err := pair.GetByField("value")
+			if err != nil {
+				return err
+			}
+
+			e, err := FromDocument(vv.V.(document.Document))
+			if err != nil {
+				return err
+			}
+
+			kvp = append(kvp, KVPair{K: k.V.(string), V: e})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return kvp, nil
+	case "cast":
+		e, err := FromDocument(v.V.(document.Document))
+		if err != nil {
+			return nil, err
+		}
+
+		as, err := d.GetByField("as")
+		if err != nil {
+			return nil, err
+		}
+
+		return CastFunc{Expr: e, CastAs: document.ValueType(as.V.(int64))}, nil
+	case "case":
+		var c CaseExpr
+		err := v.V.(document.Array).Iterate(func(_ int, value document.Value) error {
+			clause := value.V.(document.Document)
+
+			cond, err := decodeField(clause, "when")
+			if err != nil {
+				return err
+			}
+			then, err := decodeField(clause, "then")
+			if err != nil {
+				return err
+			}
+
+			c.Whens = append(c.Whens, WhenClause{Cond: cond, Then: then})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := d.GetByField("operand"); err == nil {
+			c.Operand, err = decodeField(d, "operand")
+			if err != nil {
+				return nil, err
+			}
+		}
+		if _, err := d.GetByField("else"); err == nil {
+			c.Else, err = decodeField(d, "else")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return c, nil
+	case "function":
+		name := v.V.(string)
+
+		if w, err := d.GetByField("wildcard"); err == nil && w.V.(bool) {
+			return &CountFunc{Wildcard: true}, nil
+		}
+
+		args, err := d.GetByField("args")
+		if err != nil {
+			return nil, err
+		}
+
+		l, err := arrayToExprList(args)
+		if err != nil {
+			return nil, err
+		}
+
+		if dst, err := d.GetByField("distinct"); err == nil && dst.V.(bool) {
+			if name == "count" {
+				return &CountDistinctFunc{Exprs: l}, nil
+			}
+
+			return UserFunc{FuncName: name, Exprs: l, Distinct: true}, nil
+		}
+
+		return NewFunctions().GetFunc(name, l...)
+	case "operator":
+		fn, ok := operators[v.V.(string)]
+		if !ok {
+			return nil, fmt.Errorf("unknown operator %q", v.V)
+		}
+
+		a, err := d.GetByField("a")
+		if err != nil {
+			return nil, err
+		}
+		ea, err := FromDocument(a.V.(document.Document))
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := d.GetByField("b")
+		if err != nil {
+			return nil, err
+		}
+		eb, err := FromDocument(b.V.(document.Document))
+		if err != nil {
+			return nil, err
+		}
+
+		return fn(ea, eb), nil
+	}
+
+	return nil, errors.New("invalid expression encoding")
+}
+
+// decodeField decodes the expression stored in the given field of d.
+func decodeField(d document.Document, field string) (Expr, error) {
+	v, err := d.GetByField(field)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromDocument(v.V.(document.Document))
+}
+
+func arrayToExprList(v document.Value) (LiteralExprList, error) {
+	var l LiteralExprList
+	err := v.V.(document.Array).Iterate(func(_ int, value document.Value) error {
+		e, err := FromDocument(value.V.(document.Document))
+		if err != nil {
+			return err
+		}
+
+		l = append(l, e)
+		return nil
+	})
+
+	return l, err
+}
+
+func arrayToValuePath(v document.Value) (document.ValuePath, error) {
+	var path document.ValuePath
+
+	err := v.V.(document.Array).Iterate(func(_ int, value document.Value) error {
+		if value.Type == document.TextValue {
+			path = append(path, document.ValuePathFragment{FieldName: value.V.(string)})
+		} else {
+			path = append(path, document.ValuePathFragment{ArrayIndex: int(value.V.(int64))})
+		}
+		return nil
+	})
+
+	return path, err
+}
+
+func valuePathToArray(path document.ValuePath) document.Array {
+	abuf := document.NewValueBuffer()
+	for _, p := range path {
+		if p.FieldName != "" {
+			abuf = abuf.Append(document.NewTextValue(p.FieldName))
+		} else {
+			abuf = abuf.Append(document.NewIntegerValue(int64(p.ArrayIndex)))
+		}
+	}
+
+	return abuf
+}
+
+func copyValue(v document.Value) document.Value {
+	switch v.Type {
+	case document.BlobValue:
+		b := make([]byte, len(v.V.([]byte)))
+		copy(b, v.V.([]byte))
+		return document.NewBlobValue(b)
+	case document.DocumentValue:
+		var fb document.FieldBuffer
+		if err := fb.Copy(v.V.(document.Document)); err == nil {
+			return document.NewDocumentValue(&fb)
+		}
+	case document.ArrayValue:
+		var vb document.ValueBuffer
+		if err := vb.Copy(v.V.(document.Array)); err == nil {
+			return document.NewArrayValue(&vb)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,69 @@
+package expr_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/genjidb/genji/document/encoding/msgpack"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncoding(t *testing.T) {
+	tests := []string{
+		`10`,
+		`10.5`,
+		`"hello"`,
+		`true`,
+		`NULL`,
+		`a.b[1].c`,
+		`$foo`,
+		`?`,
+		`[1, a, "foo"]`,
+		`{a: 1, b: {c: a + 1}}`,
+		`CAST(a AS integer)`,
+		`CASE WHEN a > 1 THEN "big" ELSE b END`,
+		`CASE a WHEN 1 THEN "one" WHEN 2 THEN "two" END`,
+		`pk()`,
+		`now()`,
+		`COUNT(*)`,
+		`COUNT(a)`,
+		`COUNT(DISTINCT a, b.c)`,
+		`myfunc(a, 1) + myagg(DISTINCT b)`,
+		`MIN(a) + MAX(b) - SUM(c) * AVG(d)`,
+		`(a + 1) * 2`,
+		`a = 1 AND (b != 2 OR c > 3)`,
+		`a >= 1 AND b < 2 AND c <= 3`,
+		`a % 2 = 1 OR b & 1 = 0 OR c | 1 > 0 OR d ^ 1 = 0`,
+		`a / 2 IN [1, 2] AND b NOT IN [3, 4]`,
+		`a IS NULL AND b IS NOT NULL`,
+		`a IS DISTINCT FROM b OR a IS NOT DISTINCT FROM c`,
+		`a ANY = 1 AND b ANY <= c`,
+		`NOT a AND NOT (b = 1 OR c)`,
+	}
+
+	codec := msgpack.NewCodec()
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			e, _, err := parser.NewParser(strings.NewReader(test)).ParseExpr()
+			require.NoError(t, err)
+
+			d, err := expr.ToDocument(e)
+			require.NoError(t, err)
+
+			// make sure the document can be stored
+			var buf bytes.Buffer
+			err = codec.NewEncoder(&buf).EncodeDocument(d)
+			require.NoError(t, err)
+
+			got, err := expr.FromDocument(codec.NewDocument(buf.Bytes()))
+			require.NoError(t, err)
+			require.True(t, expr.Equal(e, got), "expected %v, got %v", e, got)
+			require.Equal(t, fmt.Sprintf("%v", e), fmt.Sprintf("%v", got))
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package expr
 
 import (
+	"fmt"
+
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
 	"github.com/genjidb/genji/sql/scanner"
@@ -132,3 +134,19 @@ type Parentheses struct {
 func (p Parentheses) Eval(es EvalStack) (document.Value, error) {
 	return p.E.Eval(es)
 }
+
+// String implements the fmt.Stringer interface.
+func (p Parentheses) String() string {
+	return fmt.Sprintf("(%v)", p.E)
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (p Parentheses) IsEqual(other Expr) bool {
+	o, ok := other.(Parentheses)
+	if !ok {
+		return false
+	}
+
+	return Equal(p.E, o.E)
+}
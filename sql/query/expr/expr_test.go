@@ -64,6 +64,9 @@ func TestString(t *testing.T) {
 		`{"a": "foo", "b": 10}`,
 		"pk()",
 		"CAST(10 AS integer)",
+		"POINT(48.8566, 2.3522)",
+		"distance(a, POINT(48.8566, 2.3522))",
+		"within_radius(a, b, 1000)",
 	}
 
 	var operators = []string{
@@ -87,3 +90,48 @@ func TestString(t *testing.T) {
 		testFn(want, want)
 	}
 }
+
+func TestStringRoundTrip(t *testing.T) {
+	tests := []string{
+		`10.0`,
+		`-10`,
+		`"foo \"bar\" \\ baz\n"`,
+		`true`,
+		`NULL`,
+		"`foo bar`.b[1].`1`",
+		"`select`",
+		`$foo`,
+		`[1, a, [2.5]]`,
+		`{"a": 1, "b c": {"d": a}}`,
+		`1 + 2 * 3 - 4 / 5 % 6`,
+		`(1 + 2) * 3`,
+		`((a + 1) * (b - 2))`,
+		`a = 1 AND (b != 2 OR c > 3)`,
+		`a >= 1 AND b < 2 AND c <= 3`,
+		`a & 1 | b ^ 2`,
+		`a IN [1, 2] AND b NOT IN [3, 4]`,
+		`a IS NULL OR b IS NOT NULL`,
+		`a IS DISTINCT FROM b AND a IS NOT DISTINCT FROM c`,
+		`pk()`,
+		`now()`,
+		`COUNT(*)`,
+		`COUNT(a) + MIN(b) + MAX(c) + SUM(d) + AVG(e)`,
+		`CAST(a + 1 AS double)`,
+		`GREATEST(a, 1.5) + LEAST(b, "c", NULL)`,
+		`CASE WHEN a > 1 THEN "big" WHEN a = 1 THEN "one" ELSE "small" END`,
+		`CASE a + 1 WHEN 1 THEN b END`,
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			e, _, err := parser.NewParser(strings.NewReader(test)).ParseExpr()
+			require.NoError(t, err)
+
+			s := fmt.Sprintf("%v", e)
+			got, _, err := parser.NewParser(strings.NewReader(s)).ParseExpr()
+			require.NoError(t, err)
+			require.True(t, expr.Equal(e, got), "expected %v, got %v", e, got)
+			require.Equal(t, s, fmt.Sprintf("%v", got))
+		})
+	}
+}
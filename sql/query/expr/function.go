@@ -1,12 +1,18 @@
 package expr
 
 import (
+	"crypto/rand"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
+	"time"
 
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/key"
 )
 
 // Functions represents a map of builtin SQL functions.
@@ -53,59 +59,849 @@ func BuiltinFunctions() map[string]func(args ...Expr) (Expr, error) {
 			}
 			return &AvgFunc{Expr: args[0]}, nil
 		},
+		"now": func(args ...Expr) (Expr, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("now() takes no arguments")
+			}
+			return new(NowFunc), nil
+		},
+		"uuid": func(args ...Expr) (Expr, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("uuid() takes no arguments")
+			}
+			return new(UUIDFunc), nil
+		},
+		"point": func(args ...Expr) (Expr, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("POINT() takes 2 arguments")
+			}
+			return PointFunc{Lat: args[0], Lng: args[1]}, nil
+		},
+		"distance": func(args ...Expr) (Expr, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("distance() takes 2 arguments")
+			}
+			return DistanceFunc{A: args[0], B: args[1]}, nil
+		},
+		"within_radius": func(args ...Expr) (Expr, error) {
+			if len(args) != 3 {
+				return nil, fmt.Errorf("within_radius() takes 3 arguments")
+			}
+			return WithinRadiusFunc{Point: args[0], Center: args[1], Radius: args[2]}, nil
+		},
+		"round": func(args ...Expr) (Expr, error) {
+			switch len(args) {
+			case 1:
+				return RoundFunc{Expr: args[0]}, nil
+			case 2:
+				return RoundFunc{Expr: args[0], Digits: args[1]}, nil
+			}
+			return nil, fmt.Errorf("ROUND() takes 1 or 2 arguments")
+		},
+		"floor": func(args ...Expr) (Expr, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("FLOOR() takes 1 argument")
+			}
+			return FloorFunc{Expr: args[0]}, nil
+		},
+		"ceil": func(args ...Expr) (Expr, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("CEIL() takes 1 argument")
+			}
+			return CeilFunc{Expr: args[0]}, nil
+		},
+		"abs": func(args ...Expr) (Expr, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("ABS() takes 1 argument")
+			}
+			return AbsFunc{Expr: args[0]}, nil
+		},
+		"greatest": func(args ...Expr) (Expr, error) {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("GREATEST() takes at least 1 argument")
+			}
+			return GreatestFunc{Exprs: args}, nil
+		},
+		"least": func(args ...Expr) (Expr, error) {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("LEAST() takes at least 1 argument")
+			}
+			return LeastFunc{Exprs: args}, nil
+		},
+	}
+}
+
+// A Function is an expression returned by a function call.
+// It can be turned back into the original call using its name and arguments.
+type Function interface {
+	Expr
+
+	// Name of the function, as registered in the function map.
+	Name() string
+	// Args returns the list of arguments passed to the function.
+	Args() []Expr
+}
+
+func NewFunctions() Functions {
+	return Functions{
+		m: BuiltinFunctions(),
+	}
+}
+
+// AddFunc adds function to the map.
+func (f Functions) AddFunc(name string, fn func(args ...Expr) (Expr, error)) {
+	f.m[name] = fn
+}
+
+// GetFunc return a function expression by name.
+// Unknown functions are looked up among the functions registered in the database
+// when they are evaluated.
+func (f Functions) GetFunc(name string, args ...Expr) (Expr, error) {
+	fn, ok := f.m[strings.ToLower(name)]
+	if !ok {
+		return UserFunc{FuncName: strings.ToLower(name), Exprs: args}, nil
+	}
+
+	return fn(args...)
+}
+
+// UserFunc represents a call to a function registered by the user in the database.
+// Calls to aggregate functions are turned into UserAggregateFunc expressions
+// once the statement is bound to a transaction.
+type UserFunc struct {
+	FuncName string
+	Exprs    []Expr
+	// Distinct is true if the function was called with DISTINCT,
+	// which is only allowed for aggregate functions.
+	Distinct bool
+}
+
+// Eval calls the function registered under the name of f with the values of its arguments.
+// It returns an error if no function was registered under that name.
+func (f UserFunc) Eval(ctx EvalStack) (document.Value, error) {
+	var fn database.Function
+	if ctx.Tx != nil {
+		fn, _ = ctx.Tx.DB().GetFunction(f.FuncName)
+		if _, ok := ctx.Tx.DB().GetAggregate(f.FuncName); ok {
+			return nullLitteral, fmt.Errorf("aggregate function %s() can only be called in the projection", f.FuncName)
+		}
+	}
+	if fn == nil {
+		return nullLitteral, fmt.Errorf("no such function: %q", f.FuncName)
+	}
+	if f.Distinct {
+		return nullLitteral, fmt.Errorf("DISTINCT can only be used with aggregate functions")
+	}
+
+	args := make([]document.Value, len(f.Exprs))
+	for i, e := range f.Exprs {
+		v, err := e.Eval(ctx)
+		if err != nil {
+			return nullLitteral, err
+		}
+		args[i] = v
+	}
+
+	return fn(args...)
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (f UserFunc) IsEqual(other Expr) bool {
+	o, ok := other.(UserFunc)
+	return ok && f.FuncName == o.FuncName && f.Distinct == o.Distinct &&
+		LiteralExprList(f.Exprs).IsEqual(LiteralExprList(o.Exprs))
+}
+
+// Name implements the Function interface.
+func (f UserFunc) Name() string {
+	return f.FuncName
+}
+
+// Args implements the Function interface.
+func (f UserFunc) Args() []Expr {
+	return f.Exprs
+}
+
+func (f UserFunc) String() string {
+	return userFuncString(f.FuncName, f.Exprs, f.Distinct)
+}
+
+func userFuncString(name string, args []Expr, distinct bool) string {
+	if distinct {
+		return fmt.Sprintf("%s(DISTINCT %s)", name, joinExprs(args))
+	}
+
+	return fmt.Sprintf("%s(%s)", name, joinExprs(args))
+}
+
+// PKFunc represents the pk() function.
+// It returns the primary key of the current document.
+type PKFunc struct{}
+
+// Eval returns the primary key of the current document.
+func (k PKFunc) Eval(ctx EvalStack) (document.Value, error) {
+	if ctx.Info == nil {
+		return document.Value{}, errors.New("no table specified")
+	}
+
+	pk := ctx.Info.GetPrimaryKey()
+	if pk != nil {
+		return pk.Path.GetValue(ctx.Document)
+	}
+
+	i, _ := binary.Uvarint(ctx.Document.(document.Keyer).Key())
+
+	return document.NewIntegerValue(int64(i)), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (k PKFunc) IsEqual(other Expr) bool {
+	switch other.(type) {
+	case PKFunc, *PKFunc:
+		return true
+	}
+
+	return false
+}
+
+// Name implements the Function interface.
+func (k PKFunc) Name() string {
+	return "pk"
+}
+
+// Args implements the Function interface.
+func (k PKFunc) Args() []Expr {
+	return nil
+}
+
+func (k PKFunc) String() string {
+	return "pk()"
+}
+
+// TimestampFormat is the layout used to represent timestamps as text.
+// Timestamps are always stored in UTC with a fixed number of digits,
+// which ensures they sort lexicographically.
+const TimestampFormat = "2006-01-02T15:04:05.000000000Z"
+
+// NowFunc represents the now() function.
+// It returns the time at which the current transaction started,
+// which guarantees consistent values for the duration of a statement.
+type NowFunc struct{}
+
+// Eval returns the start time of the transaction as text, using the TimestampFormat layout.
+// If there is no transaction, it returns the current time.
+func (n NowFunc) Eval(ctx EvalStack) (document.Value, error) {
+	t := time.Now()
+	if ctx.Tx != nil {
+		t = ctx.Tx.StartTime()
+	}
+
+	return document.NewTextValue(t.UTC().Format(TimestampFormat)), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (n NowFunc) IsEqual(other Expr) bool {
+	switch other.(type) {
+	case NowFunc, *NowFunc:
+		return true
+	}
+
+	return false
+}
+
+// Name implements the Function interface.
+func (n NowFunc) Name() string {
+	return "now"
+}
+
+// Args implements the Function interface.
+func (n NowFunc) Args() []Expr {
+	return nil
+}
+
+func (n NowFunc) String() string {
+	return "now()"
+}
+
+// UUIDFunc represents the uuid() function.
+// It returns a random (version 4) UUID.
+type UUIDFunc struct{}
+
+// Eval generates a new UUID and returns its textual representation.
+func (u UUIDFunc) Eval(ctx EvalStack) (document.Value, error) {
+	var id [16]byte
+
+	_, err := rand.Read(id[:])
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	// set the version (4) and the variant (RFC 4122)
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+
+	return document.NewTextValue(string(buf[:])), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (u UUIDFunc) IsEqual(other Expr) bool {
+	switch other.(type) {
+	case UUIDFunc, *UUIDFunc:
+		return true
+	}
+
+	return false
+}
+
+// Name implements the Function interface.
+func (u UUIDFunc) Name() string {
+	return "uuid"
+}
+
+// Args implements the Function interface.
+func (u UUIDFunc) Args() []Expr {
+	return nil
+}
+
+func (u UUIDFunc) String() string {
+	return "uuid()"
+}
+
+// PointFunc represents the POINT() function.
+// It creates a point from a latitude and a longitude.
+type PointFunc struct {
+	Lat, Lng Expr
+}
+
+// Eval returns a point value. It returns NULL if the latitude or the longitude is NULL
+// and fails if they are not numbers or if they are out of range.
+func (p PointFunc) Eval(ctx EvalStack) (document.Value, error) {
+	lat, err := evalDouble(ctx, p.Lat)
+	if err != nil || lat.Type == document.NullValue {
+		return nullLitteral, err
+	}
+
+	lng, err := evalDouble(ctx, p.Lng)
+	if err != nil || lng.Type == document.NullValue {
+		return nullLitteral, err
+	}
+
+	pt := document.Point{Lat: lat.V.(float64), Lng: lng.V.(float64)}
+	if !pt.IsValid() {
+		return nullLitteral, fmt.Errorf("invalid point %v: latitude must be between -90 and 90 and longitude between -180 and 180", document.NewPointValue(pt))
+	}
+
+	return document.NewPointValue(pt), nil
+}
+
+// evalDouble evaluates e and converts the result to a double.
+// It fails if the result is not a number nor NULL.
+func evalDouble(ctx EvalStack, e Expr) (document.Value, error) {
+	v, err := e.Eval(ctx)
+	if err != nil {
+		return v, err
+	}
+
+	if v.Type == document.NullValue {
+		return v, nil
+	}
+
+	if !v.Type.IsNumber() {
+		return nullLitteral, fmt.Errorf("expected a number, got %s", v.Type)
+	}
+
+	return v.CastAsDouble()
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (p PointFunc) IsEqual(other Expr) bool {
+	o, ok := other.(PointFunc)
+	if !ok {
+		return false
+	}
+
+	return Equal(p.Lat, o.Lat) && Equal(p.Lng, o.Lng)
+}
+
+// Name implements the Function interface.
+func (p PointFunc) Name() string {
+	return "point"
+}
+
+// Args implements the Function interface.
+func (p PointFunc) Args() []Expr {
+	return []Expr{p.Lat, p.Lng}
+}
+
+func (p PointFunc) String() string {
+	return fmt.Sprintf("POINT(%v, %v)", p.Lat, p.Lng)
+}
+
+// DistanceFunc represents the distance() function.
+// It returns the distance in meters between two points.
+type DistanceFunc struct {
+	A, B Expr
+}
+
+// Eval returns the distance between the two points as a double.
+// If one of the values is not a point, it returns NULL.
+func (d DistanceFunc) Eval(ctx EvalStack) (document.Value, error) {
+	a, err := d.A.Eval(ctx)
+	if err != nil || a.Type != document.PointValue {
+		return nullLitteral, err
+	}
+
+	b, err := d.B.Eval(ctx)
+	if err != nil || b.Type != document.PointValue {
+		return nullLitteral, err
+	}
+
+	return document.NewDoubleValue(a.V.(document.Point).Distance(b.V.(document.Point))), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (d DistanceFunc) IsEqual(other Expr) bool {
+	o, ok := other.(DistanceFunc)
+	if !ok {
+		return false
+	}
+
+	return Equal(d.A, o.A) && Equal(d.B, o.B)
+}
+
+// Name implements the Function interface.
+func (d DistanceFunc) Name() string {
+	return "distance"
+}
+
+// Args implements the Function interface.
+func (d DistanceFunc) Args() []Expr {
+	return []Expr{d.A, d.B}
+}
+
+func (d DistanceFunc) String() string {
+	return fmt.Sprintf("distance(%v, %v)", d.A, d.B)
+}
+
+// WithinRadiusFunc represents the within_radius() function.
+// It returns whether a point is located within a given distance, in meters, of a center point.
+type WithinRadiusFunc struct {
+	Point, Center, Radius Expr
+}
+
+// Eval returns true if the distance between the point and the center is lower than
+// or equal to the radius. If the point or the center is not a point or if the radius is not
+// a number, it returns NULL.
+func (w WithinRadiusFunc) Eval(ctx EvalStack) (document.Value, error) {
+	d, err := DistanceFunc{A: w.Point, B: w.Center}.Eval(ctx)
+	if err != nil || d.Type == document.NullValue {
+		return nullLitteral, err
+	}
+
+	r, err := w.Radius.Eval(ctx)
+	if err != nil || !r.Type.IsNumber() {
+		return nullLitteral, err
+	}
+
+	ok, err := d.IsLesserThanOrEqual(r)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	return document.NewBoolValue(ok), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (w WithinRadiusFunc) IsEqual(other Expr) bool {
+	o, ok := other.(WithinRadiusFunc)
+	if !ok {
+		return false
+	}
+
+	return Equal(w.Point, o.Point) && Equal(w.Center, o.Center) && Equal(w.Radius, o.Radius)
+}
+
+// Name implements the Function interface.
+func (w WithinRadiusFunc) Name() string {
+	return "within_radius"
+}
+
+// Args implements the Function interface.
+func (w WithinRadiusFunc) Args() []Expr {
+	return []Expr{w.Point, w.Center, w.Radius}
+}
+
+func (w WithinRadiusFunc) String() string {
+	return fmt.Sprintf("within_radius(%v, %v, %v)", w.Point, w.Center, w.Radius)
+}
+
+// evalNumber evaluates e and makes sure the result is a number or NULL.
+func evalNumber(ctx EvalStack, e Expr, fn string) (document.Value, error) {
+	v, err := e.Eval(ctx)
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	if v.Type != document.NullValue && !v.Type.IsNumber() {
+		return nullLitteral, fmt.Errorf("%s() expects a number, got %s", fn, v.Type)
+	}
+
+	return v, nil
+}
+
+// RoundFunc represents the ROUND() function.
+// It rounds a number to a given number of decimal digits, zero by default.
+// A negative number of digits rounds to the left of the decimal point.
+// The number of digits must be a whole number.
+type RoundFunc struct {
+	Expr Expr
+	// Digits is nil if the number of digits is not specified.
+	Digits Expr
+}
+
+// Eval returns the rounded value as a double.
+// If the value is NULL, it returns NULL.
+func (r RoundFunc) Eval(ctx EvalStack) (document.Value, error) {
+	v, err := evalNumber(ctx, r.Expr, "ROUND")
+	if err != nil || v.Type == document.NullValue {
+		return nullLitteral, err
+	}
+
+	var digits int64
+	if r.Digits != nil {
+		d, err := evalNumber(ctx, r.Digits, "ROUND")
+		if err != nil || d.Type == document.NullValue {
+			return nullLitteral, err
+		}
+
+		if d.Type == document.DoubleValue {
+			f := d.V.(float64)
+			if f != math.Trunc(f) {
+				return nullLitteral, fmt.Errorf("ROUND() expects a whole number of digits, got %v", f)
+			}
+		}
+
+		d, err = d.CastAsInteger()
+		if err != nil {
+			return nullLitteral, err
+		}
+		digits = d.V.(int64)
+	}
+
+	v, err = v.CastAsDouble()
+	if err != nil {
+		return nullLitteral, err
+	}
+	f := v.V.(float64)
+
+	if digits >= 0 {
+		p := math.Pow10(int(digits))
+		return document.NewDoubleValue(math.Round(f*p) / p), nil
+	}
+
+	p := math.Pow10(int(-digits))
+	return document.NewDoubleValue(math.Round(f/p) * p), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (r RoundFunc) IsEqual(other Expr) bool {
+	o, ok := other.(RoundFunc)
+	if !ok {
+		return false
+	}
+
+	if r.Digits == nil || o.Digits == nil {
+		return r.Digits == o.Digits && Equal(r.Expr, o.Expr)
+	}
+
+	return Equal(r.Expr, o.Expr) && Equal(r.Digits, o.Digits)
+}
+
+// Name implements the Function interface.
+func (r RoundFunc) Name() string {
+	return "round"
+}
+
+// Args implements the Function interface.
+func (r RoundFunc) Args() []Expr {
+	if r.Digits == nil {
+		return []Expr{r.Expr}
+	}
+
+	return []Expr{r.Expr, r.Digits}
+}
+
+func (r RoundFunc) String() string {
+	if r.Digits == nil {
+		return fmt.Sprintf("ROUND(%v)", r.Expr)
+	}
+
+	return fmt.Sprintf("ROUND(%v, %v)", r.Expr, r.Digits)
+}
+
+// FloorFunc represents the FLOOR() function.
+// It returns the greatest integer value lesser than or equal to a number.
+type FloorFunc struct {
+	Expr Expr
+}
+
+// Eval returns the result as a double.
+// If the value is NULL, it returns NULL.
+func (f FloorFunc) Eval(ctx EvalStack) (document.Value, error) {
+	v, err := evalNumber(ctx, f.Expr, "FLOOR")
+	if err != nil || v.Type == document.NullValue {
+		return nullLitteral, err
+	}
+
+	v, err = v.CastAsDouble()
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	return document.NewDoubleValue(math.Floor(v.V.(float64))), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (f FloorFunc) IsEqual(other Expr) bool {
+	o, ok := other.(FloorFunc)
+	return ok && Equal(f.Expr, o.Expr)
+}
+
+// Name implements the Function interface.
+func (f FloorFunc) Name() string {
+	return "floor"
+}
+
+// Args implements the Function interface.
+func (f FloorFunc) Args() []Expr {
+	return []Expr{f.Expr}
+}
+
+func (f FloorFunc) String() string {
+	return fmt.Sprintf("FLOOR(%v)", f.Expr)
+}
+
+// CeilFunc represents the CEIL() function.
+// It returns the least integer value greater than or equal to a number.
+type CeilFunc struct {
+	Expr Expr
+}
+
+// Eval returns the result as a double.
+// If the value is NULL, it returns NULL.
+func (c CeilFunc) Eval(ctx EvalStack) (document.Value, error) {
+	v, err := evalNumber(ctx, c.Expr, "CEIL")
+	if err != nil || v.Type == document.NullValue {
+		return nullLitteral, err
 	}
+
+	v, err = v.CastAsDouble()
+	if err != nil {
+		return nullLitteral, err
+	}
+
+	return document.NewDoubleValue(math.Ceil(v.V.(float64))), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (c CeilFunc) IsEqual(other Expr) bool {
+	o, ok := other.(CeilFunc)
+	return ok && Equal(c.Expr, o.Expr)
+}
+
+// Name implements the Function interface.
+func (c CeilFunc) Name() string {
+	return "ceil"
+}
+
+// Args implements the Function interface.
+func (c CeilFunc) Args() []Expr {
+	return []Expr{c.Expr}
+}
+
+func (c CeilFunc) String() string {
+	return fmt.Sprintf("CEIL(%v)", c.Expr)
+}
+
+// AbsFunc represents the ABS() function.
+// It returns the absolute value of a number.
+type AbsFunc struct {
+	Expr Expr
+}
+
+// Eval returns an integer if the value is an integer, a double otherwise.
+// If the value is NULL, it returns NULL.
+// The absolute value of the smallest integer overflows, which returns
+// document.ErrIntegerOverflow, like arithmetic operators.
+func (a AbsFunc) Eval(ctx EvalStack) (document.Value, error) {
+	v, err := evalNumber(ctx, a.Expr, "ABS")
+	if err != nil || v.Type == document.NullValue {
+		return nullLitteral, err
+	}
+
+	if v.Type == document.IntegerValue {
+		i := v.V.(int64)
+		if i == math.MinInt64 {
+			return nullLitteral, document.ErrIntegerOverflow
+		}
+		if i < 0 {
+			i = -i
+		}
+		return document.NewIntegerValue(i), nil
+	}
+
+	return document.NewDoubleValue(math.Abs(v.V.(float64))), nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (a AbsFunc) IsEqual(other Expr) bool {
+	o, ok := other.(AbsFunc)
+	return ok && Equal(a.Expr, o.Expr)
+}
+
+// Name implements the Function interface.
+func (a AbsFunc) Name() string {
+	return "abs"
+}
+
+// Args implements the Function interface.
+func (a AbsFunc) Args() []Expr {
+	return []Expr{a.Expr}
+}
+
+func (a AbsFunc) String() string {
+	return fmt.Sprintf("ABS(%v)", a.Expr)
+}
+
+// GreatestFunc represents the GREATEST() function.
+// It returns the largest of its arguments.
+type GreatestFunc struct {
+	Exprs []Expr
+}
+
+// Eval returns the largest non-null argument, using the ordering of document.Value.Compare.
+// If all the arguments are NULL, it returns NULL.
+func (g GreatestFunc) Eval(ctx EvalStack) (document.Value, error) {
+	return evalExtremum(ctx, g.Exprs, 1)
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (g GreatestFunc) IsEqual(other Expr) bool {
+	o, ok := other.(GreatestFunc)
+	return ok && LiteralExprList(g.Exprs).IsEqual(LiteralExprList(o.Exprs))
+}
+
+// Name implements the Function interface.
+func (g GreatestFunc) Name() string {
+	return "greatest"
+}
+
+// Args implements the Function interface.
+func (g GreatestFunc) Args() []Expr {
+	return g.Exprs
 }
 
-func NewFunctions() Functions {
-	return Functions{
-		m: BuiltinFunctions(),
-	}
+func (g GreatestFunc) String() string {
+	return fmt.Sprintf("GREATEST(%s)", joinExprs(g.Exprs))
 }
 
-// AddFunc adds function to the map.
-func (f Functions) AddFunc(name string, fn func(args ...Expr) (Expr, error)) {
-	f.m[name] = fn
+// LeastFunc represents the LEAST() function.
+// It returns the smallest of its arguments.
+type LeastFunc struct {
+	Exprs []Expr
 }
 
-// GetFunc return a function expression by name.
-func (f Functions) GetFunc(name string, args ...Expr) (Expr, error) {
-	fn, ok := f.m[strings.ToLower(name)]
-	if !ok {
-		return nil, fmt.Errorf("no such function: %q", name)
-	}
+// Eval returns the smallest non-null argument, using the ordering of document.Value.Compare.
+// If all the arguments are NULL, it returns NULL.
+func (l LeastFunc) Eval(ctx EvalStack) (document.Value, error) {
+	return evalExtremum(ctx, l.Exprs, -1)
+}
 
-	return fn(args...)
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (l LeastFunc) IsEqual(other Expr) bool {
+	o, ok := other.(LeastFunc)
+	return ok && LiteralExprList(l.Exprs).IsEqual(LiteralExprList(o.Exprs))
 }
 
-// PKFunc represents the pk() function.
-// It returns the primary key of the current document.
-type PKFunc struct{}
+// Name implements the Function interface.
+func (l LeastFunc) Name() string {
+	return "least"
+}
 
-// Eval returns the primary key of the current document.
-func (k PKFunc) Eval(ctx EvalStack) (document.Value, error) {
-	if ctx.Info == nil {
-		return document.Value{}, errors.New("no table specified")
-	}
+// Args implements the Function interface.
+func (l LeastFunc) Args() []Expr {
+	return l.Exprs
+}
 
-	pk := ctx.Info.GetPrimaryKey()
-	if pk != nil {
-		return pk.Path.GetValue(ctx.Document)
-	}
+func (l LeastFunc) String() string {
+	return fmt.Sprintf("LEAST(%s)", joinExprs(l.Exprs))
+}
 
-	i, _ := binary.Uvarint(ctx.Document.(document.Keyer).Key())
+// evalExtremum evaluates all the expressions and returns the non-null value v
+// for which v.Compare(other) == sign for all the other values.
+// NULL values are ignored.
+func evalExtremum(ctx EvalStack, exprs []Expr, sign int) (document.Value, error) {
+	res := nullLitteral
 
-	return document.NewIntegerValue(int64(i)), nil
-}
+	for _, e := range exprs {
+		v, err := e.Eval(ctx)
+		if err != nil {
+			return nullLitteral, err
+		}
+		if v.Type == document.NullValue {
+			continue
+		}
+		if res.Type == document.NullValue {
+			res = v
+			continue
+		}
 
-// IsEqual compares this expression with the other expression and returns
-// true if they are equal.
-func (k PKFunc) IsEqual(other Expr) bool {
-	_, ok := other.(PKFunc)
-	return ok
+		cmp, err := v.Compare(res)
+		if err != nil {
+			return nullLitteral, err
+		}
+		if cmp == sign {
+			res = v
+		}
+	}
+
+	return res, nil
 }
 
-func (k PKFunc) String() string {
-	return "pk()"
+func joinExprs(exprs []Expr) string {
+	var sb strings.Builder
+	for i, e := range exprs {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "%v", e)
+	}
+
+	return sb.String()
 }
 
 // CastFunc represents the CAST expression.
@@ -191,11 +987,30 @@ func (c *CountFunc) IsEqual(other Expr) bool {
 	return Equal(c.Expr, o.Expr)
 }
 
+// Name implements the Function interface.
+func (c *CountFunc) Name() string {
+	return "count"
+}
+
+// Args implements the Function interface.
+// It returns no arguments for COUNT(*).
+func (c *CountFunc) Args() []Expr {
+	if c.Wildcard {
+		return nil
+	}
+
+	return []Expr{c.Expr}
+}
+
 func (c *CountFunc) String() string {
 	if c.Alias != "" {
 		return c.Alias
 	}
 
+	if c.Wildcard {
+		return "COUNT(*)"
+	}
+
 	return fmt.Sprintf("COUNT(%v)", c.Expr)
 }
 
@@ -231,6 +1046,263 @@ func (c *CountAggregator) Aggregate(fb *document.FieldBuffer) error {
 	return nil
 }
 
+// CountDistinctFunc is the COUNT(DISTINCT ...) aggregator function.
+// It counts the distinct combinations of the values of its arguments.
+// Combinations containing a null value, or a value that doesn't exist,
+// are not counted, like COUNT ignores null values.
+type CountDistinctFunc struct {
+	Exprs []Expr
+	Alias string
+	// Reserve, if not nil, is called with the size of every combination
+	// kept in memory. If it returns an error, the aggregation stops.
+	Reserve func(size int64) error
+}
+
+func (c *CountDistinctFunc) Eval(ctx EvalStack) (document.Value, error) {
+	return ctx.Document.GetByField(c.String())
+}
+
+// SetAlias implements the planner.AggregatorBuilder interface.
+func (c *CountDistinctFunc) SetAlias(alias string) {
+	c.Alias = alias
+}
+
+// NewAggregator implements the planner.AggregatorBuilder interface.
+func (c *CountDistinctFunc) NewAggregator(group document.Value) document.Aggregator {
+	return &CountDistinctAggregator{
+		Fn:   c,
+		Seen: make(map[string]struct{}),
+	}
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (c *CountDistinctFunc) IsEqual(other Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	o, ok := other.(*CountDistinctFunc)
+	if !ok || len(c.Exprs) != len(o.Exprs) {
+		return false
+	}
+
+	for i := range c.Exprs {
+		if !Equal(c.Exprs[i], o.Exprs[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Name implements the Function interface.
+func (c *CountDistinctFunc) Name() string {
+	return "count"
+}
+
+// Args implements the Function interface.
+func (c *CountDistinctFunc) Args() []Expr {
+	return c.Exprs
+}
+
+func (c *CountDistinctFunc) String() string {
+	if c.Alias != "" {
+		return c.Alias
+	}
+
+	var b strings.Builder
+	b.WriteString("COUNT(DISTINCT ")
+	for i, e := range c.Exprs {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%v", e)
+	}
+	b.WriteString(")")
+
+	return b.String()
+}
+
+// CountDistinctAggregator is an aggregator that counts distinct combinations
+// of non-null values.
+type CountDistinctAggregator struct {
+	Fn *CountDistinctFunc
+	// encoded combinations already counted
+	Seen map[string]struct{}
+}
+
+// Add evaluates the arguments of the function and records their combination
+// if none of them is null.
+// Combinations are encoded with key.KeyEncodeMulti, which encodes equal
+// values the same way, for example integers and doubles.
+func (c *CountDistinctAggregator) Add(d document.Document) error {
+	values := make([]document.Value, len(c.Fn.Exprs))
+
+	for i, e := range c.Fn.Exprs {
+		v, err := e.Eval(EvalStack{
+			Document: d,
+		})
+		if err != nil && err != document.ErrFieldNotFound {
+			return err
+		}
+		if err == document.ErrFieldNotFound || v.Type == document.NullValue {
+			return nil
+		}
+
+		values[i] = v
+	}
+
+	k, err := key.KeyEncodeMulti(values...)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := c.Seen[string(k)]; ok {
+		return nil
+	}
+	if c.Fn.Reserve != nil {
+		err = c.Fn.Reserve(int64(len(k)))
+		if err != nil {
+			return err
+		}
+	}
+
+	c.Seen[string(k)] = struct{}{}
+	return nil
+}
+
+// Aggregate adds a field to the given buffer with the number of distinct combinations.
+func (c *CountDistinctAggregator) Aggregate(fb *document.FieldBuffer) error {
+	fb.Add(c.Fn.String(), document.NewIntegerValue(int64(len(c.Seen))))
+	return nil
+}
+
+// UserAggregateFunc represents a call to an aggregate function registered by the user
+// in the database.
+type UserAggregateFunc struct {
+	FuncName string
+	Exprs    []Expr
+	// If Distinct is true, the aggregate only receives each distinct combination
+	// of the values of the arguments once.
+	Distinct bool
+	Alias    string
+	Fn       database.AggregateFunction
+	// Reserve, if not nil, is called with the size of every distinct combination
+	// kept in memory. If it returns an error, the aggregation stops.
+	Reserve func(size int64) error
+}
+
+// Eval extracts the result of the aggregate from the given document and returns it.
+func (f *UserAggregateFunc) Eval(ctx EvalStack) (document.Value, error) {
+	return ctx.Document.GetByField(f.String())
+}
+
+// SetAlias implements the planner.AggregatorBuilder interface.
+func (f *UserAggregateFunc) SetAlias(alias string) {
+	f.Alias = alias
+}
+
+// NewAggregator implements the planner.AggregatorBuilder interface.
+func (f *UserAggregateFunc) NewAggregator(group document.Value) document.Aggregator {
+	agg := UserAggregator{
+		Fn:  f,
+		Agg: f.Fn(),
+	}
+	agg.Agg.Init()
+
+	if f.Distinct {
+		agg.Seen = make(map[string]struct{})
+	}
+
+	return &agg
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (f *UserAggregateFunc) IsEqual(other Expr) bool {
+	o, ok := other.(*UserAggregateFunc)
+	return ok && f.FuncName == o.FuncName && f.Distinct == o.Distinct &&
+		LiteralExprList(f.Exprs).IsEqual(LiteralExprList(o.Exprs))
+}
+
+// Name implements the Function interface.
+func (f *UserAggregateFunc) Name() string {
+	return f.FuncName
+}
+
+// Args implements the Function interface.
+func (f *UserAggregateFunc) Args() []Expr {
+	return f.Exprs
+}
+
+func (f *UserAggregateFunc) String() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+
+	return userFuncString(f.FuncName, f.Exprs, f.Distinct)
+}
+
+// UserAggregator drives the aggregate of a group of documents.
+type UserAggregator struct {
+	Fn  *UserAggregateFunc
+	Agg database.Aggregate
+	// encoded combinations already added, if the function was called with DISTINCT
+	Seen map[string]struct{}
+}
+
+// Add evaluates the arguments of the function and passes their values to the aggregate.
+// Values that don't exist are passed as NULL.
+func (u *UserAggregator) Add(d document.Document) error {
+	values := make([]document.Value, len(u.Fn.Exprs))
+
+	for i, e := range u.Fn.Exprs {
+		v, err := e.Eval(EvalStack{
+			Document: d,
+		})
+		if err != nil && err != document.ErrFieldNotFound {
+			return err
+		}
+		if err == document.ErrFieldNotFound {
+			v = nullLitteral
+		}
+
+		values[i] = v
+	}
+
+	if u.Seen != nil {
+		k, err := key.KeyEncodeMulti(values...)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := u.Seen[string(k)]; ok {
+			return nil
+		}
+		if u.Fn.Reserve != nil {
+			err = u.Fn.Reserve(int64(len(k)))
+			if err != nil {
+				return err
+			}
+		}
+		u.Seen[string(k)] = struct{}{}
+	}
+
+	return u.Agg.Step(values...)
+}
+
+// Aggregate adds a field to the given buffer with the result of the aggregate.
+func (u *UserAggregator) Aggregate(fb *document.FieldBuffer) error {
+	v, err := u.Agg.Result()
+	if err != nil {
+		return err
+	}
+
+	fb.Add(u.Fn.String(), v)
+	return nil
+}
+
 // MinFunc is the MIN aggregator function.
 type MinFunc struct {
 	Expr  Expr
@@ -269,6 +1341,16 @@ func (m *MinFunc) IsEqual(other Expr) bool {
 	return Equal(m.Expr, o.Expr)
 }
 
+// Name implements the Function interface.
+func (m *MinFunc) Name() string {
+	return "min"
+}
+
+// Args implements the Function interface.
+func (m *MinFunc) Args() []Expr {
+	return []Expr{m.Expr}
+}
+
 // String returns the alias if non-zero, otherwise it returns a string representation
 // of the count expression.
 func (m *MinFunc) String() string {
@@ -303,7 +1385,7 @@ func (m *MinAggregator) Add(d document.Document) error {
 		return nil
 	}
 
-	if m.Min.Type == v.Type || m.Min.Type.IsNumber() && m.Min.Type.IsNumber() {
+	if m.Min.Type == v.Type || m.Min.Type.IsNumber() && v.Type.IsNumber() {
 		ok, err := m.Min.IsGreaterThan(v)
 		if err != nil {
 			return err
@@ -323,7 +1405,13 @@ func (m *MinAggregator) Add(d document.Document) error {
 }
 
 // Aggregate adds a field to the given buffer with the minimum value.
+// If no value was added, the field is null.
 func (m *MinAggregator) Aggregate(fb *document.FieldBuffer) error {
+	if m.Min.Type == 0 {
+		fb.Add(m.Fn.String(), document.NewNullValue())
+		return nil
+	}
+
 	fb.Add(m.Fn.String(), m.Min)
 	return nil
 }
@@ -366,6 +1454,16 @@ func (m *MaxFunc) IsEqual(other Expr) bool {
 	return Equal(m.Expr, o.Expr)
 }
 
+// Name implements the Function interface.
+func (m *MaxFunc) Name() string {
+	return "max"
+}
+
+// Args implements the Function interface.
+func (m *MaxFunc) Args() []Expr {
+	return []Expr{m.Expr}
+}
+
 // String returns the alias if non-zero, otherwise it returns a string representation
 // of the count expression.
 func (m *MaxFunc) String() string {
@@ -400,7 +1498,7 @@ func (m *MaxAggregator) Add(d document.Document) error {
 		return nil
 	}
 
-	if m.Max.Type == v.Type || m.Max.Type.IsNumber() && m.Max.Type.IsNumber() {
+	if m.Max.Type == v.Type || m.Max.Type.IsNumber() && v.Type.IsNumber() {
 		ok, err := m.Max.IsLesserThan(v)
 		if err != nil {
 			return err
@@ -420,7 +1518,13 @@ func (m *MaxAggregator) Add(d document.Document) error {
 }
 
 // Aggregate adds a field to the given buffer with the maximum value.
+// If no value was added, the field is null.
 func (m *MaxAggregator) Aggregate(fb *document.FieldBuffer) error {
+	if m.Max.Type == 0 {
+		fb.Add(m.Fn.String(), document.NewNullValue())
+		return nil
+	}
+
 	fb.Add(m.Fn.String(), m.Max)
 	return nil
 }
@@ -463,6 +1567,16 @@ func (s *SumFunc) IsEqual(other Expr) bool {
 	return Equal(s.Expr, o.Expr)
 }
 
+// Name implements the Function interface.
+func (s *SumFunc) Name() string {
+	return "sum"
+}
+
+// Args implements the Function interface.
+func (s *SumFunc) Args() []Expr {
+	return []Expr{s.Expr}
+}
+
 // String returns the alias if non-zero, otherwise it returns a string representation
 // of the count expression.
 func (s *SumFunc) String() string {
@@ -473,7 +1587,7 @@ func (s *SumFunc) String() string {
 	return fmt.Sprintf("SUM(%v)", s.Expr)
 }
 
-// SumAggregator is an aggregator that returns the minimum non-null value.
+// SumAggregator is an aggregator that returns the sum of the non-null numeric values.
 type SumAggregator struct {
 	Fn   *SumFunc
 	SumI *int64
@@ -483,6 +1597,8 @@ type SumAggregator struct {
 // Add stores the sum of all non-NULL numeric values in the group.
 // The result is an integer value if all summed values are integers.
 // If any of the value is a double, the returned result will be a double.
+// If the sum of the integers overflows, it returns document.ErrIntegerOverflow,
+// like the addition operator.
 func (s *SumAggregator) Add(d document.Document) error {
 	v, err := s.Fn.Expr.Eval(EvalStack{
 		Document: d,
@@ -520,11 +1636,17 @@ func (s *SumAggregator) Add(d document.Document) error {
 		s.SumI = &sumI
 	}
 
-	*s.SumI += v.V.(int64)
+	i := v.V.(int64)
+	sumI := *s.SumI + i
+	if (*s.SumI >= 0) == (i >= 0) && (sumI >= 0) != (i >= 0) {
+		return document.ErrIntegerOverflow
+	}
+
+	*s.SumI = sumI
 	return nil
 }
 
-// Aggregate adds a field to the given buffer with the maximum value.
+// Aggregate adds a field to the given buffer with the sum.
 func (s *SumAggregator) Aggregate(fb *document.FieldBuffer) error {
 	if s.SumF != nil {
 		fb.Add(s.Fn.String(), document.NewDoubleValue(*s.SumF))
@@ -575,6 +1697,16 @@ func (s *AvgFunc) IsEqual(other Expr) bool {
 	return Equal(s.Expr, o.Expr)
 }
 
+// Name implements the Function interface.
+func (s *AvgFunc) Name() string {
+	return "avg"
+}
+
+// Args implements the Function interface.
+func (s *AvgFunc) Args() []Expr {
+	return []Expr{s.Expr}
+}
+
 // String returns the alias if non-zero, otherwise it returns a string representation
 // of the average expression.
 func (s *AvgFunc) String() string {
@@ -614,13 +1746,105 @@ func (s *AvgAggregator) Add(d document.Document) error {
 	return nil
 }
 
-// Aggregate adds a field to the given buffer with the maximum value.
+// Aggregate adds a field to the given buffer with the average value.
+// If no numeric value was added, the field is null.
 func (s *AvgAggregator) Aggregate(fb *document.FieldBuffer) error {
 	if s.Counter == 0 {
-		fb.Add(s.Fn.String(), document.NewDoubleValue(0))
+		fb.Add(s.Fn.String(), document.NewNullValue())
 	} else {
 		fb.Add(s.Fn.String(), document.NewDoubleValue(s.Avg/float64(s.Counter)))
 	}
 
 	return nil
 }
+
+// FilteredAggregateFunc is an aggregate function followed by a FILTER clause.
+// Only the documents for which the filter evaluates to a truthy value
+// are passed to the aggregate.
+type FilteredAggregateFunc struct {
+	// Aggregate function, which must implement document.AggregatorBuilder.
+	Agg    Expr
+	Filter Expr
+	Alias  string
+}
+
+// Eval returns the result of the aggregate, stored in the aggregated document.
+func (f *FilteredAggregateFunc) Eval(ctx EvalStack) (document.Value, error) {
+	return ctx.Document.GetByField(f.String())
+}
+
+// SetAlias implements the planner.AggregatorBuilder interface.
+func (f *FilteredAggregateFunc) SetAlias(alias string) {
+	f.Alias = alias
+}
+
+// NewAggregator implements the planner.AggregatorBuilder interface.
+func (f *FilteredAggregateFunc) NewAggregator(group document.Value) document.Aggregator {
+	return &FilteredAggregator{
+		Fn:  f,
+		Agg: f.Agg.(document.AggregatorBuilder).NewAggregator(group),
+	}
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (f *FilteredAggregateFunc) IsEqual(other Expr) bool {
+	o, ok := other.(*FilteredAggregateFunc)
+	if !ok {
+		return false
+	}
+
+	return Equal(f.Agg, o.Agg) && Equal(f.Filter, o.Filter)
+}
+
+func (f *FilteredAggregateFunc) String() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+
+	return fmt.Sprintf("%v FILTER (WHERE %v)", f.Agg, f.Filter)
+}
+
+// FilteredAggregator passes the documents that satisfy the filter of its function
+// to the aggregator of the filtered function.
+type FilteredAggregator struct {
+	Fn  *FilteredAggregateFunc
+	Agg document.Aggregator
+}
+
+// Add passes the document to the aggregator if the filter evaluates to a truthy value.
+// Documents for which the filter can't be evaluated because a field doesn't exist are ignored.
+func (f *FilteredAggregator) Add(d document.Document) error {
+	v, err := f.Fn.Filter.Eval(EvalStack{
+		Document: d,
+	})
+	if err == document.ErrFieldNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ok, err := v.IsTruthy()
+	if err != nil || !ok {
+		return err
+	}
+
+	return f.Agg.Add(d)
+}
+
+// Aggregate adds a field to the given buffer with the result of the filtered aggregator,
+// named after the filtered function so that it doesn't conflict with the same aggregate
+// called without a filter.
+func (f *FilteredAggregator) Aggregate(fb *document.FieldBuffer) error {
+	var res document.FieldBuffer
+	err := f.Agg.Aggregate(&res)
+	if err != nil {
+		return err
+	}
+
+	return res.Iterate(func(_ string, v document.Value) error {
+		fb.Add(f.Fn.String(), v)
+		return nil
+	})
+}
@@ -1,10 +1,14 @@
 package expr_test
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
 	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPkExpr(t *testing.T) {
@@ -25,3 +29,114 @@ func TestPkExpr(t *testing.T) {
 		})
 	}
 }
+
+func TestGeoFunctions(t *testing.T) {
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"POINT(48.8566, 2.3522)", document.NewPointValue(document.Point{Lat: 48.8566, Lng: 2.3522}), false},
+		{"POINT(48, NULL)", nullLitteral, false},
+		{"POINT(48, 'foo')", nullLitteral, true},
+		{"POINT(91, 0)", nullLitteral, true},
+		{"distance(POINT(10, 10), POINT(10, 10))", document.NewDoubleValue(0), false},
+		{"distance(POINT(10, 10), 10)", nullLitteral, false},
+		{"within_radius(POINT(48.8566, 2.3522), POINT(51.5074, -0.1278), 344000)", document.NewBoolValue(true), false},
+		{"within_radius(POINT(48.8566, 2.3522), POINT(51.5074, -0.1278), 343000)", document.NewBoolValue(false), false},
+		{"within_radius(POINT(48.8566, 2.3522), POINT(51.5074, -0.1278), 'foo')", nullLitteral, false},
+		{"within_radius(NULL, POINT(51.5074, -0.1278), 1000)", nullLitteral, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, expr.EvalStack{}, test.res, test.fails)
+		})
+	}
+
+	t.Run("distance", func(t *testing.T) {
+		e, _, err := parser.NewParser(strings.NewReader("distance(POINT(48.8566, 2.3522), POINT(51.5074, -0.1278))")).ParseExpr()
+		require.NoError(t, err)
+		v, err := e.Eval(expr.EvalStack{})
+		require.NoError(t, err)
+		require.Equal(t, document.DoubleValue, v.Type)
+		require.InDelta(t, 343.5e3, v.V.(float64), 500)
+	})
+}
+
+func TestNumericFunctions(t *testing.T) {
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"ROUND(1.4)", document.NewDoubleValue(1), false},
+		{"ROUND(1.5)", document.NewDoubleValue(2), false},
+		{"ROUND(-1.5)", document.NewDoubleValue(-2), false},
+		{"ROUND(3)", document.NewDoubleValue(3), false},
+		{"ROUND(3.14159, 2)", document.NewDoubleValue(3.14), false},
+		{"ROUND(3.145, 0)", document.NewDoubleValue(3), false},
+		{"ROUND(1234.5, -1)", document.NewDoubleValue(1230), false},
+		{"ROUND(1250, -2)", document.NewDoubleValue(1300), false},
+		{"ROUND(1234, -5)", document.NewDoubleValue(0), false},
+		{"ROUND(3.14159, 2.0)", document.NewDoubleValue(3.14), false},
+		{"ROUND(3.14159, 2.9)", nullLitteral, true},
+		{"ROUND(1234.5, -1.5)", nullLitteral, true},
+		{"ROUND(NULL)", nullLitteral, false},
+		{"ROUND(1.5, NULL)", nullLitteral, false},
+		{"ROUND('foo')", nullLitteral, true},
+		{"ROUND(1.5, 'foo')", nullLitteral, true},
+		{"FLOOR(1.7)", document.NewDoubleValue(1), false},
+		{"FLOOR(-1.2)", document.NewDoubleValue(-2), false},
+		{"FLOOR(5)", document.NewDoubleValue(5), false},
+		{"FLOOR(NULL)", nullLitteral, false},
+		{"FLOOR(true)", nullLitteral, true},
+		{"CEIL(1.2)", document.NewDoubleValue(2), false},
+		{"CEIL(-1.7)", document.NewDoubleValue(-1), false},
+		{"CEIL(5)", document.NewDoubleValue(5), false},
+		{"CEIL(NULL)", nullLitteral, false},
+		{"CEIL([1])", nullLitteral, true},
+		{"ABS(-5)", document.NewIntegerValue(5), false},
+		{"ABS(5)", document.NewIntegerValue(5), false},
+		{"ABS(-5.5)", document.NewDoubleValue(5.5), false},
+		{"ABS(-9223372036854775808)", nullLitteral, true},
+		{"ABS(NULL)", nullLitteral, false},
+		{"ABS('foo')", nullLitteral, true},
+		{"GREATEST(1, 3, 2)", document.NewIntegerValue(3), false},
+		{"GREATEST(1, 2.5, 2)", document.NewDoubleValue(2.5), false},
+		{"GREATEST(3, 2.5)", document.NewIntegerValue(3), false},
+		{"GREATEST(-1.5, -1)", document.NewIntegerValue(-1), false},
+		{"GREATEST(1)", document.NewIntegerValue(1), false},
+		{"GREATEST('a', 'c', 'b')", document.NewTextValue("c"), false},
+		{"GREATEST(NULL, 1, NULL)", document.NewIntegerValue(1), false},
+		{"GREATEST(NULL, NULL)", nullLitteral, false},
+		{"GREATEST(a, 2)", document.NewIntegerValue(2), false},
+		{"LEAST(1, 3, 2)", document.NewIntegerValue(1), false},
+		{"LEAST(1, 0.5, 2)", document.NewDoubleValue(0.5), false},
+		{"LEAST(-1, -1.5)", document.NewDoubleValue(-1.5), false},
+		{"LEAST('b', 'a', 'c')", document.NewTextValue("a"), false},
+		{"LEAST(2, NULL, 1)", document.NewIntegerValue(1), false},
+		{"LEAST(NULL)", nullLitteral, false},
+		{"LEAST(a, 2)", document.NewIntegerValue(1), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, stackWithDoc, test.res, test.fails)
+		})
+	}
+
+	t.Run("Overflow", func(t *testing.T) {
+		e, _, err := parser.NewParser(strings.NewReader("ABS(-9223372036854775808)")).ParseExpr()
+		require.NoError(t, err)
+		_, err = e.Eval(stackWithDoc)
+		require.True(t, errors.Is(err, document.ErrIntegerOverflow), err)
+	})
+
+	t.Run("Arguments", func(t *testing.T) {
+		for _, s := range []string{"ROUND()", "ROUND(1, 2, 3)", "FLOOR()", "CEIL(1, 2)", "ABS()", "GREATEST()", "LEAST()"} {
+			_, _, err := parser.NewParser(strings.NewReader(s)).ParseExpr()
+			require.Error(t, err, s)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package expr
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/genjidb/genji/document"
@@ -22,10 +23,46 @@ func (v LiteralValue) IsEqual(other Expr) bool {
 }
 
 // String implements the fmt.Stringer interface.
+// It returns a representation of the value that can be parsed back
+// into the same literal.
 func (v LiteralValue) String() string {
+	switch v.Type {
+	case document.TextValue:
+		return quoteString(v.V.(string))
+	case document.DoubleValue:
+		s := strconv.FormatFloat(v.V.(float64), 'f', -1, 64)
+		// make sure the value isn't parsed back as an integer
+		if !strings.ContainsAny(s, ".NI") {
+			s += ".0"
+		}
+		return s
+	}
+
 	return document.Value(v).String()
 }
 
+// quoteString returns a double-quoted string literal, only escaping
+// the characters that are supported by the scanner.
+func quoteString(s string) string {
+	var b strings.Builder
+
+	b.WriteRune('"')
+	for _, c := range s {
+		switch c {
+		case '"', '\\':
+			b.WriteRune('\\')
+			b.WriteRune(c)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteRune('"')
+
+	return b.String()
+}
+
 // BlobValue creates a litteral value of type Blob.
 func BlobValue(v []byte) LiteralValue {
 	return LiteralValue(document.NewBlobValue(v))
@@ -132,7 +169,7 @@ type KVPair struct {
 
 // String implements the fmt.Stringer interface.
 func (p KVPair) String() string {
-	return fmt.Sprintf("%q: %v", p.K, p.V)
+	return fmt.Sprintf("%s: %v", quoteString(p.K), p.V)
 }
 
 // KVPairs is a list of KVPair.
@@ -19,6 +19,8 @@ func And(a, b Expr) Expr {
 
 // Eval implements the Expr interface. It evaluates a and b and returns true if both evaluate
 // to true.
+// b is not evaluated if a is falsy. Null values are falsy: NULL AND b returns false
+// without evaluating b.
 func (op *AndOp) Eval(ctx EvalStack) (document.Value, error) {
 	s, err := op.a.Eval(ctx)
 	if err != nil {
@@ -58,6 +60,8 @@ func Or(a, b Expr) Expr {
 
 // Eval implements the Expr interface. It evaluates a and b and returns true if a or b evalutate
 // to true.
+// b is not evaluated if a is truthy. Null values are falsy: NULL OR b evaluates b
+// and returns false if it is falsy.
 func (op *OrOp) Eval(ctx EvalStack) (document.Value, error) {
 	s, err := op.a.Eval(ctx)
 	if err != nil {
@@ -90,3 +94,51 @@ func (op *OrOp) Eval(ctx EvalStack) (document.Value, error) {
 func (op *OrOp) String() string {
 	return fmt.Sprintf("%v OR %v", op.a, op.b)
 }
+
+// NotOp is the NOT operator.
+type NotOp struct {
+	E Expr
+}
+
+// Not creates an expression that returns true if e is falsy and false if e is truthy.
+func Not(e Expr) Expr {
+	return NotOp{E: e}
+}
+
+// Eval implements the Expr interface. It evaluates e and negates its truthiness.
+// NOT NULL returns NULL, which is falsy.
+func (op NotOp) Eval(ctx EvalStack) (document.Value, error) {
+	v, err := op.E.Eval(ctx)
+	if err != nil {
+		return falseLitteral, err
+	}
+	if v.Type == document.NullValue {
+		return nullLitteral, nil
+	}
+
+	isTruthy, err := v.IsTruthy()
+	if err != nil {
+		return falseLitteral, err
+	}
+	if isTruthy {
+		return falseLitteral, nil
+	}
+
+	return trueLitteral, nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (op NotOp) IsEqual(other Expr) bool {
+	o, ok := other.(NotOp)
+	if !ok {
+		return false
+	}
+
+	return Equal(op.E, o.E)
+}
+
+// String implements the fmt.Stringer interface.
+func (op NotOp) String() string {
+	return fmt.Sprintf("NOT %v", op.E)
+}
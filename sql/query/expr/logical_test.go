@@ -0,0 +1,66 @@
+package expr_test
+
+import (
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+func TestLogicalExpr(t *testing.T) {
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"true AND true", document.NewBoolValue(true), false},
+		{"true AND false", document.NewBoolValue(false), false},
+		{"1 AND a", document.NewBoolValue(true), false},
+		{"a AND NULL", document.NewBoolValue(false), false},
+		{"true OR false", document.NewBoolValue(true), false},
+		{"false OR false", document.NewBoolValue(false), false},
+		{"0 OR a", document.NewBoolValue(true), false},
+		{"NULL OR notFound", document.NewBoolValue(false), false},
+		{"notFound IS NOT NULL AND notFound > 10", document.NewBoolValue(false), false},
+		{"NOT true", document.NewBoolValue(false), false},
+		{"NOT false", document.NewBoolValue(true), false},
+		{"NOT 0", document.NewBoolValue(true), false},
+		{"NOT a", document.NewBoolValue(false), false},
+		{"NOT NULL", nullLitteral, false},
+		{"NOT notFound", nullLitteral, false},
+		{"NOT a = 2", document.NewBoolValue(true), false},
+		{"NOT NOT a", document.NewBoolValue(true), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, stackWithDoc, test.res, test.fails)
+		})
+	}
+}
+
+// Without document, evaluating a path returns an error,
+// which shows whether the right operand is evaluated or not.
+func TestLogicalExprShortCircuit(t *testing.T) {
+	tests := []struct {
+		expr  string
+		res   document.Value
+		fails bool
+	}{
+		{"false AND a", document.NewBoolValue(false), false},
+		{"NULL AND a", document.NewBoolValue(false), false},
+		{"true AND a", nullLitteral, true},
+		{"true OR a", document.NewBoolValue(true), false},
+		{"false OR a", nullLitteral, true},
+		{"NULL OR a", nullLitteral, true},
+		{"false AND (true OR a)", document.NewBoolValue(false), false},
+		{"true OR (false AND a) OR a", document.NewBoolValue(true), false},
+		{"a AND false", nullLitteral, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			testExpr(t, test.expr, expr.EvalStack{}, test.res, test.fails)
+		})
+	}
+}
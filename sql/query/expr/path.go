@@ -1,7 +1,11 @@
 package expr
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/scanner"
 )
 
 // A FieldSelector is a ResultField that extracts a field from a document at a given path.
@@ -9,10 +13,12 @@ type FieldSelector document.ValuePath
 
 // Name joins the chunks of the fields selector with the . separator.
 func (f FieldSelector) Name() string {
-	return f.String()
+	return document.ValuePath(f).String()
 }
 
 // Eval extracts the document from the context and selects the right field.
+// If the field doesn't exist, it returns NULL: a missing field and a field
+// explicitly set to NULL can't be distinguished, by IS NULL or any other operator.
 // It implements the Expr interface.
 func (f FieldSelector) Eval(stack EvalStack) (document.Value, error) {
 	if stack.Document == nil {
@@ -52,6 +58,54 @@ func (f FieldSelector) IsEqual(other Expr) bool {
 	return true
 }
 
+// String returns the path of the selector, quoting the field names
+// that are not valid identifiers with backquotes.
 func (f FieldSelector) String() string {
-	return document.ValuePath(f).String()
+	var b strings.Builder
+
+	for i := range f {
+		if f[i].FieldName != "" {
+			if i != 0 {
+				b.WriteRune('.')
+			}
+			b.WriteString(quoteIdent(f[i].FieldName))
+		} else {
+			b.WriteString("[" + strconv.Itoa(f[i].ArrayIndex) + "]")
+		}
+	}
+
+	return b.String()
+}
+
+// quoteIdent returns the identifier as is if it can be parsed
+// as is, otherwise it returns it surrounded by backquotes.
+func quoteIdent(ident string) string {
+	valid := ident != "" && scanner.Lookup(ident) == scanner.IDENT
+	for i, c := range ident {
+		if !valid {
+			break
+		}
+		valid = c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')
+	}
+	if valid {
+		return ident
+	}
+
+	var b strings.Builder
+
+	b.WriteRune('`')
+	for _, c := range ident {
+		switch c {
+		case '`', '\\':
+			b.WriteRune('\\')
+			b.WriteRune(c)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteRune('`')
+
+	return b.String()
 }
@@ -15,6 +15,9 @@ type InsertStmt struct {
 	TableName  string
 	FieldNames []string
 	Values     expr.LiteralExprList
+	// If true, a single document is inserted, made only of
+	// the default values of the table.
+	DefaultValues bool
 }
 
 // IsReadOnly always returns false. It implements the Statement interface.
@@ -31,7 +34,7 @@ func (stmt InsertStmt) Run(ctx context.Context, tx *database.Transaction, args [
 		return res, errors.New("missing table name")
 	}
 
-	if stmt.Values == nil {
+	if stmt.Values == nil && !stmt.DefaultValues {
 		return res, errors.New("values are empty")
 	}
 
@@ -45,53 +48,66 @@ func (stmt InsertStmt) Run(ctx context.Context, tx *database.Transaction, args [
 		Params: args,
 	}
 
-	if len(stmt.FieldNames) > 0 {
-		return stmt.insertExprList(t, stack)
-	}
+	err = stmt.iterateDocuments(stack, func(d document.Document) error {
+		k, err := t.Insert(d)
+		if err != nil {
+			return err
+		}
 
-	return stmt.insertDocuments(t, stack)
+		res.LastInsertKey = k
+		res.RowsAffected++
+		return nil
+	})
+
+	return res, err
 }
 
-func (stmt InsertStmt) insertDocuments(t *database.Table, stack expr.EvalStack) (Result, error) {
-	var res Result
+// iterateDocuments evaluates the values of the statement and calls fn
+// for every document to insert.
+func (stmt InsertStmt) iterateDocuments(stack expr.EvalStack, fn func(d document.Document) error) error {
+	if stmt.DefaultValues {
+		// the document is filled with the default values
+		// when validating the constraints of the table
+		return fn(document.NewFieldBuffer())
+	}
+
+	if len(stmt.FieldNames) > 0 {
+		return stmt.iterateExprList(stack, fn)
+	}
 
 	for _, e := range stmt.Values {
 		v, err := e.Eval(stack)
 		if err != nil {
-			return res, err
+			return err
 		}
 
 		if v.Type != document.DocumentValue {
-			return res, fmt.Errorf("expected document, got %s", v.Type)
+			return fmt.Errorf("expected document, got %s", v.Type)
 		}
 
-		res.LastInsertKey, err = t.Insert(v.V.(document.Document))
+		err = fn(v.V.(document.Document))
 		if err != nil {
-			return res, err
+			return err
 		}
-
-		res.RowsAffected++
 	}
 
-	return res, nil
+	return nil
 }
 
-func (stmt InsertStmt) insertExprList(t *database.Table, stack expr.EvalStack) (Result, error) {
-	var res Result
-
+func (stmt InsertStmt) iterateExprList(stack expr.EvalStack, fn func(d document.Document) error) error {
 	// iterate over all of the documents (r1, r2, r3, ...)
 	for _, e := range stmt.Values {
 		var fb document.FieldBuffer
 
 		v, err := e.Eval(stack)
 		if err != nil {
-			return res, err
+			return err
 		}
 
 		// each document must be a list of expressions
 		// (e1, e2, e3, ...) or [e1, e2, e2, ....]
 		if v.Type != document.ArrayValue {
-			return res, fmt.Errorf("expected array, got %s", v.Type)
+			return fmt.Errorf("expected array, got %s", v.Type)
 		}
 
 		// iterate over each value
@@ -105,13 +121,105 @@ func (stmt InsertStmt) insertExprList(t *database.Table, stack expr.EvalStack) (
 			return nil
 		})
 
-		res.LastInsertKey, err = t.Insert(&fb)
+		err = fn(&fb)
 		if err != nil {
-			return res, err
+			return err
 		}
+	}
 
-		res.RowsAffected++
+	return nil
+}
+
+// Validate checks that the table exists and that every document satisfies the constraints of the table,
+// including primary key uniqueness, without inserting anything.
+// It implements the Validator interface.
+func (stmt InsertStmt) Validate(tx *database.Transaction, args []expr.Param) error {
+	if stmt.TableName == "" {
+		return ValidationErrors{{Err: errors.New("missing table name")}}
+	}
+
+	t, err := validateTable(tx, stmt.TableName)
+	if err != nil {
+		return err
+	}
+
+	info, err := t.Info()
+	if err != nil {
+		return err
+	}
+	pk := info.GetPrimaryKey()
+
+	var errs ValidationErrors
+	addError := func(path document.ValuePath, err error) {
+		errs = append(errs, &ValidationError{TableName: stmt.TableName, Path: path, Err: err})
+	}
+
+	// keys of the documents of the statement, to detect duplicates among them
+	keys := make(map[string]struct{})
+
+	// errors that are not caused by the statement itself
+	var dbErr error
+
+	err = stmt.iterateDocuments(expr.EvalStack{Tx: tx, Params: args}, func(d document.Document) error {
+		dbErr = stmt.validateDocument(t, pk, d, keys, addError)
+		return dbErr
+	})
+	if dbErr != nil {
+		return dbErr
+	}
+	if err != nil {
+		// errors returned while evaluating the values
+		// are caused by the statement itself
+		addError(nil, err)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// validateDocument reports the constraints that d violates using addError.
+func (stmt InsertStmt) validateDocument(t *database.Table, pk *database.FieldConstraint, d document.Document, keys map[string]struct{}, addError func(document.ValuePath, error)) error {
+	d, err := t.ValidateConstraints(d)
+	if err != nil {
+		var cerr *database.ConstraintError
+		if !errors.As(err, &cerr) {
+			return err
+		}
+
+		addError(cerr.Path, cerr.Err)
+		return nil
+	}
+
+	if pk == nil {
+		return nil
+	}
+
+	v, err := pk.Path.GetValue(d)
+	if err == document.ErrFieldNotFound {
+		addError(pk.Path, errors.New("missing primary key"))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	k, err := t.EncodeKey(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.GetDocument(k)
+	if _, ok := keys[string(k)]; ok || err == nil {
+		addError(pk.Path, database.ErrDuplicateDocument)
+		return nil
+	}
+	if err != database.ErrDocumentNotFound {
+		return err
 	}
 
-	return res, nil
+	keys[string(k)] = struct{}{}
+	return nil
 }
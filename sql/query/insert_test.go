@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/genjidb/genji"
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/querytest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -98,7 +100,50 @@ func TestInsertStmt(t *testing.T) {
 		require.NoError(t, err)
 
 		err = db.Exec(ctx, `INSERT INTO test (bar, foo) VALUES (1, 2)`)
-		require.Equal(t, err, database.ErrDuplicateDocument)
+		require.True(t, errors.Is(err, database.ErrDuplicateDocument), err)
+	})
+
+	t.Run("with NOCASE primary key", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test (foo TEXT PRIMARY KEY COLLATE NOCASE)")
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `INSERT INTO test (foo) VALUES ('a')`)
+		require.NoError(t, err)
+		err = db.Exec(ctx, `INSERT INTO test (foo) VALUES ('A')`)
+		require.True(t, errors.Is(err, database.ErrDuplicateDocument), err)
+
+		// the key is looked up regardless of its case, the value is returned unchanged
+		d, err := db.QueryDocument(ctx, `SELECT foo FROM test WHERE pk() = 'A'`)
+		require.NoError(t, err)
+		var foo string
+		err = document.Scan(d, &foo)
+		require.NoError(t, err)
+		require.Equal(t, "a", foo)
+	})
+
+	t.Run("with null primary key", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test (foo PRIMARY KEY)")
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `INSERT INTO test (bar) VALUES (1)`)
+		require.EqualError(t, err, `primary key "foo" is required and must be not null`)
+		err = db.Exec(ctx, `INSERT INTO test (bar, foo) VALUES (1, NULL)`)
+		require.EqualError(t, err, `primary key "foo" is required and must be not null`)
+		err = db.Exec(ctx, `INSERT INTO test (bar, foo) VALUES (1, 2)`)
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `UPDATE test SET foo = NULL`)
+		require.EqualError(t, err, `primary key "foo" is required and must be not null`)
+		err = db.Exec(ctx, `UPDATE test UNSET foo`)
+		require.EqualError(t, err, `primary key "foo" is required and must be not null`)
 	})
 
 	t.Run("with shadowing", func(t *testing.T) {
@@ -179,6 +224,171 @@ func TestInsertStmt(t *testing.T) {
 		  }`, buf.String())
 	})
 
+	t.Run("with default values", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `CREATE TABLE test(a INTEGER DEFAULT 10 NOT NULL, b TEXT DEFAULT now(), c TEXT DEFAULT uuid())`)
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `INSERT INTO test (a) VALUES (1), (2)`)
+		require.NoError(t, err)
+		err = db.Exec(ctx, `INSERT INTO test (b) VALUES ('foo')`)
+		require.NoError(t, err)
+
+		res, err := db.Query(ctx, "SELECT a, b, c FROM test")
+		require.NoError(t, err)
+		defer res.Close()
+
+		type row struct {
+			A int
+			B string
+			C string
+		}
+		var rows []row
+		err = res.Iterate(func(d document.Document) error {
+			var r row
+			err := document.StructScan(d, &r)
+			rows = append(rows, r)
+			return err
+		})
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+
+		require.Equal(t, 1, rows[0].A)
+		require.Equal(t, 2, rows[1].A)
+		require.Equal(t, 10, rows[2].A)
+
+		// now() is evaluated once per transaction
+		require.NotEmpty(t, rows[0].B)
+		require.Equal(t, rows[0].B, rows[1].B)
+		require.Equal(t, "foo", rows[2].B)
+
+		// uuid() is evaluated for every document
+		require.Len(t, rows[0].C, 36)
+		require.NotEqual(t, rows[0].C, rows[1].C)
+		require.NotEqual(t, rows[1].C, rows[2].C)
+	})
+
+	t.Run("with default expressions", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test(a INTEGER DEFAULT 1 + 2 * 3 NOT NULL, b DOUBLE DEFAULT (1.5 + 1) * 2, c BOOL DEFAULT (1 NOT IN (2, 3)));
+			INSERT INTO test DEFAULT VALUES;
+		`)
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument(ctx, "SELECT a, b, c FROM test")
+		require.NoError(t, err)
+		data, err := document.MarshalJSON(d)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"a": 7, "b": 5.0, "c": true}`, string(data))
+	})
+
+	t.Run("with default values only", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test(a INTEGER DEFAULT 10 NOT NULL, b TEXT DEFAULT now(), c TEXT DEFAULT uuid(), d DOUBLE);
+			CREATE TABLE required(a INTEGER DEFAULT 10, b INTEGER NOT NULL);
+		`)
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `INSERT INTO test DEFAULT VALUES`)
+		require.NoError(t, err)
+		err = db.Exec(ctx, `INSERT INTO test DEFAULT VALUES`)
+		require.NoError(t, err)
+
+		res, err := db.Query(ctx, "SELECT a, b, c, d FROM test")
+		require.NoError(t, err)
+
+		type row struct {
+			A int
+			B string
+			C string
+			D *float64
+		}
+		var rows []row
+		err = res.Iterate(func(d document.Document) error {
+			var r row
+			err := document.StructScan(d, &r)
+			rows = append(rows, r)
+			return err
+		})
+		require.NoError(t, err)
+		require.NoError(t, res.Close())
+		require.Len(t, rows, 2)
+
+		for _, r := range rows {
+			require.Equal(t, 10, r.A)
+			require.NotEmpty(t, r.B)
+			require.Len(t, r.C, 36)
+			require.Nil(t, r.D)
+		}
+		require.NotEqual(t, rows[0].C, rows[1].C)
+
+		err = db.Exec(ctx, `INSERT INTO required DEFAULT VALUES`)
+		require.EqualError(t, err, `field "b" is required and must be not null`)
+	})
+
+	t.Run("with strict tables", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			query string
+			fails bool
+		}{
+			{"declared fields", `INSERT INTO test (a, b) VALUES (1, {c: 'foo'})`, false},
+			{"subset of declared fields", `INSERT INTO test (b) VALUES ({c: 'foo'})`, false},
+			{"undeclared field", `INSERT INTO test (a, b, d) VALUES (1, {c: 'foo'}, 2)`, true},
+			{"undeclared nested field", `INSERT INTO test (a, b) VALUES (1, {c: 'foo', d: 1})`, false},
+		}
+
+		for _, strict := range []bool{false, true} {
+			for _, test := range tests {
+				t.Run(fmt.Sprintf("strict: %v/%s", strict, test.name), func(t *testing.T) {
+					db, err := genji.Open(":memory:")
+					require.NoError(t, err)
+					defer db.Close()
+
+					q := "CREATE TABLE test (a INTEGER, b.c TEXT)"
+					if strict {
+						q += " STRICT"
+					}
+					err = db.Exec(ctx, q)
+					require.NoError(t, err)
+
+					err = db.Exec(ctx, test.query)
+					// schemaless tables accept any field
+					if strict && test.fails {
+						require.EqualError(t, err, `field "d" is not declared in strict table "test"`)
+						return
+					}
+					require.NoError(t, err)
+				})
+			}
+		}
+
+		t.Run("update", func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `CREATE TABLE test (a INTEGER) STRICT; INSERT INTO test (a) VALUES (1)`)
+			require.NoError(t, err)
+
+			err = db.Exec(ctx, `UPDATE test SET a = 2`)
+			require.NoError(t, err)
+			err = db.Exec(ctx, `UPDATE test SET d = 2`)
+			require.Error(t, err)
+		})
+	})
+
 	t.Run("with tests that require an error", func(t *testing.T) {
 		tests := []struct {
 			name            string
@@ -266,3 +476,125 @@ func TestInsertStmt(t *testing.T) {
 		}
 	})
 }
+
+func TestInsertSelectStmt(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		query    string
+		fails    bool
+		expected string
+	}{
+		{"Wildcard", `INSERT INTO test SELECT * FROM foo`, false, `[{"a": 1, "b": 10}, {"a": 2, "b": 20}, {"a": 3}]`},
+		{"With cond", `INSERT INTO test SELECT * FROM foo WHERE a > 1`, false, `[{"a": 2, "b": 20}, {"a": 3}]`},
+		{"With fields", `INSERT INTO test (c, d) SELECT a, b + 1 FROM foo`, false, `[{"c": 1, "d": 11}, {"c": 2, "d": 21}, {"c": 3, "d": null}]`},
+		{"With limit", `INSERT INTO test SELECT a FROM foo ORDER BY a DESC LIMIT 2`, false, `[{"a": 3}, {"a": 2}]`},
+		{"Too many fields", `INSERT INTO test (c) SELECT a, b FROM foo`, true, ``},
+		{"Unknown table", `INSERT INTO test SELECT * FROM unknown`, true, ``},
+		{"Read-only table", `INSERT INTO __genji_tables SELECT * FROM foo`, true, ``},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.OpenWithOptions(":memory:", &genji.Options{InsertBatchSize: 2})
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE test;
+				CREATE TABLE foo;
+				INSERT INTO foo (a, b) VALUES (1, 10), (2, 20);
+				INSERT INTO foo (a) VALUES (3);
+			`)
+			require.NoError(t, err)
+
+			err = db.Exec(ctx, test.query)
+			if test.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			st, err := db.Query(ctx, "SELECT * FROM test")
+			require.NoError(t, err)
+			defer st.Close()
+
+			querytest.AssertResultsEqual(t, st, test.expected)
+		})
+	}
+
+	t.Run("Into same table", func(t *testing.T) {
+		db, err := genji.OpenWithOptions(":memory:", &genji.Options{InsertBatchSize: 2})
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE foo;
+			INSERT INTO foo (a) VALUES (1), (2), (3);
+			INSERT INTO foo SELECT a + 10 AS a FROM foo;
+		`)
+		require.NoError(t, err)
+
+		st, err := db.Query(ctx, "SELECT a FROM foo")
+		require.NoError(t, err)
+		defer st.Close()
+
+		querytest.AssertResultsEqual(t, st, `[{"a": 1}, {"a": 2}, {"a": 3}, {"a": 11}, {"a": 12}, {"a": 13}]`)
+	})
+
+	t.Run("With bounded memory", func(t *testing.T) {
+		const n = 1000
+
+		// the limit is large enough for a batch of documents,
+		// but not for the whole source
+		db, err := genji.OpenWithOptions(":memory:", &genji.Options{
+			InsertBatchSize:      10,
+			StatementMemoryLimit: 2000,
+		})
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE big; CREATE TABLE archive")
+		require.NoError(t, err)
+		for i := 0; i < n; i++ {
+			err = db.Exec(ctx, "INSERT INTO big (a, b) VALUES (?, ?)", i, fmt.Sprintf("document %d", i))
+			require.NoError(t, err)
+		}
+
+		err = db.Exec(ctx, "INSERT INTO archive SELECT * FROM big")
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument(ctx, "SELECT COUNT(*) AS n, SUM(a) AS s FROM archive")
+		require.NoError(t, err)
+		var count, sum int
+		err = document.Scan(d, &count, &sum)
+		require.NoError(t, err)
+		require.Equal(t, n, count)
+		require.Equal(t, n*(n-1)/2, sum)
+
+		// the source must be read entirely before inserting into the same table
+		err = db.Exec(ctx, "INSERT INTO big SELECT * FROM big")
+		require.True(t, errors.Is(err, database.ErrResultTooLarge), err)
+	})
+
+	t.Run("With unique index", func(t *testing.T) {
+		db, err := genji.OpenWithOptions(":memory:", &genji.Options{InsertBatchSize: 2})
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE foo;
+			CREATE TABLE test;
+			CREATE UNIQUE INDEX idx_test_a ON test (a);
+			INSERT INTO foo (a) VALUES (1), (2), (3), (2);
+		`)
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, "INSERT INTO test SELECT * FROM foo")
+		var cErr *database.ConstraintError
+		require.True(t, errors.As(err, &cErr), err)
+		require.Equal(t, database.ErrUniqueViolation, cErr.Violation)
+		require.Equal(t, document.NewIntegerValue(2), cErr.Value)
+	})
+}
@@ -0,0 +1,46 @@
+package query
+
+import "errors"
+
+var (
+	// ErrNotPaginated is returned by Result.NextToken if the statement wasn't paginated.
+	ErrNotPaginated = errors.New("result is not paginated")
+
+	// ErrInvalidToken is returned when resuming a statement from a token that is malformed
+	// or that was returned by a different statement.
+	ErrInvalidToken = errors.New("invalid page token")
+)
+
+// A PageToken paginates the SELECT statement it is passed to, alongside the arguments
+// of the query. The statement returns the documents that follow the position encoded
+// in the token, and the token of the next page is returned by Result.NextToken.
+// The empty token starts from the first document:
+//
+//	res, err := db.Query(ctx, "SELECT * FROM foo ORDER BY a LIMIT 10", query.PageToken(""))
+//
+// The documents of a paginated statement are sorted by the ORDER BY clause, then by primary key,
+// or only by primary key if there is no ORDER BY clause. Unlike OFFSET, resuming from a token
+// neither skips nor repeats documents when documents are inserted or deleted between two pages.
+//
+// A token can only resume the statement that returned it, with the same parameters.
+// Only the LIMIT and OFFSET clauses can change, unless they are parameters.
+// Otherwise, the statement fails with ErrInvalidToken.
+type PageToken string
+
+// A Cursor tracks the position of the last document returned by a paginated statement.
+type Cursor interface {
+	// Token returns a token encoding the position of the last document returned.
+	Token() (string, error)
+}
+
+// NextToken returns the token of the page that follows the documents returned by the result.
+// It must be called once the result was iterated. If the result didn't return any document,
+// the token the statement was resumed from is returned.
+// If the statement wasn't paginated, it returns ErrNotPaginated.
+func (r *Result) NextToken() (string, error) {
+	if r.Cursor == nil {
+		return "", ErrNotPaginated
+	}
+
+	return r.Cursor.Token()
+}
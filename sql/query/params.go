@@ -0,0 +1,157 @@
+package query
+
+import (
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// A ParamSource provides a list of parameters whose values are already typed.
+// It can be passed to the Exec and Query methods alongside raw arguments, in which case
+// its parameters are inserted in place of it.
+type ParamSource interface {
+	Params() []expr.Param
+}
+
+// NamedParamList is a list of typed named parameters.
+// Unlike raw arguments, whose types are guessed using reflection,
+// the type of each parameter is chosen explicitly:
+//
+//	query.Params().Int("age", 10).Text("name", "bob")
+type NamedParamList struct {
+	params []expr.Param
+}
+
+var _ ParamSource = (*NamedParamList)(nil)
+
+// Params returns an empty list of named parameters.
+func Params() *NamedParamList {
+	return new(NamedParamList)
+}
+
+// Value binds v to the parameter with the given name.
+func (p *NamedParamList) Value(name string, v document.Value) *NamedParamList {
+	p.params = append(p.params, expr.Param{Name: name, Value: v})
+	return p
+}
+
+// Null binds NULL to the parameter with the given name.
+func (p *NamedParamList) Null(name string) *NamedParamList {
+	return p.Value(name, document.NewNullValue())
+}
+
+// Bool binds a boolean to the parameter with the given name.
+func (p *NamedParamList) Bool(name string, x bool) *NamedParamList {
+	return p.Value(name, document.NewBoolValue(x))
+}
+
+// Int binds an integer to the parameter with the given name.
+func (p *NamedParamList) Int(name string, x int64) *NamedParamList {
+	return p.Value(name, document.NewIntegerValue(x))
+}
+
+// Double binds a double to the parameter with the given name.
+func (p *NamedParamList) Double(name string, x float64) *NamedParamList {
+	return p.Value(name, document.NewDoubleValue(x))
+}
+
+// Point binds a point to the parameter with the given name.
+func (p *NamedParamList) Point(name string, x document.Point) *NamedParamList {
+	return p.Value(name, document.NewPointValue(x))
+}
+
+// Text binds a text to the parameter with the given name.
+func (p *NamedParamList) Text(name string, x string) *NamedParamList {
+	return p.Value(name, document.NewTextValue(x))
+}
+
+// Blob binds a blob to the parameter with the given name.
+func (p *NamedParamList) Blob(name string, x []byte) *NamedParamList {
+	return p.Value(name, document.NewBlobValue(x))
+}
+
+// Array binds an array to the parameter with the given name.
+func (p *NamedParamList) Array(name string, x document.Array) *NamedParamList {
+	return p.Value(name, document.NewArrayValue(x))
+}
+
+// Document binds a document to the parameter with the given name.
+func (p *NamedParamList) Document(name string, x document.Document) *NamedParamList {
+	return p.Value(name, document.NewDocumentValue(x))
+}
+
+// Params returns the list of parameters. It implements the ParamSource interface.
+func (p *NamedParamList) Params() []expr.Param {
+	return p.params
+}
+
+// PositionalParamList is a list of typed positional parameters.
+// The first value is bound to the first ? of the statement, the second value
+// to the second ?, and so on:
+//
+//	query.PositionalParams().Int(10).Text("bob")
+type PositionalParamList struct {
+	params []expr.Param
+}
+
+var _ ParamSource = (*PositionalParamList)(nil)
+
+// PositionalParams returns an empty list of positional parameters.
+func PositionalParams() *PositionalParamList {
+	return new(PositionalParamList)
+}
+
+// Value binds v to the next parameter.
+func (p *PositionalParamList) Value(v document.Value) *PositionalParamList {
+	p.params = append(p.params, expr.Param{Value: v})
+	return p
+}
+
+// Null binds NULL to the next parameter.
+func (p *PositionalParamList) Null() *PositionalParamList {
+	return p.Value(document.NewNullValue())
+}
+
+// Bool binds a boolean to the next parameter.
+func (p *PositionalParamList) Bool(x bool) *PositionalParamList {
+	return p.Value(document.NewBoolValue(x))
+}
+
+// Int binds an integer to the next parameter.
+func (p *PositionalParamList) Int(x int64) *PositionalParamList {
+	return p.Value(document.NewIntegerValue(x))
+}
+
+// Double binds a double to the next parameter.
+func (p *PositionalParamList) Double(x float64) *PositionalParamList {
+	return p.Value(document.NewDoubleValue(x))
+}
+
+// Point binds a point to the next parameter.
+func (p *PositionalParamList) Point(x document.Point) *PositionalParamList {
+	return p.Value(document.NewPointValue(x))
+}
+
+// Text binds a text to the next parameter.
+func (p *PositionalParamList) Text(x string) *PositionalParamList {
+	return p.Value(document.NewTextValue(x))
+}
+
+// Blob binds a blob to the next parameter.
+func (p *PositionalParamList) Blob(x []byte) *PositionalParamList {
+	return p.Value(document.NewBlobValue(x))
+}
+
+// Array binds an array to the next parameter.
+func (p *PositionalParamList) Array(x document.Array) *PositionalParamList {
+	return p.Value(document.NewArrayValue(x))
+}
+
+// Document binds a document to the next parameter.
+func (p *PositionalParamList) Document(x document.Document) *PositionalParamList {
+	return p.Value(document.NewDocumentValue(x))
+}
+
+// Params returns the list of parameters. It implements the ParamSource interface.
+func (p *PositionalParamList) Params() []expr.Param {
+	return p.params
+}
@@ -0,0 +1,97 @@
+package query_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParams(t *testing.T) {
+	doc := document.NewFieldBuffer().Add("a", document.NewIntegerValue(1))
+	arr := document.NewValueBuffer(document.NewTextValue("a"))
+
+	tests := []struct {
+		name       string
+		named      *query.NamedParamList
+		positional *query.PositionalParamList
+		expected   document.Value
+	}{
+		{"null", query.Params().Null("p"), query.PositionalParams().Null(), document.NewNullValue()},
+		{"bool", query.Params().Bool("p", true), query.PositionalParams().Bool(true), document.NewBoolValue(true)},
+		{"integer", query.Params().Int("p", 10), query.PositionalParams().Int(10), document.NewIntegerValue(10)},
+		{"double", query.Params().Double("p", 10), query.PositionalParams().Double(10), document.NewDoubleValue(10)},
+		{"point", query.Params().Point("p", document.Point{Lat: 1, Lng: 2}), query.PositionalParams().Point(document.Point{Lat: 1, Lng: 2}), document.NewPointValue(document.Point{Lat: 1, Lng: 2})},
+		{"text", query.Params().Text("p", "bob"), query.PositionalParams().Text("bob"), document.NewTextValue("bob")},
+		{"blob", query.Params().Blob("p", []byte("bob")), query.PositionalParams().Blob([]byte("bob")), document.NewBlobValue([]byte("bob"))},
+		{"array", query.Params().Array("p", arr), query.PositionalParams().Array(arr), document.NewArrayValue(arr)},
+		{"document", query.Params().Document("p", doc), query.PositionalParams().Document(doc), document.NewDocumentValue(doc)},
+		{"value", query.Params().Value("p", document.NewTextValue("bob")), query.PositionalParams().Value(document.NewTextValue("bob")), document.NewTextValue("bob")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := expr.NamedParam("p").Eval(expr.EvalStack{Params: test.named.Params()})
+			require.NoError(t, err)
+			require.Equal(t, test.expected, v)
+
+			v, err = expr.PositionalParam(1).Eval(expr.EvalStack{Params: test.positional.Params()})
+			require.NoError(t, err)
+			require.Equal(t, test.expected, v)
+		})
+	}
+
+	t.Run("order", func(t *testing.T) {
+		params := query.PositionalParams().Int(1).Text("a").Params()
+		require.Equal(t, []expr.Param{
+			{Value: document.NewIntegerValue(1)},
+			{Value: document.NewTextValue("a")},
+		}, params)
+
+		params = query.Params().Int("b", 1).Text("a", "a").Params()
+		require.Equal(t, []expr.Param{
+			{Name: "b", Value: document.NewIntegerValue(1)},
+			{Name: "a", Value: document.NewTextValue("a")},
+		}, params)
+	})
+
+	t.Run("exec", func(t *testing.T) {
+		ctx := context.Background()
+
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test")
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, "INSERT INTO test (a, b, c) VALUES ($a, $b, $c)",
+			query.Params().Double("a", 1).Text("b", "bob"), sql.Named("c", 10))
+		require.NoError(t, err)
+
+		// the typed parameters are inserted in place of the list
+		err = db.Exec(ctx, "INSERT INTO test (a, b, c) VALUES (?, ?, ?)",
+			query.PositionalParams().Double(2).Text("alice"), 20)
+		require.NoError(t, err)
+
+		for _, test := range []struct {
+			a        float64
+			expected string
+		}{
+			{1, `{"a": 1.0, "b": "bob", "c": 10}`},
+			{2, `{"a": 2.0, "b": "alice", "c": 20}`},
+		} {
+			d, err := db.QueryDocument(ctx, "SELECT a, b, c FROM test WHERE a = ?", query.PositionalParams().Double(test.a))
+			require.NoError(t, err)
+
+			data, err := document.MarshalJSON(d)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, string(data))
+		}
+	})
+}
@@ -3,6 +3,7 @@ package query
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
@@ -31,6 +32,8 @@ func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param
 		q.autoCommit = true
 	}
 
+	args, timeout := splitTimeout(db, args)
+
 	type queryAlterer interface {
 		alterQuery(db *database.Database, q *Query) error
 	}
@@ -61,8 +64,10 @@ func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param
 			}
 		}
 
-		res, err = stmt.Run(ctx, q.tx, args)
+		sctx, cancel := statementContext(ctx, timeout)
+		res, err = stmt.Run(sctx, q.tx, args)
 		if err != nil {
+			cancel()
 			if q.autoCommit {
 				q.tx.Rollback()
 			}
@@ -70,6 +75,13 @@ func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param
 			return nil, err
 		}
 
+		// only the last result is returned, the others are discarded
+		if i+1 < len(q.Statements) {
+			cancel()
+		} else {
+			res.cancel = cancel
+		}
+
 		// it there is an opened transaction but there are still statements
 		// to be executed, close the current transaction.
 		if q.tx != nil && q.autoCommit && i+1 < len(q.Statements) {
@@ -97,22 +109,51 @@ func (q Query) Run(ctx context.Context, db *database.Database, args []expr.Param
 	return &res, nil
 }
 
+// RunEach returns an iterator over the results of every statement of the query,
+// in the order in which they appear in the query.
+// Unlike Run, results are not flattened: each call to Results.Next runs the next
+// statement, allowing the caller to associate each result with its statement.
+// Statements that don't return documents, such as INSERT or BEGIN, yield a result
+// with an empty stream. The returned iterator must always be closed after usage.
+func (q Query) RunEach(ctx context.Context, db *database.Database, args []expr.Param) *Results {
+	q.tx = db.GetAttachedTx()
+	args, timeout := splitTimeout(db, args)
+
+	return &Results{
+		ctx:     ctx,
+		db:      db,
+		q:       q,
+		args:    args,
+		timeout: timeout,
+	}
+}
+
 // Exec the query within the given transaction.
 func (q Query) Exec(ctx context.Context, tx *database.Transaction, args []expr.Param) (*Result, error) {
 	var res Result
 	var err error
 
-	for _, stmt := range q.Statements {
+	args, timeout := splitTimeout(tx.DB(), args)
+
+	for i, stmt := range q.Statements {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
 		}
 
-		res, err = stmt.Run(ctx, tx, args)
+		sctx, cancel := statementContext(ctx, timeout)
+		res, err = stmt.Run(sctx, tx, args)
 		if err != nil {
+			cancel()
 			return nil, err
 		}
+
+		if i+1 < len(q.Statements) {
+			cancel()
+		} else {
+			res.cancel = cancel
+		}
 	}
 
 	return &res, nil
@@ -135,7 +176,11 @@ type Result struct {
 	RowsAffected  int64
 	LastInsertKey []byte
 	Tx            *database.Transaction
-	closed        bool
+	// Cursor of the result, if the statement was paginated.
+	Cursor Cursor
+	closed bool
+	// releases the context of the statement.
+	cancel context.CancelFunc
 }
 
 // Close the result stream.
@@ -153,6 +198,10 @@ func (r *Result) Close() (err error) {
 
 	r.closed = true
 
+	if r.cancel != nil {
+		defer r.cancel()
+	}
+
 	if r.Tx != nil {
 		if r.Tx.Writable() {
 			err = r.Tx.Commit()
@@ -164,6 +213,129 @@ func (r *Result) Close() (err error) {
 	return err
 }
 
+// Results iterates over the results of the statements of a query.
+// Each result is only valid until the next call to Next or Close.
+type Results struct {
+	ctx     context.Context
+	db      *database.Database
+	q       Query
+	args    []expr.Param
+	timeout time.Duration
+	i       int
+	cur     *Result
+	err     error
+}
+
+// Next closes the current result and runs the next statement.
+// It returns false once all the statements were run or if an error
+// occurred, in which case the error is returned by Err.
+func (r *Results) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	r.err = r.closeCurrent()
+	if r.err != nil || r.i >= len(r.q.Statements) {
+		return false
+	}
+
+	select {
+	case <-r.ctx.Done():
+		r.err = r.ctx.Err()
+		return false
+	default:
+	}
+
+	stmt := r.q.Statements[r.i]
+	r.i++
+
+	r.cur, r.err = r.run(stmt)
+	return r.err == nil
+}
+
+func (r *Results) run(stmt Statement) (*Result, error) {
+	type queryAlterer interface {
+		alterQuery(db *database.Database, q *Query) error
+	}
+
+	if qa, ok := stmt.(queryAlterer); ok {
+		err := qa.alterQuery(r.db, &r.q)
+		if err != nil {
+			if tx := r.db.GetAttachedTx(); tx != nil {
+				tx.Rollback()
+			}
+			return nil, err
+		}
+
+		return &Result{}, nil
+	}
+
+	// if there is no active transaction, the statement
+	// is run in its own transaction, owned by its result.
+	tx := r.q.tx
+	if tx == nil {
+		var err error
+		tx, err = r.db.Begin(!stmt.IsReadOnly())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := statementContext(r.ctx, r.timeout)
+	res, err := stmt.Run(ctx, tx, r.args)
+	if err != nil {
+		cancel()
+		if r.q.tx == nil {
+			tx.Rollback()
+		}
+		return nil, err
+	}
+
+	res.cancel = cancel
+	if r.q.tx == nil {
+		res.Tx = tx
+	}
+
+	return &res, nil
+}
+
+func (r *Results) closeCurrent() error {
+	if r.cur == nil {
+		return nil
+	}
+
+	err := r.cur.Close()
+	r.cur = nil
+	return err
+}
+
+// Statement returns the statement whose result is returned by Result.
+func (r *Results) Statement() Statement {
+	if r.cur == nil {
+		return nil
+	}
+
+	return r.q.Statements[r.i-1]
+}
+
+// Result returns the result of the statement that was run
+// by the last call to Next.
+func (r *Results) Result() *Result {
+	return r.cur
+}
+
+// Err returns the error that occurred while iterating, if any.
+func (r *Results) Err() error {
+	return r.err
+}
+
+// Close the current result. The remaining statements are not run.
+func (r *Results) Close() error {
+	err := r.closeCurrent()
+	r.i = len(r.q.Statements)
+	return err
+}
+
 func whereClause(e expr.Expr, stack expr.EvalStack) func(d document.Document) (bool, error) {
 	if e == nil {
 		return func(d document.Document) (bool, error) {
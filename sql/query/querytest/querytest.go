@@ -0,0 +1,143 @@
+// Package querytest provides helpers to test the results of queries.
+package querytest
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AssertResultsEqual asserts that got returns the documents of want, a JSON array of
+// documents, regardless of their order. Result sets are compared as multisets: each
+// document must be returned as many times as it appears in want.
+// Documents are compared using document.Value.IsEqual, so 1 and 1.0 are equal.
+// On mismatch, it reports a diff of both result sets, sorted.
+// Use AssertOrderedResultsEqual for queries with an ORDER BY clause.
+func AssertResultsEqual(t testing.TB, got document.Iterator, want string) bool {
+	t.Helper()
+
+	return assertResultsEqual(t, got, want, false)
+}
+
+// AssertOrderedResultsEqual asserts that got returns the documents of want, a JSON array
+// of documents, in the same order.
+func AssertOrderedResultsEqual(t testing.TB, got document.Iterator, want string) bool {
+	t.Helper()
+
+	return assertResultsEqual(t, got, want, true)
+}
+
+func assertResultsEqual(t testing.TB, got document.Iterator, want string, ordered bool) bool {
+	t.Helper()
+
+	gotValues := collect(t, got)
+	wantValues := parse(t, want)
+
+	if !ordered {
+		sortValues(t, gotValues)
+		sortValues(t, wantValues)
+	}
+
+	if equalValues(t, gotValues, wantValues) {
+		return true
+	}
+
+	wantJSON, gotJSON := toJSON(t, wantValues), toJSON(t, gotValues)
+	if !assert.Equal(t, wantJSON, gotJSON, "result sets are not equal") {
+		return false
+	}
+
+	// the JSON representations of the documents may be identical
+	// even though the values are not, if their types differ.
+	return assert.Fail(t, "result sets are not equal", "expected: %v\nactual  : %v", wantJSON, gotJSON)
+}
+
+// collect copies the documents returned by it, which may be reused between iterations.
+func collect(t testing.TB, it document.Iterator) []document.Value {
+	t.Helper()
+
+	var values []document.Value
+	err := it.Iterate(func(d document.Document) error {
+		var fb document.FieldBuffer
+		err := fb.Copy(d)
+		if err != nil {
+			return err
+		}
+
+		values = append(values, document.NewDocumentValue(&fb))
+		return nil
+	})
+	require.NoError(t, err)
+
+	return values
+}
+
+// parse decodes a JSON array of documents.
+func parse(t testing.TB, data string) []document.Value {
+	t.Helper()
+
+	var raw []json.RawMessage
+	err := json.Unmarshal([]byte(data), &raw)
+	require.NoError(t, err, "expected results must be a JSON array of documents")
+
+	values := make([]document.Value, 0, len(raw))
+	for _, r := range raw {
+		d, err := document.NewFromJSON(r)
+		require.NoError(t, err)
+
+		values = append(values, document.NewDocumentValue(d))
+	}
+
+	return values
+}
+
+func sortValues(t testing.TB, values []document.Value) {
+	t.Helper()
+
+	var err error
+	sort.SliceStable(values, func(i, j int) bool {
+		cmp, e := values[i].Compare(values[j])
+		if e != nil {
+			err = e
+		}
+		return cmp < 0
+	})
+	require.NoError(t, err)
+}
+
+func equalValues(t testing.TB, a, b []document.Value) bool {
+	t.Helper()
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		ok, err := a[i].IsEqual(b[i])
+		require.NoError(t, err)
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// toJSON returns the JSON representation of each document, one per line.
+func toJSON(t testing.TB, values []document.Value) []string {
+	t.Helper()
+
+	lines := make([]string, len(values))
+	for i, v := range values {
+		data, err := document.MarshalJSON(v.V.(document.Document))
+		require.NoError(t, err)
+
+		lines[i] = string(data)
+	}
+
+	return lines
+}
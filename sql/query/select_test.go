@@ -4,10 +4,18 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
 	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
 	"github.com/stretchr/testify/require"
 )
 
@@ -28,16 +36,25 @@ func TestSelectStmt(t *testing.T) {
 		{"No table, BitwiseAnd", "SELECT 10 & 6", false, `[{"10 & 6":2}]`, nil},
 		{"No table, BitwiseOr", "SELECT 10 | 6", false, `[{"10 | 6":14}]`, nil},
 		{"No table, BitwiseXor", "SELECT 10 ^ 6", false, `[{"10 ^ 6":12}]`, nil},
+		{"No table, integer overflow", "SELECT 9223372036854775807 + 1", true, ``, nil},
+		{"No table, double promotion", "SELECT 9223372036854775807 + 1.0", false, `[{"9223372036854775807 + 1.0":9223372036854775808.0}]`, nil},
 		{"No table, function pk()", "SELECT pk()", true, ``, nil},
 		{"No table, field", "SELECT a", true, ``, nil},
 		{"No table, wildcard", "SELECT *", true, ``, nil},
 		{"No table, document", "SELECT {a: 1, b: 2 + 1}", false, `[{"{a: 1, b: 2 + 1}":{"a":1,"b":3}}]`, nil},
 		{"No cond", "SELECT * FROM test", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100},{"k":3,"height":100,"weight":200}]`, nil},
+		{"Qualified wildcard", "SELECT test.*, color FROM test WHERE k = 1", false, `[{"k":1,"color":"red","size":10,"shape":"square","color":"red"}]`, nil},
+		{"Qualified wildcard with unknown table", "SELECT foo.* FROM test", true, ``, nil},
+		{"Qualified wildcard with no table", "SELECT test.*", true, ``, nil},
 		{"Multiple wildcards cond", "SELECT *, *, color FROM test", false, `[{"k":1,"color":"red","size":10,"shape":"square","k":1,"color":"red","size":10,"shape":"square","color":"red"},{"k":2,"color":"blue","size":10,"weight":100,"k":2,"color":"blue","size":10,"weight":100,"color":"blue"},{"k":3,"height":100,"weight":200,"k":3,"height":100,"weight":200,"color":null}]`, nil},
 		{"With fields", "SELECT color, shape FROM test", false, `[{"color":"red","shape":"square"},{"color":"blue","shape":null},{"color":null,"shape":null}]`, nil},
 		{"With expr fields", "SELECT color, color != 'red' AS notred FROM test", false, `[{"color":"red","notred":false},{"color":"blue","notred":true},{"color":null,"notred":null}]`, nil},
 		{"With eq op", "SELECT * FROM test WHERE size = 10", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
 		{"With neq op", "SELECT * FROM test WHERE color != 'red'", false, `[{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
+		{"With non-boolean cond", "SELECT k FROM test WHERE shape", false, `[{"k":1}]`, nil},
+		{"With numeric cond", "SELECT k FROM test WHERE size - 10", false, `[]`, nil},
+		{"With NOT cond", "SELECT k FROM test WHERE NOT color = 'red'", false, `[{"k":2}]`, nil},
+		{"With literal cond", "SELECT k FROM test WHERE 'a'", false, `[{"k":1},{"k":2},{"k":3}]`, nil},
 		{"With gt op", "SELECT * FROM test WHERE size > 10", false, `[]`, nil},
 		{"With lt op", "SELECT * FROM test WHERE size < 15", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
 		{"With lte op", "SELECT * FROM test WHERE color <= 'salmon' ORDER BY k ASC", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
@@ -48,6 +65,13 @@ func TestSelectStmt(t *testing.T) {
 		{"With IN op", "SELECT color FROM test WHERE color IN ['red', 'purple'] ORDER BY k", false, `[{"color":"red"}]`, nil},
 		{"With IN op on PK", "SELECT color FROM test WHERE k IN [1.1, 1.0] ORDER BY k", false, `[{"color":"red"}]`, nil},
 		{"With NOT IN op", "SELECT color FROM test WHERE color NOT IN ['red', 'purple'] ORDER BY k", false, `[{"color":"blue"}]`, nil},
+		{"With BETWEEN op", "SELECT k FROM test WHERE weight BETWEEN 100 AND 150", false, `[{"k":2}]`, nil},
+		{"With BETWEEN op, doubles", "SELECT k FROM test WHERE weight BETWEEN 99.5 AND 200.0 ORDER BY weight", false, `[{"k":2},{"k":3}]`, nil},
+		{"With BETWEEN op, text", "SELECT k FROM test WHERE color BETWEEN 'a' AND 'c'", false, `[{"k":2}]`, nil},
+		{"With BETWEEN op, different types", "SELECT k FROM test WHERE weight BETWEEN 100 AND 'z'", false, `[]`, nil},
+		{"With BETWEEN op, params", "SELECT k FROM test WHERE weight BETWEEN ? AND ?", false, `[{"k":3}]`, []interface{}{150, 250}},
+		{"With NOT BETWEEN op", "SELECT k FROM test WHERE weight NOT BETWEEN 150 AND 300", false, `[{"k":2}]`, nil},
+		{"With exclusive range", "SELECT k FROM test WHERE weight > 100 AND weight < 200", false, `[]`, nil},
 		{"With field comparison", "SELECT * FROM test WHERE color < shape", false, `[{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
 		{"With group by", "SELECT * FROM test GROUP BY color", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100},{"k":3,"height":100,"weight":200}]`, nil},
 		{"With group by and count", "SELECT COUNT(k) FROM test GROUP BY size", false, `[{"COUNT(k)":2},{"COUNT(k)":1}]`, nil},
@@ -66,19 +90,48 @@ func TestSelectStmt(t *testing.T) {
 		{"With order by desc with limit offset", "SELECT * FROM test ORDER BY color DESC LIMIT 1 OFFSET 1", false, `[{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
 		{"With order by pk asc", "SELECT * FROM test ORDER BY k ASC", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":2,"color":"blue","size":10,"weight":100},{"k":3,"height":100,"weight":200}]`, nil},
 		{"With order by pk desc", "SELECT * FROM test ORDER BY k DESC", false, `[{"k":3,"height":100,"weight":200},{"k":2,"color":"blue","size":10,"weight":100},{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
+		{"With order by nulls first", "SELECT k FROM test ORDER BY color NULLS FIRST", false, `[{"k":3},{"k":2},{"k":1}]`, nil},
+		{"With order by nulls last", "SELECT k FROM test ORDER BY color NULLS LAST", false, `[{"k":2},{"k":1},{"k":3}]`, nil},
+		{"With order by desc nulls first", "SELECT k FROM test ORDER BY color DESC NULLS FIRST", false, `[{"k":3},{"k":1},{"k":2}]`, nil},
+		{"With order by desc nulls last", "SELECT k FROM test ORDER BY color DESC NULLS LAST", false, `[{"k":1},{"k":2},{"k":3}]`, nil},
+		{"With order by nulls last with limit", "SELECT k FROM test ORDER BY weight NULLS LAST LIMIT 2", false, `[{"k":2},{"k":3}]`, nil},
+		{"With order by desc nulls first with limit", "SELECT k FROM test ORDER BY shape DESC NULLS FIRST LIMIT 2", false, `[{"k":2},{"k":3}]`, nil},
 		{"With order by and where", "SELECT * FROM test WHERE color != 'blue' ORDER BY color DESC LIMIT 1", false, `[{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
 		{"With limit", "SELECT * FROM test WHERE size = 10 LIMIT 1", false, `[{"k":1,"color":"red","size":10,"shape":"square"}]`, nil},
 		{"With offset", "SELECT *, pk() FROM test WHERE size = 10 OFFSET 1", false, `[{"pk()":2,"color":"blue","size":10,"weight":100,"k":2}]`, nil},
 		{"With limit then offset", "SELECT * FROM test WHERE size = 10 LIMIT 1 OFFSET 1", false, `[{"k":2,"color":"blue","size":10,"weight":100,"k":2}]`, nil},
 		{"With offset then limit", "SELECT * FROM test WHERE size = 10 OFFSET 1 LIMIT 1", true, "", nil},
+		{"With limit all", "SELECT k FROM test LIMIT ALL OFFSET 1", false, `[{"k":2},{"k":3}]`, nil},
+		{"With limit and offset params", "SELECT k FROM test LIMIT ? OFFSET ?", false, `[{"k":2},{"k":3}]`, []interface{}{2, 1}},
+		{"With named limit param", "SELECT k FROM test LIMIT $n", false, `[{"k":1}]`, []interface{}{sql.Named("n", 1)}},
+		{"With negative limit param", "SELECT k FROM test LIMIT ?", true, ``, []interface{}{-1}},
+		{"With negative offset", "SELECT k FROM test OFFSET -1", true, ``, nil},
+		{"With double limit param", "SELECT k FROM test LIMIT ?", true, ``, []interface{}{1.9}},
+		{"With whole double limit", "SELECT k FROM test LIMIT 2.0", false, `[{"k":1},{"k":2}]`, nil},
+		{"With double limit", "SELECT k FROM test LIMIT 1.5", true, ``, nil},
+		{"With double offset", "SELECT k FROM test OFFSET 1.7", true, ``, nil},
+		{"With text limit param", "SELECT k FROM test LIMIT ?", true, ``, []interface{}{"1"}},
+		{"With missing limit param", "SELECT k FROM test LIMIT ?", true, ``, nil},
 		{"With positional params", "SELECT * FROM test WHERE color = ? OR height = ?", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":3,"height":100,"weight":200}]`, []interface{}{"red", 100}},
 		{"With named params", "SELECT * FROM test WHERE color = $a OR height = $d", false, `[{"k":1,"color":"red","size":10,"shape":"square"},{"k":3,"height":100,"weight":200}]`, []interface{}{sql.Named("a", "red"), sql.Named("d", 100)}},
 		{"With pk()", "SELECT pk(), color FROM test", false, `[{"pk()":1,"color":"red"},{"pk()":2,"color":"blue"},{"pk()":3,"color":null}]`, []interface{}{sql.Named("a", "red"), sql.Named("d", 100)}},
 		{"With pk in cond, gt", "SELECT * FROM test WHERE k > 0 AND weight = 100", false, `[{"k":2,"color":"blue","size":10,"weight":100,"k":2}]`, nil},
 		{"With pk in cond, =", "SELECT * FROM test WHERE k = 2.0 AND weight = 100", false, `[{"k":2,"color":"blue","size":10,"weight":100,"k":2}]`, nil},
+		{"With pk() in cond", "SELECT * FROM test WHERE pk() = 2", false, `[{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
+		{"With pk() in cond, reversed", "SELECT * FROM test WHERE 2.0 = pk()", false, `[{"k":2,"color":"blue","size":10,"weight":100}]`, nil},
+		{"With pk() in cond, not found", "SELECT * FROM test WHERE pk() = 10", false, `[]`, nil},
+		{"With pk() in cond, wrong type", "SELECT * FROM test WHERE pk() = 'foo'", false, `[]`, nil},
+		{"With pk() in cond, param", "SELECT * FROM test WHERE pk() = ? AND size = 10", false, `[{"k":1,"color":"red","size":10,"shape":"square"}]`, []interface{}{1}},
 		{"With count", "SELECT COUNT(k) FROM test", false, `[{"COUNT(k)": 3}]`, nil},
 		{"With count wildcard", "SELECT COUNT(*) FROM test", false, `[{"COUNT(*)": 3}]`, nil},
 		{"With multiple counts", "SELECT COUNT(k), COUNT(color) FROM test", false, `[{"COUNT(k)": 3, "COUNT(color)": 2}]`, nil},
+		{"With count and where", "SELECT COUNT(weight) FROM test WHERE weight > 150", false, `[{"COUNT(weight)": 1}]`, nil},
+		{"With count alias", "SELECT COUNT(*) AS total FROM test", false, `[{"total": 3}]`, nil},
+		{"With count and no documents", "SELECT COUNT(*) AS total FROM test WHERE k > 10", false, `[{"total": 0}]`, nil},
+		{"With aggregates and no documents", "SELECT COUNT(color), MIN(k), MAX(k), SUM(k) FROM test WHERE k > 10", false, `[{"COUNT(color)": 0, "MIN(k)": null, "MAX(k)": null, "SUM(k)": null}]`, nil},
+		{"With count, group by and no documents", "SELECT COUNT(*) FROM test WHERE k > 10 GROUP BY color", false, `[]`, nil},
+		{"With count distinct", "SELECT COUNT(DISTINCT size) FROM test", false, `[{"COUNT(DISTINCT size)": 1}]`, nil},
+		{"With count distinct on multiple fields", "SELECT COUNT(DISTINCT color, size) FROM test", false, `[{"COUNT(DISTINCT color, size)": 2}]`, nil},
 		{"With min", "SELECT MIN(k) FROM test", false, `[{"MIN(k)": 1}]`, nil},
 		{"With multiple mins", "SELECT MIN(color), MIN(weight) FROM test", false, `[{"MIN(color)": "blue", "MIN(weight)": 100}]`, nil},
 		{"With max", "SELECT MAX(k) FROM test", false, `[{"MAX(k)": 3}]`, nil},
@@ -135,6 +188,80 @@ func TestSelectStmt(t *testing.T) {
 		t.Run("With Index/"+test.name, testFn(true))
 	}
 
+	t.Run("with integers and doubles in indexes", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test (b DOUBLE);
+			CREATE INDEX idx_a ON test (a);
+			CREATE INDEX idx_b ON test (b);
+			INSERT INTO test (a, b) VALUES (10, 1), (10.0, 2.5), (10.5, 3), (-10.5, 10), (-10, 10.0), (20, 20);
+		`)
+		require.NoError(t, err)
+
+		tests := []struct {
+			query    string
+			expected string
+		}{
+			{"SELECT a FROM test WHERE a = 10", `[{"a":10},{"a":10.0}]`},
+			{"SELECT a FROM test WHERE a = 10.0", `[{"a":10},{"a":10.0}]`},
+			{"SELECT a FROM test WHERE a = 10.5", `[{"a":10.5}]`},
+			{"SELECT a FROM test WHERE a > 10", `[{"a":10.5},{"a":20}]`},
+			{"SELECT a FROM test WHERE a >= 10.0", `[{"a":10},{"a":10.0},{"a":10.5},{"a":20}]`},
+			{"SELECT a FROM test WHERE a < -10", `[{"a":-10.5}]`},
+			{"SELECT a FROM test WHERE a <= -10.0", `[{"a":-10.5},{"a":-10}]`},
+			{"SELECT b FROM test WHERE b = 10", `[{"b":10.0},{"b":10.0}]`},
+			{"SELECT b FROM test WHERE b > 2", `[{"b":2.5},{"b":3.0},{"b":10.0},{"b":10.0},{"b":20.0}]`},
+			{"SELECT b FROM test WHERE b < 3", `[{"b":1.0},{"b":2.5}]`},
+			{"SELECT b FROM test WHERE b <= 3", `[{"b":1.0},{"b":2.5},{"b":3.0}]`},
+		}
+
+		for _, test := range tests {
+			st, err := db.Query(ctx, test.query)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String(), test.query)
+			st.Close()
+		}
+	})
+
+	t.Run("with pk() and no primary key", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test")
+		require.NoError(t, err)
+		err = db.Exec(ctx, "INSERT INTO test (a) VALUES (1), (2), (3)")
+		require.NoError(t, err)
+
+		tests := []struct {
+			query    string
+			expected string
+		}{
+			{"SELECT pk(), a FROM test WHERE pk() = 2", `[{"pk()":2,"a":2}]`},
+			{"SELECT pk(), a FROM test WHERE pk() = 4", `[]`},
+			{"SELECT pk(), a FROM test WHERE pk() = -1", `[]`},
+			{"SELECT pk(), a FROM test WHERE pk() = 2.5", `[]`},
+		}
+
+		for _, test := range tests {
+			st, err := db.Query(ctx, test.query)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			require.JSONEq(t, test.expected, buf.String())
+			st.Close()
+		}
+	})
+
 	t.Run("with primary key only", func(t *testing.T) {
 		db, err := genji.Open(":memory:")
 		require.NoError(t, err)
@@ -201,6 +328,422 @@ func TestSelectStmt(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("with table sample", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		tx, err := db.Begin(true)
+		require.NoError(t, err)
+		err = tx.Exec(ctx, "CREATE TABLE test; CREATE INDEX idx_a ON test(a)")
+		require.NoError(t, err)
+		for i := 0; i < 10000; i++ {
+			err = tx.Exec(ctx, "INSERT INTO test (a) VALUES (?)", i)
+			require.NoError(t, err)
+		}
+		require.NoError(t, tx.Commit())
+
+		sample := func(t *testing.T, q string) string {
+			t.Helper()
+
+			st, err := db.Query(ctx, q)
+			require.NoError(t, err)
+			defer st.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			return buf.String()
+		}
+
+		count := func(t *testing.T, q string) int {
+			t.Helper()
+
+			st, err := db.Query(ctx, q)
+			require.NoError(t, err)
+			defer st.Close()
+
+			n, err := st.Count()
+			require.NoError(t, err)
+			return n
+		}
+
+		// the proportion of documents returned is close to the requested percentage
+		n := count(t, "SELECT a FROM test TABLESAMPLE (10 PERCENT) REPEATABLE (42)")
+		require.InDelta(t, 1000, n, 150)
+		n = count(t, "SELECT a FROM test TABLESAMPLE (50 PERCENT) REPEATABLE (42)")
+		require.InDelta(t, 5000, n, 300)
+		require.Equal(t, 0, count(t, "SELECT a FROM test TABLESAMPLE (0 PERCENT)"))
+		require.Equal(t, 10000, count(t, "SELECT a FROM test TABLESAMPLE (100 PERCENT)"))
+
+		// the same seed always returns the same documents
+		a := sample(t, "SELECT a FROM test TABLESAMPLE (10 PERCENT) REPEATABLE (42)")
+		require.Equal(t, a, sample(t, "SELECT a FROM test TABLESAMPLE (10 PERCENT) REPEATABLE (42)"))
+		require.NotEqual(t, a, sample(t, "SELECT a FROM test TABLESAMPLE (10 PERCENT) REPEATABLE (43)"))
+
+		// the filter is applied on the sampled documents, without using indexes
+		n = count(t, "SELECT a FROM test TABLESAMPLE (10 PERCENT) REPEATABLE (42) WHERE a < 5000")
+		require.InDelta(t, 500, n, 100)
+	})
+
+	t.Run("with missing fields and explicit nulls", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test;
+			INSERT INTO test (k, a) VALUES (1, NULL);
+			INSERT INTO test (k) VALUES (2);
+			INSERT INTO test (k, a) VALUES (3, 1);
+		`)
+		require.NoError(t, err)
+
+		tests := []struct {
+			query    string
+			expected string
+		}{
+			{"SELECT k, a FROM test WHERE a IS NULL", `[{"k": 1, "a": null}, {"k": 2, "a": null}]`},
+			{"SELECT k FROM test WHERE a IS NOT NULL", `[{"k": 3}]`},
+			{"SELECT k FROM test WHERE a = NULL", `[]`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.query, func(t *testing.T) {
+				st, err := db.Query(ctx, test.query)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+	})
+
+	t.Run("with boolean fields", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test (k INTEGER PRIMARY KEY, active BOOLEAN NOT NULL DEFAULT false);
+			CREATE INDEX idx_active ON test (active);
+			INSERT INTO test (k, active) VALUES (1, true), (2, false);
+			INSERT INTO test (k) VALUES (3);
+		`)
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `INSERT INTO test (k, active) VALUES (4, NULL)`)
+		require.Error(t, err)
+
+		tests := []struct {
+			query    string
+			expected string
+		}{
+			{"SELECT * FROM test WHERE k = 3", `[{"k": 3, "active": false}]`},
+			{"SELECT k FROM test WHERE active", `[{"k": 1}]`},
+			{"SELECT k FROM test WHERE active = true", `[{"k": 1}]`},
+			{"SELECT k FROM test WHERE NOT active", `[{"k": 2}, {"k": 3}]`},
+			{"SELECT k FROM test WHERE active = false", `[{"k": 2}, {"k": 3}]`},
+			{"SELECT k FROM test WHERE NOT active AND k > 2", `[{"k": 3}]`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.query, func(t *testing.T) {
+				st, err := db.Query(ctx, test.query)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+	})
+
+	t.Run("with count distinct", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test;
+			INSERT INTO test (city, state, n) VALUES ('Paris', 'TX', 1);
+			INSERT INTO test (city, state, n) VALUES ('Paris', 'TX', 1.0);
+			INSERT INTO test (city, state, n) VALUES ('Paris', 'France', 2);
+			INSERT INTO test (city, state, n) VALUES ('Austin', 'TX', 2);
+			INSERT INTO test (city, state, n) VALUES ('Austin', 'TX', 3);
+			INSERT INTO test (city, state) VALUES ('Lyon', NULL);
+			INSERT INTO test (city) VALUES ('Lyon');
+		`)
+		require.NoError(t, err)
+
+		tests := []struct {
+			query    string
+			expected string
+		}{
+			{"SELECT COUNT(DISTINCT city) AS c FROM test", `[{"c": 3}]`},
+			{"SELECT COUNT(DISTINCT city, state) AS c FROM test", `[{"c": 3}]`},
+			{"SELECT COUNT(DISTINCT state, city) AS c FROM test", `[{"c": 3}]`},
+			{"SELECT COUNT(DISTINCT city, state, n) AS c FROM test", `[{"c": 4}]`},
+			{"SELECT COUNT(DISTINCT n) AS c FROM test", `[{"c": 3}]`},
+			{"SELECT COUNT(DISTINCT city, state) AS c FROM test WHERE city = 'Lyon'", `[{"c": 0}]`},
+			{"SELECT COUNT(DISTINCT city, n) AS c FROM test WHERE state = 'TX' GROUP BY state", `[{"c": 3}]`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.query, func(t *testing.T) {
+				st, err := db.Query(ctx, test.query)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+	})
+
+	t.Run("with numeric aggregates", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE orders;
+			INSERT INTO orders (k, price) VALUES (1, 10), (2, 20), (3, 'free'), (4, true), (5, NULL);
+			INSERT INTO orders (k) VALUES (6);
+			INSERT INTO orders (k, price) VALUES (7, 2.5);
+			CREATE TABLE big;
+			INSERT INTO big (a) VALUES (9223372036854775807), (1);
+		`)
+		require.NoError(t, err)
+
+		tests := []struct {
+			query    string
+			expected string
+		}{
+			{"SELECT SUM(price), AVG(price) FROM orders WHERE k < 3", `[{"SUM(price)": 30, "AVG(price)": 15.0}]`},
+			{"SELECT SUM(price), AVG(price) FROM orders", `[{"SUM(price)": 32.5, "AVG(price)": 10.833333333333334}]`},
+			{"SELECT SUM(price), AVG(price) FROM orders WHERE k > 2 AND k < 7", `[{"SUM(price)": null, "AVG(price)": null}]`},
+			{"SELECT MIN(price), MAX(price) FROM orders WHERE k < 3 OR k = 7", `[{"MIN(price)": 2.5, "MAX(price)": 20}]`},
+			{"SELECT MIN(price), MAX(price) FROM orders", `[{"MIN(price)": true, "MAX(price)": "free"}]`},
+			{"SELECT MIN(price) AS min, MAX(price) AS max FROM orders WHERE k > 5", `[{"min": 2.5, "max": 2.5}]`},
+			{"SELECT SUM(a) FROM big WHERE a = 1", `[{"SUM(a)": 1}]`},
+			{"SELECT SUM(a + 0.0) FROM big", `[{"SUM(a + 0.0)": 9223372036854775808.0}]`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.query, func(t *testing.T) {
+				st, err := db.Query(ctx, test.query)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+
+		// the sum of integers overflows like the addition operator
+		_, err = db.QueryDocument(ctx, "SELECT SUM(a) FROM big")
+		require.True(t, errors.Is(err, document.ErrIntegerOverflow), err)
+	})
+
+	t.Run("with group by", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE users;
+			INSERT INTO users (city, age) VALUES ('Lyon', 10), ('Paris', 20), ('Lyon', 30), ('Lyon', 10.0);
+			INSERT INTO users (city, age) VALUES (NULL, 40);
+			INSERT INTO users (age) VALUES (50);
+		`)
+		require.NoError(t, err)
+
+		tests := []struct {
+			query    string
+			expected string
+		}{
+			{"SELECT city, COUNT(*) FROM users GROUP BY city",
+				`[{"city": "Lyon", "COUNT(*)": 3}, {"city": "Paris", "COUNT(*)": 1}, {"city": null, "COUNT(*)": 2}]`},
+			{"SELECT city AS c, COUNT(*) AS n, MAX(age) AS m FROM users WHERE city IS NOT NULL GROUP BY city",
+				`[{"c": "Lyon", "n": 3, "m": 30}, {"c": "Paris", "n": 1, "m": 20}]`},
+			{"SELECT city, age, COUNT(*) FROM users GROUP BY city, age",
+				`[{"city": "Lyon", "age": 10, "COUNT(*)": 2}, {"city": "Paris", "age": 20, "COUNT(*)": 1}, {"city": "Lyon", "age": 30, "COUNT(*)": 1}, {"city": null, "age": 40, "COUNT(*)": 1}, {"city": null, "age": 50, "COUNT(*)": 1}]`},
+			{"SELECT city FROM users GROUP BY city",
+				`[{"city": "Lyon"}, {"city": "Paris"}, {"city": null}]`},
+			{"SELECT city, COUNT(*) AS n FROM users GROUP BY city ORDER BY n DESC LIMIT 1",
+				`[{"city": "Lyon", "n": 3}]`},
+			{"SELECT city, COUNT(*) FROM users WHERE age > 100 GROUP BY city", `[]`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.query, func(t *testing.T) {
+				st, err := db.Query(ctx, test.query)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+	})
+
+	t.Run("with aggregate filters", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test;
+			INSERT INTO test (city, status, n) VALUES ('Lyon', 'active', 1);
+			INSERT INTO test (city, status, n) VALUES ('Lyon', 'active', 2);
+			INSERT INTO test (city, status, n) VALUES ('Lyon', 'closed', 4);
+			INSERT INTO test (city, status, n) VALUES ('Paris', 'closed', 8);
+			INSERT INTO test (city, n) VALUES ('Paris', 16);
+		`)
+		require.NoError(t, err)
+
+		tests := []struct {
+			query    string
+			fails    bool
+			expected string
+		}{
+			{"SELECT COUNT(*) FILTER (WHERE status = 'active'), COUNT(*) FROM test", false,
+				`[{"COUNT(*) FILTER (WHERE status = 'active')": 2, "COUNT(*)": 5}]`},
+			{"SELECT MAX(city) AS city, COUNT(*) FILTER (WHERE status = 'active') AS active, COUNT(*) AS total FROM test GROUP BY city", false,
+				`[{"city": "Lyon", "active": 2, "total": 3}, {"city": "Paris", "active": 0, "total": 2}]`},
+			{"SELECT SUM(n) FILTER (WHERE status = 'closed') AS closed, SUM(n) AS total, MIN(n) FILTER (WHERE n > 1) AS m FROM test", false,
+				`[{"closed": 12, "total": 31, "m": 2}]`},
+			{"SELECT COUNT(DISTINCT city) FILTER (WHERE n < 10) AS c, AVG(n) FILTER (WHERE city = 'Lyon' AND n > 1) AS a FROM test", false,
+				`[{"c": 2, "a": 3.0}]`},
+			{"SELECT COUNT(*) FILTER (WHERE missing = 1) AS c FROM test", false, `[{"c": 0}]`},
+			{"SELECT ABS(n) FILTER (WHERE n > 1) FROM test", true, ``},
+			{"SELECT COUNT(*) FILTER (status = 'active') FROM test", true, ``},
+		}
+
+		for _, test := range tests {
+			t.Run(test.query, func(t *testing.T) {
+				st, err := db.Query(ctx, test.query)
+				if test.fails {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+	})
+
+	t.Run("with text primary key", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test(k TEXT PRIMARY KEY, v TEXT);
+			CREATE INDEX test_v ON test(v);
+		`)
+		require.NoError(t, err)
+
+		// texts that are prefixes of each other or contain null bytes
+		for i, k := range []string{"ab", "a\x00b", "", "a", "a\x00"} {
+			err = db.Exec(ctx, "INSERT INTO test (k, v, i) VALUES (?, ?, ?)", k, k, i)
+			require.NoError(t, err)
+		}
+		err = db.Exec(ctx, "INSERT INTO test (k, v, i) VALUES ('b', 'a', 5)")
+		require.NoError(t, err)
+
+		tests := []struct {
+			query    string
+			args     []interface{}
+			expected string
+		}{
+			{"SELECT i FROM test", nil, `[{"i": 2}, {"i": 3}, {"i": 4}, {"i": 1}, {"i": 0}, {"i": 5}]`},
+			{"SELECT i FROM test ORDER BY k DESC", nil, `[{"i": 5}, {"i": 0}, {"i": 1}, {"i": 4}, {"i": 3}, {"i": 2}]`},
+			{"SELECT i FROM test WHERE k = ?", []interface{}{"a"}, `[{"i": 3}]`},
+			{"SELECT i FROM test WHERE k = ?", []interface{}{"a\x00b"}, `[{"i": 1}]`},
+			{"SELECT i FROM test WHERE k = ?", []interface{}{""}, `[{"i": 2}]`},
+			{"SELECT i FROM test WHERE k > ?", []interface{}{"a"}, `[{"i": 4}, {"i": 1}, {"i": 0}, {"i": 5}]`},
+			{"SELECT i FROM test WHERE v = ?", []interface{}{"a"}, `[{"i": 3}, {"i": 5}]`},
+			{"SELECT i FROM test WHERE v = ?", []interface{}{"a\x00"}, `[{"i": 4}]`},
+			{"SELECT i FROM test WHERE v < ? ORDER BY v", []interface{}{"a\x00b"}, `[{"i": 2}, {"i": 3}, {"i": 5}, {"i": 4}]`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.query, func(t *testing.T) {
+				st, err := db.Query(ctx, test.query, test.args...)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+	})
+
+	t.Run("with case", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test(id INTEGER PRIMARY KEY);
+			INSERT INTO test (id, priority) VALUES (1, 'low');
+			INSERT INTO test (id, priority) VALUES (2, 'high');
+			INSERT INTO test (id, priority) VALUES (3, 'medium');
+			INSERT INTO test (id, priority) VALUES (4, 'high');
+			INSERT INTO test (id) VALUES (5);
+		`)
+		require.NoError(t, err)
+
+		tests := []struct {
+			query    string
+			expected string
+		}{
+			{"SELECT id FROM test ORDER BY CASE WHEN priority = 'high' THEN 0 - id ELSE id END",
+				`[{"id": 4}, {"id": 2}, {"id": 1}, {"id": 3}, {"id": 5}]`},
+			{"SELECT id, CASE priority WHEN 'high' THEN 1 WHEN 'medium' THEN 2 ELSE 3 END AS p FROM test WHERE id < 4",
+				`[{"id": 1, "p": 3}, {"id": 2, "p": 1}, {"id": 3, "p": 2}]`},
+			{"SELECT id FROM test ORDER BY CASE WHEN priority = 'high' THEN 0 WHEN priority IS NULL THEN 2 ELSE 1 END DESC LIMIT 1",
+				`[{"id": 5}]`},
+			{"SELECT id FROM test ORDER BY CASE priority WHEN 'high' THEN 0 - id WHEN 'medium' THEN 1 WHEN 'low' THEN 2 END",
+				`[{"id": 5}, {"id": 4}, {"id": 2}, {"id": 3}, {"id": 1}]`},
+			{"SELECT COUNT(*) AS c FROM test GROUP BY CASE WHEN priority = 'high' THEN 'urgent' ELSE 'normal' END",
+				`[{"c": 3}, {"c": 2}]`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.query, func(t *testing.T) {
+				st, err := db.Query(ctx, test.query)
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+	})
+
 	t.Run("with order by and indexes", func(t *testing.T) {
 		db, err := genji.Open(":memory:")
 		require.NoError(t, err)
@@ -221,4 +764,858 @@ func TestSelectStmt(t *testing.T) {
 		require.NoError(t, err)
 		require.JSONEq(t, `[{"foo": true},{"foo": 1}, {"foo": 2},{"foo": "hello"}]`, buf.String())
 	})
+
+	t.Run("with order by using an index instead of sorting", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		// the index of the first table is read instead of sorting the documents,
+		// which must return them in the same order.
+		queries := []string{
+			"SELECT a FROM %s ORDER BY a",
+			"SELECT a FROM %s ORDER BY a DESC",
+			"SELECT a FROM %s WHERE b > 2 ORDER BY a DESC",
+			"SELECT a FROM %s ORDER BY a LIMIT 5 OFFSET 2",
+			"SELECT a + 1 FROM %s ORDER BY a DESC LIMIT 3",
+		}
+
+		err = db.Exec(ctx, `
+			CREATE TABLE indexed;
+			CREATE INDEX idx_indexed_a ON indexed(a);
+			CREATE TABLE sorted;
+		`)
+		require.NoError(t, err)
+
+		for _, tb := range []string{"indexed", "sorted"} {
+			err = db.Exec(ctx, fmt.Sprintf(`INSERT INTO %s (a, b) VALUES
+				(3, 1), ('foo', 2), (1.5, 3), (null, 4), (true, 5), (3, 6), (-10, 7), ('bar', 8)`, tb))
+			require.NoError(t, err)
+			err = db.Exec(ctx, fmt.Sprintf(`INSERT INTO %s (b) VALUES (9)`, tb))
+			require.NoError(t, err)
+		}
+
+		for _, q := range queries {
+			var results []string
+			for _, tb := range []string{"indexed", "sorted"} {
+				st, err := db.Query(ctx, fmt.Sprintf(q, tb))
+				require.NoError(t, err)
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.NoError(t, st.Close())
+				results = append(results, buf.String())
+			}
+
+			require.JSONEq(t, results[1], results[0], q)
+		}
+
+		// an index forced by a hint can be used to sort the documents
+		var results []string
+		for _, q := range []string{
+			"SELECT a FROM indexed USE INDEX (idx_indexed_a) WHERE b > 2 ORDER BY a DESC",
+			"SELECT a FROM sorted WHERE b > 2 ORDER BY a DESC",
+		} {
+			st, err := db.Query(ctx, q)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			require.NoError(t, st.Close())
+			results = append(results, buf.String())
+		}
+		require.JSONEq(t, results[1], results[0])
+	})
+
+	t.Run("with a time range", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE events (created TEXT NOT NULL);
+			CREATE INDEX idx_events_created ON events(created);
+		`)
+		require.NoError(t, err)
+
+		// insert one event per minute, in a random order
+		start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		const n = 100
+		for _, i := range rand.New(rand.NewSource(42)).Perm(n) {
+			created := start.Add(time.Duration(i) * time.Minute).Format(expr.TimestampFormat)
+			err = db.Exec(ctx, "INSERT INTO events (created, i) VALUES (?, ?)", created, i)
+			require.NoError(t, err)
+		}
+
+		from := start.Add(10 * time.Minute).Format(expr.TimestampFormat)
+		to := start.Add(20 * time.Minute).Format(expr.TimestampFormat)
+
+		const q = "SELECT i FROM events %s WHERE created BETWEEN ? AND ? ORDER BY created"
+
+		d, err := db.QueryDocument(ctx, "EXPLAIN "+fmt.Sprintf(q, ""), from, to)
+		require.NoError(t, err)
+		v, err := d.GetByField("plan")
+		require.NoError(t, err)
+		require.Equal(t, `"Index(idx_events_created) -> ∏(i)"`, v.String())
+
+		// the index returns the events in chronological order,
+		// which must be the same as when they are filtered and sorted.
+		var results []string
+		for _, hint := range []string{"", "IGNORE INDEX (idx_events_created)"} {
+			st, err := db.Query(ctx, fmt.Sprintf(q, hint), from, to)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.NoError(t, err)
+			require.NoError(t, st.Close())
+			results = append(results, buf.String())
+		}
+
+		require.JSONEq(t, `[{"i":10},{"i":11},{"i":12},{"i":13},{"i":14},{"i":15},{"i":16},{"i":17},{"i":18},{"i":19},{"i":20}]`, results[0])
+		require.JSONEq(t, results[1], results[0])
+	})
+
+	t.Run("with arrays and documents in order by", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, "CREATE TABLE test; CREATE TABLE indexed; CREATE INDEX idx_foo ON indexed(foo)")
+		require.NoError(t, err)
+
+		for _, tb := range []string{"test", "indexed"} {
+			err = db.Exec(ctx, `INSERT INTO `+tb+` (foo) VALUES
+				({b: 1}), ([1, 2]), ('hello'), ({a: 2}), ([1]), (10), ([0, 5]), ({a: 1, b: 1})`)
+			require.NoError(t, err)
+		}
+
+		tests := []struct {
+			query    string
+			expected string
+		}{
+			{"SELECT foo FROM test ORDER BY foo", `[
+				{"foo": 10}, {"foo": "hello"},
+				{"foo": [0, 5]}, {"foo": [1]}, {"foo": [1, 2]},
+				{"foo": {"a": 1, "b": 1}}, {"foo": {"a": 2}}, {"foo": {"b": 1}}
+			]`},
+			{"SELECT foo FROM test ORDER BY foo DESC", `[
+				{"foo": {"b": 1}}, {"foo": {"a": 2}}, {"foo": {"a": 1, "b": 1}},
+				{"foo": [1, 2]}, {"foo": [1]}, {"foo": [0, 5]},
+				{"foo": "hello"}, {"foo": 10}
+			]`},
+			{"SELECT foo FROM test ORDER BY foo LIMIT 4", `[
+				{"foo": 10}, {"foo": "hello"}, {"foo": [0, 5]}, {"foo": [1]}
+			]`},
+		}
+
+		for _, test := range tests {
+			// the index must return the values in the same order as the sort
+			for _, q := range []string{test.query, strings.Replace(test.query, "test", "indexed", 1)} {
+				st, err := db.Query(ctx, q)
+				require.NoError(t, err)
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.NoError(t, st.Close())
+				require.JSONEq(t, test.expected, buf.String(), q)
+			}
+		}
+	})
+
+	t.Run("with points", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE cities;
+			INSERT INTO cities (name, location) VALUES
+				('Paris', POINT(48.8566, 2.3522)),
+				('Versailles', POINT(48.8049, 2.1204)),
+				('London', POINT(51.5074, -0.1278)),
+				('Sydney', POINT(-33.8688, 151.2093));
+		`)
+		require.NoError(t, err)
+
+		st, err := db.Query(ctx, `
+			SELECT name FROM cities
+			WHERE within_radius(location, POINT(48.8566, 2.3522), 20000)
+			ORDER BY name
+		`)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, st)
+		require.NoError(t, err)
+		require.NoError(t, st.Close())
+		require.JSONEq(t, `[{"name": "Paris"}, {"name": "Versailles"}]`, buf.String())
+
+		d, err := db.QueryDocument(ctx, `
+			SELECT distance(location, POINT(48.8566, 2.3522)) AS d, location FROM cities WHERE name = 'London'
+		`)
+		require.NoError(t, err)
+
+		var dist float64
+		var location document.Point
+		err = document.Scan(d, &dist, &location)
+		require.NoError(t, err)
+		require.InDelta(t, 343.5e3, dist, 500)
+		require.Equal(t, document.Point{Lat: 51.5074, Lng: -0.1278}, location)
+	})
+
+	t.Run("with collations", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			query    string
+			expected string
+		}{
+			{"BINARY", "SELECT a FROM test ORDER BY a", `[{"a": "B"}, {"a": "D"}, {"a": "a"}, {"a": "c"}]`},
+			{"BINARY DESC", "SELECT a FROM test ORDER BY a DESC", `[{"a": "c"}, {"a": "a"}, {"a": "D"}, {"a": "B"}]`},
+			{"NOCASE", "SELECT b FROM test ORDER BY b", `[{"b": "a"}, {"b": "B"}, {"b": "c"}, {"b": "D"}]`},
+			{"NOCASE DESC", "SELECT b FROM test ORDER BY b DESC", `[{"b": "D"}, {"b": "c"}, {"b": "B"}, {"b": "a"}]`},
+			{"NOCASE with limit", "SELECT b FROM test ORDER BY b LIMIT 2", `[{"b": "a"}, {"b": "B"}]`},
+		}
+
+		for _, withIndexes := range []bool{false, true} {
+			for _, test := range tests {
+				t.Run(fmt.Sprintf("%s/indexes: %v", test.name, withIndexes), func(t *testing.T) {
+					db, err := genji.Open(":memory:")
+					require.NoError(t, err)
+					defer db.Close()
+
+					err = db.Exec(ctx, "CREATE TABLE test(a TEXT COLLATE BINARY, b TEXT COLLATE NOCASE)")
+					require.NoError(t, err)
+
+					if withIndexes {
+						err = db.Exec(ctx, "CREATE INDEX idx_a ON test(a); CREATE INDEX idx_b ON test(b)")
+						require.NoError(t, err)
+					}
+
+					err = db.Exec(ctx, `INSERT INTO test (a, b) VALUES ('c', 'c'), ('B', 'B'), ('a', 'a'), ('D', 'D')`)
+					require.NoError(t, err)
+
+					st, err := db.Query(ctx, test.query)
+					require.NoError(t, err)
+					defer st.Close()
+
+					var buf bytes.Buffer
+					err = document.IteratorToJSONArray(&buf, st)
+					require.NoError(t, err)
+					require.JSONEq(t, test.expected, buf.String())
+				})
+			}
+		}
+
+		t.Run("NOCASE index lookup", func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE test(b TEXT COLLATE NOCASE);
+				CREATE INDEX idx_b ON test(b);
+				INSERT INTO test (b) VALUES ('Hello'), ('world');
+			`)
+			require.NoError(t, err)
+
+			d, err := db.QueryDocument(ctx, "SELECT b FROM test WHERE b = 'HELLO'")
+			require.NoError(t, err)
+			data, err := document.MarshalJSON(d)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"b": "Hello"}`, string(data))
+		})
+	})
+
+	t.Run("with common table expressions", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			query    string
+			expected string
+		}{
+			{"simple", "WITH old AS (SELECT * FROM test WHERE age >= 30) SELECT name FROM old ORDER BY name",
+				`[{"name": "a"}, {"name": "c"}]`},
+			{"projection", "WITH names AS (SELECT name AS n, age + 1 AS next FROM test) SELECT * FROM names WHERE next > 30 ORDER BY n",
+				`[{"n": "a", "next": 41}, {"n": "c", "next": 31}]`},
+			{"chained", "WITH a AS (SELECT * FROM test WHERE age > 10), b AS (SELECT name FROM a WHERE age < 40) SELECT * FROM b ORDER BY name",
+				`[{"name": "b"}, {"name": "c"}]`},
+			{"shadowing", "WITH test AS (SELECT name FROM test WHERE name = 'b') SELECT * FROM test",
+				`[{"name": "b"}]`},
+			{"aggregation", "WITH old AS (SELECT * FROM test WHERE age >= 30) SELECT COUNT(*) AS c FROM old",
+				`[{"c": 2}]`},
+			{"with indexes", "WITH a AS (SELECT * FROM test WHERE name = 'c') SELECT age FROM a",
+				`[{"age": 30}]`},
+			{"empty", "WITH a AS (SELECT * FROM test WHERE age > 100) SELECT * FROM a",
+				`[]`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, `
+					CREATE TABLE test;
+					CREATE INDEX idx_name ON test(name);
+					INSERT INTO test (name, age) VALUES ('a', 40), ('b', 20), ('c', 30);
+				`)
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query)
+				require.NoError(t, err)
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.NoError(t, st.Close())
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+
+		t.Run("scope", func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `CREATE TABLE test`)
+			require.NoError(t, err)
+
+			err = db.Exec(ctx, `WITH a AS (SELECT * FROM test) SELECT * FROM a; SELECT * FROM a`)
+			require.True(t, errors.Is(err, database.ErrTableNotFound))
+		})
+	})
+
+	t.Run("with IN subqueries", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			query    string
+			expected string
+		}{
+			{"matching", "SELECT name FROM users WHERE id IN (SELECT user_id FROM active) ORDER BY name",
+				`[{"name": "a"}, {"name": "c"}]`},
+			{"not in", "SELECT name FROM users WHERE id NOT IN (SELECT user_id FROM active)",
+				`[{"name": "b"}]`},
+			{"non matching", "SELECT name FROM users WHERE id IN (SELECT user_id + 10 FROM active)",
+				`[]`},
+			{"empty", "SELECT name FROM users WHERE id IN (SELECT user_id FROM active WHERE user_id > 100)",
+				`[]`},
+			{"empty not in", "SELECT name FROM users WHERE id NOT IN (SELECT user_id FROM active WHERE user_id > 100) ORDER BY name",
+				`[{"name": "a"}, {"name": "b"}, {"name": "c"}]`},
+			{"with params", "SELECT name FROM users WHERE id IN (SELECT user_id FROM active WHERE user_id > ?) AND name != ?",
+				`[{"name": "c"}]`},
+			{"with indexes", "SELECT id FROM users WHERE name IN (SELECT name FROM users WHERE name = 'b')",
+				`[{"id": 2}]`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, `
+					CREATE TABLE users;
+					CREATE INDEX idx_name ON users(name);
+					CREATE TABLE active;
+					INSERT INTO users (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+					INSERT INTO active (user_id) VALUES (1), (3);
+				`)
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query, 1, "a")
+				require.NoError(t, err)
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.NoError(t, st.Close())
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+
+		t.Run("multiple columns", func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `CREATE TABLE test; CREATE TABLE foo`)
+			require.NoError(t, err)
+
+			err = db.Exec(ctx, `SELECT * FROM test WHERE a IN (SELECT a, b FROM foo)`)
+			require.Error(t, err)
+			err = db.Exec(ctx, `SELECT * FROM test WHERE a IN (SELECT * FROM foo)`)
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("with correlated subqueries", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			query    string
+			expected string
+		}{
+			{"matching", "SELECT id, (SELECT name FROM users u WHERE u.id = o.user_id) AS name FROM orders o",
+				`[{"id": 10, "name": "a"}, {"id": 11, "name": "c"}, {"id": 12, "name": null}, {"id": 13, "name": "a"}]`},
+			{"no match", "SELECT (SELECT name FROM users u WHERE u.id = o.user_id + 100) AS name FROM orders o WHERE o.id = 10",
+				`[{"name": null}]`},
+			{"aggregate", "SELECT name, (SELECT COUNT(*) FROM orders WHERE user_id = u.id) AS n FROM users AS u WHERE u.id != 2",
+				`[{"name": "a", "n": 2}, {"name": "c", "n": 1}]`},
+			{"in where", "SELECT o.id FROM orders o WHERE o.user_id IN (SELECT id FROM users WHERE name = 'a' AND id = o.user_id)",
+				`[{"id": 10}, {"id": 13}]`},
+			{"nested", "SELECT id, (SELECT (SELECT name FROM users u WHERE u.id = o.user_id) FROM users x WHERE x.id = 2) AS name FROM orders o WHERE id < 12",
+				`[{"id": 10, "name": "a"}, {"id": 11, "name": "c"}]`},
+			{"with params", "SELECT id FROM orders o WHERE (SELECT name FROM users u WHERE u.id = o.user_id) = ?",
+				`[{"id": 11}]`},
+			{"uncorrelated", "SELECT id FROM orders WHERE user_id = (SELECT id FROM users WHERE name = 'c')",
+				`[{"id": 11}]`},
+			{"qualified wildcard", "SELECT u.* FROM users u WHERE u.id = 2",
+				`[{"id": 2, "name": "b"}]`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, `
+					CREATE TABLE users(id INTEGER PRIMARY KEY);
+					CREATE TABLE orders(id INTEGER PRIMARY KEY);
+					INSERT INTO users (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+					INSERT INTO orders (id, user_id) VALUES (10, 1), (11, 3), (12, 4), (13, 1);
+				`)
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query, "c")
+				require.NoError(t, err)
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.NoError(t, st.Close())
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+
+		t.Run("multiple rows", func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, `CREATE TABLE test; INSERT INTO test (a) VALUES (1), (2)`)
+			require.NoError(t, err)
+
+			st, err := db.Query(ctx, `SELECT (SELECT a FROM test t WHERE t.a >= x.a) AS a FROM test x`)
+			require.NoError(t, err)
+			defer st.Close()
+
+			var buf bytes.Buffer
+			err = document.IteratorToJSONArray(&buf, st)
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("with arrays and ANY", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			query    string
+			expected string
+		}{
+			{"IN", "SELECT id FROM test WHERE 'go' IN tags", `[{"id": 1}]`},
+			{"ANY =", "SELECT id FROM test WHERE tags ANY = 'go'", `[{"id": 1}]`},
+			{"ANY !=", "SELECT id FROM test WHERE tags ANY != 'go'", `[{"id": 1}, {"id": 2}]`},
+			{"ANY >", "SELECT id FROM test WHERE tags ANY > 'd'", `[{"id": 1}]`},
+			{"ANY with param", "SELECT id FROM test WHERE tags ANY = ?", `[{"id": 2}]`},
+			{"NOT ANY", "SELECT id FROM test WHERE NOT tags ANY = 'go'", `[{"id": 2}, {"id": 3}, {"id": 4}]`},
+		}
+
+		for _, test := range tests {
+			testFn := func(withIndex bool) func(t *testing.T) {
+				return func(t *testing.T) {
+					db, err := genji.Open(":memory:")
+					require.NoError(t, err)
+					defer db.Close()
+
+					err = db.Exec(ctx, "CREATE TABLE test(id INTEGER PRIMARY KEY)")
+					require.NoError(t, err)
+					if withIndex {
+						err = db.Exec(ctx, "CREATE INDEX idx_tags ON test(tags)")
+						require.NoError(t, err)
+					}
+
+					err = db.Exec(ctx, `INSERT INTO test (id, tags) VALUES
+						(1, ['go', 'rust']), (2, ['c']), (3, 'go'), (4, NULL)`)
+					require.NoError(t, err)
+
+					st, err := db.Query(ctx, test.query, "c")
+					require.NoError(t, err)
+					defer st.Close()
+
+					var buf bytes.Buffer
+					err = document.IteratorToJSONArray(&buf, st)
+					require.NoError(t, err)
+					require.JSONEq(t, test.expected, buf.String())
+				}
+			}
+
+			t.Run("No Index/"+test.name, testFn(false))
+			t.Run("With Index/"+test.name, testFn(true))
+		}
+	})
+
+	t.Run("with INTERSECT and EXCEPT", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			query    string
+			fails    bool
+			expected string
+		}{
+			{"INTERSECT overlapping", "SELECT x FROM a INTERSECT SELECT y FROM b", false, `[{"x": 2}, {"x": 3}]`},
+			{"EXCEPT overlapping", "SELECT x FROM a EXCEPT SELECT y FROM b", false, `[{"x": 1}, {"x": 4}]`},
+			{"INTERSECT ALL overlapping", "SELECT x FROM a INTERSECT ALL SELECT y FROM b", false, `[{"x": 2}, {"x": 2}, {"x": 3}]`},
+			{"EXCEPT ALL overlapping", "SELECT x FROM a EXCEPT ALL SELECT y FROM b", false, `[{"x": 1}, {"x": 2}, {"x": 4}]`},
+			{"INTERSECT disjoint", "SELECT x FROM a INTERSECT SELECT w FROM d", false, `[]`},
+			{"EXCEPT disjoint", "SELECT x FROM a EXCEPT SELECT w FROM d", false, `[{"x": 1}, {"x": 2}, {"x": 3}, {"x": 4}]`},
+			{"INTERSECT subset", "SELECT z FROM c INTERSECT SELECT x FROM a", false, `[{"z": 1}, {"z": 2}, {"z": 3}, {"z": 4}]`},
+			{"EXCEPT subset", "SELECT x FROM a EXCEPT SELECT z FROM c", false, `[]`},
+			{"EXCEPT superset", "SELECT z FROM c EXCEPT SELECT x FROM a", false, `[{"z": 5}]`},
+			{"Multiple fields", "SELECT x, x + 1 FROM a INTERSECT SELECT y, y + 1 FROM b", false, `[{"x": 2, "x + 1": 3}, {"x": 3, "x + 1": 4}]`},
+			{"Precedence", "SELECT x FROM a EXCEPT SELECT w FROM d INTERSECT SELECT y FROM b", false, `[{"x": 1}, {"x": 2}, {"x": 3}, {"x": 4}]`},
+			{"Left to right", "SELECT x FROM a EXCEPT SELECT y FROM b EXCEPT SELECT 4", false, `[{"x": 1}]`},
+			{"With WHERE", "SELECT x FROM a WHERE x > 2 INTERSECT SELECT y FROM b WHERE y < 5", false, `[{"x": 3}]`},
+			{"No table", "SELECT 1 INTERSECT SELECT 1", false, `[{"1": 1}]`},
+			{"In common table expression", "WITH e AS (SELECT x FROM a EXCEPT SELECT y FROM b) SELECT x FROM e WHERE x > 1", false, `[{"x": 4}]`},
+			{"Different number of fields", "SELECT x, x FROM a INTERSECT SELECT y FROM b", true, ``},
+			{"Missing SELECT", "SELECT x FROM a EXCEPT x", true, ``},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, `
+					CREATE TABLE a; CREATE TABLE b; CREATE TABLE c; CREATE TABLE d;
+					INSERT INTO a (x) VALUES (1), (2), (2), (2), (3), (4);
+					INSERT INTO b (y) VALUES (2), (2), (3), (5);
+					INSERT INTO c (z) VALUES (1), (2), (3), (4), (5);
+					INSERT INTO d (w) VALUES (10), (11);
+				`)
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, test.query)
+				if test.fails {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, test.expected, buf.String())
+			})
+		}
+	})
+
+	t.Run("with FOR UPDATE", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		err = db.Exec(ctx, `
+			CREATE TABLE test(a INTEGER PRIMARY KEY, b INTEGER);
+			CREATE INDEX idx_b ON test(b);
+			INSERT INTO test (a, b) VALUES (1, 10), (2, 20), (3, 30);
+		`)
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `BEGIN`)
+		require.NoError(t, err)
+
+		st, err := db.Query(ctx, `SELECT a FROM test WHERE b >= 20 ORDER BY b DESC FOR UPDATE`)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		err = document.IteratorToJSONArray(&buf, st)
+		require.NoError(t, err)
+		require.NoError(t, st.Close())
+		require.JSONEq(t, `[{"a": 3}, {"a": 2}]`, buf.String())
+
+		err = db.Exec(ctx, `UPDATE test SET b = b + 1 WHERE a = 2`)
+		require.NoError(t, err)
+
+		err = db.Exec(ctx, `COMMIT`)
+		require.NoError(t, err)
+
+		d, err := db.QueryDocument(ctx, `SELECT b FROM test WHERE a = 2`)
+		require.NoError(t, err)
+		var b int
+		err = document.Scan(d, &b)
+		require.NoError(t, err)
+		require.Equal(t, 21, b)
+
+		// locking requires a read-write transaction
+		err = db.Exec(ctx, `BEGIN READ ONLY`)
+		require.NoError(t, err)
+		err = db.Exec(ctx, `SELECT * FROM test FOR UPDATE`)
+		require.Error(t, err)
+		// but it can be explained in any transaction
+		err = db.Exec(ctx, `EXPLAIN SELECT * FROM test FOR UPDATE`)
+		require.NoError(t, err)
+		err = db.Exec(ctx, `ROLLBACK`)
+		require.NoError(t, err)
+	})
+}
+
+func TestSelectPagination(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test(id INTEGER PRIMARY KEY, a INTEGER, b TEXT);
+		CREATE INDEX idx_a ON test(a);
+	`)
+	require.NoError(t, err)
+	// many documents share the same value of a
+	for i := 0; i < 25; i++ {
+		err = db.Exec(ctx, "INSERT INTO test (id, a, b) VALUES (?, ?, ?)", 25-i, i%4, fmt.Sprintf("b%d", i))
+		require.NoError(t, err)
+	}
+
+	// page returns the ids and the sort values of the documents of a page, and the next token
+	page := func(t *testing.T, q string, token query.PageToken, args ...interface{}) ([]int, []int, query.PageToken) {
+		t.Helper()
+
+		res, err := db.Query(ctx, q, append(args, token)...)
+		require.NoError(t, err)
+		defer res.Close()
+
+		var ids, values []int
+		err = res.Iterate(func(d document.Document) error {
+			var id, v int
+			err := document.Scan(d, &id, &v)
+			ids = append(ids, id)
+			values = append(values, v)
+			return err
+		})
+		require.NoError(t, err)
+
+		next, err := res.NextToken()
+		require.NoError(t, err)
+		return ids, values, query.PageToken(next)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		args  []interface{}
+		count int
+		// -1 if descending, 0 if the values are not sorted
+		order int
+	}{
+		{"No order by", "SELECT id, id FROM test LIMIT 4", nil, 25, 1},
+		{"Order by", "SELECT id, a FROM test ORDER BY a LIMIT 4", nil, 25, 1},
+		{"Order by desc", "SELECT id, a FROM test ORDER BY a DESC LIMIT 4", nil, 25, -1},
+		{"Order by not projected", "SELECT id, 0 FROM test ORDER BY b LIMIT 4", nil, 25, 0},
+		{"Order by expression", "SELECT id, a FROM test ORDER BY 0 - a LIMIT 4", nil, 25, -1},
+		{"Where", "SELECT id, a FROM test WHERE a > ? ORDER BY a LIMIT 4", []interface{}{1}, 12, 1},
+		{"Top-N sort disabled", "SELECT id, a FROM test ORDER BY a LIMIT 4 OFFSET 0", nil, 25, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ids, values []int
+			var token query.PageToken
+			var pages int
+
+			for {
+				pids, pvalues, next := page(t, test.query, token, test.args...)
+				ids = append(ids, pids...)
+				values = append(values, pvalues...)
+				if len(pids) == 0 {
+					require.Equal(t, token, next)
+					break
+				}
+				require.LessOrEqual(t, len(pids), 4)
+				token = next
+				pages++
+			}
+
+			require.Equal(t, (test.count+3)/4, pages)
+
+			// every document is returned exactly once
+			seen := make(map[int]bool)
+			for _, id := range ids {
+				require.False(t, seen[id], "document %d returned twice", id)
+				seen[id] = true
+			}
+			require.Len(t, ids, test.count)
+
+			for i := 1; i < len(values) && test.order != 0; i++ {
+				if test.order > 0 {
+					require.LessOrEqual(t, values[i-1], values[i])
+				} else {
+					require.GreaterOrEqual(t, values[i-1], values[i])
+				}
+			}
+		})
+	}
+
+	t.Run("Documents inserted and deleted between pages", func(t *testing.T) {
+		q := "SELECT id, id FROM test LIMIT 10"
+
+		ids, _, token := page(t, q, "")
+		require.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, ids)
+
+		err := db.Exec(ctx, "DELETE FROM test WHERE id <= 2 OR id = 11")
+		require.NoError(t, err)
+		err = db.Exec(ctx, "INSERT INTO test (id, a) VALUES (0, 0), (100, 0)")
+		require.NoError(t, err)
+
+		ids, _, _ = page(t, q, token)
+		require.Equal(t, []int{12, 13, 14, 15, 16, 17, 18, 19, 20, 21}, ids)
+	})
+
+	t.Run("Invalid tokens", func(t *testing.T) {
+		_, _, token := page(t, "SELECT id, a FROM test ORDER BY a LIMIT 4", "")
+
+		run := func(q string, args ...interface{}) error {
+			res, err := db.Query(ctx, q, args...)
+			if err != nil {
+				return err
+			}
+			return res.Close()
+		}
+
+		// the size of the pages can change
+		err := run("SELECT id, a FROM test ORDER BY a LIMIT 10", token)
+		require.NoError(t, err)
+
+		err = run("SELECT id, a FROM test ORDER BY a DESC LIMIT 4", token)
+		require.True(t, errors.Is(err, query.ErrInvalidToken))
+		err = run("SELECT id, b FROM test ORDER BY a LIMIT 4", token)
+		require.True(t, errors.Is(err, query.ErrInvalidToken))
+		err = run("SELECT id, a FROM test WHERE a > ? ORDER BY a LIMIT 4", 1, token)
+		require.True(t, errors.Is(err, query.ErrInvalidToken))
+
+		for _, token := range []query.PageToken{"foo", "%%", "AAAA"} {
+			err = run("SELECT id, a FROM test ORDER BY a LIMIT 4", token)
+			require.Equal(t, query.ErrInvalidToken, err)
+		}
+	})
+
+	t.Run("Prepared statement", func(t *testing.T) {
+		q := "SELECT id, a FROM test ORDER BY a LIMIT 4"
+		stmt, err := db.Prepare(ctx, q)
+		require.NoError(t, err)
+
+		run := func(args ...interface{}) ([]int, query.PageToken) {
+			res, err := stmt.Query(ctx, args...)
+			require.NoError(t, err)
+			defer res.Close()
+
+			var ids []int
+			err = res.Iterate(func(d document.Document) error {
+				var id, a int
+				err := document.Scan(d, &id, &a)
+				ids = append(ids, id)
+				return err
+			})
+			require.NoError(t, err)
+
+			next, err := res.NextToken()
+			if err == query.ErrNotPaginated {
+				return ids, ""
+			}
+			require.NoError(t, err)
+			return ids, query.PageToken(next)
+		}
+
+		_, token := run(query.PageToken(""))
+		// runs that are not paginated don't affect the next ones
+		all, _ := run()
+		require.Len(t, all, 4)
+
+		ids, _ := run(token)
+		expected, _, _ := page(t, q, token)
+		require.Equal(t, expected, ids)
+		require.Len(t, ids, 4)
+	})
+
+	t.Run("Not paginated", func(t *testing.T) {
+		res, err := db.Query(ctx, "SELECT * FROM test")
+		require.NoError(t, err)
+		_, err = res.NextToken()
+		require.Equal(t, query.ErrNotPaginated, err)
+		require.NoError(t, res.Close())
+
+		for _, q := range []string{
+			"SELECT a, COUNT(*) FROM test GROUP BY a",
+			"SELECT COUNT(*) FROM test",
+			"WITH t AS (SELECT * FROM test) SELECT * FROM t",
+			"UPDATE test SET a = 1",
+		} {
+			err = db.Exec(ctx, q, query.PageToken(""))
+			require.Error(t, err, q)
+		}
+	})
+}
+
+func BenchmarkSelectTimeRange(b *testing.B) {
+	ctx := context.Background()
+
+	for _, withIndex := range []bool{false, true} {
+		b.Run(fmt.Sprintf("index: %v", withIndex), func(b *testing.B) {
+			db, err := genji.Open(":memory:")
+			require.NoError(b, err)
+			defer db.Close()
+
+			err = db.Exec(ctx, "CREATE TABLE events (created TEXT NOT NULL)")
+			require.NoError(b, err)
+			if withIndex {
+				err = db.Exec(ctx, "CREATE INDEX idx_events_created ON events(created)")
+				require.NoError(b, err)
+			}
+
+			// one event per second during a day, inserted in a random order
+			start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+			const n = 24 * 60 * 60
+			tx, err := db.Begin(true)
+			require.NoError(b, err)
+			for _, i := range rand.New(rand.NewSource(42)).Perm(n) {
+				created := start.Add(time.Duration(i) * time.Second).Format(expr.TimestampFormat)
+				err = tx.Exec(ctx, "INSERT INTO events (created, i) VALUES (?, ?)", created, i)
+				require.NoError(b, err)
+			}
+			require.NoError(b, tx.Commit())
+
+			// a ten minute window
+			from := start.Add(12 * time.Hour).Format(expr.TimestampFormat)
+			to := start.Add(12*time.Hour + 10*time.Minute).Format(expr.TimestampFormat)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				st, err := db.Query(ctx, "SELECT i FROM events WHERE created BETWEEN ? AND ? ORDER BY created", from, to)
+				require.NoError(b, err)
+
+				var count int
+				err = st.Iterate(func(d document.Document) error {
+					count++
+					return nil
+				})
+				require.NoError(b, err)
+				require.NoError(b, st.Close())
+				require.Equal(b, 601, count)
+			}
+		})
+	}
 }
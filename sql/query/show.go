@@ -0,0 +1,72 @@
+package query
+
+import (
+	"context"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// ShowSizeStmt is a DSL that allows creating a full SHOW SIZE statement.
+// It returns a document with the number of records of the table and an estimate of the
+// number of bytes used by the table and its indexes, followed by one document per index
+// with the number of entries of the index and an estimate of the number of bytes they use.
+type ShowSizeStmt struct {
+	TableName string
+}
+
+// IsReadOnly always returns true. It implements the Statement interface.
+func (stmt ShowSizeStmt) IsReadOnly() bool {
+	return true
+}
+
+// Run runs the Show size statement in the given transaction.
+// It implements the Statement interface.
+func (stmt ShowSizeStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	t, err := tx.GetTable(stmt.TableName)
+	if err != nil {
+		return res, err
+	}
+
+	records, size, err := t.SizeEstimate()
+	if err != nil {
+		return res, err
+	}
+
+	docs := []document.Document{
+		sizeDocument(stmt.TableName, "", records, size),
+	}
+
+	indexes, err := t.Indexes()
+	if err != nil {
+		return res, err
+	}
+
+	for _, idx := range indexes {
+		entries, size, err := idx.Size()
+		if err != nil {
+			return res, err
+		}
+
+		docs = append(docs, sizeDocument(stmt.TableName, idx.Opts.IndexName, entries, size))
+	}
+
+	res.Stream = document.NewStream(document.NewIterator(docs...))
+	return res, nil
+}
+
+func sizeDocument(tableName, indexName string, records, size int64) document.Document {
+	fb := document.NewFieldBuffer().
+		Add("table_name", document.NewTextValue(tableName))
+	if indexName != "" {
+		fb.Add("index_name", document.NewTextValue(indexName))
+	} else {
+		fb.Add("index_name", document.NewNullValue())
+	}
+
+	return fb.Add("records", document.NewIntegerValue(records)).
+		Add("bytes", document.NewIntegerValue(size))
+}
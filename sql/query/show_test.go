@@ -0,0 +1,57 @@
+package query_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowSizeStmt(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := genji.Open(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Exec(ctx, `
+		CREATE TABLE test(a INTEGER PRIMARY KEY);
+		CREATE INDEX idx_test_b ON test(b);
+		INSERT INTO test (a, b) VALUES (1, 'x'), (2, 'y'), (3, 'z');
+	`)
+	require.NoError(t, err)
+
+	type size struct {
+		TableName string `genji:"table_name"`
+		IndexName string `genji:"index_name"`
+		Records   int64  `genji:"records"`
+		Bytes     int64  `genji:"bytes"`
+	}
+
+	res, err := db.Query(ctx, "SHOW SIZE test")
+	require.NoError(t, err)
+	defer res.Close()
+
+	var sizes []size
+	err = res.Iterate(func(d document.Document) error {
+		var s size
+		err := document.StructScan(d, &s)
+		sizes = append(sizes, s)
+		return err
+	})
+	require.NoError(t, err)
+
+	require.Len(t, sizes, 2)
+	require.Equal(t, "test", sizes[0].TableName)
+	require.Empty(t, sizes[0].IndexName)
+	require.EqualValues(t, 3, sizes[0].Records)
+	require.Equal(t, "idx_test_b", sizes[1].IndexName)
+	require.EqualValues(t, 3, sizes[1].Records)
+	require.NotZero(t, sizes[1].Bytes)
+	require.Greater(t, sizes[0].Bytes, sizes[1].Bytes)
+
+	_, err = db.Query(ctx, "SHOW SIZE unknown")
+	require.Error(t, err)
+}
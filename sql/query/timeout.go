@@ -0,0 +1,44 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// A Timeout limits the duration of each statement of the query it is passed to, alongside
+// the arguments of the query. It takes precedence over the StatementTimeout of the database:
+//
+//	res, err := db.Query(ctx, "SELECT * FROM foo", query.Timeout(time.Minute))
+//
+// A timeout that is not positive disables the timeout of the database.
+// Statements running longer fail with context.DeadlineExceeded.
+type Timeout time.Duration
+
+// splitTimeout removes the Timeout from the arguments and returns the timeout of the statements,
+// which defaults to the StatementTimeout of the database.
+func splitTimeout(db *database.Database, args []expr.Param) ([]expr.Param, time.Duration) {
+	for i, p := range args {
+		if t, ok := p.Value.(Timeout); ok {
+			rest := make([]expr.Param, 0, len(args)-1)
+			rest = append(rest, args[:i]...)
+			rest = append(rest, args[i+1:]...)
+			return rest, time.Duration(t)
+		}
+	}
+
+	return args, db.StatementTimeout
+}
+
+// statementContext returns the context of a statement, which expires after timeout,
+// and a function that releases its resources once the result of the statement is closed.
+// If timeout is not positive, the statement only depends on ctx.
+func statementContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
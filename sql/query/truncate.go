@@ -0,0 +1,46 @@
+package query
+
+import (
+	"context"
+	"errors"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// TruncateTableStmt is a DSL that allows creating a TRUNCATE TABLE query.
+type TruncateTableStmt struct {
+	TableName string
+	// If set to true, the sequence used to generate the keys
+	// of documents without primary key is restarted.
+	RestartIdentity bool
+}
+
+// IsReadOnly always returns false. It implements the Statement interface.
+func (stmt TruncateTableStmt) IsReadOnly() bool {
+	return false
+}
+
+// Run runs the TruncateTable statement in the given transaction.
+// It implements the Statement interface.
+func (stmt TruncateTableStmt) Run(ctx context.Context, tx *database.Transaction, args []expr.Param) (Result, error) {
+	var res Result
+
+	if stmt.TableName == "" {
+		return res, errors.New("missing table name")
+	}
+
+	t, err := tx.GetTable(stmt.TableName)
+	if err != nil {
+		return res, err
+	}
+
+	return res, t.Truncate(stmt.RestartIdentity)
+}
+
+// Validate checks that the table exists.
+// It implements the Validator interface.
+func (stmt TruncateTableStmt) Validate(tx *database.Transaction, args []expr.Param) error {
+	_, err := validateTable(tx, stmt.TableName)
+	return err
+}
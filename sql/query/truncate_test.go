@@ -0,0 +1,69 @@
+package query_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected int64
+	}{
+		{"Default", "TRUNCATE TABLE test", 3},
+		{"Continue identity", "TRUNCATE TABLE test CONTINUE IDENTITY", 3},
+		{"Restart identity", "TRUNCATE TABLE test RESTART IDENTITY", 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			ctx := context.Background()
+
+			err = db.Exec(ctx, "CREATE TABLE test; CREATE INDEX idx_test_a ON test(a)")
+			require.NoError(t, err)
+			err = db.Exec(ctx, "INSERT INTO test (a) VALUES (1), (2)")
+			require.NoError(t, err)
+
+			err = db.Exec(ctx, test.query)
+			require.NoError(t, err)
+
+			// the index must have been truncated as well
+			_, err = db.QueryDocument(ctx, "SELECT * FROM test WHERE a > 0")
+			require.Equal(t, database.ErrDocumentNotFound, err)
+
+			err = db.Exec(ctx, "INSERT INTO test (a) VALUES (3)")
+			require.NoError(t, err)
+
+			d, err := db.QueryDocument(ctx, "SELECT pk() FROM test")
+			require.NoError(t, err)
+			var pk int64
+			err = document.Scan(d, &pk)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, pk)
+		})
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		db, err := genji.Open(":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		ctx := context.Background()
+
+		err = db.Exec(ctx, "TRUNCATE TABLE unknown")
+		require.Error(t, err)
+
+		err = db.Exec(ctx, "TRUNCATE TABLE __genji_tables")
+		require.Error(t, err)
+	})
+}
@@ -88,7 +88,7 @@ func TestUpdateStmt(t *testing.T) {
 			params   []interface{}
 		}{
 			{"SET / No cond add field ", `UPDATE foo set b = 0`, false, `[{"a": [1, 0, 0], "b": 0}, {"a": [2, 0], "b": 0}]`, nil},
-			{"SET / No cond / with path at existing index only", `UPDATE foo SET a[2] = 10`, false, `[{"a": [1, 0, 10]}, {"a": [2, 0]}]`, nil},
+			{"SET / No cond / index out of range in some documents", `UPDATE foo SET a[2] = 10`, true, ``, nil},
 			{"SET / No cond / with index array", `UPDATE foo SET a[1] = 10`, false, `[{"a": [1, 10, 0]}, {"a": [2, 10]}]`, nil},
 			{"SET / No cond / with path on non existing field", `UPDATE foo SET a.foo[1] = 10`, false, `[{"a": [1, 0, 0]}, {"a": [2, 0]}]`, nil},
 			{"SET / With cond / index array", `UPDATE foo SET a[0] = 1 WHERE a[0] = 2`, false, `[{"a": [1, 0, 0]}, {"a": [1, 0]}]`, nil},
@@ -126,4 +126,47 @@ func TestUpdateStmt(t *testing.T) {
 			require.JSONEq(t, tt.expected, buf.String())
 		}
 	})
+
+	t.Run("with documents in arrays", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			query    string
+			fails    bool
+			expected string
+		}{
+			{"SET / existing element", `UPDATE foo SET items[0].price = 9.99 WHERE id = 1`, false, `[{"id": 1, "items": [{"name": "a", "price": 9.99}, {"name": "b", "price": 2}]}, {"id": 2, "items": [{"name": "c", "price": 3}]}]`},
+			{"SET / new field in element", `UPDATE foo SET items[1].qty = 5 WHERE id = 1`, false, `[{"id": 1, "items": [{"name": "a", "price": 1}, {"name": "b", "price": 2, "qty": 5}]}, {"id": 2, "items": [{"name": "c", "price": 3}]}]`},
+			{"SET / index out of range", `UPDATE foo SET items[1].price = 9.99`, true, ``},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				db, err := genji.Open(":memory:")
+				require.NoError(t, err)
+				defer db.Close()
+
+				err = db.Exec(ctx, `CREATE TABLE foo;
+					INSERT INTO foo (id, items) VALUES
+						(1, [{name: 'a', price: 1}, {name: 'b', price: 2}]),
+						(2, [{name: 'c', price: 3}])`)
+				require.NoError(t, err)
+
+				err = db.Exec(ctx, tt.query)
+				if tt.fails {
+					require.EqualError(t, err, "array index 1 out of range")
+					return
+				}
+				require.NoError(t, err)
+
+				st, err := db.Query(ctx, "SELECT * FROM foo")
+				require.NoError(t, err)
+				defer st.Close()
+
+				var buf bytes.Buffer
+				err = document.IteratorToJSONArray(&buf, st)
+				require.NoError(t, err)
+				require.JSONEq(t, tt.expected, buf.String())
+			})
+		}
+	})
 }
@@ -0,0 +1,113 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/query/expr"
+)
+
+// A ValidationError describes why a statement cannot be executed
+// against the current schema of the database.
+type ValidationError struct {
+	// Name of the table concerned by the error, if any.
+	TableName string
+	// Path of the field concerned by the error, if any.
+	Path document.ValuePath
+	// Err describes the problem.
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+
+	if e.TableName != "" {
+		fmt.Fprintf(&b, "table %q: ", e.TableName)
+	}
+	if len(e.Path) > 0 {
+		fmt.Fprintf(&b, "field %q: ", e.Path)
+	}
+	b.WriteString(e.Err.Error())
+
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors is returned by Validate methods when a statement is not valid.
+// It lists every problem found.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// A Validator is a statement that can be checked against the current schema
+// of the database without being run.
+// Validate must return a ValidationErrors if the statement is not valid.
+type Validator interface {
+	Validate(tx *database.Transaction, args []expr.Param) error
+}
+
+// Validate checks every statement of the query against the current schema of the database,
+// without running them, and returns the list of problems found as a ValidationErrors.
+// Statements are validated independently from each other: the changes a statement would make,
+// such as creating a table, are not visible to the following statements.
+// Statements that don't implement the Validator interface are considered valid.
+func (q Query) Validate(db *database.Database, args []expr.Param) error {
+	tx := db.GetAttachedTx()
+	if tx == nil {
+		var err error
+		tx, err = db.Begin(false)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	var errs ValidationErrors
+	for _, stmt := range q.Statements {
+		v, ok := stmt.(Validator)
+		if !ok {
+			continue
+		}
+
+		err := v.Validate(tx, args)
+		if err == nil {
+			continue
+		}
+
+		var verrs ValidationErrors
+		if !errors.As(err, &verrs) {
+			return err
+		}
+		errs = append(errs, verrs...)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// validateTable returns a ValidationErrors if the table doesn't exist.
+func validateTable(tx *database.Transaction, tableName string) (*database.Table, error) {
+	t, err := tx.GetTable(tableName)
+	if errors.Is(err, database.ErrTableNotFound) {
+		return nil, ValidationErrors{{TableName: tableName, Err: database.ErrTableNotFound}}
+	}
+
+	return t, err
+}
@@ -0,0 +1,139 @@
+package query_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/genjidb/genji"
+	"github.com/genjidb/genji/document"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query"
+	"github.com/genjidb/genji/sql/query/expr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		params   []expr.Param
+		expected []string
+	}{
+		{"Insert / Valid", "INSERT INTO test (a, b) VALUES (2, 'foo'), (3, 'bar')", nil, nil},
+		{"Insert / Valid with params", "INSERT INTO test (a, b) VALUES (?, ?)", []expr.Param{{Value: 2}, {Value: "foo"}}, nil},
+		{"Insert / Unknown table", "INSERT INTO unknown (a) VALUES (1)", nil, []string{
+			`table "unknown": table not found`,
+		}},
+		{"Insert / Not null", "INSERT INTO test (a) VALUES (2)", nil, []string{
+			`table "test": field "b": field "b" is required and must be not null`,
+		}},
+		{"Insert / Wrong type", "INSERT INTO test (a, b) VALUES ('foo', 'bar')", nil, []string{
+			`table "test": field "a": cannot cast "foo" as integer: strconv.ParseInt: parsing "foo": invalid syntax`,
+		}},
+		{"Insert / Missing primary key", "INSERT INTO test (b) VALUES ('foo')", nil, []string{
+			`table "test": field "a": primary key "a" is required and must be not null`,
+		}},
+		{"Insert / Duplicate", "INSERT INTO test (a, b) VALUES (1, 'foo')", nil, []string{
+			`table "test": field "a": duplicate document`,
+		}},
+		{"Insert / Duplicate in statement", "INSERT INTO test (a, b) VALUES (2, 'foo'), (2, 'bar')", nil, []string{
+			`table "test": field "a": duplicate document`,
+		}},
+		{"Insert / Several errors", "INSERT INTO test (a, b) VALUES (1, 'foo'), (2, NULL)", nil, []string{
+			`table "test": field "a": duplicate document`,
+			`table "test": field "b": field "b" is required and must be not null`,
+		}},
+		{"Insert / Missing param", "INSERT INTO test (a, b) VALUES (2, ?)", nil, []string{
+			`table "test": cannot find param number 1`,
+		}},
+		{"Select / Valid", "SELECT * FROM test WHERE unknown = 1", nil, nil},
+		{"Select / Unknown table", "SELECT * FROM unknown", nil, []string{
+			`table "unknown": table not found`,
+		}},
+		{"Update / Valid", "UPDATE test SET b = 'foo', c = NULL", nil, nil},
+		{"Update / Not null", "UPDATE test SET b = NULL", nil, []string{
+			`table "test": field "b": field is required and must be not null`,
+		}},
+		{"Update / Wrong type", "UPDATE test SET a = ?", []expr.Param{{Value: "foo"}}, []string{
+			`table "test": field "a": cannot cast "foo" as integer: strconv.ParseInt: parsing "foo": invalid syntax`,
+		}},
+		{"Unset / Not null", "UPDATE test UNSET b", nil, []string{
+			`table "test": field "b": field is required and must be not null`,
+		}},
+		{"Unset / Primary key", "UPDATE test UNSET a", nil, []string{
+			`table "test": field "a": cannot unset the primary key`,
+		}},
+		{"Delete / Valid", "DELETE FROM test WHERE a > 1", nil, nil},
+		{"Delete / Unknown table", "DELETE FROM unknown", nil, []string{
+			`table "unknown": table not found`,
+		}},
+		{"Create table / Already exists", "CREATE TABLE test", nil, []string{
+			`table "test": table already exists`,
+		}},
+		{"Create table / If not exists", "CREATE TABLE IF NOT EXISTS test", nil, nil},
+		{"Create index / Already exists", "CREATE INDEX idx_b ON test (b)", nil, []string{
+			`table "test": field "b": index already exists`,
+		}},
+		{"Create index / Unknown table", "CREATE INDEX idx_c ON unknown (c)", nil, []string{
+			`table "unknown": table not found`,
+		}},
+		{"Drop table / Unknown table", "DROP TABLE unknown", nil, []string{
+			`table "unknown": table not found`,
+		}},
+		{"Drop table / If exists", "DROP TABLE IF EXISTS unknown", nil, nil},
+		{"Drop index / Unknown index", "DROP INDEX unknown", nil, []string{
+			`index not found: "unknown"`,
+		}},
+		{"Alter / Already exists", "ALTER TABLE test RENAME TO other", nil, []string{
+			`table "other": table already exists`,
+		}},
+		{"Several statements", "DROP TABLE unknown; DELETE FROM test; UPDATE test UNSET b", nil, []string{
+			`table "unknown": table not found`,
+			`table "test": field "b": field is required and must be not null`,
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := genji.Open(":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			ctx := context.Background()
+
+			err = db.Exec(ctx, `
+				CREATE TABLE test (a INTEGER PRIMARY KEY, b TEXT NOT NULL);
+				CREATE INDEX idx_b ON test (b);
+				CREATE TABLE other;
+				INSERT INTO test (a, b) VALUES (1, 'a');
+			`)
+			require.NoError(t, err)
+
+			q, err := parser.ParseQuery(ctx, test.query)
+			require.NoError(t, err)
+
+			err = q.Validate(db.DB, test.params)
+			if test.expected == nil {
+				require.NoError(t, err)
+			} else {
+				var errs query.ValidationErrors
+				require.True(t, errors.As(err, &errs), "unexpected error %v", err)
+
+				var msgs []string
+				for _, e := range errs {
+					msgs = append(msgs, e.Error())
+				}
+				require.Equal(t, test.expected, msgs)
+			}
+
+			// validating must not modify the database
+			d, err := db.QueryDocument(ctx, "SELECT COUNT(*) FROM test")
+			require.NoError(t, err)
+			var count int
+			err = document.Scan(d, &count)
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+		})
+	}
+}
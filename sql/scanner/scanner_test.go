@@ -54,6 +54,8 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `>=`, tok: scanner.GTE, raw: `>=`},
 		{s: `IN`, tok: scanner.IN, raw: `IN`},
 		{s: `IS`, tok: scanner.IS, raw: `IS`},
+		{s: `BETWEEN`, tok: scanner.BETWEEN, raw: `BETWEEN`},
+		{s: `ANY`, tok: scanner.ANY, raw: `ANY`},
 
 		// Misc tokens
 		{s: `(`, tok: scanner.LPAREN, raw: `(`},
@@ -116,46 +118,81 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `-10.3`, tok: scanner.NUMBER, lit: `-10.3`, raw: `-10.3`},
 
 		// Keywords
+		{s: `ALL`, tok: scanner.ALL, raw: `ALL`},
 		{s: `ALTER`, tok: scanner.ALTER, raw: `ALTER`},
 		{s: `AS`, tok: scanner.AS, raw: `AS`},
 		{s: `ASC`, tok: scanner.ASC, raw: `ASC`},
 		{s: `BY`, tok: scanner.BY, raw: `BY`},
 		{s: `BEGIN`, tok: scanner.BEGIN, raw: `BEGIN`},
 		{s: `CAST`, tok: scanner.CAST, raw: `CAST`},
+		{s: `CHECK`, tok: scanner.CHECK, raw: `CHECK`},
+		{s: `COLLATE`, tok: scanner.COLLATE, raw: `COLLATE`},
 		{s: `COMMIT`, tok: scanner.COMMIT, raw: `COMMIT`},
+		{s: `CONTINUE`, tok: scanner.CONTINUE, raw: `CONTINUE`},
 		{s: `CREATE`, tok: scanner.CREATE, raw: `CREATE`},
+		{s: `EXCEPT`, tok: scanner.EXCEPT, raw: `EXCEPT`},
 		{s: `EXPLAIN`, tok: scanner.EXPLAIN, raw: `EXPLAIN`},
+		{s: `FILTER`, tok: scanner.FILTER, raw: `FILTER`},
 		{s: `DELETE`, tok: scanner.DELETE, raw: `DELETE`},
+		{s: `DEFAULT`, tok: scanner.DEFAULT, raw: `DEFAULT`},
 		{s: `DESC`, tok: scanner.DESC, raw: `DESC`},
+		{s: `DISTINCT`, tok: scanner.DISTINCT, raw: `DISTINCT`},
 		{s: `DROP`, tok: scanner.DROP, raw: `DROP`},
+		{s: `ELSE`, tok: scanner.ELSE, raw: `ELSE`},
+		{s: `ENCRYPTED`, tok: scanner.ENCRYPTED, raw: `ENCRYPTED`},
+		{s: `END`, tok: scanner.END, raw: `END`},
+		{s: `FOR`, tok: scanner.FOR, raw: `FOR`},
+		{s: `ANALYZE`, tok: scanner.ANALYZE, raw: `ANALYZE`},
+		{s: `FOREIGN`, tok: scanner.FOREIGN, raw: `FOREIGN`},
+		{s: `REFERENCES`, tok: scanner.REFERENCES, raw: `REFERENCES`},
+		{s: `CASCADE`, tok: scanner.CASCADE, raw: `CASCADE`},
+		{s: `CASE`, tok: scanner.CASE, raw: `CASE`},
+		{s: `RESTRICT`, tok: scanner.RESTRICT, raw: `RESTRICT`},
 		{s: `FROM`, tok: scanner.FROM, raw: `FROM`},
 		{s: `GROUP`, tok: scanner.GROUP, raw: `GROUP`},
+		{s: `IDENTITY`, tok: scanner.IDENTITY, raw: `IDENTITY`},
+		{s: `IGNORE`, tok: scanner.IGNORE, raw: `IGNORE`},
 		{s: `INSERT`, tok: scanner.INSERT, raw: `INSERT`},
+		{s: `INTERSECT`, tok: scanner.INTERSECT, raw: `INTERSECT`},
 		{s: `INTO`, tok: scanner.INTO, raw: `INTO`},
 		{s: `LIMIT`, tok: scanner.LIMIT, raw: `LIMIT`},
 		{s: `ONLY`, tok: scanner.ONLY, raw: `ONLY`},
 		{s: `OFFSET`, tok: scanner.OFFSET, raw: `OFFSET`},
 		{s: `ORDER`, tok: scanner.ORDER, raw: `ORDER`},
+		{s: `PERCENT`, tok: scanner.PERCENT, raw: `PERCENT`},
 		{s: `PRIMARY`, tok: scanner.PRIMARY, raw: `PRIMARY`},
 		{s: `READ`, tok: scanner.READ, raw: `READ`},
+		{s: `RECURSIVE`, tok: scanner.RECURSIVE, raw: `RECURSIVE`},
 		{s: `REINDEX`, tok: scanner.REINDEX, raw: `REINDEX`},
 		{s: `RENAME`, tok: scanner.RENAME, raw: `RENAME`},
+		{s: `REPEATABLE`, tok: scanner.REPEATABLE, raw: `REPEATABLE`},
+		{s: `REPLACE`, tok: scanner.REPLACE, raw: `REPLACE`},
+		{s: `RESTART`, tok: scanner.RESTART, raw: `RESTART`},
 		{s: `ROLLBACK`, tok: scanner.ROLLBACK, raw: `ROLLBACK`},
 		{s: `SELECT`, tok: scanner.SELECT, raw: `SELECT`},
 		{s: `SET`, tok: scanner.SET, raw: `SET`},
+		{s: `SHOW`, tok: scanner.SHOW, raw: `SHOW`},
+		{s: `STRICT`, tok: scanner.STRICT, raw: `STRICT`},
 		{s: `TABLE`, tok: scanner.TABLE, raw: `TABLE`},
+		{s: `TABLESAMPLE`, tok: scanner.TABLESAMPLE, raw: `TABLESAMPLE`},
+		{s: `THEN`, tok: scanner.THEN, raw: `THEN`},
 		{s: `TO`, tok: scanner.TO, raw: `TO`},
 		{s: `TRANSACTION`, tok: scanner.TRANSACTION, raw: `TRANSACTION`},
+		{s: `TRUNCATE`, tok: scanner.TRUNCATE, raw: `TRUNCATE`},
 		{s: `UPDATE`, tok: scanner.UPDATE, raw: `UPDATE`},
 		{s: `UNSET`, tok: scanner.UNSET, raw: `UNSET`},
+		{s: `USE`, tok: scanner.USE, raw: `USE`},
 		{s: `VALUES`, tok: scanner.VALUES, raw: `VALUES`},
+		{s: `WHEN`, tok: scanner.WHEN, raw: `WHEN`},
 		{s: `WHERE`, tok: scanner.WHERE, raw: `WHERE`},
+		{s: `WITH`, tok: scanner.WITH, raw: `WITH`},
 		{s: `WRITE`, tok: scanner.WRITE, raw: `WRITE`},
 		{s: `seLECT`, tok: scanner.SELECT, raw: `seLECT`}, // case insensitive
 
 		// types
 		{s: "BYTES", tok: scanner.TYPEBYTES, raw: `BYTES`},
 		{s: "BOOL", tok: scanner.TYPEBOOL, raw: `BOOL`},
+		{s: "BOOLEAN", tok: scanner.TYPEBOOLEAN, raw: `BOOLEAN`},
 		{s: "DOUBLE", tok: scanner.TYPEDOUBLE, raw: `DOUBLE`},
 		{s: "INTEGER", tok: scanner.TYPEINTEGER, raw: `INTEGER`},
 		{s: "TEXT", tok: scanner.TYPETEXT, raw: `TEXT`},
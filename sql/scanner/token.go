@@ -56,6 +56,8 @@ const (
 	GTE      // >=
 	IN       // IN
 	IS       // IS
+	BETWEEN  // BETWEEN
+	ANY      // ANY
 	operatorEnd
 
 	LPAREN      // (
@@ -72,24 +74,43 @@ const (
 
 	keywordBeg
 	// ALL and the following are Genji SQL Keywords
+	ALL
 	ALTER
+	ANALYZE
 	AS
 	ASC
 	BEGIN
 	BY
+	CASCADE
+	CASE
 	CAST
+	CHECK
+	COLLATE
 	COMMIT
+	CONTINUE
 	CREATE
+	DEFAULT
 	DELETE
 	DESC
+	DISTINCT
 	DROP
+	ELSE
+	ENCRYPTED
+	END
+	EXCEPT
 	EXISTS
 	EXPLAIN
+	FILTER
+	FOR
+	FOREIGN
 	FROM
 	GROUP
+	IDENTITY
 	IF
+	IGNORE
 	INDEX
 	INSERT
+	INTERSECT
 	INTO
 	KEY
 	LIMIT
@@ -98,22 +119,37 @@ const (
 	ON
 	ONLY
 	ORDER
+	PERCENT
 	PRECISION
 	PRIMARY
 	READ
+	RECURSIVE
+	REFERENCES
 	REINDEX
 	RENAME
+	REPEATABLE
+	REPLACE
+	RESTART
+	RESTRICT
 	ROLLBACK
 	SELECT
 	SET
+	SHOW
+	STRICT
 	TABLE
+	TABLESAMPLE
+	THEN
 	TO
 	TRANSACTION
+	TRUNCATE
 	UNIQUE
 	UNSET
 	UPDATE
+	USE
 	VALUES
+	WHEN
 	WHERE
+	WITH
 	WRITE
 
 	// Aliases
@@ -121,6 +157,7 @@ const (
 	TYPEBIGINT
 	TYPEBLOB
 	TYPEBOOL
+	TYPEBOOLEAN
 	TYPEBYTES
 	TYPECHARACTER
 	TYPEDOCUMENT
@@ -177,6 +214,8 @@ var tokens = [...]string{
 	GTE:      ">=",
 	IN:       "IN",
 	IS:       "IS",
+	BETWEEN:  "BETWEEN",
+	ANY:      "ANY",
 
 	LPAREN:      "(",
 	RPAREN:      ")",
@@ -190,25 +229,44 @@ var tokens = [...]string{
 	SEMICOLON:   ";",
 	DOT:         ".",
 
+	ALL:         "ALL",
 	ALTER:       "ALTER",
+	ANALYZE:     "ANALYZE",
 	AS:          "AS",
 	ASC:         "ASC",
 	BEGIN:       "BEGIN",
 	COMMIT:      "COMMIT",
 	GROUP:       "GROUP",
 	BY:          "BY",
+	CONTINUE:    "CONTINUE",
 	CREATE:      "CREATE",
+	CASCADE:     "CASCADE",
+	CASE:        "CASE",
 	CAST:        "CAST",
+	CHECK:       "CHECK",
+	COLLATE:     "COLLATE",
+	DEFAULT:     "DEFAULT",
 	DELETE:      "DELETE",
 	DESC:        "DESC",
+	DISTINCT:    "DISTINCT",
 	DROP:        "DROP",
+	ELSE:        "ELSE",
+	ENCRYPTED:   "ENCRYPTED",
+	END:         "END",
+	EXCEPT:      "EXCEPT",
 	EXISTS:      "EXISTS",
 	EXPLAIN:     "EXPLAIN",
+	FILTER:      "FILTER",
 	KEY:         "KEY",
+	FOR:         "FOR",
+	FOREIGN:     "FOREIGN",
 	FROM:        "FROM",
+	IDENTITY:    "IDENTITY",
 	IF:          "IF",
+	IGNORE:      "IGNORE",
 	INDEX:       "INDEX",
 	INSERT:      "INSERT",
+	INTERSECT:   "INTERSECT",
 	INTO:        "INTO",
 	LIMIT:       "LIMIT",
 	NOT:         "NOT",
@@ -216,28 +274,44 @@ var tokens = [...]string{
 	ON:          "ON",
 	ONLY:        "ONLY",
 	ORDER:       "ORDER",
+	PERCENT:     "PERCENT",
 	PRECISION:   "PRECISION",
 	PRIMARY:     "PRIMARY",
 	READ:        "READ",
+	RECURSIVE:   "RECURSIVE",
+	REFERENCES:  "REFERENCES",
 	REINDEX:     "REINDEX",
 	RENAME:      "RENAME",
+	REPEATABLE:  "REPEATABLE",
+	REPLACE:     "REPLACE",
+	RESTART:     "RESTART",
+	RESTRICT:    "RESTRICT",
 	ROLLBACK:    "ROLLBACK",
 	SELECT:      "SELECT",
 	SET:         "SET",
+	SHOW:        "SHOW",
+	STRICT:      "STRICT",
 	TABLE:       "TABLE",
+	TABLESAMPLE: "TABLESAMPLE",
+	THEN:        "THEN",
 	TO:          "TO",
 	TRANSACTION: "TRANSACTION",
+	TRUNCATE:    "TRUNCATE",
 	UNIQUE:      "UNIQUE",
 	UNSET:       "UNSET",
 	UPDATE:      "UPDATE",
+	USE:         "USE",
 	VALUES:      "VALUES",
+	WHEN:        "WHEN",
 	WHERE:       "WHERE",
+	WITH:        "WITH",
 	WRITE:       "WRITE",
 
 	TYPEARRAY:     "ARRAY",
 	TYPEBIGINT:    "BIGINT",
 	TYPEBLOB:      "BLOB",
 	TYPEBOOL:      "BOOL",
+	TYPEBOOLEAN:   "BOOLEAN",
 	TYPEBYTES:     "BYTES",
 	TYPECHARACTER: "CHARACTER",
 	TYPEDOCUMENT:  "DOCUMENT",
@@ -261,7 +335,7 @@ func initKeywords() {
 	for tok := keywordBeg + 1; tok < keywordEnd; tok++ {
 		keywords[strings.ToLower(tokens[tok])] = tok
 	}
-	for _, tok := range []Token{AND, OR, TRUE, FALSE, NULL, IN, IS} {
+	for _, tok := range []Token{AND, OR, TRUE, FALSE, NULL, IN, IS, BETWEEN, ANY} {
 		keywords[strings.ToLower(tokens[tok])] = tok
 	}
 }
@@ -283,7 +357,7 @@ func (tok Token) Precedence() int {
 		return 2
 	case IN:
 		return 3
-	case EQ, NEQ, EQREGEX, NEQREGEX, LT, LTE, GT, GTE, IS:
+	case EQ, NEQ, EQREGEX, NEQREGEX, LT, LTE, GT, GTE, IS, BETWEEN, ANY:
 		return 4
 	case ADD, SUB, BITWISEOR, BITWISEXOR:
 		return 5
@@ -0,0 +1,54 @@
+package genji
+
+import (
+	"context"
+
+	"github.com/genjidb/genji/database"
+	"github.com/genjidb/genji/sql/parser"
+	"github.com/genjidb/genji/sql/query"
+)
+
+// Statement is a parsed query that can be run multiple times, either in its own
+// transactions or within a transaction managed by the caller.
+type Statement struct {
+	db *DB
+	pq query.Query
+}
+
+// Prepare parses the query and returns a statement that can be run multiple times.
+func (db *DB) Prepare(ctx context.Context, q string) (*Statement, error) {
+	pq, err := parser.ParseQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	db.DB.Log(database.Event{Kind: database.EventQueryParsed, Query: q})
+
+	return &Statement{db: db, pq: pq}, nil
+}
+
+// Query runs the statement against the database and returns the result.
+// Like DB.Query, each statement of the query is run in its own transaction,
+// unless the query opens one using BEGIN.
+// The returned result must always be closed after usage.
+func (s *Statement) Query(ctx context.Context, args ...interface{}) (*query.Result, error) {
+	return s.pq.Run(ctx, s.db.DB, argsToParams(args))
+}
+
+// Exec runs the statement against the database without returning the result.
+func (s *Statement) Exec(ctx context.Context, args ...interface{}) error {
+	res, err := s.Query(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	return res.Close()
+}
+
+// Run the statement within tx and return the result.
+// The transaction is neither committed nor rolled back, which allows running several
+// statements, and operations on the transaction itself, atomically.
+// Statements that write to the database return engine.ErrTransactionReadOnly if tx is read-only.
+// Closing the returned result after usage is not mandatory.
+func (s *Statement) Run(ctx context.Context, tx *Tx, args ...interface{}) (*query.Result, error) {
+	return s.pq.Exec(ctx, tx.Transaction, argsToParams(args))
+}